@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"localcloud/internal/alarms"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	alarmCmd = &cobra.Command{
+		Use:   "alarm",
+		Short: "Manage metric alarms",
+	}
+
+	alarmCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create an alarm, e.g. --metric cpu --comparison '>' --threshold 80 --periods 3",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			metric, _ := cmd.Flags().GetString("metric")
+			comparison, _ := cmd.Flags().GetString("comparison")
+			threshold, _ := cmd.Flags().GetFloat64("threshold")
+			periods, _ := cmd.Flags().GetInt("periods")
+			period, _ := cmd.Flags().GetDuration("period")
+			instance, _ := cmd.Flags().GetString("instance")
+			project, _ := cmd.Flags().GetString("project")
+			description, _ := cmd.Flags().GetString("description")
+			webhooks, _ := cmd.Flags().GetStringArray("webhook")
+			logTransitions, _ := cmd.Flags().GetBool("log")
+			instanceAction, _ := cmd.Flags().GetString("action")
+
+			alarm := alarms.Alarm{
+				Name:              args[0],
+				Description:       description,
+				Metric:            metric,
+				Comparison:        comparison,
+				Threshold:         threshold,
+				Period:            int(period / time.Second),
+				EvaluationPeriods: periods,
+				Instance:          instance,
+				Project:           project,
+			}
+			// Notifications go out when the alarm fires and when it clears
+			for _, state := range []string{alarms.StateAlarm, alarms.StateOK} {
+				for _, webhook := range webhooks {
+					alarm.Actions = append(alarm.Actions, alarms.Action{Type: "webhook", URL: webhook, State: state})
+				}
+				if logTransitions {
+					alarm.Actions = append(alarm.Actions, alarms.Action{Type: "log", State: state})
+				}
+			}
+			if instanceAction != "" {
+				alarm.Actions = append(alarm.Actions, alarms.Action{Type: instanceAction, State: alarms.StateAlarm})
+			}
+
+			var status alarms.AlarmStatus
+			if err := callServer(cmd, http.MethodPost, "/alarms", alarm, &status); err != nil {
+				return fmt.Errorf("failed to create alarm: %w", err)
+			}
+			fmt.Printf("Created alarm %s: %s\n", status.Name, alarmCondition(status.Alarm))
+			return nil
+		},
+	}
+
+	alarmListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List alarms and their states",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var list []alarms.AlarmStatus
+			if err := callServer(cmd, http.MethodGet, "/alarms", nil, &list); err != nil {
+				return fmt.Errorf("failed to list alarms: %w", err)
+			}
+			if len(list) == 0 {
+				fmt.Println("No alarms found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-18s %-32s %-20s %s\n", "NAME", "STATE", "CONDITION", "SCOPE", "REASON")
+			for _, status := range list {
+				fmt.Printf("%-20s %-18s %-32s %-20s %s\n", status.Name, status.State, alarmCondition(status.Alarm),
+					alarmScope(status.Alarm), status.Reason)
+			}
+			return nil
+		},
+	}
+
+	alarmDescribeCmd = &cobra.Command{
+		Use:   "describe NAME",
+		Short: "Show an alarm, its recent datapoints and its history",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var status alarms.AlarmStatus
+			if err := callServer(cmd, http.MethodGet, "/alarms/"+args[0], nil, &status); err != nil {
+				return fmt.Errorf("failed to get alarm: %w", err)
+			}
+			var history []alarms.Transition
+			if err := callServer(cmd, http.MethodGet, "/alarms/"+args[0]+"/history", nil, &history); err != nil {
+				return fmt.Errorf("failed to get alarm history: %w", err)
+			}
+
+			fmt.Printf("Name:      %s\n", status.Name)
+			if status.Description != "" {
+				fmt.Printf("Desc:      %s\n", status.Description)
+			}
+			fmt.Printf("Condition: %s\n", alarmCondition(status.Alarm))
+			fmt.Printf("Scope:     %s\n", alarmScope(status.Alarm))
+			fmt.Printf("State:     %s since %s\n", status.State, status.StateUpdated.Local().Format("2006-01-02 15:04:05"))
+			fmt.Printf("Reason:    %s\n", status.Reason)
+			for _, action := range status.Actions {
+				target := action.Type
+				if action.URL != "" {
+					target += " " + action.URL
+				}
+				fmt.Printf("Action:    on %s %s\n", action.State, target)
+			}
+
+			// Last few samples are enough to see the trend
+			points := status.Datapoints
+			if len(points) > 10 {
+				points = points[len(points)-10:]
+			}
+			if len(points) > 0 {
+				fmt.Println("\nDatapoints:")
+				for _, point := range points {
+					value := fmt.Sprintf("%.1f", point.Value)
+					if point.Missing {
+						value = "no data"
+					}
+					fmt.Printf("  %s  %-10s %s\n", point.Time.Local().Format("15:04:05"), value, strings.Join(point.Breaching, ", "))
+				}
+			}
+
+			if len(history) > 0 {
+				fmt.Println("\nHistory:")
+				for _, t := range history {
+					fmt.Printf("  %s  %s -> %s: %s\n", t.Time.Local().Format("2006-01-02 15:04:05"), t.From, t.To, t.Reason)
+					for _, result := range t.Actions {
+						outcome := "ok"
+						if result.Error != "" {
+							outcome = "failed: " + result.Error
+						}
+						fmt.Printf("      %s %s %s\n", result.Type, result.Target, outcome)
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	alarmSetStateCmd = &cobra.Command{
+		Use:   "set-state NAME STATE",
+		Short: "Force OK, ALARM or INSUFFICIENT_DATA to try out an alarm's actions",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reason, _ := cmd.Flags().GetString("reason")
+			body := map[string]string{"state": strings.ToUpper(args[1]), "reason": reason}
+
+			var status alarms.AlarmStatus
+			if err := callServer(cmd, http.MethodPost, "/alarms/"+args[0]+"/state", body, &status); err != nil {
+				return fmt.Errorf("failed to set alarm state: %w", err)
+			}
+			fmt.Printf("Alarm %s is %s\n", status.Name, status.State)
+			return nil
+		},
+	}
+
+	alarmDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete an alarm",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/alarms/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete alarm: %w", err)
+			}
+			fmt.Printf("Deleted alarm %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func alarmCondition(alarm alarms.Alarm) string {
+	return fmt.Sprintf("%s %s %g for %d x %ds", alarm.Metric, alarm.Comparison, alarm.Threshold, alarm.EvaluationPeriods, alarm.Period)
+}
+
+func alarmScope(alarm alarms.Alarm) string {
+	scope := alarm.Instance
+	if scope == "" {
+		scope = "all instances"
+	}
+	if alarm.Project != "" {
+		scope += ", " + alarm.Project
+	}
+	return scope
+}
+
+func init() {
+	alarmCreateCmd.Flags().String("metric", "cpu", "Metric to watch: cpu, memory (percent of limit) or memory_mb")
+	alarmCreateCmd.Flags().String("comparison", ">", "Comparison with the threshold: >, >=, < or <=")
+	alarmCreateCmd.Flags().Float64("threshold", 0, "Threshold the metric is compared with")
+	alarmCreateCmd.Flags().Int("periods", 1, "Breaching samples in a row before the alarm fires")
+	alarmCreateCmd.Flags().Duration("period", time.Minute, "Time between samples (at least 10s)")
+	alarmCreateCmd.Flags().String("instance", "", "Instance ID or name (every running instance if empty)")
+	alarmCreateCmd.Flags().String("project", "", "Only watch this project's instances")
+	alarmCreateCmd.Flags().String("description", "", "Description sent with notifications")
+	alarmCreateCmd.Flags().StringArray("webhook", nil, "URL to POST a notification to when the alarm fires or clears (repeatable)")
+	alarmCreateCmd.Flags().Bool("log", false, "Log when the alarm fires or clears")
+	alarmCreateCmd.Flags().String("action", "", "Instance action when the alarm fires: restart or stop")
+	alarmCreateCmd.MarkFlagRequired("threshold")
+
+	alarmSetStateCmd.Flags().String("reason", "", "Reason recorded in the history")
+
+	alarmCmd.AddCommand(alarmCreateCmd, alarmListCmd, alarmDescribeCmd, alarmSetStateCmd, alarmDeleteCmd)
+	rootCmd.AddCommand(alarmCmd)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"localcloud/internal/autoscaling"
+	"localcloud/internal/compute"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	asgCmd = &cobra.Command{
+		Use:   "asg",
+		Short: "Manage auto scaling groups",
+	}
+
+	// Create a scaling group
+	asgCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Create an auto scaling group",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			image, _ := cmd.Flags().GetString("image")
+			snapshot, _ := cmd.Flags().GetString("snapshot")
+			ports, _ := cmd.Flags().GetString("ports")
+			minSize, _ := cmd.Flags().GetInt("min")
+			maxSize, _ := cmd.Flags().GetInt("max")
+			desired, _ := cmd.Flags().GetInt("desired")
+			cpuTarget, _ := cmd.Flags().GetFloat64("cpu-target")
+			memoryTarget, _ := cmd.Flags().GetFloat64("memory-target")
+			cooldown, _ := cmd.Flags().GetDuration("cooldown")
+
+			if desired < 0 {
+				desired = minSize
+			}
+			secretRefs, err := secretRefsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			parameterRefs, err := parameterRefsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			healthCheck, err := healthCheckFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			group := autoscaling.Group{
+				Name:     name,
+				Template: compute.CreateSpec{
+					Image:       image,
+					Snapshot:    snapshot,
+					Ports:       ports,
+					HealthCheck: healthCheck,
+					Secrets:     secretRefs,
+					Parameters:  parameterRefs,
+				},
+				Min:      minSize,
+				Max:      maxSize,
+				Desired:  desired,
+				Cooldown: int(cooldown.Seconds()),
+			}
+			if cpuTarget > 0 {
+				group.Policies = append(group.Policies, autoscaling.Policy{Metric: "cpu", Target: cpuTarget})
+			}
+			if memoryTarget > 0 {
+				group.Policies = append(group.Policies, autoscaling.Policy{Metric: "memory", Target: memoryTarget})
+			}
+
+			if err := callServer(cmd, http.MethodPost, "/autoscaling/groups", group, nil); err != nil {
+				return fmt.Errorf("failed to create scaling group: %w", err)
+			}
+
+			fmt.Printf("Created scaling group: %s (desired %d)\n", name, desired)
+			return nil
+		},
+	}
+
+	// List scaling groups
+	asgListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List auto scaling groups",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var groups []autoscaling.GroupStatus
+			if err := callServer(cmd, http.MethodGet, "/autoscaling/groups", nil, &groups); err != nil {
+				return fmt.Errorf("failed to list scaling groups: %w", err)
+			}
+
+			if len(groups) == 0 {
+				fmt.Println("No scaling groups found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-25s %-6s %-8s %-6s %-8s\n", "NAME", "IMAGE", "MIN", "DESIRED", "MAX", "RUNNING")
+			for _, group := range groups {
+				running := 0
+				for _, instance := range group.Instances {
+					if instance.State == "running" {
+						running++
+					}
+				}
+				fmt.Printf("%-20s %-25s %-6d %-8d %-6d %-8d\n",
+					group.Name, group.Template.Image, group.Min, group.Desired, group.Max, running)
+			}
+			return nil
+		},
+	}
+
+	// Show a group and its scaling history
+	asgDescribeCmd = &cobra.Command{
+		Use:   "describe NAME",
+		Short: "Show a scaling group and its activity history",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var group autoscaling.GroupStatus
+			if err := callServer(cmd, http.MethodGet, "/autoscaling/groups/"+args[0], nil, &group); err != nil {
+				return fmt.Errorf("failed to get scaling group: %w", err)
+			}
+			var activities []autoscaling.Activity
+			if err := callServer(cmd, http.MethodGet, "/autoscaling/groups/"+args[0]+"/activities", nil, &activities); err != nil {
+				return fmt.Errorf("failed to get activities: %w", err)
+			}
+
+			fmt.Printf("Name:      %s\n", group.Name)
+			fmt.Printf("Image:     %s\n", group.Template.Image)
+			fmt.Printf("Capacity:  min %d, desired %d, max %d\n", group.Min, group.Desired, group.Max)
+			fmt.Printf("Cooldown:  %ds\n", group.Cooldown)
+			for _, policy := range group.Policies {
+				fmt.Printf("Policy:    %s target %.1f%%\n", policy.Metric, policy.Target)
+			}
+
+			fmt.Println("\nInstances:")
+			for _, instance := range group.Instances {
+				fmt.Printf("  %-12s %-30s %s\n", instance.ID[:12], instance.Name, instance.State)
+			}
+
+			fmt.Println("\nActivity:")
+			for _, activity := range activities {
+				cause := activity.Cause
+				if activity.Error != "" {
+					cause = "error: " + activity.Error
+				}
+				fmt.Printf("  %s  %-10s %d -> %d  %s\n",
+					activity.Time.Format(time.DateTime), activity.Action, activity.From, activity.To, cause)
+			}
+			return nil
+		},
+	}
+
+	// Change capacity
+	asgSetCapacityCmd = &cobra.Command{
+		Use:   "set-capacity NAME",
+		Short: "Change min, max or desired capacity",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			req := map[string]int{}
+			for _, flag := range []string{"min", "max", "desired"} {
+				if cmd.Flags().Changed(flag) {
+					value, _ := cmd.Flags().GetInt(flag)
+					req[flag] = value
+				}
+			}
+			if len(req) == 0 {
+				return fmt.Errorf("one of --min, --max or --desired is required")
+			}
+
+			var group autoscaling.Group
+			if err := callServer(cmd, http.MethodPut, "/autoscaling/groups/"+args[0]+"/capacity", req, &group); err != nil {
+				return fmt.Errorf("failed to set capacity: %w", err)
+			}
+
+			fmt.Printf("Scaling group %s: min %d, desired %d, max %d\n", group.Name, group.Min, group.Desired, group.Max)
+			return nil
+		},
+	}
+
+	// Delete a group
+	asgDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a scaling group and terminate its instances",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/autoscaling/groups/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete scaling group: %w", err)
+			}
+
+			fmt.Printf("Deleted scaling group: %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	// Create command flags
+	asgCreateCmd.Flags().String("name", "", "Group name")
+	asgCreateCmd.Flags().String("image", "nginx:latest", "Instance image")
+	asgCreateCmd.Flags().String("snapshot", "", "Launch instances from a snapshot instead of --image")
+	asgCreateCmd.Flags().String("ports", "", "Port mapping, host port auto, empty or a range (e.g. :80)")
+	asgCreateCmd.Flags().Int("min", 1, "Minimum instances")
+	asgCreateCmd.Flags().Int("max", 3, "Maximum instances")
+	asgCreateCmd.Flags().Int("desired", -1, "Desired instances (defaults to min)")
+	asgCreateCmd.Flags().Float64("cpu-target", 0, "Target average CPU percent")
+	asgCreateCmd.Flags().Float64("memory-target", 0, "Target average memory percent")
+	asgCreateCmd.Flags().Duration("cooldown", time.Minute, "Minimum time between scaling actions")
+	asgCreateCmd.Flags().StringArray("secret", nil, "Inject a secret: NAME[@VERSION]=env:VAR or NAME[@VERSION]=file:NAME (repeatable)")
+	asgCreateCmd.Flags().StringArray("param", nil, "Set an env var from a parameter: ENV=/parameter/name (repeatable)")
+	asgCreateCmd.Flags().StringArray("param-path", nil, "Set env vars from every parameter under [PREFIX=]/path (repeatable)")
+	addHealthFlags(asgCreateCmd)
+	asgCreateCmd.MarkFlagRequired("name")
+
+	// Capacity command flags
+	asgSetCapacityCmd.Flags().Int("min", 0, "Minimum instances")
+	asgSetCapacityCmd.Flags().Int("max", 0, "Maximum instances")
+	asgSetCapacityCmd.Flags().Int("desired", 0, "Desired instances")
+
+	asgCmd.AddCommand(asgCreateCmd, asgListCmd, asgDescribeCmd, asgSetCapacityCmd, asgDeleteCmd)
+	rootCmd.AddCommand(asgCmd)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"localcloud/internal/backups"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupCmd = &cobra.Command{
+		Use:   "backup",
+		Short: "Back up and restore volumes",
+	}
+
+	backupCreateCmd = &cobra.Command{
+		Use:   "create VOLUME",
+		Short: "Archive a volume now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			compress, _ := cmd.Flags().GetBool("compress")
+
+			var backup backups.Backup
+			body := backups.CreateInput{Volume: args[0], Compress: compress}
+			done, err := callOperation(cmd, http.MethodPost, "/backups", body, &backup)
+			if err != nil {
+				return fmt.Errorf("failed to back up volume: %w", err)
+			}
+			if !done {
+				return nil
+			}
+
+			fmt.Printf("Created backup %s (%s, sha256 %s)\n", backup.ID, formatSize(backup.Size), backup.SHA256[:12])
+			return nil
+		},
+	}
+
+	backupListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List backups, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			volume, _ := cmd.Flags().GetString("volume")
+
+			var list []backups.Backup
+			if err := callServer(cmd, http.MethodGet, "/backups?volume="+url.QueryEscape(volume), nil, &list); err != nil {
+				return fmt.Errorf("failed to list backups: %w", err)
+			}
+
+			if len(list) == 0 {
+				fmt.Println("No backups found")
+				return nil
+			}
+
+			fmt.Printf("%-40s %-20s %-15s %-10s %-20s\n", "ID", "VOLUME", "PLAN", "SIZE", "CREATED")
+			for _, backup := range list {
+				plan := backup.Plan
+				if plan == "" {
+					plan = "-"
+				}
+				fmt.Printf("%-40s %-20s %-15s %-10s %-20s\n",
+					backup.ID, backup.Volume, plan, formatSize(backup.Size), backup.Created.Local().Format("2006-01-02 15:04:05"))
+			}
+			return nil
+		},
+	}
+
+	backupRestoreCmd = &cobra.Command{
+		Use:   "restore ID",
+		Short: "Restore a backup into a new or existing volume",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			volume, _ := cmd.Flags().GetString("volume")
+			overwrite, _ := cmd.Flags().GetBool("overwrite")
+			project, _ := cmd.Flags().GetString("project")
+			if !cmd.Flags().Changed("project") {
+				target, err := currentTarget(cmd)
+				if err != nil {
+					return err
+				}
+				project = target.Project
+			}
+
+			var backup backups.Backup
+			body := backups.RestoreInput{Volume: volume, Overwrite: overwrite, Project: project}
+			done, err := callOperation(cmd, http.MethodPost, "/backups/"+args[0]+"/restore", body, &backup)
+			if err != nil {
+				return fmt.Errorf("failed to restore backup: %w", err)
+			}
+			if !done {
+				return nil
+			}
+
+			if volume == "" {
+				volume = backup.Volume
+			}
+			fmt.Printf("Restored backup %s into volume %s\n", backup.ID, volume)
+			return nil
+		},
+	}
+
+	backupVerifyCmd = &cobra.Command{
+		Use:   "verify ID",
+		Short: "Check a backup against its checksum",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var backup backups.Backup
+			done, err := callOperation(cmd, http.MethodPost, "/backups/"+args[0]+"/verify", nil, &backup)
+			if err != nil {
+				return fmt.Errorf("verification failed: %w", err)
+			}
+			if !done {
+				return nil
+			}
+
+			fmt.Printf("Backup %s is intact (sha256 %s)\n", backup.ID, backup.SHA256)
+			return nil
+		},
+	}
+
+	backupPruneCmd = &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old backups of a volume or plan",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			volume, _ := cmd.Flags().GetString("volume")
+			plan, _ := cmd.Flags().GetString("plan")
+			keep, _ := cmd.Flags().GetInt("keep")
+			olderThan, _ := cmd.Flags().GetDuration("older-than")
+
+			if olderThan > 0 && olderThan < time.Hour {
+				return fmt.Errorf("--older-than must be at least 1h")
+			}
+			body := backups.PruneInput{Volume: volume, Plan: plan, Keep: keep, OlderThanHours: int(olderThan.Hours())}
+
+			var removed []backups.Backup
+			if err := callServer(cmd, http.MethodPost, "/backups/prune", body, &removed); err != nil {
+				return fmt.Errorf("failed to prune backups: %w", err)
+			}
+
+			for _, backup := range removed {
+				fmt.Printf("Deleted backup %s\n", backup.ID)
+			}
+			fmt.Printf("Pruned %d backups\n", len(removed))
+			return nil
+		},
+	}
+
+	backupDeleteCmd = &cobra.Command{
+		Use:   "delete ID",
+		Short: "Delete a backup",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/backups/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete backup: %w", err)
+			}
+
+			fmt.Printf("Deleted backup %s\n", args[0])
+			return nil
+		},
+	}
+
+	backupPlanCmd = &cobra.Command{
+		Use:   "plan",
+		Short: "Manage scheduled backup plans",
+	}
+
+	backupPlanCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Back up a volume on a cron schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			volume, _ := cmd.Flags().GetString("volume")
+			schedule, _ := cmd.Flags().GetString("schedule")
+			compress, _ := cmd.Flags().GetBool("compress")
+			retention, _ := cmd.Flags().GetInt("retention")
+
+			plan := backups.Plan{Name: args[0], Volume: volume, Schedule: schedule, Compress: compress, Retention: retention}
+			var status backups.PlanStatus
+			if err := callServer(cmd, http.MethodPost, "/backup-plans", plan, &status); err != nil {
+				return fmt.Errorf("failed to create backup plan: %w", err)
+			}
+
+			fmt.Printf("Created backup plan %s for volume %s, keeping %d backups\n", status.Name, status.Volume, status.Retention)
+			if status.NextRun != nil {
+				fmt.Printf("Next run: %s\n", status.NextRun.Local().Format("2006-01-02 15:04:05"))
+			}
+			return nil
+		},
+	}
+
+	backupPlanListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List backup plans",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var plans []backups.PlanStatus
+			if err := callServer(cmd, http.MethodGet, "/backup-plans", nil, &plans); err != nil {
+				return fmt.Errorf("failed to list backup plans: %w", err)
+			}
+
+			if len(plans) == 0 {
+				fmt.Println("No backup plans found")
+				return nil
+			}
+
+			fmt.Printf("%-15s %-20s %-15s %-6s %-20s %s\n", "NAME", "VOLUME", "SCHEDULE", "KEEP", "NEXT RUN", "LAST RESULT")
+			for _, plan := range plans {
+				next := "-"
+				if plan.NextRun != nil {
+					next = plan.NextRun.Local().Format("2006-01-02 15:04:05")
+				}
+				last := "-"
+				switch {
+				case plan.LastError != "":
+					last = "failed: " + plan.LastError
+				case plan.LastBackup != "":
+					last = plan.LastBackup
+				}
+				fmt.Printf("%-15s %-20s %-15s %-6d %-20s %s\n", plan.Name, plan.Volume, plan.Schedule, plan.Retention, next, last)
+			}
+			return nil
+		},
+	}
+
+	backupPlanRunCmd = &cobra.Command{
+		Use:   "run NAME",
+		Short: "Run a backup plan now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var backup backups.Backup
+			done, err := callOperation(cmd, http.MethodPost, "/backup-plans/"+args[0]+"/run", nil, &backup)
+			if err != nil {
+				return fmt.Errorf("failed to run backup plan: %w", err)
+			}
+			if !done {
+				return nil
+			}
+
+			fmt.Printf("Created backup %s (%s)\n", backup.ID, formatSize(backup.Size))
+			return nil
+		},
+	}
+
+	backupPlanDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a backup plan, keeping its backups",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/backup-plans/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete backup plan: %w", err)
+			}
+
+			fmt.Printf("Deleted backup plan %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{backupCreateCmd, backupRestoreCmd, backupVerifyCmd, backupPlanRunCmd} {
+		addDetachFlag(cmd)
+	}
+	backupCreateCmd.Flags().Bool("compress", false, "Gzip the archive")
+
+	backupListCmd.Flags().String("volume", "", "Only backups of this volume")
+
+	backupRestoreCmd.Flags().String("volume", "", "Volume to restore into (the backed up volume if empty)")
+	backupRestoreCmd.Flags().Bool("overwrite", false, "Replace the contents of an existing volume")
+	backupRestoreCmd.Flags().String("project", "", "Project a new volume counts against")
+
+	backupPruneCmd.Flags().String("volume", "", "Prune backups of this volume")
+	backupPruneCmd.Flags().String("plan", "", "Prune backups made by this plan")
+	backupPruneCmd.Flags().Int("keep", 0, "Number of newest backups to keep")
+	backupPruneCmd.Flags().Duration("older-than", 0, "Delete backups older than this (e.g. 720h)")
+
+	backupPlanCreateCmd.Flags().String("volume", "", "Volume to back up")
+	backupPlanCreateCmd.Flags().String("schedule", "@daily", "Cron expression")
+	backupPlanCreateCmd.Flags().Bool("compress", true, "Gzip the archives")
+	backupPlanCreateCmd.Flags().Int("retention", 7, "Backups from this plan to keep")
+	backupPlanCreateCmd.MarkFlagRequired("volume")
+
+	backupPlanCmd.AddCommand(backupPlanCreateCmd, backupPlanListCmd, backupPlanRunCmd, backupPlanDeleteCmd)
+	backupCmd.AddCommand(backupCreateCmd, backupListCmd, backupRestoreCmd, backupVerifyCmd, backupPruneCmd, backupDeleteCmd, backupPlanCmd)
+	rootCmd.AddCommand(backupCmd)
+}
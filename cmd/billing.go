@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"localcloud/internal/billing"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	billingCmd = &cobra.Command{
+		Use:   "billing",
+		Short: "Simulated costs, prices and budgets",
+	}
+
+	billingReportCmd = &cobra.Command{
+		Use:   "report",
+		Short: "Show charges grouped by project, tag, resource or day",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			by, _ := cmd.Flags().GetString("by")
+			tagKey, _ := cmd.Flags().GetString("tag")
+			from, _ := cmd.Flags().GetString("from")
+			to, _ := cmd.Flags().GetString("to")
+
+			query := url.Values{"by": {by}, "tag_key": {tagKey}, "from": {from}, "to": {to}}
+			var report billing.Report
+			if err := callServer(cmd, http.MethodGet, "/billing/report?"+query.Encode(), nil, &report); err != nil {
+				return fmt.Errorf("failed to get report: %w", err)
+			}
+
+			fmt.Printf("Charges from %s to %s by %s\n\n", report.From, report.To, report.By)
+			fmt.Printf("%-35s %-12s %-12s %-12s %-12s %-12s %s\n", strings.ToUpper(report.By), "INST HOURS", "VCPU HOURS", "GB HOURS", "STORAGE GBH", "NETWORK GB", "COST")
+			for _, group := range report.Groups {
+				fmt.Printf("%-35s %-12.2f %-12.2f %-12.2f %-12.2f %-12.3f %.4f %s\n", group.Key,
+					group.InstanceHours, group.VCPUHours, group.GBHours, group.StorageGBHours, group.NetworkGB, group.Cost, report.Currency)
+			}
+			fmt.Printf("\nTotal: %.4f %s\n", report.Total.Cost, report.Currency)
+			return nil
+		},
+	}
+
+	billingPricesCmd = &cobra.Command{
+		Use:   "prices",
+		Short: "Show the price sheet, or change it with flags",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var prices billing.PriceSheet
+			if err := callServer(cmd, http.MethodGet, "/billing/prices", nil, &prices); err != nil {
+				return fmt.Errorf("failed to get prices: %w", err)
+			}
+
+			changed := false
+			for flag, field := range map[string]*float64{
+				"instance-hour":    &prices.InstanceHour,
+				"vcpu-hour":        &prices.VCPUHour,
+				"gb-hour":          &prices.GBHour,
+				"storage-gb-month": &prices.StorageGBMonth,
+				"network-gb":       &prices.NetworkGB,
+			} {
+				if cmd.Flags().Changed(flag) {
+					*field, _ = cmd.Flags().GetFloat64(flag)
+					changed = true
+				}
+			}
+			if cmd.Flags().Changed("currency") {
+				prices.Currency, _ = cmd.Flags().GetString("currency")
+				changed = true
+			}
+			if changed {
+				if err := callServer(cmd, http.MethodPut, "/billing/prices", prices, &prices); err != nil {
+					return fmt.Errorf("failed to set prices: %w", err)
+				}
+			}
+
+			fmt.Printf("Currency:             %s\n", prices.Currency)
+			fmt.Printf("Per instance hour:    %g\n", prices.InstanceHour)
+			fmt.Printf("Per vCPU hour:        %g\n", prices.VCPUHour)
+			fmt.Printf("Per GB memory hour:   %g\n", prices.GBHour)
+			fmt.Printf("Per GB storage month: %g\n", prices.StorageGBMonth)
+			fmt.Printf("Per GB network:       %g\n", prices.NetworkGB)
+			return nil
+		},
+	}
+
+	budgetCmd = &cobra.Command{
+		Use:   "budget",
+		Short: "Manage monthly budgets",
+	}
+
+	budgetCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a monthly budget that alerts at thresholds",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			amount, _ := cmd.Flags().GetFloat64("amount")
+			project, _ := cmd.Flags().GetString("project")
+			tag, _ := cmd.Flags().GetString("tag")
+			thresholdList, _ := cmd.Flags().GetString("thresholds")
+
+			budget := billing.Budget{Name: args[0], Amount: amount, Project: project, Tag: tag}
+			for _, value := range strings.Split(thresholdList, ",") {
+				if value = strings.TrimSpace(value); value == "" {
+					continue
+				}
+				threshold, err := strconv.Atoi(value)
+				if err != nil {
+					return fmt.Errorf("invalid threshold %q", value)
+				}
+				budget.Thresholds = append(budget.Thresholds, threshold)
+			}
+
+			if err := callServer(cmd, http.MethodPost, "/billing/budgets", budget, &budget); err != nil {
+				return fmt.Errorf("failed to create budget: %w", err)
+			}
+			fmt.Printf("Created budget %s (%.2f a month, alerts at %v%%)\n", budget.Name, budget.Amount, budget.Thresholds)
+			return nil
+		},
+	}
+
+	budgetListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List budgets with this month's spend",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var budgets []billing.BudgetStatus
+			if err := callServer(cmd, http.MethodGet, "/billing/budgets", nil, &budgets); err != nil {
+				return fmt.Errorf("failed to list budgets: %w", err)
+			}
+			if len(budgets) == 0 {
+				fmt.Println("No budgets found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-30s %-12s %-12s %-8s %s\n", "NAME", "SCOPE", "AMOUNT", "SPENT", "USED", "FORECAST")
+			for _, budget := range budgets {
+				scope := budget.Project
+				if scope == "" {
+					scope = "all projects"
+				}
+				if budget.Tag != "" {
+					scope += ", " + budget.Tag
+				}
+				fmt.Printf("%-20s %-30s %-12.2f %-12.4f %-8s %.2f\n", budget.Name, scope, budget.Amount, budget.Spent,
+					fmt.Sprintf("%.0f%%", budget.Percent), budget.Forecast)
+			}
+			return nil
+		},
+	}
+
+	budgetDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a budget",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/billing/budgets/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete budget: %w", err)
+			}
+			fmt.Printf("Deleted budget %s\n", args[0])
+			return nil
+		},
+	}
+
+	billingAlertsCmd = &cobra.Command{
+		Use:   "alerts",
+		Short: "List budget alerts, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var alerts []billing.Alert
+			if err := callServer(cmd, http.MethodGet, "/billing/alerts", nil, &alerts); err != nil {
+				return fmt.Errorf("failed to list alerts: %w", err)
+			}
+			if len(alerts) == 0 {
+				fmt.Println("No alerts")
+				return nil
+			}
+
+			for _, alert := range alerts {
+				fmt.Printf("%s  %s reached %d%% (%.2f of %.2f)\n", alert.Time.Local().Format("2006-01-02 15:04:05"),
+					alert.Budget, alert.Threshold, alert.Spent, alert.Amount)
+			}
+			return nil
+		},
+	}
+)
+
+func init() {
+	billingReportCmd.Flags().String("by", "project", "Group by project, tag, resource or day")
+	billingReportCmd.Flags().String("tag", "", "With --by tag, group by this tag key's value")
+	billingReportCmd.Flags().String("from", "", "First day, YYYY-MM-DD (default start of this month)")
+	billingReportCmd.Flags().String("to", "", "Last day, YYYY-MM-DD (default today)")
+
+	billingPricesCmd.Flags().String("currency", "", "Currency code")
+	billingPricesCmd.Flags().Float64("instance-hour", 0, "Price per running instance hour")
+	billingPricesCmd.Flags().Float64("vcpu-hour", 0, "Price per vCPU hour")
+	billingPricesCmd.Flags().Float64("gb-hour", 0, "Price per GB of memory per hour")
+	billingPricesCmd.Flags().Float64("storage-gb-month", 0, "Price per GB of volume storage per month")
+	billingPricesCmd.Flags().Float64("network-gb", 0, "Price per GB of network traffic")
+
+	budgetCreateCmd.Flags().Float64("amount", 0, "Monthly amount")
+	budgetCreateCmd.Flags().String("project", "", "Only count this project")
+	budgetCreateCmd.Flags().String("tag", "", "Only count resources with this tag (key=value)")
+	budgetCreateCmd.Flags().String("thresholds", "50,80,100", "Percentages of the amount to alert at")
+	budgetCreateCmd.MarkFlagRequired("amount")
+
+	budgetCmd.AddCommand(budgetCreateCmd, budgetListCmd, budgetDeleteCmd)
+	billingCmd.AddCommand(billingReportCmd, billingPricesCmd, budgetCmd, billingAlertsCmd)
+	rootCmd.AddCommand(billingCmd)
+}
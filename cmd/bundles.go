@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"localcloud/internal/bundles"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	exportCmd = &cobra.Command{
+		Use:   "export",
+		Short: "Export instances, networks, volumes and images to a bundle",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			output, _ := cmd.Flags().GetString("output")
+			instances, _ := cmd.Flags().GetStringArray("instance")
+			volumes, _ := cmd.Flags().GetBool("volumes")
+			images, _ := cmd.Flags().GetBool("images")
+
+			if output == "" {
+				output = "localcloud-bundle-" + time.Now().Format("20060102-150405") + ".tar.gz"
+			}
+			file, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create %s: %w", output, err)
+			}
+			defer file.Close()
+
+			opts := bundles.Options{Instances: instances, IncludeVolumes: volumes, IncludeImages: images}
+			if err := downloadFromServer(cmd, http.MethodPost, "/bundles/export", opts, file); err != nil {
+				file.Close()
+				os.Remove(output)
+				return fmt.Errorf("failed to export: %w", err)
+			}
+
+			stat, err := file.Stat()
+			if err != nil {
+				return fmt.Errorf("failed to export: %w", err)
+			}
+			fmt.Printf("Exported bundle to %s (%s)\n", output, formatSize(stat.Size()))
+			return nil
+		},
+	}
+
+	importCmd = &cobra.Command{
+		Use:   "import FILE",
+		Short: "Recreate an environment from a bundle",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			onConflict, _ := cmd.Flags().GetString("on-conflict")
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open bundle: %w", err)
+			}
+			defer file.Close()
+
+			query := url.Values{}
+			query.Set("dry_run", strconv.FormatBool(dryRun))
+			query.Set("on_conflict", onConflict)
+
+			var report bundles.Report
+			err = streamToServer(cmd, http.MethodPost, "/bundles/import?"+query.Encode(), "application/gzip", file, &report)
+			if report.Actions != nil {
+				printImportReport(report)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to import: %w", err)
+			}
+			if len(report.Errors) > 0 {
+				return fmt.Errorf("import finished with %d errors", len(report.Errors))
+			}
+			return nil
+		},
+	}
+)
+
+func printImportReport(report bundles.Report) {
+	if report.DryRun {
+		fmt.Printf("Dry run: bundle from %s, exported %s\n\n", report.Source, report.Created.Local().Format("2006-01-02 15:04:05"))
+	}
+
+	fmt.Printf("%-10s %-25s %-8s %-25s %s\n", "KIND", "NAME", "ACTION", "TARGET", "NOTE")
+	for _, action := range report.Actions {
+		target := action.Target
+		if target == "" {
+			target = "-"
+		}
+		fmt.Printf("%-10s %-25s %-8s %-25s %s\n", action.Kind, action.Name, action.Action, target, action.Note)
+	}
+	for _, message := range report.Errors {
+		fmt.Printf("Error: %s\n", message)
+	}
+}
+
+func init() {
+	exportCmd.Flags().StringP("output", "o", "", "Bundle file (localcloud-bundle-<time>.tar.gz if empty)")
+	exportCmd.Flags().StringArray("instance", nil, "Only export this instance (repeatable)")
+	exportCmd.Flags().Bool("volumes", false, "Include the contents of named volumes")
+	exportCmd.Flags().Bool("images", false, "Include the images instances run, for machines without registry access")
+
+	importCmd.Flags().Bool("dry-run", false, "Show what would be created without changing anything")
+	importCmd.Flags().String("on-conflict", bundles.ConflictSkip, "When a name is taken: skip, rename or replace")
+
+	rootCmd.AddCommand(exportCmd, importCmd)
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"localcloud/internal/store"
+
+	"github.com/spf13/cobra"
+)
+
+const defaultEndpoint = "http://localhost:8080"
+
+// CLI settings, kept apart from the server's data directory since the CLI
+// may run on another machine
+type cliConfig struct {
+	CurrentContext string                 `json:"current_context,omitempty"`
+	Contexts       map[string]*cliContext `json:"contexts"`
+}
+
+// A LocalCloud the CLI can talk to
+type cliContext struct {
+	Endpoint string `json:"endpoint,omitempty"`
+	Token    string `json:"token,omitempty"`   // the server's LOCALCLOUD_API_TOKEN
+	Project  string `json:"project,omitempty"` // default for --project
+	// Manage instances through the local Docker daemon instead of the API.
+	// Server-backed commands still use the endpoint.
+	Direct bool `json:"direct,omitempty"`
+}
+
+// Where commands send their requests, resolved from flags, environment and
+// the config file
+type cliTarget struct {
+	cliContext
+	Context string // name of the context used, if any
+	Source  string // what picked it, for `context show`
+}
+
+var resolvedTarget *cliTarget
+
+// ~/.localcloud/cli.json unless LOCALCLOUD_CLI_CONFIG is set
+func cliConfigPath() string {
+	if path := os.Getenv("LOCALCLOUD_CLI_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(".localcloud", "cli.json")
+	}
+	return filepath.Join(home, ".localcloud", "cli.json")
+}
+
+func loadCLIConfig() (*cliConfig, error) {
+	cfg := &cliConfig{Contexts: make(map[string]*cliContext)}
+	if err := store.Load(cliConfigPath(), cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Contexts == nil {
+		cfg.Contexts = make(map[string]*cliContext)
+	}
+	return cfg, nil
+}
+
+func (cfg *cliConfig) save() error {
+	return store.Save(cliConfigPath(), cfg)
+}
+
+// The first of: --endpoint, --context, LOCALCLOUD_ENDPOINT (or the older
+// LOCALCLOUD_SERVER), LOCALCLOUD_CONTEXT, the config file's current context,
+// and finally the API at http://localhost:8080. Direct mode is only ever
+// used when the chosen context asks for it.
+func currentTarget(cmd *cobra.Command) (*cliTarget, error) {
+	if resolvedTarget != nil {
+		return resolvedTarget, nil
+	}
+
+	endpointFlag := cmd.Flags().Lookup("endpoint")
+	serverFlag := cmd.Flags().Lookup("server")
+	contextName, _ := cmd.Flags().GetString("context")
+
+	var target *cliTarget
+	switch {
+	case endpointFlag != nil && endpointFlag.Changed:
+		target = endpointTarget(endpointFlag.Value.String(), "--endpoint")
+	case serverFlag != nil && serverFlag.Changed:
+		target = endpointTarget(serverFlag.Value.String(), "--server")
+	case contextName != "":
+		named, err := namedTarget(contextName, "--context")
+		if err != nil {
+			return nil, err
+		}
+		target = named
+	case os.Getenv("LOCALCLOUD_ENDPOINT") != "":
+		target = endpointTarget(os.Getenv("LOCALCLOUD_ENDPOINT"), "LOCALCLOUD_ENDPOINT")
+	case os.Getenv("LOCALCLOUD_SERVER") != "":
+		target = endpointTarget(os.Getenv("LOCALCLOUD_SERVER"), "LOCALCLOUD_SERVER")
+	case os.Getenv("LOCALCLOUD_CONTEXT") != "":
+		named, err := namedTarget(os.Getenv("LOCALCLOUD_CONTEXT"), "LOCALCLOUD_CONTEXT")
+		if err != nil {
+			return nil, err
+		}
+		target = named
+	default:
+		cfg, err := loadCLIConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load CLI config: %w", err)
+		}
+		if cfg.CurrentContext == "" {
+			target = endpointTarget(defaultEndpoint, "default")
+			break
+		}
+		named, err := namedTarget(cfg.CurrentContext, "current context")
+		if err != nil {
+			return nil, err
+		}
+		target = named
+	}
+
+	if target.Endpoint == "" {
+		target.Endpoint = defaultEndpoint
+	}
+	// The environment can supply credentials for any endpoint
+	if target.Token == "" {
+		target.Token = os.Getenv("LOCALCLOUD_TOKEN")
+	}
+	resolvedTarget = target
+	return target, nil
+}
+
+func endpointTarget(endpoint, source string) *cliTarget {
+	return &cliTarget{cliContext: cliContext{Endpoint: endpoint}, Source: source}
+}
+
+func namedTarget(name, source string) (*cliTarget, error) {
+	cfg, err := loadCLIConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load CLI config: %w", err)
+	}
+	ctx, ok := cfg.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("context %q not found in %s (see `localcloud context list`)", name, cliConfigPath())
+	}
+	return &cliTarget{cliContext: *ctx, Context: name, Source: source}, nil
+}
+
+var (
+	contextCmd = &cobra.Command{
+		Use:   "context",
+		Short: "Manage the LocalCloud servers the CLI talks to",
+		Long: `A context names a LocalCloud server: its endpoint, API token and default
+project. Commands use the current context unless --context or --endpoint
+says otherwise. A context created with --direct manages instances through
+the local Docker daemon instead.`,
+	}
+
+	contextListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List contexts",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCLIConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load CLI config: %w", err)
+			}
+			if len(cfg.Contexts) == 0 {
+				fmt.Printf("No contexts; commands use %s\n", defaultEndpoint)
+				return nil
+			}
+
+			names := make([]string, 0, len(cfg.Contexts))
+			for name := range cfg.Contexts {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			fmt.Printf("  %-16s %-32s %-16s %-7s %s\n", "NAME", "ENDPOINT", "PROJECT", "MODE", "TOKEN")
+			for _, name := range names {
+				ctx := cfg.Contexts[name]
+				current := " "
+				if name == cfg.CurrentContext {
+					current = "*"
+				}
+				endpoint := ctx.Endpoint
+				if endpoint == "" {
+					endpoint = defaultEndpoint
+				}
+				mode, token := "api", "no"
+				if ctx.Direct {
+					mode = "direct"
+				}
+				if ctx.Token != "" {
+					token = "yes"
+				}
+				fmt.Printf("%s %-16s %-32s %-16s %-7s %s\n", current, name, endpoint, ctx.Project, mode, token)
+			}
+			return nil
+		},
+	}
+
+	contextSetCmd = &cobra.Command{
+		Use:   "set NAME",
+		Short: "Create or update a context",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCLIConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load CLI config: %w", err)
+			}
+
+			ctx, exists := cfg.Contexts[args[0]]
+			if !exists {
+				ctx = &cliContext{}
+				cfg.Contexts[args[0]] = ctx
+			}
+			// Only the flags given change an existing context
+			if cmd.Flags().Changed("endpoint") {
+				ctx.Endpoint, _ = cmd.Flags().GetString("endpoint")
+			}
+			if cmd.Flags().Changed("token") {
+				ctx.Token, _ = cmd.Flags().GetString("token")
+			}
+			if cmd.Flags().Changed("project") {
+				ctx.Project, _ = cmd.Flags().GetString("project")
+			}
+			if cmd.Flags().Changed("direct") {
+				ctx.Direct, _ = cmd.Flags().GetBool("direct")
+			}
+			use, _ := cmd.Flags().GetBool("use")
+			if use || len(cfg.Contexts) == 1 {
+				cfg.CurrentContext = args[0]
+			}
+
+			if err := cfg.save(); err != nil {
+				return fmt.Errorf("failed to save CLI config: %w", err)
+			}
+			verb := "Updated"
+			if !exists {
+				verb = "Created"
+			}
+			fmt.Printf("%s context %s\n", verb, args[0])
+			if cfg.CurrentContext == args[0] {
+				fmt.Printf("Using context %s\n", args[0])
+			}
+			return nil
+		},
+	}
+
+	contextUseCmd = &cobra.Command{
+		Use:   "use NAME",
+		Short: "Make a context the current one",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCLIConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load CLI config: %w", err)
+			}
+			if _, ok := cfg.Contexts[args[0]]; !ok {
+				return fmt.Errorf("context %q not found", args[0])
+			}
+			cfg.CurrentContext = args[0]
+			if err := cfg.save(); err != nil {
+				return fmt.Errorf("failed to save CLI config: %w", err)
+			}
+			fmt.Printf("Using context %s\n", args[0])
+			return nil
+		},
+	}
+
+	contextDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a context",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := loadCLIConfig()
+			if err != nil {
+				return fmt.Errorf("failed to load CLI config: %w", err)
+			}
+			if _, ok := cfg.Contexts[args[0]]; !ok {
+				return fmt.Errorf("context %q not found", args[0])
+			}
+			delete(cfg.Contexts, args[0])
+			if cfg.CurrentContext == args[0] {
+				cfg.CurrentContext = ""
+			}
+			if err := cfg.save(); err != nil {
+				return fmt.Errorf("failed to save CLI config: %w", err)
+			}
+			fmt.Printf("Deleted context %s\n", args[0])
+			return nil
+		},
+	}
+
+	contextShowCmd = &cobra.Command{
+		Use:   "show",
+		Short: "Show where commands will go and why",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			target, err := currentTarget(cmd)
+			if err != nil {
+				return err
+			}
+
+			if target.Context != "" {
+				fmt.Printf("Context:  %s (from %s)\n", target.Context, target.Source)
+			} else {
+				fmt.Printf("Context:  none (endpoint from %s)\n", target.Source)
+			}
+			if target.Direct {
+				fmt.Printf("Mode:     direct (instances through the local Docker daemon)\n")
+			} else {
+				fmt.Printf("Mode:     api\n")
+			}
+			fmt.Printf("Endpoint: %s\n", target.Endpoint)
+			if target.Token != "" {
+				fmt.Printf("Token:    set\n")
+			}
+			if target.Project != "" {
+				fmt.Printf("Project:  %s\n", target.Project)
+			}
+			fmt.Printf("Config:   %s\n", cliConfigPath())
+			return nil
+		},
+	}
+)
+
+func init() {
+	contextSetCmd.Flags().String("endpoint", "", "Server address, e.g. https://localcloud.example.com")
+	contextSetCmd.Flags().String("token", "", "API token the server was started with (LOCALCLOUD_API_TOKEN)")
+	contextSetCmd.Flags().String("project", "", "Project used when a command's --project is not given")
+	contextSetCmd.Flags().Bool("direct", false, "Manage instances through the local Docker daemon instead of the API")
+	contextSetCmd.Flags().Bool("use", false, "Make this the current context")
+
+	contextCmd.AddCommand(contextListCmd, contextSetCmd, contextUseCmd, contextDeleteCmd, contextShowCmd)
+	rootCmd.AddCommand(contextCmd)
+}
+
+// Whether instance commands go to the local Docker daemon rather than the API
+func directMode(cmd *cobra.Command) (bool, error) {
+	target, err := currentTarget(cmd)
+	if err != nil {
+		return false, err
+	}
+	return target.Direct, nil
+}
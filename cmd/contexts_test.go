@@ -0,0 +1,78 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// A command with the root's connection flags, set from args
+func targetCommand(t *testing.T, args ...string) *cobra.Command {
+	t.Helper()
+	cmd := &cobra.Command{}
+	cmd.Flags().String("endpoint", "", "")
+	cmd.Flags().String("context", "", "")
+	cmd.Flags().String("server", "", "")
+	if err := cmd.Flags().Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+func TestCurrentTarget(t *testing.T) {
+	t.Setenv("LOCALCLOUD_CLI_CONFIG", filepath.Join(t.TempDir(), "cli.json"))
+	for _, env := range []string{"LOCALCLOUD_ENDPOINT", "LOCALCLOUD_SERVER", "LOCALCLOUD_CONTEXT", "LOCALCLOUD_TOKEN"} {
+		t.Setenv(env, "")
+	}
+	cfg := &cliConfig{Contexts: map[string]*cliContext{
+		"prod":  {Endpoint: "https://prod.example.com", Token: "prod-token", Project: "web"},
+		"local": {Direct: true},
+	}}
+	if err := cfg.save(); err != nil {
+		t.Fatal(err)
+	}
+
+	resolve := func(env map[string]string, args ...string) *cliTarget {
+		t.Helper()
+		for key, value := range env {
+			t.Setenv(key, value)
+		}
+		resolvedTarget = nil
+		defer func() { resolvedTarget = nil }()
+		target, err := currentTarget(targetCommand(t, args...))
+		for key := range env {
+			t.Setenv(key, "")
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return target
+	}
+
+	if target := resolve(nil); target.Endpoint != defaultEndpoint || target.Source != "default" {
+		t.Errorf("without config: %+v", target)
+	}
+	if target := resolve(nil, "--context", "prod"); target.Endpoint != "https://prod.example.com" || target.Token != "prod-token" || target.Context != "prod" {
+		t.Errorf("--context: %+v", target)
+	}
+	// --endpoint wins over everything, taking its token from the environment
+	target := resolve(map[string]string{"LOCALCLOUD_CONTEXT": "prod", "LOCALCLOUD_TOKEN": "env-token"}, "--endpoint", "http://other:8080", "--context", "prod")
+	if target.Endpoint != "http://other:8080" || target.Token != "env-token" || target.Source != "--endpoint" {
+		t.Errorf("--endpoint: %+v", target)
+	}
+	if target := resolve(map[string]string{"LOCALCLOUD_ENDPOINT": "http://env:8080", "LOCALCLOUD_CONTEXT": "prod"}); target.Endpoint != "http://env:8080" {
+		t.Errorf("LOCALCLOUD_ENDPOINT: %+v", target)
+	}
+
+	cfg.CurrentContext = "local"
+	cfg.save()
+	if target := resolve(nil); !target.Direct || target.Endpoint != defaultEndpoint || target.Source != "current context" {
+		t.Errorf("current context: %+v", target)
+	}
+
+	resolvedTarget = nil
+	if _, err := currentTarget(targetCommand(t, "--context", "staging")); err == nil {
+		t.Error("resolved a missing context")
+	}
+}
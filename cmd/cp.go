@@ -0,0 +1,323 @@
+package main
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"localcloud/internal/compute"
+
+	"github.com/spf13/cobra"
+)
+
+var cpCmd = &cobra.Command{
+	Use:   "cp SRC DST",
+	Short: "Copy files between the local machine and a container",
+	Long: `Copy a file or directory into or out of a container. One side is a
+local path, the other is CONTAINER:PATH with an absolute path, e.g.
+
+  localcloud cp ./site web:/usr/share/nginx/html
+  localcloud cp web:/etc/nginx/nginx.conf .`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		srcID, srcPath := splitContainerPath(args[0])
+		dstID, dstPath := splitContainerPath(args[1])
+		if (srcID == "") == (dstID == "") {
+			return fmt.Errorf("exactly one of SRC and DST must be CONTAINER:PATH")
+		}
+
+		direct, err := directMode(cmd)
+		if err != nil {
+			return err
+		}
+		var manager *compute.Manager
+		if direct {
+			if manager, err = compute.NewManager(); err != nil {
+				return fmt.Errorf("failed to initialize compute manager: %w", err)
+			}
+		}
+
+		if dstID != "" {
+			return copyToContainer(cmd, manager, srcPath, dstID, dstPath)
+		}
+		return copyFromContainer(cmd, manager, srcID, srcPath, dstPath)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cpCmd)
+}
+
+// Split CONTAINER:PATH; local paths (including ./a:b and /a:b) return no ID
+func splitContainerPath(arg string) (string, string) {
+	if strings.HasPrefix(arg, ".") || filepath.IsAbs(arg) {
+		return "", arg
+	}
+	id, p, ok := strings.Cut(arg, ":")
+	if !ok || id == "" {
+		return "", arg
+	}
+	return id, p
+}
+
+// Without a manager the copy goes through the server's files endpoint
+func copyToContainer(cmd *cobra.Command, manager *compute.Manager, srcPath, containerID, dstPath string) error {
+	if _, err := os.Lstat(srcPath); err != nil {
+		return fmt.Errorf("failed to read %s: %w", srcPath, err)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(writeTar(pw, srcPath))
+	}()
+
+	var err error
+	if manager != nil {
+		err = manager.CopyTo(containerID, dstPath, pr)
+	} else {
+		query := url.Values{"path": {dstPath}}
+		err = streamToServer(cmd, http.MethodPut, "/containers/"+url.PathEscape(containerID)+"/files?"+query.Encode(), "application/x-tar", pr, nil)
+	}
+	pr.Close()
+	if err != nil {
+		return fmt.Errorf("failed to copy %s: %w", srcPath, err)
+	}
+	fmt.Printf("Copied %s to %s:%s\n", srcPath, containerID, dstPath)
+	return nil
+}
+
+// Tar a local file or directory, rooted at its base name, keeping modes
+func writeTar(w io.Writer, srcPath string) error {
+	tw := tar.NewWriter(w)
+	root := filepath.Clean(srcPath)
+	base := filepath.Base(root)
+
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(base, rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+func copyFromContainer(cmd *cobra.Command, manager *compute.Manager, containerID, srcPath, dstPath string) error {
+	var reader io.ReadCloser
+	var root string
+	var err error
+	if manager != nil {
+		var info *compute.FileInfo
+		reader, info, err = manager.CopyFrom(containerID, srcPath)
+		if err == nil {
+			root = info.Name
+		}
+	} else {
+		query := url.Values{"path": {srcPath}, "format": {"tar"}}
+		reader, err = openFromServer(cmd, http.MethodGet, "/containers/"+url.PathEscape(containerID)+"/files?"+query.Encode(), nil)
+		root = path.Base(path.Clean(srcPath))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to copy %s:%s: %w", containerID, srcPath, err)
+	}
+	defer reader.Close()
+
+	// like docker cp: into dstPath if it is a directory, otherwise as dstPath
+	dir, name := dstPath, root
+	if stat, err := os.Stat(dstPath); err != nil || !stat.IsDir() {
+		dir, name = filepath.Dir(dstPath), filepath.Base(dstPath)
+	}
+
+	if err := extractTar(reader, dir, root, name); err != nil {
+		return fmt.Errorf("failed to extract %s: %w", srcPath, err)
+	}
+	fmt.Printf("Copied %s:%s to %s\n", containerID, srcPath, filepath.Join(dir, name))
+	return nil
+}
+
+// Extract a tar into dir, renaming its root entry from root to name.
+// Entries may not escape dir, whether by their path, by a symlink's target
+// or by being written through a symlink made by an earlier entry.
+func extractTar(r io.Reader, dir, root, name string) error {
+	// absolute, so that entries of "." still look like they are inside it
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	var links []string
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		rel := strings.TrimSuffix(header.Name, "/")
+		if rel == root {
+			rel = name
+		} else if strings.HasPrefix(rel, root+"/") {
+			rel = name + strings.TrimPrefix(rel, root)
+		}
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if !within(dir, target) {
+			return fmt.Errorf("archive entry %s escapes the destination", header.Name)
+		}
+		if err := checkNoSymlinkParents(dir, target); err != nil {
+			return fmt.Errorf("archive entry %s: %w", header.Name, err)
+		}
+
+		mode := os.FileMode(header.Mode).Perm()
+		switch header.Typeflag {
+		case tar.TypeDir, tar.TypeReg:
+			// replace rather than follow a symlink already at target
+			if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				if err := os.Remove(target); err != nil {
+					return err
+				}
+			}
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				return err
+			}
+			if err := os.Chmod(target, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// common in images (e.g. /etc/localtime), but here they would
+			// point into this machine's filesystem
+			if filepath.IsAbs(header.Linkname) {
+				fmt.Fprintf(os.Stderr, "Skipping %s: absolute symlink to %s\n", header.Name, header.Linkname)
+				continue
+			}
+			if !within(dir, filepath.Join(filepath.Dir(target), header.Linkname)) {
+				return fmt.Errorf("archive entry %s links outside the destination (%s)", header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Remove(target); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return err
+			}
+			links = append(links, target)
+			continue
+		default:
+			// devices, fifos and hard links are skipped
+			continue
+		}
+		os.Chtimes(target, header.ModTime, header.ModTime)
+	}
+
+	// A link can still resolve outside through other links, e.g. b -> . and
+	// c -> b/.., so check where each one really ends up
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		resolved, err := filepath.EvalSymlinks(link)
+		if err != nil {
+			continue // dangling, nothing to follow
+		}
+		if !within(realDir, resolved) {
+			os.Remove(link)
+			return fmt.Errorf("symlink %s resolves outside the destination", link)
+		}
+	}
+	return nil
+}
+
+// Whether p is dir or inside it; both must be clean
+func within(dir, p string) bool {
+	return p == dir || strings.HasPrefix(p, dir+string(os.PathSeparator))
+}
+
+// Fail if a directory between dir and target is a symlink, which an entry
+// would otherwise be written through
+func checkNoSymlinkParents(dir, target string) error {
+	rel, err := filepath.Rel(dir, filepath.Dir(target))
+	if err != nil || rel == "." {
+		return err
+	}
+	p := dir
+	for _, part := range strings.Split(rel, string(os.PathSeparator)) {
+		p = filepath.Join(p, part)
+		info, err := os.Lstat(p)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", p)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// One tar entry: a directory if name ends in /, a symlink if link is set,
+// otherwise a file holding body
+type entry struct {
+	name, link, body string
+}
+
+func tarOf(t *testing.T, entries ...entry) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0o644}
+		switch {
+		case strings.HasSuffix(e.name, "/"):
+			header.Typeflag, header.Mode = tar.TypeDir, 0o755
+		case e.link != "":
+			header.Typeflag, header.Linkname = tar.TypeSymlink, e.link
+		default:
+			header.Typeflag, header.Size = tar.TypeReg, int64(len(e.body))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestExtractTar(t *testing.T) {
+	dir := t.TempDir()
+	archive := tarOf(t,
+		entry{name: "app/"},
+		entry{name: "app/conf/"},
+		entry{name: "app/conf/app.yaml", body: "port: 80"},
+		entry{name: "app/current", link: "conf/app.yaml"},
+		entry{name: "app/up", link: "../copy"},
+	)
+	// app in the archive is written as copy
+	if err := extractTar(archive, dir, "app", "copy"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "copy", "current"))
+	if err != nil || string(data) != "port: 80" {
+		t.Fatalf("copy/current = %q, %v", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "app")); !os.IsNotExist(err) {
+		t.Errorf("entries were written under their archive name: %v", err)
+	}
+}
+
+func TestExtractTarRelativeDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	if err := extractTar(tarOf(t, entry{name: "a.txt", body: "a"}), ".", "a.txt", "b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "b.txt")); err != nil || string(data) != "a" {
+		t.Fatalf("b.txt = %q, %v", data, err)
+	}
+}
+
+func TestExtractTarRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []entry
+		err     string
+		outside string // must not exist outside the destination afterwards
+	}{
+		{"parent path", []entry{{name: "app/../../evil", body: "x"}}, "escapes the destination", "evil"},
+		{"relative link", []entry{{name: "app/up", link: "../../"}}, "links outside the destination", ""},
+		{"write through link", []entry{
+			{name: "app/sub/"},
+			{name: "app/s", link: "sub"},
+			{name: "app/s/f", body: "x"},
+		}, "is a symlink", ""},
+		{"chained links", []entry{
+			{name: "app/b", link: "."},
+			{name: "app/c", link: "b/../.."},
+		}, "resolves outside the destination", ""},
+	}
+	for _, tt := range tests {
+		parent := t.TempDir()
+		dir := filepath.Join(parent, "dest")
+		if err := os.Mkdir(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		err := extractTar(tarOf(t, tt.entries...), dir, "app", "app")
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+		if tt.outside != "" {
+			if _, err := os.Lstat(filepath.Join(parent, tt.outside)); err == nil {
+				t.Errorf("%s: %s was written outside the destination", tt.name, tt.outside)
+			}
+		}
+	}
+}
+
+func TestExtractTarSkipsAbsoluteLinks(t *testing.T) {
+	dir := t.TempDir()
+	err := extractTar(tarOf(t,
+		entry{name: "etc/localtime", link: "/usr/share/zoneinfo/UTC"},
+		entry{name: "etc/hostname", body: "web"},
+	), dir, "etc", "etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "etc", "localtime")); !os.IsNotExist(err) {
+		t.Errorf("absolute link was written: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "etc", "hostname")); string(data) != "web" {
+		t.Errorf("hostname = %q", data)
+	}
+}
+
+func TestExtractTarReplacesLinks(t *testing.T) {
+	dir := t.TempDir()
+	outside := filepath.Join(t.TempDir(), "target")
+	if err := os.WriteFile(outside, []byte("keep"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// a link left behind by an earlier copy
+	if err := os.MkdirAll(filepath.Join(dir, "app"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "app", "f")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := extractTar(tarOf(t, entry{name: "app/f", body: "new"}), dir, "app", "app"); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(outside); string(data) != "keep" {
+		t.Errorf("file outside the destination was overwritten with %q", data)
+	}
+	info, err := os.Lstat(filepath.Join(dir, "app", "f"))
+	if err != nil || !info.Mode().IsRegular() {
+		t.Errorf("app/f was not replaced with a regular file: %v", err)
+	}
+}
+
+func TestWriteTarRoundTrip(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "site")
+	if err := os.MkdirAll(filepath.Join(src, "css"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "css", "main.css"), []byte("body {}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("css/main.css", filepath.Join(src, "style.css")); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := writeTar(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := extractTar(&buf, dir, "site", "copy"); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "copy", "css", "main.css"))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Fatalf("main.css = %v, %v", info, err)
+	}
+	if link, err := os.Readlink(filepath.Join(dir, "copy", "style.css")); err != nil || link != "css/main.css" {
+		t.Errorf("style.css links to %q, %v", link, err)
+	}
+}
+
+func TestSplitContainerPath(t *testing.T) {
+	tests := []struct{ arg, id, path string }{
+		{"web:/etc/nginx", "web", "/etc/nginx"},
+		{"./web:/etc", "", "./web:/etc"},
+		{"/tmp/a:b", "", "/tmp/a:b"},
+		{"notes.txt", "", "notes.txt"},
+		{":/etc", "", ":/etc"},
+	}
+	for _, tt := range tests {
+		if id, p := splitContainerPath(tt.arg); id != tt.id || p != tt.path {
+			t.Errorf("splitContainerPath(%q) = %q, %q", tt.arg, id, p)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+
+	"localcloud/internal/databases"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	dbCmd = &cobra.Command{
+		Use:   "db",
+		Short: "Manage databases",
+	}
+
+	// Provision a database
+	dbCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Create a managed database",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			engine, _ := cmd.Flags().GetString("engine")
+			version, _ := cmd.Flags().GetString("version")
+			username, _ := cmd.Flags().GetString("user")
+			dbName, _ := cmd.Flags().GetString("db")
+			password, _ := cmd.Flags().GetString("password")
+			port, _ := cmd.Flags().GetInt("port")
+			memory, _ := cmd.Flags().GetInt("memory")
+			interval, _ := cmd.Flags().GetInt("backup-interval")
+			retention, _ := cmd.Flags().GetInt("retention")
+			wait, _ := cmd.Flags().GetBool("wait")
+
+			if name == "" {
+				return fmt.Errorf("--name is required")
+			}
+
+			spec := databases.Database{
+				Name:            name,
+				Engine:          engine,
+				Version:         version,
+				Username:        username,
+				DBName:          dbName,
+				Password:        password,
+				Port:            port,
+				MemoryMB:        memory,
+				BackupInterval:  interval,
+				BackupRetention: retention,
+			}
+			var db databases.Info
+			if err := callServer(cmd, http.MethodPost, "/databases", spec, &db); err != nil {
+				return fmt.Errorf("failed to create database: %w", err)
+			}
+			fmt.Printf("Creating %s database %s\n", db.Engine, db.Name)
+
+			if !wait {
+				fmt.Printf("Run `localcloud db list` to see when it is available\n")
+				return nil
+			}
+
+			// provisioning includes pulling the image, which can take a while
+			for db.Status == databases.StatusCreating {
+				time.Sleep(2 * time.Second)
+				if err := callServer(cmd, http.MethodGet, "/databases/"+name, nil, &db); err != nil {
+					return fmt.Errorf("failed to get database: %w", err)
+				}
+			}
+			if db.Status != databases.StatusAvailable {
+				return fmt.Errorf("database is %s: %s", db.Status, db.Error)
+			}
+
+			printConnection(db)
+			return nil
+		},
+	}
+
+	dbListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List databases",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var dbs []databases.Info
+			if err := callServer(cmd, http.MethodGet, "/databases", nil, &dbs); err != nil {
+				return fmt.Errorf("failed to list databases: %w", err)
+			}
+
+			if len(dbs) == 0 {
+				fmt.Println("No databases found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-15s %-12s %-22s %-20s\n", "NAME", "ENGINE", "STATUS", "ENDPOINT", "LAST BACKUP")
+			for _, db := range dbs {
+				lastBackup := "-"
+				if db.LastBackup != nil {
+					lastBackup = db.LastBackup.Format("2006-01-02 15:04:05")
+				}
+				fmt.Printf("%-20s %-15s %-12s %-22s %-20s\n",
+					db.Name, db.Engine+" "+db.Version, db.Status, db.Endpoint, lastBackup)
+			}
+			return nil
+		},
+	}
+
+	// Take a backup now
+	dbBackupCmd = &cobra.Command{
+		Use:   "backup NAME",
+		Short: "Back up a database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var backup databases.Backup
+			if err := callServer(cmd, http.MethodPost, "/databases/"+args[0]+"/backups", nil, &backup); err != nil {
+				return fmt.Errorf("failed to back up database: %w", err)
+			}
+
+			fmt.Printf("Created backup %s (%d bytes)\n", backup.ID, backup.Size)
+			return nil
+		},
+	}
+
+	dbBackupsCmd = &cobra.Command{
+		Use:   "backups NAME",
+		Short: "List backups of a database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var backups []databases.Backup
+			if err := callServer(cmd, http.MethodGet, "/databases/"+args[0]+"/backups", nil, &backups); err != nil {
+				return fmt.Errorf("failed to list backups: %w", err)
+			}
+
+			if len(backups) == 0 {
+				fmt.Println("No backups found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-20s %-12s %-10s\n", "ID", "CREATED", "SIZE", "TYPE")
+			for _, b := range backups {
+				kind := "manual"
+				if b.Automatic {
+					kind = "automatic"
+				}
+				fmt.Printf("%-20s %-20s %-12d %-10s\n", b.ID, b.Created.Format("2006-01-02 15:04:05"), b.Size, kind)
+			}
+			return nil
+		},
+	}
+
+	dbRestoreCmd = &cobra.Command{
+		Use:   "restore NAME",
+		Short: "Restore a database from a backup (the latest by default)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			backupID, _ := cmd.Flags().GetString("backup")
+
+			body := map[string]string{"backup_id": backupID}
+			var backup databases.Backup
+			if err := callServer(cmd, http.MethodPost, "/databases/"+args[0]+"/restore", body, &backup); err != nil {
+				return fmt.Errorf("failed to restore database: %w", err)
+			}
+
+			fmt.Printf("Restored %s from backup %s\n", args[0], backup.ID)
+			return nil
+		},
+	}
+
+	// Print connection details, or open the engine's shell
+	dbConnectCmd = &cobra.Command{
+		Use:   "connect NAME",
+		Short: "Show how to connect to a database",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell, _ := cmd.Flags().GetBool("shell")
+
+			var db databases.Info
+			if err := callServer(cmd, http.MethodGet, "/databases/"+args[0], nil, &db); err != nil {
+				return fmt.Errorf("failed to get database: %w", err)
+			}
+			if db.Status != databases.StatusAvailable {
+				return fmt.Errorf("database is %s", db.Status)
+			}
+
+			if !shell {
+				printConnection(db)
+				return nil
+			}
+
+			// the client ships in the engine image, so run it there
+			dockerArgs := append([]string{"exec", "-it", db.InstanceID}, db.ShellCommand...)
+			docker := exec.Command("docker", dockerArgs...)
+			docker.Stdin = os.Stdin
+			docker.Stdout = os.Stdout
+			docker.Stderr = os.Stderr
+			return docker.Run()
+		},
+	}
+
+	dbDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a database with its data and backups",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/databases/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete database: %w", err)
+			}
+
+			fmt.Printf("Deleted database %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	dbCreateCmd.Flags().String("name", "", "Database name")
+	dbCreateCmd.Flags().String("engine", "postgres", "Engine (postgres or mysql)")
+	dbCreateCmd.Flags().String("version", "", "Engine version (image tag)")
+	dbCreateCmd.Flags().String("user", "", "Username (default localcloud)")
+	dbCreateCmd.Flags().String("db", "", "Name of the database to create (default from --name)")
+	dbCreateCmd.Flags().String("password", "", "Password (generated if empty)")
+	dbCreateCmd.Flags().Int("port", 0, "Host port (any free port if 0)")
+	dbCreateCmd.Flags().Int("memory", 0, "Memory limit in MB")
+	dbCreateCmd.Flags().Int("backup-interval", 0, "Take automatic backups every N hours")
+	dbCreateCmd.Flags().Int("retention", 0, "Automatic backups to keep (default 7)")
+	dbCreateCmd.Flags().Bool("wait", false, "Wait until the database is available")
+
+	dbRestoreCmd.Flags().String("backup", "", "Backup ID (latest if empty)")
+	dbConnectCmd.Flags().Bool("shell", false, "Open an interactive client inside the database instance")
+
+	dbCmd.AddCommand(dbCreateCmd, dbListCmd, dbBackupCmd, dbBackupsCmd, dbRestoreCmd, dbConnectCmd, dbDeleteCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+func printConnection(db databases.Info) {
+	fmt.Printf("Endpoint:          %s\n", db.Endpoint)
+	fmt.Printf("Database:          %s\n", db.DBName)
+	fmt.Printf("Username:          %s\n", db.Username)
+	fmt.Printf("Password:          %s\n", db.Password)
+	fmt.Printf("Connection string: %s\n", db.ConnectionString)
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"localcloud/internal/dns"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	dnsCmd = &cobra.Command{
+		Use:   "dns",
+		Short: "Manage internal DNS records",
+	}
+
+	// List all records, or those for one name
+	dnsListCmd = &cobra.Command{
+		Use:   "list [NAME]",
+		Short: "List DNS records",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			path := "/dns/records"
+			if len(args) == 1 {
+				path += "?name=" + url.QueryEscape(args[0])
+			}
+
+			var records []dns.Record
+			if err := callServer(cmd, http.MethodGet, path, nil, &records); err != nil {
+				return fmt.Errorf("failed to list records: %w", err)
+			}
+
+			if len(records) == 0 {
+				fmt.Println("No records found")
+				return nil
+			}
+
+			fmt.Printf("%-45s %-6s %-35s %-6s %-12s\n", "NAME", "TYPE", "VALUE", "PORT", "SOURCE")
+			for _, r := range records {
+				port := ""
+				if r.Port > 0 {
+					port = fmt.Sprint(r.Port)
+				}
+				fmt.Printf("%-45s %-6s %-35s %-6s %-12s\n", r.Name, r.Type, r.Value, port, r.Source)
+			}
+			return nil
+		},
+	}
+
+	// Add a custom record
+	dnsAddCmd = &cobra.Command{
+		Use:   "add NAME TYPE VALUE",
+		Short: "Add a custom record (A, CNAME, TXT or SRV)",
+		Args:  cobra.ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			port, _ := cmd.Flags().GetInt("port")
+			ttl, _ := cmd.Flags().GetInt("ttl")
+
+			record := dns.Record{Name: args[0], Type: args[1], Value: args[2], Port: port, TTL: ttl}
+			var created dns.Record
+			if err := callServer(cmd, http.MethodPost, "/dns/records", record, &created); err != nil {
+				return fmt.Errorf("failed to add record: %w", err)
+			}
+
+			fmt.Printf("Added %s record: %s -> %s\n", created.Type, created.Name, created.Value)
+			return nil
+		},
+	}
+
+	// Delete custom records
+	dnsDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete custom records for a name",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			recordType, _ := cmd.Flags().GetString("type")
+
+			path := "/dns/records?name=" + url.QueryEscape(args[0])
+			if recordType != "" {
+				path += "&type=" + url.QueryEscape(recordType)
+			}
+
+			var result struct {
+				Removed int `json:"removed"`
+			}
+			if err := callServer(cmd, http.MethodDelete, path, nil, &result); err != nil {
+				return fmt.Errorf("failed to delete records: %w", err)
+			}
+
+			fmt.Printf("Deleted %d record(s) for %s\n", result.Removed, args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	dnsAddCmd.Flags().Int("port", 0, "Port for SRV records")
+	dnsAddCmd.Flags().Int("ttl", 0, "TTL in seconds")
+	dnsDeleteCmd.Flags().String("type", "", "Only delete records of this type")
+
+	dnsCmd.AddCommand(dnsListCmd, dnsAddCmd, dnsDeleteCmd)
+	rootCmd.AddCommand(dnsCmd)
+}
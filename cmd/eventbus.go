@@ -0,0 +1,283 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"localcloud/internal/eventbus"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	eventBusCmd = &cobra.Command{
+		Use:   "eventbus",
+		Short: "Route events to webhooks, queues and instances with rules",
+	}
+
+	eventRuleCmd = &cobra.Command{
+		Use:   "rule",
+		Short: "Manage event rules",
+	}
+
+	eventRuleCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a rule sending events matching --pattern to its targets",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pattern, _ := cmd.Flags().GetString("pattern")
+			description, _ := cmd.Flags().GetString("description")
+			webhooks, _ := cmd.Flags().GetStringArray("webhook")
+			secret, _ := cmd.Flags().GetString("secret")
+			queueNames, _ := cmd.Flags().GetStringArray("queue")
+			execs, _ := cmd.Flags().GetStringArray("exec")
+			attempts, _ := cmd.Flags().GetInt("max-attempts")
+			dlq, _ := cmd.Flags().GetString("dlq")
+
+			if !json.Valid([]byte(pattern)) {
+				return fmt.Errorf("invalid pattern: not valid JSON")
+			}
+			rule := eventbus.Rule{Name: args[0], Description: description, EventPattern: json.RawMessage(pattern)}
+			for _, webhook := range webhooks {
+				rule.Targets = append(rule.Targets, eventbus.Target{Type: "webhook", URL: webhook, Secret: secret})
+			}
+			for _, queue := range queueNames {
+				rule.Targets = append(rule.Targets, eventbus.Target{Type: "queue", Queue: queue})
+			}
+			for _, exec := range execs {
+				instance, command, ok := strings.Cut(exec, ":")
+				if !ok {
+					return fmt.Errorf("invalid exec target %q, expected INSTANCE:COMMAND", exec)
+				}
+				rule.Targets = append(rule.Targets, eventbus.Target{Type: "exec", Instance: instance, Command: command})
+			}
+			for i := range rule.Targets {
+				rule.Targets[i].MaxAttempts = attempts
+				rule.Targets[i].DeadLetterQueue = dlq
+			}
+
+			if err := callServer(cmd, http.MethodPost, "/eventbus/rules", rule, &rule); err != nil {
+				return fmt.Errorf("failed to create rule: %w", err)
+			}
+			fmt.Printf("Created rule %s with %d targets\n", rule.Name, len(rule.Targets))
+			return nil
+		},
+	}
+
+	eventRuleListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List event rules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var rules []eventbus.Rule
+			if err := callServer(cmd, http.MethodGet, "/eventbus/rules", nil, &rules); err != nil {
+				return fmt.Errorf("failed to list rules: %w", err)
+			}
+			if len(rules) == 0 {
+				fmt.Println("No rules found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-9s %-50s %s\n", "NAME", "STATE", "PATTERN", "TARGETS")
+			for _, rule := range rules {
+				state := "enabled"
+				if rule.Disabled {
+					state = "disabled"
+				}
+				var targets []string
+				for _, target := range rule.Targets {
+					targets = append(targets, describeTarget(target))
+				}
+				fmt.Printf("%-20s %-9s %-50s %s\n", rule.Name, state, string(rule.EventPattern), strings.Join(targets, ", "))
+			}
+			return nil
+		},
+	}
+
+	eventRuleDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete an event rule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/eventbus/rules/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete rule: %w", err)
+			}
+			fmt.Printf("Deleted rule %s\n", args[0])
+			return nil
+		},
+	}
+
+	eventRuleEnableCmd = &cobra.Command{
+		Use:   "enable NAME",
+		Short: "Start routing events for a rule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodPost, "/eventbus/rules/"+args[0]+"/enable", nil, nil); err != nil {
+				return fmt.Errorf("failed to enable rule: %w", err)
+			}
+			fmt.Printf("Enabled rule %s\n", args[0])
+			return nil
+		},
+	}
+
+	eventRuleDisableCmd = &cobra.Command{
+		Use:   "disable NAME",
+		Short: "Stop routing events for a rule without deleting it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodPost, "/eventbus/rules/"+args[0]+"/disable", nil, nil); err != nil {
+				return fmt.Errorf("failed to disable rule: %w", err)
+			}
+			fmt.Printf("Disabled rule %s\n", args[0])
+			return nil
+		},
+	}
+
+	eventPutCmd = &cobra.Command{
+		Use:   "put",
+		Short: "Publish a custom event",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			source, _ := cmd.Flags().GetString("source")
+			detailType, _ := cmd.Flags().GetString("detail-type")
+			detail, _ := cmd.Flags().GetString("detail")
+			resources, _ := cmd.Flags().GetStringArray("resource")
+
+			if !json.Valid([]byte(detail)) {
+				return fmt.Errorf("invalid detail: not valid JSON")
+			}
+			body := map[string][]eventbus.Event{"events": {{
+				Source:     source,
+				DetailType: detailType,
+				Resources:  resources,
+				Detail:     json.RawMessage(detail),
+			}}}
+			var events []eventbus.Event
+			if err := callServer(cmd, http.MethodPost, "/eventbus/events", body, &events); err != nil {
+				return fmt.Errorf("failed to put event: %w", err)
+			}
+			for _, event := range events {
+				fmt.Printf("Published event %s\n", event.ID)
+			}
+			return nil
+		},
+	}
+
+	eventListCmd = &cobra.Command{
+		Use:   "events",
+		Short: "List recent events, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var events []eventbus.Event
+			if err := callServer(cmd, http.MethodGet, "/eventbus/events", nil, &events); err != nil {
+				return fmt.Errorf("failed to list events: %w", err)
+			}
+			if len(events) == 0 {
+				fmt.Println("No events yet")
+				return nil
+			}
+
+			for _, event := range events {
+				fmt.Printf("%s  %-20s %-22s %s\n", event.Time.Local().Format("2006-01-02 15:04:05"), event.Source, event.DetailType, string(event.Detail))
+			}
+			return nil
+		},
+	}
+
+	eventDeliveriesCmd = &cobra.Command{
+		Use:   "deliveries",
+		Short: "Show the delivery log, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			rule, _ := cmd.Flags().GetString("rule")
+			status, _ := cmd.Flags().GetString("status")
+
+			query := url.Values{"rule": {rule}, "status": {status}}
+			var deliveries []eventbus.Delivery
+			if err := callServer(cmd, http.MethodGet, "/eventbus/deliveries?"+query.Encode(), nil, &deliveries); err != nil {
+				return fmt.Errorf("failed to list deliveries: %w", err)
+			}
+			if len(deliveries) == 0 {
+				fmt.Println("No deliveries found")
+				return nil
+			}
+
+			fmt.Printf("%-19s %-20s %-14s %-22s %-14s %-8s %s\n", "TIME", "RULE", "TARGET", "EVENT", "STATUS", "ATTEMPTS", "ERROR")
+			for _, d := range deliveries {
+				fmt.Printf("%-19s %-20s %-14s %-22s %-14s %-8d %s\n", d.Time.Local().Format("2006-01-02 15:04:05"),
+					d.Rule, d.Target, d.DetailType, d.Status, d.Attempts, d.Error)
+			}
+			return nil
+		},
+	}
+
+	eventTestPatternCmd = &cobra.Command{
+		Use:   "test-pattern",
+		Short: "Check whether an event matches a pattern",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pattern, _ := cmd.Flags().GetString("pattern")
+			event, _ := cmd.Flags().GetString("event")
+
+			body := map[string]json.RawMessage{"event_pattern": json.RawMessage(pattern), "event": json.RawMessage(event)}
+			for name, value := range body {
+				if !json.Valid(value) {
+					return fmt.Errorf("invalid %s: not valid JSON", strings.TrimPrefix(name, "event_"))
+				}
+			}
+			var result struct {
+				Matched bool `json:"matched"`
+			}
+			if err := callServer(cmd, http.MethodPost, "/eventbus/test-pattern", body, &result); err != nil {
+				return fmt.Errorf("failed to test pattern: %w", err)
+			}
+			if result.Matched {
+				fmt.Println("Matched")
+			} else {
+				fmt.Println("No match")
+			}
+			return nil
+		},
+	}
+)
+
+func describeTarget(target eventbus.Target) string {
+	switch target.Type {
+	case "webhook":
+		return "webhook " + target.URL
+	case "queue":
+		return "queue " + target.Queue
+	case "exec":
+		return "exec " + target.Instance + ": " + target.Command
+	}
+	return target.Type
+}
+
+func init() {
+	eventRuleCreateCmd.Flags().String("pattern", "", `Event pattern, e.g. '{"source": ["localcloud.compute"], "detail": {"state": ["exited"]}}'`)
+	eventRuleCreateCmd.Flags().String("description", "", "Rule description")
+	eventRuleCreateCmd.Flags().StringArray("webhook", nil, "URL to POST matching events to (repeatable)")
+	eventRuleCreateCmd.Flags().String("secret", "", "HMAC key webhook deliveries are signed with")
+	eventRuleCreateCmd.Flags().StringArray("queue", nil, "Queue to send matching events to (repeatable)")
+	eventRuleCreateCmd.Flags().StringArray("exec", nil, "INSTANCE:COMMAND to run with the event on stdin (repeatable)")
+	eventRuleCreateCmd.Flags().Int("max-attempts", 4, "Delivery attempts per target before giving up")
+	eventRuleCreateCmd.Flags().String("dlq", "", "Queue for events that failed every attempt")
+	eventRuleCreateCmd.MarkFlagRequired("pattern")
+
+	eventPutCmd.Flags().String("source", "", "Event source, e.g. my.app")
+	eventPutCmd.Flags().String("detail-type", "", "Event detail type, e.g. Order Placed")
+	eventPutCmd.Flags().String("detail", "{}", "Event detail as a JSON object")
+	eventPutCmd.Flags().StringArray("resource", nil, "Resource the event concerns (repeatable)")
+	eventPutCmd.MarkFlagRequired("source")
+	eventPutCmd.MarkFlagRequired("detail-type")
+
+	eventDeliveriesCmd.Flags().String("rule", "", "Only this rule's deliveries")
+	eventDeliveriesCmd.Flags().String("status", "", "Only delivered, failed or dead_lettered")
+
+	eventTestPatternCmd.Flags().String("pattern", "", "Event pattern")
+	eventTestPatternCmd.Flags().String("event", "", "Event JSON with source, detail-type and detail")
+	eventTestPatternCmd.MarkFlagRequired("pattern")
+	eventTestPatternCmd.MarkFlagRequired("event")
+
+	eventRuleCmd.AddCommand(eventRuleCreateCmd, eventRuleListCmd, eventRuleDeleteCmd, eventRuleEnableCmd, eventRuleDisableCmd)
+	eventBusCmd.AddCommand(eventRuleCmd, eventPutCmd, eventListCmd, eventDeliveriesCmd, eventTestPatternCmd)
+	rootCmd.AddCommand(eventBusCmd)
+}
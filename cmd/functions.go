@@ -0,0 +1,284 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"localcloud/internal/functions"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	fnCmd = &cobra.Command{
+		Use:   "fn",
+		Short: "Manage serverless functions",
+	}
+
+	// Package and upload a function
+	fnDeployCmd = &cobra.Command{
+		Use:   "deploy",
+		Short: "Deploy a function from a directory or zip file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			runtime, _ := cmd.Flags().GetString("runtime")
+			handler, _ := cmd.Flags().GetString("handler")
+			memory, _ := cmd.Flags().GetInt("memory")
+			timeout, _ := cmd.Flags().GetInt("timeout")
+			env, _ := cmd.Flags().GetStringSlice("env")
+			dir, _ := cmd.Flags().GetString("dir")
+			zipFile, _ := cmd.Flags().GetString("zip")
+
+			if name == "" || runtime == "" {
+				return fmt.Errorf("--name and --runtime are required")
+			}
+
+			var code []byte
+			var err error
+			switch {
+			case zipFile != "" && dir != "":
+				return fmt.Errorf("use either --dir or --zip, not both")
+			case zipFile != "":
+				code, err = os.ReadFile(zipFile)
+			default:
+				if dir == "" {
+					dir = "."
+				}
+				code, err = zipDir(dir)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to package code: %w", err)
+			}
+
+			fields := map[string][]string{
+				"name":            {name},
+				"runtime":         {runtime},
+				"handler":         {handler},
+				"memory_mb":       {fmt.Sprint(memory)},
+				"timeout_seconds": {fmt.Sprint(timeout)},
+				"env":             env,
+			}
+
+			fmt.Printf("Building %s (%d bytes of code)...\n", name, len(code))
+			var result struct {
+				Function functions.Function `json:"function"`
+				BuildLog string             `json:"build_log"`
+			}
+			err = uploadToServer(cmd, "/functions", fields, "code", name+".zip", code, &result)
+			if verbose, _ := cmd.Flags().GetBool("verbose"); verbose || err != nil {
+				fmt.Print(result.BuildLog)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to deploy function: %w", err)
+			}
+
+			fn := result.Function
+			fmt.Printf("Deployed %s v%d (%s, handler %s, %d MB, %ds timeout)\n",
+				fn.Name, fn.Version, fn.Runtime, fn.Handler, fn.MemoryMB, fn.Timeout)
+			return nil
+		},
+	}
+
+	// Run a function once
+	fnInvokeCmd = &cobra.Command{
+		Use:   "invoke NAME",
+		Short: "Invoke a function with a JSON event",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			event, _ := cmd.Flags().GetString("event")
+			eventFile, _ := cmd.Flags().GetString("event-file")
+			if eventFile != "" {
+				data, err := os.ReadFile(eventFile)
+				if err != nil {
+					return fmt.Errorf("failed to read event: %w", err)
+				}
+				event = string(data)
+			}
+
+			var inv functions.Invocation
+			if err := callServer(cmd, http.MethodPost, "/functions/"+args[0]+"/invoke", rawJSON(event), &inv); err != nil {
+				return fmt.Errorf("failed to invoke function: %w", err)
+			}
+
+			printInvocation(inv)
+			if inv.Status != "success" {
+				return fmt.Errorf("invocation %s", inv.Status)
+			}
+			return nil
+		},
+	}
+
+	// Show recent invocations and their output
+	fnLogsCmd = &cobra.Command{
+		Use:   "logs NAME",
+		Short: "Show recent invocations of a function",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			var invocations []functions.Invocation
+			path := fmt.Sprintf("/functions/%s/invocations?limit=%d", url.PathEscape(args[0]), limit)
+			if err := callServer(cmd, http.MethodGet, path, nil, &invocations); err != nil {
+				return fmt.Errorf("failed to get invocations: %w", err)
+			}
+
+			if len(invocations) == 0 {
+				fmt.Println("No invocations yet")
+				return nil
+			}
+
+			// oldest first, like a log
+			for i := len(invocations) - 1; i >= 0; i-- {
+				printInvocation(invocations[i])
+				fmt.Println()
+			}
+			return nil
+		},
+	}
+
+	fnListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List functions",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var statuses []functions.Status
+			if err := callServer(cmd, http.MethodGet, "/functions", nil, &statuses); err != nil {
+				return fmt.Errorf("failed to list functions: %w", err)
+			}
+
+			if len(statuses) == 0 {
+				fmt.Println("No functions found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-8s %-20s %-8s %-8s %-8s %-10s\n", "NAME", "RUNTIME", "HANDLER", "VERSION", "MEMORY", "TIMEOUT", "WARM/BUSY")
+			for _, fn := range statuses {
+				fmt.Printf("%-20s %-8s %-20s %-8s %-8s %-8s %-10s\n",
+					fn.Name, fn.Runtime, fn.Handler,
+					fmt.Sprintf("v%d", fn.Version),
+					fmt.Sprintf("%dMB", fn.MemoryMB),
+					fmt.Sprintf("%ds", fn.Timeout),
+					fmt.Sprintf("%d/%d", fn.Warm, fn.Busy))
+			}
+			return nil
+		},
+	}
+
+	fnDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a function and its warm containers",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/functions/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete function: %w", err)
+			}
+
+			fmt.Printf("Deleted function %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	fnDeployCmd.Flags().String("name", "", "Function name")
+	fnDeployCmd.Flags().String("runtime", "", "Runtime (python, node or go)")
+	fnDeployCmd.Flags().String("handler", "", "Handler, e.g. handler.handle (runtime default if empty)")
+	fnDeployCmd.Flags().Int("memory", 0, "Memory limit in MB (default 128)")
+	fnDeployCmd.Flags().Int("timeout", 0, "Timeout per invocation in seconds (default 30)")
+	fnDeployCmd.Flags().StringSlice("env", nil, "Environment variables (KEY=VALUE)")
+	fnDeployCmd.Flags().String("dir", "", "Directory with the function code (default current directory)")
+	fnDeployCmd.Flags().String("zip", "", "Zip file with the function code")
+	fnDeployCmd.Flags().BoolP("verbose", "v", false, "Print the image build output")
+
+	fnInvokeCmd.Flags().String("event", "", "Event as JSON")
+	fnInvokeCmd.Flags().String("event-file", "", "Read the event from a file")
+
+	fnLogsCmd.Flags().IntP("limit", "n", 10, "Number of invocations to show")
+
+	fnCmd.AddCommand(fnDeployCmd, fnInvokeCmd, fnLogsCmd, fnListCmd, fnDeleteCmd)
+	rootCmd.AddCommand(fnCmd)
+}
+
+// Event body sent as is rather than re-encoded
+type rawJSON string
+
+func (r rawJSON) MarshalJSON() ([]byte, error) {
+	if strings.TrimSpace(string(r)) == "" {
+		return []byte("null"), nil
+	}
+	return []byte(r), nil
+}
+
+func printInvocation(inv functions.Invocation) {
+	cold := ""
+	if inv.ColdStart {
+		cold = fmt.Sprintf(" (cold start, init %d ms)", inv.InitMs)
+	}
+	fmt.Printf("%s  %s  v%d  %s  %d ms%s\n",
+		inv.Time.Format("2006-01-02 15:04:05"), inv.RequestID, inv.Version, strings.ToUpper(inv.Status), inv.DurationMs, cold)
+	if inv.Logs != "" {
+		fmt.Println(strings.TrimRight(inv.Logs, "\n"))
+	}
+	if len(inv.Result) > 0 {
+		fmt.Printf("Result: %s\n", inv.Result)
+	}
+	if inv.Error != "" {
+		fmt.Printf("Error: %s\n", strings.TrimRight(inv.Error, "\n"))
+	}
+}
+
+// Zip a directory, skipping hidden files
+func zipDir(dir string) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		if rel != "." && strings.HasPrefix(info.Name(), ".") {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir() || !info.Mode().IsRegular() {
+			return nil
+		}
+
+		header, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		header.Method = zip.Deflate
+		w, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(w, f)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
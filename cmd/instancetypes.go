@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/config"
+	"localcloud/internal/quotas"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	typesCmd = &cobra.Command{
+		Use:   "types",
+		Short: "List instance types",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			direct, err := directMode(cmd)
+			if err != nil {
+				return err
+			}
+
+			var types []compute.InstanceType
+			if direct {
+				manager, err := typesManager()
+				if err != nil {
+					return err
+				}
+				types = manager.InstanceTypes()
+			} else if err := callServer(cmd, http.MethodGet, "/instance-types", nil, &types); err != nil {
+				return fmt.Errorf("failed to list instance types: %w", err)
+			}
+
+			fmt.Printf("%-14s %-6s %-10s %-8s %-10s %s\n", "NAME", "CPUS", "MEMORY", "PIDS", "DISK", "DESCRIPTION")
+			for _, it := range types {
+				disk := "-"
+				if it.DiskMB > 0 {
+					disk = formatSize(int64(it.DiskMB) << 20)
+				}
+				fmt.Printf("%-14s %-6g %-10s %-8d %-10s %s\n",
+					it.Name, it.CPUs, formatSize(int64(it.MemoryMB)<<20), it.PidsLimit, disk, it.Description)
+			}
+			return nil
+		},
+	}
+
+	resizeCmd = &cobra.Command{
+		Use:   "resize",
+		Short: "Change a running container to another instance type",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerID, _ := cmd.Flags().GetString("id")
+			typeName, _ := cmd.Flags().GetString("type")
+
+			direct, err := directMode(cmd)
+			if err != nil {
+				return err
+			}
+
+			instance := &compute.Instance{}
+			if direct {
+				if err := rejectDetach(cmd); err != nil {
+					return err
+				}
+				manager, err := typesManager()
+				if err != nil {
+					return err
+				}
+				quotaService, err := quotas.NewService(manager, config.New().DataDir)
+				if err != nil {
+					return fmt.Errorf("failed to load quotas: %w", err)
+				}
+				manager.UseQuotas(quotaService)
+
+				if instance, err = manager.Resize(containerID, typeName); err != nil {
+					return err
+				}
+			} else {
+				body := map[string]string{"instance_type": typeName}
+				done, err := callOperation(cmd, http.MethodPost, "/containers/"+url.PathEscape(containerID)+"/resize", body, instance)
+				if err != nil {
+					return fmt.Errorf("failed to resize container: %w", err)
+				}
+				if !done {
+					return nil
+				}
+			}
+			fmt.Printf("Resized %s to %s\n", instance.Name, typeName)
+			return nil
+		},
+	}
+)
+
+// Manager with the configured custom instance types loaded
+func typesManager() (*compute.Manager, error) {
+	manager, err := compute.NewManager()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize compute manager: %w", err)
+	}
+	cfg := config.New()
+	if err := manager.LoadInstanceTypes(cfg.InstanceTypesPath()); err != nil {
+		return nil, err
+	}
+	if err := manager.LoadResizes(cfg.ResizesPath()); err != nil {
+		return nil, err
+	}
+	return manager, nil
+}
+
+func init() {
+	resizeCmd.Flags().String("id", "", "Container ID")
+	resizeCmd.Flags().String("type", "", "New instance type")
+	resizeCmd.MarkFlagRequired("id")
+	resizeCmd.MarkFlagRequired("type")
+	addDetachFlag(resizeCmd)
+
+	rootCmd.AddCommand(typesCmd, resizeCmd)
+}
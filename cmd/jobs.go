@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"localcloud/internal/batch"
+	"localcloud/internal/compute"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	jobCmd = &cobra.Command{
+		Use:   "job",
+		Short: "Manage batch jobs",
+	}
+
+	jobSubmitCmd = &cobra.Command{
+		Use:   "submit NAME",
+		Short: "Submit a batch job to a job queue",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			queue, _ := cmd.Flags().GetString("queue")
+			image, _ := cmd.Flags().GetString("image")
+			command, _ := cmd.Flags().GetString("command")
+			env, _ := cmd.Flags().GetStringArray("env")
+			attempts, _ := cmd.Flags().GetInt("attempts")
+			backoff, _ := cmd.Flags().GetDuration("backoff")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			arraySize, _ := cmd.Flags().GetInt("array-size")
+
+			secretRefs, err := secretRefsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			parameterRefs, err := parameterRefsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			input := batch.SubmitInput{
+				Name:  args[0],
+				Queue: queue,
+				Spec: compute.CreateSpec{
+					Image:      image,
+					Env:        env,
+					Secrets:    secretRefs,
+					Parameters: parameterRefs,
+				},
+				MaxAttempts:    attempts,
+				BackoffSeconds: int(backoff.Seconds()),
+				TimeoutSeconds: int(timeout.Seconds()),
+				ArraySize:      arraySize,
+			}
+			if command != "" {
+				input.Spec.Command = []string{"sh", "-c", command}
+			}
+
+			var job batch.JobStatus
+			if err := callServer(cmd, http.MethodPost, "/jobs", input, &job); err != nil {
+				return fmt.Errorf("failed to submit job: %w", err)
+			}
+
+			if job.ArraySize > 0 {
+				fmt.Printf("Submitted array job %s (%s) with %d children to queue %s\n", job.ID, job.Name, job.ArraySize, job.Queue)
+			} else {
+				fmt.Printf("Submitted job %s (%s) to queue %s\n", job.ID, job.Name, job.Queue)
+			}
+			return nil
+		},
+	}
+
+	jobListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List batch jobs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			queue, _ := cmd.Flags().GetString("queue")
+			status, _ := cmd.Flags().GetString("status")
+
+			query := url.Values{}
+			if queue != "" {
+				query.Set("queue", queue)
+			}
+			if status != "" {
+				query.Set("status", status)
+			}
+
+			var jobs []batch.JobStatus
+			if err := callServer(cmd, http.MethodGet, "/jobs?"+query.Encode(), nil, &jobs); err != nil {
+				return fmt.Errorf("failed to list jobs: %w", err)
+			}
+
+			if len(jobs) == 0 {
+				fmt.Println("No jobs found")
+				return nil
+			}
+
+			fmt.Printf("%-10s %-20s %-12s %-10s %-9s %-20s\n", "ID", "NAME", "QUEUE", "STATUS", "ATTEMPTS", "CREATED")
+			for _, job := range jobs {
+				attempts := fmt.Sprintf("%d/%d", len(job.Attempts), job.MaxAttempts)
+				if job.ArraySize > 0 {
+					attempts = fmt.Sprintf("[%d]", job.ArraySize)
+				}
+				fmt.Printf("%-10s %-20s %-12s %-10s %-9s %-20s\n",
+					job.ID, job.Name, job.Queue, job.Status, attempts, job.Created.Local().Format("2006-01-02 15:04:05"))
+			}
+			return nil
+		},
+	}
+
+	jobStatusCmd = &cobra.Command{
+		Use:   "status ID",
+		Short: "Show a job's state and attempts",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var job batch.JobStatus
+			if err := callServer(cmd, http.MethodGet, "/jobs/"+args[0], nil, &job); err != nil {
+				return fmt.Errorf("failed to get job: %w", err)
+			}
+
+			fmt.Printf("Job:      %s (%s)\n", job.ID, job.Name)
+			fmt.Printf("Queue:    %s\n", job.Queue)
+			fmt.Printf("Image:    %s\n", job.Spec.Image)
+			fmt.Printf("Status:   %s\n", job.Status)
+			if job.StatusReason != "" {
+				fmt.Printf("Reason:   %s\n", job.StatusReason)
+			}
+			if job.Started != nil && job.Stopped != nil {
+				fmt.Printf("Duration: %s\n", job.Stopped.Sub(*job.Started).Round(time.Millisecond))
+			}
+
+			if job.ArraySize > 0 {
+				fmt.Printf("Children: %d submitted, %d running, %d succeeded, %d failed\n",
+					job.ArraySummary[batch.StatusSubmitted], job.ArraySummary[batch.StatusRunning],
+					job.ArraySummary[batch.StatusSucceeded], job.ArraySummary[batch.StatusFailed])
+
+				var children []batch.Job
+				if err := callServer(cmd, http.MethodGet, "/jobs/"+args[0]+"/children", nil, &children); err != nil {
+					return fmt.Errorf("failed to list children: %w", err)
+				}
+				fmt.Printf("\n%-14s %-10s %-9s %s\n", "CHILD", "STATUS", "ATTEMPTS", "REASON")
+				for _, child := range children {
+					fmt.Printf("%-14s %-10s %-9s %s\n",
+						child.ID, child.Status, fmt.Sprintf("%d/%d", len(child.Attempts), child.MaxAttempts), child.StatusReason)
+				}
+				return nil
+			}
+
+			if len(job.Attempts) == 0 {
+				return nil
+			}
+			fmt.Printf("\n%-8s %-5s %-20s %-10s %s\n", "ATTEMPT", "EXIT", "STARTED", "DURATION", "REASON")
+			for _, attempt := range job.Attempts {
+				exit := "-"
+				if attempt.ExitCode != nil {
+					exit = strconv.Itoa(*attempt.ExitCode)
+				}
+				duration := "-"
+				if attempt.Stopped != nil {
+					duration = attempt.Stopped.Sub(attempt.Started).Round(time.Millisecond).String()
+				}
+				fmt.Printf("%-8d %-5s %-20s %-10s %s\n",
+					attempt.Number, exit, attempt.Started.Local().Format("2006-01-02 15:04:05"), duration, attempt.Reason)
+			}
+			return nil
+		},
+	}
+
+	jobLogsCmd = &cobra.Command{
+		Use:   "logs ID",
+		Short: "Print the output of a job attempt (the latest by default)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			attempt, _ := cmd.Flags().GetInt("attempt")
+
+			path := "/jobs/" + args[0] + "/logs"
+			if attempt > 0 {
+				path += "?attempt=" + strconv.Itoa(attempt)
+			}
+
+			var result struct {
+				Logs string `json:"logs"`
+			}
+			if err := callServer(cmd, http.MethodGet, path, nil, &result); err != nil {
+				return fmt.Errorf("failed to get job logs: %w", err)
+			}
+
+			fmt.Print(result.Logs)
+			return nil
+		},
+	}
+
+	jobCancelCmd = &cobra.Command{
+		Use:   "cancel ID",
+		Short: "Cancel a job, stopping it if running",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reason, _ := cmd.Flags().GetString("reason")
+
+			body := map[string]string{"reason": reason}
+			if err := callServer(cmd, http.MethodPost, "/jobs/"+args[0]+"/cancel", body, nil); err != nil {
+				return fmt.Errorf("failed to cancel job: %w", err)
+			}
+
+			fmt.Printf("Cancelled job %s\n", args[0])
+			return nil
+		},
+	}
+
+	jobQueueCmd = &cobra.Command{
+		Use:   "queue",
+		Short: "Manage job queues",
+	}
+
+	jobQueueCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a job queue",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			maxParallel, _ := cmd.Flags().GetInt("max-parallel")
+
+			var queue batch.Queue
+			body := batch.Queue{Name: args[0], MaxParallel: maxParallel}
+			if err := callServer(cmd, http.MethodPost, "/job-queues", body, &queue); err != nil {
+				return fmt.Errorf("failed to create job queue: %w", err)
+			}
+
+			fmt.Printf("Created job queue %s running up to %d jobs at once\n", queue.Name, queue.MaxParallel)
+			return nil
+		},
+	}
+
+	jobQueueUpdateCmd = &cobra.Command{
+		Use:   "update NAME",
+		Short: "Change how many jobs a queue runs at once",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			maxParallel, _ := cmd.Flags().GetInt("max-parallel")
+
+			var queue batch.Queue
+			body := map[string]int{"max_parallel": maxParallel}
+			if err := callServer(cmd, http.MethodPut, "/job-queues/"+args[0], body, &queue); err != nil {
+				return fmt.Errorf("failed to update job queue: %w", err)
+			}
+
+			fmt.Printf("Job queue %s now runs up to %d jobs at once\n", queue.Name, queue.MaxParallel)
+			return nil
+		},
+	}
+
+	jobQueueListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List job queues",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var queues []batch.QueueStatus
+			if err := callServer(cmd, http.MethodGet, "/job-queues", nil, &queues); err != nil {
+				return fmt.Errorf("failed to list job queues: %w", err)
+			}
+
+			fmt.Printf("%-20s %-13s %-10s %-10s\n", "NAME", "MAX PARALLEL", "SUBMITTED", "RUNNING")
+			for _, queue := range queues {
+				fmt.Printf("%-20s %-13d %-10d %-10d\n", queue.Name, queue.MaxParallel, queue.Submitted, queue.Running)
+			}
+			return nil
+		},
+	}
+
+	jobQueueDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a job queue with no unfinished jobs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/job-queues/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete job queue: %w", err)
+			}
+
+			fmt.Printf("Deleted job queue %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	jobSubmitCmd.Flags().String("queue", batch.DefaultQueue, "Job queue to submit to")
+	jobSubmitCmd.Flags().String("image", "alpine:latest", "Image to run")
+	jobSubmitCmd.Flags().String("command", "", "Shell command to run (image default if empty)")
+	jobSubmitCmd.Flags().StringArray("env", nil, "Environment variable KEY=value (repeatable)")
+	jobSubmitCmd.Flags().Int("attempts", 1, "Attempts before the job fails")
+	jobSubmitCmd.Flags().Duration("backoff", 5*time.Second, "Delay before the first retry, doubling after each failure")
+	jobSubmitCmd.Flags().Duration("timeout", 0, "Fail attempts that take longer than this")
+	jobSubmitCmd.Flags().Int("array-size", 0, "Run as an array job with this many children, each given "+batch.ArrayIndexEnv)
+	jobSubmitCmd.Flags().StringArray("secret", nil, "Inject a secret: NAME[@VERSION]=env:VAR or NAME[@VERSION]=file:NAME (repeatable)")
+	jobSubmitCmd.Flags().StringArray("param", nil, "Set an env var from a parameter: ENV=/parameter/name (repeatable)")
+	jobSubmitCmd.Flags().StringArray("param-path", nil, "Set env vars from every parameter under [PREFIX=]/path (repeatable)")
+
+	jobListCmd.Flags().String("queue", "", "Only jobs in this queue")
+	jobListCmd.Flags().String("status", "", "Only jobs in this state (SUBMITTED, RUNNING, SUCCEEDED, FAILED)")
+
+	jobLogsCmd.Flags().Int("attempt", 0, "Attempt number (latest if 0)")
+
+	jobCancelCmd.Flags().String("reason", "", "Reason recorded on the job")
+
+	jobQueueCreateCmd.Flags().Int("max-parallel", 4, "Jobs the queue runs at once")
+	jobQueueUpdateCmd.Flags().Int("max-parallel", 4, "Jobs the queue runs at once")
+
+	jobQueueCmd.AddCommand(jobQueueCreateCmd, jobQueueUpdateCmd, jobQueueListCmd, jobQueueDeleteCmd)
+	jobCmd.AddCommand(jobSubmitCmd, jobListCmd, jobStatusCmd, jobLogsCmd, jobCancelCmd, jobQueueCmd)
+	rootCmd.AddCommand(jobCmd)
+}
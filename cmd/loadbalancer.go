@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"localcloud/internal/loadbalancer"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lbCmd = &cobra.Command{
+		Use:   "lb",
+		Short: "Manage load balancers",
+	}
+
+	// Create a load balancer
+	lbCreateCmd = &cobra.Command{
+		Use:   "create",
+		Short: "Create a load balancer",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name, _ := cmd.Flags().GetString("name")
+			protocol, _ := cmd.Flags().GetString("protocol")
+			port, _ := cmd.Flags().GetInt("port")
+			algorithm, _ := cmd.Flags().GetString("algorithm")
+			sticky, _ := cmd.Flags().GetBool("sticky")
+			instances, _ := cmd.Flags().GetStringSlice("instances")
+			group, _ := cmd.Flags().GetString("group")
+			targetPort, _ := cmd.Flags().GetInt("target-port")
+			healthPath, _ := cmd.Flags().GetString("health-path")
+			healthTCP, _ := cmd.Flags().GetBool("health-tcp")
+
+			balancer := loadbalancer.Balancer{
+				Name:       name,
+				Protocol:   protocol,
+				ListenPort: port,
+				Algorithm:  algorithm,
+				Sticky:     sticky,
+				Targets: loadbalancer.TargetGroup{
+					Instances: instances,
+					Group:     group,
+					Port:      targetPort,
+				},
+			}
+			if healthPath != "" || healthTCP {
+				balancer.HealthCheck = &loadbalancer.TargetHealth{Path: healthPath}
+			}
+
+			if err := callServer(cmd, http.MethodPost, "/loadbalancers", balancer, nil); err != nil {
+				return fmt.Errorf("failed to create load balancer: %w", err)
+			}
+
+			fmt.Printf("Created load balancer: %s listening on :%d\n", name, port)
+			return nil
+		},
+	}
+
+	// List load balancers
+	lbListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List load balancers",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var balancers []loadbalancer.Status
+			if err := callServer(cmd, http.MethodGet, "/loadbalancers", nil, &balancers); err != nil {
+				return fmt.Errorf("failed to list load balancers: %w", err)
+			}
+
+			if len(balancers) == 0 {
+				fmt.Println("No load balancers found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-8s %-8s %-18s %-10s %-8s\n", "NAME", "PROTO", "PORT", "ALGORITHM", "TARGETS", "HEALTHY")
+			for _, lb := range balancers {
+				healthy := 0
+				for _, t := range lb.TargetStats {
+					if t.Healthy {
+						healthy++
+					}
+				}
+				fmt.Printf("%-20s %-8s %-8d %-18s %-10d %-8d\n",
+					lb.Name, lb.Protocol, lb.ListenPort, lb.Algorithm, len(lb.TargetStats), healthy)
+			}
+			return nil
+		},
+	}
+
+	// Show per-target metrics
+	lbDescribeCmd = &cobra.Command{
+		Use:   "describe NAME",
+		Short: "Show a load balancer and per-target metrics",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var lb loadbalancer.Status
+			if err := callServer(cmd, http.MethodGet, "/loadbalancers/"+args[0], nil, &lb); err != nil {
+				return fmt.Errorf("failed to get load balancer: %w", err)
+			}
+
+			state := "listening"
+			if !lb.Running {
+				state = "stopped " + lb.Error
+			}
+			fmt.Printf("Name:      %s (%s)\n", lb.Name, state)
+			fmt.Printf("Listener:  %s :%d\n", lb.Protocol, lb.ListenPort)
+			fmt.Printf("Algorithm: %s (sticky: %t)\n", lb.Algorithm, lb.Sticky)
+			if lb.Targets.Group != "" {
+				fmt.Printf("Group:     %s\n", lb.Targets.Group)
+			}
+			if len(lb.Targets.Instances) > 0 {
+				fmt.Printf("Instances: %s\n", strings.Join(lb.Targets.Instances, ", "))
+			}
+
+			fmt.Printf("\n%-25s %-22s %-9s %-7s %-9s %-7s %-10s\n", "TARGET", "ADDRESS", "HEALTHY", "ACTIVE", "REQUESTS", "ERRORS", "LATENCY")
+			for _, t := range lb.TargetStats {
+				fmt.Printf("%-25s %-22s %-9t %-7d %-9d %-7d %.1fms\n",
+					t.Name, t.Address, t.Healthy, t.ActiveConnections, t.Requests, t.Errors, t.AvgLatencyMs)
+			}
+			return nil
+		},
+	}
+
+	// Replace targets
+	lbTargetsCmd = &cobra.Command{
+		Use:   "set-targets NAME",
+		Short: "Replace the target group of a load balancer",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			instances, _ := cmd.Flags().GetStringSlice("instances")
+			group, _ := cmd.Flags().GetString("group")
+			targetPort, _ := cmd.Flags().GetInt("target-port")
+
+			targets := loadbalancer.TargetGroup{Instances: instances, Group: group, Port: targetPort}
+			if err := callServer(cmd, http.MethodPut, "/loadbalancers/"+args[0]+"/targets", targets, nil); err != nil {
+				return fmt.Errorf("failed to set targets: %w", err)
+			}
+
+			fmt.Printf("Updated targets for %s\n", args[0])
+			return nil
+		},
+	}
+
+	// Delete a load balancer
+	lbDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a load balancer",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/loadbalancers/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete load balancer: %w", err)
+			}
+
+			fmt.Printf("Deleted load balancer: %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	// Create command flags
+	lbCreateCmd.Flags().String("name", "", "Load balancer name")
+	lbCreateCmd.Flags().String("protocol", "http", "Listener protocol (http or tcp)")
+	lbCreateCmd.Flags().Int("port", 0, "Host port to listen on")
+	lbCreateCmd.Flags().String("algorithm", loadbalancer.RoundRobin, "round_robin or least_connections")
+	lbCreateCmd.Flags().Bool("sticky", false, "Sticky sessions (cookie for http, client IP for tcp)")
+	lbCreateCmd.Flags().String("health-path", "", "HTTP path to health check targets")
+	lbCreateCmd.Flags().Bool("health-tcp", false, "Health check targets with a TCP connect")
+	lbCreateCmd.MarkFlagRequired("name")
+	lbCreateCmd.MarkFlagRequired("port")
+
+	// Target flags
+	for _, cmd := range []*cobra.Command{lbCreateCmd, lbTargetsCmd} {
+		cmd.Flags().StringSlice("instances", nil, "Target instance IDs or names")
+		cmd.Flags().String("group", "", "Target auto scaling group")
+		cmd.Flags().Int("target-port", 80, "Container port on each target")
+	}
+
+	lbCmd.AddCommand(lbCreateCmd, lbListCmd, lbDescribeCmd, lbTargetsCmd, lbDeleteCmd)
+	rootCmd.AddCommand(lbCmd)
+}
@@ -1,12 +1,18 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"localcloud/internal/api"
 	"localcloud/internal/compute"
 	"localcloud/internal/config"
+	"localcloud/internal/quotas"
 	"log"
+	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -31,7 +37,10 @@ var (
 				return fmt.Errorf("failed to initialize compute manager: %w", err)
 			}
 
-			server := api.NewServer(manager, cfg)
+			server, err := api.NewServer(manager, cfg)
+			if err != nil {
+				return err
+			}
 			return server.Start()
 		},
 	}
@@ -41,12 +50,22 @@ var (
 		Use:   "list",
 		Short: "List all containers",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			manager, err := compute.NewManager()
+			direct, err := directMode(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to initialize compute manager: %w", err)
+				return err
+			}
+
+			var instances []compute.Instance
+			if direct {
+				manager, err := compute.NewManager()
+				if err != nil {
+					return fmt.Errorf("failed to initialize compute manager: %w", err)
+				}
+				instances = manager.List()
+			} else if err := callServer(cmd, http.MethodGet, "/containers", nil, &instances); err != nil {
+				return fmt.Errorf("failed to list containers: %w", err)
 			}
 
-			instances := manager.List()
 			if len(instances) == 0 {
 				fmt.Println("No containers found")
 				return nil
@@ -69,18 +88,65 @@ var (
 			image, _ := cmd.Flags().GetString("image")
 			name, _ := cmd.Flags().GetString("name")
 			ports, _ := cmd.Flags().GetString("ports")
+			snapshot, _ := cmd.Flags().GetString("snapshot")
 
-			manager, err := compute.NewManager()
+			project, _ := cmd.Flags().GetString("project")
+			cpus, _ := cmd.Flags().GetFloat64("cpus")
+			memory, _ := cmd.Flags().GetInt("memory")
+			instanceType, _ := cmd.Flags().GetString("type")
+
+			spec := compute.CreateSpec{Image: image, Name: name, Ports: ports, Snapshot: snapshot, Project: project, CPUs: cpus, MemoryMB: memory, InstanceType: instanceType}
+			tags, _ := cmd.Flags().GetStringArray("tag")
+			for _, tag := range tags {
+				key, value, ok := strings.Cut(tag, "=")
+				if !ok || key == "" || strings.HasPrefix(key, "localcloud.") {
+					return fmt.Errorf("invalid tag %q, expected key=value", tag)
+				}
+				if spec.Labels == nil {
+					spec.Labels = make(map[string]string)
+				}
+				spec.Labels[key] = value
+			}
+			healthCheck, err := healthCheckFromFlags(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to initialize compute manager: %w", err)
+				return err
 			}
+			spec.HealthCheck = healthCheck
 
-			instance, err := manager.Create(image, name, ports)
+			target, err := currentTarget(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to create container: %w", err)
+				return err
+			}
+			if !cmd.Flags().Changed("project") {
+				spec.Project = target.Project
+			}
+
+			var instance *compute.Instance
+			if target.Direct {
+				if err := rejectDetach(cmd); err != nil {
+					return err
+				}
+				if instance, err = createDirect(spec); err != nil {
+					return err
+				}
+			} else {
+				instance = &compute.Instance{}
+				done, err := callOperation(cmd, http.MethodPost, "/containers", spec, instance)
+				if err != nil {
+					return fmt.Errorf("failed to create container: %w", err)
+				}
+				if !done {
+					return nil
+				}
 			}
 
 			fmt.Printf("Created container: %s (%s)\n", instance.Name, instance.ID[:12])
+			if instance.InstanceType != "" {
+				fmt.Printf("Type: %s\n", instance.InstanceType)
+			}
+			if instance.Ports != "" {
+				fmt.Printf("Ports: %s\n", instance.Ports)
+			}
 			return nil
 		},
 	}
@@ -97,14 +163,25 @@ var (
 				return fmt.Errorf("both --id and --command are required")
 			}
 
-			manager, err := compute.NewManager()
+			direct, err := directMode(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to initialize compute manager: %w", err)
+				return err
 			}
 
-			output, err := manager.Exec(containerID, command)
-			if err != nil {
-				return fmt.Errorf("failed to execute command: %w", err)
+			var output string
+			if direct {
+				manager, err := compute.NewManager()
+				if err != nil {
+					return fmt.Errorf("failed to initialize compute manager: %w", err)
+				}
+				if output, err = manager.Exec(containerID, command); err != nil {
+					return fmt.Errorf("failed to execute command: %w", err)
+				}
+			} else {
+				body := map[string]string{"command": command}
+				if err := callServer(cmd, http.MethodPost, "/containers/"+url.PathEscape(containerID)+"/exec", body, &output); err != nil {
+					return fmt.Errorf("failed to execute command: %w", err)
+				}
 			}
 
 			fmt.Print(output)
@@ -122,13 +199,30 @@ var (
 				return fmt.Errorf("--id is required")
 			}
 
-			manager, err := compute.NewManager()
+			direct, err := directMode(cmd)
 			if err != nil {
-				return fmt.Errorf("failed to initialize compute manager: %w", err)
+				return err
 			}
 
-			if err := manager.Delete(containerID); err != nil {
-				return fmt.Errorf("failed to delete container: %w", err)
+			if direct {
+				if err := rejectDetach(cmd); err != nil {
+					return err
+				}
+				manager, err := compute.NewManager()
+				if err != nil {
+					return fmt.Errorf("failed to initialize compute manager: %w", err)
+				}
+				if err := manager.Delete(containerID); err != nil {
+					return fmt.Errorf("failed to delete container: %w", err)
+				}
+			} else {
+				done, err := callOperation(cmd, http.MethodDelete, "/containers/"+url.PathEscape(containerID), nil, nil)
+				if err != nil {
+					return fmt.Errorf("failed to delete container: %w", err)
+				}
+				if !done {
+					return nil
+				}
 			}
 
 			fmt.Printf("Deleted container: %s\n", containerID[:12])
@@ -138,13 +232,27 @@ var (
 )
 
 func init() {
+	// Which LocalCloud to talk to, see contexts.go
+	rootCmd.PersistentFlags().String("endpoint", "", "LocalCloud API address (default from the current context, else "+defaultEndpoint+")")
+	rootCmd.PersistentFlags().String("context", "", "Context from the CLI config to use instead of the current one")
+	rootCmd.PersistentFlags().String("server", "", "LocalCloud API address")
+	rootCmd.PersistentFlags().MarkDeprecated("server", "use --endpoint")
+
 	// Web command flags
 	webCmd.Flags().Int("port", 8080, "Port to run the web interface on")
 
 	// New command flags
 	newCmd.Flags().String("image", "nginx:latest", "Container image")
 	newCmd.Flags().String("name", "", "Container name (auto-generated if empty)")
-	newCmd.Flags().String("ports", "80:80", "Port mapping (host:container)")
+	newCmd.Flags().String("ports", "80:80", "Port mappings ([bind:]host:container, host can be auto or a range like 8000-8100)")
+	newCmd.Flags().String("project", "", "Project the instance counts against (default project if empty)")
+	newCmd.Flags().Float64("cpus", 0, "CPU limit, e.g. 0.5")
+	newCmd.Flags().Int("memory", 0, "Memory limit in MB")
+	newCmd.Flags().String("type", "", "Instance type, e.g. lc.small (see localcloud types)")
+	newCmd.Flags().StringArray("tag", nil, "Tag for cost reports, key=value (repeatable)")
+	newCmd.Flags().String("snapshot", "", "Launch from a snapshot instead of --image")
+	addHealthFlags(newCmd)
+	addDetachFlag(newCmd)
 
 	// Exec command flags
 	execCmd.Flags().String("id", "", "Container ID")
@@ -155,11 +263,115 @@ func init() {
 	// Delete command flags
 	deleteCmd.Flags().String("id", "", "Container ID")
 	deleteCmd.MarkFlagRequired("id")
+	addDetachFlag(deleteCmd)
 
 	// Add commands
 	rootCmd.AddCommand(webCmd, listCmd, newCmd, execCmd, deleteCmd)
 }
 
+// Create an instance through the local Docker daemon, with the server's
+// port range, instance types and quotas
+func createDirect(spec compute.CreateSpec) (*compute.Instance, error) {
+	manager, err := compute.NewManager()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize compute manager: %w", err)
+	}
+	cfg := config.New()
+	if err := manager.UsePorts(cfg.PortRange, cfg.BindAddress); err != nil {
+		return nil, err
+	}
+	if err := manager.LoadInstanceTypes(cfg.InstanceTypesPath()); err != nil {
+		return nil, err
+	}
+	if err := manager.LoadResizes(cfg.ResizesPath()); err != nil {
+		return nil, err
+	}
+	quotaService, err := quotas.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load quotas: %w", err)
+	}
+	manager.UseQuotas(quotaService)
+
+	// Pulling a new image can take a while, so say what's going on
+	lastStep := ""
+	progress := func(step string, percent int) {
+		if step != lastStep {
+			fmt.Fprintf(os.Stderr, "%s...\n", strings.ToUpper(step[:1])+step[1:])
+			lastStep = step
+		}
+	}
+	instance, err := manager.CreateWithProgress(context.Background(), spec, progress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create container: %w", err)
+	}
+	return instance, nil
+}
+
+// Health check flags shared by commands that create instances
+func addHealthFlags(cmd *cobra.Command) {
+	cmd.Flags().String("health-http", "", "HTTP health check path, e.g. /healthz")
+	cmd.Flags().Bool("health-tcp", false, "TCP connect health check")
+	cmd.Flags().String("health-cmd", "", "Command health check run inside the container")
+	cmd.Flags().Int("health-port", 80, "Container port for HTTP and TCP health checks")
+	cmd.Flags().Duration("health-interval", 10*time.Second, "Time between health checks")
+	cmd.Flags().Duration("health-timeout", 3*time.Second, "Health check timeout")
+	cmd.Flags().Int("health-retries", 3, "Consecutive failures before unhealthy")
+	cmd.Flags().Duration("health-start-period", 0, "Grace period before failures count")
+	cmd.Flags().String("health-action", "none", "Action when unhealthy (none, restart, replace)")
+}
+
+// Build a health check from the command's flags, nil if none requested
+func healthCheckFromFlags(cmd *cobra.Command) (*compute.HealthCheck, error) {
+	httpPath, _ := cmd.Flags().GetString("health-http")
+	tcp, _ := cmd.Flags().GetBool("health-tcp")
+	command, _ := cmd.Flags().GetString("health-cmd")
+
+	check := &compute.HealthCheck{}
+	switch {
+	case httpPath != "":
+		check.Type = "http"
+		check.Path = httpPath
+	case tcp:
+		check.Type = "tcp"
+	case command != "":
+		check.Type = "exec"
+		check.Command = command
+	default:
+		return nil, nil
+	}
+
+	port, _ := cmd.Flags().GetInt("health-port")
+	retries, _ := cmd.Flags().GetInt("health-retries")
+	action, _ := cmd.Flags().GetString("health-action")
+
+	if check.Type != "exec" {
+		check.Port = port
+	}
+	var err error
+	if check.Interval, err = wholeSeconds(cmd, "health-interval"); err != nil {
+		return nil, err
+	}
+	if check.Timeout, err = wholeSeconds(cmd, "health-timeout"); err != nil {
+		return nil, err
+	}
+	if check.StartPeriod, err = wholeSeconds(cmd, "health-start-period"); err != nil {
+		return nil, err
+	}
+	check.Retries = retries
+	check.Action = action
+	return check, nil
+}
+
+// Health checks are specified in whole seconds, so refuse 500ms rather
+// than round it to 0, which means the default
+func wholeSeconds(cmd *cobra.Command, flag string) (int, error) {
+	d, _ := cmd.Flags().GetDuration(flag)
+	if d < 0 || d%time.Second != 0 {
+		return 0, fmt.Errorf("--%s must be a whole number of seconds, e.g. 1s or 30s (got %s)", flag, d)
+	}
+	return int(d / time.Second), nil
+}
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestHealthCheckFromFlags(t *testing.T) {
+	parse := func(args ...string) *cobra.Command {
+		t.Helper()
+		cmd := &cobra.Command{}
+		addHealthFlags(cmd)
+		if err := cmd.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return cmd
+	}
+
+	if check, err := healthCheckFromFlags(parse()); check != nil || err != nil {
+		t.Errorf("without flags: %+v, %v", check, err)
+	}
+	check, err := healthCheckFromFlags(parse("--health-http", "/healthz", "--health-interval", "1m", "--health-start-period", "5s"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.Type != "http" || check.Path != "/healthz" || check.Port != 80 || check.Interval != 60 || check.Timeout != 3 || check.StartPeriod != 5 {
+		t.Errorf("check = %+v", check)
+	}
+
+	for _, args := range [][]string{
+		{"--health-tcp", "--health-interval", "500ms"},
+		{"--health-tcp", "--health-timeout", "1.5s"},
+		{"--health-cmd", "true", "--health-start-period", "-1s"},
+	} {
+		if _, err := healthCheckFromFlags(parse(args...)); err == nil || !strings.Contains(err.Error(), "whole number of seconds") {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+}
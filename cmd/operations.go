@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"localcloud/internal/operations"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	operationsCmd = &cobra.Command{
+		Use:     "operations",
+		Aliases: []string{"ops"},
+		Short:   "Follow, wait for and cancel long-running operations",
+	}
+
+	operationsListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List recent operations, newest first",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind, _ := cmd.Flags().GetString("kind")
+			status, _ := cmd.Flags().GetString("status")
+
+			query := url.Values{"kind": {kind}, "status": {status}}
+			var list []operations.Operation
+			if err := callServer(cmd, http.MethodGet, "/operations?"+query.Encode(), nil, &list); err != nil {
+				return fmt.Errorf("failed to list operations: %w", err)
+			}
+			if len(list) == 0 {
+				fmt.Println("No operations found")
+				return nil
+			}
+
+			fmt.Printf("%-36s %-16s %-24s %-10s %-5s %-19s %s\n", "ID", "KIND", "TARGET", "STATUS", "DONE", "CREATED", "STEP/ERROR")
+			for _, op := range list {
+				detail := op.Step
+				if op.Error != "" {
+					detail = op.Error
+				}
+				fmt.Printf("%-36s %-16s %-24s %-10s %3d%%  %-19s %s\n", op.ID, op.Kind, op.Target, op.Status, op.Progress,
+					op.Created.Local().Format("2006-01-02 15:04:05"), detail)
+			}
+			return nil
+		},
+	}
+
+	operationsGetCmd = &cobra.Command{
+		Use:   "get ID",
+		Short: "Show an operation and its result",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var op operations.Operation
+			if err := callServer(cmd, http.MethodGet, "/operations/"+args[0], nil, &op); err != nil {
+				return fmt.Errorf("failed to get operation: %w", err)
+			}
+			printOperation(op)
+			return nil
+		},
+	}
+
+	operationsWaitCmd = &cobra.Command{
+		Use:   "wait ID",
+		Short: "Follow an operation's progress until it finishes",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+
+			op, err := followOperation(cmd, args[0], timeout)
+			if err != nil {
+				return err
+			}
+			printOperation(op)
+			if op.Status != operations.StatusSucceeded {
+				return fmt.Errorf("operation %s", op.Status)
+			}
+			return nil
+		},
+	}
+
+	operationsCancelCmd = &cobra.Command{
+		Use:   "cancel ID",
+		Short: "Ask a running operation to stop",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var op operations.Operation
+			if err := callServer(cmd, http.MethodPost, "/operations/"+args[0]+"/cancel", nil, &op); err != nil {
+				return fmt.Errorf("failed to cancel operation: %w", err)
+			}
+			fmt.Printf("Cancelling operation %s\n", op.ID)
+			return nil
+		},
+	}
+)
+
+func printOperation(op operations.Operation) {
+	fmt.Printf("ID:       %s\n", op.ID)
+	fmt.Printf("Kind:     %s\n", op.Kind)
+	fmt.Printf("Target:   %s\n", op.Target)
+	fmt.Printf("Status:   %s (%d%%)\n", op.Status, op.Progress)
+	if op.Step != "" {
+		fmt.Printf("Step:     %s\n", op.Step)
+	}
+	fmt.Printf("Created:  %s\n", op.Created.Local().Format("2006-01-02 15:04:05"))
+	if op.Finished != nil {
+		fmt.Printf("Finished: %s (took %s)\n", op.Finished.Local().Format("2006-01-02 15:04:05"), op.Finished.Sub(op.Created).Round(time.Millisecond))
+	}
+	if op.Error != "" {
+		fmt.Printf("Error:    %s\n", op.Error)
+	}
+	if len(op.Result) > 0 {
+		fmt.Printf("Result:   %s\n", string(op.Result))
+	}
+}
+
+// Poll an operation, printing each new step to stderr, until it finishes
+// or timeout (if set) passes
+func followOperation(cmd *cobra.Command, id string, timeout time.Duration) (operations.Operation, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+
+	var op operations.Operation
+	last := ""
+	for {
+		// The server holds each poll until the operation finishes or 5s pass
+		if err := callServer(cmd, http.MethodGet, "/operations/"+id+"?wait=5", nil, &op); err != nil {
+			return op, fmt.Errorf("failed to get operation: %w", err)
+		}
+		if progress := fmt.Sprintf("%s %d%%", op.Step, op.Progress); op.Step != "" && progress != last {
+			fmt.Fprintf(os.Stderr, "  %s\n", progress)
+			last = progress
+		}
+		if op.Done() {
+			return op, nil
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return op, fmt.Errorf("operation %s still running after %s", id, timeout)
+		}
+	}
+}
+
+// Make a request as an operation. With --detach only the operation ID is
+// printed and false returned; otherwise progress is followed and the
+// result decoded into out.
+func callOperation(cmd *cobra.Command, method, path string, body, out interface{}) (bool, error) {
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+	var op operations.Operation
+	if err := callServer(cmd, method, path+separator+"async=true", body, &op); err != nil {
+		return false, err
+	}
+
+	if detach, _ := cmd.Flags().GetBool("detach"); detach {
+		fmt.Printf("Started operation %s, follow it with `localcloud operations wait %s`\n", op.ID, op.ID)
+		return false, nil
+	}
+
+	op, err := followOperation(cmd, op.ID, 0)
+	if err != nil {
+		return false, err
+	}
+	switch op.Status {
+	case operations.StatusSucceeded:
+	case operations.StatusCancelled:
+		return false, fmt.Errorf("operation %s was cancelled", op.ID)
+	default:
+		return false, fmt.Errorf("%s", op.Error)
+	}
+	if out != nil && len(op.Result) > 0 {
+		if err := json.Unmarshal(op.Result, out); err != nil {
+			return false, fmt.Errorf("failed to decode result: %w", err)
+		}
+	}
+	return true, nil
+}
+
+// For commands backed by callOperation
+func addDetachFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("detach", false, "Return once the operation has started instead of waiting for it")
+}
+
+func init() {
+	operationsListCmd.Flags().String("kind", "", "Only operations of this kind, e.g. instance.create")
+	operationsListCmd.Flags().String("status", "", "Only running, succeeded, failed or cancelled operations")
+
+	operationsWaitCmd.Flags().Duration("timeout", 0, "Give up waiting after this long (0 waits indefinitely)")
+
+	operationsCmd.AddCommand(operationsListCmd, operationsGetCmd, operationsWaitCmd, operationsCancelCmd)
+	rootCmd.AddCommand(operationsCmd)
+}
+
+// Direct mode does the work in the CLI process, so there is nothing to detach from
+func rejectDetach(cmd *cobra.Command) error {
+	if detach, _ := cmd.Flags().GetBool("detach"); detach {
+		return fmt.Errorf("--detach needs a LocalCloud server; the current context uses direct mode")
+	}
+	return nil
+}
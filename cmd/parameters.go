@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/parameters"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	paramCmd = &cobra.Command{
+		Use:   "param",
+		Short: "Manage the parameter store",
+	}
+
+	paramGetCmd = &cobra.Command{
+		Use:   "get NAME",
+		Short: "Print a parameter (NAME may end in :VERSION or :LABEL)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			decrypt, _ := cmd.Flags().GetBool("decrypt")
+			quiet, _ := cmd.Flags().GetBool("quiet")
+
+			query := url.Values{"decrypt": {strconv.FormatBool(decrypt)}}
+			var p parameters.Parameter
+			if err := callServer(cmd, http.MethodGet, "/parameters"+args[0]+"?"+query.Encode(), nil, &p); err != nil {
+				return fmt.Errorf("failed to get parameter: %w", err)
+			}
+
+			if quiet {
+				fmt.Println(p.Value)
+				return nil
+			}
+			fmt.Printf("Name:     %s\n", p.Name)
+			fmt.Printf("Type:     %s\n", p.Type)
+			fmt.Printf("Version:  %d\n", p.Version)
+			if len(p.Labels) > 0 {
+				fmt.Printf("Labels:   %s\n", strings.Join(p.Labels, ", "))
+			}
+			fmt.Printf("Modified: %s\n", p.Modified.Format("2006-01-02 15:04:05"))
+			fmt.Printf("Value:    %s\n", parameterValue(p))
+			return nil
+		},
+	}
+
+	paramPutCmd = &cobra.Command{
+		Use:   "put NAME VALUE",
+		Short: "Create a parameter, or a new version with --overwrite",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paramType, _ := cmd.Flags().GetString("type")
+			description, _ := cmd.Flags().GetString("description")
+			overwrite, _ := cmd.Flags().GetBool("overwrite")
+
+			in := parameters.PutInput{
+				Value:       args[1],
+				Type:        paramType,
+				Description: description,
+				Overwrite:   overwrite,
+			}
+			var result struct {
+				Version int `json:"version"`
+			}
+			if err := callServer(cmd, http.MethodPut, "/parameters"+args[0], in, &result); err != nil {
+				return fmt.Errorf("failed to put parameter: %w", err)
+			}
+
+			fmt.Printf("Stored %s version %d\n", args[0], result.Version)
+			return nil
+		},
+	}
+
+	// Get by path
+	paramListCmd = &cobra.Command{
+		Use:   "list [PATH]",
+		Short: "List parameters under a path",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			recursive, _ := cmd.Flags().GetBool("recursive")
+			decrypt, _ := cmd.Flags().GetBool("decrypt")
+
+			path := "/"
+			if len(args) > 0 {
+				path = args[0]
+			}
+			query := url.Values{
+				"path":      {path},
+				"recursive": {strconv.FormatBool(recursive)},
+				"decrypt":   {strconv.FormatBool(decrypt)},
+			}
+			var list []parameters.Parameter
+			if err := callServer(cmd, http.MethodGet, "/parameters?"+query.Encode(), nil, &list); err != nil {
+				return fmt.Errorf("failed to list parameters: %w", err)
+			}
+
+			if len(list) == 0 {
+				fmt.Println("No parameters found")
+				return nil
+			}
+
+			fmt.Printf("%-40s %-13s %-8s %-30s\n", "NAME", "TYPE", "VERSION", "VALUE")
+			for _, p := range list {
+				fmt.Printf("%-40s %-13s %-8d %-30s\n", p.Name, p.Type, p.Version, parameterValue(p))
+			}
+			return nil
+		},
+	}
+
+	paramHistoryCmd = &cobra.Command{
+		Use:   "history NAME",
+		Short: "Show every version of a parameter",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			decrypt, _ := cmd.Flags().GetBool("decrypt")
+
+			query := url.Values{"decrypt": {strconv.FormatBool(decrypt)}}
+			var history []parameters.Parameter
+			if err := callServer(cmd, http.MethodGet, "/parameter-history"+args[0]+"?"+query.Encode(), nil, &history); err != nil {
+				return fmt.Errorf("failed to get parameter history: %w", err)
+			}
+
+			fmt.Printf("%-8s %-13s %-20s %-20s %-30s\n", "VERSION", "TYPE", "MODIFIED", "LABELS", "VALUE")
+			for _, p := range history {
+				fmt.Printf("%-8d %-13s %-20s %-20s %-30s\n", p.Version, p.Type,
+					p.Modified.Format("2006-01-02 15:04:05"), strings.Join(p.Labels, ","), parameterValue(p))
+			}
+			return nil
+		},
+	}
+
+	paramLabelCmd = &cobra.Command{
+		Use:   "label NAME LABEL...",
+		Short: "Attach labels to a version of a parameter",
+		Args:  cobra.MinimumNArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, _ := cmd.Flags().GetInt("version")
+
+			body := map[string]interface{}{"version": version, "labels": args[1:]}
+			if err := callServer(cmd, http.MethodPost, "/parameter-labels"+args[0], body, nil); err != nil {
+				return fmt.Errorf("failed to label parameter: %w", err)
+			}
+
+			fmt.Printf("Labelled %s\n", args[0])
+			return nil
+		},
+	}
+
+	paramDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a parameter and its history",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/parameters"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete parameter: %w", err)
+			}
+
+			fmt.Printf("Deleted parameter %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	paramGetCmd.Flags().Bool("decrypt", false, "Decrypt SecureString values")
+	paramGetCmd.Flags().BoolP("quiet", "q", false, "Print only the value")
+	paramPutCmd.Flags().String("type", parameters.TypeString, "String, StringList or SecureString")
+	paramPutCmd.Flags().String("description", "", "Description")
+	paramPutCmd.Flags().Bool("overwrite", false, "Store a new version of an existing parameter")
+	paramListCmd.Flags().BoolP("recursive", "r", false, "Include parameters in nested paths")
+	paramListCmd.Flags().Bool("decrypt", false, "Decrypt SecureString values")
+	paramHistoryCmd.Flags().Bool("decrypt", false, "Decrypt SecureString values")
+	paramLabelCmd.Flags().Int("version", 0, "Version to label (latest if 0)")
+
+	paramCmd.AddCommand(paramGetCmd, paramPutCmd, paramListCmd, paramHistoryCmd, paramLabelCmd, paramDeleteCmd)
+	rootCmd.AddCommand(paramCmd)
+}
+
+// Value for display, masked if it is still encrypted
+func parameterValue(p parameters.Parameter) string {
+	if p.Type == parameters.TypeSecureString && p.Value == "" {
+		return "********"
+	}
+	return p.Value
+}
+
+// Parse --param ENV=/name and --param-path [PREFIX=]/path flags
+func parameterRefsFromFlags(cmd *cobra.Command) ([]compute.ParameterRef, error) {
+	single, _ := cmd.Flags().GetStringArray("param")
+	paths, _ := cmd.Flags().GetStringArray("param-path")
+
+	var refs []compute.ParameterRef
+	for _, value := range single {
+		env, name, ok := strings.Cut(value, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid --param %q, expected ENV=/parameter/name", value)
+		}
+		refs = append(refs, compute.ParameterRef{Name: name, Env: env})
+	}
+	for _, value := range paths {
+		prefix, path, ok := strings.Cut(value, "=")
+		if !ok {
+			prefix, path = "", value
+		}
+		refs = append(refs, compute.ParameterRef{Path: path, Env: prefix})
+	}
+	return refs, nil
+}
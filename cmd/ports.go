@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"localcloud/internal/compute"
+
+	"github.com/spf13/cobra"
+)
+
+var portsCmd = &cobra.Command{
+	Use:   "ports",
+	Short: "List host ports published by running containers",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		direct, err := directMode(cmd)
+		if err != nil {
+			return err
+		}
+
+		var usage []compute.PortUsage
+		if direct {
+			manager, err := compute.NewManager()
+			if err != nil {
+				return fmt.Errorf("failed to initialize compute manager: %w", err)
+			}
+			if usage, err = manager.PortUsage(); err != nil {
+				return fmt.Errorf("failed to list ports: %w", err)
+			}
+		} else if err := callServer(cmd, http.MethodGet, "/ports", nil, &usage); err != nil {
+			return fmt.Errorf("failed to list ports: %w", err)
+		}
+		if len(usage) == 0 {
+			fmt.Println("No published ports")
+			return nil
+		}
+
+		fmt.Printf("%-22s %-12s %-25s %s\n", "HOST", "CONTAINER", "NAME", "LOCALCLOUD")
+		for _, port := range usage {
+			host := fmt.Sprintf("%s:%d", port.HostIP, port.HostPort)
+			target := fmt.Sprintf("%d/%s", port.ContainerPort, port.Protocol)
+			managed := "no"
+			if port.Managed {
+				managed = "yes"
+			}
+			fmt.Printf("%-22s %-12s %-25s %s\n", host, target, port.ContainerName, managed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(portsCmd)
+}
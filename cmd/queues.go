@@ -0,0 +1,251 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"localcloud/internal/queues"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	queueCmd = &cobra.Command{
+		Use:   "queue",
+		Short: "Manage message queues",
+	}
+
+	queueCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a queue (names ending in .fifo are FIFO queues)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			visibility, _ := cmd.Flags().GetInt("visibility-timeout")
+			delay, _ := cmd.Flags().GetInt("delay")
+			retention, _ := cmd.Flags().GetInt("retention")
+			wait, _ := cmd.Flags().GetInt("wait")
+			dlq, _ := cmd.Flags().GetString("dead-letter-queue")
+			maxReceives, _ := cmd.Flags().GetInt("max-receives")
+			contentDedup, _ := cmd.Flags().GetBool("content-dedup")
+
+			spec := queues.Queue{
+				Name:               args[0],
+				VisibilityTimeout:  visibility,
+				DelaySeconds:       delay,
+				RetentionSeconds:   retention,
+				ReceiveWaitSeconds: wait,
+				DeadLetterQueue:    dlq,
+				MaxReceiveCount:    maxReceives,
+				ContentBasedDedup:  contentDedup,
+			}
+			var queue queues.Status
+			if err := callServer(cmd, http.MethodPost, "/queues", spec, &queue); err != nil {
+				return fmt.Errorf("failed to create queue: %w", err)
+			}
+
+			fmt.Printf("Created queue %s\n", queue.Name)
+			fmt.Printf("ARN: %s\n", queues.QueueARN(queue.Name))
+			return nil
+		},
+	}
+
+	queueListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List queues",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var list []queues.Status
+			if err := callServer(cmd, http.MethodGet, "/queues", nil, &list); err != nil {
+				return fmt.Errorf("failed to list queues: %w", err)
+			}
+
+			if len(list) == 0 {
+				fmt.Println("No queues found")
+				return nil
+			}
+
+			fmt.Printf("%-30s %-9s %-10s %-10s %-8s %-10s %-20s\n",
+				"NAME", "TYPE", "AVAILABLE", "IN FLIGHT", "DELAYED", "OLDEST", "DEAD-LETTER")
+			for _, q := range list {
+				kind := "standard"
+				if q.FIFO {
+					kind = "fifo"
+				}
+				dlq := "-"
+				if q.DeadLetterQueue != "" {
+					dlq = fmt.Sprintf("%s (%d)", q.DeadLetterQueue, q.MaxReceiveCount)
+				}
+				fmt.Printf("%-30s %-9s %-10d %-10d %-8d %-10s %-20s\n",
+					q.Name, kind, q.Visible, q.InFlight, q.Delayed, fmt.Sprintf("%.0fs", q.OldestAgeSeconds), dlq)
+			}
+			return nil
+		},
+	}
+
+	queueSendCmd = &cobra.Command{
+		Use:   "send NAME BODY",
+		Short: "Send a message to a queue",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			group, _ := cmd.Flags().GetString("group")
+			dedup, _ := cmd.Flags().GetString("dedup-id")
+
+			in := queues.SendInput{
+				Body:    args[1],
+				GroupID: group,
+				DedupID: dedup,
+			}
+			if cmd.Flags().Changed("delay") {
+				delay, _ := cmd.Flags().GetInt("delay")
+				in.DelaySeconds = &delay
+			}
+
+			var result queues.SendResult
+			if err := callServer(cmd, http.MethodPost, "/queues/"+args[0]+"/messages", in, &result); err != nil {
+				return fmt.Errorf("failed to send message: %w", err)
+			}
+
+			fmt.Printf("Sent message %s\n", result.MessageID)
+			return nil
+		},
+	}
+
+	// Receive messages, optionally deleting them straight away
+	queueReceiveCmd = &cobra.Command{
+		Use:   "receive NAME",
+		Short: "Receive messages from a queue",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			max, _ := cmd.Flags().GetInt("max")
+			del, _ := cmd.Flags().GetBool("delete")
+
+			query := url.Values{"max": {strconv.Itoa(max)}}
+			if cmd.Flags().Changed("wait") {
+				wait, _ := cmd.Flags().GetInt("wait")
+				query.Set("wait", strconv.Itoa(wait))
+			}
+			if cmd.Flags().Changed("visibility-timeout") {
+				visibility, _ := cmd.Flags().GetInt("visibility-timeout")
+				query.Set("visibility", strconv.Itoa(visibility))
+			}
+
+			var messages []queues.Message
+			if err := callServer(cmd, http.MethodGet, "/queues/"+args[0]+"/messages?"+query.Encode(), nil, &messages); err != nil {
+				return fmt.Errorf("failed to receive messages: %w", err)
+			}
+
+			if len(messages) == 0 {
+				fmt.Println("No messages available")
+				return nil
+			}
+
+			for _, m := range messages {
+				fmt.Printf("Message:        %s (received %d times)\n", m.ID, m.ReceiveCount)
+				if m.GroupID != "" {
+					fmt.Printf("Group:          %s\n", m.GroupID)
+				}
+				fmt.Printf("Receipt handle: %s\n", m.ReceiptHandle)
+				fmt.Printf("%s\n\n", m.Body)
+
+				if del {
+					if err := deleteQueueMessage(cmd, args[0], m.ReceiptHandle); err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		},
+	}
+
+	queueDeleteMessageCmd = &cobra.Command{
+		Use:   "delete-message NAME RECEIPT_HANDLE",
+		Short: "Delete a received message",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := deleteQueueMessage(cmd, args[0], args[1]); err != nil {
+				return err
+			}
+
+			fmt.Println("Deleted message")
+			return nil
+		},
+	}
+
+	queuePurgeCmd = &cobra.Command{
+		Use:   "purge NAME",
+		Short: "Delete all messages in a queue",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodPost, "/queues/"+args[0]+"/purge", nil, nil); err != nil {
+				return fmt.Errorf("failed to purge queue: %w", err)
+			}
+
+			fmt.Printf("Purged queue %s\n", args[0])
+			return nil
+		},
+	}
+
+	// Move messages from a dead-letter queue back to where they came from
+	queueRedriveCmd = &cobra.Command{
+		Use:   "redrive DLQ",
+		Short: "Move dead-lettered messages back to their source queues",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var result struct {
+				Moved int `json:"moved"`
+			}
+			if err := callServer(cmd, http.MethodPost, "/queues/"+args[0]+"/redrive", nil, &result); err != nil {
+				return fmt.Errorf("failed to redrive queue: %w", err)
+			}
+
+			fmt.Printf("Moved %d message(s)\n", result.Moved)
+			return nil
+		},
+	}
+
+	queueDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a queue and its messages",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/queues/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete queue: %w", err)
+			}
+
+			fmt.Printf("Deleted queue %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	queueCreateCmd.Flags().Int("visibility-timeout", 0, "Visibility timeout in seconds (default 30)")
+	queueCreateCmd.Flags().Int("delay", 0, "Delivery delay in seconds")
+	queueCreateCmd.Flags().Int("retention", 0, "Message retention in seconds (default 4 days)")
+	queueCreateCmd.Flags().Int("wait", 0, "Default long polling wait in seconds")
+	queueCreateCmd.Flags().String("dead-letter-queue", "", "Queue that receives messages after --max-receives")
+	queueCreateCmd.Flags().Int("max-receives", 0, "Receives before a message moves to the dead-letter queue")
+	queueCreateCmd.Flags().Bool("content-dedup", false, "Deduplicate FIFO messages by body")
+
+	queueSendCmd.Flags().String("group", "", "Message group ID (FIFO queues)")
+	queueSendCmd.Flags().String("dedup-id", "", "Deduplication ID (FIFO queues)")
+	queueSendCmd.Flags().Int("delay", 0, "Delivery delay in seconds")
+
+	queueReceiveCmd.Flags().Int("max", 1, "Maximum number of messages (1-10)")
+	queueReceiveCmd.Flags().Int("wait", 0, "Long polling wait in seconds")
+	queueReceiveCmd.Flags().Int("visibility-timeout", 0, "Visibility timeout in seconds")
+	queueReceiveCmd.Flags().Bool("delete", false, "Delete messages after printing them")
+
+	queueCmd.AddCommand(queueCreateCmd, queueListCmd, queueSendCmd, queueReceiveCmd, queueDeleteMessageCmd,
+		queuePurgeCmd, queueRedriveCmd, queueDeleteCmd)
+	rootCmd.AddCommand(queueCmd)
+}
+
+func deleteQueueMessage(cmd *cobra.Command, queue, receiptHandle string) error {
+	path := "/queues/" + queue + "/messages?receipt_handle=" + url.QueryEscape(receiptHandle)
+	if err := callServer(cmd, http.MethodDelete, path, nil, nil); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+	return nil
+}
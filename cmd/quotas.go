@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/quotas"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	quotaCmd = &cobra.Command{
+		Use:   "quota",
+		Short: "Show and set per-project resource quotas",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var report []quotas.Status
+			if err := callServer(cmd, http.MethodGet, "/quotas", nil, &report); err != nil {
+				return fmt.Errorf("failed to list quotas: %w", err)
+			}
+			if len(report) == 0 {
+				fmt.Println("No projects found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-12s %-14s %-20s %-10s %-22s %s\n", "PROJECT", "INSTANCES", "CPUS", "MEMORY", "VOLUMES", "STORAGE", "PORTS")
+			for _, status := range report {
+				q := status.Quota
+				if q == nil {
+					q = &compute.Quota{}
+				}
+				u := status.Usage
+				project := status.Project
+				if status.Inherited {
+					project += " (*)"
+				}
+				fmt.Printf("%-20s %-12s %-14s %-20s %-10s %-22s %s\n", project,
+					usedOf(fmt.Sprint(u.Instances), q.MaxInstances > 0, fmt.Sprint(q.MaxInstances)),
+					usedOf(strconv.FormatFloat(u.CPUs, 'g', -1, 64), q.MaxCPUs > 0, strconv.FormatFloat(q.MaxCPUs, 'g', -1, 64)),
+					usedOf(fmt.Sprintf("%d MB", u.MemoryMB), q.MaxMemoryMB > 0, fmt.Sprintf("%d MB", q.MaxMemoryMB)),
+					usedOf(fmt.Sprint(u.Volumes), q.MaxVolumes > 0, fmt.Sprint(q.MaxVolumes)),
+					usedOf(formatSize(u.StorageBytes), q.MaxStorageBytes > 0, formatSize(q.MaxStorageBytes)),
+					usedOf(fmt.Sprint(u.Ports), q.MaxPorts > 0, fmt.Sprint(q.MaxPorts)))
+			}
+			return nil
+		},
+	}
+
+	quotaSetCmd = &cobra.Command{
+		Use:   "set PROJECT",
+		Short: "Set the limits of a project (* for every project without its own)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var quota compute.Quota
+			quota.MaxInstances, _ = cmd.Flags().GetInt("instances")
+			quota.MaxCPUs, _ = cmd.Flags().GetFloat64("cpus")
+			quota.MaxMemoryMB, _ = cmd.Flags().GetInt("memory")
+			quota.MaxVolumes, _ = cmd.Flags().GetInt("volumes")
+			quota.MaxPorts, _ = cmd.Flags().GetInt("ports")
+
+			storage, _ := cmd.Flags().GetString("storage")
+			if storage != "" {
+				bytes, err := parseSize(storage)
+				if err != nil {
+					return err
+				}
+				quota.MaxStorageBytes = bytes
+			}
+
+			if err := callServer(cmd, http.MethodPut, "/quotas/"+args[0], quota, nil); err != nil {
+				return fmt.Errorf("failed to set quota: %w", err)
+			}
+			fmt.Printf("Set quota for %s\n", args[0])
+			return nil
+		},
+	}
+
+	quotaDeleteCmd = &cobra.Command{
+		Use:   "delete PROJECT",
+		Short: "Remove a project's quota",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/quotas/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete quota: %w", err)
+			}
+			fmt.Printf("Deleted quota for %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	quotaSetCmd.Flags().Int("instances", 0, "Maximum instances (0 for no limit)")
+	quotaSetCmd.Flags().Float64("cpus", 0, "Maximum total CPUs")
+	quotaSetCmd.Flags().Int("memory", 0, "Maximum total memory in MB")
+	quotaSetCmd.Flags().Int("volumes", 0, "Maximum named volumes")
+	quotaSetCmd.Flags().String("storage", "", "Maximum volume storage (e.g. 500M, 10G)")
+	quotaSetCmd.Flags().Int("ports", 0, "Maximum published ports")
+
+	quotaCmd.AddCommand(quotaSetCmd, quotaDeleteCmd)
+	rootCmd.AddCommand(quotaCmd)
+}
+
+// "3 / 10", or just the usage without a limit
+func usedOf(used string, limited bool, limit string) string {
+	if !limited {
+		return used
+	}
+	return used + " / " + limit
+}
+
+// Byte count like 512, 500M or 10G
+func parseSize(input string) (int64, error) {
+	units := map[string]int64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30, "T": 1 << 40}
+	value := strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(input)), "B")
+	multiplier := int64(1)
+	if n := len(value); n > 0 && units[value[n-1:]] > 0 {
+		multiplier = units[value[n-1:]]
+		value = value[:n-1]
+	}
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("invalid size %q", input)
+	}
+	return int64(number * float64(multiplier)), nil
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/scheduler"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	scheduleCmd = &cobra.Command{
+		Use:   "schedule",
+		Short: "Manage cron-scheduled jobs",
+	}
+
+	scheduleCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Run a container on a cron schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cron, _ := cmd.Flags().GetString("cron")
+			image, _ := cmd.Flags().GetString("image")
+			command, _ := cmd.Flags().GetString("command")
+			env, _ := cmd.Flags().GetStringArray("env")
+			concurrency, _ := cmd.Flags().GetString("concurrency")
+			timeout, _ := cmd.Flags().GetDuration("timeout")
+			history, _ := cmd.Flags().GetInt("history")
+
+			if cron == "" {
+				return fmt.Errorf("--cron is required")
+			}
+			secretRefs, err := secretRefsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+			parameterRefs, err := parameterRefsFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			job := scheduler.Job{
+				Name:     args[0],
+				Schedule: cron,
+				Spec: compute.CreateSpec{
+					Image:      image,
+					Env:        env,
+					Secrets:    secretRefs,
+					Parameters: parameterRefs,
+				},
+				Concurrency:    concurrency,
+				TimeoutSeconds: int(timeout.Seconds()),
+				HistoryLimit:   history,
+			}
+			if command != "" {
+				job.Spec.Command = []string{"sh", "-c", command}
+			}
+
+			var status scheduler.JobStatus
+			if err := callServer(cmd, http.MethodPost, "/schedules", job, &status); err != nil {
+				return fmt.Errorf("failed to create schedule: %w", err)
+			}
+
+			fmt.Printf("Created schedule %s (%s)\n", status.Name, status.Schedule)
+			if status.NextRun != nil {
+				fmt.Printf("Next run: %s\n", status.NextRun.Local().Format("2006-01-02 15:04:05"))
+			}
+			return nil
+		},
+	}
+
+	scheduleListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List schedules",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var jobs []scheduler.JobStatus
+			if err := callServer(cmd, http.MethodGet, "/schedules", nil, &jobs); err != nil {
+				return fmt.Errorf("failed to list schedules: %w", err)
+			}
+
+			if len(jobs) == 0 {
+				fmt.Println("No schedules found")
+				return nil
+			}
+
+			fmt.Printf("%-20s %-18s %-25s %-12s %-20s %-10s\n", "NAME", "SCHEDULE", "IMAGE", "CONCURRENCY", "NEXT RUN", "LAST RUN")
+			for _, job := range jobs {
+				next := "-"
+				if job.Suspended {
+					next = "suspended"
+				} else if job.NextRun != nil {
+					next = job.NextRun.Local().Format("2006-01-02 15:04:05")
+				}
+				last := "-"
+				if job.LastRun != nil {
+					last = job.LastRun.Status
+				}
+				fmt.Printf("%-20s %-18s %-25s %-12s %-20s %-10s\n",
+					job.Name, job.Schedule, job.Spec.Image, job.Concurrency, next, last)
+			}
+			return nil
+		},
+	}
+
+	scheduleRunCmd = &cobra.Command{
+		Use:   "run NAME",
+		Short: "Start a run now",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var run scheduler.Run
+			if err := callServer(cmd, http.MethodPost, "/schedules/"+args[0]+"/run", nil, &run); err != nil {
+				return fmt.Errorf("failed to start run: %w", err)
+			}
+
+			if run.Status == scheduler.StatusSkipped {
+				fmt.Printf("Run skipped: %s\n", run.Error)
+				return nil
+			}
+			fmt.Printf("Started run %s\n", run.ID)
+			return nil
+		},
+	}
+
+	scheduleRunsCmd = &cobra.Command{
+		Use:   "runs NAME",
+		Short: "Show recent runs of a schedule",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+
+			var runs []scheduler.Run
+			if err := callServer(cmd, http.MethodGet, "/schedules/"+args[0]+"/runs?limit="+strconv.Itoa(limit), nil, &runs); err != nil {
+				return fmt.Errorf("failed to list runs: %w", err)
+			}
+
+			if len(runs) == 0 {
+				fmt.Println("No runs yet")
+				return nil
+			}
+
+			fmt.Printf("%-10s %-9s %-10s %-5s %-20s %-10s\n", "RUN", "TRIGGER", "STATUS", "EXIT", "STARTED", "DURATION")
+			for _, run := range runs {
+				exit := "-"
+				if run.ExitCode != nil {
+					exit = strconv.Itoa(*run.ExitCode)
+				}
+				duration := "-"
+				if run.Status != scheduler.StatusRunning {
+					duration = (time.Duration(run.DurationMS) * time.Millisecond).String()
+				}
+				fmt.Printf("%-10s %-9s %-10s %-5s %-20s %-10s\n",
+					run.ID, run.Trigger, run.Status, exit, run.Started.Local().Format("2006-01-02 15:04:05"), duration)
+			}
+			return nil
+		},
+	}
+
+	scheduleLogsCmd = &cobra.Command{
+		Use:   "logs NAME [RUN]",
+		Short: "Print the output of a run (the latest by default)",
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			runID := ""
+			if len(args) > 1 {
+				runID = args[1]
+			} else {
+				var runs []scheduler.Run
+				if err := callServer(cmd, http.MethodGet, "/schedules/"+args[0]+"/runs?limit=1", nil, &runs); err != nil {
+					return fmt.Errorf("failed to list runs: %w", err)
+				}
+				if len(runs) == 0 {
+					return fmt.Errorf("schedule %s has no runs yet", args[0])
+				}
+				runID = runs[0].ID
+			}
+
+			var run scheduler.Run
+			if err := callServer(cmd, http.MethodGet, "/schedules/"+args[0]+"/runs/"+runID, nil, &run); err != nil {
+				return fmt.Errorf("failed to get run: %w", err)
+			}
+
+			fmt.Printf("Run %s: %s\n", run.ID, run.Status)
+			if run.Error != "" {
+				fmt.Printf("Error: %s\n", run.Error)
+			}
+			fmt.Print(run.Logs)
+			return nil
+		},
+	}
+
+	scheduleSuspendCmd = &cobra.Command{
+		Use:   "suspend NAME",
+		Short: "Stop starting scheduled runs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodPost, "/schedules/"+args[0]+"/suspend", nil, nil); err != nil {
+				return fmt.Errorf("failed to suspend schedule: %w", err)
+			}
+
+			fmt.Printf("Suspended schedule %s\n", args[0])
+			return nil
+		},
+	}
+
+	scheduleResumeCmd = &cobra.Command{
+		Use:   "resume NAME",
+		Short: "Start scheduled runs again",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodPost, "/schedules/"+args[0]+"/resume", nil, nil); err != nil {
+				return fmt.Errorf("failed to resume schedule: %w", err)
+			}
+
+			fmt.Printf("Resumed schedule %s\n", args[0])
+			return nil
+		},
+	}
+
+	scheduleDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a schedule, stopping active runs",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/schedules/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete schedule: %w", err)
+			}
+
+			fmt.Printf("Deleted schedule %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	scheduleCreateCmd.Flags().String("cron", "", "Cron expression (e.g. \"*/5 * * * *\", @daily, \"@every 90s\")")
+	scheduleCreateCmd.Flags().String("image", "alpine:latest", "Image to run")
+	scheduleCreateCmd.Flags().String("command", "", "Shell command to run (image default if empty)")
+	scheduleCreateCmd.Flags().StringArray("env", nil, "Environment variable KEY=value (repeatable)")
+	scheduleCreateCmd.Flags().String("concurrency", scheduler.ConcurrencyAllow, "When a run is still active: allow, forbid or replace")
+	scheduleCreateCmd.Flags().Duration("timeout", 0, "Stop runs that take longer than this")
+	scheduleCreateCmd.Flags().Int("history", 0, "Runs to keep (default 20)")
+	scheduleCreateCmd.Flags().StringArray("secret", nil, "Inject a secret: NAME[@VERSION]=env:VAR or NAME[@VERSION]=file:NAME (repeatable)")
+	scheduleCreateCmd.Flags().StringArray("param", nil, "Set an env var from a parameter: ENV=/parameter/name (repeatable)")
+	scheduleCreateCmd.Flags().StringArray("param-path", nil, "Set env vars from every parameter under [PREFIX=]/path (repeatable)")
+
+	scheduleRunsCmd.Flags().IntP("limit", "n", 20, "Number of runs to show")
+
+	scheduleCmd.AddCommand(scheduleCreateCmd, scheduleListCmd, scheduleRunCmd, scheduleRunsCmd, scheduleLogsCmd,
+		scheduleSuspendCmd, scheduleResumeCmd, scheduleDeleteCmd)
+	rootCmd.AddCommand(scheduleCmd)
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/secrets"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	secretCmd = &cobra.Command{
+		Use:   "secret",
+		Short: "Manage secrets",
+	}
+
+	secretCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create a secret (a random value is generated unless one is given)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			description, _ := cmd.Flags().GetString("description")
+			value, err := secretValueFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			body := map[string]string{"name": args[0], "description": description, "value": value}
+			var secret secrets.Secret
+			if err := callServer(cmd, http.MethodPost, "/secrets", body, &secret); err != nil {
+				return fmt.Errorf("failed to create secret: %w", err)
+			}
+
+			fmt.Printf("Created secret %s (version %d)\n", secret.Name, secret.CurrentVersion)
+			return nil
+		},
+	}
+
+	secretListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List secrets (without their values)",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var list []secrets.Secret
+			if err := callServer(cmd, http.MethodGet, "/secrets", nil, &list); err != nil {
+				return fmt.Errorf("failed to list secrets: %w", err)
+			}
+
+			if len(list) == 0 {
+				fmt.Println("No secrets found")
+				return nil
+			}
+
+			fmt.Printf("%-30s %-8s %-20s %-30s\n", "NAME", "VERSION", "UPDATED", "DESCRIPTION")
+			for _, secret := range list {
+				fmt.Printf("%-30s %-8d %-20s %-30s\n",
+					secret.Name, secret.CurrentVersion, secret.Updated.Format("2006-01-02 15:04:05"), secret.Description)
+			}
+			return nil
+		},
+	}
+
+	// Print a value, e.g. for use in scripts
+	secretGetCmd = &cobra.Command{
+		Use:   "get NAME",
+		Short: "Print the value of a secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version, _ := cmd.Flags().GetInt("version")
+
+			path := "/secrets/" + args[0] + "/value"
+			if version > 0 {
+				path += "?" + url.Values{"version": {strconv.Itoa(version)}}.Encode()
+			}
+			var result struct {
+				Value string `json:"value"`
+			}
+			if err := callServer(cmd, http.MethodGet, path, nil, &result); err != nil {
+				return fmt.Errorf("failed to get secret: %w", err)
+			}
+
+			fmt.Println(result.Value)
+			return nil
+		},
+	}
+
+	secretRotateCmd = &cobra.Command{
+		Use:   "rotate NAME",
+		Short: "Store a new version of a secret (generated unless a value is given)",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			value, err := secretValueFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			var secret secrets.Secret
+			if err := callServer(cmd, http.MethodPost, "/secrets/"+args[0]+"/rotate", map[string]string{"value": value}, &secret); err != nil {
+				return fmt.Errorf("failed to rotate secret: %w", err)
+			}
+
+			fmt.Printf("Rotated secret %s to version %d\n", secret.Name, secret.CurrentVersion)
+			fmt.Println("Instances pick up the new value when they are next created or restarted")
+			return nil
+		},
+	}
+
+	secretDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a secret and all its versions",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := callServer(cmd, http.MethodDelete, "/secrets/"+args[0], nil, nil); err != nil {
+				return fmt.Errorf("failed to delete secret: %w", err)
+			}
+
+			fmt.Printf("Deleted secret %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	for _, c := range []*cobra.Command{secretCreateCmd, secretRotateCmd} {
+		c.Flags().String("value", "", "Secret value (visible in shell history, prefer --from-file)")
+		c.Flags().String("from-file", "", "Read the value from a file, - for stdin")
+	}
+	secretCreateCmd.Flags().String("description", "", "Description")
+	secretGetCmd.Flags().Int("version", 0, "Version to print (current if 0)")
+
+	secretCmd.AddCommand(secretCreateCmd, secretListCmd, secretGetCmd, secretRotateCmd, secretDeleteCmd)
+	rootCmd.AddCommand(secretCmd)
+}
+
+// Value from --value or --from-file, empty to have one generated
+func secretValueFromFlags(cmd *cobra.Command) (string, error) {
+	value, _ := cmd.Flags().GetString("value")
+	file, _ := cmd.Flags().GetString("from-file")
+
+	switch {
+	case value != "" && file != "":
+		return "", fmt.Errorf("use either --value or --from-file")
+	case file == "-":
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", fmt.Errorf("failed to read stdin: %w", err)
+		}
+		return string(data), nil
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		return string(data), nil
+	}
+	return value, nil
+}
+
+// Parse --secret flags: NAME[@VERSION][=env:VAR|=file:NAME], a file named
+// after the secret by default
+func secretRefsFromFlags(cmd *cobra.Command) ([]compute.SecretRef, error) {
+	values, _ := cmd.Flags().GetStringArray("secret")
+
+	refs := make([]compute.SecretRef, 0, len(values))
+	for _, value := range values {
+		source, target, _ := strings.Cut(value, "=")
+
+		var ref compute.SecretRef
+		name, version, pinned := strings.Cut(source, "@")
+		ref.Name = name
+		if pinned {
+			v, err := strconv.Atoi(version)
+			if err != nil {
+				return nil, fmt.Errorf("invalid secret version in %q", value)
+			}
+			ref.Version = v
+		}
+
+		switch kind, dest, _ := strings.Cut(target, ":"); kind {
+		case "":
+		case "env":
+			ref.Env = dest
+		case "file":
+			ref.File = dest
+		default:
+			return nil, fmt.Errorf("invalid secret %q, expected NAME[@VERSION]=env:VAR or NAME[@VERSION]=file:NAME", value)
+		}
+		refs = append(refs, ref)
+	}
+	return refs, nil
+}
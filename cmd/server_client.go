@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Commands for services that run inside `localcloud web` talk to it over
+// HTTP, at the endpoint and with the token of the current context
+
+// Build a request to the LocalCloud API
+func newServerRequest(cmd *cobra.Command, method, path string, body io.Reader) (*http.Request, *cliTarget, error) {
+	target, err := currentTarget(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequest(method, strings.TrimRight(target.Endpoint, "/")+"/api/v1"+path, body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	if target.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+target.Token)
+	}
+	return req, target, nil
+}
+
+// Send a request to the LocalCloud API and decode the response data into out
+func callServer(cmd *cobra.Command, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, target, err := newServerRequest(cmd, method, path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	return doServerRequest(req, target.Endpoint, out)
+}
+
+// Upload a file with form fields as multipart/form-data
+func uploadToServer(cmd *cobra.Command, path string, fields map[string][]string, fileField, fileName string, data []byte, out interface{}) error {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	for key, values := range fields {
+		for _, value := range values {
+			if err := writer.WriteField(key, value); err != nil {
+				return fmt.Errorf("failed to encode request: %w", err)
+			}
+		}
+	}
+	part, err := writer.CreateFormFile(fileField, fileName)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, target, err := newServerRequest(cmd, http.MethodPost, path, &buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return doServerRequest(req, target.Endpoint, out)
+}
+
+// Send a raw body, such as an archive, without loading it into memory
+func streamToServer(cmd *cobra.Command, method, path, contentType string, body io.Reader, out interface{}) error {
+	req, target, err := newServerRequest(cmd, method, path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return doServerRequest(req, target.Endpoint, out)
+}
+
+// Send a JSON body (if not nil) and copy a non-JSON response, such as an
+// archive, to w
+func downloadFromServer(cmd *cobra.Command, method, path string, body interface{}, w io.Writer) error {
+	reader, err := openFromServer(cmd, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to download: %w", err)
+	}
+	return nil
+}
+
+// Like downloadFromServer, leaving the caller to read and close the response
+func openFromServer(cmd *cobra.Command, method, path string, body interface{}) (io.ReadCloser, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, target, err := newServerRequest(cmd, method, path, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach LocalCloud at %s (is `localcloud web` running?): %w", target.Endpoint, err)
+	}
+
+	// errors come back in the usual JSON envelope
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+		defer resp.Body.Close()
+		var envelope struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+			return nil, fmt.Errorf("invalid response from server (HTTP %d): %w", resp.StatusCode, err)
+		}
+		return nil, fmt.Errorf("%s", envelope.Error)
+	}
+	return resp.Body, nil
+}
+
+func doServerRequest(req *http.Request, server string, out interface{}) error {
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach LocalCloud at %s (is `localcloud web` running?): %w", server, err)
+	}
+	defer resp.Body.Close()
+
+	var envelope struct {
+		Success bool            `json:"success"`
+		Data    json.RawMessage `json:"data"`
+		Error   string          `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("invalid response from server (HTTP %d): %w", resp.StatusCode, err)
+	}
+
+	// error responses may still carry data (e.g. a build log)
+	if out != nil && len(envelope.Data) > 0 && string(envelope.Data) != "null" {
+		if err := json.Unmarshal(envelope.Data, out); err != nil && envelope.Success {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	if !envelope.Success {
+		return fmt.Errorf("%s", envelope.Error)
+	}
+	return nil
+}
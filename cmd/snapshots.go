@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"localcloud/internal/compute"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapshotCmd = &cobra.Command{
+		Use:   "snapshot",
+		Short: "Manage container snapshots",
+	}
+
+	snapshotCreateCmd = &cobra.Command{
+		Use:   "create NAME",
+		Short: "Commit a container into a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			containerID, _ := cmd.Flags().GetString("id")
+			description, _ := cmd.Flags().GetString("description")
+
+			input := compute.SnapshotInput{Name: args[0], Description: description}
+
+			direct, err := directMode(cmd)
+			if err != nil {
+				return err
+			}
+
+			snapshot := &compute.Snapshot{}
+			if direct {
+				if err := rejectDetach(cmd); err != nil {
+					return err
+				}
+				manager, err := compute.NewManager()
+				if err != nil {
+					return fmt.Errorf("failed to initialize compute manager: %w", err)
+				}
+				if snapshot, err = manager.Snapshot(containerID, input); err != nil {
+					return fmt.Errorf("failed to create snapshot: %w", err)
+				}
+			} else {
+				body := struct {
+					compute.SnapshotInput
+					InstanceID string `json:"instance_id"`
+				}{input, containerID}
+				done, err := callOperation(cmd, http.MethodPost, "/snapshots", body, snapshot)
+				if err != nil {
+					return fmt.Errorf("failed to create snapshot: %w", err)
+				}
+				if !done {
+					return nil
+				}
+			}
+
+			fmt.Printf("Created snapshot %s from %s (%s)\n", snapshot.Name, snapshot.Source, formatSize(snapshot.SizeBytes))
+			fmt.Printf("Launch it with: localcloud new --snapshot %s\n", snapshot.Name)
+			return nil
+		},
+	}
+
+	snapshotListCmd = &cobra.Command{
+		Use:   "list",
+		Short: "List snapshots",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			direct, err := directMode(cmd)
+			if err != nil {
+				return err
+			}
+
+			var snapshots []compute.Snapshot
+			if direct {
+				manager, err := compute.NewManager()
+				if err != nil {
+					return fmt.Errorf("failed to initialize compute manager: %w", err)
+				}
+				if snapshots, err = manager.ListSnapshots(); err != nil {
+					return fmt.Errorf("failed to list snapshots: %w", err)
+				}
+			} else if err := callServer(cmd, http.MethodGet, "/snapshots", nil, &snapshots); err != nil {
+				return fmt.Errorf("failed to list snapshots: %w", err)
+			}
+			if len(snapshots) == 0 {
+				fmt.Println("No snapshots found")
+				return nil
+			}
+
+			fmt.Printf("%-25s %-20s %-25s %-10s %-20s %s\n", "NAME", "SOURCE", "BASE IMAGE", "SIZE", "CREATED", "DESCRIPTION")
+			for _, snapshot := range snapshots {
+				fmt.Printf("%-25s %-20s %-25s %-10s %-20s %s\n",
+					snapshot.Name, snapshot.Source, snapshot.SourceImage, formatSize(snapshot.SizeBytes),
+					snapshot.Created.Local().Format("2006-01-02 15:04:05"), snapshot.Description)
+			}
+			return nil
+		},
+	}
+
+	snapshotDeleteCmd = &cobra.Command{
+		Use:   "delete NAME",
+		Short: "Delete a snapshot",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			direct, err := directMode(cmd)
+			if err != nil {
+				return err
+			}
+
+			if direct {
+				manager, err := compute.NewManager()
+				if err != nil {
+					return fmt.Errorf("failed to initialize compute manager: %w", err)
+				}
+				if err := manager.DeleteSnapshot(args[0]); err != nil {
+					return fmt.Errorf("failed to delete snapshot: %w", err)
+				}
+			} else if err := callServer(cmd, http.MethodDelete, "/snapshots/"+url.PathEscape(args[0]), nil, nil); err != nil {
+				return fmt.Errorf("failed to delete snapshot: %w", err)
+			}
+
+			fmt.Printf("Deleted snapshot %s\n", args[0])
+			return nil
+		},
+	}
+)
+
+func init() {
+	snapshotCreateCmd.Flags().String("id", "", "Container ID or name")
+	snapshotCreateCmd.Flags().String("description", "", "What the snapshot contains")
+	snapshotCreateCmd.MarkFlagRequired("id")
+	addDetachFlag(snapshotCreateCmd)
+
+	snapshotCmd.AddCommand(snapshotCreateCmd, snapshotListCmd, snapshotDeleteCmd)
+	rootCmd.AddCommand(snapshotCmd)
+}
+
+// Human readable byte count
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,654 @@
+// Alarms on instance metrics with notification and instance actions
+package alarms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+)
+
+const (
+	evaluationInterval = 5 * time.Second
+	defaultPeriod      = 60 // seconds
+	minPeriod          = 10
+	datapointsKept     = 100
+	historySize        = 200
+	webhookTimeout     = 5 * time.Second
+)
+
+const (
+	StateOK               = "OK"
+	StateAlarm            = "ALARM"
+	StateInsufficientData = "INSUFFICIENT_DATA"
+)
+
+var (
+	ErrAlarmNotFound = errors.New("alarm not found")
+	ErrAlarmExists   = errors.New("alarm already exists")
+	ErrInvalidAlarm  = errors.New("invalid alarm")
+)
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,62}$`)
+
+// Units of each metric an alarm can watch
+var Metrics = map[string]string{
+	"cpu":       "percent",
+	"memory":    "percent of limit",
+	"memory_mb": "MB",
+}
+
+// Rule over an instance metric: metric comparison threshold for
+// evaluation_periods samples in a row puts the alarm in ALARM
+type Alarm struct {
+	Name              string    `json:"name"`
+	Description       string    `json:"description,omitempty"`
+	Metric            string    `json:"metric"`     // cpu, memory or memory_mb
+	Comparison        string    `json:"comparison"` // >, >=, < or <=
+	Threshold         float64   `json:"threshold"`
+	Period            int       `json:"period_seconds"`     // time between samples
+	EvaluationPeriods int       `json:"evaluation_periods"` // breaching samples in a row before ALARM
+	Instance          string    `json:"instance,omitempty"` // ID or name, every running instance if empty
+	Project           string    `json:"project,omitempty"`  // only this project's instances
+	Actions           []Action  `json:"actions,omitempty"`
+	Created           time.Time `json:"created"`
+}
+
+// What to do when the alarm enters a state
+type Action struct {
+	Type  string `json:"type"`            // webhook, log, restart or stop
+	URL   string `json:"url,omitempty"`   // webhook only
+	State string `json:"state,omitempty"` // ALARM if empty; restart and stop only run on ALARM
+}
+
+// Alarm plus where it stands
+type AlarmStatus struct {
+	Alarm
+	State        string      `json:"state"`
+	Reason       string      `json:"reason"`
+	StateUpdated time.Time   `json:"state_updated"`
+	Datapoints   []Datapoint `json:"datapoints,omitempty"` // recent samples, only on Get
+}
+
+// One sample: the worst value across the alarm's instances
+type Datapoint struct {
+	Time      time.Time `json:"time"`
+	Value     float64   `json:"value"`
+	Missing   bool      `json:"missing,omitempty"` // no instance reported the metric
+	Breaching []string  `json:"breaching,omitempty"`
+}
+
+// A state change and what its actions did
+type Transition struct {
+	Time    time.Time      `json:"time"`
+	Alarm   string         `json:"alarm"`
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Reason  string         `json:"reason"`
+	Actions []ActionResult `json:"actions,omitempty"`
+}
+
+type ActionResult struct {
+	Type   string `json:"type"`
+	Target string `json:"target,omitempty"` // webhook URL or instance name
+	Error  string `json:"error,omitempty"`
+}
+
+// Body POSTed to webhook actions
+type Notification struct {
+	Alarm         string    `json:"alarm"`
+	Description   string    `json:"description,omitempty"`
+	State         string    `json:"state"`
+	PreviousState string    `json:"previous_state"`
+	Reason        string    `json:"reason"`
+	Metric        string    `json:"metric"`
+	Threshold     float64   `json:"threshold"`
+	Value         float64   `json:"value"`
+	Instances     []string  `json:"instances,omitempty"` // the ones breaching
+	Time          time.Time `json:"time"`
+}
+
+// Receives every state change as an event, whatever the alarm's actions
+type EventPublisher interface {
+	Emit(source, detailType string, resources []string, detail interface{})
+}
+
+// Instance breaching at a sample, for restart and stop actions
+type target struct {
+	id   string
+	name string
+}
+
+type Service struct {
+	manager *compute.Manager
+	dir     string
+	client  *http.Client
+	events  EventPublisher
+
+	mu         sync.Mutex
+	alarms     map[string]*AlarmStatus
+	history    []Transition
+	datapoints map[string][]Datapoint
+	targets    map[string][]target // breaching at the latest sample
+	sampled    map[string]time.Time
+}
+
+func NewService(manager *compute.Manager, dataDir string) (*Service, error) {
+	s := &Service{
+		manager:    manager,
+		dir:        filepath.Join(dataDir, "alarms"),
+		client:     &http.Client{Timeout: webhookTimeout},
+		alarms:     make(map[string]*AlarmStatus),
+		datapoints: make(map[string][]Datapoint),
+		targets:    make(map[string][]target),
+		sampled:    make(map[string]time.Time),
+	}
+	if err := store.Load(filepath.Join(s.dir, "alarms.json"), &s.alarms); err != nil {
+		return nil, err
+	}
+	if err := store.Load(filepath.Join(s.dir, "history.json"), &s.history); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Publish state changes from now on
+func (s *Service) UseEvents(publisher EventPublisher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = publisher
+}
+
+// Sample and evaluate alarms until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(evaluationInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				s.evaluateAll(now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) List() []AlarmStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]AlarmStatus, 0, len(s.alarms))
+	for _, status := range s.alarms {
+		list = append(list, *status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (s *Service) Get(name string) (*AlarmStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.alarms[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAlarmNotFound, name)
+	}
+	result := *status
+	result.Datapoints = append([]Datapoint(nil), s.datapoints[name]...)
+	return &result, nil
+}
+
+func (s *Service) Create(alarm Alarm) (*AlarmStatus, error) {
+	if err := validate(&alarm); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.alarms[alarm.Name]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrAlarmExists, alarm.Name)
+	}
+	alarm.Created = time.Now()
+	status := &AlarmStatus{
+		Alarm:        alarm,
+		State:        StateInsufficientData,
+		Reason:       "alarm created, waiting for datapoints",
+		StateUpdated: alarm.Created,
+	}
+	s.alarms[alarm.Name] = status
+	if err := s.saveLocked(); err != nil {
+		delete(s.alarms, alarm.Name)
+		return nil, err
+	}
+	log.Printf("alarms: created %s (%s %s %g for %d x %ds)", alarm.Name, alarm.Metric, alarm.Comparison, alarm.Threshold, alarm.EvaluationPeriods, alarm.Period)
+	result := *status
+	return &result, nil
+}
+
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.alarms[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrAlarmNotFound, name)
+	}
+	delete(s.alarms, name)
+	delete(s.datapoints, name)
+	delete(s.targets, name)
+	delete(s.sampled, name)
+	return s.saveLocked()
+}
+
+// State changes, newest first; every alarm's if name is empty
+func (s *Service) History(name string) ([]Transition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if name != "" {
+		if _, ok := s.alarms[name]; !ok {
+			return nil, fmt.Errorf("%w: %s", ErrAlarmNotFound, name)
+		}
+	}
+	history := []Transition{}
+	for i := len(s.history) - 1; i >= 0; i-- {
+		if name == "" || s.history[i].Alarm == name {
+			history = append(history, s.history[i])
+		}
+	}
+	return history, nil
+}
+
+// Force a state, to try out actions. Restart and stop act on the instances
+// breaching at the latest sample; the next sample evaluates as usual.
+func (s *Service) SetState(name, state, reason string) (*AlarmStatus, error) {
+	if state != StateOK && state != StateAlarm && state != StateInsufficientData {
+		return nil, fmt.Errorf("%w: state must be OK, ALARM or INSUFFICIENT_DATA", ErrInvalidAlarm)
+	}
+	if reason == "" {
+		reason = "state set manually"
+	}
+
+	s.mu.Lock()
+	if _, ok := s.alarms[name]; !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrAlarmNotFound, name)
+	}
+	s.transitionLocked(name, state, reason, s.targets[name], 0)
+	s.mu.Unlock()
+
+	return s.Get(name)
+}
+
+// Sample every alarm whose period is up. Docker is queried without the
+// lock, and each instance's metrics are read at most once per round.
+func (s *Service) evaluateAll(now time.Time) {
+	s.mu.Lock()
+	var due []Alarm
+	for name, status := range s.alarms {
+		if now.Sub(s.sampled[name]) >= time.Duration(status.Period)*time.Second-evaluationInterval/2 {
+			due = append(due, status.Alarm)
+		}
+	}
+	s.mu.Unlock()
+	if len(due) == 0 {
+		return
+	}
+
+	var instances []compute.Instance
+	listed := false
+	metrics := make(map[string]*compute.Metrics)
+	readMetrics := func(id string) *compute.Metrics {
+		if m, ok := metrics[id]; ok {
+			return m
+		}
+		m, err := s.manager.GetMetrics(id)
+		if err != nil {
+			m = nil
+		}
+		metrics[id] = m
+		return m
+	}
+
+	for _, alarm := range due {
+		var candidates []compute.Instance
+		if alarm.Instance != "" {
+			if instance, err := s.manager.Get(alarm.Instance); err == nil {
+				candidates = []compute.Instance{*instance}
+			}
+		} else {
+			if !listed {
+				instances = s.manager.List()
+				listed = true
+			}
+			candidates = instances
+		}
+
+		point := Datapoint{Time: now, Missing: true}
+		var breaching []target
+		for _, instance := range candidates {
+			if instance.State != "running" || (alarm.Project != "" && instance.Project != alarm.Project) {
+				continue
+			}
+			m := readMetrics(instance.ID)
+			if m == nil {
+				continue
+			}
+			value, ok := metricValue(alarm.Metric, m)
+			if !ok {
+				continue
+			}
+			if point.Missing || worse(alarm.Comparison, value, point.Value) {
+				point.Value = value
+			}
+			point.Missing = false
+			if compare(alarm.Comparison, value, alarm.Threshold) {
+				breaching = append(breaching, target{id: instance.ID, name: instance.Name})
+				point.Breaching = append(point.Breaching, instance.Name)
+			}
+		}
+
+		s.record(alarm.Name, point, breaching)
+	}
+}
+
+// Add a datapoint and move the alarm to the state it now calls for
+func (s *Service) record(name string, point Datapoint, breaching []target) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.alarms[name]
+	if !ok {
+		return // deleted while sampling
+	}
+	s.sampled[name] = point.Time
+	points := append(s.datapoints[name], point)
+	if len(points) > datapointsKept {
+		points = points[len(points)-datapointsKept:]
+	}
+	s.datapoints[name] = points
+	s.targets[name] = breaching
+
+	alarm := status.Alarm
+	switch {
+	case point.Missing:
+		s.transitionLocked(name, StateInsufficientData, "no running instance reported "+alarm.Metric, breaching, 0)
+
+	case !compare(alarm.Comparison, point.Value, alarm.Threshold):
+		s.transitionLocked(name, StateOK, fmt.Sprintf("%s %s is not %s %g", alarm.Metric, formatValue(point.Value), alarm.Comparison, alarm.Threshold), breaching, point.Value)
+
+	case len(points) >= alarm.EvaluationPeriods && allBreaching(alarm, points[len(points)-alarm.EvaluationPeriods:]):
+		reason := fmt.Sprintf("%s %s %s %g for %d of %d samples", alarm.Metric, formatValue(point.Value), alarm.Comparison, alarm.Threshold,
+			alarm.EvaluationPeriods, alarm.EvaluationPeriods)
+		if alarm.Instance == "" {
+			reason += " on " + strings.Join(point.Breaching, ", ")
+		}
+		s.transitionLocked(name, StateAlarm, reason, breaching, point.Value)
+	}
+	// Breaching, but not for long enough yet: keep the current state
+}
+
+func allBreaching(alarm Alarm, points []Datapoint) bool {
+	for _, point := range points {
+		if point.Missing || !compare(alarm.Comparison, point.Value, alarm.Threshold) {
+			return false
+		}
+	}
+	return true
+}
+
+// Change state, run the new state's actions and keep the transition.
+// Actions run in the background so slow webhooks never hold the lock.
+func (s *Service) transitionLocked(name, state, reason string, breaching []target, value float64) {
+	status := s.alarms[name]
+	if status.State == state {
+		status.Reason = reason
+		return
+	}
+
+	now := time.Now()
+	transition := Transition{Time: now, Alarm: name, From: status.State, To: state, Reason: reason}
+	status.State = state
+	status.Reason = reason
+	status.StateUpdated = now
+
+	notification := Notification{
+		Alarm:         name,
+		Description:   status.Description,
+		State:         state,
+		PreviousState: transition.From,
+		Reason:        reason,
+		Metric:        status.Metric,
+		Threshold:     status.Threshold,
+		Value:         value,
+		Time:          now,
+	}
+	for _, t := range breaching {
+		notification.Instances = append(notification.Instances, t.name)
+	}
+
+	var actions []Action
+	for _, action := range status.Actions {
+		if action.State == state {
+			actions = append(actions, action)
+		}
+	}
+
+	if s.events != nil {
+		s.events.Emit("localcloud.alarms", "Alarm State Change", []string{name}, notification)
+	}
+
+	s.history = append(s.history, transition)
+	if len(s.history) > historySize {
+		s.history = s.history[len(s.history)-historySize:]
+	}
+	if err := s.saveLocked(); err != nil {
+		log.Printf("alarms: %v", err)
+	}
+	if len(actions) == 0 {
+		return
+	}
+
+	index := len(s.history) - 1
+	go func() {
+		results := s.runActions(actions, notification, breaching)
+
+		// Attach the results unless the transition has been pushed out since
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i := min(index, len(s.history)-1); i >= 0; i-- {
+			if s.history[i].Alarm == name && s.history[i].Time.Equal(now) {
+				s.history[i].Actions = results
+				break
+			}
+		}
+		if err := s.saveLocked(); err != nil {
+			log.Printf("alarms: %v", err)
+		}
+	}()
+}
+
+func (s *Service) runActions(actions []Action, notification Notification, breaching []target) []ActionResult {
+	var results []ActionResult
+	for _, action := range actions {
+		switch action.Type {
+		case "log":
+			log.Printf("alarms: %s is %s (was %s): %s", notification.Alarm, notification.State, notification.PreviousState, notification.Reason)
+			results = append(results, ActionResult{Type: action.Type})
+
+		case "webhook":
+			err := s.notify(action.URL, notification)
+			if err != nil {
+				log.Printf("alarms: %s webhook failed: %v", notification.Alarm, err)
+			}
+			results = append(results, ActionResult{Type: action.Type, Target: action.URL, Error: errString(err)})
+
+		case "restart", "stop":
+			for _, t := range breaching {
+				var err error
+				if action.Type == "restart" {
+					err = s.manager.Restart(t.id)
+				} else {
+					err = s.manager.Stop(t.id)
+				}
+				if err != nil {
+					log.Printf("alarms: %s failed to %s %s: %v", notification.Alarm, action.Type, t.name, err)
+				} else {
+					log.Printf("alarms: %s: %s %s", notification.Alarm, action.Type, t.name)
+				}
+				results = append(results, ActionResult{Type: action.Type, Target: t.name, Error: errString(err)})
+			}
+		}
+	}
+	return results
+}
+
+func (s *Service) notify(endpoint string, notification Notification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	resp, err := s.client.Post(endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *Service) saveLocked() error {
+	if err := store.Save(filepath.Join(s.dir, "alarms.json"), s.alarms); err != nil {
+		return fmt.Errorf("failed to save alarms: %w", err)
+	}
+	if err := store.Save(filepath.Join(s.dir, "history.json"), s.history); err != nil {
+		return fmt.Errorf("failed to save alarm history: %w", err)
+	}
+	return nil
+}
+
+func validate(alarm *Alarm) error {
+	if !validName.MatchString(alarm.Name) {
+		return fmt.Errorf("%w: name must be 1-63 letters, digits, '.', '_' or '-'", ErrInvalidAlarm)
+	}
+	if _, ok := Metrics[alarm.Metric]; !ok {
+		return fmt.Errorf("%w: unknown metric %q (cpu, memory or memory_mb)", ErrInvalidAlarm, alarm.Metric)
+	}
+	if alarm.Comparison == "" {
+		alarm.Comparison = ">"
+	}
+	switch alarm.Comparison {
+	case ">", ">=", "<", "<=":
+	default:
+		return fmt.Errorf("%w: comparison must be >, >=, < or <=", ErrInvalidAlarm)
+	}
+	if alarm.Threshold < 0 {
+		return fmt.Errorf("%w: threshold must not be negative", ErrInvalidAlarm)
+	}
+	if alarm.Period == 0 {
+		alarm.Period = defaultPeriod
+	}
+	if alarm.Period < minPeriod {
+		return fmt.Errorf("%w: period must be at least %d seconds", ErrInvalidAlarm, minPeriod)
+	}
+	if alarm.EvaluationPeriods == 0 {
+		alarm.EvaluationPeriods = 1
+	}
+	if alarm.EvaluationPeriods < 1 || alarm.EvaluationPeriods > datapointsKept {
+		return fmt.Errorf("%w: evaluation periods must be between 1 and %d", ErrInvalidAlarm, datapointsKept)
+	}
+	if alarm.Project != "" && !compute.ValidProjectName(alarm.Project) {
+		return fmt.Errorf("%w: invalid project %q", ErrInvalidAlarm, alarm.Project)
+	}
+
+	for i := range alarm.Actions {
+		action := &alarm.Actions[i]
+		if action.State == "" {
+			action.State = StateAlarm
+		}
+		if action.State != StateOK && action.State != StateAlarm && action.State != StateInsufficientData {
+			return fmt.Errorf("%w: action state must be OK, ALARM or INSUFFICIENT_DATA", ErrInvalidAlarm)
+		}
+		switch action.Type {
+		case "log":
+		case "webhook":
+			u, err := url.Parse(action.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%w: webhook needs an http or https url", ErrInvalidAlarm)
+			}
+		case "restart", "stop":
+			if action.State != StateAlarm {
+				return fmt.Errorf("%w: %s actions only run on ALARM", ErrInvalidAlarm, action.Type)
+			}
+		default:
+			return fmt.Errorf("%w: unknown action %q (webhook, log, restart or stop)", ErrInvalidAlarm, action.Type)
+		}
+	}
+	return nil
+}
+
+func metricValue(metric string, m *compute.Metrics) (float64, bool) {
+	switch metric {
+	case "cpu":
+		return m.CPUPercent, true
+	case "memory":
+		if m.MemoryLimit == 0 {
+			return 0, false
+		}
+		return float64(m.MemoryUsage) / float64(m.MemoryLimit) * 100, true
+	case "memory_mb":
+		return float64(m.MemoryUsage) / (1 << 20), true
+	}
+	return 0, false
+}
+
+func compare(comparison string, value, threshold float64) bool {
+	switch comparison {
+	case ">":
+		return value > threshold
+	case ">=":
+		return value >= threshold
+	case "<":
+		return value < threshold
+	case "<=":
+		return value <= threshold
+	}
+	return false
+}
+
+// Whether value is further towards breaching than current
+func worse(comparison string, value, current float64) bool {
+	if strings.HasPrefix(comparison, "<") {
+		return value < current
+	}
+	return value > current
+}
+
+func formatValue(value float64) string {
+	return fmt.Sprintf("%.1f", value)
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
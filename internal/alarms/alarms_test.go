@@ -0,0 +1,275 @@
+package alarms
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types"
+)
+
+func newTestService(t *testing.T) (*Service, *dockertest.Server, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s, err := NewService(manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, docker, dir
+}
+
+// A running instance whose stats report memoryMB in use
+func addInstance(docker *dockertest.Server, name string, memoryMB uint64) string {
+	id := docker.AddContainer(dockertest.Container{Name: name, Image: "nginx"})
+	docker.Handle("GET /containers/"+id+"/stats", types.StatsJSON{Stats: types.Stats{
+		MemoryStats: types.MemoryStats{Usage: memoryMB << 20, Limit: 1 << 30},
+	}})
+	return id
+}
+
+// Webhook endpoint recording the notifications it gets
+type receiver struct {
+	*httptest.Server
+	mu            sync.Mutex
+	notifications []Notification
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var n Notification
+		json.NewDecoder(req.Body).Decode(&n)
+		r.mu.Lock()
+		r.notifications = append(r.notifications, n)
+		r.mu.Unlock()
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// Wait for the actions of the newest transition to finish
+func waitActions(t *testing.T, s *Service, name string) Transition {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		history, _ := s.History(name)
+		if len(history) > 0 && len(history[0].Actions) > 0 {
+			return history[0]
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("actions of %s did not run: %+v", name, history)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	s, _, _ := newTestService(t)
+	tests := []struct {
+		alarm Alarm
+		err   string
+	}{
+		{Alarm{Name: "-bad", Metric: "cpu"}, "name"},
+		{Alarm{Name: "a", Metric: "disk"}, "unknown metric"},
+		{Alarm{Name: "a", Metric: "cpu", Comparison: "=="}, "comparison"},
+		{Alarm{Name: "a", Metric: "cpu", Threshold: -1}, "threshold"},
+		{Alarm{Name: "a", Metric: "cpu", Period: 5}, "period"},
+		{Alarm{Name: "a", Metric: "cpu", EvaluationPeriods: datapointsKept + 1}, "evaluation periods"},
+		{Alarm{Name: "a", Metric: "cpu", Project: "Team"}, "project"},
+		{Alarm{Name: "a", Metric: "cpu", Actions: []Action{{Type: "webhook", URL: "ftp://host"}}}, "webhook"},
+		{Alarm{Name: "a", Metric: "cpu", Actions: []Action{{Type: "stop", State: StateOK}}}, "only run on ALARM"},
+		{Alarm{Name: "a", Metric: "cpu", Actions: []Action{{Type: "page"}}}, "unknown action"},
+	}
+	for _, tt := range tests {
+		if _, err := s.Create(tt.alarm); !errors.Is(err, ErrInvalidAlarm) || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Create(%+v) = %v, want %q", tt.alarm, err, tt.err)
+		}
+	}
+
+	status, err := s.Create(Alarm{Name: "busy", Metric: "cpu", Threshold: 80, Actions: []Action{{Type: "log"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Comparison != ">" || status.Period != defaultPeriod || status.EvaluationPeriods != 1 ||
+		status.Actions[0].State != StateAlarm || status.State != StateInsufficientData {
+		t.Errorf("defaults = %+v", status)
+	}
+	if _, err := s.Create(Alarm{Name: "busy", Metric: "cpu"}); !errors.Is(err, ErrAlarmExists) {
+		t.Errorf("creating twice: %v", err)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	webhook := newReceiver(t)
+	web := addInstance(docker, "web", 600)
+	addInstance(docker, "db", 100)
+
+	_, err := s.Create(Alarm{
+		Name:              "memory",
+		Metric:            "memory_mb",
+		Threshold:         500,
+		Period:            minPeriod,
+		EvaluationPeriods: 2,
+		Actions:           []Action{{Type: "webhook", URL: webhook.URL}, {Type: "stop"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// one breaching sample is not enough
+	start := time.Now()
+	s.evaluateAll(start)
+	if status, _ := s.Get("memory"); status.State != StateInsufficientData || len(status.Datapoints) != 1 || status.Datapoints[0].Value != 600 {
+		t.Fatalf("after one sample: %+v", status)
+	}
+	// and samples only come once a period
+	s.evaluateAll(start.Add(time.Second))
+	if status, _ := s.Get("memory"); len(status.Datapoints) != 1 {
+		t.Fatalf("sampled early: %+v", status.Datapoints)
+	}
+
+	s.evaluateAll(start.Add(minPeriod * time.Second))
+	status, _ := s.Get("memory")
+	if status.State != StateAlarm || !strings.HasSuffix(status.Reason, "on web") {
+		t.Fatalf("after two samples: %+v", status)
+	}
+	transition := waitActions(t, s, "memory")
+	if transition.From != StateInsufficientData || len(transition.Actions) != 2 || transition.Actions[1].Target != "web" || transition.Actions[1].Error != "" {
+		t.Errorf("transition = %+v", transition)
+	}
+	webhook.mu.Lock()
+	if len(webhook.notifications) != 1 || webhook.notifications[0].State != StateAlarm || webhook.notifications[0].Instances[0] != "web" {
+		t.Errorf("notifications = %+v", webhook.notifications)
+	}
+	webhook.mu.Unlock()
+	for _, c := range docker.Containers() {
+		if c.ID == web && c.State != "exited" {
+			t.Error("breaching instance not stopped")
+		}
+	}
+
+	// with web stopped, only db is left and it is under the threshold
+	s.evaluateAll(start.Add(2 * minPeriod * time.Second))
+	if status, _ := s.Get("memory"); status.State != StateOK {
+		t.Errorf("after stopping web: %+v", status)
+	}
+}
+
+func TestSetStateAndHistory(t *testing.T) {
+	s, _, dir := newTestService(t)
+	if _, err := s.Create(Alarm{Name: "a", Metric: "cpu", Actions: []Action{{Type: "log"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(Alarm{Name: "b", Metric: "cpu"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.SetState("a", "BROKEN", ""); !errors.Is(err, ErrInvalidAlarm) {
+		t.Errorf("invalid state: %v", err)
+	}
+	status, err := s.SetState("a", StateAlarm, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.State != StateAlarm || status.Reason != "state set manually" {
+		t.Errorf("status = %+v", status)
+	}
+	if transition := waitActions(t, s, "a"); transition.Actions[0].Type != "log" {
+		t.Errorf("transition = %+v", transition)
+	}
+	s.SetState("b", StateOK, "testing")
+	s.SetState("a", StateOK, "")
+
+	history, _ := s.History("")
+	if len(history) != 3 || history[0].Alarm != "a" || history[1].Alarm != "b" || history[1].Reason != "testing" {
+		t.Errorf("history = %+v", history)
+	}
+	if history, _ := s.History("a"); len(history) != 2 || history[0].To != StateOK {
+		t.Errorf("history of a = %+v", history)
+	}
+
+	// alarms and history survive a restart; deleting an alarm leaves its history
+	reopened, err := NewService(s.manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reopened.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if list := reopened.List(); len(list) != 1 || list[0].State != StateOK {
+		t.Errorf("alarms = %+v", list)
+	}
+	if history, _ := reopened.History(""); len(history) != 3 {
+		t.Errorf("history after restart = %+v", history)
+	}
+	if _, err := reopened.History("b"); !errors.Is(err, ErrAlarmNotFound) {
+		t.Errorf("history of a deleted alarm: %v", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		comparison       string
+		value, threshold float64
+		breaching        bool
+		worse            bool // than 50
+	}{
+		{">", 90, 80, true, true},
+		{">", 80, 80, false, true},
+		{">=", 80, 80, true, true},
+		{">", 40, 80, false, false},
+		{"<", 10, 20, true, true},
+		{"<=", 20, 20, true, true},
+		{"<", 60, 20, false, false},
+	}
+	for _, tt := range tests {
+		if got := compare(tt.comparison, tt.value, tt.threshold); got != tt.breaching {
+			t.Errorf("compare(%s, %g, %g) = %v", tt.comparison, tt.value, tt.threshold, got)
+		}
+		if got := worse(tt.comparison, tt.value, 50); got != tt.worse {
+			t.Errorf("worse(%s, %g, 50) = %v", tt.comparison, tt.value, got)
+		}
+	}
+}
+
+type publisher struct {
+	mu     sync.Mutex
+	events []Notification
+}
+
+func (p *publisher) Emit(source, detailType string, resources []string, detail interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, detail.(Notification))
+}
+
+func TestStateChangesArePublished(t *testing.T) {
+	s, _, _ := newTestService(t)
+	events := &publisher{}
+	s.UseEvents(events)
+	if _, err := s.Create(Alarm{Name: "a", Metric: "cpu"}); err != nil {
+		t.Fatal(err)
+	}
+	s.SetState("a", StateAlarm, "testing")
+	s.SetState("a", StateAlarm, "still testing")
+
+	events.mu.Lock()
+	defer events.mu.Unlock()
+	if len(events.events) != 1 || events.events[0].State != StateAlarm || events.events[0].PreviousState != StateInsufficientData {
+		t.Errorf("published %+v", events.events)
+	}
+}
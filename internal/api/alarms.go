@@ -0,0 +1,130 @@
+// Metric alarm handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"localcloud/internal/alarms"
+
+	"github.com/gin-gonic/gin"
+)
+
+func alarmErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, alarms.ErrAlarmNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, alarms.ErrAlarmExists):
+		return http.StatusConflict
+	case errors.Is(err, alarms.ErrInvalidAlarm):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) listAlarms(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.alarms.List(),
+	})
+}
+
+func (s *Server) createAlarm(c *gin.Context) {
+	var req alarms.Alarm
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	alarm, err := s.alarms.Create(req)
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    alarm,
+	})
+}
+
+func (s *Server) getAlarm(c *gin.Context) {
+	alarm, err := s.alarms.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    alarm,
+	})
+}
+
+func (s *Server) deleteAlarm(c *gin.Context) {
+	if err := s.alarms.Delete(c.Param("name")); err != nil {
+		c.JSON(alarmErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) getAlarmHistory(c *gin.Context) {
+	history, err := s.alarms.History(c.Param("name"))
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    history,
+	})
+}
+
+// Force a state to try out the alarm's actions
+func (s *Server) setAlarmState(c *gin.Context) {
+	var req struct {
+		State  string `json:"state" binding:"required"`
+		Reason string `json:"reason"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	alarm, err := s.alarms.SetState(c.Param("name"), req.State, req.Reason)
+	if err != nil {
+		c.JSON(alarmErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    alarm,
+	})
+}
@@ -0,0 +1,63 @@
+// API token authentication, on when LOCALCLOUD_API_TOKEN is set
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Holds the token for the dashboard, whose pages call the API from the browser
+const tokenCookie = "localcloud_token"
+
+// Require the API token from every request. API clients send it as a
+// bearer token; the dashboard is opened once with ?token= and keeps it in a
+// cookie. AWS SDKs can't send either, so the SQS endpoint checks their
+// Signature V4, made with the token as the secret key, itself.
+func (s *Server) authenticate(c *gin.Context) {
+	if s.config.APIToken == "" || c.Request.URL.Path == "/sqs" || strings.HasPrefix(c.Request.URL.Path, "/sqs/") {
+		c.Next()
+		return
+	}
+
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && s.validToken(token) {
+		c.Next()
+		return
+	}
+	if token, err := c.Cookie(tokenCookie); err == nil && s.validToken(token) {
+		c.Next()
+		return
+	}
+
+	page := c.Request.URL.Path != "/ws" && !strings.HasPrefix(c.Request.URL.Path, "/api/")
+	if token := c.Query("token"); page && s.validToken(token) {
+		c.SetSameSite(http.SameSiteStrictMode)
+		c.SetCookie(tokenCookie, token, 0, "/", "", c.Request.TLS != nil, true)
+		query := c.Request.URL.Query()
+		query.Del("token")
+		target := c.Request.URL.Path
+		if encoded := query.Encode(); encoded != "" {
+			target += "?" + encoded
+		}
+		c.Redirect(http.StatusFound, target)
+		c.Abort()
+		return
+	}
+
+	if page {
+		c.Data(http.StatusUnauthorized, "text/html; charset=utf-8",
+			[]byte(`<!DOCTYPE html><p>This LocalCloud needs its API token: open this page with <code>?token=&lt;token&gt;</code>.</p>`))
+		c.Abort()
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusUnauthorized, Response{
+		Success: false,
+		Error:   "missing or invalid API token",
+	})
+}
+
+func (s *Server) validToken(token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.config.APIToken)) == 1
+}
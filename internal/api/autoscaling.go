@@ -0,0 +1,134 @@
+// Auto scaling group handlers
+package api
+
+import (
+	"net/http"
+
+	"localcloud/internal/autoscaling"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listScalingGroups(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.scaling.List(),
+	})
+}
+
+func (s *Server) createScalingGroup(c *gin.Context) {
+	var req autoscaling.Group
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	group, err := s.scaling.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    group,
+	})
+}
+
+func (s *Server) getScalingGroup(c *gin.Context) {
+	group, err := s.scaling.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    group,
+	})
+}
+
+func (s *Server) setScalingCapacity(c *gin.Context) {
+	// omitted fields keep their current value
+	var req struct {
+		Min     *int `json:"min"`
+		Max     *int `json:"max"`
+		Desired *int `json:"desired"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	group, err := s.scaling.SetCapacity(c.Param("name"), req.Min, req.Max, req.Desired)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    group,
+	})
+}
+
+func (s *Server) deleteScalingGroup(c *gin.Context) {
+	if err := s.scaling.Delete(c.Param("name")); err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) getScalingActivities(c *gin.Context) {
+	activities, err := s.scaling.Activities(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    activities,
+	})
+}
+
+func (s *Server) getScalingSamples(c *gin.Context) {
+	samples, err := s.scaling.Samples(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    samples,
+	})
+}
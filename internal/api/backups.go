@@ -0,0 +1,197 @@
+// Volume backup handlers
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"localcloud/internal/backups"
+	"localcloud/internal/operations"
+
+	"github.com/gin-gonic/gin"
+)
+
+func backupErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, backups.ErrBackupNotFound), errors.Is(err, backups.ErrPlanNotFound), errors.Is(err, backups.ErrVolumeNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, backups.ErrPlanExists), errors.Is(err, backups.ErrVolumeExists), errors.Is(err, backups.ErrVolumeBusy):
+		return http.StatusConflict
+	case errors.Is(err, backups.ErrChecksumMismatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, backups.ErrInvalidVolume):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) listBackups(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.backups.List(c.Query("volume")),
+	})
+}
+
+func (s *Server) createBackup(c *gin.Context) {
+	var req backups.CreateInput
+	if err := c.ShouldBindJSON(&req); err != nil || req.Volume == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	s.runOperation(c, "backup.create", req.Volume, http.StatusCreated, backupErrorStatus,
+		func(ctx context.Context, progress operations.Progress) (interface{}, error) {
+			progress("archiving volume", 10)
+			return s.backups.Create(req)
+		})
+}
+
+func (s *Server) getBackup(c *gin.Context) {
+	backup, err := s.backups.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(backupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    backup,
+	})
+}
+
+func (s *Server) deleteBackup(c *gin.Context) {
+	if err := s.backups.Delete(c.Param("id")); err != nil {
+		c.JSON(backupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) restoreBackup(c *gin.Context) {
+	var req backups.RestoreInput
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid request format",
+			})
+			return
+		}
+	}
+	if req.Project == "" {
+		req.Project = c.GetHeader("X-LocalCloud-Project")
+	}
+
+	id := c.Param("id")
+	s.runOperation(c, "backup.restore", id, http.StatusOK, backupErrorStatus,
+		func(ctx context.Context, progress operations.Progress) (interface{}, error) {
+			progress("restoring volume", 10)
+			return s.backups.Restore(id, req)
+		})
+}
+
+func (s *Server) verifyBackup(c *gin.Context) {
+	id := c.Param("id")
+	s.runOperation(c, "backup.verify", id, http.StatusOK, backupErrorStatus,
+		func(ctx context.Context, progress operations.Progress) (interface{}, error) {
+			progress("verifying archive", 10)
+			return s.backups.Verify(id)
+		})
+}
+
+func (s *Server) pruneBackups(c *gin.Context) {
+	var req backups.PruneInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	removed, err := s.backups.Prune(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    removed,
+	})
+}
+
+func (s *Server) listBackupPlans(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.backups.ListPlans(),
+	})
+}
+
+func (s *Server) createBackupPlan(c *gin.Context) {
+	var req backups.Plan
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	plan, err := s.backups.CreatePlan(req)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, backups.ErrPlanExists) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    plan,
+	})
+}
+
+func (s *Server) deleteBackupPlan(c *gin.Context) {
+	if err := s.backups.DeletePlan(c.Param("name")); err != nil {
+		c.JSON(backupErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) runBackupPlan(c *gin.Context) {
+	name := c.Param("name")
+	s.runOperation(c, "backup-plan.run", name, http.StatusCreated, backupErrorStatus,
+		func(ctx context.Context, progress operations.Progress) (interface{}, error) {
+			progress("archiving volume", 10)
+			return s.backups.RunPlan(name)
+		})
+}
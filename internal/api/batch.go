@@ -0,0 +1,217 @@
+// Batch job handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"localcloud/internal/batch"
+
+	"github.com/gin-gonic/gin"
+)
+
+func batchErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, batch.ErrJobNotFound), errors.Is(err, batch.ErrQueueNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, batch.ErrQueueExists), errors.Is(err, batch.ErrQueueInUse), errors.Is(err, batch.ErrJobFinished):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) listJobs(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.batch.List(c.Query("queue"), c.Query("status")),
+	})
+}
+
+func (s *Server) submitJob(c *gin.Context) {
+	var req batch.SubmitInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	job, err := s.batch.Submit(req)
+	if err != nil {
+		c.JSON(batchErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    job,
+	})
+}
+
+func (s *Server) getJob(c *gin.Context) {
+	job, err := s.batch.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(batchErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    job,
+	})
+}
+
+func (s *Server) listJobChildren(c *gin.Context) {
+	children, err := s.batch.Children(c.Param("id"))
+	if err != nil {
+		c.JSON(batchErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    children,
+	})
+}
+
+func (s *Server) cancelJob(c *gin.Context) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid request format",
+			})
+			return
+		}
+	}
+
+	job, err := s.batch.Cancel(c.Param("id"), req.Reason)
+	if err != nil {
+		c.JSON(batchErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    job,
+	})
+}
+
+func (s *Server) getJobLogs(c *gin.Context) {
+	attempt := 0
+	if attemptParam := c.Query("attempt"); attemptParam != "" {
+		parsed, err := strconv.Atoi(attemptParam)
+		if err != nil || parsed < 1 {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "attempt must be a positive number",
+			})
+			return
+		}
+		attempt = parsed
+	}
+
+	logs, err := s.batch.Logs(c.Param("id"), attempt)
+	if err != nil {
+		c.JSON(batchErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    gin.H{"logs": logs},
+	})
+}
+
+func (s *Server) listJobQueues(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.batch.ListQueues(),
+	})
+}
+
+func (s *Server) createJobQueue(c *gin.Context) {
+	var req batch.Queue
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	queue, err := s.batch.CreateQueue(req)
+	if err != nil {
+		c.JSON(batchErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    queue,
+	})
+}
+
+func (s *Server) updateJobQueue(c *gin.Context) {
+	var req struct {
+		MaxParallel int `json:"max_parallel"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	queue, err := s.batch.SetMaxParallel(c.Param("name"), req.MaxParallel)
+	if err != nil {
+		c.JSON(batchErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    queue,
+	})
+}
+
+func (s *Server) deleteJobQueue(c *gin.Context) {
+	if err := s.batch.DeleteQueue(c.Param("name")); err != nil {
+		c.JSON(batchErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
@@ -0,0 +1,129 @@
+// Billing handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"localcloud/internal/billing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func billingErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, billing.ErrBudgetNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, billing.ErrBudgetExists):
+		return http.StatusConflict
+	case errors.Is(err, billing.ErrInvalidInput):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) getBillingPrices(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.billing.Prices(),
+	})
+}
+
+func (s *Server) setBillingPrices(c *gin.Context) {
+	var prices billing.PriceSheet
+	if err := c.ShouldBindJSON(&prices); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.billing.SetPrices(prices); err != nil {
+		c.JSON(billingErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.billing.Prices(),
+	})
+}
+
+// GET /billing/report?by=project|tag|resource|day&tag_key=&from=&to=
+func (s *Server) getBillingReport(c *gin.Context) {
+	report, err := s.billing.Report(billing.ReportInput{
+		By:     c.Query("by"),
+		TagKey: c.Query("tag_key"),
+		From:   c.Query("from"),
+		To:     c.Query("to"),
+	})
+	if err != nil {
+		c.JSON(billingErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
+func (s *Server) listBudgets(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.billing.ListBudgets(),
+	})
+}
+
+func (s *Server) createBudget(c *gin.Context) {
+	var req billing.Budget
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	budget, err := s.billing.CreateBudget(req)
+	if err != nil {
+		c.JSON(billingErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    budget,
+	})
+}
+
+func (s *Server) deleteBudget(c *gin.Context) {
+	if err := s.billing.DeleteBudget(c.Param("name")); err != nil {
+		c.JSON(billingErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) listBudgetAlerts(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.billing.Alerts(),
+	})
+}
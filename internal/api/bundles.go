@@ -0,0 +1,77 @@
+// Environment bundle handlers
+package api
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"localcloud/internal/bundles"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Stream a gzipped bundle of the environment
+func (s *Server) exportBundle(c *gin.Context) {
+	var req bundles.Options
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid request format",
+			})
+			return
+		}
+	}
+
+	bundle, err := s.bundles.Export(req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, bundles.ErrInstanceNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer bundle.Close()
+
+	filename := "localcloud-bundle-" + time.Now().Format("20060102-150405") + ".tar.gz"
+	c.Header("Content-Type", "application/gzip")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+	if err := bundle.Stream(c.Writer); err != nil {
+		// headers are already sent, so the client sees a truncated archive
+		log.Printf("Failed to stream bundle: %v", err)
+	}
+}
+
+// Recreate an environment from a bundle in the request body
+func (s *Server) importBundle(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+	opts := bundles.ImportOptions{DryRun: dryRun, OnConflict: c.Query("on_conflict")}
+
+	report, err := s.bundles.Import(c.Request.Body, opts)
+	if err != nil {
+		status := http.StatusBadRequest
+		if report != nil {
+			// failed partway, report what was done
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, Response{
+			Success: false,
+			Data:    report,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
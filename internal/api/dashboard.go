@@ -3,11 +3,42 @@ package api
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func (s *Server) handleDashboard(c *gin.Context) {
+// Pages linked from the dashboard header
+var dashboardPages = []struct {
+	Path  string
+	Title string
+}{
+	{"/", "Containers"},
+	{"/autoscaling", "Auto Scaling"},
+	{"/loadbalancers", "Load Balancers"},
+	{"/functions", "Functions"},
+	{"/databases", "Databases"},
+	{"/queues", "Queues"},
+	{"/secrets", "Secrets"},
+	{"/parameters", "Parameters"},
+	{"/snapshots", "Snapshots"},
+	{"/schedules", "Schedules"},
+	{"/billing", "Billing"},
+	{"/alarms", "Alarms"},
+	{"/api-docs", "API"},
+}
+
+// Wrap page content in the shared head, header and navigation
+func renderPage(c *gin.Context, active, content string) {
+	var nav strings.Builder
+	for _, page := range dashboardPages {
+		class := "text-gray-600 hover:text-gray-900"
+		if page.Path == active {
+			class = "text-blue-600 font-semibold border-b-2 border-blue-600"
+		}
+		nav.WriteString(`<a href="` + page.Path + `" class="pb-1 ` + class + `">` + page.Title + `</a>`)
+	}
+
 	html := `<!DOCTYPE html>
 <html lang="en">
 <head>
@@ -15,37 +46,61 @@ func (s *Server) handleDashboard(c *gin.Context) {
     <meta name="viewport" content="width=device-width, initial-scale=1.0">
     <title>LocalCloud</title>
     <script src="https://cdn.tailwindcss.com"></script>
+    <script src="https://cdn.jsdelivr.net/npm/chart.js"></script>
     <style>
         .status-running { color: #10b981; }
         .status-exited { color: #ef4444; }
+        .status-pending { color: #f59e0b; }
+        .health-healthy { color: #10b981; }
+        .health-unhealthy { color: #ef4444; }
+        .health-starting { color: #f59e0b; }
         .live-dot { animation: pulse 2s infinite; }
     </style>
 </head>
 <body class="bg-gray-50 min-h-screen">
-    <div class="container mx-auto px-4 py-8">
-        <div class="flex justify-between items-center mb-8">
+    <div class="container mx-auto px-4 pt-8">
+        <div class="flex justify-between items-center mb-4">
             <h1 class="text-3xl font-bold text-gray-900">LocalCloud Dashboard</h1>
             <div class="flex items-center space-x-2">
                 <div class="live-dot w-3 h-3 bg-green-500 rounded-full"></div>
                 <span class="text-sm text-gray-600">Live</span>
             </div>
         </div>
+        <nav class="flex space-x-6 mb-8 border-b">` + nav.String() + `</nav>
+    </div>
+` + content + `
+</body>
+</html>`
+
+	c.Header("Content-Type", "text/html")
+	c.String(http.StatusOK, html)
+}
 
-        <!-- Create Container Form -->
+func (s *Server) handleDashboard(c *gin.Context) {
+	renderPage(c, "/", containersPage)
+}
+
+const containersPage = `    <div class="container mx-auto px-4 pb-8">
+    <!-- Create Container Form -->
         <div class="bg-white rounded-lg shadow mb-6 p-6">
             <h2 class="text-xl font-semibold mb-4">Create New Container</h2>
-            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+            <div class="grid grid-cols-1 md:grid-cols-5 gap-4">
                 <input id="imageInput" type="text" placeholder="Image (e.g., nginx:latest)" 
                        class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
                 <input id="nameInput" type="text" placeholder="Name (optional)" 
                        class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
-                <input id="portsInput" type="text" placeholder="Ports (e.g., 80:80)" 
+                <input id="portsInput" type="text" placeholder="Ports (e.g., auto:80 or 8080:80)" 
                        class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <select id="typeInput"
+                        class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <option value="">No size limit</option>
+                </select>
                 <button onclick="createContainer()" 
                         class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
                     Create
                 </button>
             </div>
+            <div id="createProgress" class="hidden mt-3 text-sm text-gray-600"></div>
         </div>
 
         <!-- Containers Table -->
@@ -60,7 +115,9 @@ func (s *Server) handleDashboard(c *gin.Context) {
                             <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">ID</th>
                             <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
                             <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Image</th>
+                            <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Type</th>
                             <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Status</th>
+                            <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Health</th>
                             <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Ports</th>
                             <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Uptime</th>
                             <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
@@ -88,6 +145,34 @@ func (s *Server) handleDashboard(c *gin.Context) {
         </div>
     </div>
 
+    <!-- Health -->
+    <div id="healthModal" class="fixed inset-0 bg-black bg-opacity-50 hidden items-center justify-center z-50">
+        <div class="bg-white rounded-lg p-6 max-w-3xl w-full mx-4 max-h-[80vh] flex flex-col">
+            <div class="flex justify-between items-center mb-4">
+                <h3 class="text-lg font-semibold">Health Checks <span id="healthStatus" class="ml-2 text-sm"></span></h3>
+                <button onclick="closeHealthModal()" class="text-gray-400 hover:text-gray-600">
+                    <svg class="w-6 h-6" fill="none" stroke="currentColor" viewBox="0 0 24 24">
+                        <path stroke-linecap="round" stroke-linejoin="round" stroke-width="2" d="M6 18L18 6M6 6l12 12"></path>
+                    </svg>
+                </button>
+            </div>
+            <div class="overflow-auto flex-1">
+                <table class="min-w-full text-sm">
+                    <thead>
+                        <tr class="text-left text-gray-500">
+                            <th class="py-2 pr-4">Time</th>
+                            <th class="py-2 pr-4">Result</th>
+                            <th class="py-2 pr-4">Duration</th>
+                            <th class="py-2 pr-4">Output</th>
+                            <th class="py-2">Action</th>
+                        </tr>
+                    </thead>
+                    <tbody id="healthHistory" class="divide-y divide-gray-200"></tbody>
+                </table>
+            </div>
+        </div>
+    </div>
+
     <!-- Metrics -->
     <div id="metricsModal" class="fixed inset-0 bg-black bg-opacity-50 hidden items-center justify-center z-50">
         <div class="bg-white rounded-lg p-6 max-w-2xl w-full mx-4">
@@ -131,7 +216,12 @@ func (s *Server) handleDashboard(c *gin.Context) {
             
             ws.onmessage = function(event) {
                 const data = JSON.parse(event.data);
-                updateContainerTable(data.containers || []);
+                if (data.operation) {
+                    showCreateProgress(data.operation);
+                    return;
+                }
+                if (!data.containers) return; // other notifications, e.g. parameter changes
+                updateContainerTable(data.containers);
             };
             
             ws.onclose = function() {
@@ -151,7 +241,9 @@ func (s *Server) handleDashboard(c *gin.Context) {
                     <td class="px-6 py-4 text-sm font-mono text-gray-500">${container.id.substring(0, 12)}</td>
                     <td class="px-6 py-4 text-sm text-gray-900">${container.name}</td>
                     <td class="px-6 py-4 text-sm text-gray-500">${container.image}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${container.instance_type || '-'}</td>
                     <td class="px-6 py-4 text-sm ${statusClass}">${container.status}</td>
+                    <td class="px-6 py-4 text-sm health-${container.health || 'none'}">${container.health || '-'}</td>
                     <td class="px-6 py-4 text-sm text-gray-500">${container.ports || '-'}</td>
                     <td class="px-6 py-4 text-sm text-gray-500">${container.uptime || '-'}</td>
                     <td class="px-6 py-4 text-sm space-x-2">
@@ -159,6 +251,14 @@ func (s *Server) handleDashboard(c *gin.Context) {
                                 class="text-blue-600 hover:text-blue-900">Logs</button>
                         <button onclick="viewMetrics('${container.id}')" 
                                 class="text-green-600 hover:text-green-900">Metrics</button>
+                        ${container.health ? ` + "`" + `<button onclick="viewHealth('${container.id}')" 
+                                class="text-yellow-600 hover:text-yellow-900">Health</button>` + "`" + ` : ''}
+                        <button onclick="resizeContainer('${container.id}', '${container.instance_type || ''}')"
+                                class="text-indigo-600 hover:text-indigo-900">Resize</button>
+                        <a href="/files?id=${container.id}"
+                                class="text-gray-600 hover:text-gray-900">Files</a>
+                        <button onclick="createSnapshot('${container.id}', '${container.name}')"
+                                class="text-purple-600 hover:text-purple-900">Create snapshot</button>
                         <button onclick="deleteContainer('${container.id}')" 
                                 class="text-red-600 hover:text-red-900">Delete</button>
                     </td>
@@ -171,12 +271,14 @@ func (s *Server) handleDashboard(c *gin.Context) {
             const image = document.getElementById('imageInput').value || 'nginx:latest';
             const name = document.getElementById('nameInput').value;
             const ports = document.getElementById('portsInput').value;
+            const instance_type = document.getElementById('typeInput').value;
 
             try {
-                const response = await fetch('/api/v1/containers', {
+                // Pulling an image can take minutes, so follow it as an operation
+                const response = await fetch('/api/v1/containers?async=true', {
                     method: 'POST',
                     headers: { 'Content-Type': 'application/json' },
-                    body: JSON.stringify({ image, name, ports })
+                    body: JSON.stringify({ image, name, ports, instance_type })
                 });
                 
                 const result = await response.json();
@@ -184,6 +286,12 @@ func (s *Server) handleDashboard(c *gin.Context) {
                     document.getElementById('imageInput').value = '';
                     document.getElementById('nameInput').value = '';
                     document.getElementById('portsInput').value = '';
+                    createOperation = result.data.id;
+                    showCreateProgress(result.data);
+
+                    // Updates sent before we knew the ID were missed
+                    const current = await (await fetch('/api/v1/operations/' + createOperation)).json();
+                    if (current.success) showCreateProgress(current.data);
                 } else {
                     alert('Error: ' + result.error);
                 }
@@ -192,6 +300,89 @@ func (s *Server) handleDashboard(c *gin.Context) {
             }
         }
 
+        let createOperation = null;
+
+        function showCreateProgress(op) {
+            if (op.id !== createOperation) return;
+            const el = document.getElementById('createProgress');
+            el.classList.remove('hidden');
+
+            if (op.status === 'running') {
+                el.innerHTML = ` + "`" + `${op.target}: ${op.step || 'starting'} (${op.progress}%)
+                    <button onclick="cancelOperation('${op.id}')" class="ml-2 text-red-600 hover:text-red-900">Cancel</button>` + "`" + `;
+                return;
+            }
+            createOperation = null;
+            if (op.status === 'succeeded') {
+                el.classList.add('hidden');
+            } else {
+                el.textContent = op.target + ': ' + op.status + (op.error && op.status === 'failed' ? ' - ' + op.error : '');
+            }
+        }
+
+        async function cancelOperation(id) {
+            const response = await fetch('/api/v1/operations/' + id + '/cancel', { method: 'POST' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+        }
+
+        let instanceTypes = [];
+
+        async function loadInstanceTypes() {
+            const response = await fetch('/api/v1/instance-types');
+            const result = await response.json();
+            if (!result.success) return;
+
+            instanceTypes = result.data || [];
+            const select = document.getElementById('typeInput');
+            instanceTypes.forEach(type => {
+                const option = document.createElement('option');
+                option.value = type.name;
+                option.textContent = ` + "`" + `${type.name} (${type.cpus} CPU, ${type.memory_mb} MB)` + "`" + `;
+                select.appendChild(option);
+            });
+        }
+
+        async function resizeContainer(id, current) {
+            const names = instanceTypes.map(type => type.name);
+            const type = prompt('New instance type (' + names.join(', ') + '):', current);
+            if (!type || type === current) return;
+
+            const response = await fetch('/api/v1/containers/' + id + '/resize', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ instance_type: type })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+        }
+
+        async function createSnapshot(id, name) {
+            const snapshot = prompt('Snapshot name for ' + name + ':', name + '-' + new Date().toISOString().slice(0, 10));
+            if (!snapshot) return;
+            const description = prompt('Description (optional):') || '';
+
+            try {
+                const response = await fetch('/api/v1/snapshots', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify({ instance_id: id, name: snapshot, description })
+                });
+                const result = await response.json();
+                if (!result.success) {
+                    alert('Error: ' + result.error);
+                    return;
+                }
+                alert('Created snapshot ' + result.data.name);
+            } catch (error) {
+                alert('Error creating snapshot: ' + error.message);
+            }
+        }
+
         async function deleteContainer(id) {
             if (!confirm('Are you sure you want to delete this container?')) return;
             
@@ -247,6 +438,46 @@ func (s *Server) handleDashboard(c *gin.Context) {
             }
         }
 
+        async function viewHealth(id) {
+            try {
+                const response = await fetch('/api/v1/containers/' + id + '/health');
+                const result = await response.json();
+
+                if (result.success) {
+                    const report = result.data;
+                    const status = document.getElementById('healthStatus');
+                    status.textContent = report.status + (report.failing_streak ? ' (' + report.failing_streak + ' failing)' : '');
+                    status.className = 'ml-2 text-sm health-' + report.status;
+
+                    const tbody = document.getElementById('healthHistory');
+                    tbody.innerHTML = '';
+                    report.history.slice().reverse().forEach(check => {
+                        const row = document.createElement('tr');
+                        row.innerHTML = ` + "`" + `
+                            <td class="py-2 pr-4 text-gray-500">${new Date(check.time).toLocaleTimeString()}</td>
+                            <td class="py-2 pr-4 ${check.healthy ? 'health-healthy' : 'health-unhealthy'}">${check.healthy ? 'pass' : 'fail'}</td>
+                            <td class="py-2 pr-4 text-gray-500">${check.duration_ms} ms</td>
+                            <td class="py-2 pr-4 font-mono text-xs text-gray-700">${check.output || ''}</td>
+                            <td class="py-2 text-gray-700">${check.action || ''}</td>
+                        ` + "`" + `;
+                        tbody.appendChild(row);
+                    });
+
+                    document.getElementById('healthModal').classList.remove('hidden');
+                    document.getElementById('healthModal').classList.add('flex');
+                } else {
+                    alert('Error: ' + result.error);
+                }
+            } catch (error) {
+                alert('Error fetching health: ' + error.message);
+            }
+        }
+
+        function closeHealthModal() {
+            document.getElementById('healthModal').classList.add('hidden');
+            document.getElementById('healthModal').classList.remove('flex');
+        }
+
         function closeMetricsModal() {
             document.getElementById('metricsModal').classList.add('hidden');
             document.getElementById('metricsModal').classList.remove('flex');
@@ -267,6 +498,7 @@ func (s *Server) handleDashboard(c *gin.Context) {
 
         // Initialize
         connectWebSocket();
+        loadInstanceTypes();
         
         // Load initial data
         fetch('/api/v1/containers')
@@ -277,9 +509,4 @@ func (s *Server) handleDashboard(c *gin.Context) {
                 }
             });
     </script>
-</body>
-</html>`
-
-	c.Header("Content-Type", "text/html")
-	c.String(http.StatusOK, html)
-}
+`
@@ -0,0 +1,276 @@
+// Metric alarms page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleAlarmsDashboard(c *gin.Context) {
+	renderPage(c, "/alarms", alarmsPage)
+}
+
+const alarmsPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Create Alarm -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Create Alarm</h2>
+            <div class="grid grid-cols-1 md:grid-cols-6 gap-4">
+                <input id="alarmName" type="text" placeholder="Alarm name"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <select id="alarmMetric" class="border rounded px-3 py-2">
+                    <option value="cpu">CPU %</option>
+                    <option value="memory">Memory % of limit</option>
+                    <option value="memory_mb">Memory MB</option>
+                </select>
+                <div class="flex gap-2">
+                    <select id="alarmComparison" class="border rounded px-2 py-2">
+                        <option>&gt;</option>
+                        <option>&gt;=</option>
+                        <option>&lt;</option>
+                        <option>&lt;=</option>
+                    </select>
+                    <input id="alarmThreshold" type="number" step="any" placeholder="Threshold"
+                           class="border rounded px-3 py-2 w-full focus:outline-none focus:ring-2 focus:ring-blue-500">
+                </div>
+                <div class="flex gap-2">
+                    <input id="alarmPeriods" type="number" min="1" value="3" title="Samples in a row"
+                           class="border rounded px-3 py-2 w-20 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <input id="alarmPeriod" type="number" min="10" value="60" title="Seconds between samples"
+                           class="border rounded px-3 py-2 w-full focus:outline-none focus:ring-2 focus:ring-blue-500">
+                </div>
+                <input id="alarmInstance" type="text" placeholder="Instance (all if empty)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="alarmProject" type="text" placeholder="Project (all if empty)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+            </div>
+            <div class="grid grid-cols-1 md:grid-cols-6 gap-4 mt-4">
+                <input id="alarmWebhook" type="text" placeholder="Webhook URL (optional)"
+                       class="md:col-span-3 border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <select id="alarmInstanceAction" class="border rounded px-3 py-2">
+                    <option value="">No instance action</option>
+                    <option value="restart">Restart instance</option>
+                    <option value="stop">Stop instance</option>
+                </select>
+                <label class="flex items-center gap-2 text-sm text-gray-600">
+                    <input id="alarmLog" type="checkbox" checked> Log transitions
+                </label>
+                <button onclick="createAlarm()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Create
+                </button>
+            </div>
+            <p class="text-sm text-gray-500 mt-2">The alarm fires when the metric breaches the threshold for the given number of samples in a row, sampled every period seconds.</p>
+        </div>
+
+        <!-- Alarms -->
+        <div class="bg-white rounded-lg shadow mb-6 overflow-hidden">
+            <div class="px-6 py-4 border-b flex justify-between items-center">
+                <h2 class="text-xl font-semibold">Alarms</h2>
+                <span id="alarmCounts" class="text-sm text-gray-500"></span>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">State</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Condition</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Scope</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Reason</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="alarmsTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+
+        <!-- Selected alarm -->
+        <div id="alarmDetail" class="hidden grid grid-cols-1 md:grid-cols-2 gap-6">
+            <div class="bg-white rounded-lg shadow p-6">
+                <h2 class="text-xl font-semibold mb-4" id="detailTitle"></h2>
+                <div class="h-64"><canvas id="datapointsChart"></canvas></div>
+            </div>
+            <div class="bg-white rounded-lg shadow p-6">
+                <h2 class="text-xl font-semibold mb-4">History</h2>
+                <ul id="historyList" class="space-y-2 text-sm max-h-64 overflow-y-auto"></ul>
+            </div>
+        </div>
+    </div>
+
+    <script>
+        let selected = null;
+        let chart = null;
+        const stateColors = {
+            OK: 'bg-green-100 text-green-800',
+            ALARM: 'bg-red-100 text-red-800',
+            INSUFFICIENT_DATA: 'bg-gray-100 text-gray-800'
+        };
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text;
+            return div.innerHTML;
+        }
+
+        function condition(alarm) {
+            return alarm.metric + ' ' + alarm.comparison + ' ' + alarm.threshold +
+                ' for ' + alarm.evaluation_periods + ' x ' + alarm.period_seconds + 's';
+        }
+
+        async function loadAlarms() {
+            const response = await fetch('/api/v1/alarms');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const alarms = result.data || [];
+            const firing = alarms.filter(a => a.state === 'ALARM').length;
+            document.getElementById('alarmCounts').textContent = alarms.length + ' alarms, ' + firing + ' in ALARM';
+
+            const tbody = document.getElementById('alarmsTable');
+            tbody.innerHTML = '';
+            alarms.forEach(alarm => {
+                const scope = [alarm.instance || 'all instances', alarm.project].filter(x => x).join(', ');
+                const actions = (alarm.actions || []).map(a => a.type + (a.state !== 'ALARM' ? ' on ' + a.state : '')).join(', ');
+                const row = document.createElement('tr');
+                row.className = 'cursor-pointer hover:bg-gray-50';
+                row.onclick = () => selectAlarm(alarm.name);
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-3 text-sm font-medium text-gray-900">${escapeHtml(alarm.name)}</td>
+                    <td class="px-6 py-3 text-sm">
+                        <span class="px-2 py-1 text-xs font-semibold rounded-full ${stateColors[alarm.state]}">${alarm.state}</span>
+                    </td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${escapeHtml(condition(alarm))}</td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${escapeHtml(scope)}</td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${escapeHtml(alarm.reason)}</td>
+                    <td class="px-6 py-3 text-sm space-x-2">
+                        <span class="text-gray-400">${escapeHtml(actions || '-')}</span>
+                        <button onclick="event.stopPropagation(); testAlarm('${alarm.name}')" class="text-indigo-600 hover:text-indigo-900">Test</button>
+                        <button onclick="event.stopPropagation(); deleteAlarm('${alarm.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function selectAlarm(name) {
+            selected = name;
+            document.getElementById('alarmDetail').classList.remove('hidden');
+            await loadDetail();
+        }
+
+        async function loadDetail() {
+            if (!selected) return;
+            const [alarmResponse, historyResponse] = await Promise.all([
+                fetch('/api/v1/alarms/' + selected),
+                fetch('/api/v1/alarms/' + selected + '/history')
+            ]);
+            const alarmResult = await alarmResponse.json();
+            const historyResult = await historyResponse.json();
+            if (!alarmResult.success) {
+                selected = null;
+                document.getElementById('alarmDetail').classList.add('hidden');
+                return;
+            }
+
+            const alarm = alarmResult.data;
+            document.getElementById('detailTitle').textContent = alarm.name + ': ' + condition(alarm);
+            const points = (alarm.datapoints || []).filter(p => !p.missing);
+            if (chart) chart.destroy();
+            chart = new Chart(document.getElementById('datapointsChart'), {
+                type: 'line',
+                data: {
+                    labels: points.map(p => new Date(p.time).toLocaleTimeString()),
+                    datasets: [
+                        { label: alarm.metric, data: points.map(p => p.value), borderColor: '#3b82f6', tension: 0.2 },
+                        { label: 'threshold', data: points.map(() => alarm.threshold), borderColor: '#ef4444', borderDash: [6, 4], pointRadius: 0 }
+                    ]
+                },
+                options: { animation: false, maintainAspectRatio: false, scales: { y: { min: 0 } } }
+            });
+
+            const list = document.getElementById('historyList');
+            list.innerHTML = '';
+            const history = historyResult.success ? historyResult.data : [];
+            if (!history.length) {
+                list.innerHTML = '<li class="text-gray-500">No state changes yet</li>';
+                return;
+            }
+            history.forEach(t => {
+                const actions = (t.actions || []).map(a =>
+                    a.type + (a.target ? ' ' + a.target : '') + (a.error ? ' failed: ' + a.error : '')).join('; ');
+                const item = document.createElement('li');
+                item.innerHTML = ` + "`" + `
+                    <div><span class="text-gray-500">${new Date(t.time).toLocaleString()}</span>
+                        ${t.from} &rarr; <span class="px-2 text-xs font-semibold rounded-full ${stateColors[t.to]}">${t.to}</span></div>
+                    <div class="text-gray-600">${escapeHtml(t.reason)}</div>
+                    ${actions ? '<div class="text-gray-400">' + escapeHtml(actions) + '</div>' : ''}
+                ` + "`" + `;
+                list.appendChild(item);
+            });
+        }
+
+        async function createAlarm() {
+            const actions = [];
+            const webhook = document.getElementById('alarmWebhook').value.trim();
+            if (webhook) {
+                actions.push({ type: 'webhook', url: webhook, state: 'ALARM' });
+                actions.push({ type: 'webhook', url: webhook, state: 'OK' });
+            }
+            if (document.getElementById('alarmLog').checked) {
+                ['ALARM', 'OK'].forEach(state => actions.push({ type: 'log', state }));
+            }
+            const instanceAction = document.getElementById('alarmInstanceAction').value;
+            if (instanceAction) actions.push({ type: instanceAction, state: 'ALARM' });
+
+            const response = await fetch('/api/v1/alarms', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({
+                    name: document.getElementById('alarmName').value,
+                    metric: document.getElementById('alarmMetric').value,
+                    comparison: document.getElementById('alarmComparison').value,
+                    threshold: parseFloat(document.getElementById('alarmThreshold').value) || 0,
+                    evaluation_periods: parseInt(document.getElementById('alarmPeriods').value) || 1,
+                    period_seconds: parseInt(document.getElementById('alarmPeriod').value) || 60,
+                    instance: document.getElementById('alarmInstance').value.trim(),
+                    project: document.getElementById('alarmProject').value.trim(),
+                    actions
+                })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            loadAlarms();
+        }
+
+        async function testAlarm(name) {
+            if (!confirm('Put ' + name + ' in ALARM to run its actions?')) return;
+            const response = await fetch('/api/v1/alarms/' + name + '/state', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ state: 'ALARM', reason: 'test from the dashboard' })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            loadAlarms();
+            if (selected === name) loadDetail();
+        }
+
+        async function deleteAlarm(name) {
+            if (!confirm('Delete alarm ' + name + '?')) return;
+            const response = await fetch('/api/v1/alarms/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            if (selected === name) {
+                selected = null;
+                document.getElementById('alarmDetail').classList.add('hidden');
+            }
+            loadAlarms();
+        }
+
+        // Initialize
+        loadAlarms();
+        setInterval(() => { loadAlarms(); loadDetail(); }, 10000);
+    </script>`
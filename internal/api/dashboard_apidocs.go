@@ -0,0 +1,33 @@
+// API explorer page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleAPIDocs(c *gin.Context) {
+	renderPage(c, "/api-docs", apiDocsPage)
+}
+
+// Swagger UI rendering /api/v1/openapi.json, with "Try it out" against this server
+const apiDocsPage = `    <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css">
+    <div class="container mx-auto px-4 pb-8">
+        <div class="bg-white rounded-lg shadow p-4">
+            <p class="text-sm text-gray-600 mb-2">
+                Every endpoint of the REST API. Download the document from
+                <a href="/api/v1/openapi.json" class="text-blue-600 hover:underline">/api/v1/openapi.json</a>
+                to generate clients, or import <code>localcloud/pkg/client</code> from Go.
+            </p>
+            <div id="swagger-ui"></div>
+        </div>
+    </div>
+
+    <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+    <script>
+        SwaggerUIBundle({
+            url: '/api/v1/openapi.json',
+            dom_id: '#swagger-ui',
+            deepLinking: true,
+            docExpansion: 'none',
+            tryItOutEnabled: false
+        });
+    </script>
+`
@@ -0,0 +1,262 @@
+// Auto scaling page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleAutoscalingDashboard(c *gin.Context) {
+	renderPage(c, "/autoscaling", autoscalingPage)
+}
+
+const autoscalingPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Create Group Form -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Create Scaling Group</h2>
+            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+                <input id="groupName" type="text" placeholder="Group name"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="groupImage" type="text" placeholder="Image (e.g., nginx:latest)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="groupPorts" type="text" placeholder="Ports (e.g., :80)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="groupCooldown" type="number" placeholder="Cooldown seconds (60)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="groupMin" type="number" placeholder="Min (1)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="groupMax" type="number" placeholder="Max (3)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="groupCPU" type="number" placeholder="CPU target % (optional)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="groupMemory" type="number" placeholder="Memory target % (optional)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <button onclick="createGroup()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Create
+                </button>
+            </div>
+        </div>
+
+        <!-- Groups Table -->
+        <div class="bg-white rounded-lg shadow overflow-hidden mb-6">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">Scaling Groups</h2>
+            </div>
+            <div class="overflow-x-auto">
+                <table class="min-w-full divide-y divide-gray-200">
+                    <thead class="bg-gray-50">
+                        <tr>
+                            <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                            <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Image</th>
+                            <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Min / Desired / Max</th>
+                            <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Running</th>
+                            <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Policies</th>
+                            <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                        </tr>
+                    </thead>
+                    <tbody id="groupTable" class="bg-white divide-y divide-gray-200">
+                    </tbody>
+                </table>
+            </div>
+        </div>
+
+        <!-- Selected Group -->
+        <div id="groupDetail" class="hidden grid grid-cols-1 lg:grid-cols-2 gap-6">
+            <div class="bg-white rounded-lg shadow p-6">
+                <h2 class="text-xl font-semibold mb-4">Metrics and Capacity: <span id="detailName"></span></h2>
+                <canvas id="scalingChart" height="200"></canvas>
+            </div>
+            <div class="bg-white rounded-lg shadow p-6 flex flex-col">
+                <h2 class="text-xl font-semibold mb-4">Scaling Activity</h2>
+                <div class="overflow-auto max-h-96">
+                    <table class="min-w-full text-sm">
+                        <thead>
+                            <tr class="text-left text-gray-500">
+                                <th class="py-2 pr-4">Time</th>
+                                <th class="py-2 pr-4">Action</th>
+                                <th class="py-2 pr-4">Count</th>
+                                <th class="py-2">Cause</th>
+                            </tr>
+                        </thead>
+                        <tbody id="activityTable" class="divide-y divide-gray-200"></tbody>
+                    </table>
+                </div>
+            </div>
+        </div>
+    </div>
+
+    <script>
+        let selectedGroup = null;
+        let chart = null;
+
+        function intOrUndefined(id) {
+            const value = document.getElementById(id).value;
+            return value === '' ? undefined : parseInt(value, 10);
+        }
+
+        async function loadGroups() {
+            const response = await fetch('/api/v1/autoscaling/groups');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('groupTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(group => {
+                const running = group.instances.filter(i => i.state === 'running').length;
+                const policies = (group.policies || []).map(p => p.metric + ' ' + p.target + '%').join(', ');
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm text-gray-900">${group.name}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${group.template.image}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${group.min} / ${group.desired} / ${group.max}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${running}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${policies || '-'}</td>
+                    <td class="px-6 py-4 text-sm space-x-2">
+                        <button onclick="selectGroup('${group.name}')"
+                                class="text-blue-600 hover:text-blue-900">Activity</button>
+                        <button onclick="setDesired('${group.name}', ${group.desired})"
+                                class="text-green-600 hover:text-green-900">Set Desired</button>
+                        <button onclick="deleteGroup('${group.name}')"
+                                class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function createGroup() {
+            const policies = [];
+            const cpu = intOrUndefined('groupCPU');
+            const memory = intOrUndefined('groupMemory');
+            if (cpu) policies.push({ metric: 'cpu', target: cpu });
+            if (memory) policies.push({ metric: 'memory', target: memory });
+
+            const min = intOrUndefined('groupMin') ?? 1;
+            const body = {
+                name: document.getElementById('groupName').value,
+                template: {
+                    image: document.getElementById('groupImage').value || 'nginx:latest',
+                    ports: document.getElementById('groupPorts').value
+                },
+                min: min,
+                max: intOrUndefined('groupMax') ?? 3,
+                desired: min,
+                policies: policies,
+                cooldown_seconds: intOrUndefined('groupCooldown') ?? 60
+            };
+
+            try {
+                const response = await fetch('/api/v1/autoscaling/groups', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const result = await response.json();
+                if (!result.success) {
+                    alert('Error: ' + result.error);
+                    return;
+                }
+                loadGroups();
+            } catch (error) {
+                alert('Error creating group: ' + error.message);
+            }
+        }
+
+        async function setDesired(name, current) {
+            const value = prompt('Desired capacity for ' + name, current);
+            if (value === null) return;
+
+            const response = await fetch('/api/v1/autoscaling/groups/' + name + '/capacity', {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ desired: parseInt(value, 10) })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            loadGroups();
+        }
+
+        async function deleteGroup(name) {
+            if (!confirm('Delete group ' + name + ' and terminate its instances?')) return;
+
+            const response = await fetch('/api/v1/autoscaling/groups/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            if (selectedGroup === name) {
+                selectedGroup = null;
+                document.getElementById('groupDetail').classList.add('hidden');
+            }
+            loadGroups();
+        }
+
+        function selectGroup(name) {
+            selectedGroup = name;
+            document.getElementById('detailName').textContent = name;
+            document.getElementById('groupDetail').classList.remove('hidden');
+            loadDetail();
+        }
+
+        async function loadDetail() {
+            if (!selectedGroup) return;
+
+            const [samplesResponse, activityResponse] = await Promise.all([
+                fetch('/api/v1/autoscaling/groups/' + selectedGroup + '/samples'),
+                fetch('/api/v1/autoscaling/groups/' + selectedGroup + '/activities')
+            ]);
+            const samples = await samplesResponse.json();
+            const activities = await activityResponse.json();
+
+            if (samples.success) {
+                drawChart(samples.data || []);
+            }
+            if (activities.success) {
+                const tbody = document.getElementById('activityTable');
+                tbody.innerHTML = '';
+                (activities.data || []).slice().reverse().forEach(activity => {
+                    const row = document.createElement('tr');
+                    row.innerHTML = ` + "`" + `
+                        <td class="py-2 pr-4 text-gray-500">${new Date(activity.time).toLocaleTimeString()}</td>
+                        <td class="py-2 pr-4 text-gray-900">${activity.action}</td>
+                        <td class="py-2 pr-4 text-gray-500">${activity.from} &rarr; ${activity.to}</td>
+                        <td class="py-2 ${activity.error ? 'text-red-600' : 'text-gray-700'}">${activity.error || activity.cause}</td>
+                    ` + "`" + `;
+                    tbody.appendChild(row);
+                });
+            }
+        }
+
+        function drawChart(samples) {
+            const labels = samples.map(s => new Date(s.time).toLocaleTimeString());
+            const datasets = [
+                { label: 'CPU %', data: samples.map(s => s.metrics.cpu || 0), borderColor: '#2563eb', yAxisID: 'percent' },
+                { label: 'Memory %', data: samples.map(s => s.metrics.memory || 0), borderColor: '#16a34a', yAxisID: 'percent' },
+                { label: 'Desired', data: samples.map(s => s.desired), borderColor: '#f59e0b', stepped: true, yAxisID: 'count' },
+                { label: 'Running', data: samples.map(s => s.running), borderColor: '#ef4444', stepped: true, yAxisID: 'count' }
+            ];
+
+            if (chart) {
+                chart.data.labels = labels;
+                chart.data.datasets.forEach((dataset, i) => dataset.data = datasets[i].data);
+                chart.update();
+                return;
+            }
+
+            chart = new Chart(document.getElementById('scalingChart'), {
+                type: 'line',
+                data: { labels, datasets },
+                options: {
+                    animation: false,
+                    scales: {
+                        percent: { type: 'linear', position: 'left', min: 0, max: 100 },
+                        count: { type: 'linear', position: 'right', min: 0, ticks: { stepSize: 1 }, grid: { drawOnChartArea: false } }
+                    }
+                }
+            });
+        }
+
+        // Initialize
+        loadGroups();
+        setInterval(() => { loadGroups(); loadDetail(); }, 5000);
+    </script>`
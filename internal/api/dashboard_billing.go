@@ -0,0 +1,290 @@
+// Billing cost explorer page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleBillingDashboard(c *gin.Context) {
+	renderPage(c, "/billing", billingPage)
+}
+
+const billingPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Cost Explorer -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <div class="flex flex-wrap items-end justify-between gap-4 mb-4">
+                <div>
+                    <h2 class="text-xl font-semibold">Cost Explorer</h2>
+                    <p class="text-sm text-gray-500 mt-1">Simulated charges, metered every minute against the price sheet below.</p>
+                </div>
+                <div class="flex flex-wrap items-center gap-2 text-sm">
+                    <select id="reportBy" onchange="loadReport()" class="border rounded px-3 py-2">
+                        <option value="project">By project</option>
+                        <option value="tag">By tag</option>
+                        <option value="resource">By resource</option>
+                        <option value="day">By day</option>
+                    </select>
+                    <input id="reportTagKey" type="text" placeholder="Tag key (optional)" onchange="loadReport()"
+                           class="border rounded px-3 py-2 w-36">
+                    <input id="reportFrom" type="date" onchange="loadReport()" class="border rounded px-3 py-2">
+                    <input id="reportTo" type="date" onchange="loadReport()" class="border rounded px-3 py-2">
+                </div>
+            </div>
+            <div class="text-3xl font-bold mb-4" id="reportTotal">-</div>
+            <div class="h-64 mb-6"><canvas id="costChart"></canvas></div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Group</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Instance hours</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">vCPU hours</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">GB hours</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Storage GB hours</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Network GB</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Cost</th>
+                    </tr>
+                </thead>
+                <tbody id="reportTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+
+        <!-- Budgets -->
+        <div class="bg-white rounded-lg shadow mb-6 overflow-hidden">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold mb-4">Budgets</h2>
+                <div class="grid grid-cols-1 md:grid-cols-6 gap-4">
+                    <input id="budgetName" type="text" placeholder="Budget name"
+                           class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <input id="budgetAmount" type="number" step="0.01" placeholder="Monthly amount"
+                           class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <input id="budgetProject" type="text" placeholder="Project (all if empty)"
+                           class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <input id="budgetTag" type="text" placeholder="Tag key=value (optional)"
+                           class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <input id="budgetThresholds" type="text" placeholder="Alert at % (50,80,100)"
+                           class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <button onclick="createBudget()"
+                            class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                        Create
+                    </button>
+                </div>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Scope</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Spent this month</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Forecast</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="budgetsTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+
+        <!-- Alerts and prices -->
+        <div class="grid grid-cols-1 md:grid-cols-2 gap-6">
+            <div class="bg-white rounded-lg shadow p-6">
+                <h2 class="text-xl font-semibold mb-4">Budget Alerts</h2>
+                <ul id="alertsList" class="space-y-2 text-sm"></ul>
+            </div>
+            <div class="bg-white rounded-lg shadow p-6">
+                <h2 class="text-xl font-semibold mb-4">Price Sheet</h2>
+                <div class="grid grid-cols-2 gap-3 text-sm" id="pricesForm"></div>
+                <button onclick="savePrices()" class="mt-4 bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">Save prices</button>
+            </div>
+        </div>
+    </div>
+
+    <script>
+        let chart = null;
+        let currency = 'USD';
+        const priceFields = [
+            ['currency', 'Currency'],
+            ['instance_hour', 'Per instance hour'],
+            ['vcpu_hour', 'Per vCPU hour'],
+            ['gb_hour', 'Per GB memory hour'],
+            ['storage_gb_month', 'Per GB storage month'],
+            ['network_gb', 'Per GB network']
+        ];
+
+        function money(value) {
+            return value.toLocaleString(undefined, { style: 'currency', currency, minimumFractionDigits: 2, maximumFractionDigits: 4 });
+        }
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text;
+            return div.innerHTML;
+        }
+
+        async function loadReport() {
+            const params = new URLSearchParams({ by: document.getElementById('reportBy').value });
+            const tagKey = document.getElementById('reportTagKey').value.trim();
+            const from = document.getElementById('reportFrom').value;
+            const to = document.getElementById('reportTo').value;
+            if (tagKey) params.set('tag_key', tagKey);
+            if (from) params.set('from', from);
+            if (to) params.set('to', to);
+
+            const response = await fetch('/api/v1/billing/report?' + params);
+            const result = await response.json();
+            if (!result.success) {
+                document.getElementById('reportTotal').textContent = result.error;
+                return;
+            }
+
+            const report = result.data;
+            currency = report.currency;
+            document.getElementById('reportFrom').value = report.from;
+            document.getElementById('reportTo').value = report.to;
+            document.getElementById('reportTotal').textContent = money(report.total.cost);
+
+            const tbody = document.getElementById('reportTable');
+            tbody.innerHTML = '';
+            report.groups.forEach(group => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-3 text-sm font-medium text-gray-900">${escapeHtml(group.key)}</td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${group.instance_hours.toFixed(2)}</td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${group.vcpu_hours.toFixed(2)}</td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${group.gb_hours.toFixed(2)}</td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${group.storage_gb_hours.toFixed(2)}</td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${group.network_gb.toFixed(3)}</td>
+                    <td class="px-6 py-3 text-sm font-semibold">${money(group.cost)}</td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+
+            if (chart) chart.destroy();
+            chart = new Chart(document.getElementById('costChart'), {
+                type: 'bar',
+                data: {
+                    labels: report.groups.map(g => g.key),
+                    datasets: [{ label: 'Cost (' + currency + ')', data: report.groups.map(g => g.cost), backgroundColor: '#3b82f6' }]
+                },
+                options: { animation: false, maintainAspectRatio: false, scales: { y: { min: 0 } } }
+            });
+        }
+
+        async function loadBudgets() {
+            const response = await fetch('/api/v1/billing/budgets');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('budgetsTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(budget => {
+                const percent = Math.min(budget.percent, 100);
+                const color = budget.percent >= 100 ? 'bg-red-500' : budget.percent >= 80 ? 'bg-yellow-500' : 'bg-green-500';
+                const scope = [budget.project || 'all projects', budget.tag].filter(x => x).join(', ');
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-3 text-sm font-medium text-gray-900">${budget.name}</td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${escapeHtml(scope)}</td>
+                    <td class="px-6 py-3 text-sm">
+                        <div>${money(budget.spent)} of ${money(budget.amount)} (${budget.percent.toFixed(1)}%)</div>
+                        <div class="w-48 bg-gray-200 rounded h-2 mt-1"><div class="${color} h-2 rounded" style="width: ${percent}%"></div></div>
+                    </td>
+                    <td class="px-6 py-3 text-sm text-gray-500">${money(budget.forecast)}</td>
+                    <td class="px-6 py-3 text-sm">
+                        <button onclick="deleteBudget('${budget.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function createBudget() {
+            const thresholds = document.getElementById('budgetThresholds').value
+                .split(',').map(t => parseInt(t.trim())).filter(t => !isNaN(t));
+            const response = await fetch('/api/v1/billing/budgets', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({
+                    name: document.getElementById('budgetName').value,
+                    amount: parseFloat(document.getElementById('budgetAmount').value) || 0,
+                    project: document.getElementById('budgetProject').value,
+                    tag: document.getElementById('budgetTag').value,
+                    thresholds
+                })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            loadBudgets();
+        }
+
+        async function deleteBudget(name) {
+            if (!confirm('Delete budget ' + name + '?')) return;
+            const response = await fetch('/api/v1/billing/budgets/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            loadBudgets();
+        }
+
+        async function loadAlerts() {
+            const response = await fetch('/api/v1/billing/alerts');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const list = document.getElementById('alertsList');
+            list.innerHTML = '';
+            if (!(result.data || []).length) {
+                list.innerHTML = '<li class="text-gray-500">No alerts</li>';
+                return;
+            }
+            result.data.slice(0, 20).forEach(alert => {
+                const item = document.createElement('li');
+                item.className = alert.threshold >= 100 ? 'text-red-700' : 'text-yellow-700';
+                item.textContent = new Date(alert.time).toLocaleString() + ': ' + alert.budget + ' reached ' +
+                    alert.threshold + '% (' + money(alert.spent) + ' of ' + money(alert.amount) + ')';
+                list.appendChild(item);
+            });
+        }
+
+        async function loadPrices() {
+            const response = await fetch('/api/v1/billing/prices');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const form = document.getElementById('pricesForm');
+            form.innerHTML = '';
+            priceFields.forEach(([key, label]) => {
+                const type = key === 'currency' ? 'text' : 'number';
+                form.innerHTML += ` + "`" + `
+                    <label class="text-gray-600 self-center">${label}</label>
+                    <input id="price_${key}" type="${type}" step="any" value="${result.data[key]}" class="border rounded px-2 py-1">
+                ` + "`" + `;
+            });
+        }
+
+        async function savePrices() {
+            const prices = {};
+            priceFields.forEach(([key]) => {
+                const value = document.getElementById('price_' + key).value;
+                prices[key] = key === 'currency' ? value : (parseFloat(value) || 0);
+            });
+            const response = await fetch('/api/v1/billing/prices', {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(prices)
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            loadReport();
+        }
+
+        // Initialize
+        loadPrices();
+        loadReport();
+        loadBudgets();
+        loadAlerts();
+        setInterval(() => { loadReport(); loadBudgets(); loadAlerts(); }, 60000);
+    </script>`
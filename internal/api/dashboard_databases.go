@@ -0,0 +1,223 @@
+// Databases page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleDatabasesDashboard(c *gin.Context) {
+	renderPage(c, "/databases", databasesPage)
+}
+
+const databasesPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Create Database Form -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Create Database</h2>
+            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+                <input id="dbName" type="text" placeholder="Name"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <select id="dbEngine" class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <option value="postgres">PostgreSQL</option>
+                    <option value="mysql">MySQL</option>
+                </select>
+                <input id="dbVersion" type="text" placeholder="Version (engine default)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="dbPort" type="number" placeholder="Host port (any free port)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="dbUser" type="text" placeholder="Username (localcloud)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="dbDatabase" type="text" placeholder="Database name (from name)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="dbBackupInterval" type="number" placeholder="Automatic backup every N hours"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <button onclick="createDatabase()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Create
+                </button>
+            </div>
+        </div>
+
+        <!-- Databases Table -->
+        <div class="bg-white rounded-lg shadow overflow-hidden mb-6">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">Databases</h2>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Engine</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Status</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Connection</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Last Backup</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="databasesTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+
+        <!-- Backups -->
+        <div id="backupsPanel" class="hidden bg-white rounded-lg shadow overflow-hidden">
+            <div class="px-6 py-4 border-b flex justify-between items-center">
+                <h2 class="text-xl font-semibold">Backups of <span id="backupsName"></span></h2>
+                <button onclick="createBackup()" class="bg-green-600 text-white px-4 py-2 rounded hover:bg-green-700">Back Up Now</button>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">ID</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Created</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Size</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Type</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="backupsTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+    </div>
+
+    <script>
+        let selectedDatabase = null;
+
+        const dbStatusClass = {
+            available: 'status-running',
+            creating: 'status-pending',
+            'backing-up': 'status-pending',
+            restoring: 'status-pending',
+            unavailable: 'status-exited',
+            failed: 'status-exited'
+        };
+
+        async function loadDatabases() {
+            const response = await fetch('/api/v1/databases');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('databasesTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(db => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-medium text-gray-900">${db.name}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${db.engine} ${db.version}</td>
+                    <td class="px-6 py-4 text-sm ${dbStatusClass[db.status] || ''}" title="${db.error || ''}">${db.status}</td>
+                    <td class="px-6 py-4 text-xs font-mono text-gray-500 break-all">${db.connection_string || '-'}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${db.last_backup ? new Date(db.last_backup).toLocaleString() : '-'}</td>
+                    <td class="px-6 py-4 text-sm font-medium space-x-2">
+                        <button onclick="showBackups('${db.name}')" class="text-green-600 hover:text-green-900">Backups</button>
+                        <button onclick="deleteDatabase('${db.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function createDatabase() {
+            const body = {
+                name: document.getElementById('dbName').value,
+                engine: document.getElementById('dbEngine').value,
+                version: document.getElementById('dbVersion').value,
+                username: document.getElementById('dbUser').value,
+                db_name: document.getElementById('dbDatabase').value,
+                port: parseInt(document.getElementById('dbPort').value || '0', 10),
+                backup_interval_hours: parseInt(document.getElementById('dbBackupInterval').value || '0', 10)
+            };
+
+            try {
+                const response = await fetch('/api/v1/databases', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const result = await response.json();
+                if (!result.success) {
+                    alert('Error: ' + result.error);
+                    return;
+                }
+                loadDatabases();
+            } catch (error) {
+                alert('Error creating database: ' + error.message);
+            }
+        }
+
+        async function deleteDatabase(name) {
+            if (!confirm('Delete database ' + name + ' with its data and backups?')) return;
+
+            const response = await fetch('/api/v1/databases/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            if (selectedDatabase === name) {
+                selectedDatabase = null;
+                document.getElementById('backupsPanel').classList.add('hidden');
+            }
+            loadDatabases();
+        }
+
+        function showBackups(name) {
+            selectedDatabase = name;
+            document.getElementById('backupsName').textContent = name;
+            document.getElementById('backupsPanel').classList.remove('hidden');
+            loadBackups();
+        }
+
+        async function loadBackups() {
+            if (!selectedDatabase) return;
+
+            const response = await fetch('/api/v1/databases/' + selectedDatabase + '/backups');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('backupsTable');
+            tbody.innerHTML = '';
+            (result.data || []).slice().reverse().forEach(b => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-mono text-gray-900">${b.id}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${new Date(b.created).toLocaleString()}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${formatBytes(b.size)}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${b.automatic ? 'automatic' : 'manual'}</td>
+                    <td class="px-6 py-4 text-sm font-medium space-x-2">
+                        <a href="/api/v1/databases/${b.database}/backups/${b.id}" class="text-blue-600 hover:text-blue-900">Download</a>
+                        <button onclick="restoreBackup('${b.id}')" class="text-yellow-600 hover:text-yellow-900">Restore</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function createBackup() {
+            const response = await fetch('/api/v1/databases/' + selectedDatabase + '/backups', { method: 'POST' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            loadBackups();
+            loadDatabases();
+        }
+
+        async function restoreBackup(id) {
+            if (!confirm('Restore ' + selectedDatabase + ' from backup ' + id + '? Current data will be replaced.')) return;
+
+            const response = await fetch('/api/v1/databases/' + selectedDatabase + '/restore', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ backup_id: id })
+            });
+            const result = await response.json();
+            alert(result.success ? 'Restored backup ' + id : 'Error: ' + result.error);
+        }
+
+        function formatBytes(bytes) {
+            if (bytes === 0) return '0 B';
+            const k = 1024;
+            const sizes = ['B', 'KB', 'MB', 'GB'];
+            const i = Math.floor(Math.log(bytes) / Math.log(k));
+            return parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];
+        }
+
+        // Initialize
+        loadDatabases();
+        setInterval(loadDatabases, 3000);
+    </script>`
@@ -0,0 +1,225 @@
+// File browser page of the web UI, opened from a container row
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleFilesDashboard(c *gin.Context) {
+	renderPage(c, "/", filesPage)
+}
+
+const filesPage = `    <div class="container mx-auto px-4 pb-8">
+        <div class="bg-white rounded-lg shadow overflow-hidden">
+            <div class="px-6 py-4 border-b flex flex-wrap items-center justify-between gap-4">
+                <div>
+                    <h2 class="text-xl font-semibold">Files in <span id="containerName" class="font-mono"></span></h2>
+                    <div id="breadcrumbs" class="text-sm font-mono mt-1"></div>
+                </div>
+                <div class="flex items-center space-x-2">
+                    <input id="uploadInput" type="file" multiple class="text-sm">
+                    <button onclick="uploadFiles()" class="bg-blue-600 text-white px-3 py-1 rounded text-sm hover:bg-blue-700">Upload here</button>
+                </div>
+            </div>
+            <div id="browseError" class="hidden px-6 py-3 bg-red-50 text-red-700 text-sm"></div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Size</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Mode</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Modified</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="filesTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+    </div>
+
+    <!-- Editor -->
+    <div id="editorModal" class="fixed inset-0 bg-black bg-opacity-50 hidden items-center justify-center z-50">
+        <div class="bg-white rounded-lg shadow-xl w-11/12 max-w-5xl">
+            <div class="px-6 py-4 border-b flex justify-between items-center">
+                <h3 id="editorTitle" class="text-lg font-semibold font-mono"></h3>
+                <button onclick="closeEditor()" class="text-gray-400 hover:text-gray-600">&times;</button>
+            </div>
+            <div class="p-6">
+                <div id="editorNotice" class="hidden mb-3 text-sm text-yellow-700"></div>
+                <textarea id="editorContent" class="w-full h-96 font-mono text-sm border rounded p-2" spellcheck="false"></textarea>
+            </div>
+            <div class="px-6 py-4 border-t flex justify-end space-x-2">
+                <button onclick="downloadFile(editorPath)" class="px-4 py-2 border rounded text-sm">Download</button>
+                <button id="saveButton" onclick="saveFile()" class="px-4 py-2 bg-blue-600 text-white rounded text-sm hover:bg-blue-700">Save</button>
+            </div>
+        </div>
+    </div>
+
+    <script>
+        const params = new URLSearchParams(window.location.search);
+        const containerId = params.get('id');
+        let currentPath = params.get('path') || '/';
+        let editorPath = '';
+
+        function escapeHtml(text) {
+            const div = document.createElement('div');
+            div.textContent = text;
+            return div.innerHTML;
+        }
+
+        function fsUrl(api, path) {
+            return '/api/v1/containers/' + encodeURIComponent(containerId) + '/' + api + '?path=' + encodeURIComponent(path);
+        }
+
+        function joinPath(dir, name) {
+            return (dir === '/' ? '' : dir) + '/' + name;
+        }
+
+        function showError(message) {
+            const box = document.getElementById('browseError');
+            box.textContent = message;
+            box.classList.toggle('hidden', !message);
+        }
+
+        async function browse(path) {
+            const response = await fetch(fsUrl('fs', path));
+            const result = await response.json();
+            if (!result.success) {
+                showError(result.error);
+                return;
+            }
+            showError('');
+
+            const info = result.data;
+            if (!info.is_dir) {
+                openEditor(info);
+                return;
+            }
+
+            currentPath = info.path;
+            history.replaceState(null, '', '/files?id=' + encodeURIComponent(containerId) + '&path=' + encodeURIComponent(currentPath));
+            renderBreadcrumbs();
+
+            const tbody = document.getElementById('filesTable');
+            tbody.innerHTML = '';
+            if (currentPath !== '/') {
+                const up = currentPath.substring(0, currentPath.lastIndexOf('/')) || '/';
+                const row = document.createElement('tr');
+                row.innerHTML = '<td class="px-6 py-2 text-sm" colspan="5"><a href="#" class="text-blue-600">..</a></td>';
+                row.querySelector('a').onclick = (e) => { e.preventDefault(); browse(up); };
+                tbody.appendChild(row);
+            }
+            (info.entries || []).forEach(entry => {
+                const isLink = (entry.mode & 0x8000000) !== 0;
+                const entryPath = joinPath(currentPath, entry.name);
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-2 text-sm font-mono"><a href="#" class="${entry.is_dir ? 'text-blue-600 font-semibold' : 'text-gray-900'}">${escapeHtml(entry.name)}${entry.is_dir ? '/' : ''}${isLink ? ' &rarr;' : ''}</a></td>
+                    <td class="px-6 py-2 text-sm text-gray-500">${entry.is_dir ? '-' : formatBytes(entry.size)}</td>
+                    <td class="px-6 py-2 text-sm font-mono text-gray-500">${formatMode(entry)}</td>
+                    <td class="px-6 py-2 text-sm text-gray-500">${new Date(entry.mod_time).toLocaleString()}</td>
+                    <td class="px-6 py-2 text-sm"><button class="text-green-600 hover:text-green-900">Download</button></td>
+                ` + "`" + `;
+                row.querySelector('a').onclick = (e) => { e.preventDefault(); browse(entryPath); };
+                row.querySelector('button').onclick = () => downloadFile(entryPath);
+                tbody.appendChild(row);
+            });
+        }
+
+        function renderBreadcrumbs() {
+            const crumbs = document.getElementById('breadcrumbs');
+            crumbs.innerHTML = '';
+            let path = '';
+            const parts = [''].concat(currentPath.split('/').filter(p => p));
+            parts.forEach((part, i) => {
+                path = i === 0 ? '/' : joinPath(path, part);
+                const target = path;
+                const link = document.createElement('a');
+                link.href = '#';
+                link.className = 'text-blue-600 hover:underline';
+                link.textContent = i === 0 ? '/' : part;
+                link.onclick = (e) => { e.preventDefault(); browse(target); };
+                crumbs.appendChild(link);
+                if (i > 0 && i < parts.length - 1) crumbs.appendChild(document.createTextNode('/'));
+            });
+        }
+
+        function openEditor(info) {
+            editorPath = info.path;
+            document.getElementById('editorTitle').textContent = info.path;
+            const notice = document.getElementById('editorNotice');
+            const textarea = document.getElementById('editorContent');
+            let message = '';
+            if (info.binary) message = 'Binary file, download it to view.';
+            else if (info.truncated) message = 'Showing the first 1 MB of ' + formatBytes(info.size) + '; download the file to see all of it.';
+            else if (!info.editable) message = 'Not a regular file.';
+            notice.textContent = message;
+            notice.classList.toggle('hidden', !message);
+            textarea.value = info.binary ? '' : info.content;
+            textarea.readOnly = !info.editable;
+            document.getElementById('saveButton').classList.toggle('hidden', !info.editable);
+
+            document.getElementById('editorModal').classList.remove('hidden');
+            document.getElementById('editorModal').classList.add('flex');
+        }
+
+        function closeEditor() {
+            document.getElementById('editorModal').classList.add('hidden');
+            document.getElementById('editorModal').classList.remove('flex');
+        }
+
+        async function saveFile() {
+            const response = await fetch(fsUrl('fs', editorPath), {
+                method: 'PUT',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ content: document.getElementById('editorContent').value })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            closeEditor();
+            browse(currentPath);
+        }
+
+        function downloadFile(path) {
+            window.location = fsUrl('files', path);
+        }
+
+        async function uploadFiles() {
+            const input = document.getElementById('uploadInput');
+            if (!input.files.length) return;
+
+            const form = new FormData();
+            for (const file of input.files) form.append('file', file);
+            const response = await fetch(fsUrl('files', currentPath), { method: 'PUT', body: form });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            input.value = '';
+            browse(currentPath);
+        }
+
+        function formatMode(entry) {
+            const type = entry.is_dir ? 'd' : ((entry.mode & 0x8000000) !== 0 ? 'l' : '-');
+            const chars = 'rwxrwxrwx';
+            let perms = '';
+            for (let i = 0; i < 9; i++) {
+                perms += (entry.mode & (1 << (8 - i))) ? chars[i] : '-';
+            }
+            return type + perms;
+        }
+
+        function formatBytes(bytes) {
+            if (bytes === 0) return '0 B';
+            const k = 1024;
+            const sizes = ['B', 'KB', 'MB', 'GB'];
+            const i = Math.floor(Math.log(bytes) / Math.log(k));
+            return parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];
+        }
+
+        // Initialize
+        document.getElementById('containerName').textContent = containerId;
+        browse(currentPath);
+    </script>`
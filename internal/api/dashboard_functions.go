@@ -0,0 +1,230 @@
+// Functions page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleFunctionsDashboard(c *gin.Context) {
+	renderPage(c, "/functions", functionsPage)
+}
+
+const functionsPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Deploy Function Form -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Deploy Function</h2>
+            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+                <input id="fnName" type="text" placeholder="Name"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <select id="fnRuntime" class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <option value="python">Python</option>
+                    <option value="node">Node</option>
+                    <option value="go">Go</option>
+                </select>
+                <input id="fnHandler" type="text" placeholder="Handler (runtime default)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="fnCode" type="file" accept=".zip"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="fnMemory" type="number" placeholder="Memory MB (128)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="fnTimeout" type="number" placeholder="Timeout seconds (30)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="fnEnv" type="text" placeholder="Env KEY=VALUE, comma separated"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <button id="fnDeployButton" onclick="deployFunction()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Deploy
+                </button>
+            </div>
+            <pre id="fnBuildLog" class="hidden mt-4 bg-gray-900 text-green-400 p-4 rounded text-xs overflow-auto max-h-64"></pre>
+        </div>
+
+        <!-- Functions Table -->
+        <div class="bg-white rounded-lg shadow overflow-hidden mb-6">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">Functions</h2>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Runtime</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Handler</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Version</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Memory</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Timeout</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Warm / Busy</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="functionsTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+
+        <!-- Invoke -->
+        <div id="invokePanel" class="hidden bg-white rounded-lg shadow p-6">
+            <h2 class="text-xl font-semibold mb-4">Invoke <span id="invokeName"></span></h2>
+            <textarea id="invokeEvent" rows="4" placeholder='Event JSON, e.g. {"name": "world"}'
+                      class="w-full border rounded px-3 py-2 font-mono text-sm focus:outline-none focus:ring-2 focus:ring-blue-500"></textarea>
+            <button onclick="invokeFunction()" class="mt-2 bg-green-600 text-white px-4 py-2 rounded hover:bg-green-700">Invoke</button>
+            <pre id="invokeResult" class="hidden mt-4 bg-gray-900 text-green-400 p-4 rounded text-xs overflow-auto max-h-64"></pre>
+
+            <h3 class="text-lg font-semibold mt-6 mb-2">Recent Invocations</h3>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Time</th>
+                        <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Version</th>
+                        <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Status</th>
+                        <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Duration</th>
+                        <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Cold Start</th>
+                        <th class="px-4 py-2 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Logs / Error</th>
+                    </tr>
+                </thead>
+                <tbody id="invocationsTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+    </div>
+
+    <script>
+        let selectedFunction = null;
+
+        async function loadFunctions() {
+            const response = await fetch('/api/v1/functions');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('functionsTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(fn => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-medium text-gray-900">${fn.name}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${fn.runtime}</td>
+                    <td class="px-6 py-4 text-sm font-mono text-gray-500">${fn.handler}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">v${fn.version}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${fn.memory_mb} MB</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${fn.timeout_seconds}s</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${fn.warm} / ${fn.busy}</td>
+                    <td class="px-6 py-4 text-sm font-medium space-x-2">
+                        <button onclick="selectFunction('${fn.name}')" class="text-green-600 hover:text-green-900">Invoke</button>
+                        <button onclick="deleteFunction('${fn.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function deployFunction() {
+            const file = document.getElementById('fnCode').files[0];
+            if (!file) {
+                alert('Choose a zip file with the function code');
+                return;
+            }
+
+            const form = new FormData();
+            form.append('name', document.getElementById('fnName').value);
+            form.append('runtime', document.getElementById('fnRuntime').value);
+            form.append('handler', document.getElementById('fnHandler').value);
+            form.append('memory_mb', document.getElementById('fnMemory').value);
+            form.append('timeout_seconds', document.getElementById('fnTimeout').value);
+            document.getElementById('fnEnv').value.split(',').map(s => s.trim()).filter(s => s)
+                .forEach(env => form.append('env', env));
+            form.append('code', file);
+
+            const button = document.getElementById('fnDeployButton');
+            const log = document.getElementById('fnBuildLog');
+            button.disabled = true;
+            button.textContent = 'Building...';
+            try {
+                const response = await fetch('/api/v1/functions', { method: 'POST', body: form });
+                const result = await response.json();
+                const buildLog = result.data && result.data.build_log;
+                log.textContent = (buildLog || '') + (result.success ? '' : '\nError: ' + result.error);
+                log.classList.remove('hidden');
+                if (result.success) loadFunctions();
+            } catch (error) {
+                alert('Error deploying function: ' + error.message);
+            } finally {
+                button.disabled = false;
+                button.textContent = 'Deploy';
+            }
+        }
+
+        async function deleteFunction(name) {
+            if (!confirm('Delete function ' + name + '?')) return;
+
+            const response = await fetch('/api/v1/functions/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            if (selectedFunction === name) {
+                selectedFunction = null;
+                document.getElementById('invokePanel').classList.add('hidden');
+            }
+            loadFunctions();
+        }
+
+        function selectFunction(name) {
+            selectedFunction = name;
+            document.getElementById('invokeName').textContent = name;
+            document.getElementById('invokeResult').classList.add('hidden');
+            document.getElementById('invokePanel').classList.remove('hidden');
+            loadInvocations();
+        }
+
+        async function invokeFunction() {
+            const output = document.getElementById('invokeResult');
+            output.textContent = 'Invoking...';
+            output.classList.remove('hidden');
+
+            const response = await fetch('/api/v1/functions/' + selectedFunction + '/invoke', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: document.getElementById('invokeEvent').value
+            });
+            const result = await response.json();
+            if (!result.success) {
+                output.textContent = 'Error: ' + result.error;
+                return;
+            }
+
+            const inv = result.data;
+            let text = 'Status: ' + inv.status + '  Duration: ' + inv.duration_ms + ' ms';
+            if (inv.cold_start) text += '  Init: ' + inv.init_duration_ms + ' ms (cold start)';
+            text += '\n\nResult:\n' + JSON.stringify(inv.result, null, 2);
+            if (inv.error) text += '\n\nError:\n' + inv.error;
+            if (inv.logs) text += '\n\nLogs:\n' + inv.logs;
+            output.textContent = text;
+            loadInvocations();
+            loadFunctions();
+        }
+
+        async function loadInvocations() {
+            if (!selectedFunction) return;
+
+            const response = await fetch('/api/v1/functions/' + selectedFunction + '/invocations?limit=20');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('invocationsTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(inv => {
+                const row = document.createElement('tr');
+                const statusClass = inv.status === 'success' ? 'health-healthy' : 'health-unhealthy';
+                row.innerHTML = ` + "`" + `
+                    <td class="px-4 py-2 text-sm text-gray-500">${new Date(inv.time).toLocaleString()}</td>
+                    <td class="px-4 py-2 text-sm text-gray-500">v${inv.version}</td>
+                    <td class="px-4 py-2 text-sm ${statusClass}">${inv.status}</td>
+                    <td class="px-4 py-2 text-sm text-gray-500">${inv.duration_ms} ms</td>
+                    <td class="px-4 py-2 text-sm text-gray-500">${inv.cold_start ? 'yes (' + inv.init_duration_ms + ' ms)' : 'no'}</td>
+                    <td class="px-4 py-2 text-xs font-mono text-gray-500 whitespace-pre-wrap"></td>
+                ` + "`" + `;
+                row.lastElementChild.textContent = inv.error || inv.logs || '';
+                tbody.appendChild(row);
+            });
+        }
+
+        // Initialize
+        loadFunctions();
+        setInterval(loadFunctions, 5000);
+    </script>`
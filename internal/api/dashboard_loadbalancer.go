@@ -0,0 +1,162 @@
+// Load balancer page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleLoadBalancerDashboard(c *gin.Context) {
+	renderPage(c, "/loadbalancers", loadBalancerPage)
+}
+
+const loadBalancerPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Create Load Balancer Form -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Create Load Balancer</h2>
+            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+                <input id="lbName" type="text" placeholder="Name"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="lbPort" type="number" placeholder="Listen port (e.g., 9000)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <select id="lbProtocol" class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <option value="http">HTTP</option>
+                    <option value="tcp">TCP</option>
+                </select>
+                <select id="lbAlgorithm" class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <option value="round_robin">Round robin</option>
+                    <option value="least_connections">Least connections</option>
+                </select>
+                <input id="lbGroup" type="text" placeholder="Scaling group (optional)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="lbInstances" type="text" placeholder="Instance IDs, comma separated"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="lbTargetPort" type="number" placeholder="Target container port (80)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="lbHealthPath" type="text" placeholder="Health check path (optional)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <label class="flex items-center space-x-2 text-sm text-gray-700">
+                    <input id="lbSticky" type="checkbox"> <span>Sticky sessions</span>
+                </label>
+                <button onclick="createBalancer()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Create
+                </button>
+            </div>
+        </div>
+
+        <div id="balancers" class="space-y-6"></div>
+    </div>
+
+    <script>
+        async function loadBalancers() {
+            const response = await fetch('/api/v1/loadbalancers');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const container = document.getElementById('balancers');
+            container.innerHTML = '';
+            (result.data || []).forEach(lb => {
+                const targets = lb.targets.group ? 'group ' + lb.targets.group : (lb.targets.instances || []).map(id => id.substring(0, 12)).join(', ');
+                const rows = lb.target_stats.map(t => ` + "`" + `
+                    <tr>
+                        <td class="px-6 py-3 text-sm text-gray-900">${t.name}</td>
+                        <td class="px-6 py-3 text-sm font-mono text-gray-500">${t.address || '-'}</td>
+                        <td class="px-6 py-3 text-sm ${t.healthy ? 'health-healthy' : 'health-unhealthy'}">${t.healthy ? 'healthy' : 'unhealthy'}</td>
+                        <td class="px-6 py-3 text-sm text-gray-500">${t.active_connections}</td>
+                        <td class="px-6 py-3 text-sm text-gray-500">${t.requests}</td>
+                        <td class="px-6 py-3 text-sm text-gray-500">${t.errors}</td>
+                        <td class="px-6 py-3 text-sm text-gray-500">${t.avg_latency_ms.toFixed(1)} ms</td>
+                        <td class="px-6 py-3 text-sm text-gray-500">${formatBytes(t.bytes_in)} / ${formatBytes(t.bytes_out)}</td>
+                    </tr>
+                ` + "`" + `).join('');
+
+                const card = document.createElement('div');
+                card.className = 'bg-white rounded-lg shadow overflow-hidden';
+                card.innerHTML = ` + "`" + `
+                    <div class="px-6 py-4 border-b flex justify-between items-center">
+                        <div>
+                            <h2 class="text-xl font-semibold">${lb.name}
+                                <span class="text-sm ${lb.running ? 'status-running' : 'status-exited'}">${lb.running ? 'listening' : (lb.error || 'stopped')}</span>
+                            </h2>
+                            <div class="text-sm text-gray-500">
+                                ${lb.protocol.toUpperCase()} :${lb.listen_port} &rarr; ${targets} port ${lb.targets.port}
+                                &middot; ${lb.algorithm.replace('_', ' ')}${lb.sticky ? ' &middot; sticky' : ''}
+                            </div>
+                        </div>
+                        <button onclick="deleteBalancer('${lb.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </div>
+                    <table class="min-w-full divide-y divide-gray-200">
+                        <thead class="bg-gray-50">
+                            <tr>
+                                <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Target</th>
+                                <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Address</th>
+                                <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Health</th>
+                                <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Active</th>
+                                <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Requests</th>
+                                <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Errors</th>
+                                <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Avg Latency</th>
+                                <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">In / Out</th>
+                            </tr>
+                        </thead>
+                        <tbody class="divide-y divide-gray-200">${rows || '<tr><td colspan="8" class="px-6 py-3 text-sm text-gray-500">No targets</td></tr>'}</tbody>
+                    </table>
+                ` + "`" + `;
+                container.appendChild(card);
+            });
+        }
+
+        async function createBalancer() {
+            const instances = document.getElementById('lbInstances').value.split(',').map(s => s.trim()).filter(s => s);
+            const healthPath = document.getElementById('lbHealthPath').value;
+            const body = {
+                name: document.getElementById('lbName').value,
+                protocol: document.getElementById('lbProtocol').value,
+                listen_port: parseInt(document.getElementById('lbPort').value, 10),
+                algorithm: document.getElementById('lbAlgorithm').value,
+                sticky: document.getElementById('lbSticky').checked,
+                targets: {
+                    instances: instances,
+                    group: document.getElementById('lbGroup').value,
+                    port: parseInt(document.getElementById('lbTargetPort').value || '80', 10)
+                }
+            };
+            if (healthPath) body.health_check = { path: healthPath };
+
+            try {
+                const response = await fetch('/api/v1/loadbalancers', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const result = await response.json();
+                if (!result.success) {
+                    alert('Error: ' + result.error);
+                    return;
+                }
+                loadBalancers();
+            } catch (error) {
+                alert('Error creating load balancer: ' + error.message);
+            }
+        }
+
+        async function deleteBalancer(name) {
+            if (!confirm('Delete load balancer ' + name + '?')) return;
+
+            const response = await fetch('/api/v1/loadbalancers/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            loadBalancers();
+        }
+
+        function formatBytes(bytes) {
+            if (bytes === 0) return '0 B';
+            const k = 1024;
+            const sizes = ['B', 'KB', 'MB', 'GB'];
+            const i = Math.floor(Math.log(bytes) / Math.log(k));
+            return parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];
+        }
+
+        // Initialize
+        loadBalancers();
+        setInterval(loadBalancers, 3000);
+    </script>`
@@ -0,0 +1,237 @@
+// Parameter store page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleParametersDashboard(c *gin.Context) {
+	renderPage(c, "/parameters", parametersPage)
+}
+
+const parametersPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Put Parameter Form -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Put Parameter</h2>
+            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+                <input id="paramName" type="text" placeholder="Name (e.g., /app/prod/db/host)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <select id="paramType" class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <option value="String">String</option>
+                    <option value="StringList">StringList</option>
+                    <option value="SecureString">SecureString</option>
+                </select>
+                <input id="paramValue" type="text" placeholder="Value"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="paramDescription" type="text" placeholder="Description"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <label class="flex items-center space-x-2 text-sm text-gray-700">
+                    <input id="paramOverwrite" type="checkbox"> <span>Overwrite (new version)</span>
+                </label>
+                <button onclick="putParameter()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Save
+                </button>
+            </div>
+        </div>
+
+        <!-- Parameters Table -->
+        <div class="bg-white rounded-lg shadow overflow-hidden mb-6">
+            <div class="px-6 py-4 border-b flex justify-between items-center">
+                <h2 class="text-xl font-semibold">Parameters</h2>
+                <div class="flex items-center space-x-2">
+                    <input id="browsePath" type="text" value="/" placeholder="Path"
+                           class="border rounded px-3 py-1 text-sm focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <label class="text-sm text-gray-700"><input id="browseRecursive" type="checkbox" checked> Recursive</label>
+                    <button onclick="loadParameters()" class="bg-gray-600 text-white px-3 py-1 rounded text-sm hover:bg-gray-700">Browse</button>
+                </div>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Type</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Value</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Version</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Labels</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Modified</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="parametersTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+            <p id="lastChange" class="px-6 py-3 text-sm text-gray-500"></p>
+        </div>
+
+        <!-- History -->
+        <div id="historyPanel" class="hidden bg-white rounded-lg shadow overflow-hidden">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">History of <span id="historyName" class="font-mono"></span></h2>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Version</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Type</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Value</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Labels</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Modified</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="historyTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+    </div>
+
+    <script>
+        let selectedParameter = null;
+
+        // SecureString values are never requested decrypted by this page
+        function displayValue(p) {
+            return p.type === 'SecureString' ? '••••••••' : p.value;
+        }
+
+        async function loadParameters() {
+            const path = document.getElementById('browsePath').value || '/';
+            const recursive = document.getElementById('browseRecursive').checked;
+            const response = await fetch('/api/v1/parameters?path=' + encodeURIComponent(path) + '&recursive=' + recursive);
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+
+            const tbody = document.getElementById('parametersTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(p => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-mono text-gray-900">${p.name}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${p.type}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500 font-mono break-all">${displayValue(p)}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${p.version}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${(p.labels || []).join(', ') || '-'}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${new Date(p.modified).toLocaleString()}</td>
+                    <td class="px-6 py-4 text-sm font-medium space-x-2">
+                        <button onclick="showHistory('${p.name}')" class="text-blue-600 hover:text-blue-900">History</button>
+                        <button onclick="deleteParameter('${p.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function putParameter() {
+            const name = document.getElementById('paramName').value;
+            const body = {
+                type: document.getElementById('paramType').value,
+                value: document.getElementById('paramValue').value,
+                description: document.getElementById('paramDescription').value,
+                overwrite: document.getElementById('paramOverwrite').checked
+            };
+
+            try {
+                const response = await fetch('/api/v1/parameters' + name, {
+                    method: 'PUT',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const result = await response.json();
+                if (!result.success) {
+                    alert('Error: ' + result.error);
+                    return;
+                }
+                document.getElementById('paramValue').value = '';
+            } catch (error) {
+                alert('Error saving parameter: ' + error.message);
+            }
+        }
+
+        async function deleteParameter(name) {
+            if (!confirm('Delete ' + name + ' and its history?')) return;
+
+            const response = await fetch('/api/v1/parameters' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+        }
+
+        function showHistory(name) {
+            selectedParameter = name;
+            document.getElementById('historyName').textContent = name;
+            document.getElementById('historyPanel').classList.remove('hidden');
+            loadHistory();
+        }
+
+        async function loadHistory() {
+            if (!selectedParameter) return;
+
+            const response = await fetch('/api/v1/parameter-history' + selectedParameter);
+            const result = await response.json();
+            if (!result.success) {
+                selectedParameter = null;
+                document.getElementById('historyPanel').classList.add('hidden');
+                return;
+            }
+
+            const tbody = document.getElementById('historyTable');
+            tbody.innerHTML = '';
+            result.data.slice().reverse().forEach(p => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm text-gray-900">${p.version}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${p.type}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500 font-mono break-all">${displayValue(p)}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${(p.labels || []).join(', ') || '-'}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${new Date(p.modified).toLocaleString()}</td>
+                    <td class="px-6 py-4 text-sm font-medium">
+                        <button onclick="labelVersion(${p.version})" class="text-blue-600 hover:text-blue-900">Label</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function labelVersion(version) {
+            const input = prompt('Labels for version ' + version + ' (comma-separated):');
+            if (!input) return;
+
+            const labels = input.split(',').map(l => l.trim()).filter(l => l);
+            const response = await fetch('/api/v1/parameter-labels' + selectedParameter, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ version, labels })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+        }
+
+        // Changes arrive over the WebSocket, so the page stays current without polling
+        function connectWebSocket() {
+            const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
+            const ws = new WebSocket(protocol + '//' + window.location.host + '/ws');
+
+            ws.onmessage = function(event) {
+                const data = JSON.parse(event.data);
+                if (!data.parameter) return;
+
+                const change = data.parameter;
+                document.getElementById('lastChange').textContent =
+                    'Last change: ' + change.action + ' ' + change.name +
+                    (change.version ? ' (version ' + change.version + ')' : '') +
+                    ' at ' + new Date(change.time).toLocaleTimeString();
+                loadParameters();
+                if (change.name === selectedParameter) loadHistory();
+            };
+
+            ws.onclose = function() {
+                setTimeout(connectWebSocket, 3000);
+            };
+        }
+
+        // Initialize
+        loadParameters();
+        connectWebSocket();
+    </script>`
@@ -0,0 +1,262 @@
+// Queues page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleQueuesDashboard(c *gin.Context) {
+	renderPage(c, "/queues", queuesPage)
+}
+
+const queuesPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Create Queue Form -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Create Queue</h2>
+            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+                <input id="queueName" type="text" placeholder="Name (end in .fifo for FIFO)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="queueVisibility" type="number" placeholder="Visibility timeout seconds (30)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="queueDelay" type="number" placeholder="Delay seconds (0)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="queueWait" type="number" placeholder="Receive wait seconds (0)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="queueDLQ" type="text" placeholder="Dead-letter queue (optional)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="queueMaxReceives" type="number" placeholder="Max receives before dead-letter"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <label class="flex items-center space-x-2 text-sm text-gray-700">
+                    <input id="queueContentDedup" type="checkbox"> <span>Content-based deduplication (FIFO)</span>
+                </label>
+                <button onclick="createQueue()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Create
+                </button>
+            </div>
+            <p class="text-sm text-gray-500 mt-4">
+                AWS SDKs can use these queues with the endpoint <span id="sqsEndpoint" class="font-mono"></span>
+            </p>
+        </div>
+
+        <!-- Queues Table -->
+        <div class="bg-white rounded-lg shadow overflow-hidden mb-6">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">Queues</h2>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Type</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Available</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">In Flight</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Delayed</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Oldest</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Dead-Letter</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="queuesTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+
+        <!-- Selected Queue -->
+        <div id="queueDetail" class="hidden">
+            <div class="grid grid-cols-1 lg:grid-cols-2 gap-6 mb-6">
+                <div class="bg-white rounded-lg shadow p-6">
+                    <h2 class="text-xl font-semibold mb-4">Depth: <span id="detailName"></span></h2>
+                    <canvas id="depthChart" height="200"></canvas>
+                </div>
+                <div class="bg-white rounded-lg shadow p-6">
+                    <h2 class="text-xl font-semibold mb-4">Age of Oldest Message</h2>
+                    <canvas id="ageChart" height="200"></canvas>
+                </div>
+            </div>
+            <div class="bg-white rounded-lg shadow p-6">
+                <h2 class="text-xl font-semibold mb-4">Send Message</h2>
+                <textarea id="messageBody" rows="3" placeholder="Message body"
+                          class="w-full border rounded px-3 py-2 font-mono text-sm focus:outline-none focus:ring-2 focus:ring-blue-500"></textarea>
+                <div class="grid grid-cols-1 md:grid-cols-4 gap-4 mt-2">
+                    <input id="messageGroup" type="text" placeholder="Message group (FIFO)"
+                           class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <input id="messageDedup" type="text" placeholder="Deduplication ID (FIFO)"
+                           class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <input id="messageDelay" type="number" placeholder="Delay seconds"
+                           class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <button onclick="sendMessage()" class="bg-green-600 text-white px-4 py-2 rounded hover:bg-green-700">Send</button>
+                </div>
+            </div>
+        </div>
+    </div>
+
+    <script>
+        let selectedQueue = null;
+        let depthChart = null;
+        let ageChart = null;
+
+        document.getElementById('sqsEndpoint').textContent = window.location.origin + '/sqs';
+
+        function intOrUndefined(id) {
+            const value = document.getElementById(id).value;
+            return value === '' ? undefined : parseInt(value, 10);
+        }
+
+        function formatAge(seconds) {
+            if (seconds < 60) return Math.round(seconds) + 's';
+            if (seconds < 3600) return Math.round(seconds / 60) + 'm';
+            return (seconds / 3600).toFixed(1) + 'h';
+        }
+
+        async function loadQueues() {
+            const response = await fetch('/api/v1/queues');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('queuesTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(q => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-medium text-gray-900">${q.name}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${q.fifo ? 'FIFO' : 'Standard'}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${q.visible}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${q.in_flight}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${q.delayed}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${formatAge(q.oldest_age_seconds)}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${q.dead_letter_queue ? q.dead_letter_queue + ' after ' + q.max_receive_count : '-'}</td>
+                    <td class="px-6 py-4 text-sm font-medium space-x-2">
+                        <button onclick="selectQueue('${q.name}')" class="text-blue-600 hover:text-blue-900">Details</button>
+                        <button onclick="queueAction('${q.name}', 'redrive')" class="text-green-600 hover:text-green-900">Redrive</button>
+                        <button onclick="queueAction('${q.name}', 'purge')" class="text-yellow-600 hover:text-yellow-900">Purge</button>
+                        <button onclick="deleteQueue('${q.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function createQueue() {
+            const body = {
+                name: document.getElementById('queueName').value,
+                visibility_timeout: intOrUndefined('queueVisibility'),
+                delay_seconds: intOrUndefined('queueDelay'),
+                receive_wait_seconds: intOrUndefined('queueWait'),
+                dead_letter_queue: document.getElementById('queueDLQ').value,
+                max_receive_count: intOrUndefined('queueMaxReceives'),
+                content_based_deduplication: document.getElementById('queueContentDedup').checked
+            };
+
+            try {
+                const response = await fetch('/api/v1/queues', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const result = await response.json();
+                if (!result.success) {
+                    alert('Error: ' + result.error);
+                    return;
+                }
+                loadQueues();
+            } catch (error) {
+                alert('Error creating queue: ' + error.message);
+            }
+        }
+
+        async function queueAction(name, action) {
+            if (action === 'purge' && !confirm('Delete all messages in ' + name + '?')) return;
+
+            const response = await fetch('/api/v1/queues/' + name + '/' + action, { method: 'POST' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            } else if (action === 'redrive') {
+                alert('Moved ' + result.data.moved + ' message(s) back to their source queues');
+            }
+            loadQueues();
+        }
+
+        async function deleteQueue(name) {
+            if (!confirm('Delete queue ' + name + ' and its messages?')) return;
+
+            const response = await fetch('/api/v1/queues/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            if (selectedQueue === name) {
+                selectedQueue = null;
+                document.getElementById('queueDetail').classList.add('hidden');
+            }
+            loadQueues();
+        }
+
+        async function sendMessage() {
+            const body = {
+                body: document.getElementById('messageBody').value,
+                group_id: document.getElementById('messageGroup').value,
+                deduplication_id: document.getElementById('messageDedup').value,
+                delay_seconds: intOrUndefined('messageDelay')
+            };
+
+            const response = await fetch('/api/v1/queues/' + selectedQueue + '/messages', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify(body)
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            document.getElementById('messageBody').value = '';
+            loadQueues();
+        }
+
+        function selectQueue(name) {
+            selectedQueue = name;
+            document.getElementById('detailName').textContent = name;
+            document.getElementById('queueDetail').classList.remove('hidden');
+            loadDetail();
+        }
+
+        async function loadDetail() {
+            if (!selectedQueue) return;
+
+            const response = await fetch('/api/v1/queues/' + selectedQueue + '/samples');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const samples = result.data || [];
+            const labels = samples.map(s => new Date(s.time).toLocaleTimeString());
+            depthChart = drawChart(depthChart, 'depthChart', labels, [
+                { label: 'Available', data: samples.map(s => s.visible), borderColor: '#2563eb' },
+                { label: 'In flight', data: samples.map(s => s.in_flight), borderColor: '#f59e0b' },
+                { label: 'Delayed', data: samples.map(s => s.delayed), borderColor: '#6b7280' }
+            ]);
+            ageChart = drawChart(ageChart, 'ageChart', labels, [
+                { label: 'Oldest message (seconds)', data: samples.map(s => s.oldest_age_seconds), borderColor: '#ef4444' }
+            ]);
+        }
+
+        function drawChart(chart, canvas, labels, datasets) {
+            if (chart) {
+                chart.data.labels = labels;
+                chart.data.datasets.forEach((dataset, i) => dataset.data = datasets[i].data);
+                chart.update();
+                return chart;
+            }
+
+            return new Chart(document.getElementById(canvas), {
+                type: 'line',
+                data: { labels, datasets },
+                options: {
+                    animation: false,
+                    scales: { y: { min: 0 } }
+                }
+            });
+        }
+
+        // Initialize
+        loadQueues();
+        setInterval(() => { loadQueues(); loadDetail(); }, 5000);
+    </script>`
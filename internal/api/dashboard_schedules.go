@@ -0,0 +1,251 @@
+// Scheduled jobs page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleSchedulesDashboard(c *gin.Context) {
+	renderPage(c, "/schedules", schedulesPage)
+}
+
+const schedulesPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Create Schedule Form -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Create Schedule</h2>
+            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+                <input id="jobName" type="text" placeholder="Name"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="jobSchedule" type="text" placeholder="Cron (e.g., */5 * * * *, @daily)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="jobImage" type="text" placeholder="Image (e.g., alpine:latest)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="jobCommand" type="text" placeholder="Shell command (image default if empty)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="jobEnv" type="text" placeholder="Env (KEY=value, comma-separated)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <select id="jobConcurrency" class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                    <option value="allow">Allow overlapping runs</option>
+                    <option value="forbid">Forbid (skip if running)</option>
+                    <option value="replace">Replace running run</option>
+                </select>
+                <input id="jobTimeout" type="number" placeholder="Timeout seconds (none)"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <button onclick="createSchedule()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Create
+                </button>
+            </div>
+        </div>
+
+        <!-- Schedules Table -->
+        <div class="bg-white rounded-lg shadow overflow-hidden mb-6">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">Schedules</h2>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Schedule</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Image</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Concurrency</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Next Run</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Last Run</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Active</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="schedulesTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+
+        <!-- Run History -->
+        <div id="runsPanel" class="hidden bg-white rounded-lg shadow overflow-hidden">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">Runs of <span id="runsName"></span></h2>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Run</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Trigger</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Status</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Exit Code</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Started</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Duration</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="runsTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+            <pre id="runLogs" class="hidden bg-gray-900 text-gray-100 text-xs p-4 overflow-auto max-h-96"></pre>
+        </div>
+    </div>
+
+    <script>
+        let selectedSchedule = null;
+
+        const runStatusClasses = {
+            running: 'status-pending',
+            succeeded: 'status-running',
+            failed: 'status-exited',
+            'timed-out': 'status-exited',
+            replaced: 'status-pending',
+            skipped: 'status-pending',
+            stopped: 'status-exited'
+        };
+
+        function runStatus(run) {
+            if (!run) return '-';
+            return '<span class="' + (runStatusClasses[run.status] || '') + '">' + run.status + '</span>';
+        }
+
+        async function loadSchedules() {
+            const response = await fetch('/api/v1/schedules');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('schedulesTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(job => {
+                const row = document.createElement('tr');
+                const next = job.suspended ? 'suspended' : (job.next_run ? new Date(job.next_run).toLocaleString() : '-');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-medium text-gray-900">${job.name}</td>
+                    <td class="px-6 py-4 text-sm font-mono text-gray-500">${job.schedule}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${job.spec.image}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${job.concurrency}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${next}</td>
+                    <td class="px-6 py-4 text-sm">${runStatus(job.last_run)}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${job.active}</td>
+                    <td class="px-6 py-4 text-sm font-medium space-x-2">
+                        <button onclick="triggerSchedule('${job.name}')" class="text-green-600 hover:text-green-900">Run Now</button>
+                        <button onclick="setSuspended('${job.name}', ${!job.suspended})" class="text-yellow-600 hover:text-yellow-900">${job.suspended ? 'Resume' : 'Suspend'}</button>
+                        <button onclick="selectSchedule('${job.name}')" class="text-blue-600 hover:text-blue-900">Runs</button>
+                        <button onclick="deleteSchedule('${job.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function createSchedule() {
+            const command = document.getElementById('jobCommand').value;
+            const env = document.getElementById('jobEnv').value.split(',').map(s => s.trim()).filter(s => s);
+            const timeout = document.getElementById('jobTimeout').value;
+            const body = {
+                name: document.getElementById('jobName').value,
+                schedule: document.getElementById('jobSchedule').value,
+                concurrency: document.getElementById('jobConcurrency').value,
+                timeout_seconds: timeout ? parseInt(timeout, 10) : 0,
+                spec: {
+                    image: document.getElementById('jobImage').value,
+                    command: command ? ['sh', '-c', command] : undefined,
+                    env
+                }
+            };
+
+            try {
+                const response = await fetch('/api/v1/schedules', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const result = await response.json();
+                if (!result.success) {
+                    alert('Error: ' + result.error);
+                    return;
+                }
+                loadSchedules();
+            } catch (error) {
+                alert('Error creating schedule: ' + error.message);
+            }
+        }
+
+        async function triggerSchedule(name) {
+            const response = await fetch('/api/v1/schedules/' + name + '/run', { method: 'POST' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            if (result.data.status === 'skipped') {
+                alert('Run skipped: ' + result.data.error);
+            }
+            selectSchedule(name);
+            loadSchedules();
+        }
+
+        async function setSuspended(name, suspended) {
+            const response = await fetch('/api/v1/schedules/' + name + '/' + (suspended ? 'suspend' : 'resume'), { method: 'POST' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            loadSchedules();
+        }
+
+        async function deleteSchedule(name) {
+            if (!confirm('Delete schedule ' + name + '? Active runs are stopped.')) return;
+
+            const response = await fetch('/api/v1/schedules/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            if (selectedSchedule === name) {
+                selectedSchedule = null;
+                document.getElementById('runsPanel').classList.add('hidden');
+            }
+            loadSchedules();
+        }
+
+        function selectSchedule(name) {
+            selectedSchedule = name;
+            document.getElementById('runsName').textContent = name;
+            document.getElementById('runLogs').classList.add('hidden');
+            document.getElementById('runsPanel').classList.remove('hidden');
+            loadRuns();
+        }
+
+        async function loadRuns() {
+            if (!selectedSchedule) return;
+
+            const response = await fetch('/api/v1/schedules/' + selectedSchedule + '/runs?limit=50');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('runsTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(run => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-mono text-gray-900">${run.id}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${run.trigger}</td>
+                    <td class="px-6 py-4 text-sm" title="${run.error || ''}">${runStatus(run)}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${run.exit_code !== undefined ? run.exit_code : '-'}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${new Date(run.started).toLocaleString()}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${run.status === 'running' ? '-' : (run.duration_ms / 1000).toFixed(1) + 's'}</td>
+                    <td class="px-6 py-4 text-sm font-medium">
+                        <button onclick="showLogs('${run.id}')" class="text-blue-600 hover:text-blue-900">Logs</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function showLogs(id) {
+            const response = await fetch('/api/v1/schedules/' + selectedSchedule + '/runs/' + id);
+            const result = await response.json();
+            if (!result.success) return;
+
+            const run = result.data;
+            const logs = document.getElementById('runLogs');
+            logs.textContent = (run.error ? 'Error: ' + run.error + '\n\n' : '') +
+                (run.logs || (run.status === 'running' ? 'Logs are collected when the run finishes.' : '(no output)'));
+            logs.classList.remove('hidden');
+        }
+
+        // Initialize
+        loadSchedules();
+        setInterval(() => { loadSchedules(); loadRuns(); }, 5000);
+    </script>`
@@ -0,0 +1,157 @@
+// Secrets page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleSecretsDashboard(c *gin.Context) {
+	renderPage(c, "/secrets", secretsPage)
+}
+
+const secretsPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Create Secret Form -->
+        <div class="bg-white rounded-lg shadow mb-6 p-6">
+            <h2 class="text-xl font-semibold mb-4">Create Secret</h2>
+            <div class="grid grid-cols-1 md:grid-cols-4 gap-4">
+                <input id="secretName" type="text" placeholder="Name"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="secretDescription" type="text" placeholder="Description"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <input id="secretValue" type="password" placeholder="Value (generated if empty)" autocomplete="new-password"
+                       class="border rounded px-3 py-2 focus:outline-none focus:ring-2 focus:ring-blue-500">
+                <button onclick="createSecret()"
+                        class="bg-blue-600 text-white px-4 py-2 rounded hover:bg-blue-700">
+                    Create
+                </button>
+            </div>
+            <p class="text-sm text-gray-500 mt-4">
+                Reference secrets when creating instances, e.g.
+                <span class="font-mono">"secrets": [{"name": "db-password", "env": "DB_PASSWORD"}, {"name": "tls-key", "file": "key.pem"}]</span>.
+                Files are mounted under /run/secrets.
+            </p>
+        </div>
+
+        <!-- Secrets Table -->
+        <div class="bg-white rounded-lg shadow overflow-hidden">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">Secrets</h2>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Description</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Value</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Version</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Updated</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="secretsTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+    </div>
+
+    <script>
+        const masked = '••••••••';
+
+        async function loadSecrets() {
+            const response = await fetch('/api/v1/secrets');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('secretsTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(secret => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-medium text-gray-900">${secret.name}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${secret.description || '-'}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500 font-mono break-all" id="value-${secret.name}">${masked}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">v${secret.current_version} (${secret.versions.length} total)</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${new Date(secret.updated).toLocaleString()}</td>
+                    <td class="px-6 py-4 text-sm font-medium space-x-2">
+                        <button onclick="toggleValue('${secret.name}', this)" class="text-blue-600 hover:text-blue-900">Reveal</button>
+                        <button onclick="rotateSecret('${secret.name}')" class="text-green-600 hover:text-green-900">Rotate</button>
+                        <button onclick="deleteSecret('${secret.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function createSecret() {
+            const body = {
+                name: document.getElementById('secretName').value,
+                description: document.getElementById('secretDescription').value,
+                value: document.getElementById('secretValue').value
+            };
+
+            try {
+                const response = await fetch('/api/v1/secrets', {
+                    method: 'POST',
+                    headers: { 'Content-Type': 'application/json' },
+                    body: JSON.stringify(body)
+                });
+                const result = await response.json();
+                if (!result.success) {
+                    alert('Error: ' + result.error);
+                    return;
+                }
+                ['secretName', 'secretDescription', 'secretValue'].forEach(id => document.getElementById(id).value = '');
+                loadSecrets();
+            } catch (error) {
+                alert('Error creating secret: ' + error.message);
+            }
+        }
+
+        // Values are fetched only on request and hidden again on the next refresh
+        async function toggleValue(name, button) {
+            const cell = document.getElementById('value-' + name);
+            if (button.textContent === 'Hide') {
+                cell.textContent = masked;
+                button.textContent = 'Reveal';
+                return;
+            }
+
+            const response = await fetch('/api/v1/secrets/' + name + '/value');
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            cell.textContent = result.data.value;
+            button.textContent = 'Hide';
+        }
+
+        async function rotateSecret(name) {
+            const value = prompt('New value for ' + name + ' (leave empty to generate one):');
+            if (value === null) return;
+
+            const response = await fetch('/api/v1/secrets/' + name + '/rotate', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ value })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            loadSecrets();
+        }
+
+        async function deleteSecret(name) {
+            if (!confirm('Delete secret ' + name + ' and all its versions?')) return;
+
+            const response = await fetch('/api/v1/secrets/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            loadSecrets();
+        }
+
+        // Initialize
+        loadSecrets();
+        setInterval(loadSecrets, 30000);
+    </script>`
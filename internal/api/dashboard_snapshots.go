@@ -0,0 +1,101 @@
+// Snapshots page of the web UI
+package api
+
+import "github.com/gin-gonic/gin"
+
+func (s *Server) handleSnapshotsDashboard(c *gin.Context) {
+	renderPage(c, "/snapshots", snapshotsPage)
+}
+
+const snapshotsPage = `    <div class="container mx-auto px-4 pb-8">
+        <!-- Snapshots Table -->
+        <div class="bg-white rounded-lg shadow overflow-hidden">
+            <div class="px-6 py-4 border-b">
+                <h2 class="text-xl font-semibold">Snapshots</h2>
+                <p class="text-sm text-gray-500 mt-1">
+                    Create snapshots from the Containers page. Volumes are not included.
+                </p>
+            </div>
+            <table class="min-w-full divide-y divide-gray-200">
+                <thead class="bg-gray-50">
+                    <tr>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Name</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Description</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Source</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Base Image</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Size</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Created</th>
+                        <th class="px-6 py-3 text-left text-xs font-medium text-gray-500 uppercase tracking-wider">Actions</th>
+                    </tr>
+                </thead>
+                <tbody id="snapshotsTable" class="divide-y divide-gray-200"></tbody>
+            </table>
+        </div>
+    </div>
+
+    <script>
+        async function loadSnapshots() {
+            const response = await fetch('/api/v1/snapshots');
+            const result = await response.json();
+            if (!result.success) return;
+
+            const tbody = document.getElementById('snapshotsTable');
+            tbody.innerHTML = '';
+            (result.data || []).forEach(snapshot => {
+                const row = document.createElement('tr');
+                row.innerHTML = ` + "`" + `
+                    <td class="px-6 py-4 text-sm font-medium text-gray-900">${snapshot.name}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${snapshot.description || '-'}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${snapshot.source}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${snapshot.source_image}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${formatBytes(snapshot.size_bytes)}</td>
+                    <td class="px-6 py-4 text-sm text-gray-500">${new Date(snapshot.created).toLocaleString()}</td>
+                    <td class="px-6 py-4 text-sm font-medium space-x-2">
+                        <button onclick="launchSnapshot('${snapshot.name}')" class="text-blue-600 hover:text-blue-900">Launch</button>
+                        <button onclick="deleteSnapshot('${snapshot.name}')" class="text-red-600 hover:text-red-900">Delete</button>
+                    </td>
+                ` + "`" + `;
+                tbody.appendChild(row);
+            });
+        }
+
+        async function launchSnapshot(name) {
+            const ports = prompt('Ports for the new instance (e.g., 8080:80, empty for none):');
+            if (ports === null) return;
+
+            const response = await fetch('/api/v1/containers', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ snapshot: name, ports })
+            });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+                return;
+            }
+            alert('Launched ' + result.data.name);
+        }
+
+        async function deleteSnapshot(name) {
+            if (!confirm('Delete snapshot ' + name + '?')) return;
+
+            const response = await fetch('/api/v1/snapshots/' + name, { method: 'DELETE' });
+            const result = await response.json();
+            if (!result.success) {
+                alert('Error: ' + result.error);
+            }
+            loadSnapshots();
+        }
+
+        function formatBytes(bytes) {
+            if (bytes === 0) return '0 B';
+            const k = 1024;
+            const sizes = ['B', 'KB', 'MB', 'GB'];
+            const i = Math.floor(Math.log(bytes) / Math.log(k));
+            return parseFloat((bytes / Math.pow(k, i)).toFixed(2)) + ' ' + sizes[i];
+        }
+
+        // Initialize
+        loadSnapshots();
+        setInterval(loadSnapshots, 30000);
+    </script>`
@@ -0,0 +1,148 @@
+// Managed database handlers
+package api
+
+import (
+	"net/http"
+
+	"localcloud/internal/databases"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listDatabases(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.databases.List(),
+	})
+}
+
+func (s *Server) createDatabase(c *gin.Context) {
+	var req databases.Database
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	// provisioning continues in the background
+	db, err := s.databases.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, Response{
+		Success: true,
+		Data:    db,
+	})
+}
+
+func (s *Server) getDatabase(c *gin.Context) {
+	db, err := s.databases.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    db,
+	})
+}
+
+func (s *Server) deleteDatabase(c *gin.Context) {
+	if err := s.databases.Delete(c.Param("name")); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) listDatabaseBackups(c *gin.Context) {
+	backups, err := s.databases.Backups(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    backups,
+	})
+}
+
+func (s *Server) createDatabaseBackup(c *gin.Context) {
+	backup, err := s.databases.Backup(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    backup,
+	})
+}
+
+func (s *Server) downloadDatabaseBackup(c *gin.Context) {
+	path, err := s.databases.BackupFile(c.Param("name"), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.FileAttachment(path, c.Param("name")+"-"+c.Param("id")+".sql")
+}
+
+func (s *Server) restoreDatabase(c *gin.Context) {
+	// empty backup_id restores the latest backup
+	var req struct {
+		BackupID string `json:"backup_id"`
+	}
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid request format",
+			})
+			return
+		}
+	}
+
+	backup, err := s.databases.Restore(c.Param("name"), req.BackupID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    backup,
+	})
+}
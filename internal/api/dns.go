@@ -0,0 +1,147 @@
+// DNS record handlers and service discovery sources
+package api
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dns"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listDNSRecords(c *gin.Context) {
+	records := s.dns.Records()
+	if name := c.Query("name"); name != "" {
+		records = s.dns.Lookup(name)
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    records,
+	})
+}
+
+func (s *Server) createDNSRecord(c *gin.Context) {
+	var req dns.Record
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	record, err := s.dns.AddRecord(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    record,
+	})
+}
+
+func (s *Server) deleteDNSRecords(c *gin.Context) {
+	// custom records only; type narrows the match
+	removed, err := s.dns.DeleteRecords(c.Query("name"), c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    gin.H{"removed": removed},
+	})
+}
+
+// Records for auto scaling groups: <group>.asg.<zone> and _<group>._tcp.<zone>
+func (s *Server) scalingRecords() []dns.Record {
+	var records []dns.Record
+	for _, group := range s.scaling.List() {
+		name := group.Name + ".asg"
+
+		// Container port from a template mapping like ":80"
+		port := 0
+		if mappings, err := compute.ParsePorts(group.Template.Ports); err == nil && len(mappings) > 0 {
+			port = mappings[0].ContainerPort
+		}
+
+		for _, instance := range group.Instances {
+			if instance.State != "running" || instance.IP == "" {
+				continue
+			}
+			records = append(records, dns.Record{Name: name, Type: "A", Value: instance.IP, Source: "group"})
+			if port > 0 {
+				target := instance.Name + "." + s.dns.Zone()
+				records = append(records, dns.Record{Name: s.dns.ServiceName(group.Name), Type: "SRV", Value: target, Port: port, Source: "group"})
+			}
+		}
+	}
+	return records
+}
+
+// Records for load balancers, which listen on the host: <lb>.lb.<zone> and _<lb>._tcp.<zone>
+func (s *Server) balancerRecords(hostIP string) func() []dns.Record {
+	return func() []dns.Record {
+		var records []dns.Record
+		for _, lb := range s.balancers.List() {
+			if !lb.Running {
+				continue
+			}
+			name := lb.Name + ".lb"
+			records = append(records,
+				dns.Record{Name: name, Type: "A", Value: hostIP, Source: "loadbalancer"},
+				dns.Record{Name: s.dns.ServiceName(lb.Name), Type: "SRV", Value: name + "." + s.dns.Zone(), Port: lb.ListenPort, Source: "loadbalancer"},
+			)
+		}
+		return records
+	}
+}
+
+// Start the DNS server and point new containers at it
+func (s *Server) startDNS(ctx context.Context) error {
+	gateway, err := s.manager.BridgeGateway()
+	if err != nil {
+		return err
+	}
+
+	addr := s.config.DNSAddr
+	if addr == "" {
+		addr = net.JoinHostPort(gateway, "53")
+	}
+	resolver, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(resolver); ip == nil || ip.IsUnspecified() {
+		resolver = gateway
+	}
+
+	s.dns.AddSource(s.scalingRecords)
+	s.dns.AddSource(s.balancerRecords(gateway))
+	if err := s.dns.Start(ctx, addr); err != nil {
+		return err
+	}
+
+	// Upstream stays as a fallback in case LocalCloud is not running
+	servers := []string{resolver}
+	if upstream, _, err := net.SplitHostPort(s.config.DNSUpstream); err == nil {
+		servers = append(servers, upstream)
+	}
+	s.manager.UseDNS(servers, s.dns.Zone())
+	log.Printf("LocalCloud DNS serving %s on %s", s.dns.Zone(), addr)
+	return nil
+}
@@ -0,0 +1,206 @@
+// Event bus handlers
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"localcloud/internal/eventbus"
+
+	"github.com/gin-gonic/gin"
+)
+
+func eventBusErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, eventbus.ErrRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, eventbus.ErrRuleExists):
+		return http.StatusConflict
+	case errors.Is(err, eventbus.ErrInvalidRule), errors.Is(err, eventbus.ErrInvalidEvent):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) listEventRules(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.events.ListRules(),
+	})
+}
+
+func (s *Server) createEventRule(c *gin.Context) {
+	var req eventbus.Rule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	rule, err := s.events.CreateRule(req)
+	if err != nil {
+		c.JSON(eventBusErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    rule,
+	})
+}
+
+func (s *Server) getEventRule(c *gin.Context) {
+	rule, err := s.events.GetRule(c.Param("name"))
+	if err != nil {
+		c.JSON(eventBusErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    rule,
+	})
+}
+
+func (s *Server) updateEventRule(c *gin.Context) {
+	var req eventbus.Rule
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	rule, err := s.events.UpdateRule(c.Param("name"), req)
+	if err != nil {
+		c.JSON(eventBusErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    rule,
+	})
+}
+
+func (s *Server) deleteEventRule(c *gin.Context) {
+	if err := s.events.DeleteRule(c.Param("name")); err != nil {
+		c.JSON(eventBusErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) enableEventRule(c *gin.Context) {
+	s.setEventRuleEnabled(c, true)
+}
+
+func (s *Server) disableEventRule(c *gin.Context) {
+	s.setEventRuleEnabled(c, false)
+}
+
+func (s *Server) setEventRuleEnabled(c *gin.Context, enabled bool) {
+	rule, err := s.events.SetEnabled(c.Param("name"), enabled)
+	if err != nil {
+		c.JSON(eventBusErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    rule,
+	})
+}
+
+// POST /eventbus/events takes {"events": [...]} of custom events
+func (s *Server) putEvents(c *gin.Context) {
+	var req struct {
+		Events []eventbus.Event `json:"events" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	events, err := s.events.Put(req.Events)
+	if err != nil {
+		c.JSON(eventBusErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    events,
+	})
+}
+
+func (s *Server) listEvents(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.events.Events(),
+	})
+}
+
+// GET /eventbus/deliveries?rule=&status=
+func (s *Server) listEventDeliveries(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.events.Deliveries(c.Query("rule"), c.Query("status")),
+	})
+}
+
+func (s *Server) testEventPattern(c *gin.Context) {
+	var req struct {
+		EventPattern json.RawMessage `json:"event_pattern" binding:"required"`
+		Event        eventbus.Event  `json:"event"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	matched, err := s.events.TestPattern(req.EventPattern, req.Event)
+	if err != nil {
+		c.JSON(eventBusErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    gin.H{"matched": matched},
+	})
+}
@@ -0,0 +1,342 @@
+// File copy handlers
+package api
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"localcloud/internal/compute"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Text files up to this size are previewed and editable in the dashboard
+const maxPreviewSize = 1 << 20
+
+func fileErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, compute.ErrPathNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, compute.ErrNotADirectory), errors.Is(err, compute.ErrIsADirectory):
+		return http.StatusBadRequest
+	case errors.Is(err, compute.ErrPermissionDenied):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// A directory listing or a file preview
+type pathContents struct {
+	*compute.FileInfo
+	Entries   []compute.FileInfo `json:"entries,omitempty"`
+	Content   string             `json:"content"`
+	Binary    bool               `json:"binary"`
+	Truncated bool               `json:"truncated"`
+	Editable  bool               `json:"editable"`
+}
+
+// List a directory, or preview a file. Binary and large files are only
+// described; fetch them with GET /containers/:id/files.
+func (s *Server) browseContainerPath(c *gin.Context) {
+	containerID := c.Param("id")
+	filePath := c.DefaultQuery("path", "/")
+	if !path.IsAbs(filePath) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "path must be an absolute path",
+		})
+		return
+	}
+
+	info, err := s.manager.StatPath(containerID, path.Clean(filePath))
+	if err == nil && info.LinkTarget != "" && info.LinkTarget != info.Path {
+		info, err = s.manager.StatPath(containerID, info.LinkTarget)
+	}
+	if err != nil {
+		c.JSON(fileErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	contents := pathContents{FileInfo: info}
+
+	if info.IsDir {
+		contents.Entries, err = s.manager.ListDir(containerID, info.Path)
+		if err != nil {
+			c.JSON(fileErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+			Data:    contents,
+		})
+		return
+	}
+
+	if info.Mode.IsRegular() {
+		reader, _, err := s.manager.OpenFile(containerID, info.Path)
+		if err != nil {
+			c.JSON(fileErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, maxPreviewSize+1))
+		reader.Close()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, Response{
+				Success: false,
+				Error:   "failed to read file: " + err.Error(),
+			})
+			return
+		}
+
+		if len(data) > maxPreviewSize {
+			data = data[:maxPreviewSize]
+			contents.Truncated = true
+			// the cut may split a multi-byte character
+			for i := 0; i < utf8.UTFMax && !utf8.Valid(data); i++ {
+				data = data[:len(data)-1]
+			}
+		}
+		contents.Binary = bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data)
+		if !contents.Binary {
+			contents.Content = string(data)
+		}
+		contents.Editable = !contents.Binary && !contents.Truncated
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    contents,
+	})
+}
+
+// Save a text file edited in the dashboard, keeping its mode and owner
+func (s *Server) saveContainerFile(c *gin.Context) {
+	containerID := c.Param("id")
+	filePath := c.Query("path")
+	if !path.IsAbs(filePath) || path.Clean(filePath) == "/" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "path must be an absolute file path",
+		})
+		return
+	}
+
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := s.manager.WriteFile(containerID, path.Clean(filePath), []byte(req.Content)); err != nil {
+		c.JSON(fileErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	info, err := s.manager.StatPath(containerID, path.Clean(filePath))
+	if err != nil {
+		c.JSON(fileErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    info,
+	})
+}
+
+// Download a file as is, or a directory (or anything with format=tar) as a tar
+func (s *Server) downloadContainerFile(c *gin.Context) {
+	filePath := c.Query("path")
+	if !path.IsAbs(filePath) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "path must be an absolute path",
+		})
+		return
+	}
+
+	reader, info, err := s.manager.CopyFrom(c.Param("id"), filePath)
+	if err != nil {
+		c.JSON(fileErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer reader.Close()
+
+	c.Header("X-File-Mode", fmt.Sprintf("%04o", info.Mode.Perm()))
+	if info.IsDir || c.Query("format") == "tar" {
+		c.Header("Content-Type", "application/x-tar")
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name + ".tar"}))
+		c.Status(http.StatusOK)
+		io.Copy(c.Writer, reader)
+		return
+	}
+
+	// a single file is the only entry in the archive
+	tr := tar.NewReader(reader)
+	header, err := tr.Next()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   "failed to read file: " + err.Error(),
+		})
+		return
+	}
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": info.Name}))
+	c.Header("Content-Length", strconv.FormatInt(header.Size, 10))
+	c.Status(http.StatusOK)
+	io.Copy(c.Writer, tr)
+}
+
+// Upload files with multipart/form-data (field "file", repeatable, and an
+// optional octal "mode"), or a file or directory as an application/x-tar body
+func (s *Server) uploadContainerFile(c *gin.Context) {
+	containerID := c.Param("id")
+	dstPath := c.Query("path")
+	if !path.IsAbs(dstPath) {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "path must be an absolute path",
+		})
+		return
+	}
+
+	if strings.HasPrefix(c.ContentType(), "application/x-tar") {
+		if err := s.manager.CopyTo(containerID, dstPath, c.Request.Body); err != nil {
+			c.JSON(fileErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, Response{
+			Success: true,
+		})
+		return
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil || len(form.File["file"]) == 0 {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Expected multipart/form-data with one or more files in the file field, or an application/x-tar body",
+		})
+		return
+	}
+	files := form.File["file"]
+
+	mode := int64(0o644)
+	if modeParam := c.PostForm("mode"); modeParam != "" {
+		parsed, err := strconv.ParseInt(modeParam, 8, 32)
+		if err != nil || parsed < 0 || parsed > 0o7777 {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "mode must be octal, e.g. 0755",
+			})
+			return
+		}
+		mode = parsed
+	}
+
+	// several files can only go into a directory
+	target, err := s.manager.StatPath(containerID, dstPath)
+	isDir := err == nil && target.IsDir
+	if len(files) > 1 && !isDir {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "path must be an existing directory when uploading several files",
+		})
+		return
+	}
+
+	uploaded := make([]*compute.FileInfo, 0, len(files))
+	for _, file := range files {
+		if err := s.copyUploadedFile(containerID, dstPath, file, mode); err != nil {
+			c.JSON(fileErrorStatus(err), Response{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+
+		finalPath := dstPath
+		if isDir {
+			finalPath = path.Join(dstPath, path.Base(file.Filename))
+		}
+		if info, err := s.manager.StatPath(containerID, finalPath); err == nil {
+			uploaded = append(uploaded, info)
+		}
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    uploaded,
+	})
+}
+
+// Wrap one uploaded file in a tar for CopyTo
+func (s *Server) copyUploadedFile(containerID, dstPath string, file *multipart.FileHeader, mode int64) error {
+	src, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	// The goroutine owns src: CopyTo may return before it is done reading,
+	// and closing pr below makes its next write fail so it returns
+	pr, pw := io.Pipe()
+	go func() {
+		defer src.Close()
+		tw := tar.NewWriter(pw)
+		header := &tar.Header{
+			Name:    path.Base(file.Filename),
+			Mode:    mode,
+			Size:    file.Size,
+			ModTime: time.Now(),
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(tw, src); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(tw.Close())
+	}()
+
+	err = s.manager.CopyTo(containerID, dstPath, pr)
+	pr.Close()
+	return err
+}
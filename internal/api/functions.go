@@ -0,0 +1,151 @@
+// Serverless function handlers
+package api
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+
+	"localcloud/internal/functions"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listFunctions(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.functions.List(),
+	})
+}
+
+func (s *Server) getFunction(c *gin.Context) {
+	fn, err := s.functions.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    fn,
+	})
+}
+
+func (s *Server) deployFunction(c *gin.Context) {
+	// multipart form: settings as fields, code as a zip in "code"
+	file, err := c.FormFile("code")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "code zip file is required",
+		})
+		return
+	}
+	reader, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	code, err := io.ReadAll(reader)
+	reader.Close()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	spec := functions.Function{
+		Name:    c.PostForm("name"),
+		Runtime: c.PostForm("runtime"),
+		Handler: c.PostForm("handler"),
+		Env:     c.PostFormArray("env"),
+	}
+	spec.MemoryMB, _ = strconv.Atoi(c.PostForm("memory_mb"))
+	spec.Timeout, _ = strconv.Atoi(c.PostForm("timeout_seconds"))
+
+	fn, buildLog, err := s.functions.Deploy(spec, code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Data:    gin.H{"build_log": buildLog},
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    gin.H{"function": fn, "build_log": buildLog},
+	})
+}
+
+func (s *Server) deleteFunction(c *gin.Context) {
+	if err := s.functions.Delete(c.Param("name")); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) invokeFunction(c *gin.Context) {
+	// request body is the event
+	event, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	invocation, err := s.functions.Invoke(c.Request.Context(), c.Param("name"), event)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    invocation,
+	})
+}
+
+func (s *Server) getFunctionInvocations(c *gin.Context) {
+	limit := 20
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsed, err := strconv.Atoi(limitParam); err == nil {
+			limit = parsed
+		}
+	}
+
+	invocations, err := s.functions.Invocations(c.Param("name"), limit)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    invocations,
+	})
+}
@@ -1,9 +1,13 @@
 package api
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
+	"localcloud/internal/compute"
+	"localcloud/internal/operations"
+
 	"github.com/gin-gonic/gin"
 )
 
@@ -20,12 +24,8 @@ func (s *Server) listContainers(c *gin.Context) {
 }
 
 func (s *Server) createContainer(c *gin.Context) {
-	var req struct {
-		Image string `json:"image"`
-		Name  string `json:"name"`
-		Ports string `json:"ports"`
-	}
-	
+	var req compute.CreateSpec
+
 	// Validate json 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, Response{
@@ -35,42 +35,36 @@ func (s *Server) createContainer(c *gin.Context) {
 		return
 	}
 
-	if req.Image == "" {
+	// Callers acting for a project or user can name it in a header
+	if req.Project == "" {
+		req.Project = c.GetHeader("X-LocalCloud-Project")
+	}
+
+	if req.Image == "" && req.Snapshot == "" {
 		req.Image = "nginx:latest" // Defualt image
 	}
 	
-	// Create container using manager
-	instance, err := s.manager.Create(req.Image, req.Name, req.Ports)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Success: false,
-			Error:   err.Error(),
-		})
-		return
+	target := req.Name
+	if target == "" {
+		target = req.Image
 	}
 
-	// return container info
-	c.JSON(http.StatusCreated, Response{
-		Success: true,
-		Data:    instance,
-	})
+	// Create container using manager, pulling the image first if needed
+	s.runOperation(c, "instance.create", target, http.StatusCreated, createErrorStatus,
+		func(ctx context.Context, progress operations.Progress) (interface{}, error) {
+			return s.manager.CreateWithProgress(ctx, req, compute.ProgressFunc(progress))
+		})
 }
 
 func (s *Server) deleteContainer(c *gin.Context) {
 	containerID := c.Param("id")
 	
 	// delete container
-	if err := s.manager.Delete(containerID); err != nil {
-		c.JSON(http.StatusInternalServerError, Response{
-			Success: false,
-			Error:   err.Error(),
+	s.runOperation(c, "instance.delete", containerID, http.StatusOK, internalErrorStatus,
+		func(ctx context.Context, progress operations.Progress) (interface{}, error) {
+			progress("deleting container", 10)
+			return nil, s.manager.Delete(containerID)
 		})
-		return
-	}
-
-	c.JSON(http.StatusOK, Response{
-		Success: true,
-	})
 }
 
 func (s *Server) getContainerLogs(c *gin.Context) {
@@ -83,6 +77,12 @@ func (s *Server) getContainerLogs(c *gin.Context) {
 		}
 	}
 	
+	// ?follow=true streams plain text until the container stops
+	if follow, _ := strconv.ParseBool(c.Query("follow")); follow {
+		s.followContainerLogs(c, containerID, tail)
+		return
+	}
+
 	// container logs as plain text	
 	logs, err := s.manager.GetLogs(containerID, tail)
 	if err != nil {
@@ -117,6 +117,24 @@ func (s *Server) getContainerMetrics(c *gin.Context) {
 	})
 }
 
+func (s *Server) getContainerHealth(c *gin.Context) {
+	containerID := c.Param("id")
+	// current health status and recent check results
+	report, err := s.manager.HealthReport(containerID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
 func (s *Server) execContainer(c *gin.Context) {
 	containerID := c.Param("id")
 	
@@ -147,3 +165,35 @@ func (s *Server) execContainer(c *gin.Context) {
 		Data:    output,
 	})
 }
+
+// Stream logs, flushing each chunk so clients see lines as they come
+func (s *Server) followContainerLogs(c *gin.Context, containerID string, tail int) {
+	logs, err := s.manager.FollowLogs(c.Request.Context(), containerID, tail)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+	defer logs.Close()
+
+	c.Header("Content-Type", "text/plain; charset=utf-8")
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := logs.Read(buf)
+		if n > 0 {
+			if _, werr := c.Writer.Write(buf[:n]); werr != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+		if err != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,38 @@
+// Instance type handlers
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"localcloud/internal/operations"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listInstanceTypes(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.manager.InstanceTypes(),
+	})
+}
+
+func (s *Server) resizeContainer(c *gin.Context) {
+	var req struct {
+		InstanceType string `json:"instance_type" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	id := c.Param("id")
+	s.runOperation(c, "instance.resize", id, http.StatusOK, createErrorStatus,
+		func(ctx context.Context, progress operations.Progress) (interface{}, error) {
+			progress("resizing to "+req.InstanceType, 10)
+			return s.manager.Resize(id, req.InstanceType)
+		})
+}
@@ -0,0 +1,98 @@
+// Load balancer handlers
+package api
+
+import (
+	"net/http"
+
+	"localcloud/internal/loadbalancer"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) listLoadBalancers(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.balancers.List(),
+	})
+}
+
+func (s *Server) createLoadBalancer(c *gin.Context) {
+	var req loadbalancer.Balancer
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	balancer, err := s.balancers.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    balancer,
+	})
+}
+
+func (s *Server) getLoadBalancer(c *gin.Context) {
+	// includes per-target request metrics
+	status, err := s.balancers.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    status,
+	})
+}
+
+func (s *Server) setLoadBalancerTargets(c *gin.Context) {
+	var req loadbalancer.TargetGroup
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	balancer, err := s.balancers.SetTargets(c.Param("name"), req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    balancer,
+	})
+}
+
+func (s *Server) deleteLoadBalancer(c *gin.Context) {
+	if err := s.balancers.Delete(c.Param("name")); err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
@@ -0,0 +1,387 @@
+// OpenAPI document for the REST API, built from the endpoint table and the
+// Go types handlers bind and return, so it can't drift from the code
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/operations"
+	"localcloud/internal/parameters"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Version of the API described by the document
+const apiVersion = "1.0.0"
+
+// An API route and what it takes and returns
+type endpoint struct {
+	method  string
+	path    string // as registered with gin, under /api/v1
+	tag     string
+	summary string
+	query   []queryParam
+
+	body        interface{} // JSON request body
+	bodyContent string      // other request media type, e.g. multipart/form-data, with body as its fields
+	optional    bool        // request body may be left out
+
+	status  int         // success status, 200 if zero
+	data    interface{} // JSON response data
+	content string      // other response media type, e.g. text/plain
+
+	async bool // accepts ?async=true, see operations
+}
+
+type queryParam struct {
+	name        string
+	kind        string // string, integer, number or boolean
+	description string
+}
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+)
+
+func (s *Server) getOpenAPI(c *gin.Context) {
+	openAPIOnce.Do(func() {
+		doc, err := json.MarshalIndent(s.openAPI(), "", "  ")
+		if err != nil {
+			panic(err) // the document is built from static values
+		}
+		openAPIDoc = doc
+	})
+	c.Data(http.StatusOK, "application/json", openAPIDoc)
+}
+
+// The OpenAPI 3 document for every route under /api/v1, plus /ws
+func (s *Server) openAPI() map[string]interface{} {
+	b := &schemaBuilder{schemas: map[string]interface{}{
+		"Response": map[string]interface{}{
+			"type":        "object",
+			"description": "Envelope around every JSON response",
+			"properties": map[string]interface{}{
+				"success": map[string]interface{}{"type": "boolean"},
+				"data":    map[string]interface{}{"description": "Result, described per operation"},
+				"error":   map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"success"},
+		},
+	}}
+
+	paths := map[string]map[string]interface{}{}
+	documented := map[string]bool{}
+	for _, e := range apiEndpoints {
+		addPath(paths, e.method, "/api/v1"+e.path, b.operation(e))
+		documented[e.method+" /api/v1"+e.path] = true
+	}
+
+	// Anything registered but missing from the table still shows up
+	for _, route := range s.router.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/") || documented[route.Method+" "+route.Path] {
+			continue
+		}
+		e := endpoint{method: route.Method, path: strings.TrimPrefix(route.Path, "/api/v1"), tag: "other", summary: handlerName(route.Handler)}
+		addPath(paths, e.method, route.Path, b.operation(e))
+	}
+
+	addPath(paths, http.MethodGet, "/ws", map[string]interface{}{
+		"tags":        []string{"events"},
+		"operationId": "events",
+		"summary":     "WebSocket of live updates",
+		"description": "Upgrade to a WebSocket to receive JSON messages: `containers` (every instance, every 2 seconds), " +
+			"`parameter` (a parameter change) and `operation` (an operation update), each with a `timestamp`.",
+		"responses": map[string]interface{}{
+			"101": map[string]interface{}{
+				"description": "Switching to the WebSocket protocol",
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": b.schema(reflect.TypeOf(liveUpdate{}))},
+				},
+			},
+		},
+	})
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "LocalCloud API",
+			"version": apiVersion,
+			"description": "Every JSON response is wrapped in `{success, data, error}`. " +
+				"Endpoints marked async accept `?async=true` and answer 202 with an operation to poll at `/api/v1/operations/{id}`. " +
+				"AWS SDKs can also use the SQS protocol at `/sqs`. " +
+				"Servers started with LOCALCLOUD_API_TOKEN require it as a bearer token.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": "/"}},
+		"paths":   paths,
+		"components": map[string]interface{}{
+			"schemas": b.schemas,
+			"securitySchemes": map[string]interface{}{
+				"apiToken": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		// the token is only needed when the server has one
+		"security": []interface{}{
+			map[string]interface{}{"apiToken": []string{}},
+			map[string]interface{}{},
+		},
+	}
+}
+
+// Message sent over /ws; only the fields for one kind of update are set
+type liveUpdate struct {
+	Containers []compute.Instance    `json:"containers,omitempty"`
+	Parameter  *parameters.Change    `json:"parameter,omitempty"`
+	Operation  *operations.Operation `json:"operation,omitempty"`
+	Timestamp  time.Time             `json:"timestamp"`
+}
+
+func addPath(paths map[string]map[string]interface{}, method, ginPath string, op map[string]interface{}) {
+	p := openAPIPath(ginPath)
+	if paths[p] == nil {
+		paths[p] = map[string]interface{}{}
+	}
+	paths[p][strings.ToLower(method)] = op
+}
+
+var pathParam = regexp.MustCompile(`[:*](\w+)`)
+
+// /containers/:id becomes /containers/{id}
+func openAPIPath(ginPath string) string {
+	return pathParam.ReplaceAllString(ginPath, "{$1}")
+}
+
+// e.g. listContainers for localcloud/internal/api.(*Server).listContainers-fm
+func handlerName(handler string) string {
+	name := path.Ext(handler)
+	return strings.TrimSuffix(strings.TrimPrefix(name, "."), "-fm")
+}
+
+func (b *schemaBuilder) operation(e endpoint) map[string]interface{} {
+	op := map[string]interface{}{
+		"tags":        []string{e.tag},
+		"summary":     e.summary,
+		"operationId": operationID(e),
+	}
+
+	var params []interface{}
+	for _, match := range pathParam.FindAllStringSubmatch(e.path, -1) {
+		param := map[string]interface{}{
+			"name":     match[1],
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		}
+		if strings.HasPrefix(match[0], "*") {
+			param["description"] = "Hierarchical name; may contain slashes, e.g. app/db/url"
+		}
+		params = append(params, param)
+	}
+	query := append([]queryParam{}, e.query...)
+	if e.async {
+		query = append(query, queryParam{"async", "boolean", "Run in the background and answer 202 with an operation"})
+	}
+	for _, q := range query {
+		params = append(params, map[string]interface{}{
+			"name":        q.name,
+			"in":          "query",
+			"description": q.description,
+			"schema":      map[string]interface{}{"type": q.kind},
+		})
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	if e.body != nil || e.bodyContent != "" {
+		media := e.bodyContent
+		if media == "" {
+			media = "application/json"
+		}
+		schema := map[string]interface{}{"type": "string", "format": "binary"}
+		if e.body != nil {
+			schema = b.schema(reflect.TypeOf(e.body))
+		}
+		op["requestBody"] = map[string]interface{}{
+			"required": !e.optional,
+			"content":  map[string]interface{}{media: map[string]interface{}{"schema": schema}},
+		}
+	}
+
+	status := e.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	if e.content != "" {
+		success["content"] = map[string]interface{}{
+			e.content: map[string]interface{}{"schema": map[string]interface{}{"type": "string", "format": "binary"}},
+		}
+	} else {
+		success["content"] = map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": b.envelope(e.data)},
+		}
+	}
+	responses := map[string]interface{}{
+		strconv.Itoa(status): success,
+		"default": map[string]interface{}{
+			"description": "Error",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/Response"}},
+			},
+		},
+	}
+	if e.async {
+		responses["202"] = map[string]interface{}{
+			"description": "Started in the background (with ?async=true); the Location header points at the operation",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": b.envelope(asyncOperation)},
+			},
+		}
+	}
+	op["responses"] = responses
+	return op
+}
+
+// Response envelope with data of the given Go value's type
+func (b *schemaBuilder) envelope(data interface{}) map[string]interface{} {
+	ref := map[string]interface{}{"$ref": "#/components/schemas/Response"}
+	if data == nil {
+		return ref
+	}
+	return map[string]interface{}{
+		"allOf": []interface{}{
+			ref,
+			map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"data": b.schema(reflect.TypeOf(data))},
+			},
+		},
+	}
+}
+
+// e.g. GET /containers/:id/logs becomes getContainersIdLogs
+func operationID(e endpoint) string {
+	var id strings.Builder
+	id.WriteString(strings.ToLower(e.method))
+	for _, part := range strings.FieldsFunc(e.path, func(r rune) bool { return r == '/' || r == '-' }) {
+		part = strings.TrimLeft(part, ":*")
+		id.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return id.String()
+}
+
+// JSON schemas for Go types, with named structs collected as components
+type schemaBuilder struct {
+	schemas map[string]interface{}
+}
+
+var (
+	timeType = reflect.TypeOf(time.Time{})
+	rawType  = reflect.TypeOf(json.RawMessage{})
+	fileType = reflect.TypeOf(formFile(""))
+)
+
+func (b *schemaBuilder) schema(t reflect.Type) map[string]interface{} {
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawType:
+		return map[string]interface{}{"description": "Any JSON value"}
+	case fileType:
+		return map[string]interface{}{"type": "string", "format": "binary"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		if t == reflect.TypeOf(time.Duration(0)) {
+			return map[string]interface{}{"type": "integer", "format": "int64", "description": "Nanoseconds"}
+		}
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": b.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.object(t)
+		}
+		name := path.Base(t.PkgPath()) + "." + t.Name()
+		if _, ok := b.schemas[name]; !ok {
+			b.schemas[name] = map[string]interface{}{} // placeholder in case the type refers to itself
+			b.schemas[name] = b.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{} // interface{}: anything
+}
+
+func (b *schemaBuilder) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	b.fields(t, properties, &required)
+
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// Add t's JSON fields, flattening embedded structs as encoding/json does
+func (b *schemaBuilder) fields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				b.fields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schema(field.Type)
+		if strings.Contains(field.Tag.Get("binding"), "required") {
+			*required = append(*required, name)
+		}
+	}
+}
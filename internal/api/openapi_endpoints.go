@@ -0,0 +1,455 @@
+// Every route under /api/v1, for the OpenAPI document. Add new routes here
+// along with setupRoutes.
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"localcloud/internal/alarms"
+	"localcloud/internal/autoscaling"
+	"localcloud/internal/backups"
+	"localcloud/internal/batch"
+	"localcloud/internal/billing"
+	"localcloud/internal/bundles"
+	"localcloud/internal/compute"
+	"localcloud/internal/databases"
+	"localcloud/internal/dns"
+	"localcloud/internal/eventbus"
+	"localcloud/internal/functions"
+	"localcloud/internal/loadbalancer"
+	"localcloud/internal/operations"
+	"localcloud/internal/parameters"
+	"localcloud/internal/queues"
+	"localcloud/internal/quotas"
+	"localcloud/internal/scheduler"
+	"localcloud/internal/secrets"
+)
+
+// A file field in a multipart form
+type formFile string
+
+// Returned by endpoints started with ?async=true
+var asyncOperation = operations.Operation{}
+
+var (
+	limitParam   = queryParam{"limit", "integer", "Most recent entries to return"}
+	decryptParam = queryParam{"decrypt", "boolean", "Return SecureString values in plain text"}
+)
+
+var apiEndpoints = []endpoint{
+	// Instances
+	{method: http.MethodGet, path: "/containers", tag: "instances", summary: "List instances",
+		data: []compute.Instance{}},
+	{method: http.MethodPost, path: "/containers", tag: "instances", summary: "Create an instance, pulling its image if needed",
+		body: compute.CreateSpec{}, status: http.StatusCreated, data: compute.Instance{}, async: true},
+	{method: http.MethodDelete, path: "/containers/:id", tag: "instances", summary: "Stop and remove an instance",
+		async: true},
+	{method: http.MethodGet, path: "/containers/:id/logs", tag: "instances", summary: "Recent output, or a plain text stream with follow=true",
+		query: []queryParam{
+			{"tail", "integer", "Lines to return (default 100)"},
+			{"follow", "boolean", "Keep streaming new output as text/plain until the instance stops"},
+		},
+		data: ""},
+	{method: http.MethodGet, path: "/containers/:id/metrics", tag: "instances", summary: "CPU, memory and network usage",
+		data: compute.Metrics{}},
+	{method: http.MethodGet, path: "/containers/:id/health", tag: "instances", summary: "Health status and recent check results",
+		data: compute.HealthReport{}},
+	{method: http.MethodPost, path: "/containers/:id/exec", tag: "instances", summary: "Run a shell command and return its output",
+		body: struct {
+			Command string `json:"command"`
+		}{}, data: ""},
+	{method: http.MethodGet, path: "/containers/:id/files", tag: "files", summary: "Download a file, or a directory as a tar",
+		query: []queryParam{
+			{"path", "string", "Absolute path in the instance"},
+			{"format", "string", "tar to get a file as a tar archive"},
+		},
+		content: "application/octet-stream"},
+	{method: http.MethodPut, path: "/containers/:id/files", tag: "files", summary: "Upload files as multipart file fields, or an application/x-tar body",
+		query:       []queryParam{{"path", "string", "Absolute destination path"}},
+		bodyContent: "multipart/form-data",
+		body: struct {
+			File formFile `json:"file"`
+			Mode string   `json:"mode"` // octal, e.g. 0755
+		}{},
+		data: []compute.FileInfo{}},
+	{method: http.MethodGet, path: "/containers/:id/fs", tag: "files", summary: "List a directory or preview a text file",
+		query: []queryParam{{"path", "string", "Absolute path (default /)"}},
+		data:  pathContents{}},
+	{method: http.MethodPut, path: "/containers/:id/fs", tag: "files", summary: "Save a text file, keeping its mode and owner",
+		query: []queryParam{{"path", "string", "Absolute file path"}},
+		body: struct {
+			Content string `json:"content"`
+		}{},
+		data: compute.FileInfo{}},
+	{method: http.MethodGet, path: "/ports", tag: "instances", summary: "Host ports in use",
+		data: []compute.PortUsage{}},
+	{method: http.MethodGet, path: "/instance-types", tag: "instances", summary: "List instance types",
+		data: []compute.InstanceType{}},
+	{method: http.MethodPost, path: "/containers/:id/resize", tag: "instances", summary: "Change an instance's type",
+		body: struct {
+			InstanceType string `json:"instance_type" binding:"required"`
+		}{},
+		data: compute.Instance{}, async: true},
+
+	// Snapshots
+	{method: http.MethodGet, path: "/snapshots", tag: "snapshots", summary: "List snapshots",
+		data: []compute.Snapshot{}},
+	{method: http.MethodPost, path: "/snapshots", tag: "snapshots", summary: "Snapshot an instance into an image",
+		body: struct {
+			compute.SnapshotInput
+			InstanceID string `json:"instance_id" binding:"required"`
+		}{},
+		status: http.StatusCreated, data: compute.Snapshot{}, async: true},
+	{method: http.MethodGet, path: "/snapshots/:name", tag: "snapshots", summary: "Get a snapshot",
+		data: compute.Snapshot{}},
+	{method: http.MethodDelete, path: "/snapshots/:name", tag: "snapshots", summary: "Delete a snapshot"},
+
+	// Auto scaling
+	{method: http.MethodGet, path: "/autoscaling/groups", tag: "autoscaling", summary: "List scaling groups",
+		data: []autoscaling.GroupStatus{}},
+	{method: http.MethodPost, path: "/autoscaling/groups", tag: "autoscaling", summary: "Create a scaling group",
+		body: autoscaling.Group{}, status: http.StatusCreated, data: autoscaling.Group{}},
+	{method: http.MethodGet, path: "/autoscaling/groups/:name", tag: "autoscaling", summary: "Get a scaling group and its instances",
+		data: autoscaling.GroupStatus{}},
+	{method: http.MethodPut, path: "/autoscaling/groups/:name/capacity", tag: "autoscaling", summary: "Set min, max or desired capacity; omitted fields are kept",
+		body: struct {
+			Min     *int `json:"min"`
+			Max     *int `json:"max"`
+			Desired *int `json:"desired"`
+		}{},
+		data: autoscaling.Group{}},
+	{method: http.MethodDelete, path: "/autoscaling/groups/:name", tag: "autoscaling", summary: "Delete a scaling group and its instances"},
+	{method: http.MethodGet, path: "/autoscaling/groups/:name/activities", tag: "autoscaling", summary: "Scaling activity log",
+		data: []autoscaling.Activity{}},
+	{method: http.MethodGet, path: "/autoscaling/groups/:name/samples", tag: "autoscaling", summary: "Recent metric samples",
+		data: []autoscaling.Sample{}},
+
+	// Load balancers
+	{method: http.MethodGet, path: "/loadbalancers", tag: "loadbalancers", summary: "List load balancers",
+		data: []loadbalancer.Status{}},
+	{method: http.MethodPost, path: "/loadbalancers", tag: "loadbalancers", summary: "Create a load balancer",
+		body: loadbalancer.Balancer{}, status: http.StatusCreated, data: loadbalancer.Balancer{}},
+	{method: http.MethodGet, path: "/loadbalancers/:name", tag: "loadbalancers", summary: "Get a load balancer with per-target metrics",
+		data: loadbalancer.Status{}},
+	{method: http.MethodPut, path: "/loadbalancers/:name/targets", tag: "loadbalancers", summary: "Replace a load balancer's targets",
+		body: loadbalancer.TargetGroup{}, data: loadbalancer.Balancer{}},
+	{method: http.MethodDelete, path: "/loadbalancers/:name", tag: "loadbalancers", summary: "Delete a load balancer"},
+
+	// DNS
+	{method: http.MethodGet, path: "/dns/records", tag: "dns", summary: "List DNS records, or look up a name",
+		query: []queryParam{{"name", "string", "Only records answering this name"}},
+		data:  []dns.Record{}},
+	{method: http.MethodPost, path: "/dns/records", tag: "dns", summary: "Add a static DNS record",
+		body: dns.Record{}, status: http.StatusCreated, data: dns.Record{}},
+	{method: http.MethodDelete, path: "/dns/records", tag: "dns", summary: "Delete static DNS records",
+		query: []queryParam{
+			{"name", "string", "Record name"},
+			{"type", "string", "Only records of this type"},
+		},
+		data: struct {
+			Removed int `json:"removed"`
+		}{}},
+
+	// Functions
+	{method: http.MethodGet, path: "/functions", tag: "functions", summary: "List functions",
+		data: []functions.Status{}},
+	{method: http.MethodPost, path: "/functions", tag: "functions", summary: "Deploy a function from a zip of its code",
+		bodyContent: "multipart/form-data",
+		body: struct {
+			Code     formFile `json:"code" binding:"required"`
+			Name     string   `json:"name" binding:"required"`
+			Runtime  string   `json:"runtime" binding:"required"`
+			Handler  string   `json:"handler"`
+			MemoryMB int      `json:"memory_mb"`
+			Timeout  int      `json:"timeout_seconds"`
+			Env      []string `json:"env"`
+		}{},
+		status: http.StatusCreated,
+		data: struct {
+			Function *functions.Function `json:"function"`
+			BuildLog string              `json:"build_log"`
+		}{}},
+	{method: http.MethodGet, path: "/functions/:name", tag: "functions", summary: "Get a function",
+		data: functions.Status{}},
+	{method: http.MethodDelete, path: "/functions/:name", tag: "functions", summary: "Delete a function"},
+	{method: http.MethodPost, path: "/functions/:name/invoke", tag: "functions", summary: "Invoke a function with the request body as its event",
+		body: json.RawMessage{}, optional: true, data: functions.Invocation{}},
+	{method: http.MethodGet, path: "/functions/:name/invocations", tag: "functions", summary: "Recent invocations",
+		query: []queryParam{limitParam}, data: []functions.Invocation{}},
+
+	// Databases
+	{method: http.MethodGet, path: "/databases", tag: "databases", summary: "List databases",
+		data: []databases.Info{}},
+	{method: http.MethodPost, path: "/databases", tag: "databases", summary: "Create a database; provisioning continues in the background",
+		body: databases.Database{}, status: http.StatusAccepted, data: databases.Info{}},
+	{method: http.MethodGet, path: "/databases/:name", tag: "databases", summary: "Get a database and its connection details",
+		data: databases.Info{}},
+	{method: http.MethodDelete, path: "/databases/:name", tag: "databases", summary: "Delete a database"},
+	{method: http.MethodGet, path: "/databases/:name/backups", tag: "databases", summary: "List a database's backups",
+		data: []databases.Backup{}},
+	{method: http.MethodPost, path: "/databases/:name/backups", tag: "databases", summary: "Back up a database now",
+		status: http.StatusCreated, data: databases.Backup{}},
+	{method: http.MethodGet, path: "/databases/:name/backups/:id", tag: "databases", summary: "Download a backup as SQL",
+		content: "application/sql"},
+	{method: http.MethodPost, path: "/databases/:name/restore", tag: "databases", summary: "Restore a backup (the latest if backup_id is empty)",
+		body: struct {
+			BackupID string `json:"backup_id"`
+		}{},
+		optional: true, data: databases.Backup{}},
+
+	// Queues
+	{method: http.MethodGet, path: "/queues", tag: "queues", summary: "List queues",
+		query: []queryParam{{"prefix", "string", "Only queues whose name starts with this"}},
+		data:  []queues.Status{}},
+	{method: http.MethodPost, path: "/queues", tag: "queues", summary: "Create a queue",
+		body: queues.Queue{}, status: http.StatusCreated, data: queues.Status{}},
+	{method: http.MethodGet, path: "/queues/:name", tag: "queues", summary: "Get a queue and its message counts",
+		data: queues.Status{}},
+	{method: http.MethodDelete, path: "/queues/:name", tag: "queues", summary: "Delete a queue"},
+	{method: http.MethodPost, path: "/queues/:name/purge", tag: "queues", summary: "Delete every message in a queue"},
+	{method: http.MethodPost, path: "/queues/:name/redrive", tag: "queues", summary: "Move dead-lettered messages back to their source queue",
+		data: struct {
+			Moved int `json:"moved"`
+		}{}},
+	{method: http.MethodPost, path: "/queues/:name/messages", tag: "queues", summary: "Send a message",
+		body: queues.SendInput{}, status: http.StatusCreated, data: queues.SendResult{}},
+	{method: http.MethodGet, path: "/queues/:name/messages", tag: "queues", summary: "Receive messages, long polling up to wait seconds",
+		query: []queryParam{
+			{"max", "integer", "Messages to receive, 1 to 10 (default 1)"},
+			{"visibility", "integer", "Seconds the messages stay hidden from other receivers"},
+			{"wait", "integer", "Seconds to wait for a message"},
+		},
+		data: []queues.Message{}},
+	{method: http.MethodDelete, path: "/queues/:name/messages", tag: "queues", summary: "Delete a received message",
+		query: []queryParam{{"receipt_handle", "string", "Receipt handle from receive"}}},
+	{method: http.MethodGet, path: "/queues/:name/samples", tag: "queues", summary: "Recent queue depth samples",
+		data: []queues.Sample{}},
+
+	// Secrets
+	{method: http.MethodGet, path: "/secrets", tag: "secrets", summary: "List secrets, without values",
+		data: []secrets.Secret{}},
+	{method: http.MethodPost, path: "/secrets", tag: "secrets", summary: "Create a secret; an empty value is generated",
+		body: secretRequest{}, status: http.StatusCreated, data: secrets.Secret{}},
+	{method: http.MethodGet, path: "/secrets/:name", tag: "secrets", summary: "Get a secret's metadata",
+		data: secrets.Secret{}},
+	{method: http.MethodDelete, path: "/secrets/:name", tag: "secrets", summary: "Delete a secret"},
+	{method: http.MethodGet, path: "/secrets/:name/value", tag: "secrets", summary: "Get a secret's value",
+		query: []queryParam{{"version", "integer", "Version to read (latest if empty)"}},
+		data:  secretValue{}},
+	{method: http.MethodPost, path: "/secrets/:name/rotate", tag: "secrets", summary: "Add a new version; an empty value is generated",
+		body: secretRequest{}, optional: true, data: secrets.Secret{}},
+
+	// Parameters
+	{method: http.MethodGet, path: "/parameters", tag: "parameters", summary: "Get the parameters under a path",
+		query: []queryParam{
+			{"path", "string", "Path prefix (default /)"},
+			{"recursive", "boolean", "Include parameters in nested paths"},
+			decryptParam,
+		},
+		data: []parameters.Parameter{}},
+	{method: http.MethodGet, path: "/parameters/*name", tag: "parameters", summary: "Get a parameter",
+		query: []queryParam{
+			{"version", "integer", "Version to read"},
+			{"label", "string", "Label to read"},
+			decryptParam,
+		},
+		data: parameters.Parameter{}},
+	{method: http.MethodPut, path: "/parameters/*name", tag: "parameters", summary: "Create a parameter or add a version",
+		body: parameters.PutInput{},
+		data: struct {
+			Name    string `json:"name"`
+			Version int    `json:"version"`
+		}{}},
+	{method: http.MethodDelete, path: "/parameters/*name", tag: "parameters", summary: "Delete a parameter and its history"},
+	{method: http.MethodGet, path: "/parameter-history/*name", tag: "parameters", summary: "Every version of a parameter",
+		query: []queryParam{decryptParam}, data: []parameters.Parameter{}},
+	{method: http.MethodPost, path: "/parameter-labels/*name", tag: "parameters", summary: "Attach labels to a version",
+		body: labelRequest{}},
+
+	// Schedules
+	{method: http.MethodGet, path: "/schedules", tag: "schedules", summary: "List scheduled jobs",
+		data: []scheduler.JobStatus{}},
+	{method: http.MethodPost, path: "/schedules", tag: "schedules", summary: "Create a scheduled job",
+		body: scheduler.Job{}, status: http.StatusCreated, data: scheduler.JobStatus{}},
+	{method: http.MethodGet, path: "/schedules/:name", tag: "schedules", summary: "Get a scheduled job",
+		data: scheduler.JobStatus{}},
+	{method: http.MethodDelete, path: "/schedules/:name", tag: "schedules", summary: "Delete a scheduled job"},
+	{method: http.MethodPost, path: "/schedules/:name/suspend", tag: "schedules", summary: "Stop starting runs",
+		data: scheduler.JobStatus{}},
+	{method: http.MethodPost, path: "/schedules/:name/resume", tag: "schedules", summary: "Start runs again",
+		data: scheduler.JobStatus{}},
+	{method: http.MethodPost, path: "/schedules/:name/run", tag: "schedules", summary: "Start a run now",
+		status: http.StatusAccepted, data: scheduler.Run{}},
+	{method: http.MethodGet, path: "/schedules/:name/runs", tag: "schedules", summary: "Recent runs",
+		query: []queryParam{limitParam}, data: []scheduler.Run{}},
+	{method: http.MethodGet, path: "/schedules/:name/runs/:id", tag: "schedules", summary: "Get a run with its output",
+		data: scheduler.Run{}},
+
+	// Batch
+	{method: http.MethodGet, path: "/jobs", tag: "batch", summary: "List batch jobs",
+		query: []queryParam{
+			{"queue", "string", "Only jobs in this job queue"},
+			{"status", "string", "Only jobs with this status"},
+		},
+		data: []batch.JobStatus{}},
+	{method: http.MethodPost, path: "/jobs", tag: "batch", summary: "Submit a job, or an array job",
+		body: batch.SubmitInput{}, status: http.StatusCreated, data: batch.JobStatus{}},
+	{method: http.MethodGet, path: "/jobs/:id", tag: "batch", summary: "Get a job",
+		data: batch.JobStatus{}},
+	{method: http.MethodGet, path: "/jobs/:id/children", tag: "batch", summary: "An array job's children",
+		data: []batch.Job{}},
+	{method: http.MethodPost, path: "/jobs/:id/cancel", tag: "batch", summary: "Cancel a job",
+		body: struct {
+			Reason string `json:"reason"`
+		}{},
+		optional: true, data: batch.JobStatus{}},
+	{method: http.MethodGet, path: "/jobs/:id/logs", tag: "batch", summary: "A job attempt's output",
+		query: []queryParam{{"attempt", "integer", "Attempt number (latest if empty)"}},
+		data: struct {
+			Logs string `json:"logs"`
+		}{}},
+	{method: http.MethodGet, path: "/job-queues", tag: "batch", summary: "List job queues",
+		data: []batch.QueueStatus{}},
+	{method: http.MethodPost, path: "/job-queues", tag: "batch", summary: "Create a job queue",
+		body: batch.Queue{}, status: http.StatusCreated, data: batch.Queue{}},
+	{method: http.MethodPut, path: "/job-queues/:name", tag: "batch", summary: "Change how many jobs a queue runs at once",
+		body: struct {
+			MaxParallel int `json:"max_parallel"`
+		}{},
+		data: batch.Queue{}},
+	{method: http.MethodDelete, path: "/job-queues/:name", tag: "batch", summary: "Delete a job queue"},
+
+	// Backups
+	{method: http.MethodGet, path: "/backups", tag: "backups", summary: "List volume backups, newest first",
+		query: []queryParam{{"volume", "string", "Only backups of this volume"}},
+		data:  []backups.Backup{}},
+	{method: http.MethodPost, path: "/backups", tag: "backups", summary: "Archive a volume now",
+		body: backups.CreateInput{}, status: http.StatusCreated, data: backups.Backup{}, async: true},
+	{method: http.MethodPost, path: "/backups/prune", tag: "backups", summary: "Delete old backups of a volume or plan",
+		body: backups.PruneInput{}, data: []backups.Backup{}},
+	{method: http.MethodGet, path: "/backups/:id", tag: "backups", summary: "Get a backup",
+		data: backups.Backup{}},
+	{method: http.MethodDelete, path: "/backups/:id", tag: "backups", summary: "Delete a backup"},
+	{method: http.MethodPost, path: "/backups/:id/restore", tag: "backups", summary: "Restore a backup into a new or existing volume",
+		body: backups.RestoreInput{}, optional: true, data: backups.Backup{}, async: true},
+	{method: http.MethodPost, path: "/backups/:id/verify", tag: "backups", summary: "Check a backup against its checksum",
+		data: backups.Backup{}, async: true},
+	{method: http.MethodGet, path: "/backup-plans", tag: "backups", summary: "List backup plans",
+		data: []backups.PlanStatus{}},
+	{method: http.MethodPost, path: "/backup-plans", tag: "backups", summary: "Create a backup plan",
+		body: backups.Plan{}, status: http.StatusCreated, data: backups.PlanStatus{}},
+	{method: http.MethodDelete, path: "/backup-plans/:name", tag: "backups", summary: "Delete a backup plan, keeping its backups"},
+	{method: http.MethodPost, path: "/backup-plans/:name/run", tag: "backups", summary: "Run a backup plan now",
+		status: http.StatusCreated, data: backups.Backup{}, async: true},
+
+	// Bundles
+	{method: http.MethodPost, path: "/bundles/export", tag: "bundles", summary: "Export the environment as a gzipped bundle",
+		body: bundles.Options{}, optional: true, content: "application/gzip"},
+	{method: http.MethodPost, path: "/bundles/import", tag: "bundles", summary: "Recreate an environment from a bundle",
+		query: []queryParam{
+			{"dry_run", "boolean", "Report what would happen without changing anything"},
+			{"on_conflict", "string", "skip, rename or replace when something already exists"},
+		},
+		bodyContent: "application/gzip", data: bundles.Report{}},
+
+	// Quotas
+	{method: http.MethodGet, path: "/quotas", tag: "quotas", summary: "Quotas and usage of every project",
+		data: []quotas.Status{}},
+	{method: http.MethodGet, path: "/quotas/:project", tag: "quotas", summary: "A project's quota and usage",
+		data: quotas.Status{}},
+	{method: http.MethodPut, path: "/quotas/:project", tag: "quotas", summary: "Set a project's quota",
+		body: compute.Quota{}, data: compute.Quota{}},
+	{method: http.MethodDelete, path: "/quotas/:project", tag: "quotas", summary: "Remove a project's quota"},
+
+	// Billing
+	{method: http.MethodGet, path: "/billing/prices", tag: "billing", summary: "Get the price sheet",
+		data: billing.PriceSheet{}},
+	{method: http.MethodPut, path: "/billing/prices", tag: "billing", summary: "Replace the price sheet",
+		body: billing.PriceSheet{}, data: billing.PriceSheet{}},
+	{method: http.MethodGet, path: "/billing/report", tag: "billing", summary: "Costs grouped by project, tag, resource or day",
+		query: []queryParam{
+			{"by", "string", "project, tag, resource or day"},
+			{"tag_key", "string", "Tag to group by when by=tag"},
+			{"from", "string", "First day, YYYY-MM-DD"},
+			{"to", "string", "Last day, YYYY-MM-DD"},
+		},
+		data: billing.Report{}},
+	{method: http.MethodGet, path: "/billing/budgets", tag: "billing", summary: "List budgets with spend and forecast",
+		data: []billing.BudgetStatus{}},
+	{method: http.MethodPost, path: "/billing/budgets", tag: "billing", summary: "Create a budget",
+		body: billing.Budget{}, status: http.StatusCreated, data: billing.Budget{}},
+	{method: http.MethodDelete, path: "/billing/budgets/:name", tag: "billing", summary: "Delete a budget"},
+	{method: http.MethodGet, path: "/billing/alerts", tag: "billing", summary: "Budget alerts raised so far",
+		data: []billing.Alert{}},
+
+	// Alarms
+	{method: http.MethodGet, path: "/alarms", tag: "alarms", summary: "List alarms and their states",
+		data: []alarms.AlarmStatus{}},
+	{method: http.MethodPost, path: "/alarms", tag: "alarms", summary: "Create an alarm",
+		body: alarms.Alarm{}, status: http.StatusCreated, data: alarms.AlarmStatus{}},
+	{method: http.MethodGet, path: "/alarms/:name", tag: "alarms", summary: "Get an alarm with its recent datapoints",
+		data: alarms.AlarmStatus{}},
+	{method: http.MethodDelete, path: "/alarms/:name", tag: "alarms", summary: "Delete an alarm"},
+	{method: http.MethodGet, path: "/alarms/:name/history", tag: "alarms", summary: "An alarm's state transitions",
+		data: []alarms.Transition{}},
+	{method: http.MethodPost, path: "/alarms/:name/state", tag: "alarms", summary: "Force a state to try out an alarm's actions",
+		body: struct {
+			State  string `json:"state" binding:"required"`
+			Reason string `json:"reason"`
+		}{},
+		data: alarms.AlarmStatus{}},
+
+	// Event bus
+	{method: http.MethodGet, path: "/eventbus/rules", tag: "eventbus", summary: "List event rules",
+		data: []eventbus.Rule{}},
+	{method: http.MethodPost, path: "/eventbus/rules", tag: "eventbus", summary: "Create an event rule",
+		body: eventbus.Rule{}, status: http.StatusCreated, data: eventbus.Rule{}},
+	{method: http.MethodGet, path: "/eventbus/rules/:name", tag: "eventbus", summary: "Get an event rule",
+		data: eventbus.Rule{}},
+	{method: http.MethodPut, path: "/eventbus/rules/:name", tag: "eventbus", summary: "Replace an event rule; redacted secrets are kept",
+		body: eventbus.Rule{}, data: eventbus.Rule{}},
+	{method: http.MethodDelete, path: "/eventbus/rules/:name", tag: "eventbus", summary: "Delete an event rule"},
+	{method: http.MethodPost, path: "/eventbus/rules/:name/enable", tag: "eventbus", summary: "Start routing events for a rule",
+		data: eventbus.Rule{}},
+	{method: http.MethodPost, path: "/eventbus/rules/:name/disable", tag: "eventbus", summary: "Stop routing events for a rule",
+		data: eventbus.Rule{}},
+	{method: http.MethodGet, path: "/eventbus/events", tag: "eventbus", summary: "Recent events, newest first",
+		data: []eventbus.Event{}},
+	{method: http.MethodPost, path: "/eventbus/events", tag: "eventbus", summary: "Publish custom events",
+		body: struct {
+			Events []eventbus.Event `json:"events" binding:"required"`
+		}{},
+		data: []eventbus.Event{}},
+	{method: http.MethodGet, path: "/eventbus/deliveries", tag: "eventbus", summary: "Delivery log, newest first",
+		query: []queryParam{
+			{"rule", "string", "Only this rule's deliveries"},
+			{"status", "string", "delivered, failed or dead_lettered"},
+		},
+		data: []eventbus.Delivery{}},
+	{method: http.MethodPost, path: "/eventbus/test-pattern", tag: "eventbus", summary: "Check whether an event matches a pattern",
+		body: struct {
+			EventPattern json.RawMessage `json:"event_pattern" binding:"required"`
+			Event        eventbus.Event  `json:"event"`
+		}{},
+		data: struct {
+			Matched bool `json:"matched"`
+		}{}},
+
+	// Operations
+	{method: http.MethodGet, path: "/operations", tag: "operations", summary: "List operations, newest first",
+		query: []queryParam{
+			{"kind", "string", "Only operations of this kind, e.g. instance.create"},
+			{"status", "string", "running, succeeded, failed or cancelled"},
+		},
+		data: []operations.Operation{}},
+	{method: http.MethodGet, path: "/operations/:id", tag: "operations", summary: "Get an operation, optionally waiting for it to finish",
+		query: []queryParam{{"wait", "integer", "Seconds to wait for the operation to finish (at most 120)"}},
+		data:  operations.Operation{}},
+	{method: http.MethodPost, path: "/operations/:id/cancel", tag: "operations", summary: "Ask a running operation to stop",
+		status: http.StatusAccepted, data: operations.Operation{}},
+
+	// API description
+	{method: http.MethodGet, path: "/openapi.json", tag: "meta", summary: "This OpenAPI document",
+		content: "application/json"},
+}
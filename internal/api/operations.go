@@ -0,0 +1,112 @@
+// Long-running operation handlers
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"localcloud/internal/operations"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Longest a GET /operations/:id?wait= may block
+const maxOperationWait = 2 * time.Minute
+
+func operationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, operations.ErrOperationNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, operations.ErrNotCancellable):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+func internalErrorStatus(error) int {
+	return http.StatusInternalServerError
+}
+
+// Run fn within the request, answering status with its result, or with
+// ?async=true as an operation, answering 202 with its ID straight away.
+// Within the request fn is cancelled when the client goes away; as an
+// operation it may outlive the request, so it must not use c: read params
+// first.
+func (s *Server) runOperation(c *gin.Context, kind, target string, status int, errorStatus func(error) int, fn operations.Func) {
+	if async, _ := strconv.ParseBool(c.Query("async")); async {
+		op := s.ops.Run(kind, target, fn)
+		c.Header("Location", "/api/v1/operations/"+op.ID)
+		c.JSON(http.StatusAccepted, Response{
+			Success: true,
+			Data:    op,
+		})
+		return
+	}
+
+	result, err := fn(c.Request.Context(), func(string, int) {})
+	if err != nil {
+		c.JSON(errorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(status, Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
+func (s *Server) listOperations(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.ops.List(c.Query("kind"), c.Query("status")),
+	})
+}
+
+// ?wait=SECONDS blocks until the operation finishes or the time is up
+func (s *Server) getOperation(c *gin.Context) {
+	var timeout time.Duration
+	if wait, err := strconv.Atoi(c.Query("wait")); err == nil && wait > 0 {
+		timeout = time.Duration(wait) * time.Second
+	}
+	if timeout > maxOperationWait {
+		timeout = maxOperationWait
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+	defer cancel()
+	op, err := s.ops.Wait(ctx, c.Param("id"))
+	if err != nil {
+		c.JSON(operationErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    op,
+	})
+}
+
+func (s *Server) cancelOperation(c *gin.Context) {
+	op, err := s.ops.Cancel(c.Param("id"))
+	if err != nil {
+		c.JSON(operationErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, Response{
+		Success: true,
+		Data:    op,
+	})
+}
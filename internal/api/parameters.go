@@ -0,0 +1,156 @@
+// Parameter store handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"localcloud/internal/parameters"
+
+	"github.com/gin-gonic/gin"
+)
+
+type labelRequest struct {
+	Version int      `json:"version"` // latest if 0
+	Labels  []string `json:"labels"`
+}
+
+func parameterErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, parameters.ErrParameterNotFound), errors.Is(err, parameters.ErrVersionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, parameters.ErrParameterExists):
+		return http.StatusConflict
+	case errors.Is(err, parameters.ErrInvalidParameter):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) getParametersByPath(c *gin.Context) {
+	path := c.DefaultQuery("path", "/")
+	recursive, _ := strconv.ParseBool(c.Query("recursive"))
+	decrypt, _ := strconv.ParseBool(c.Query("decrypt"))
+
+	list, err := s.params.GetByPath(path, recursive, decrypt)
+	if err != nil {
+		c.JSON(parameterErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    list,
+	})
+}
+
+func (s *Server) getParameter(c *gin.Context) {
+	decrypt, _ := strconv.ParseBool(c.Query("decrypt"))
+
+	// a version or label can also be given as /name:selector
+	name := c.Param("name")
+	if version := c.Query("version"); version != "" {
+		name += ":" + version
+	} else if label := c.Query("label"); label != "" {
+		name += ":" + label
+	}
+
+	parameter, err := s.params.Get(name, decrypt)
+	if err != nil {
+		c.JSON(parameterErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    parameter,
+	})
+}
+
+func (s *Server) putParameter(c *gin.Context) {
+	var req parameters.PutInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+	req.Name = c.Param("name")
+
+	version, err := s.params.Put(req)
+	if err != nil {
+		c.JSON(parameterErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    gin.H{"name": req.Name, "version": version},
+	})
+}
+
+func (s *Server) deleteParameter(c *gin.Context) {
+	if err := s.params.Delete(c.Param("name")); err != nil {
+		c.JSON(parameterErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) getParameterHistory(c *gin.Context) {
+	decrypt, _ := strconv.ParseBool(c.Query("decrypt"))
+
+	history, err := s.params.History(c.Param("name"), decrypt)
+	if err != nil {
+		c.JSON(parameterErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    history,
+	})
+}
+
+func (s *Server) labelParameter(c *gin.Context) {
+	var req labelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.params.Label(c.Param("name"), req.Version, req.Labels); err != nil {
+		c.JSON(parameterErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
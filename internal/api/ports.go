@@ -0,0 +1,42 @@
+// Host port handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"localcloud/internal/compute"
+
+	"github.com/gin-gonic/gin"
+)
+
+func createErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, compute.ErrPortInUse), errors.Is(err, compute.ErrNoFreePorts):
+		return http.StatusConflict
+	case errors.Is(err, compute.ErrQuotaExceeded):
+		return http.StatusForbidden
+	case errors.Is(err, compute.ErrUnknownInstanceType):
+		return http.StatusBadRequest
+	case strings.HasPrefix(err.Error(), "invalid "):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) listPorts(c *gin.Context) {
+	usage, err := s.manager.PortUsage()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    usage,
+	})
+}
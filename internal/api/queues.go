@@ -0,0 +1,195 @@
+// Message queue handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"localcloud/internal/queues"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Not found for unknown queues, bad request otherwise
+func queueErrorStatus(err error) int {
+	if errors.Is(err, queues.ErrQueueNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) listQueues(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.queues.ListQueues(c.Query("prefix")),
+	})
+}
+
+func (s *Server) createQueue(c *gin.Context) {
+	var req queues.Queue
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	queue, err := s.queues.CreateQueue(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    queue,
+	})
+}
+
+func (s *Server) getQueue(c *gin.Context) {
+	queue, err := s.queues.GetQueue(c.Param("name"))
+	if err != nil {
+		c.JSON(queueErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    queue,
+	})
+}
+
+func (s *Server) deleteQueue(c *gin.Context) {
+	if err := s.queues.DeleteQueue(c.Param("name")); err != nil {
+		c.JSON(queueErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) purgeQueue(c *gin.Context) {
+	if err := s.queues.Purge(c.Param("name")); err != nil {
+		c.JSON(queueErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) redriveQueue(c *gin.Context) {
+	// moves dead-lettered messages back to their source queues
+	moved, err := s.queues.Redrive(c.Param("name"))
+	if err != nil {
+		c.JSON(queueErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    gin.H{"moved": moved},
+	})
+}
+
+func (s *Server) sendQueueMessage(c *gin.Context) {
+	var req queues.SendInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	result, err := s.queues.Send(c.Param("name"), req)
+	if err != nil {
+		c.JSON(queueErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    result,
+	})
+}
+
+func (s *Server) receiveQueueMessages(c *gin.Context) {
+	in := queues.ReceiveInput{MaxMessages: 1}
+	if max, err := strconv.Atoi(c.Query("max")); err == nil {
+		in.MaxMessages = max
+	}
+	if visibility, err := strconv.Atoi(c.Query("visibility")); err == nil {
+		in.VisibilityTimeout = &visibility
+	}
+	if wait, err := strconv.Atoi(c.Query("wait")); err == nil {
+		in.WaitSeconds = &wait
+	}
+
+	messages, err := s.queues.Receive(c.Request.Context(), c.Param("name"), in)
+	if err != nil {
+		c.JSON(queueErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    messages,
+	})
+}
+
+func (s *Server) deleteQueueMessage(c *gin.Context) {
+	if err := s.queues.DeleteMessage(c.Param("name"), c.Query("receipt_handle")); err != nil {
+		c.JSON(queueErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) getQueueSamples(c *gin.Context) {
+	samples, err := s.queues.Samples(c.Param("name"))
+	if err != nil {
+		c.JSON(queueErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    samples,
+	})
+}
@@ -0,0 +1,92 @@
+// Project quota handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/quotas"
+
+	"github.com/gin-gonic/gin"
+)
+
+func quotaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, quotas.ErrQuotaNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, compute.ErrInvalidProject), errors.Is(err, quotas.ErrInvalidQuota):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) listQuotas(c *gin.Context) {
+	report, err := s.quotas.Report()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    report,
+	})
+}
+
+func (s *Server) getQuota(c *gin.Context) {
+	status, err := s.quotas.Get(c.Param("project"))
+	if err != nil {
+		c.JSON(quotaErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    status,
+	})
+}
+
+func (s *Server) setQuota(c *gin.Context) {
+	var quota compute.Quota
+	if err := c.ShouldBindJSON(&quota); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	if err := s.quotas.Set(c.Param("project"), quota); err != nil {
+		c.JSON(quotaErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    quota,
+	})
+}
+
+func (s *Server) deleteQuota(c *gin.Context) {
+	if err := s.quotas.Delete(c.Param("project")); err != nil {
+		c.JSON(quotaErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
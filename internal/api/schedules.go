@@ -0,0 +1,161 @@
+// Scheduled job handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"localcloud/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+func scheduleErrorStatus(err error) int {
+	if errors.Is(err, scheduler.ErrJobNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) listSchedules(c *gin.Context) {
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.scheduler.List(),
+	})
+}
+
+func (s *Server) createSchedule(c *gin.Context) {
+	var req scheduler.Job
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	job, err := s.scheduler.Create(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    job,
+	})
+}
+
+func (s *Server) getSchedule(c *gin.Context) {
+	job, err := s.scheduler.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    job,
+	})
+}
+
+func (s *Server) deleteSchedule(c *gin.Context) {
+	// active runs are stopped and their containers removed
+	if err := s.scheduler.Delete(c.Param("name")); err != nil {
+		c.JSON(scheduleErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) suspendSchedule(c *gin.Context) {
+	s.setScheduleSuspended(c, true)
+}
+
+func (s *Server) resumeSchedule(c *gin.Context) {
+	s.setScheduleSuspended(c, false)
+}
+
+func (s *Server) setScheduleSuspended(c *gin.Context, suspended bool) {
+	job, err := s.scheduler.SetSuspended(c.Param("name"), suspended)
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    job,
+	})
+}
+
+func (s *Server) triggerSchedule(c *gin.Context) {
+	run, err := s.scheduler.Trigger(c.Param("name"))
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, Response{
+		Success: true,
+		Data:    run,
+	})
+}
+
+func (s *Server) listScheduleRuns(c *gin.Context) {
+	limit := 20
+	if limitParam := c.Query("limit"); limitParam != "" {
+		if parsed, err := strconv.Atoi(limitParam); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+
+	runs, err := s.scheduler.Runs(c.Param("name"), limit)
+	if err != nil {
+		c.JSON(scheduleErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    runs,
+	})
+}
+
+func (s *Server) getScheduleRun(c *gin.Context) {
+	run, err := s.scheduler.GetRun(c.Param("name"), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    run,
+	})
+}
@@ -0,0 +1,159 @@
+// Secrets handlers
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"localcloud/internal/secrets"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Value is generated when empty
+type secretRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Value       string `json:"value"`
+}
+
+type secretValue struct {
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+	Value   string `json:"value"`
+}
+
+func secretErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, secrets.ErrSecretNotFound), errors.Is(err, secrets.ErrVersionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, secrets.ErrSecretExists):
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+func (s *Server) listSecrets(c *gin.Context) {
+	// metadata only, values need a separate request
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    s.secrets.List(),
+	})
+}
+
+func (s *Server) createSecret(c *gin.Context) {
+	var req secretRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	secret, err := s.secrets.Create(req.Name, req.Description, []byte(req.Value))
+	if err != nil {
+		c.JSON(secretErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, Response{
+		Success: true,
+		Data:    secret,
+	})
+}
+
+func (s *Server) getSecret(c *gin.Context) {
+	secret, err := s.secrets.Get(c.Param("name"))
+	if err != nil {
+		c.JSON(secretErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    secret,
+	})
+}
+
+func (s *Server) deleteSecret(c *gin.Context) {
+	if err := s.secrets.Delete(c.Param("name")); err != nil {
+		c.JSON(secretErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
+
+func (s *Server) getSecretValue(c *gin.Context) {
+	version := 0
+	if v := c.Query("version"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "version must be a number",
+			})
+			return
+		}
+		version = parsed
+	}
+
+	value, version, err := s.secrets.Value(c.Param("name"), version)
+	if err != nil {
+		c.JSON(secretErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data: secretValue{
+			Name:    c.Param("name"),
+			Version: version,
+			Value:   string(value),
+		},
+	})
+}
+
+func (s *Server) rotateSecret(c *gin.Context) {
+	var req secretRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, Response{
+				Success: false,
+				Error:   "Invalid request format",
+			})
+			return
+		}
+	}
+
+	secret, err := s.secrets.Rotate(c.Param("name"), []byte(req.Value))
+	if err != nil {
+		c.JSON(secretErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    secret,
+	})
+}
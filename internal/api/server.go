@@ -2,19 +2,56 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"path/filepath"
+	"time"
 
+	"localcloud/internal/alarms"
+	"localcloud/internal/autoscaling"
+	"localcloud/internal/backups"
+	"localcloud/internal/batch"
+	"localcloud/internal/billing"
+	"localcloud/internal/bundles"
 	"localcloud/internal/compute"
 	"localcloud/internal/config"
+	"localcloud/internal/databases"
+	"localcloud/internal/dns"
+	"localcloud/internal/eventbus"
+	"localcloud/internal/functions"
+	"localcloud/internal/operations"
+	"localcloud/internal/parameters"
+	"localcloud/internal/queues"
+	"localcloud/internal/quotas"
+	"localcloud/internal/scheduler"
+	"localcloud/internal/secrets"
+	"localcloud/internal/loadbalancer"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Server struct {
-	manager *compute.Manager
-	config  *config.Config
-	router  *gin.Engine
+	manager   *compute.Manager
+	config    *config.Config
+	router    *gin.Engine
+	scaling   *autoscaling.Service
+	balancers *loadbalancer.Service
+	dns       *dns.Service
+	functions *functions.Service
+	databases *databases.Service
+	queues    *queues.Service
+	secrets   *secrets.Service
+	params    *parameters.Service
+	scheduler *scheduler.Service
+	batch     *batch.Service
+	backups   *backups.Service
+	bundles   *bundles.Service
+	quotas    *quotas.Service
+	billing   *billing.Service
+	alarms    *alarms.Service
+	events    *eventbus.Service
+	ops       *operations.Service
 }
 
 type Response struct {
@@ -23,7 +60,7 @@ type Response struct {
 	Error   string      `json:"error,omitempty"`
 }
 
-func NewServer(manager *compute.Manager, cfg *config.Config) *Server {
+func NewServer(manager *compute.Manager, cfg *config.Config) (*Server, error) {
 	if cfg.LogLevel != "DEBUG" {
 		gin.SetMode(gin.ReleaseMode)
 	}
@@ -31,27 +68,182 @@ func NewServer(manager *compute.Manager, cfg *config.Config) *Server {
 	router := gin.New()
 	router.Use(gin.Logger(), gin.Recovery()) // logging and recovery middleware
 	
+	if err := manager.UsePorts(cfg.PortRange, cfg.BindAddress); err != nil {
+		return nil, fmt.Errorf("failed to initialize ports: %w", err)
+	}
+	if err := manager.LoadInstanceTypes(cfg.InstanceTypesPath()); err != nil {
+		return nil, fmt.Errorf("failed to initialize instance types: %w", err)
+	}
+	if err := manager.LoadResizes(cfg.ResizesPath()); err != nil {
+		return nil, fmt.Errorf("failed to initialize instance types: %w", err)
+	}
+
+	quotaService, err := quotas.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize quotas: %w", err)
+	}
+	manager.UseQuotas(quotaService)
+
+	scaling, err := autoscaling.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize autoscaling: %w", err)
+	}
+
+	balancers, err := loadbalancer.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize load balancers: %w", err)
+	}
+
+	dnsService, err := dns.NewService(manager, cfg.DNSZone, cfg.DNSUpstream, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize DNS: %w", err)
+	}
+
+	fnService, err := functions.NewService(manager, cfg.DataDir, time.Duration(cfg.FunctionIdleSeconds)*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize functions: %w", err)
+	}
+
+	dbService, err := databases.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize databases: %w", err)
+	}
+
+	queueService, err := queues.NewService(cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize queues: %w", err)
+	}
+
+	secretService, err := secrets.NewService(cfg.DataDir, cfg.MasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize secrets: %w", err)
+	}
+	manager.UseSecrets(secretService)
+
+	// SecureString parameters are encrypted with the secrets master key
+	paramService, err := parameters.NewService(cfg.DataDir, secretService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize parameters: %w", err)
+	}
+	manager.UseParameters(paramService)
+
+	schedService, err := scheduler.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize scheduler: %w", err)
+	}
+
+	batchService, err := batch.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize batch jobs: %w", err)
+	}
+
+	backupDir := cfg.BackupDir
+	if backupDir == "" {
+		backupDir = filepath.Join(cfg.DataDir, "backups", "archives")
+	}
+	backupService, err := backups.NewService(manager, cfg.DataDir, backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize backups: %w", err)
+	}
+
+	billingService, err := billing.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize billing: %w", err)
+	}
+
+	alarmService, err := alarms.NewService(manager, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize alarms: %w", err)
+	}
+
+	eventBus, err := eventbus.NewService(manager, queueService, cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize event bus: %w", err)
+	}
+	alarmService.UseEvents(eventBus)
+
+	opService, err := operations.NewService(cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize operations: %w", err)
+	}
+
 	s := &Server{
-		manager: manager,
-		config:  cfg,
-		router:  router,
+		manager:   manager,
+		config:    cfg,
+		router:    router,
+		scaling:   scaling,
+		balancers: balancers,
+		dns:       dnsService,
+		functions: fnService,
+		databases: dbService,
+		queues:    queueService,
+		secrets:   secretService,
+		params:    paramService,
+		scheduler: schedService,
+		batch:     batchService,
+		backups:   backupService,
+		bundles:   bundles.NewService(manager),
+		quotas:    quotaService,
+		billing:   billingService,
+		alarms:    alarmService,
+		events:    eventBus,
+		ops:       opService,
 	}
 
 	s.setupRoutes()
-	return s
+	return s, nil
 }
 
-// Start http server
+// Start background services and the http server
 func (s *Server) Start() error {
+	ctx := context.Background()
+	s.manager.StartHealthMonitor(ctx)
+	s.scaling.Start(ctx)
+	s.balancers.Start(ctx)
+	s.functions.Start(ctx)
+	s.databases.Start(ctx)
+	s.queues.Start(ctx)
+	s.scheduler.Start(ctx)
+	s.batch.Start(ctx)
+	s.backups.Start(ctx)
+	s.billing.Start(ctx)
+	s.alarms.Start(ctx)
+	s.events.Start(ctx)
+	s.ops.Start(ctx)
+	if s.config.DNSEnabled {
+		// Instances still work without DNS, just not by name
+		if err := s.startDNS(ctx); err != nil {
+			log.Printf("DNS disabled: %v", err)
+		}
+	}
+
 	addr := fmt.Sprintf(":%d", s.config.Port)
 	log.Printf("LocalCloud web interface starting on http://localhost%s", addr)
+	if s.config.APIToken != "" {
+		log.Printf("API token required; open the dashboard with ?token=<token> once")
+	}
 	return s.router.Run(addr)
 }
 
 // Define all API endpoints
 func (s *Server) setupRoutes() {
+	s.router.Use(s.authenticate)
+
 	// Serve static dashboard
 	s.router.GET("/", s.handleDashboard)
+	s.router.GET("/autoscaling", s.handleAutoscalingDashboard)
+	s.router.GET("/loadbalancers", s.handleLoadBalancerDashboard)
+	s.router.GET("/functions", s.handleFunctionsDashboard)
+	s.router.GET("/databases", s.handleDatabasesDashboard)
+	s.router.GET("/queues", s.handleQueuesDashboard)
+	s.router.GET("/secrets", s.handleSecretsDashboard)
+	s.router.GET("/parameters", s.handleParametersDashboard)
+	s.router.GET("/snapshots", s.handleSnapshotsDashboard)
+	s.router.GET("/schedules", s.handleSchedulesDashboard)
+	s.router.GET("/files", s.handleFilesDashboard)
+	s.router.GET("/billing", s.handleBillingDashboard)
+	s.router.GET("/alarms", s.handleAlarmsDashboard)
+	s.router.GET("/api-docs", s.handleAPIDocs)
 	
 	// API routes
 	api := s.router.Group("/api/v1")
@@ -61,9 +253,164 @@ func (s *Server) setupRoutes() {
 		api.DELETE("/containers/:id", s.deleteContainer)
 		api.GET("/containers/:id/logs", s.getContainerLogs)
 		api.GET("/containers/:id/metrics", s.getContainerMetrics)
+		api.GET("/containers/:id/health", s.getContainerHealth)
 		api.POST("/containers/:id/exec", s.execContainer)
+		api.GET("/containers/:id/files", s.downloadContainerFile)
+		api.PUT("/containers/:id/files", s.uploadContainerFile)
+		api.GET("/containers/:id/fs", s.browseContainerPath)
+		api.PUT("/containers/:id/fs", s.saveContainerFile)
+
+		api.GET("/ports", s.listPorts)
+		api.GET("/instance-types", s.listInstanceTypes)
+		api.POST("/containers/:id/resize", s.resizeContainer)
+
+		api.GET("/snapshots", s.listSnapshots)
+		api.POST("/snapshots", s.createSnapshot)
+		api.GET("/snapshots/:name", s.getSnapshot)
+		api.DELETE("/snapshots/:name", s.deleteSnapshot)
+
+		api.GET("/autoscaling/groups", s.listScalingGroups)
+		api.POST("/autoscaling/groups", s.createScalingGroup)
+		api.GET("/autoscaling/groups/:name", s.getScalingGroup)
+		api.PUT("/autoscaling/groups/:name/capacity", s.setScalingCapacity)
+		api.DELETE("/autoscaling/groups/:name", s.deleteScalingGroup)
+		api.GET("/autoscaling/groups/:name/activities", s.getScalingActivities)
+		api.GET("/autoscaling/groups/:name/samples", s.getScalingSamples)
+
+		api.GET("/loadbalancers", s.listLoadBalancers)
+		api.POST("/loadbalancers", s.createLoadBalancer)
+		api.GET("/loadbalancers/:name", s.getLoadBalancer)
+		api.PUT("/loadbalancers/:name/targets", s.setLoadBalancerTargets)
+		api.DELETE("/loadbalancers/:name", s.deleteLoadBalancer)
+
+		api.GET("/dns/records", s.listDNSRecords)
+		api.POST("/dns/records", s.createDNSRecord)
+		api.DELETE("/dns/records", s.deleteDNSRecords)
+
+		api.GET("/functions", s.listFunctions)
+		api.POST("/functions", s.deployFunction)
+		api.GET("/functions/:name", s.getFunction)
+		api.DELETE("/functions/:name", s.deleteFunction)
+		api.POST("/functions/:name/invoke", s.invokeFunction)
+		api.GET("/functions/:name/invocations", s.getFunctionInvocations)
+
+		api.GET("/databases", s.listDatabases)
+		api.POST("/databases", s.createDatabase)
+		api.GET("/databases/:name", s.getDatabase)
+		api.DELETE("/databases/:name", s.deleteDatabase)
+		api.GET("/databases/:name/backups", s.listDatabaseBackups)
+		api.POST("/databases/:name/backups", s.createDatabaseBackup)
+		api.GET("/databases/:name/backups/:id", s.downloadDatabaseBackup)
+		api.POST("/databases/:name/restore", s.restoreDatabase)
+
+		api.GET("/queues", s.listQueues)
+		api.POST("/queues", s.createQueue)
+		api.GET("/queues/:name", s.getQueue)
+		api.DELETE("/queues/:name", s.deleteQueue)
+		api.POST("/queues/:name/purge", s.purgeQueue)
+		api.POST("/queues/:name/redrive", s.redriveQueue)
+		api.POST("/queues/:name/messages", s.sendQueueMessage)
+		api.GET("/queues/:name/messages", s.receiveQueueMessages)
+		api.DELETE("/queues/:name/messages", s.deleteQueueMessage)
+		api.GET("/queues/:name/samples", s.getQueueSamples)
+
+		api.GET("/secrets", s.listSecrets)
+		api.POST("/secrets", s.createSecret)
+		api.GET("/secrets/:name", s.getSecret)
+		api.DELETE("/secrets/:name", s.deleteSecret)
+		api.GET("/secrets/:name/value", s.getSecretValue)
+		api.POST("/secrets/:name/rotate", s.rotateSecret)
+
+		// parameter names contain slashes, so history and labels get their own prefixes
+		api.GET("/parameters", s.getParametersByPath)
+		api.GET("/parameters/*name", s.getParameter)
+		api.PUT("/parameters/*name", s.putParameter)
+		api.DELETE("/parameters/*name", s.deleteParameter)
+		api.GET("/parameter-history/*name", s.getParameterHistory)
+		api.POST("/parameter-labels/*name", s.labelParameter)
+
+		api.GET("/schedules", s.listSchedules)
+		api.POST("/schedules", s.createSchedule)
+		api.GET("/schedules/:name", s.getSchedule)
+		api.DELETE("/schedules/:name", s.deleteSchedule)
+		api.POST("/schedules/:name/suspend", s.suspendSchedule)
+		api.POST("/schedules/:name/resume", s.resumeSchedule)
+		api.POST("/schedules/:name/run", s.triggerSchedule)
+		api.GET("/schedules/:name/runs", s.listScheduleRuns)
+		api.GET("/schedules/:name/runs/:id", s.getScheduleRun)
+
+		api.GET("/jobs", s.listJobs)
+		api.POST("/jobs", s.submitJob)
+		api.GET("/jobs/:id", s.getJob)
+		api.GET("/jobs/:id/children", s.listJobChildren)
+		api.POST("/jobs/:id/cancel", s.cancelJob)
+		api.GET("/jobs/:id/logs", s.getJobLogs)
+		api.GET("/job-queues", s.listJobQueues)
+		api.POST("/job-queues", s.createJobQueue)
+		api.PUT("/job-queues/:name", s.updateJobQueue)
+		api.DELETE("/job-queues/:name", s.deleteJobQueue)
+
+		api.GET("/backups", s.listBackups)
+		api.POST("/backups", s.createBackup)
+		api.POST("/backups/prune", s.pruneBackups)
+		api.GET("/backups/:id", s.getBackup)
+		api.DELETE("/backups/:id", s.deleteBackup)
+		api.POST("/backups/:id/restore", s.restoreBackup)
+		api.POST("/backups/:id/verify", s.verifyBackup)
+		api.GET("/backup-plans", s.listBackupPlans)
+		api.POST("/backup-plans", s.createBackupPlan)
+		api.DELETE("/backup-plans/:name", s.deleteBackupPlan)
+		api.POST("/backup-plans/:name/run", s.runBackupPlan)
+
+		api.POST("/bundles/export", s.exportBundle)
+		api.POST("/bundles/import", s.importBundle)
+
+		api.GET("/quotas", s.listQuotas)
+		api.GET("/quotas/:project", s.getQuota)
+		api.PUT("/quotas/:project", s.setQuota)
+		api.DELETE("/quotas/:project", s.deleteQuota)
+
+		api.GET("/billing/prices", s.getBillingPrices)
+		api.PUT("/billing/prices", s.setBillingPrices)
+		api.GET("/billing/report", s.getBillingReport)
+		api.GET("/billing/budgets", s.listBudgets)
+		api.POST("/billing/budgets", s.createBudget)
+		api.DELETE("/billing/budgets/:name", s.deleteBudget)
+		api.GET("/billing/alerts", s.listBudgetAlerts)
+
+		api.GET("/alarms", s.listAlarms)
+		api.POST("/alarms", s.createAlarm)
+		api.GET("/alarms/:name", s.getAlarm)
+		api.DELETE("/alarms/:name", s.deleteAlarm)
+		api.GET("/alarms/:name/history", s.getAlarmHistory)
+		api.POST("/alarms/:name/state", s.setAlarmState)
+
+		api.GET("/eventbus/rules", s.listEventRules)
+		api.POST("/eventbus/rules", s.createEventRule)
+		api.GET("/eventbus/rules/:name", s.getEventRule)
+		api.PUT("/eventbus/rules/:name", s.updateEventRule)
+		api.DELETE("/eventbus/rules/:name", s.deleteEventRule)
+		api.POST("/eventbus/rules/:name/enable", s.enableEventRule)
+		api.POST("/eventbus/rules/:name/disable", s.disableEventRule)
+		api.GET("/eventbus/events", s.listEvents)
+		api.POST("/eventbus/events", s.putEvents)
+		api.GET("/eventbus/deliveries", s.listEventDeliveries)
+		api.POST("/eventbus/test-pattern", s.testEventPattern)
+
+		// Work started with ?async=true
+		api.GET("/operations", s.listOperations)
+		api.GET("/operations/:id", s.getOperation)
+		api.POST("/operations/:id/cancel", s.cancelOperation)
+
+		// Describes everything above, see openapi_endpoints.go
+		api.GET("/openapi.json", s.getOpenAPI)
 	}
 
+	// SQS protocol for AWS SDKs, with queue URLs under /sqs/<account>/<name>
+	sqs := gin.WrapH(s.queues.SQSHandler("/sqs", s.config.APIToken))
+	s.router.POST("/sqs", sqs)
+	s.router.POST("/sqs/*path", sqs)
+
 	// WebSocket for real-time updates
 	s.router.GET("/ws", s.handleWebSocket)
 }
@@ -0,0 +1,91 @@
+// Snapshot handlers
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/operations"
+
+	"github.com/gin-gonic/gin"
+)
+
+func snapshotErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, compute.ErrSnapshotNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, compute.ErrSnapshotExists):
+		return http.StatusConflict
+	case errors.Is(err, compute.ErrInvalidSnapshotName):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) listSnapshots(c *gin.Context) {
+	snapshots, err := s.manager.ListSnapshots()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    snapshots,
+	})
+}
+
+func (s *Server) createSnapshot(c *gin.Context) {
+	var req struct {
+		compute.SnapshotInput
+		InstanceID string `json:"instance_id"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.InstanceID == "" {
+		c.JSON(http.StatusBadRequest, Response{
+			Success: false,
+			Error:   "Invalid request format",
+		})
+		return
+	}
+
+	s.runOperation(c, "snapshot.create", req.InstanceID, http.StatusCreated, snapshotErrorStatus,
+		func(ctx context.Context, progress operations.Progress) (interface{}, error) {
+			progress("committing instance", 10)
+			return s.manager.Snapshot(req.InstanceID, req.SnapshotInput)
+		})
+}
+
+func (s *Server) getSnapshot(c *gin.Context) {
+	snapshot, err := s.manager.GetSnapshot(c.Param("name"))
+	if err != nil {
+		c.JSON(snapshotErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+		Data:    snapshot,
+	})
+}
+
+func (s *Server) deleteSnapshot(c *gin.Context) {
+	if err := s.manager.DeleteSnapshot(c.Param("name")); err != nil {
+		c.JSON(snapshotErrorStatus(err), Response{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, Response{
+		Success: true,
+	})
+}
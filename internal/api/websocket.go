@@ -42,6 +42,14 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
 
+	// Parameter changes are pushed as they happen
+	changes, cancel := s.params.Subscribe()
+	defer cancel()
+
+	// So are operation progress updates
+	ops, cancelOps := s.ops.Subscribe()
+	defer cancelOps()
+
 	for {
 		select {
 		case <-ticker.C:
@@ -56,6 +64,28 @@ func (s *Server) handleWebSocket(c *gin.Context) {
 				return
 			}
 
+		case change := <-changes:
+			data := map[string]interface{}{
+				"parameter": change,
+				"timestamp": time.Now(),
+			}
+
+			if err := conn.WriteJSON(data); err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				return
+			}
+
+		case op := <-ops:
+			data := map[string]interface{}{
+				"operation": op,
+				"timestamp": time.Now(),
+			}
+
+			if err := conn.WriteJSON(data); err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				return
+			}
+
 		case <-c.Request.Context().Done():
 			return
 		}
@@ -0,0 +1,514 @@
+// Auto scaling groups of identical instances
+package autoscaling
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Label placed on every instance owned by a group
+const LabelGroup = "localcloud.autoscaling.group"
+
+const (
+	evaluationInterval = 15 * time.Second
+	historySize        = 200
+	tolerance          = 0.1 // ignore metric drift within 10% of target
+)
+
+// Target tracking on a metric averaged across the group
+type Policy struct {
+	Metric string  `json:"metric"` // cpu or memory, both in percent
+	Target float64 `json:"target"`
+}
+
+type Group struct {
+	Name      string             `json:"name"`
+	Template  compute.CreateSpec `json:"template"`
+	Min       int                `json:"min"`
+	Max       int                `json:"max"`
+	Desired   int                `json:"desired"`
+	Policies  []Policy           `json:"policies,omitempty"`
+	Cooldown  int                `json:"cooldown_seconds"`
+	Created   time.Time          `json:"created"`
+	LastScale time.Time          `json:"last_scale,omitempty"`
+}
+
+// Something the group did and why
+type Activity struct {
+	Time   time.Time `json:"time"`
+	Group  string    `json:"group"`
+	Action string    `json:"action"` // scale_out, scale_in, launch, terminate, replace
+	From   int       `json:"from"`
+	To     int       `json:"to"`
+	Cause  string    `json:"cause"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// Metric values seen at one evaluation, for charts
+type Sample struct {
+	Time    time.Time          `json:"time"`
+	Metrics map[string]float64 `json:"metrics"`
+	Desired int                `json:"desired"`
+	Running int                `json:"running"`
+}
+
+// Group plus its live state
+type GroupStatus struct {
+	Group
+	Instances []compute.Instance `json:"instances"`
+}
+
+type state struct {
+	Groups     map[string]*Group     `json:"groups"`
+	Activities map[string][]Activity `json:"activities"`
+}
+
+type Service struct {
+	manager *compute.Manager
+	path    string
+
+	mu      sync.Mutex
+	state   state
+	samples map[string][]Sample
+	// held while a group's instances are launched or terminated, so the
+	// ticker and API calls don't both act on the same count
+	groupLocks map[string]*sync.Mutex
+}
+
+func NewService(manager *compute.Manager, dataDir string) (*Service, error) {
+	s := &Service{
+		manager: manager,
+		path:    filepath.Join(dataDir, "autoscaling.json"),
+		state: state{
+			Groups:     make(map[string]*Group),
+			Activities: make(map[string][]Activity),
+		},
+		samples:    make(map[string][]Sample),
+		groupLocks: make(map[string]*sync.Mutex),
+	}
+	if err := store.Load(s.path, &s.state); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Evaluate policies and reconcile instance counts until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(evaluationInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.evaluateAll()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) List() []GroupStatus {
+	s.mu.Lock()
+	groups := make([]Group, 0, len(s.state.Groups))
+	for _, g := range s.state.Groups {
+		groups = append(groups, *g)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	statuses := make([]GroupStatus, 0, len(groups))
+	for _, g := range groups {
+		statuses = append(statuses, GroupStatus{Group: g, Instances: s.manager.ListByLabel(LabelGroup, g.Name)})
+	}
+	return statuses
+}
+
+func (s *Service) Get(name string) (*GroupStatus, error) {
+	s.mu.Lock()
+	g, ok := s.state.Groups[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("scaling group %q not found", name)
+	}
+	group := *g
+	s.mu.Unlock()
+
+	return &GroupStatus{Group: group, Instances: s.manager.ListByLabel(LabelGroup, name)}, nil
+}
+
+func (s *Service) Create(g Group) (*Group, error) {
+	if err := validate(&g); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	if _, exists := s.state.Groups[g.Name]; exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("scaling group %q already exists", g.Name)
+	}
+	g.Created = time.Now()
+	s.state.Groups[g.Name] = &g
+	s.recordLocked(Activity{Group: g.Name, Action: "create", To: g.Desired, Cause: "group created"})
+	err := s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.reconcile(g.Name)
+	return &g, nil
+}
+
+// Change min, max or desired; nil keeps the current setting
+func (s *Service) SetCapacity(name string, minSize, maxSize, desired *int) (*Group, error) {
+	s.mu.Lock()
+	g, ok := s.state.Groups[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("scaling group %q not found", name)
+	}
+
+	updated := *g
+	if minSize != nil {
+		updated.Min = *minSize
+	}
+	if maxSize != nil {
+		updated.Max = *maxSize
+	}
+	if desired != nil {
+		updated.Desired = *desired
+	}
+	if err := validate(&updated); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	if updated.Desired != g.Desired {
+		s.recordLocked(Activity{Group: name, Action: scaleAction(g.Desired, updated.Desired), From: g.Desired, To: updated.Desired, Cause: "capacity set manually"})
+	}
+	*g = updated
+	err := s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.reconcile(name)
+	return &updated, nil
+}
+
+// Remove a group and terminate its instances
+func (s *Service) Delete(name string) error {
+	lock := s.groupLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.mu.Lock()
+	if _, ok := s.state.Groups[name]; !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("scaling group %q not found", name)
+	}
+	delete(s.state.Groups, name)
+	delete(s.state.Activities, name)
+	delete(s.samples, name)
+	err := s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range s.manager.ListByLabel(LabelGroup, name) {
+		if err := s.manager.Delete(instance.ID); err != nil {
+			return fmt.Errorf("failed to terminate %s: %w", instance.Name, err)
+		}
+	}
+	return nil
+}
+
+func (s *Service) Activities(name string) ([]Activity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.Groups[name]; !ok {
+		return nil, fmt.Errorf("scaling group %q not found", name)
+	}
+	activities := make([]Activity, len(s.state.Activities[name]))
+	copy(activities, s.state.Activities[name])
+	return activities, nil
+}
+
+func (s *Service) Samples(name string) ([]Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.Groups[name]; !ok {
+		return nil, fmt.Errorf("scaling group %q not found", name)
+	}
+	samples := make([]Sample, len(s.samples[name]))
+	copy(samples, s.samples[name])
+	return samples, nil
+}
+
+func (s *Service) evaluateAll() {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.state.Groups))
+	for name := range s.state.Groups {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	for _, name := range names {
+		s.evaluate(name)
+		s.reconcile(name)
+	}
+}
+
+// Apply target tracking policies to the desired count
+func (s *Service) evaluate(name string) {
+	instances := running(s.manager.ListByLabel(LabelGroup, name))
+
+	// Average each metric across running instances
+	totals := map[string]float64{}
+	counted := 0
+	for _, instance := range instances {
+		metrics, err := s.manager.GetMetrics(instance.ID)
+		if err != nil {
+			continue
+		}
+		totals["cpu"] += metrics.CPUPercent
+		if metrics.MemoryLimit > 0 {
+			totals["memory"] += float64(metrics.MemoryUsage) / float64(metrics.MemoryLimit) * 100
+		}
+		counted++
+	}
+	averages := map[string]float64{}
+	if counted > 0 {
+		for metric, total := range totals {
+			averages[metric] = total / float64(counted)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	g, ok := s.state.Groups[name]
+	if !ok {
+		return
+	}
+
+	s.samples[name] = append(s.samples[name], Sample{Time: time.Now(), Metrics: averages, Desired: g.Desired, Running: len(instances)})
+	if len(s.samples[name]) > historySize {
+		s.samples[name] = s.samples[name][len(s.samples[name])-historySize:]
+	}
+
+	if counted == 0 || len(g.Policies) == 0 {
+		return
+	}
+	if time.Since(g.LastScale) < time.Duration(g.Cooldown)*time.Second {
+		return
+	}
+
+	// Largest proposal wins so no policy is starved
+	proposed := 0
+	var causes []string
+	for _, policy := range g.Policies {
+		value := averages[policy.Metric]
+		ratio := value / policy.Target
+		if math.Abs(ratio-1) <= tolerance {
+			proposed = max(proposed, g.Desired)
+			continue
+		}
+		want := int(math.Ceil(float64(counted) * ratio))
+		if want != g.Desired {
+			causes = append(causes, fmt.Sprintf("%s %.1f%% vs target %.1f%%", policy.Metric, value, policy.Target))
+		}
+		proposed = max(proposed, want)
+	}
+	proposed = min(max(proposed, g.Min), g.Max)
+
+	if proposed == g.Desired {
+		return
+	}
+
+	s.recordLocked(Activity{Group: name, Action: scaleAction(g.Desired, proposed), From: g.Desired, To: proposed, Cause: strings.Join(causes, ", ")})
+	g.Desired = proposed
+	g.LastScale = time.Now()
+	if err := s.saveLocked(); err != nil {
+		log.Printf("autoscaling: %v", err)
+	}
+}
+
+func (s *Service) groupLock(name string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lock, ok := s.groupLocks[name]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.groupLocks[name] = lock
+	}
+	return lock
+}
+
+// Launch or terminate instances until the group matches its desired count
+func (s *Service) reconcile(name string) {
+	lock := s.groupLock(name)
+	lock.Lock()
+	defer lock.Unlock()
+
+	s.mu.Lock()
+	g, ok := s.state.Groups[name]
+	if !ok {
+		s.mu.Unlock()
+		return
+	}
+	group := *g
+	s.mu.Unlock()
+
+	instances := s.manager.ListByLabel(LabelGroup, name)
+
+	// Replace instances that stopped on their own
+	var alive []compute.Instance
+	for _, instance := range instances {
+		if instance.State == "running" || instance.State == "created" || instance.State == "restarting" {
+			alive = append(alive, instance)
+			continue
+		}
+		err := s.manager.Delete(instance.ID)
+		s.record(Activity{Group: name, Action: "terminate", From: len(instances), To: len(instances) - 1, Cause: fmt.Sprintf("instance %s is %s", instance.Name, instance.State), Error: errString(err)})
+	}
+
+	// Newest instances are terminated first
+	sort.Slice(alive, func(i, j int) bool { return alive[i].Created.Before(alive[j].Created) })
+
+	for len(alive) > group.Desired {
+		victim := alive[len(alive)-1]
+		err := s.manager.Delete(victim.ID)
+		s.record(Activity{Group: name, Action: "terminate", From: len(alive), To: len(alive) - 1, Cause: "above desired capacity", Error: errString(err)})
+		if err != nil {
+			return
+		}
+		alive = alive[:len(alive)-1]
+	}
+
+	for len(alive) < group.Desired {
+		spec := group.Template
+		spec.Name = fmt.Sprintf("%s-%s", name, uuid.New().String()[:8])
+		spec.Labels = make(map[string]string)
+		for key, value := range group.Template.Labels {
+			spec.Labels[key] = value
+		}
+		// last, so the template can't move the instance out of the group
+		spec.Labels[LabelGroup] = name
+
+		instance, err := s.manager.Create(spec)
+		s.record(Activity{Group: name, Action: "launch", From: len(alive), To: len(alive) + 1, Cause: "below desired capacity", Error: errString(err)})
+		if err != nil {
+			return
+		}
+		alive = append(alive, *instance)
+	}
+}
+
+func (s *Service) record(activity Activity) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.recordLocked(activity)
+	if err := s.saveLocked(); err != nil {
+		log.Printf("autoscaling: %v", err)
+	}
+}
+
+func (s *Service) recordLocked(activity Activity) {
+	if activity.Time.IsZero() {
+		activity.Time = time.Now()
+	}
+	history := append(s.state.Activities[activity.Group], activity)
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	s.state.Activities[activity.Group] = history
+}
+
+func (s *Service) saveLocked() error {
+	return store.Save(s.path, s.state)
+}
+
+func validate(g *Group) error {
+	if g.Name == "" {
+		return fmt.Errorf("group name is required")
+	}
+	if g.Template.Image == "" && g.Template.Snapshot == "" {
+		return fmt.Errorf("template image or snapshot is required")
+	}
+	if g.Min < 0 || g.Max < g.Min {
+		return fmt.Errorf("capacity must satisfy 0 <= min <= max")
+	}
+	if g.Max == 0 {
+		return fmt.Errorf("max must be at least 1")
+	}
+	if g.Desired < g.Min || g.Desired > g.Max {
+		return fmt.Errorf("desired must be between min (%d) and max (%d)", g.Min, g.Max)
+	}
+
+	// Every instance needs its own host port
+	mappings, err := compute.ParsePorts(g.Template.Ports)
+	if err != nil {
+		return fmt.Errorf("invalid template ports: %w", err)
+	}
+	for _, mapping := range mappings {
+		if g.Max > 1 && mapping.Fixed() {
+			return fmt.Errorf("template ports must not fix the host port (use :80, auto:80 or a range like 8000-8100:80) when max > 1")
+		}
+	}
+
+	for _, policy := range g.Policies {
+		if policy.Metric != "cpu" && policy.Metric != "memory" {
+			return fmt.Errorf("unknown policy metric %q", policy.Metric)
+		}
+		if policy.Target <= 0 || policy.Target > 100 {
+			return fmt.Errorf("policy target must be between 0 and 100 percent")
+		}
+	}
+	return nil
+}
+
+func running(instances []compute.Instance) []compute.Instance {
+	var result []compute.Instance
+	for _, instance := range instances {
+		if instance.State == "running" {
+			result = append(result, instance)
+		}
+	}
+	return result
+}
+
+func scaleAction(from, to int) string {
+	if to > from {
+		return "scale_out"
+	}
+	return "scale_in"
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,271 @@
+package autoscaling
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types"
+)
+
+func newTestService(t *testing.T) (*Service, *dockertest.Server, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s, err := NewService(manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, docker, dir
+}
+
+func intp(n int) *int {
+	return &n
+}
+
+// Containers of a group, oldest first
+func members(docker *dockertest.Server, group string) []dockertest.Container {
+	var list []dockertest.Container
+	for _, c := range docker.Containers() {
+		if c.Labels[LabelGroup] == group {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+func TestValidate(t *testing.T) {
+	template := compute.CreateSpec{Image: "nginx"}
+	tests := []struct {
+		group Group
+		err   string
+	}{
+		{Group{Name: "web", Template: template, Min: 1, Max: 3, Desired: 2}, ""},
+		{Group{Name: "web", Template: compute.CreateSpec{Image: "nginx", Ports: ":80"}, Max: 3}, ""},
+		{Group{Name: "web", Template: compute.CreateSpec{Image: "nginx", Ports: "8080:80"}, Max: 1, Desired: 1}, ""},
+		{Group{Template: template, Max: 1}, "name is required"},
+		{Group{Name: "web", Max: 1}, "image or snapshot is required"},
+		{Group{Name: "web", Template: template, Min: 2, Max: 1}, "0 <= min <= max"},
+		{Group{Name: "web", Template: template, Min: -1, Max: 1}, "0 <= min <= max"},
+		{Group{Name: "web", Template: template}, "max must be at least 1"},
+		{Group{Name: "web", Template: template, Min: 1, Max: 3, Desired: 4}, "desired must be between"},
+		{Group{Name: "web", Template: compute.CreateSpec{Image: "nginx", Ports: "8080:80"}, Max: 2}, "must not fix the host port"},
+		{Group{Name: "web", Template: template, Max: 2, Policies: []Policy{{Metric: "disk", Target: 50}}}, "unknown policy metric"},
+		{Group{Name: "web", Template: template, Max: 2, Policies: []Policy{{Metric: "cpu", Target: 0}}}, "between 0 and 100"},
+		{Group{Name: "web", Template: template, Max: 2, Policies: []Policy{{Metric: "cpu", Target: 120}}}, "between 0 and 100"},
+	}
+	for _, tt := range tests {
+		err := validate(&tt.group)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("validate(%+v) = %v, want %q", tt.group, err, tt.err)
+		}
+	}
+}
+
+func TestCreateLaunchesDesired(t *testing.T) {
+	s, docker, dir := newTestService(t)
+	template := compute.CreateSpec{Image: "nginx", Labels: map[string]string{"team": "web"}}
+	if _, err := s.Create(Group{Name: "web", Template: template, Min: 1, Max: 4, Desired: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	running := members(docker, "web")
+	if len(running) != 2 {
+		t.Fatalf("group has %d instances, want 2", len(running))
+	}
+	for _, c := range running {
+		if !strings.HasPrefix(c.Name, "web-") || c.Labels["team"] != "web" || c.State != "running" {
+			t.Errorf("instance %+v", c)
+		}
+	}
+	if _, err := s.Create(Group{Name: "web", Template: template, Max: 1}); err == nil {
+		t.Error("created the same group twice")
+	}
+
+	activities, err := s.Activities("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actions []string
+	for _, a := range activities {
+		actions = append(actions, a.Action)
+	}
+	if strings.Join(actions, ",") != "create,launch,launch" {
+		t.Errorf("activities = %v", actions)
+	}
+
+	// groups survive a restart
+	reopened, err := NewService(s.manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := reopened.Get("web"); err != nil || status.Desired != 2 || len(status.Instances) != 2 {
+		t.Errorf("after reopening: %+v, %v", status, err)
+	}
+}
+
+func TestSetCapacity(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	s.state.Groups["web"] = &Group{Name: "web", Template: compute.CreateSpec{Image: "nginx"}, Max: 5, Desired: 3}
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"web-old", "web-mid", "web-new"} {
+		docker.AddContainer(dockertest.Container{
+			Name:    name,
+			Image:   "nginx",
+			Labels:  map[string]string{LabelGroup: "web"},
+			Created: base.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	if _, err := s.SetCapacity("web", nil, nil, intp(6)); err == nil {
+		t.Error("desired above max was accepted")
+	}
+	if _, err := s.SetCapacity("missing", nil, nil, intp(1)); err == nil {
+		t.Error("changed a group that does not exist")
+	}
+
+	// the newest instances go first
+	if _, err := s.SetCapacity("web", nil, nil, intp(1)); err != nil {
+		t.Fatal(err)
+	}
+	if left := members(docker, "web"); len(left) != 1 || left[0].Name != "web-old" {
+		t.Fatalf("after scaling in: %+v", left)
+	}
+
+	if _, err := s.SetCapacity("web", nil, nil, intp(2)); err != nil {
+		t.Fatal(err)
+	}
+	if left := members(docker, "web"); len(left) != 2 {
+		t.Fatalf("after scaling out: %d instances", len(left))
+	}
+}
+
+func TestReconcileReplacesStopped(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	s.state.Groups["web"] = &Group{Name: "web", Template: compute.CreateSpec{Image: "nginx"}, Max: 2, Desired: 2}
+	docker.AddContainer(dockertest.Container{Name: "web-1", Image: "nginx", Labels: map[string]string{LabelGroup: "web"}})
+	docker.AddContainer(dockertest.Container{Name: "web-2", Image: "nginx", Labels: map[string]string{LabelGroup: "web"}, State: "exited"})
+
+	s.reconcile("web")
+
+	left := members(docker, "web")
+	if len(left) != 2 {
+		t.Fatalf("group has %d instances, want 2", len(left))
+	}
+	for _, c := range left {
+		if c.Name == "web-2" || c.State != "running" {
+			t.Errorf("instance %s is %s", c.Name, c.State)
+		}
+	}
+}
+
+func TestDelete(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	if _, err := s.Create(Group{Name: "web", Template: compute.CreateSpec{Image: "nginx"}, Max: 2, Desired: 2}); err != nil {
+		t.Fatal(err)
+	}
+	docker.AddContainer(dockertest.Container{Name: "other", Image: "nginx"})
+
+	if err := s.Delete("web"); err != nil {
+		t.Fatal(err)
+	}
+	if containers := docker.Containers(); len(containers) != 1 || containers[0].Name != "other" {
+		t.Errorf("containers after delete = %+v", containers)
+	}
+	if _, err := s.Get("web"); err == nil {
+		t.Error("deleted group still exists")
+	}
+}
+
+// Members reporting memory use in percent
+func withMemory(docker *dockertest.Server, group string, percents ...uint64) {
+	for i, percent := range percents {
+		id := docker.AddContainer(dockertest.Container{
+			Name:    group + "-" + string(rune('a'+i)),
+			Image:   "nginx",
+			Labels:  map[string]string{LabelGroup: group},
+			Created: time.Now().Add(-time.Hour),
+		})
+		var stats types.StatsJSON
+		stats.MemoryStats.Usage, stats.MemoryStats.Limit = percent, 100
+		docker.Handle("GET /containers/"+id+"/stats", stats)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name      string
+		memory    []uint64
+		target    float64
+		desired   int
+		lastScale time.Time
+		want      int
+	}{
+		{"scale out", []uint64{90, 90}, 45, 2, time.Time{}, 4},
+		{"capped at max", []uint64{90, 90}, 30, 2, time.Time{}, 5},
+		{"scale in", []uint64{20, 20}, 40, 2, time.Time{}, 1},
+		{"floored at min", []uint64{5, 5}, 80, 2, time.Time{}, 1},
+		{"within tolerance", []uint64{52, 52}, 50, 2, time.Time{}, 2},
+		{"cooling down", []uint64{90, 90}, 45, 2, time.Now(), 2},
+	}
+	for _, tt := range tests {
+		s, docker, _ := newTestService(t)
+		s.state.Groups["web"] = &Group{
+			Name:      "web",
+			Template:  compute.CreateSpec{Image: "nginx"},
+			Min:       1,
+			Max:       5,
+			Desired:   tt.desired,
+			Policies:  []Policy{{Metric: "memory", Target: tt.target}},
+			Cooldown:  300,
+			LastScale: tt.lastScale,
+		}
+		withMemory(docker, "web", tt.memory...)
+
+		s.evaluate("web")
+		if got := s.state.Groups["web"].Desired; got != tt.want {
+			t.Errorf("%s: desired = %d, want %d", tt.name, got, tt.want)
+		}
+		samples, _ := s.Samples("web")
+		if len(samples) != 1 || samples[0].Running != len(tt.memory) || samples[0].Metrics["memory"] != float64(tt.memory[0]) {
+			t.Errorf("%s: samples = %+v", tt.name, samples)
+		}
+	}
+}
+
+func TestTemplateKeepsGroupLabel(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	template := compute.CreateSpec{Image: "nginx", Labels: map[string]string{LabelGroup: "other"}}
+	if _, err := s.Create(Group{Name: "web", Template: template, Max: 2, Desired: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if len(members(docker, "web")) != 2 || len(members(docker, "other")) != 0 {
+		t.Errorf("instances left the group: %+v", docker.Containers())
+	}
+}
+
+func TestConcurrentReconcile(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	s.state.Groups["web"] = &Group{Name: "web", Template: compute.CreateSpec{Image: "nginx"}, Max: 3, Desired: 3}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.reconcile("web")
+		}()
+	}
+	wg.Wait()
+	if n := len(members(docker, "web")); n != 3 {
+		t.Errorf("group has %d instances, want 3", n)
+	}
+}
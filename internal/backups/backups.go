@@ -0,0 +1,615 @@
+// Volume backups: tar archives of named volumes with checksums, retention
+// and scheduled backup plans
+package backups
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/scheduler"
+	"localcloud/internal/store"
+)
+
+const (
+	defaultRetention = 7
+	checkInterval    = 15 * time.Second
+	mountPath        = "/volume"
+)
+
+var (
+	ErrBackupNotFound   = errors.New("backup not found")
+	ErrPlanNotFound     = errors.New("backup plan not found")
+	ErrPlanExists       = errors.New("backup plan already exists")
+	ErrVolumeNotFound   = errors.New("volume not found")
+	ErrVolumeExists     = errors.New("volume already exists")
+	ErrVolumeBusy       = errors.New("volume has a backup or restore in progress")
+	ErrChecksumMismatch = errors.New("backup checksum mismatch")
+	ErrInvalidVolume    = errors.New("invalid volume name")
+)
+
+var (
+	validPlanName   = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+	validVolumeName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+)
+
+type Backup struct {
+	ID         string    `json:"id"`
+	Volume     string    `json:"volume"`
+	Plan       string    `json:"plan,omitempty"` // empty for manual backups
+	File       string    `json:"file"`
+	Compressed bool      `json:"compressed"`
+	Size       int64     `json:"size"`
+	SHA256     string    `json:"sha256"`
+	DurationMS int64     `json:"duration_ms"`
+	Created    time.Time `json:"created"`
+}
+
+type Plan struct {
+	Name       string     `json:"name"`
+	Volume     string     `json:"volume"`
+	Schedule   string     `json:"schedule"` // cron expression
+	Compress   bool       `json:"compress"`
+	Retention  int        `json:"retention"` // backups from this plan that are kept
+	LastRun    *time.Time `json:"last_run,omitempty"`
+	LastBackup string     `json:"last_backup,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	Created    time.Time  `json:"created"`
+}
+
+type PlanStatus struct {
+	Plan
+	NextRun *time.Time `json:"next_run,omitempty"`
+}
+
+type CreateInput struct {
+	Volume   string `json:"volume"`
+	Compress bool   `json:"compress"`
+}
+
+type RestoreInput struct {
+	Volume    string `json:"volume"`    // the backed up volume if empty
+	Overwrite bool   `json:"overwrite"` // replace the contents of an existing volume
+	Project   string `json:"project"`   // project a new volume counts against
+}
+
+// Backups to delete: those beyond the newest Keep, and those older than
+// OlderThanHours, for one volume or plan
+type PruneInput struct {
+	Volume         string `json:"volume"`
+	Plan           string `json:"plan"`
+	Keep           int    `json:"keep"`
+	OlderThanHours int    `json:"older_than_hours"`
+}
+
+type state struct {
+	Plans   map[string]*Plan `json:"plans"`
+	Backups []Backup         `json:"backups"` // oldest first
+}
+
+type Service struct {
+	manager   *compute.Manager
+	dir       string // catalog
+	backupDir string // archives
+
+	mu        sync.Mutex
+	state     state
+	schedules map[string]*scheduler.Schedule
+	next      map[string]time.Time
+	busy      map[string]bool // by volume
+}
+
+func NewService(manager *compute.Manager, dataDir, backupDir string) (*Service, error) {
+	s := &Service{
+		manager:   manager,
+		dir:       filepath.Join(dataDir, "backups"),
+		backupDir: backupDir,
+		state:     state{Plans: make(map[string]*Plan)},
+		schedules: make(map[string]*scheduler.Schedule),
+		next:      make(map[string]time.Time),
+		busy:      make(map[string]bool),
+	}
+	if err := store.Load(filepath.Join(s.dir, "backups.json"), &s.state); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(backupDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	now := time.Now()
+	for name, plan := range s.state.Plans {
+		schedule, err := scheduler.ParseCron(plan.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("backup plan %s: %w", name, err)
+		}
+		s.schedules[name] = schedule
+		s.next[name] = schedule.Next(now)
+	}
+	return s, nil
+}
+
+// Run backup plans when they are due until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.runDuePlans()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) runDuePlans() {
+	now := time.Now()
+
+	s.mu.Lock()
+	var due []string
+	for name, next := range s.next {
+		if !now.Before(next) {
+			due = append(due, name)
+			s.next[name] = s.schedules[name].Next(now)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, name := range due {
+		go func(name string) {
+			if _, err := s.RunPlan(name); err != nil {
+				log.Printf("backups: plan %s: %v", name, err)
+			}
+		}(name)
+	}
+}
+
+// Archive a volume now
+func (s *Service) Create(in CreateInput) (*Backup, error) {
+	return s.create(in.Volume, "", in.Compress)
+}
+
+func (s *Service) create(volume, plan string, compress bool) (*Backup, error) {
+	if !validVolumeName.MatchString(volume) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidVolume, volume)
+	}
+	exists, err := s.manager.VolumeExists(volume)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrVolumeNotFound, volume)
+	}
+	if err := s.acquire(volume); err != nil {
+		return nil, err
+	}
+	defer s.release(volume)
+
+	started := time.Now()
+	b := Backup{
+		ID:         s.newBackupID(volume, started),
+		Volume:     volume,
+		Plan:       plan,
+		Compressed: compress,
+		Created:    started,
+	}
+	b.File = b.ID + ".tar"
+	if compress {
+		b.File += ".gz"
+	}
+
+	path := s.archivePath(b)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	if err := s.archive(volume, path, compress, &b); err != nil {
+		return nil, err
+	}
+	b.DurationMS = time.Since(started).Milliseconds()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state.Backups = append(s.state.Backups, b)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Stream the volume as a tar from a helper container into path, hashing the
+// archive as it is written
+func (s *Service) archive(volume, path string, compress bool, b *Backup) error {
+	tmp := path + ".partial"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(tmp)
+
+	hash := sha256.New()
+	var out io.Writer = io.MultiWriter(file, hash)
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(out)
+		out = gz
+	}
+
+	cmd := []string{"tar", "-C", mountPath, "-cf", "-", "."}
+	stderr, code, err := s.manager.RunWithVolume(context.Background(), volume, mountPath, true, cmd, nil, out)
+	if err == nil && code != 0 {
+		err = fmt.Errorf("tar exited with code %d: %s", code, strings.TrimSpace(stderr))
+	}
+	if err == nil && gz != nil {
+		err = gz.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to back up volume: %w", err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+	if stat, err := os.Stat(path); err == nil {
+		b.Size = stat.Size()
+	}
+	b.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+// Backups, newest first, optionally for one volume
+func (s *Service) List(volume string) []Backup {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := []Backup{}
+	for i := len(s.state.Backups) - 1; i >= 0; i-- {
+		if volume == "" || s.state.Backups[i].Volume == volume {
+			list = append(list, s.state.Backups[i])
+		}
+	}
+	return list
+}
+
+func (s *Service) Get(id string) (*Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range s.state.Backups {
+		if b.ID == id {
+			return &b, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrBackupNotFound, id)
+}
+
+// Check a backup's archive against the checksum taken when it was written
+func (s *Service) Verify(id string) (*Backup, error) {
+	b, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(s.archivePath(*b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if sum := hex.EncodeToString(hash.Sum(nil)); sum != b.SHA256 {
+		return nil, fmt.Errorf("%w: %s has %s, expected %s", ErrChecksumMismatch, id, sum, b.SHA256)
+	}
+	return b, nil
+}
+
+// Unpack a verified backup into a volume, creating it if needed. Existing
+// volumes are only written to with Overwrite, which empties them first.
+func (s *Service) Restore(id string, in RestoreInput) (*Backup, error) {
+	b, err := s.Verify(id)
+	if err != nil {
+		return nil, err
+	}
+
+	target := in.Volume
+	if target == "" {
+		target = b.Volume
+	}
+	if !validVolumeName.MatchString(target) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidVolume, target)
+	}
+	exists, err := s.manager.VolumeExists(target)
+	if err != nil {
+		return nil, err
+	}
+	if exists && !in.Overwrite {
+		return nil, fmt.Errorf("%w: %s, set overwrite to replace its contents", ErrVolumeExists, target)
+	}
+	if err := s.acquire(target); err != nil {
+		return nil, err
+	}
+	defer s.release(target)
+
+	if !exists {
+		if err := s.manager.CreateVolume(target, in.Project); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Open(s.archivePath(*b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	var data io.Reader = file
+	if b.Compressed {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup: %w", err)
+		}
+		defer gz.Close()
+		data = gz
+	}
+
+	script := "find " + mountPath + " -mindepth 1 -delete && tar -C " + mountPath + " -xf -"
+	stderr, code, err := s.manager.RunWithVolume(context.Background(), target, mountPath, false, []string{"sh", "-c", script}, data, nil)
+	if err == nil && code != 0 {
+		err = fmt.Errorf("restore exited with code %d: %s", code, strings.TrimSpace(stderr))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore volume: %w", err)
+	}
+	return b, nil
+}
+
+// Remove a backup and its archive
+func (s *Service) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, b := range s.state.Backups {
+		if b.ID != id {
+			continue
+		}
+		s.state.Backups = append(s.state.Backups[:i:i], s.state.Backups[i+1:]...)
+		if err := s.save(); err != nil {
+			return err
+		}
+		if err := os.Remove(s.archivePath(b)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove backup file: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrBackupNotFound, id)
+}
+
+// Delete backups matching a prune request, returning what was removed
+func (s *Service) Prune(in PruneInput) ([]Backup, error) {
+	if in.Volume == "" && in.Plan == "" {
+		return nil, fmt.Errorf("volume or plan is required")
+	}
+	if in.Keep <= 0 && in.OlderThanHours <= 0 {
+		return nil, fmt.Errorf("keep or older_than_hours is required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := s.pruneLocked(in)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// Caller holds s.mu
+func (s *Service) pruneLocked(in PruneInput) []Backup {
+	matching := 0
+	for _, b := range s.state.Backups {
+		if matches(b, in) {
+			matching++
+		}
+	}
+
+	cutoff := time.Now().Add(-time.Duration(in.OlderThanHours) * time.Hour)
+	removed := []Backup{}
+	kept := make([]Backup, 0, len(s.state.Backups))
+	for _, b := range s.state.Backups {
+		// oldest first, so the first matches are beyond the newest Keep
+		if matches(b, in) {
+			tooMany := in.Keep > 0 && matching > in.Keep
+			tooOld := in.OlderThanHours > 0 && b.Created.Before(cutoff)
+			matching--
+			if tooMany || tooOld {
+				os.Remove(s.archivePath(b))
+				removed = append(removed, b)
+				continue
+			}
+		}
+		kept = append(kept, b)
+	}
+	s.state.Backups = kept
+	return removed
+}
+
+func matches(b Backup, in PruneInput) bool {
+	return (in.Volume == "" || b.Volume == in.Volume) && (in.Plan == "" || b.Plan == in.Plan)
+}
+
+func (s *Service) CreatePlan(p Plan) (*PlanStatus, error) {
+	if !validPlanName.MatchString(p.Name) {
+		return nil, fmt.Errorf("plan name must be lowercase letters, digits and hyphens")
+	}
+	if !validVolumeName.MatchString(p.Volume) {
+		return nil, fmt.Errorf("%w %q", ErrInvalidVolume, p.Volume)
+	}
+	schedule, err := scheduler.ParseCron(p.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	if p.Retention <= 0 {
+		p.Retention = defaultRetention
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.state.Plans[p.Name]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrPlanExists, p.Name)
+	}
+	p.LastRun, p.LastBackup, p.LastError = nil, "", ""
+	p.Created = time.Now()
+	s.state.Plans[p.Name] = &p
+	if err := s.save(); err != nil {
+		delete(s.state.Plans, p.Name)
+		return nil, err
+	}
+	s.schedules[p.Name] = schedule
+	s.next[p.Name] = schedule.Next(time.Now())
+	return s.planStatus(&p), nil
+}
+
+func (s *Service) ListPlans() []PlanStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]PlanStatus, 0, len(s.state.Plans))
+	for _, p := range s.state.Plans {
+		list = append(list, *s.planStatus(p))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Delete a plan; its backups are kept until deleted or pruned
+func (s *Service) DeletePlan(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.state.Plans[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPlanNotFound, name)
+	}
+	delete(s.state.Plans, name)
+	if err := s.save(); err != nil {
+		s.state.Plans[name] = p
+		return err
+	}
+	delete(s.schedules, name)
+	delete(s.next, name)
+	return nil
+}
+
+// Back up a plan's volume now and apply its retention
+func (s *Service) RunPlan(name string) (*Backup, error) {
+	s.mu.Lock()
+	p, ok := s.state.Plans[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrPlanNotFound, name)
+	}
+	plan := *p
+	s.mu.Unlock()
+
+	b, err := s.create(plan.Volume, plan.Name, plan.Compress)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if p, ok := s.state.Plans[name]; ok {
+		now := time.Now()
+		p.LastRun = &now
+		p.LastError = ""
+		if err != nil {
+			p.LastError = err.Error()
+		} else {
+			p.LastBackup = b.ID
+			s.pruneLocked(PruneInput{Plan: name, Keep: p.Retention})
+		}
+		if saveErr := s.save(); saveErr != nil {
+			log.Printf("backups: %v", saveErr)
+		}
+	}
+	return b, err
+}
+
+// Caller holds s.mu
+func (s *Service) planStatus(p *Plan) *PlanStatus {
+	status := &PlanStatus{Plan: *p}
+	if next, ok := s.next[p.Name]; ok {
+		status.NextRun = &next
+	}
+	return status
+}
+
+// One backup or restore per volume at a time
+func (s *Service) acquire(volume string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.busy[volume] {
+		return fmt.Errorf("%w: %s", ErrVolumeBusy, volume)
+	}
+	s.busy[volume] = true
+	return nil
+}
+
+func (s *Service) release(volume string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.busy, volume)
+}
+
+// Backup IDs are the volume and a timestamp, made unique within the second
+func (s *Service) newBackupID(volume string, t time.Time) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	base := volume + "-" + t.UTC().Format("20060102-150405")
+	id := base
+	for n := 2; ; n++ {
+		taken := false
+		for _, b := range s.state.Backups {
+			if b.ID == id {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func (s *Service) archivePath(b Backup) string {
+	return filepath.Join(s.backupDir, b.Volume, b.File)
+}
+
+// Caller holds s.mu
+func (s *Service) save() error {
+	return store.Save(filepath.Join(s.dir, "backups.json"), s.state)
+}
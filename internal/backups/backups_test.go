@@ -0,0 +1,253 @@
+package backups
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Fake volumes holding a string each. Helper containers "tar" a volume by
+// writing its contents and restore one by reading stdin.
+type fakeVolumes struct {
+	mu       sync.Mutex
+	contents map[string]string
+	labels   map[string]map[string]string // of created volumes
+	failTar  bool
+}
+
+func (f *fakeVolumes) install(docker *dockertest.Server, names ...string) {
+	for _, name := range names {
+		name := name
+		docker.Handle("GET /volumes/"+name, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			_, ok := f.contents[name]
+			f.mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"message": "no such volume"})
+				return
+			}
+			json.NewEncoder(w).Encode(volume.Volume{Name: name})
+		}))
+	}
+	docker.Handle("POST /volumes/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var options volume.CreateOptions
+		json.NewDecoder(r.Body).Decode(&options)
+		f.mu.Lock()
+		f.contents[options.Name] = ""
+		f.labels[options.Name] = options.Labels
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(volume.Volume{Name: options.Name})
+	}))
+	docker.Exec(func(c dockertest.Container, cmd, env []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		name, _, _ := strings.Cut(c.HostConfig.Binds[0], ":")
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		if cmd[0] == "tar" {
+			if f.failTar {
+				io.WriteString(stderr, "tar: read error")
+				return 2
+			}
+			io.WriteString(stdout, f.contents[name])
+			return 0
+		}
+		data, _ := io.ReadAll(stdin)
+		f.contents[name] = string(data)
+		return 0
+	})
+}
+
+func (f *fakeVolumes) get(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.contents[name]
+}
+
+func newTestService(t *testing.T) (*Service, *fakeVolumes, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	volumes := &fakeVolumes{contents: map[string]string{"data": "volume contents"}, labels: make(map[string]map[string]string)}
+	volumes.install(docker, "data", "copy", "missing")
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	backupDir := t.TempDir()
+	s, err := NewService(manager, t.TempDir(), backupDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, volumes, backupDir
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		s, volumes, _ := newTestService(t)
+		b, err := s.Create(CreateInput{Volume: "data", Compress: compress})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.Compressed != compress || b.SHA256 == "" || b.Size == 0 || !strings.HasPrefix(b.ID, "data-") {
+			t.Errorf("backup = %+v", b)
+		}
+		if compress != strings.HasSuffix(b.File, ".gz") {
+			t.Errorf("file = %s", b.File)
+		}
+
+		// restoring into the existing volume needs overwrite
+		if _, err := s.Restore(b.ID, RestoreInput{}); !errors.Is(err, ErrVolumeExists) {
+			t.Errorf("restore without overwrite: %v", err)
+		}
+		if _, err := s.Restore(b.ID, RestoreInput{Volume: "copy", Project: "Team"}); err == nil {
+			t.Error("restored into an invalid project")
+		}
+		if _, err := s.Restore(b.ID, RestoreInput{Volume: "copy", Project: "team"}); err != nil {
+			t.Fatal(err)
+		}
+		if got := volumes.get("copy"); got != "volume contents" {
+			t.Errorf("restored %q", got)
+		}
+		if project := volumes.labels["copy"]["localcloud.project"]; project != "team" {
+			t.Errorf("new volume counts against %q", project)
+		}
+	}
+}
+
+func TestCreateErrors(t *testing.T) {
+	s, volumes, backupDir := newTestService(t)
+	if _, err := s.Create(CreateInput{Volume: "../etc"}); !errors.Is(err, ErrInvalidVolume) {
+		t.Errorf("invalid volume: %v", err)
+	}
+	if _, err := s.Create(CreateInput{Volume: "missing"}); !errors.Is(err, ErrVolumeNotFound) {
+		t.Errorf("missing volume: %v", err)
+	}
+
+	volumes.failTar = true
+	if _, err := s.Create(CreateInput{Volume: "data"}); err == nil || !strings.Contains(err.Error(), "tar: read error") {
+		t.Errorf("failing tar: %v", err)
+	}
+	if entries, _ := os.ReadDir(backupDir + "/data"); len(entries) != 0 {
+		t.Errorf("partial backup left behind: %v", entries)
+	}
+	if list := s.List(""); len(list) != 0 {
+		t.Errorf("failed backup listed: %+v", list)
+	}
+}
+
+func TestVerifyDetectsCorruption(t *testing.T) {
+	s, _, _ := newTestService(t)
+	b, err := s.Create(CreateInput{Volume: "data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(b.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(s.archivePath(*b), []byte("tampered"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(b.ID); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Verify = %v", err)
+	}
+	if _, err := s.Restore(b.ID, RestoreInput{Volume: "copy"}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("restored a corrupt backup: %v", err)
+	}
+}
+
+func TestBackupIDsAreUnique(t *testing.T) {
+	s, _, _ := newTestService(t)
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.state.Backups = []Backup{{ID: "data-20240301-120000"}, {ID: "data-20240301-120000-2"}}
+	if id := s.newBackupID("data", now); id != "data-20240301-120000-3" {
+		t.Errorf("newBackupID = %s", id)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s, _, _ := newTestService(t)
+	now := time.Now()
+	for i, age := range []time.Duration{72, 48, 24, 1} {
+		b := Backup{ID: "data-" + string(rune('a'+i)), Volume: "data", File: "x.tar", Created: now.Add(-age * time.Hour)}
+		s.state.Backups = append(s.state.Backups, b)
+	}
+	s.state.Backups = append(s.state.Backups, Backup{ID: "other-a", Volume: "other", Created: now.Add(-100 * time.Hour)})
+
+	if _, err := s.Prune(PruneInput{Keep: 1}); err == nil {
+		t.Error("pruned without a volume or plan")
+	}
+	removed, err := s.Prune(PruneInput{Volume: "data", Keep: 3})
+	if err != nil || len(removed) != 1 || removed[0].ID != "data-a" {
+		t.Errorf("keep 3 removed %+v, %v", removed, err)
+	}
+	removed, _ = s.Prune(PruneInput{Volume: "data", OlderThanHours: 12})
+	if len(removed) != 2 {
+		t.Errorf("older than 12h removed %+v", removed)
+	}
+	if list := s.List(""); len(list) != 2 || list[0].ID != "other-a" || list[1].ID != "data-d" {
+		t.Errorf("left %+v", list)
+	}
+}
+
+func TestPlans(t *testing.T) {
+	s, _, _ := newTestService(t)
+	if _, err := s.CreatePlan(Plan{Name: "nightly", Volume: "data", Schedule: "not cron"}); err == nil {
+		t.Error("accepted an invalid schedule")
+	}
+	status, err := s.CreatePlan(Plan{Name: "nightly", Volume: "data", Schedule: "@daily", Retention: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.NextRun == nil {
+		t.Error("no next run")
+	}
+	if _, err := s.CreatePlan(Plan{Name: "nightly", Volume: "data", Schedule: "@daily"}); !errors.Is(err, ErrPlanExists) {
+		t.Errorf("creating twice: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := s.RunPlan("nightly"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups := s.List("data")
+	if len(backups) != 2 || backups[0].Plan != "nightly" {
+		t.Errorf("retention kept %+v", backups)
+	}
+	plans := s.ListPlans()
+	if len(plans) != 1 || plans[0].LastBackup != backups[0].ID || plans[0].LastRun == nil {
+		t.Errorf("plans = %+v", plans)
+	}
+
+	// failures are recorded on the plan
+	if _, err := s.CreatePlan(Plan{Name: "broken", Volume: "missing", Schedule: "@daily"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.RunPlan("broken"); !errors.Is(err, ErrVolumeNotFound) {
+		t.Errorf("RunPlan(broken) = %v", err)
+	}
+	if plans := s.ListPlans(); plans[0].Name != "broken" || plans[0].LastError == "" {
+		t.Errorf("plans = %+v", plans)
+	}
+
+	if err := s.DeletePlan("nightly"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.List("data")) != 2 {
+		t.Error("deleting a plan removed its backups")
+	}
+}
@@ -0,0 +1,848 @@
+// Batch jobs: queued one-off containers with retries, parallelism limits and array jobs
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Label placed on job containers
+const LabelJob = "localcloud.batch.job"
+
+// Job states
+const (
+	StatusSubmitted = "SUBMITTED"
+	StatusRunning   = "RUNNING"
+	StatusSucceeded = "SUCCEEDED"
+	StatusFailed    = "FAILED"
+)
+
+// Env var holding a child's index in an array job, named as in AWS Batch
+const ArrayIndexEnv = "AWS_BATCH_JOB_ARRAY_INDEX"
+
+const (
+	DefaultQueue          = "default"
+	defaultMaxParallel    = 4
+	defaultBackoffSeconds = 5
+	maxBackoff            = 5 * time.Minute
+	maxAttemptsLimit      = 10
+	maxArraySize          = 10000
+	dispatchInterval      = time.Second
+	retention             = 7 * 24 * time.Hour // finished jobs are forgotten after this
+	maxLogBytes           = 256 * 1024
+)
+
+var (
+	ErrJobNotFound   = errors.New("job not found")
+	ErrQueueNotFound = errors.New("job queue not found")
+	ErrQueueExists   = errors.New("job queue already exists")
+	ErrQueueInUse    = errors.New("job queue has unfinished jobs")
+	ErrJobFinished   = errors.New("job has already finished")
+)
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,127}$`)
+
+type Queue struct {
+	Name        string    `json:"name"`
+	MaxParallel int       `json:"max_parallel"` // jobs running at once
+	Created     time.Time `json:"created"`
+}
+
+type QueueStatus struct {
+	Queue
+	Submitted int `json:"submitted"`
+	Running   int `json:"running"`
+}
+
+type SubmitInput struct {
+	Name           string             `json:"name"`
+	Queue          string             `json:"queue"` // default queue if empty
+	Spec           compute.CreateSpec `json:"spec"`
+	MaxAttempts    int                `json:"max_attempts"`    // 1 if 0, no retries
+	BackoffSeconds int                `json:"backoff_seconds"` // first retry delay, doubling each time
+	TimeoutSeconds int                `json:"timeout_seconds"` // per attempt, 0 for no limit
+	ArraySize      int                `json:"array_size"`      // 0 for a single job
+}
+
+type Attempt struct {
+	Number     int        `json:"number"`
+	InstanceID string     `json:"instance_id,omitempty"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	Started    time.Time  `json:"started"`
+	Stopped    *time.Time `json:"stopped,omitempty"`
+}
+
+type Job struct {
+	ID             string             `json:"id"`
+	Name           string             `json:"name"`
+	Queue          string             `json:"queue"`
+	Spec           compute.CreateSpec `json:"spec"`
+	MaxAttempts    int                `json:"max_attempts"`
+	BackoffSeconds int                `json:"backoff_seconds"`
+	TimeoutSeconds int                `json:"timeout_seconds,omitempty"`
+	ArraySize      int                `json:"array_size,omitempty"`  // set on array parents
+	ArrayIndex     *int               `json:"array_index,omitempty"` // set on array children
+	ParentID       string             `json:"parent_id,omitempty"`
+	Status         string             `json:"status"`
+	StatusReason   string             `json:"status_reason,omitempty"`
+	Attempts       []Attempt          `json:"attempts"`
+	RetryAt        *time.Time         `json:"retry_at,omitempty"` // not runnable before this
+	Created        time.Time          `json:"created"`
+	Started        *time.Time         `json:"started,omitempty"`
+	Stopped        *time.Time         `json:"stopped,omitempty"`
+}
+
+// Job plus, for array parents, how many children are in each state
+type JobStatus struct {
+	Job
+	ArraySummary map[string]int `json:"array_summary,omitempty"`
+}
+
+type state struct {
+	Queues map[string]*Queue `json:"queues"`
+	Jobs   map[string]*Job   `json:"jobs"`
+}
+
+type Service struct {
+	manager *compute.Manager
+	dir     string
+
+	mu       sync.Mutex
+	state    state
+	children map[string][]string           // array parent ID to child IDs in index order
+	running  map[string]context.CancelFunc // by job ID
+	wake     chan struct{}
+}
+
+func NewService(manager *compute.Manager, dataDir string) (*Service, error) {
+	s := &Service{
+		manager: manager,
+		dir:     filepath.Join(dataDir, "batch"),
+		state: state{
+			Queues: make(map[string]*Queue),
+			Jobs:   make(map[string]*Job),
+		},
+		children: make(map[string][]string),
+		running:  make(map[string]context.CancelFunc),
+		wake:     make(chan struct{}, 1),
+	}
+	if err := store.Load(filepath.Join(s.dir, "batch.json"), &s.state); err != nil {
+		return nil, err
+	}
+	if _, ok := s.state.Queues[DefaultQueue]; !ok {
+		s.state.Queues[DefaultQueue] = &Queue{Name: DefaultQueue, MaxParallel: defaultMaxParallel, Created: time.Now()}
+	}
+	for _, job := range s.state.Jobs {
+		if job.ParentID != "" {
+			s.children[job.ParentID] = append(s.children[job.ParentID], job.ID)
+		}
+	}
+	for _, ids := range s.children {
+		s.sortChildren(ids)
+	}
+	return s, nil
+}
+
+// Dispatch runnable jobs until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	s.recover()
+
+	go func() {
+		ticker := time.NewTicker(dispatchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.prune()
+			case <-s.wake:
+			case <-ctx.Done():
+				return
+			}
+			s.dispatch()
+		}
+	}()
+}
+
+// Attempts running when LocalCloud stopped are lost: fail them and retry
+// if the job has attempts left
+func (s *Service) recover() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, job := range s.state.Jobs {
+		if job.Status != StatusRunning || job.ArraySize > 0 {
+			continue
+		}
+		attempt := &job.Attempts[len(job.Attempts)-1]
+		if attempt.InstanceID != "" {
+			s.saveLogs(job, attempt)
+			s.manager.Delete(attempt.InstanceID)
+		}
+		attempt.Reason = "interrupted by a LocalCloud restart"
+		attempt.Stopped = &now
+		s.afterAttempt(job, false, now)
+	}
+	if err := s.save(); err != nil {
+		log.Printf("Failed to save batch jobs: %v", err)
+	}
+}
+
+func (s *Service) CreateQueue(q Queue) (*Queue, error) {
+	if !validName.MatchString(q.Name) {
+		return nil, fmt.Errorf("queue name must be letters, digits, _ and -")
+	}
+	if q.MaxParallel <= 0 {
+		q.MaxParallel = defaultMaxParallel
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.state.Queues[q.Name]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrQueueExists, q.Name)
+	}
+	q.Created = time.Now()
+	s.state.Queues[q.Name] = &q
+	if err := s.save(); err != nil {
+		delete(s.state.Queues, q.Name)
+		return nil, err
+	}
+	return &q, nil
+}
+
+// Change how many jobs a queue runs at once
+func (s *Service) SetMaxParallel(name string, maxParallel int) (*Queue, error) {
+	if maxParallel <= 0 {
+		return nil, fmt.Errorf("max_parallel must be at least 1")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.state.Queues[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrQueueNotFound, name)
+	}
+	old := q.MaxParallel
+	q.MaxParallel = maxParallel
+	if err := s.save(); err != nil {
+		q.MaxParallel = old
+		return nil, err
+	}
+	s.poke()
+	copied := *q
+	return &copied, nil
+}
+
+func (s *Service) ListQueues() []QueueStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]QueueStatus, 0, len(s.state.Queues))
+	for _, q := range s.state.Queues {
+		status := QueueStatus{Queue: *q}
+		for _, job := range s.state.Jobs {
+			if job.Queue != q.Name || job.ArraySize > 0 {
+				continue
+			}
+			switch job.Status {
+			case StatusSubmitted:
+				status.Submitted++
+			case StatusRunning:
+				status.Running++
+			}
+		}
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// Delete an idle queue; the default queue always exists
+func (s *Service) DeleteQueue(name string) error {
+	if name == DefaultQueue {
+		return fmt.Errorf("the default queue cannot be deleted")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, ok := s.state.Queues[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrQueueNotFound, name)
+	}
+	for _, job := range s.state.Jobs {
+		if job.Queue == name && (job.Status == StatusSubmitted || job.Status == StatusRunning) {
+			return fmt.Errorf("%w: %s", ErrQueueInUse, name)
+		}
+	}
+	delete(s.state.Queues, name)
+	if err := s.save(); err != nil {
+		s.state.Queues[name] = q
+		return err
+	}
+	return nil
+}
+
+// Queue a job, or an array parent with one child per index
+func (s *Service) Submit(in SubmitInput) (*JobStatus, error) {
+	if !validName.MatchString(in.Name) {
+		return nil, fmt.Errorf("job name must be letters, digits, _ and -")
+	}
+	if in.Spec.Image == "" && in.Spec.Snapshot == "" {
+		return nil, fmt.Errorf("spec.image or spec.snapshot is required")
+	}
+	if in.Queue == "" {
+		in.Queue = DefaultQueue
+	}
+	if in.MaxAttempts <= 0 {
+		in.MaxAttempts = 1
+	}
+	if in.MaxAttempts > maxAttemptsLimit {
+		return nil, fmt.Errorf("max_attempts must be at most %d", maxAttemptsLimit)
+	}
+	if in.BackoffSeconds <= 0 {
+		in.BackoffSeconds = defaultBackoffSeconds
+	}
+	if in.TimeoutSeconds < 0 {
+		return nil, fmt.Errorf("timeout_seconds must not be negative")
+	}
+	if in.ArraySize == 1 || in.ArraySize < 0 || in.ArraySize > maxArraySize {
+		return nil, fmt.Errorf("array_size must be between 2 and %d", maxArraySize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.Queues[in.Queue]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrQueueNotFound, in.Queue)
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:             uuid.New().String()[:8],
+		Name:           in.Name,
+		Queue:          in.Queue,
+		Spec:           in.Spec,
+		MaxAttempts:    in.MaxAttempts,
+		BackoffSeconds: in.BackoffSeconds,
+		TimeoutSeconds: in.TimeoutSeconds,
+		ArraySize:      in.ArraySize,
+		Status:         StatusSubmitted,
+		Attempts:       []Attempt{},
+		Created:        now,
+	}
+	s.state.Jobs[job.ID] = job
+
+	var ids []string
+	for i := 0; i < in.ArraySize; i++ {
+		index := i
+		child := *job
+		child.ID = job.ID + ":" + strconv.Itoa(i)
+		child.Name = job.Name + ":" + strconv.Itoa(i)
+		child.ArraySize = 0
+		child.ArrayIndex = &index
+		child.ParentID = job.ID
+		child.Attempts = []Attempt{}
+		s.state.Jobs[child.ID] = &child
+		ids = append(ids, child.ID)
+	}
+
+	if err := s.save(); err != nil {
+		delete(s.state.Jobs, job.ID)
+		for _, id := range ids {
+			delete(s.state.Jobs, id)
+		}
+		return nil, err
+	}
+	if len(ids) > 0 {
+		s.children[job.ID] = ids
+	}
+
+	s.poke()
+	return s.status(job), nil
+}
+
+// Jobs, newest first, optionally filtered by queue and status. Array
+// children are left out; they are listed with their parent.
+func (s *Service) List(queue, status string) []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []JobStatus
+	for _, job := range s.state.Jobs {
+		if job.ParentID != "" {
+			continue
+		}
+		if queue != "" && job.Queue != queue {
+			continue
+		}
+		if status != "" && !strings.EqualFold(job.Status, status) {
+			continue
+		}
+		list = append(list, *s.status(job))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Created.After(list[j].Created) })
+	return list
+}
+
+func (s *Service) Get(id string) (*JobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.state.Jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	return s.status(job), nil
+}
+
+// Children of an array job in index order
+func (s *Service) Children(id string) ([]Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.Jobs[id]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	children := make([]Job, 0, len(s.children[id]))
+	for _, childID := range s.children[id] {
+		children = append(children, *s.state.Jobs[childID])
+	}
+	return children, nil
+}
+
+// Fail a job that has not finished, stopping its container. Cancelling an
+// array job cancels all its children.
+func (s *Service) Cancel(id, reason string) (*JobStatus, error) {
+	if reason == "" {
+		reason = "cancelled by user"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.state.Jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if job.Status == StatusSucceeded || job.Status == StatusFailed {
+		return nil, fmt.Errorf("%w: %s", ErrJobFinished, id)
+	}
+
+	targets := []*Job{job}
+	for _, childID := range s.children[id] {
+		targets = append(targets, s.state.Jobs[childID])
+	}
+
+	now := time.Now()
+	for _, target := range targets {
+		switch target.Status {
+		case StatusSubmitted:
+			s.stop(target, StatusFailed, reason, now)
+		case StatusRunning:
+			if cancel, ok := s.running[target.ID]; ok {
+				// the attempt finishes as failed without a retry
+				target.StatusReason = reason
+				cancel()
+			} else if target.ArraySize > 0 {
+				s.stop(target, StatusFailed, reason, now)
+			}
+		}
+	}
+	if job.ParentID != "" {
+		s.updateParent(job.ParentID, now)
+	}
+
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return s.status(job), nil
+}
+
+// Output of an attempt, the latest if attempt is 0
+func (s *Service) Logs(id string, attempt int) (string, error) {
+	s.mu.Lock()
+	job, ok := s.state.Jobs[id]
+	if !ok {
+		s.mu.Unlock()
+		return "", fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if job.ArraySize > 0 {
+		s.mu.Unlock()
+		return "", fmt.Errorf("job %s is an array job, get the logs of a child such as %s:0", id, id)
+	}
+	if attempt == 0 {
+		attempt = len(job.Attempts)
+	}
+	if attempt < 1 || attempt > len(job.Attempts) {
+		s.mu.Unlock()
+		return "", fmt.Errorf("job %s has no attempt %d", id, attempt)
+	}
+	a := job.Attempts[attempt-1]
+	s.mu.Unlock()
+
+	if a.Stopped == nil && a.InstanceID != "" {
+		// still running, read straight from the container
+		return s.manager.Output(a.InstanceID, maxLogBytes)
+	}
+	data, err := os.ReadFile(s.logPath(id, attempt))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read job logs: %w", err)
+	}
+	return string(data), nil
+}
+
+// Start runnable jobs while their queues have room
+func (s *Service) dispatch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	running := make(map[string]int)
+	var runnable []*Job
+	for _, job := range s.state.Jobs {
+		if job.ArraySize > 0 {
+			continue
+		}
+		switch job.Status {
+		case StatusRunning:
+			running[job.Queue]++
+		case StatusSubmitted:
+			if job.RetryAt == nil || !now.Before(*job.RetryAt) {
+				runnable = append(runnable, job)
+			}
+		}
+	}
+
+	// first come, first served, array children in index order
+	sort.Slice(runnable, func(i, j int) bool {
+		a, b := runnable[i], runnable[j]
+		if !a.Created.Equal(b.Created) {
+			return a.Created.Before(b.Created)
+		}
+		if indexOf(a) != indexOf(b) {
+			return indexOf(a) < indexOf(b)
+		}
+		return a.ID < b.ID
+	})
+
+	started := false
+	for _, job := range runnable {
+		q, ok := s.state.Queues[job.Queue]
+		if !ok || running[job.Queue] >= q.MaxParallel {
+			continue
+		}
+		running[job.Queue]++
+		s.startAttempt(job, now)
+		started = true
+	}
+	if started {
+		if err := s.save(); err != nil {
+			log.Printf("Failed to save batch jobs: %v", err)
+		}
+	}
+}
+
+func indexOf(job *Job) int {
+	if job.ArrayIndex == nil {
+		return -1
+	}
+	return *job.ArrayIndex
+}
+
+// Caller holds s.mu
+func (s *Service) startAttempt(job *Job, now time.Time) {
+	job.Status = StatusRunning
+	job.StatusReason = ""
+	job.RetryAt = nil
+	if job.Started == nil {
+		job.Started = &now
+	}
+	job.Attempts = append(job.Attempts, Attempt{Number: len(job.Attempts) + 1, Started: now})
+	if job.ParentID != "" {
+		s.updateParent(job.ParentID, now)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if job.TimeoutSeconds > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(job.TimeoutSeconds)*time.Second)
+	}
+	s.running[job.ID] = cancel
+
+	spec := job.Spec
+	spec.Name = "localcloud-batch-" + strings.ReplaceAll(job.ID, ":", "-") + "-" + strconv.Itoa(len(job.Attempts))
+	spec.HealthCheck = nil
+	spec.Labels = make(map[string]string, len(job.Spec.Labels)+1)
+	for key, value := range job.Spec.Labels {
+		spec.Labels[key] = value
+	}
+	spec.Labels[LabelJob] = job.ID
+	spec.Env = append([]string{}, job.Spec.Env...)
+	if job.ArrayIndex != nil {
+		spec.Env = append(spec.Env, ArrayIndexEnv+"="+strconv.Itoa(*job.ArrayIndex))
+	}
+	spec.Env = append(spec.Env, "LOCALCLOUD_BATCH_JOB_ID="+job.ID, "LOCALCLOUD_BATCH_ATTEMPT="+strconv.Itoa(len(job.Attempts)))
+
+	go s.run(ctx, job.ID, spec)
+}
+
+// Run one attempt to completion
+func (s *Service) run(ctx context.Context, id string, spec compute.CreateSpec) {
+	instance, err := s.manager.Create(spec)
+	if err != nil {
+		s.finishAttempt(id, nil, "failed to start container: "+err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	if job, ok := s.state.Jobs[id]; ok {
+		job.Attempts[len(job.Attempts)-1].InstanceID = instance.ID
+	}
+	s.mu.Unlock()
+
+	exitCode, err := s.manager.Wait(ctx, instance.ID)
+	var code *int
+	reason := ""
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		reason = "attempt exceeded its timeout"
+	case ctx.Err() != nil:
+		reason = "cancelled"
+	case err != nil:
+		reason = err.Error()
+	default:
+		code = &exitCode
+		if exitCode != 0 {
+			reason = fmt.Sprintf("container exited with code %d", exitCode)
+		}
+	}
+	s.finishAttempt(id, code, reason)
+}
+
+// Record an attempt's result, keep its logs, remove its container and
+// decide whether to retry
+func (s *Service) finishAttempt(id string, exitCode *int, reason string) {
+	s.mu.Lock()
+	var instanceID string
+	if job, ok := s.state.Jobs[id]; ok {
+		instanceID = job.Attempts[len(job.Attempts)-1].InstanceID
+	}
+	s.mu.Unlock()
+
+	// collect output and clean up without holding the lock
+	var logs string
+	if instanceID != "" {
+		var err error
+		if logs, err = s.manager.Output(instanceID, maxLogBytes); err != nil {
+			log.Printf("Failed to read logs of batch job %s: %v", id, err)
+		}
+		if err := s.manager.Delete(instanceID); err != nil {
+			log.Printf("Failed to remove container of batch job %s: %v", id, err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cancel, wasRunning := s.running[id]
+	if wasRunning {
+		cancel()
+		delete(s.running, id)
+	}
+	job, ok := s.state.Jobs[id]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	attempt := &job.Attempts[len(job.Attempts)-1]
+	attempt.ExitCode = exitCode
+	attempt.Reason = reason
+	attempt.Stopped = &now
+	if instanceID != "" {
+		s.writeLogs(id, attempt.Number, logs)
+	}
+
+	succeeded := exitCode != nil && *exitCode == 0
+	if job.StatusReason != "" && !succeeded {
+		// cancelled while running
+		s.stop(job, StatusFailed, job.StatusReason, now)
+	} else {
+		s.afterAttempt(job, succeeded, now)
+	}
+	if job.ParentID != "" {
+		s.updateParent(job.ParentID, now)
+	}
+
+	if err := s.save(); err != nil {
+		log.Printf("Failed to save batch jobs: %v", err)
+	}
+	s.poke()
+}
+
+// Succeed, retry with backoff or fail after an attempt. Caller holds s.mu.
+func (s *Service) afterAttempt(job *Job, succeeded bool, now time.Time) {
+	attempt := job.Attempts[len(job.Attempts)-1]
+	switch {
+	case succeeded:
+		s.stop(job, StatusSucceeded, "", now)
+	case len(job.Attempts) < job.MaxAttempts:
+		backoff := time.Duration(job.BackoffSeconds) * time.Second << (len(job.Attempts) - 1)
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+		retryAt := now.Add(backoff)
+		job.Status = StatusSubmitted
+		job.StatusReason = fmt.Sprintf("attempt %d failed (%s), retrying at %s", attempt.Number, attempt.Reason, retryAt.Format(time.RFC3339))
+		job.RetryAt = &retryAt
+	default:
+		s.stop(job, StatusFailed, fmt.Sprintf("attempt %d of %d failed: %s", attempt.Number, job.MaxAttempts, attempt.Reason), now)
+	}
+}
+
+// Caller holds s.mu
+func (s *Service) stop(job *Job, status, reason string, now time.Time) {
+	job.Status = status
+	job.StatusReason = reason
+	job.RetryAt = nil
+	job.Stopped = &now
+}
+
+// Array parents are running once a child starts and finish with the last
+// child, succeeding only if every child did. Caller holds s.mu.
+func (s *Service) updateParent(parentID string, now time.Time) {
+	parent, ok := s.state.Jobs[parentID]
+	if !ok || parent.Status == StatusSucceeded || parent.Status == StatusFailed {
+		return
+	}
+
+	summary := s.summary(parentID)
+	switch {
+	case summary[StatusSucceeded]+summary[StatusFailed] == parent.ArraySize:
+		if summary[StatusFailed] > 0 {
+			s.stop(parent, StatusFailed, fmt.Sprintf("%d of %d children failed", summary[StatusFailed], parent.ArraySize), now)
+		} else {
+			s.stop(parent, StatusSucceeded, "", now)
+		}
+	case summary[StatusRunning] > 0 || summary[StatusSucceeded]+summary[StatusFailed] > 0:
+		parent.Status = StatusRunning
+		if parent.Started == nil {
+			parent.Started = &now
+		}
+	}
+}
+
+// Caller holds s.mu
+func (s *Service) summary(parentID string) map[string]int {
+	summary := map[string]int{StatusSubmitted: 0, StatusRunning: 0, StatusSucceeded: 0, StatusFailed: 0}
+	for _, childID := range s.children[parentID] {
+		summary[s.state.Jobs[childID].Status]++
+	}
+	return summary
+}
+
+// Caller holds s.mu
+func (s *Service) status(job *Job) *JobStatus {
+	status := &JobStatus{Job: *job}
+	status.Attempts = append([]Attempt(nil), job.Attempts...)
+	if job.ArraySize > 0 {
+		status.ArraySummary = s.summary(job.ID)
+	}
+	return status
+}
+
+// Copy an attempt's output to disk
+func (s *Service) saveLogs(job *Job, attempt *Attempt) {
+	logs, err := s.manager.Output(attempt.InstanceID, maxLogBytes)
+	if err != nil {
+		log.Printf("Failed to read logs of batch job %s: %v", job.ID, err)
+		return
+	}
+	s.writeLogs(job.ID, attempt.Number, logs)
+}
+
+func (s *Service) writeLogs(id string, attempt int, logs string) {
+	path := s.logPath(id, attempt)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Printf("Failed to save logs of batch job %s: %v", id, err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(logs), 0o600); err != nil {
+		log.Printf("Failed to save logs of batch job %s: %v", id, err)
+	}
+}
+
+// Forget finished jobs past the retention period, with their logs
+func (s *Service) prune() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-retention)
+	removed := false
+	for id, job := range s.state.Jobs {
+		if job.ParentID != "" || job.Stopped == nil || job.Stopped.After(cutoff) {
+			continue
+		}
+		for _, childID := range append(s.children[id], id) {
+			os.RemoveAll(filepath.Join(s.dir, "logs", logDirName(childID)))
+			delete(s.state.Jobs, childID)
+		}
+		delete(s.children, id)
+		removed = true
+	}
+	if removed {
+		if err := s.save(); err != nil {
+			log.Printf("Failed to save batch jobs: %v", err)
+		}
+	}
+}
+
+// Wake the dispatcher without blocking
+func (s *Service) poke() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *Service) sortChildren(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		return indexOf(s.state.Jobs[ids[i]]) < indexOf(s.state.Jobs[ids[j]])
+	})
+}
+
+// Caller holds s.mu
+func (s *Service) save() error {
+	return store.Save(filepath.Join(s.dir, "batch.json"), s.state)
+}
+
+func (s *Service) logPath(id string, attempt int) string {
+	return filepath.Join(s.dir, "logs", logDirName(id), strconv.Itoa(attempt)+".log")
+}
+
+// Child IDs contain a colon, which some file systems reject
+func logDirName(id string) string {
+	return strings.ReplaceAll(id, ":", "-")
+}
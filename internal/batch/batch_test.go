@@ -0,0 +1,306 @@
+package batch
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+)
+
+// Containers run by their command: "true" and "false" exit at once with 0
+// and 1, "odd" fails on odd array indexes, anything else keeps running
+func newTestService(t *testing.T) (*Service, *dockertest.Server, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	docker.OnCreate(func(c *dockertest.Container) {
+		if len(c.Config.Cmd) == 0 {
+			return
+		}
+		switch c.Config.Cmd[0] {
+		case "true":
+			c.RunToExit = true
+			c.Output = "ok\n"
+		case "false":
+			c.RunToExit = true
+			c.ExitCode = 1
+			c.Output = "failed\n"
+		case "odd":
+			c.RunToExit = true
+			for _, env := range c.Config.Env {
+				if env == ArrayIndexEnv+"=1" || env == ArrayIndexEnv+"=3" {
+					c.ExitCode = 1
+				}
+			}
+		}
+	})
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s, err := NewService(manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, docker, dir
+}
+
+func submit(t *testing.T, s *Service, in SubmitInput) *JobStatus {
+	t.Helper()
+	if in.Spec.Image == "" {
+		in.Spec.Image = "busybox"
+	}
+	job, err := s.Submit(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+// Dispatch until no job is running and nothing is runnable right now
+func settle(t *testing.T, s *Service) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		s.dispatch()
+		s.mu.Lock()
+		busy := len(s.running) > 0
+		s.mu.Unlock()
+		if !busy {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("jobs still running")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func get(t *testing.T, s *Service, id string) *JobStatus {
+	t.Helper()
+	job, err := s.Get(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return job
+}
+
+func TestSubmitValidation(t *testing.T) {
+	s, _, _ := newTestService(t)
+	tests := []struct {
+		in  SubmitInput
+		err string
+	}{
+		{SubmitInput{Name: "bad name", Spec: compute.CreateSpec{Image: "busybox"}}, "job name"},
+		{SubmitInput{Name: "job"}, "spec.image"},
+		{SubmitInput{Name: "job", Spec: compute.CreateSpec{Image: "busybox"}, MaxAttempts: maxAttemptsLimit + 1}, "max_attempts"},
+		{SubmitInput{Name: "job", Spec: compute.CreateSpec{Image: "busybox"}, TimeoutSeconds: -1}, "timeout_seconds"},
+		{SubmitInput{Name: "job", Spec: compute.CreateSpec{Image: "busybox"}, ArraySize: 1}, "array_size"},
+		{SubmitInput{Name: "job", Spec: compute.CreateSpec{Image: "busybox"}, Queue: "missing"}, "queue not found"},
+	}
+	for _, tt := range tests {
+		if _, err := s.Submit(tt.in); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Submit(%+v) = %v, want %q", tt.in, err, tt.err)
+		}
+	}
+
+	job := submit(t, s, SubmitInput{Name: "job"})
+	if job.Queue != DefaultQueue || job.MaxAttempts != 1 || job.BackoffSeconds != defaultBackoffSeconds || job.Status != StatusSubmitted {
+		t.Errorf("defaults = %+v", job)
+	}
+}
+
+func TestRunToCompletion(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	ok := submit(t, s, SubmitInput{Name: "ok", Spec: compute.CreateSpec{Command: []string{"true"}, Env: []string{"MODE=test"}}})
+	failed := submit(t, s, SubmitInput{Name: "failed", Spec: compute.CreateSpec{Command: []string{"false"}}})
+	settle(t, s)
+
+	job := get(t, s, ok.ID)
+	if job.Status != StatusSucceeded || len(job.Attempts) != 1 || *job.Attempts[0].ExitCode != 0 || job.Stopped == nil {
+		t.Errorf("succeeded job = %+v", job)
+	}
+	if logs, err := s.Logs(ok.ID, 0); err != nil || logs != "ok\n" {
+		t.Errorf("Logs = %q, %v", logs, err)
+	}
+	job = get(t, s, failed.ID)
+	if job.Status != StatusFailed || !strings.Contains(job.StatusReason, "exited with code 1") {
+		t.Errorf("failed job = %+v", job)
+	}
+	if _, err := s.Logs(ok.ID, 2); err == nil {
+		t.Error("got logs of a missing attempt")
+	}
+
+	if containers := docker.Containers(); len(containers) != 0 {
+		t.Errorf("%d containers left", len(containers))
+	}
+	if list := s.List("", "succeeded"); len(list) != 1 || list[0].ID != ok.ID {
+		t.Errorf("List(succeeded) = %+v", list)
+	}
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	s, _, _ := newTestService(t)
+	job := submit(t, s, SubmitInput{Name: "flaky", Spec: compute.CreateSpec{Command: []string{"false"}}, MaxAttempts: 3, BackoffSeconds: 60})
+	settle(t, s)
+
+	status := get(t, s, job.ID)
+	if status.Status != StatusSubmitted || status.RetryAt == nil || time.Until(*status.RetryAt) < 50*time.Second {
+		t.Fatalf("after the first attempt: %+v", status)
+	}
+
+	// the second retry waits twice as long
+	s.mu.Lock()
+	past := time.Now().Add(-time.Second)
+	s.state.Jobs[job.ID].RetryAt = &past
+	s.mu.Unlock()
+	settle(t, s)
+	status = get(t, s, job.ID)
+	if len(status.Attempts) != 2 || time.Until(*status.RetryAt) < 110*time.Second {
+		t.Fatalf("after the second attempt: %+v", status)
+	}
+
+	s.mu.Lock()
+	s.state.Jobs[job.ID].RetryAt = &past
+	s.mu.Unlock()
+	settle(t, s)
+	status = get(t, s, job.ID)
+	if status.Status != StatusFailed || len(status.Attempts) != 3 || !strings.Contains(status.StatusReason, "attempt 3 of 3") {
+		t.Errorf("after the last attempt: %+v", status)
+	}
+}
+
+func TestQueueLimitsParallelism(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	if _, err := s.CreateQueue(Queue{Name: "serial", MaxParallel: 1}); err != nil {
+		t.Fatal(err)
+	}
+	first := submit(t, s, SubmitInput{Name: "first", Queue: "serial", Spec: compute.CreateSpec{Command: []string{"sleep"}}})
+	second := submit(t, s, SubmitInput{Name: "second", Queue: "serial", Spec: compute.CreateSpec{Command: []string{"true"}}})
+
+	s.dispatch()
+	if get(t, s, first.ID).Status != StatusRunning || get(t, s, second.ID).Status != StatusSubmitted {
+		t.Fatal("the queue ran two jobs at once")
+	}
+	if err := s.DeleteQueue("serial"); !errors.Is(err, ErrQueueInUse) {
+		t.Errorf("deleting a busy queue: %v", err)
+	}
+
+	// wait for the container, then let it exit
+	deadline := time.Now().Add(5 * time.Second)
+	for len(docker.Containers()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("no container started")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	docker.SetState(docker.Containers()[0].ID, "exited")
+	settle(t, s)
+	if get(t, s, second.ID).Status != StatusSucceeded {
+		t.Errorf("second job = %+v", get(t, s, second.ID))
+	}
+	if err := s.DeleteQueue("serial"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestArrayJob(t *testing.T) {
+	s, _, _ := newTestService(t)
+	parent := submit(t, s, SubmitInput{Name: "sweep", Spec: compute.CreateSpec{Command: []string{"odd"}}, ArraySize: 4})
+	if parent.ArraySummary[StatusSubmitted] != 4 {
+		t.Errorf("summary = %v", parent.ArraySummary)
+	}
+	settle(t, s)
+
+	status := get(t, s, parent.ID)
+	if status.Status != StatusFailed || status.StatusReason != "2 of 4 children failed" {
+		t.Errorf("parent = %+v", status)
+	}
+	children, _ := s.Children(parent.ID)
+	for i, child := range children {
+		want := StatusSucceeded
+		if i%2 == 1 {
+			want = StatusFailed
+		}
+		if child.ID != parent.ID+":"+strconv.Itoa(i) || child.Status != want {
+			t.Errorf("child %d = %s %s", i, child.ID, child.Status)
+		}
+	}
+	if list := s.List("", ""); len(list) != 1 {
+		t.Errorf("children listed as jobs: %d", len(list))
+	}
+	if _, err := s.Logs(parent.ID, 0); err == nil {
+		t.Error("got logs of an array parent")
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s, _, _ := newTestService(t)
+	if _, err := s.CreateQueue(Queue{Name: "serial", MaxParallel: 1}); err != nil {
+		t.Fatal(err)
+	}
+	running := submit(t, s, SubmitInput{Name: "running", Queue: "serial", Spec: compute.CreateSpec{Command: []string{"sleep"}}, MaxAttempts: 3})
+	waiting := submit(t, s, SubmitInput{Name: "waiting", Queue: "serial", Spec: compute.CreateSpec{Command: []string{"true"}}})
+	s.dispatch()
+
+	if _, err := s.Cancel(waiting.ID, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Cancel(running.ID, "no longer needed"); err != nil {
+		t.Fatal(err)
+	}
+	settle(t, s)
+
+	// a cancelled job is not retried
+	status := get(t, s, running.ID)
+	if status.Status != StatusFailed || status.StatusReason != "no longer needed" || len(status.Attempts) != 1 {
+		t.Errorf("cancelled running job = %+v", status)
+	}
+	if status := get(t, s, waiting.ID); status.Status != StatusFailed || status.StatusReason != "cancelled by user" || len(status.Attempts) != 0 {
+		t.Errorf("cancelled waiting job = %+v", status)
+	}
+	if _, err := s.Cancel(running.ID, ""); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("cancelling twice: %v", err)
+	}
+}
+
+func TestRecoverAndPrune(t *testing.T) {
+	s, _, dir := newTestService(t)
+	job := submit(t, s, SubmitInput{Name: "interrupted", Spec: compute.CreateSpec{Command: []string{"true"}}, MaxAttempts: 2})
+	done := submit(t, s, SubmitInput{Name: "done", Spec: compute.CreateSpec{Command: []string{"true"}}})
+	settle(t, s)
+
+	// pretend LocalCloud stopped while the first job was running
+	s.mu.Lock()
+	j := s.state.Jobs[job.ID]
+	j.Status = StatusRunning
+	j.Attempts[0].Stopped = nil
+	j.Attempts[0].InstanceID = ""
+	old := time.Now().Add(-retention - time.Hour)
+	s.state.Jobs[done.ID].Stopped = &old
+	s.save()
+	s.mu.Unlock()
+
+	reopened, err := NewService(s.manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.recover()
+	status := get(t, reopened, job.ID)
+	if status.Status != StatusSubmitted || status.Attempts[0].Reason != "interrupted by a LocalCloud restart" {
+		t.Errorf("recovered job = %+v", status)
+	}
+
+	reopened.prune()
+	if _, err := reopened.Get(done.ID); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("old job not pruned: %v", err)
+	}
+	if _, err := os.Stat(reopened.logPath(done.ID, 1)); !os.IsNotExist(err) {
+		t.Errorf("logs of a pruned job kept: %v", err)
+	}
+}
@@ -0,0 +1,587 @@
+// Simulated billing: meters instances and volumes against a price sheet
+package billing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+)
+
+const (
+	meterInterval = time.Minute
+	hoursPerMonth = 730
+	retentionDays = 400
+	dayFormat     = "2006-01-02"
+)
+
+// Resource name line items for a project's volumes are recorded under
+const StorageResource = "volumes"
+
+var (
+	ErrBudgetNotFound = errors.New("budget not found")
+	ErrBudgetExists   = errors.New("budget already exists")
+	ErrInvalidInput   = errors.New("invalid input")
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Prices charges accrue at. Instances without CPU or memory limits are
+// billed as DefaultQuotaCPUs and DefaultQuotaMemoryMB.
+type PriceSheet struct {
+	Currency       string  `json:"currency"`
+	InstanceHour   float64 `json:"instance_hour"`    // flat, per running instance
+	VCPUHour       float64 `json:"vcpu_hour"`        // per CPU of the instance's limit
+	GBHour         float64 `json:"gb_hour"`          // per GB of the memory limit
+	StorageGBMonth float64 `json:"storage_gb_month"` // named volumes
+	NetworkGB      float64 `json:"network_gb"`       // received plus sent
+}
+
+// Roughly on-demand cloud list prices
+var DefaultPrices = PriceSheet{
+	Currency:       "USD",
+	InstanceHour:   0.005,
+	VCPUHour:       0.04,
+	GBHour:         0.005,
+	StorageGBMonth: 0.10,
+	NetworkGB:      0.09,
+}
+
+// Metered usage and its cost
+type Charges struct {
+	InstanceHours  float64 `json:"instance_hours"`
+	VCPUHours      float64 `json:"vcpu_hours"`
+	GBHours        float64 `json:"gb_hours"`
+	StorageGBHours float64 `json:"storage_gb_hours"`
+	NetworkGB      float64 `json:"network_gb"`
+	Cost           float64 `json:"cost"`
+}
+
+func (c *Charges) add(other Charges) {
+	c.InstanceHours += other.InstanceHours
+	c.VCPUHours += other.VCPUHours
+	c.GBHours += other.GBHours
+	c.StorageGBHours += other.StorageGBHours
+	c.NetworkGB += other.NetworkGB
+	c.Cost += other.Cost
+}
+
+// Charges of one resource on one day
+type LineItem struct {
+	Day      string            `json:"day"` // YYYY-MM-DD, server time zone
+	Project  string            `json:"project"`
+	Resource string            `json:"resource"` // instance name, or volumes
+	Tags     map[string]string `json:"tags,omitempty"`
+	Charges
+}
+
+// Spend limit for a calendar month
+type Budget struct {
+	Name       string    `json:"name"`
+	Amount     float64   `json:"amount"`
+	Project    string    `json:"project,omitempty"` // all projects if empty
+	Tag        string    `json:"tag,omitempty"`     // key=value, everything if empty
+	Thresholds []int     `json:"thresholds"`        // percent of amount to alert at
+	Created    time.Time `json:"created"`
+}
+
+// Budget plus its month-to-date spend
+type BudgetStatus struct {
+	Budget
+	Spent    float64 `json:"spent"`
+	Forecast float64 `json:"forecast"` // spend by month end at the current rate
+	Percent  float64 `json:"percent"`
+}
+
+// A budget crossing one of its thresholds
+type Alert struct {
+	Budget    string    `json:"budget"`
+	Month     string    `json:"month"` // YYYY-MM
+	Threshold int       `json:"threshold"`
+	Spent     float64   `json:"spent"`
+	Amount    float64   `json:"amount"`
+	Time      time.Time `json:"time"`
+}
+
+// Last network counters seen for an instance, to bill the difference
+type sample struct {
+	network uint64
+	time    time.Time
+}
+
+type Service struct {
+	manager *compute.Manager
+	dir     string
+
+	mu      sync.Mutex
+	prices  PriceSheet
+	items   map[string]*LineItem // day/project/resource
+	budgets map[string]*Budget
+	alerts  []Alert
+	samples map[string]sample // container ID
+	storage time.Time         // when storage was last metered
+}
+
+func NewService(manager *compute.Manager, dataDir string) (*Service, error) {
+	s := &Service{
+		manager: manager,
+		dir:     filepath.Join(dataDir, "billing"),
+		prices:  DefaultPrices,
+		items:   make(map[string]*LineItem),
+		budgets: make(map[string]*Budget),
+		samples: make(map[string]sample),
+	}
+	var items []*LineItem
+	for path, v := range map[string]interface{}{
+		"prices.json":  &s.prices,
+		"usage.json":   &items,
+		"budgets.json": &s.budgets,
+		"alerts.json":  &s.alerts,
+	} {
+		if err := store.Load(filepath.Join(s.dir, path), v); err != nil {
+			return nil, err
+		}
+	}
+	for _, item := range items {
+		s.items[itemKey(item.Day, item.Project, item.Resource)] = item
+	}
+	return s, nil
+}
+
+func itemKey(day, project, resource string) string {
+	return day + "/" + project + "/" + resource
+}
+
+// Meter usage every minute until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(meterInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				s.meter(now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+type instanceUsage struct {
+	project  string
+	resource string
+	tags     map[string]string
+	charges  Charges
+}
+
+// Accrue charges for the time since the last meter run. Docker is queried
+// without the lock; only the bookkeeping happens under it.
+func (s *Service) meter(now time.Time) {
+	s.mu.Lock()
+	prices := s.prices
+	previous := make(map[string]sample, len(s.samples))
+	for id, smp := range s.samples {
+		previous[id] = smp
+	}
+	lastStorage := s.storage
+	s.mu.Unlock()
+
+	var usage []instanceUsage
+	samples := make(map[string]sample)
+	for _, instance := range s.manager.List() {
+		if instance.State != "running" {
+			continue
+		}
+		spec, err := s.manager.Spec(instance.ID)
+		if err != nil {
+			continue // not a LocalCloud instance
+		}
+		metrics, err := s.manager.GetMetrics(instance.ID)
+		if err != nil {
+			continue
+		}
+		network := metrics.NetworkRx + metrics.NetworkTx
+		samples[instance.ID] = sample{network: network, time: now}
+
+		// First sighting: start the clock, nothing to bill yet
+		last, ok := previous[instance.ID]
+		if !ok {
+			continue
+		}
+		hours := now.Sub(last.time).Hours()
+		if hours > 2*meterInterval.Hours() {
+			hours = meterInterval.Hours() // LocalCloud was not running in between
+		}
+		delta := network
+		if network >= last.network {
+			delta = network - last.network
+		}
+
+		// The spec carries what the instance has now, after any resize
+		cpus, memoryMB := spec.CPUs, spec.MemoryMB
+		if cpus == 0 {
+			cpus = compute.DefaultQuotaCPUs
+		}
+		if memoryMB == 0 {
+			memoryMB = compute.DefaultQuotaMemoryMB
+		}
+		charges := Charges{
+			InstanceHours: hours,
+			VCPUHours:     hours * cpus,
+			GBHours:       hours * float64(memoryMB) / 1024,
+			NetworkGB:     float64(delta) / (1 << 30),
+		}
+		charges.Cost = charges.InstanceHours*prices.InstanceHour + charges.VCPUHours*prices.VCPUHour +
+			charges.GBHours*prices.GBHour + charges.NetworkGB*prices.NetworkGB
+
+		project := spec.Project
+		if project == "" {
+			project = compute.DefaultProject
+		}
+		usage = append(usage, instanceUsage{project: project, resource: instance.Name, tags: spec.Labels, charges: charges})
+	}
+
+	// Volume sizes, billed per project
+	if projects, err := s.manager.ProjectUsage(true); err == nil && !lastStorage.IsZero() {
+		hours := now.Sub(lastStorage).Hours()
+		if hours > 2*meterInterval.Hours() {
+			hours = meterInterval.Hours()
+		}
+		for project, u := range projects {
+			if u.StorageBytes == 0 {
+				continue
+			}
+			charges := Charges{StorageGBHours: hours * float64(u.StorageBytes) / (1 << 30)}
+			charges.Cost = charges.StorageGBHours * prices.StorageGBMonth / hoursPerMonth
+			usage = append(usage, instanceUsage{project: project, resource: StorageResource, charges: charges})
+		}
+	} else if err != nil {
+		log.Printf("billing: failed to meter storage: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.samples = samples
+	s.storage = now
+	day := now.Format(dayFormat)
+	for _, u := range usage {
+		key := itemKey(day, u.project, u.resource)
+		item := s.items[key]
+		if item == nil {
+			item = &LineItem{Day: day, Project: u.project, Resource: u.resource}
+			s.items[key] = item
+		}
+		item.Tags = u.tags
+		item.Charges.add(u.charges)
+	}
+
+	// Drop old usage
+	cutoff := now.AddDate(0, 0, -retentionDays).Format(dayFormat)
+	for key, item := range s.items {
+		if item.Day < cutoff {
+			delete(s.items, key)
+		}
+	}
+
+	s.checkBudgetsLocked(now)
+	if err := s.saveLocked(); err != nil {
+		log.Printf("billing: %v", err)
+	}
+}
+
+func (s *Service) saveLocked() error {
+	items := make([]*LineItem, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return itemKey(items[i].Day, items[i].Project, items[i].Resource) < itemKey(items[j].Day, items[j].Project, items[j].Resource)
+	})
+	if err := store.Save(filepath.Join(s.dir, "usage.json"), items); err != nil {
+		return fmt.Errorf("failed to save usage: %w", err)
+	}
+	if err := store.Save(filepath.Join(s.dir, "alerts.json"), s.alerts); err != nil {
+		return fmt.Errorf("failed to save alerts: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) Prices() PriceSheet {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prices
+}
+
+// Replace the price sheet; charges already accrued keep their old prices
+func (s *Service) SetPrices(prices PriceSheet) error {
+	if prices.InstanceHour < 0 || prices.VCPUHour < 0 || prices.GBHour < 0 ||
+		prices.StorageGBMonth < 0 || prices.NetworkGB < 0 {
+		return fmt.Errorf("%w: prices must not be negative", ErrInvalidInput)
+	}
+	if prices.Currency == "" {
+		prices.Currency = DefaultPrices.Currency
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prices = prices
+	return store.Save(filepath.Join(s.dir, "prices.json"), s.prices)
+}
+
+// Ways to group a report
+const (
+	ByProject  = "project"
+	ByTag      = "tag"
+	ByResource = "resource"
+	ByDay      = "day"
+)
+
+type ReportInput struct {
+	By     string `json:"by"`
+	TagKey string `json:"tag_key,omitempty"` // with by=tag, group by this key's value
+	From   string `json:"from,omitempty"`    // YYYY-MM-DD, first of this month if empty
+	To     string `json:"to,omitempty"`      // YYYY-MM-DD inclusive, today if empty
+}
+
+type ReportGroup struct {
+	Key string `json:"key"`
+	Charges
+}
+
+type Report struct {
+	From     string        `json:"from"`
+	To       string        `json:"to"`
+	By       string        `json:"by"`
+	Currency string        `json:"currency"`
+	Total    Charges       `json:"total"`
+	Groups   []ReportGroup `json:"groups"`
+}
+
+// Charges between two days grouped by project, tag, resource or day. Grouped
+// by tag without a key, an item counts once for each of its tags, so groups
+// can add up to more than the total.
+func (s *Service) Report(in ReportInput) (*Report, error) {
+	now := time.Now()
+	if in.By == "" {
+		in.By = ByProject
+	}
+	if in.From == "" {
+		in.From = now.Format("2006-01") + "-01"
+	}
+	if in.To == "" {
+		in.To = now.Format(dayFormat)
+	}
+	if in.By != ByProject && in.By != ByTag && in.By != ByResource && in.By != ByDay {
+		return nil, fmt.Errorf("%w: by must be project, tag, resource or day", ErrInvalidInput)
+	}
+	for _, day := range []string{in.From, in.To} {
+		if _, err := time.Parse(dayFormat, day); err != nil {
+			return nil, fmt.Errorf("%w: dates must be YYYY-MM-DD: %s", ErrInvalidInput, day)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &Report{From: in.From, To: in.To, By: in.By, Currency: s.prices.Currency}
+	groups := make(map[string]*ReportGroup)
+	addTo := func(key string, charges Charges) {
+		if groups[key] == nil {
+			groups[key] = &ReportGroup{Key: key}
+		}
+		groups[key].add(charges)
+	}
+
+	for _, item := range s.items {
+		if item.Day < in.From || item.Day > in.To {
+			continue
+		}
+		report.Total.add(item.Charges)
+
+		switch in.By {
+		case ByProject:
+			addTo(item.Project, item.Charges)
+		case ByResource:
+			addTo(item.Project+"/"+item.Resource, item.Charges)
+		case ByDay:
+			addTo(item.Day, item.Charges)
+		case ByTag:
+			if in.TagKey != "" {
+				value, ok := item.Tags[in.TagKey]
+				if !ok {
+					value = "(untagged)"
+				}
+				addTo(value, item.Charges)
+				continue
+			}
+			if len(item.Tags) == 0 {
+				addTo("(untagged)", item.Charges)
+			}
+			for key, value := range item.Tags {
+				addTo(key+"="+value, item.Charges)
+			}
+		}
+	}
+
+	report.Groups = make([]ReportGroup, 0, len(groups))
+	for _, group := range groups {
+		report.Groups = append(report.Groups, *group)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		if in.By == ByDay {
+			return report.Groups[i].Key < report.Groups[j].Key
+		}
+		if report.Groups[i].Cost != report.Groups[j].Cost {
+			return report.Groups[i].Cost > report.Groups[j].Cost
+		}
+		return report.Groups[i].Key < report.Groups[j].Key
+	})
+	return report, nil
+}
+
+// Month-to-date spend in a budget's scope
+func (s *Service) spentLocked(b *Budget, month string) float64 {
+	tagKey, tagValue, _ := strings.Cut(b.Tag, "=")
+	spent := 0.0
+	for _, item := range s.items {
+		if !strings.HasPrefix(item.Day, month) {
+			continue
+		}
+		if b.Project != "" && item.Project != b.Project {
+			continue
+		}
+		if b.Tag != "" && item.Tags[tagKey] != tagValue {
+			continue
+		}
+		spent += item.Cost
+	}
+	return spent
+}
+
+// Record an alert for each threshold crossed for the first time this month
+func (s *Service) checkBudgetsLocked(now time.Time) {
+	month := now.Format("2006-01")
+	for _, b := range s.budgets {
+		spent := s.spentLocked(b, month)
+		for _, threshold := range b.Thresholds {
+			if spent < b.Amount*float64(threshold)/100 || s.alertedLocked(b.Name, month, threshold) {
+				continue
+			}
+			alert := Alert{Budget: b.Name, Month: month, Threshold: threshold, Spent: spent, Amount: b.Amount, Time: now}
+			s.alerts = append(s.alerts, alert)
+			log.Printf("billing: budget %s is at %.0f%% (%.2f of %.2f %s)", b.Name, 100*spent/b.Amount, spent, b.Amount, s.prices.Currency)
+		}
+	}
+
+	// Keep alerts for a year
+	cutoff := now.AddDate(-1, 0, 0)
+	kept := s.alerts[:0]
+	for _, alert := range s.alerts {
+		if alert.Time.After(cutoff) {
+			kept = append(kept, alert)
+		}
+	}
+	s.alerts = kept
+}
+
+func (s *Service) alertedLocked(budget, month string, threshold int) bool {
+	for _, alert := range s.alerts {
+		if alert.Budget == budget && alert.Month == month && alert.Threshold == threshold {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Service) CreateBudget(b Budget) (*Budget, error) {
+	if !validName.MatchString(b.Name) {
+		return nil, fmt.Errorf("%w: name must be 1-63 lowercase letters, digits or -", ErrInvalidInput)
+	}
+	if b.Amount <= 0 {
+		return nil, fmt.Errorf("%w: amount must be positive", ErrInvalidInput)
+	}
+	if b.Tag != "" && !strings.Contains(b.Tag, "=") {
+		return nil, fmt.Errorf("%w: tag must be key=value", ErrInvalidInput)
+	}
+	if len(b.Thresholds) == 0 {
+		b.Thresholds = []int{50, 80, 100}
+	}
+	for _, threshold := range b.Thresholds {
+		if threshold <= 0 {
+			return nil, fmt.Errorf("%w: thresholds must be positive percentages", ErrInvalidInput)
+		}
+	}
+	sort.Ints(b.Thresholds)
+	b.Created = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.budgets[b.Name]; ok {
+		return nil, ErrBudgetExists
+	}
+	s.budgets[b.Name] = &b
+	if err := store.Save(filepath.Join(s.dir, "budgets.json"), s.budgets); err != nil {
+		delete(s.budgets, b.Name)
+		return nil, fmt.Errorf("failed to save budget: %w", err)
+	}
+	return &b, nil
+}
+
+// Budgets with this month's spend, by name
+func (s *Service) ListBudgets() []BudgetStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	month := now.Format("2006-01")
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	monthEnd := monthStart.AddDate(0, 1, 0)
+	elapsed := now.Sub(monthStart).Hours()
+
+	list := make([]BudgetStatus, 0, len(s.budgets))
+	for _, b := range s.budgets {
+		status := BudgetStatus{Budget: *b, Spent: s.spentLocked(b, month)}
+		status.Percent = 100 * status.Spent / b.Amount
+		if elapsed > 0 {
+			status.Forecast = status.Spent * monthEnd.Sub(monthStart).Hours() / elapsed
+		}
+		list = append(list, status)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func (s *Service) DeleteBudget(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.budgets[name]; !ok {
+		return ErrBudgetNotFound
+	}
+	delete(s.budgets, name)
+	return store.Save(filepath.Join(s.dir, "budgets.json"), s.budgets)
+}
+
+// Alerts, newest first
+func (s *Service) Alerts() []Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	alerts := make([]Alert, len(s.alerts))
+	for i, alert := range s.alerts {
+		alerts[len(alerts)-1-i] = alert
+	}
+	return alerts
+}
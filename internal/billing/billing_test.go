@@ -0,0 +1,247 @@
+package billing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Network bytes the fake stats report, raised by tests between meter runs
+type fakeNetwork struct {
+	mu    sync.Mutex
+	bytes uint64
+}
+
+func (f *fakeNetwork) set(bytes uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.bytes = bytes
+}
+
+func (f *fakeNetwork) stats(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(types.StatsJSON{Networks: map[string]types.NetworkStats{
+		"eth0": {RxBytes: f.bytes},
+	}})
+}
+
+func newTestService(t *testing.T) (*Service, *dockertest.Server, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	docker.Handle("GET /volumes", volume.ListResponse{})
+	docker.Handle("GET /system/df", types.DiskUsage{})
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s, err := NewService(manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, docker, dir
+}
+
+func launch(t *testing.T, docker *dockertest.Server, name string, spec compute.CreateSpec) string {
+	t.Helper()
+	raw, _ := json.Marshal(spec)
+	resources := container.Resources{NanoCPUs: int64(spec.CPUs * 1e9), Memory: int64(spec.MemoryMB) << 20}
+	return docker.AddContainer(dockertest.Container{Name: name, Image: "nginx", HostConfig: &container.HostConfig{Resources: resources}, Labels: map[string]string{
+		"localcloud.managed": "true",
+		"localcloud.spec":    string(raw),
+	}})
+}
+
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestMeter(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	network := &fakeNetwork{}
+	id := launch(t, docker, "web", compute.CreateSpec{Project: "team", CPUs: 2, MemoryMB: 2048, Labels: map[string]string{"env": "prod"}})
+	docker.Handle("GET /containers/"+id+"/stats", http.HandlerFunc(network.stats))
+	launch(t, docker, "idle", compute.CreateSpec{})
+	docker.SetState("idle", "exited")
+	docker.Handle("GET /volumes", volume.ListResponse{Volumes: []*volume.Volume{
+		{Name: "data", Labels: map[string]string{"localcloud.project": "team"}},
+	}})
+	docker.Handle("GET /system/df", types.DiskUsage{Volumes: []*volume.Volume{
+		{Name: "data", UsageData: &volume.UsageData{Size: 1 << 30}},
+	}})
+
+	// the first run only starts the clock
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	s.meter(start)
+	if len(s.items) != 0 {
+		t.Fatalf("billed on first sighting: %+v", s.items)
+	}
+
+	network.set(1 << 29)
+	s.meter(start.Add(time.Minute))
+	web := s.items[itemKey("2024-03-01", "team", "web")]
+	if web == nil {
+		t.Fatalf("items = %+v", s.items)
+	}
+	hours := 1.0 / 60
+	if !near(web.InstanceHours, hours) || !near(web.VCPUHours, 2*hours) || !near(web.GBHours, 2*hours) || !near(web.NetworkGB, 0.5) {
+		t.Errorf("web charges = %+v", web.Charges)
+	}
+	want := hours*DefaultPrices.InstanceHour + 2*hours*DefaultPrices.VCPUHour + 2*hours*DefaultPrices.GBHour + 0.5*DefaultPrices.NetworkGB
+	if !near(web.Cost, want) || web.Tags["env"] != "prod" {
+		t.Errorf("web cost = %v, want %v, tags %v", web.Cost, want, web.Tags)
+	}
+	storage := s.items[itemKey("2024-03-01", "team", StorageResource)]
+	if storage == nil || !near(storage.StorageGBHours, hours) || !near(storage.Cost, hours*DefaultPrices.StorageGBMonth/hoursPerMonth) {
+		t.Errorf("storage = %+v", storage)
+	}
+	if len(s.items) != 2 {
+		t.Errorf("stopped instance billed: %+v", s.items)
+	}
+
+	// a long gap means LocalCloud was down, which is not billed
+	s.meter(start.Add(5 * time.Hour))
+	if web := s.items[itemKey("2024-03-01", "team", "web")]; !near(web.InstanceHours, 2*hours) {
+		t.Errorf("after a gap: %v instance hours", web.InstanceHours)
+	}
+}
+
+func TestUsageSurvivesRestart(t *testing.T) {
+	s, _, dir := newTestService(t)
+	s.items[itemKey("2024-03-01", "team", "web")] = &LineItem{Day: "2024-03-01", Project: "team", Resource: "web", Charges: Charges{Cost: 1.5}}
+	s.saveLocked()
+	if err := s.SetPrices(PriceSheet{VCPUHour: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewService(s.manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := reopened.items[itemKey("2024-03-01", "team", "web")]; item == nil || item.Cost != 1.5 {
+		t.Errorf("items = %+v", reopened.items)
+	}
+	if prices := reopened.Prices(); prices.VCPUHour != 1 || prices.Currency != "USD" {
+		t.Errorf("prices = %+v", prices)
+	}
+	if err := s.SetPrices(PriceSheet{GBHour: -1}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("negative price: %v", err)
+	}
+}
+
+func TestReport(t *testing.T) {
+	s, _, _ := newTestService(t)
+	for _, item := range []*LineItem{
+		{Day: "2024-03-01", Project: "team", Resource: "web", Tags: map[string]string{"env": "prod"}, Charges: Charges{Cost: 3}},
+		{Day: "2024-03-02", Project: "team", Resource: "web", Tags: map[string]string{"env": "prod", "tier": "front"}, Charges: Charges{Cost: 2}},
+		{Day: "2024-03-02", Project: "ops", Resource: "db", Charges: Charges{Cost: 4}},
+		{Day: "2024-04-01", Project: "ops", Resource: "db", Charges: Charges{Cost: 100}},
+	} {
+		s.items[itemKey(item.Day, item.Project, item.Resource)] = item
+	}
+
+	groups := func(in ReportInput) string {
+		t.Helper()
+		in.From, in.To = "2024-03-01", "2024-03-31"
+		report, err := s.Report(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Total.Cost != 9 {
+			t.Errorf("total = %v", report.Total.Cost)
+		}
+		var out []string
+		for _, g := range report.Groups {
+			out = append(out, fmt.Sprintf("%s=%g", g.Key, g.Cost))
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		in   ReportInput
+		want string
+	}{
+		{ReportInput{}, "team=5,ops=4"},
+		{ReportInput{By: ByResource}, "team/web=5,ops/db=4"},
+		{ReportInput{By: ByDay}, "2024-03-01=3,2024-03-02=6"},
+		{ReportInput{By: ByTag}, "env=prod=5,(untagged)=4,tier=front=2"},
+		{ReportInput{By: ByTag, TagKey: "env"}, "prod=5,(untagged)=4"},
+	}
+	for _, tt := range tests {
+		if got := groups(tt.in); got != tt.want {
+			t.Errorf("by %q %q: %s, want %s", tt.in.By, tt.in.TagKey, got, tt.want)
+		}
+	}
+
+	if _, err := s.Report(ReportInput{By: "color"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("unknown grouping: %v", err)
+	}
+	if _, err := s.Report(ReportInput{From: "March"}); !errors.Is(err, ErrInvalidInput) {
+		t.Errorf("bad date: %v", err)
+	}
+}
+
+func TestBudgets(t *testing.T) {
+	s, _, _ := newTestService(t)
+	tests := []Budget{
+		{Name: "Bad Name", Amount: 10},
+		{Name: "zero", Amount: 0},
+		{Name: "tag", Amount: 10, Tag: "env"},
+		{Name: "thresholds", Amount: 10, Thresholds: []int{0}},
+	}
+	for _, b := range tests {
+		if _, err := s.CreateBudget(b); !errors.Is(err, ErrInvalidInput) {
+			t.Errorf("CreateBudget(%+v) = %v", b, err)
+		}
+	}
+
+	b, err := s.CreateBudget(Budget{Name: "prod", Amount: 10, Tag: "env=prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Thresholds) != 3 {
+		t.Errorf("default thresholds = %v", b.Thresholds)
+	}
+	if _, err := s.CreateBudget(Budget{Name: "prod", Amount: 5}); !errors.Is(err, ErrBudgetExists) {
+		t.Errorf("creating twice: %v", err)
+	}
+
+	now := time.Now()
+	day := now.Format(dayFormat)
+	s.items[itemKey(day, "team", "web")] = &LineItem{Day: day, Project: "team", Resource: "web", Tags: map[string]string{"env": "prod"}, Charges: Charges{Cost: 8.5}}
+	s.items[itemKey(day, "team", "dev")] = &LineItem{Day: day, Project: "team", Resource: "dev", Tags: map[string]string{"env": "dev"}, Charges: Charges{Cost: 50}}
+
+	// each crossed threshold alerts once a month
+	s.checkBudgetsLocked(now)
+	s.checkBudgetsLocked(now)
+	alerts := s.Alerts()
+	if len(alerts) != 2 || alerts[0].Threshold != 80 || alerts[1].Threshold != 50 || alerts[0].Spent != 8.5 {
+		t.Errorf("alerts = %+v", alerts)
+	}
+
+	list := s.ListBudgets()
+	if len(list) != 1 || list[0].Spent != 8.5 || list[0].Percent != 85 || list[0].Forecast < 8.5 {
+		t.Errorf("budgets = %+v", list)
+	}
+	if err := s.DeleteBudget("prod"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DeleteBudget("prod"); !errors.Is(err, ErrBudgetNotFound) {
+		t.Errorf("deleting twice: %v", err)
+	}
+}
@@ -0,0 +1,642 @@
+// Environment bundles: instance specs, networks, volume contents and images
+// in one archive that can be recreated on another machine
+package bundles
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"localcloud/internal/compute"
+)
+
+const (
+	bundleVersion = 1
+	manifestName  = "manifest.json"
+	imagesName    = "images.tar"
+	volumesDir    = "volumes/"
+	mountPath     = "/volume"
+)
+
+// What to do when a name in the bundle is already taken
+const (
+	ConflictSkip    = "skip"
+	ConflictRename  = "rename"
+	ConflictReplace = "replace"
+)
+
+var (
+	ErrInvalidBundle    = errors.New("invalid bundle")
+	ErrInstanceNotFound = errors.New("instance not found or not exportable")
+)
+
+type Options struct {
+	Instances      []string `json:"instances"` // names, every standalone instance if empty
+	IncludeVolumes bool     `json:"include_volumes"`
+	IncludeImages  bool     `json:"include_images"`
+}
+
+type Manifest struct {
+	Version   int               `json:"version"`
+	Created   time.Time         `json:"created"`
+	Source    string            `json:"source"` // host the bundle was exported on
+	Instances []InstanceEntry   `json:"instances"`
+	Networks  []compute.Network `json:"networks"`
+	Volumes   []VolumeEntry     `json:"volumes"`
+	Images    []string          `json:"images"`
+	HasImages bool              `json:"has_images"`
+}
+
+type InstanceEntry struct {
+	Name     string             `json:"name"`
+	Spec     compute.CreateSpec `json:"spec"`
+	Networks []string           `json:"networks,omitempty"`
+}
+
+type VolumeEntry struct {
+	Name   string `json:"name"`
+	File   string `json:"file,omitempty"` // empty if contents were not exported
+	Size   int64  `json:"size,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+type ImportOptions struct {
+	DryRun     bool   `json:"dry_run"`
+	OnConflict string `json:"on_conflict"` // skip, rename or replace
+}
+
+// One step of an import
+type Action struct {
+	Kind   string `json:"kind"` // instance, volume, network or images
+	Name   string `json:"name"`
+	Target string `json:"target,omitempty"` // name used on this machine
+	Action string `json:"action"`           // create, skip, rename, replace, reuse or load
+	Note   string `json:"note,omitempty"`
+}
+
+type Report struct {
+	DryRun  bool      `json:"dry_run"`
+	Source  string    `json:"source"`
+	Created time.Time `json:"created"`
+	Actions []Action  `json:"actions"`
+	Errors  []string  `json:"errors"`
+}
+
+type Service struct {
+	manager *compute.Manager
+}
+
+func NewService(manager *compute.Manager) *Service {
+	return &Service{manager: manager}
+}
+
+// An exported environment spooled to disk, ready to stream
+type Bundle struct {
+	Manifest Manifest
+	dir      string
+}
+
+// Collect what opts selects. Volume contents and images are written to a
+// temporary directory so the archive can be streamed without failing halfway.
+func (s *Service) Export(opts Options) (*Bundle, error) {
+	hostname, _ := os.Hostname()
+	manifest := Manifest{
+		Version:   bundleVersion,
+		Created:   time.Now(),
+		Source:    hostname,
+		Instances: []InstanceEntry{},
+		Networks:  []compute.Network{},
+		Volumes:   []VolumeEntry{},
+		Images:    []string{},
+	}
+
+	wanted := make(map[string]bool)
+	for _, name := range opts.Instances {
+		wanted[name] = true
+	}
+
+	networks := make(map[string]compute.Network)
+	volumes := make(map[string]bool)
+	images := make(map[string]bool)
+	for _, instance := range s.manager.List() {
+		if len(wanted) > 0 && !wanted[instance.Name] {
+			continue
+		}
+		spec, err := s.manager.Spec(instance.ID)
+		if err != nil || ownedByService(spec) {
+			continue
+		}
+		delete(wanted, instance.Name)
+
+		entry := InstanceEntry{Name: instance.Name, Spec: *spec}
+		attached, err := s.manager.Networks(instance.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, n := range attached {
+			networks[n.Name] = n
+			entry.Networks = append(entry.Networks, n.Name)
+		}
+		for _, v := range spec.Volumes {
+			if name, ok := namedVolume(v); ok {
+				volumes[name] = true
+			}
+		}
+		images[spec.Image] = true
+		manifest.Instances = append(manifest.Instances, entry)
+	}
+	for name := range wanted {
+		return nil, fmt.Errorf("%w: %s", ErrInstanceNotFound, name)
+	}
+
+	for _, n := range networks {
+		manifest.Networks = append(manifest.Networks, n)
+	}
+	for name := range volumes {
+		manifest.Volumes = append(manifest.Volumes, VolumeEntry{Name: name})
+	}
+	for image := range images {
+		manifest.Images = append(manifest.Images, image)
+	}
+	sort.Slice(manifest.Instances, func(i, j int) bool { return manifest.Instances[i].Name < manifest.Instances[j].Name })
+	sort.Slice(manifest.Networks, func(i, j int) bool { return manifest.Networks[i].Name < manifest.Networks[j].Name })
+	sort.Slice(manifest.Volumes, func(i, j int) bool { return manifest.Volumes[i].Name < manifest.Volumes[j].Name })
+	sort.Strings(manifest.Images)
+
+	dir, err := os.MkdirTemp("", "localcloud-bundle-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	bundle := &Bundle{Manifest: manifest, dir: dir}
+
+	if opts.IncludeVolumes {
+		for i := range bundle.Manifest.Volumes {
+			if err := s.spoolVolume(dir, &bundle.Manifest.Volumes[i]); err != nil {
+				bundle.Close()
+				return nil, err
+			}
+		}
+	}
+	if opts.IncludeImages && len(manifest.Images) > 0 {
+		if err := s.spoolImages(dir, manifest.Images); err != nil {
+			bundle.Close()
+			return nil, err
+		}
+		bundle.Manifest.HasImages = true
+	}
+	return bundle, nil
+}
+
+func (s *Service) spoolVolume(dir string, v *VolumeEntry) error {
+	v.File = volumesDir + v.Name + ".tar"
+	path := filepath.Join(dir, filepath.FromSlash(v.File))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	cmd := []string{"tar", "-C", mountPath, "-cf", "-", "."}
+	stderr, code, err := s.manager.RunWithVolume(context.Background(), v.Name, mountPath, true, cmd, nil, io.MultiWriter(file, hash))
+	if err == nil && code != 0 {
+		err = fmt.Errorf("tar exited with code %d: %s", code, strings.TrimSpace(stderr))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to export volume %s: %w", v.Name, err)
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to export volume %s: %w", v.Name, err)
+	}
+	v.Size = stat.Size()
+	v.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return nil
+}
+
+func (s *Service) spoolImages(dir string, images []string) error {
+	file, err := os.Create(filepath.Join(dir, imagesName))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	defer file.Close()
+	return s.manager.SaveImages(images, file)
+}
+
+// Write the bundle as a gzipped tar with the manifest first
+func (b *Bundle) Stream(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	manifest, err := json.MarshalIndent(b.Manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	header := &tar.Header{Name: manifestName, Mode: 0o644, Size: int64(len(manifest)), ModTime: b.Manifest.Created}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := tw.Write(manifest); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+
+	var files []string
+	for _, v := range b.Manifest.Volumes {
+		if v.File != "" {
+			files = append(files, v.File)
+		}
+	}
+	if b.Manifest.HasImages {
+		files = append(files, imagesName)
+	}
+	for _, name := range files {
+		if err := b.addFile(tw, name); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return gz.Close()
+}
+
+func (b *Bundle) addFile(tw *tar.Writer, name string) error {
+	file, err := os.Open(filepath.Join(b.dir, filepath.FromSlash(name)))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	header := &tar.Header{Name: name, Mode: 0o644, Size: stat.Size(), ModTime: b.Manifest.Created}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	if _, err := io.Copy(tw, file); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// Remove the spooled files
+func (b *Bundle) Close() error {
+	return os.RemoveAll(b.dir)
+}
+
+// Recreate a bundle's environment. Volume contents and images are applied
+// as they are read from r; networks and instances are created afterwards.
+func (s *Service) Import(r io.Reader, opts ImportOptions) (*Report, error) {
+	switch opts.OnConflict {
+	case "":
+		opts.OnConflict = ConflictSkip
+	case ConflictSkip, ConflictRename, ConflictReplace:
+	default:
+		return nil, fmt.Errorf("on_conflict must be %s, %s or %s", ConflictSkip, ConflictRename, ConflictReplace)
+	}
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a gzip archive", ErrInvalidBundle)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != manifestName {
+		return nil, fmt.Errorf("%w: %s must come first", ErrInvalidBundle, manifestName)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode manifest: %v", ErrInvalidBundle, err)
+	}
+	if manifest.Version != bundleVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, manifest.Version)
+	}
+
+	p, err := s.plan(&manifest, opts.OnConflict)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{
+		DryRun:  opts.DryRun,
+		Source:  manifest.Source,
+		Created: manifest.Created,
+		Actions: p.actions,
+		Errors:  []string{},
+	}
+	if opts.DryRun {
+		return report, nil
+	}
+
+	volumeFiles := make(map[string]VolumeEntry)
+	for _, v := range manifest.Volumes {
+		if v.File != "" {
+			volumeFiles[v.File] = v
+		}
+	}
+	volumeProjects := projectsOfVolumes(&manifest)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, fmt.Errorf("%w: %v", ErrInvalidBundle, err)
+		}
+
+		switch {
+		case header.Name == imagesName:
+			if err := s.manager.LoadImages(tr); err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+		case strings.HasPrefix(header.Name, volumesDir):
+			v, ok := volumeFiles[header.Name]
+			target := p.volumes[v.Name]
+			if !ok || target == "" || !p.restore[v.Name] {
+				continue
+			}
+			if err := s.restoreVolume(target, volumeProjects[v.Name], tr, v.SHA256); err != nil {
+				report.Errors = append(report.Errors, err.Error())
+			}
+		}
+	}
+
+	for _, n := range manifest.Networks {
+		if p.reuse[n.Name] {
+			continue
+		}
+		if err := s.manager.CreateNetwork(n); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+
+	for _, entry := range manifest.Instances {
+		target := p.instances[entry.Name]
+		if target == "" {
+			continue
+		}
+		spec := entry.Spec
+		spec.Name = target
+		spec.Volumes = make([]string, len(entry.Spec.Volumes))
+		for i, v := range entry.Spec.Volumes {
+			spec.Volumes[i] = v
+			if name, ok := namedVolume(v); ok && p.volumes[name] != "" {
+				spec.Volumes[i] = p.volumes[name] + strings.TrimPrefix(v, name)
+			}
+		}
+
+		var instance *compute.Instance
+		var err error
+		if p.replace[entry.Name] {
+			// the existing instance stays if its replacement can't be created
+			instance, err = s.manager.Replace(entry.Name, spec)
+		} else {
+			instance, err = s.manager.Create(spec)
+		}
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("instance %s: %v", entry.Name, err))
+			continue
+		}
+		for _, network := range entry.Networks {
+			if err := s.manager.ConnectNetwork(instance.ID, network); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("instance %s: %v", entry.Name, err))
+			}
+		}
+	}
+	return report, nil
+}
+
+// Empty a volume, creating it if needed, and unpack a tar into it,
+// checking the stream against the exported checksum
+func (s *Service) restoreVolume(name, project string, r io.Reader, sum string) error {
+	exists, err := s.manager.VolumeExists(name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := s.manager.CreateVolume(name, project); err != nil {
+			return err
+		}
+	}
+
+	// Feed tar through a pipe so r is not read after the copy is abandoned
+	hash := sha256.New()
+	pr, pw := io.Pipe()
+	copied := make(chan struct{})
+	go func() {
+		_, err := io.Copy(pw, io.TeeReader(r, hash))
+		pw.CloseWithError(err)
+		close(copied)
+	}()
+
+	script := "find " + mountPath + " -mindepth 1 -delete && tar -C " + mountPath + " -xf -"
+	stderr, code, err := s.manager.RunWithVolume(context.Background(), name, mountPath, false, []string{"sh", "-c", script}, pr, nil)
+	pr.Close()
+	<-copied
+	if err == nil && code != 0 {
+		err = fmt.Errorf("tar exited with code %d: %s", code, strings.TrimSpace(stderr))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to import volume %s: %w", name, err)
+	}
+
+	// tar may stop before the padding at the end of the archive
+	if _, err := io.Copy(hash, r); err != nil {
+		return fmt.Errorf("failed to import volume %s: %w", name, err)
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); got != sum {
+		return fmt.Errorf("volume %s: checksum mismatch, the bundle may be corrupt", name)
+	}
+	return nil
+}
+
+// Names on this machine for everything in a bundle
+type plan struct {
+	actions   []Action
+	instances map[string]string // bundle name to target, empty to skip
+	volumes   map[string]string
+	restore   map[string]bool // volumes whose contents are written
+	replace   map[string]bool // instances deleted before being recreated
+	reuse     map[string]bool // networks that already exist
+}
+
+func (s *Service) plan(manifest *Manifest, onConflict string) (*plan, error) {
+	p := &plan{
+		actions:   []Action{},
+		instances: make(map[string]string),
+		volumes:   make(map[string]string),
+		restore:   make(map[string]bool),
+		replace:   make(map[string]bool),
+		reuse:     make(map[string]bool),
+	}
+
+	for _, n := range manifest.Networks {
+		exists, err := s.manager.NetworkExists(n.Name)
+		if err != nil {
+			return nil, err
+		}
+		action := Action{Kind: "network", Name: n.Name, Target: n.Name, Action: "create"}
+		if exists {
+			action.Action = "reuse"
+			action.Note = "network already exists"
+			p.reuse[n.Name] = true
+		}
+		p.actions = append(p.actions, action)
+	}
+
+	for _, v := range manifest.Volumes {
+		exists, err := s.manager.VolumeExists(v.Name)
+		if err != nil {
+			return nil, err
+		}
+		action := Action{Kind: "volume", Name: v.Name, Target: v.Name, Action: "create"}
+		switch {
+		case !exists:
+		case onConflict == ConflictRename:
+			action.Action = "rename"
+			action.Target, err = s.freeName(v.Name, s.manager.VolumeExists)
+			if err != nil {
+				return nil, err
+			}
+		case onConflict == ConflictReplace:
+			action.Action = "replace"
+		default:
+			action.Action = "skip"
+			action.Note = "volume already exists and is used as is"
+		}
+		p.volumes[v.Name] = action.Target
+		if v.File == "" {
+			if action.Action != "skip" {
+				action.Note = "contents not in bundle, an empty volume will be created"
+			}
+		} else if action.Action != "skip" {
+			p.restore[v.Name] = true
+		}
+		p.actions = append(p.actions, action)
+	}
+
+	if manifest.HasImages {
+		p.actions = append(p.actions, Action{Kind: "images", Name: strings.Join(manifest.Images, ", "), Action: "load"})
+	}
+
+	instanceExists := func(name string) (bool, error) {
+		_, err := s.manager.Get(name)
+		return err == nil, nil
+	}
+	for _, entry := range manifest.Instances {
+		exists, _ := instanceExists(entry.Name)
+		action := Action{Kind: "instance", Name: entry.Name, Target: entry.Name, Action: "create"}
+		switch {
+		case !exists:
+		case onConflict == ConflictRename:
+			action.Action = "rename"
+			action.Target, _ = s.freeName(entry.Name, instanceExists)
+		case onConflict == ConflictReplace:
+			action.Action = "replace"
+			p.replace[entry.Name] = true
+		default:
+			action.Action = "skip"
+			action.Note = "an instance with this name already exists"
+			action.Target = ""
+		}
+		p.instances[entry.Name] = action.Target
+		if action.Action != "skip" {
+			action.Note = s.instanceNotes(entry, manifest.HasImages)
+		}
+		p.actions = append(p.actions, action)
+	}
+	return p, nil
+}
+
+// Things an instance needs that a bundle does not carry
+func (s *Service) instanceNotes(entry InstanceEntry, hasImages bool) string {
+	var notes []string
+	if entry.Spec.Snapshot != "" && !hasImages {
+		if _, err := s.manager.GetSnapshot(entry.Spec.Snapshot); err != nil {
+			notes = append(notes, "snapshot "+entry.Spec.Snapshot+" is not on this machine")
+		}
+	}
+	for _, v := range entry.Spec.Volumes {
+		if _, ok := namedVolume(v); !ok {
+			notes = append(notes, "host path "+strings.SplitN(v, ":", 2)[0]+" must exist")
+		}
+	}
+	if len(entry.Spec.Secrets) > 0 {
+		var names []string
+		for _, ref := range entry.Spec.Secrets {
+			names = append(names, ref.Name)
+		}
+		notes = append(notes, "needs secrets "+strings.Join(names, ", "))
+	}
+	if len(entry.Spec.Parameters) > 0 {
+		notes = append(notes, "needs its parameters in the parameter store")
+	}
+	return strings.Join(notes, "; ")
+}
+
+// name-imported, or name-imported-N if that is taken too
+func (s *Service) freeName(name string, exists func(string) (bool, error)) (string, error) {
+	candidate := name + "-imported"
+	for n := 2; ; n++ {
+		taken, err := exists(candidate)
+		if err != nil {
+			return "", err
+		}
+		if !taken {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s-imported-%d", name, n)
+	}
+}
+
+// Instances created by a LocalCloud service are recreated by that service
+func ownedByService(spec *compute.CreateSpec) bool {
+	for key := range spec.Labels {
+		if strings.HasPrefix(key, "localcloud.") {
+			return true
+		}
+	}
+	return false
+}
+
+// Project of the first instance mounting each volume, which its quota
+// usage belongs to
+func projectsOfVolumes(manifest *Manifest) map[string]string {
+	projects := make(map[string]string)
+	for _, entry := range manifest.Instances {
+		for _, v := range entry.Spec.Volumes {
+			if name, ok := namedVolume(v); ok && projects[name] == "" {
+				projects[name] = entry.Spec.Project
+			}
+		}
+	}
+	return projects
+}
+
+// Volume name of a volume:/path mount, false for host paths
+func namedVolume(mount string) (string, bool) {
+	source := strings.SplitN(mount, ":", 2)[0]
+	if source == "" || strings.ContainsAny(source[:1], "/.~") {
+		return "", false
+	}
+	return source, true
+}
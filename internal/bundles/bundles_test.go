@@ -0,0 +1,341 @@
+package bundles
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Fake volumes holding a string each, read and written by helper
+// containers the way tar would
+type fakeVolumes struct {
+	mu       sync.Mutex
+	contents map[string]string
+	labels   map[string]map[string]string // of created volumes
+}
+
+func (f *fakeVolumes) install(docker *dockertest.Server, names ...string) {
+	for _, name := range names {
+		name := name
+		docker.Handle("GET /volumes/"+name, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			f.mu.Lock()
+			_, ok := f.contents[name]
+			f.mu.Unlock()
+			w.Header().Set("Content-Type", "application/json")
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				json.NewEncoder(w).Encode(map[string]string{"message": "no such volume"})
+				return
+			}
+			json.NewEncoder(w).Encode(volume.Volume{Name: name})
+		}))
+	}
+	docker.Handle("POST /volumes/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var options volume.CreateOptions
+		json.NewDecoder(r.Body).Decode(&options)
+		f.set(options.Name, "")
+		f.mu.Lock()
+		f.labels[options.Name] = options.Labels
+		f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(volume.Volume{Name: options.Name})
+	}))
+	docker.Exec(func(c dockertest.Container, cmd, env []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		name, _, _ := strings.Cut(c.HostConfig.Binds[0], ":")
+		if cmd[0] == "tar" {
+			io.WriteString(stdout, f.get(name))
+			return 0
+		}
+		data, _ := io.ReadAll(stdin)
+		f.set(name, string(data))
+		return 0
+	})
+}
+
+func (f *fakeVolumes) get(name string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.contents[name]
+}
+
+func (f *fakeVolumes) set(name, contents string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.contents[name] = contents
+}
+
+func (f *fakeVolumes) remove(name string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.contents, name)
+}
+
+func newTestService(t *testing.T) (*Service, *compute.Manager, *dockertest.Server, *fakeVolumes) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	volumes := &fakeVolumes{contents: map[string]string{"data": "database files"}, labels: make(map[string]map[string]string)}
+	volumes.install(docker, "data", "data-imported")
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewService(manager), manager, docker, volumes
+}
+
+func launch(t *testing.T, m *compute.Manager, spec compute.CreateSpec) {
+	t.Helper()
+	if _, err := m.Create(spec); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func export(t *testing.T, s *Service, opts Options) []byte {
+	t.Helper()
+	bundle, err := s.Export(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundle.Close()
+	var buf bytes.Buffer
+	if err := bundle.Stream(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Names of the instances on the fake daemon
+func instanceNames(docker *dockertest.Server) string {
+	var names []string
+	for _, c := range docker.Containers() {
+		if c.Labels["localcloud.managed"] == "true" {
+			names = append(names, c.Name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+func TestExport(t *testing.T) {
+	s, m, _, _ := newTestService(t)
+	launch(t, m, compute.CreateSpec{Name: "db", Image: "postgres:16", Volumes: []string{"data:/var/lib/postgresql/data", "/etc/ssl:/etc/ssl"}})
+	launch(t, m, compute.CreateSpec{Name: "fn", Image: "localcloud-fn-hello:v1", Labels: map[string]string{"localcloud.function": "hello"}})
+
+	bundle, err := s.Export(Options{IncludeVolumes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bundle.Close()
+	manifest := bundle.Manifest
+	if len(manifest.Instances) != 1 || manifest.Instances[0].Name != "db" {
+		t.Errorf("instances = %+v", manifest.Instances)
+	}
+	if len(manifest.Volumes) != 1 || manifest.Volumes[0].File != "volumes/data.tar" || manifest.Volumes[0].Size != int64(len("database files")) {
+		t.Errorf("volumes = %+v", manifest.Volumes)
+	}
+	if strings.Join(manifest.Images, ",") != "postgres:16" || manifest.HasImages {
+		t.Errorf("images = %v, included %v", manifest.Images, manifest.HasImages)
+	}
+
+	// the manifest leads the archive, followed by the volume
+	gz, err := gzip.NewReader(bytes.NewReader(export(t, s, Options{IncludeVolumes: true})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, header.Name)
+	}
+	if strings.Join(names, ",") != "manifest.json,volumes/data.tar" {
+		t.Errorf("archive entries = %v", names)
+	}
+
+	if _, err := s.Export(Options{Instances: []string{"fn"}}); !errors.Is(err, ErrInstanceNotFound) {
+		t.Errorf("exporting a service instance: %v", err)
+	}
+}
+
+func TestImportRecreatesEnvironment(t *testing.T) {
+	s, m, docker, volumes := newTestService(t)
+	launch(t, m, compute.CreateSpec{Name: "db", Image: "postgres:16", Project: "team", Env: []string{"POSTGRES_DB=app"}, Volumes: []string{"data:/var/lib/postgresql/data"}})
+	data := export(t, s, Options{IncludeVolumes: true})
+
+	if err := m.Delete("db"); err != nil {
+		t.Fatal(err)
+	}
+	volumes.remove("data")
+
+	report, err := s.Import(bytes.NewReader(data), ImportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 0 {
+		t.Fatalf("errors = %v", report.Errors)
+	}
+	if got := volumes.get("data"); got != "database files" {
+		t.Errorf("volume contents = %q", got)
+	}
+	if project := volumes.labels["data"]["localcloud.project"]; project != "team" {
+		t.Errorf("restored volume counts against %q", project)
+	}
+	if names := instanceNames(docker); names != "db" {
+		t.Fatalf("instances = %s", names)
+	}
+	c := docker.Containers()[len(docker.Containers())-1]
+	if strings.Join(c.Config.Env, ",") != "POSTGRES_DB=app" || c.HostConfig.Binds[0] != "data:/var/lib/postgresql/data" {
+		t.Errorf("recreated with env %v, binds %v", c.Config.Env, c.HostConfig.Binds)
+	}
+}
+
+func TestImportConflicts(t *testing.T) {
+	s, m, docker, volumes := newTestService(t)
+	launch(t, m, compute.CreateSpec{Name: "db", Image: "postgres:16", Volumes: []string{"data:/var/lib/postgresql/data"}})
+	data := export(t, s, Options{IncludeVolumes: true})
+	volumes.set("data", "changed since the export")
+
+	actions := func(report *Report) string {
+		var out []string
+		for _, a := range report.Actions {
+			out = append(out, a.Kind+":"+a.Action+":"+a.Target)
+		}
+		return strings.Join(out, ",")
+	}
+
+	tests := []struct {
+		onConflict string
+		want       string
+	}{
+		{"", "volume:skip:data,instance:skip:"},
+		{ConflictRename, "volume:rename:data-imported,instance:rename:db-imported"},
+		{ConflictReplace, "volume:replace:data,instance:replace:db"},
+	}
+	for _, tt := range tests {
+		report, err := s.Import(bytes.NewReader(data), ImportOptions{DryRun: true, OnConflict: tt.onConflict})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := actions(report); got != tt.want {
+			t.Errorf("on_conflict %q: %s, want %s", tt.onConflict, got, tt.want)
+		}
+	}
+	if names := instanceNames(docker); names != "db" || volumes.get("data") != "changed since the export" {
+		t.Fatal("a dry run changed something")
+	}
+
+	// renamed copies sit next to the originals
+	report, err := s.Import(bytes.NewReader(data), ImportOptions{OnConflict: ConflictRename})
+	if err != nil || len(report.Errors) != 0 {
+		t.Fatalf("rename: %v, %v", err, report.Errors)
+	}
+	if names := instanceNames(docker); names != "db,db-imported" {
+		t.Errorf("instances = %s", names)
+	}
+	if volumes.get("data-imported") != "database files" || volumes.get("data") != "changed since the export" {
+		t.Error("renamed volume not restored next to the original")
+	}
+	c := docker.Containers()[len(docker.Containers())-1]
+	if c.HostConfig.Binds[0] != "data-imported:/var/lib/postgresql/data" {
+		t.Errorf("renamed instance mounts %v", c.HostConfig.Binds)
+	}
+
+	if _, err := s.Import(bytes.NewReader(data), ImportOptions{OnConflict: "merge"}); err == nil {
+		t.Error("accepted an unknown conflict policy")
+	}
+}
+
+func TestImportReplace(t *testing.T) {
+	s, m, docker, _ := newTestService(t)
+	launch(t, m, compute.CreateSpec{Name: "db", Image: "postgres:16", Env: []string{"POSTGRES_DB=app"}})
+	data := export(t, s, Options{})
+	old := docker.Containers()[0].ID
+
+	report, err := s.Import(bytes.NewReader(data), ImportOptions{OnConflict: ConflictReplace})
+	if err != nil || len(report.Errors) != 0 {
+		t.Fatalf("replace: %v, %v", err, report.Errors)
+	}
+	replaced := docker.Containers()
+	if len(replaced) != 1 || replaced[0].ID == old || replaced[0].Name != "db" || replaced[0].State != "running" {
+		t.Fatalf("after replacing: %+v", replaced)
+	}
+
+	// the existing instance stays when its replacement can't be created
+	docker.Handle("POST /containers/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"message": "no space left on device"})
+	}))
+	report, err = s.Import(bytes.NewReader(data), ImportOptions{OnConflict: ConflictReplace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Errors) != 1 || !strings.Contains(report.Errors[0], "no space left") {
+		t.Errorf("errors = %v", report.Errors)
+	}
+	if c := docker.Containers(); len(c) != 1 || c[0].ID != replaced[0].ID || c[0].Name != "db" || c[0].State != "running" {
+		t.Errorf("after a failed replace: %+v", c)
+	}
+}
+
+func TestImportRejectsInvalidBundles(t *testing.T) {
+	s, _, _, _ := newTestService(t)
+
+	archive := func(name string, manifest Manifest) []byte {
+		var buf bytes.Buffer
+		gz := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gz)
+		data, _ := json.Marshal(manifest)
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data))})
+		tw.Write(data)
+		tw.Close()
+		gz.Close()
+		return buf.Bytes()
+	}
+	tests := map[string][]byte{
+		"not gzip":         []byte("plain text"),
+		"manifest missing": archive("images.tar", Manifest{Version: bundleVersion}),
+		"future version":   archive(manifestName, Manifest{Version: bundleVersion + 1}),
+	}
+	for name, data := range tests {
+		if _, err := s.Import(bytes.NewReader(data), ImportOptions{}); !errors.Is(err, ErrInvalidBundle) {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestNamedVolume(t *testing.T) {
+	tests := []struct {
+		mount, name string
+		ok          bool
+	}{
+		{"data:/var/lib/data", "data", true},
+		{"data:/var/lib/data:ro", "data", true},
+		{"/srv/data:/data", "", false},
+		{"./data:/data", "", false},
+		{"~/data:/data", "", false},
+		{":/data", "", false},
+	}
+	for _, tt := range tests {
+		if name, ok := namedVolume(tt.mount); name != tt.name || ok != tt.ok {
+			t.Errorf("namedVolume(%q) = %q, %v", tt.mount, name, ok)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package compute
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/events"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Container and image actions worth telling anyone about
+var watchedActions = map[events.Type][]string{
+	events.ContainerEventType: {"create", "start", "die", "stop", "restart", "oom", "pause", "unpause", "destroy"},
+	events.ImageEventType:     {"pull", "push", "tag", "untag", "delete", "import", "load"},
+}
+
+// A container or image lifecycle change reported by Docker
+type LifecycleEvent struct {
+	Type     string    `json:"type"`   // container or image
+	Action   string    `json:"action"` // die, start, pull...
+	ID       string    `json:"id"`
+	Name     string    `json:"name"` // container name or image reference
+	Image    string    `json:"image,omitempty"`
+	ExitCode string    `json:"exit_code,omitempty"` // die only
+	Project  string    `json:"project,omitempty"`
+	Managed  bool      `json:"managed"` // created by LocalCloud
+	Time     time.Time `json:"time"`
+}
+
+// Call handle for each lifecycle event until ctx is done, reconnecting if
+// the Docker event stream drops
+func (m *Manager) WatchEvents(ctx context.Context, handle func(LifecycleEvent)) {
+	args := filters.NewArgs()
+	for eventType, actions := range watchedActions {
+		args.Add("type", string(eventType))
+		for _, action := range actions {
+			args.Add("event", action)
+		}
+	}
+
+	for {
+		messages, errs := m.client.Events(ctx, types.EventsOptions{Filters: args})
+	stream:
+		for {
+			select {
+			case msg := <-messages:
+				if msg.Type == events.ContainerEventType && msg.Action == "destroy" {
+					m.forgetResize(msg.Actor.ID)
+				}
+				handle(lifecycleEvent(msg))
+			case err := <-errs:
+				if ctx.Err() == nil {
+					log.Printf("Docker event stream closed: %v", err)
+				}
+				break stream
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func lifecycleEvent(msg events.Message) LifecycleEvent {
+	attributes := msg.Actor.Attributes
+	event := LifecycleEvent{
+		Type:   string(msg.Type),
+		Action: string(msg.Action),
+		ID:     msg.Actor.ID,
+		Name:   attributes["name"],
+		Time:   time.Unix(0, msg.TimeNano),
+	}
+	switch msg.Type {
+	case events.ContainerEventType:
+		event.Image = attributes["image"]
+		event.ExitCode = attributes["exitCode"]
+		event.Project = attributes[labelProject]
+		event.Managed = attributes[labelManaged] == "true"
+	case events.ImageEventType:
+		// Image events carry the reference as the name, or only the ID
+		if event.Name == "" {
+			event.Name = strings.TrimPrefix(msg.Actor.ID, "sha256:")
+		}
+	}
+	return event
+}
@@ -0,0 +1,361 @@
+package compute
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+)
+
+var (
+	ErrPathNotFound     = errors.New("path not found in container")
+	ErrNotADirectory    = errors.New("path is not a directory")
+	ErrIsADirectory     = errors.New("path is a directory")
+	ErrPermissionDenied = errors.New("permission denied")
+)
+
+// File or directory inside a container
+type FileInfo struct {
+	Name       string      `json:"name"`
+	Path       string      `json:"path"`
+	Size       int64       `json:"size"`
+	Mode       os.FileMode `json:"mode"`
+	ModTime    time.Time   `json:"mod_time"`
+	IsDir      bool        `json:"is_dir"`
+	LinkTarget string      `json:"link_target,omitempty"`
+}
+
+func (m *Manager) StatPath(containerID, filePath string) (*FileInfo, error) {
+	stat, err := m.client.ContainerStatPath(context.Background(), containerID, filePath)
+	if client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrPathNotFound, filePath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat path: %w", err)
+	}
+	return fileInfo(filePath, stat), nil
+}
+
+func fileInfo(filePath string, stat types.ContainerPathStat) *FileInfo {
+	return &FileInfo{
+		Name:       stat.Name,
+		Path:       filePath,
+		Size:       stat.Size,
+		Mode:       stat.Mode,
+		ModTime:    stat.Mtime,
+		IsDir:      stat.Mode.IsDir(),
+		LinkTarget: stat.LinkTarget,
+	}
+}
+
+// Copy a tar stream with a single top-level entry (a file, or a directory
+// and its contents) into a container, like `docker cp`: into dstPath if it
+// is an existing directory, otherwise to dstPath itself. Modes in the tar
+// headers are kept.
+func (m *Manager) CopyTo(containerID, dstPath string, content io.Reader) error {
+	ctx := context.Background()
+	dstPath = path.Clean(dstPath)
+	if !path.IsAbs(dstPath) {
+		return fmt.Errorf("destination must be an absolute path")
+	}
+
+	dir, rename := dstPath, ""
+	stat, err := m.client.ContainerStatPath(ctx, containerID, dstPath)
+	switch {
+	case err == nil && stat.Mode.IsDir():
+	case err == nil || client.IsErrNotFound(err):
+		// copying to a new name, or over an existing file
+		dir, rename = path.Dir(dstPath), path.Base(dstPath)
+		if _, err := m.client.ContainerStatPath(ctx, containerID, dir); err != nil {
+			return fmt.Errorf("%w: %s", ErrPathNotFound, dir)
+		}
+	default:
+		return fmt.Errorf("failed to stat path: %w", err)
+	}
+
+	if rename != "" {
+		content = renameRoot(content, rename)
+	}
+	if err := m.client.CopyToContainer(ctx, containerID, dir, content, types.CopyToContainerOptions{}); err != nil {
+		if isPermissionError(err.Error()) {
+			return fmt.Errorf("%w: %s", ErrPermissionDenied, err)
+		}
+		return fmt.Errorf("failed to copy to container: %w", err)
+	}
+	return nil
+}
+
+// Tar stream of a file or directory, rooted at its base name
+func (m *Manager) CopyFrom(containerID, srcPath string) (io.ReadCloser, *FileInfo, error) {
+	reader, stat, err := m.client.CopyFromContainer(context.Background(), containerID, srcPath)
+	if client.IsErrNotFound(err) {
+		return nil, nil, fmt.Errorf("%w: %s", ErrPathNotFound, srcPath)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to copy from container: %w", err)
+	}
+	return reader, fileInfo(srcPath, stat), nil
+}
+
+// Rewrite a tar so its top-level entry is called name
+func renameRoot(content io.Reader, name string) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		tr := tar.NewReader(content)
+		tw := tar.NewWriter(pw)
+		root := ""
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+
+			entry := strings.TrimPrefix(header.Name, "./")
+			first, rest, _ := strings.Cut(entry, "/")
+			if root == "" {
+				root = first
+			}
+			if first != root {
+				pw.CloseWithError(fmt.Errorf("archive must contain a single file or directory"))
+				return
+			}
+			header.Name = name
+			if rest != "" {
+				header.Name += "/" + rest
+			} else if strings.HasSuffix(entry, "/") {
+				header.Name += "/"
+			}
+
+			if err := tw.WriteHeader(header); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := io.Copy(tw, tr); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(tw.Close())
+	}()
+	return pr
+}
+
+// Read-only root filesystems and volumes, or an exec denied access
+func isPermissionError(msg string) bool {
+	msg = strings.ToLower(msg)
+	return strings.Contains(msg, "read-only") || strings.Contains(msg, "permission denied")
+}
+
+// List a directory. A running container is listed with stat(1) so large
+// trees are not transferred; otherwise, or when the image has no shell,
+// the entries are read from a tar of the directory.
+func (m *Manager) ListDir(containerID, dirPath string) ([]FileInfo, error) {
+	dirPath = path.Clean(dirPath)
+	info, err := m.StatPath(containerID, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir {
+		return nil, fmt.Errorf("%w: %s", ErrNotADirectory, dirPath)
+	}
+
+	entries, err := m.listDirExec(containerID, dirPath)
+	if errors.Is(err, ErrPermissionDenied) {
+		return nil, err
+	}
+	if err != nil {
+		entries, err = m.listDirTar(containerID, dirPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].IsDir != entries[j].IsDir {
+			return entries[i].IsDir
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// Lists "<hex mode> <size> <mtime> <name>" lines, one per entry. Runs as
+// root like the copy API, which goes through the daemon.
+const listDirScript = `cd -- "$1" || exit 1
+for f in * .[!.]* ..?*; do
+  if [ -e "$f" ] || [ -L "$f" ]; then stat -c '%f %s %Y %n' -- "$f" || exit 1; fi
+done`
+
+func (m *Manager) listDirExec(containerID, dirPath string) ([]FileInfo, error) {
+	var stdout strings.Builder
+	cmd := []string{"sh", "-c", listDirScript, "sh", dirPath}
+	stderr, exitCode, err := m.execAs(context.Background(), containerID, "0", cmd, nil, nil, &stdout)
+	if err != nil {
+		return nil, err
+	}
+	if exitCode != 0 {
+		if isPermissionError(stderr) {
+			return nil, fmt.Errorf("%w: %s", ErrPermissionDenied, dirPath)
+		}
+		return nil, fmt.Errorf("failed to list directory: %s", strings.TrimSpace(stderr))
+	}
+
+	entries := []FileInfo{}
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		fields := strings.SplitN(line, " ", 4)
+		if len(fields) != 4 {
+			continue
+		}
+		rawMode, err1 := strconv.ParseUint(fields[0], 16, 32)
+		size, err2 := strconv.ParseInt(fields[1], 10, 64)
+		mtime, err3 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return nil, fmt.Errorf("unexpected stat output: %s", line)
+		}
+
+		mode := unixMode(uint32(rawMode))
+		entries = append(entries, FileInfo{
+			Name:    fields[3],
+			Path:    path.Join(dirPath, fields[3]),
+			Size:    size,
+			Mode:    mode,
+			ModTime: time.Unix(mtime, 0),
+			IsDir:   mode.IsDir(),
+		})
+	}
+	return entries, nil
+}
+
+// Convert a st_mode to an os.FileMode
+func unixMode(raw uint32) os.FileMode {
+	mode := os.FileMode(raw & 0o777)
+	switch raw & 0o170000 {
+	case 0o040000:
+		mode |= os.ModeDir
+	case 0o120000:
+		mode |= os.ModeSymlink
+	case 0o010000:
+		mode |= os.ModeNamedPipe
+	case 0o140000:
+		mode |= os.ModeSocket
+	case 0o060000:
+		mode |= os.ModeDevice
+	case 0o020000:
+		mode |= os.ModeDevice | os.ModeCharDevice
+	}
+	if raw&0o4000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if raw&0o2000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if raw&0o1000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode
+}
+
+func (m *Manager) listDirTar(containerID, dirPath string) ([]FileInfo, error) {
+	reader, _, err := m.CopyFrom(containerID, dirPath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	entries := []FileInfo{}
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read directory: %w", err)
+		}
+
+		// only direct children of the root entry
+		_, rest, _ := strings.Cut(strings.TrimSuffix(header.Name, "/"), "/")
+		if rest == "" || strings.Contains(rest, "/") {
+			continue
+		}
+		mode := header.FileInfo().Mode()
+		entries = append(entries, FileInfo{
+			Name:       rest,
+			Path:       path.Join(dirPath, rest),
+			Size:       header.Size,
+			Mode:       mode,
+			ModTime:    header.ModTime,
+			IsDir:      mode.IsDir(),
+			LinkTarget: header.Linkname,
+		})
+	}
+}
+
+// Open a regular file for reading; the caller closes the reader
+func (m *Manager) OpenFile(containerID, filePath string) (io.ReadCloser, *tar.Header, error) {
+	reader, info, err := m.CopyFrom(containerID, filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir {
+		reader.Close()
+		return nil, nil, fmt.Errorf("%w: %s", ErrIsADirectory, filePath)
+	}
+
+	tr := tar.NewReader(reader)
+	header, err := tr.Next()
+	if err != nil {
+		reader.Close()
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{tr, reader}, header, nil
+}
+
+// Write a file, keeping the mode and owner of the file it replaces
+func (m *Manager) WriteFile(containerID, filePath string, content []byte) error {
+	header := &tar.Header{
+		Name:    path.Base(filePath),
+		Mode:    0o644,
+		ModTime: time.Now(),
+	}
+	existing, current, err := m.OpenFile(containerID, filePath)
+	switch {
+	case err == nil:
+		existing.Close()
+		header.Mode = current.Mode
+		header.Uid, header.Gid = current.Uid, current.Gid
+		header.Uname, header.Gname = current.Uname, current.Gname
+	case !errors.Is(err, ErrPathNotFound):
+		return err
+	}
+	header.Size = int64(len(content))
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	tw.Write(content)
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return m.CopyTo(containerID, filePath, &buf)
+}
@@ -0,0 +1,286 @@
+package compute
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types"
+)
+
+func tarEntries(t *testing.T, names ...string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range names {
+		header := &tar.Header{Name: name, Mode: 0o644, Typeflag: tar.TypeReg, Size: int64(len(name))}
+		if strings.HasSuffix(name, "/") {
+			header.Typeflag, header.Size = tar.TypeDir, 0
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(name[:header.Size]))
+	}
+	tw.Close()
+	return &buf
+}
+
+// Entry names of a tar, comma separated
+func readNames(r io.Reader) (string, error) {
+	tr := tar.NewReader(r)
+	var names []string
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return strings.Join(names, ","), nil
+		}
+		if err != nil {
+			return strings.Join(names, ","), err
+		}
+		names = append(names, header.Name)
+	}
+}
+
+func TestRenameRoot(t *testing.T) {
+	renamed := renameRoot(tarEntries(t, "./conf/", "conf/app.yaml", "conf/sub/"), "settings")
+	if names, err := readNames(renamed); err != nil || names != "settings/,settings/app.yaml,settings/sub/" {
+		t.Errorf("renamed = %s, %v", names, err)
+	}
+
+	if _, err := readNames(renameRoot(tarEntries(t, "a.txt", "b.txt"), "c.txt")); err == nil {
+		t.Error("renamed an archive with two top-level entries")
+	}
+}
+
+// Paths in the fake container: directories end in /
+type fakeFilesystem struct {
+	mu       sync.Mutex
+	paths    map[string]bool
+	uploaded map[string]string      // directory to entry names
+	archives map[string][]byte      // tars served for paths
+	headers  map[string]*tar.Header // last upload's first header by directory
+}
+
+func (f *fakeFilesystem) install(docker *dockertest.Server, id string) {
+	docker.Handle("HEAD /containers/"+id+"/archive", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Query().Get("path")
+		f.mu.Lock()
+		file, dir := f.paths[p], f.paths[p+"/"]
+		f.mu.Unlock()
+		if !file && !dir {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		stat := types.ContainerPathStat{Name: p[strings.LastIndex(p, "/")+1:], Mode: 0o644}
+		if dir {
+			stat.Mode = os.ModeDir | 0o755
+		}
+		data, _ := json.Marshal(stat)
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(data))
+	}))
+	docker.Handle("PUT /containers/"+id+"/archive", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		names, _ := readNames(bytes.NewReader(data))
+		header, _ := tar.NewReader(bytes.NewReader(data)).Next()
+		f.mu.Lock()
+		f.uploaded[r.URL.Query().Get("path")] = names
+		f.headers[r.URL.Query().Get("path")] = header
+		f.mu.Unlock()
+	}))
+	docker.Handle("GET /containers/"+id+"/archive", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := r.URL.Query().Get("path")
+		f.mu.Lock()
+		data, ok := f.archives[p]
+		dir := f.paths[p+"/"]
+		f.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		stat := types.ContainerPathStat{Name: p[strings.LastIndex(p, "/")+1:], Mode: 0o644}
+		if dir {
+			stat.Mode = os.ModeDir | 0o755
+		}
+		encoded, _ := json.Marshal(stat)
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(encoded))
+		w.Header().Set("Content-Type", "application/x-tar")
+		w.Write(data)
+	}))
+}
+
+func newFakeFilesystem(paths ...string) *fakeFilesystem {
+	f := &fakeFilesystem{
+		paths:    make(map[string]bool),
+		uploaded: make(map[string]string),
+		archives: make(map[string][]byte),
+		headers:  make(map[string]*tar.Header),
+	}
+	for _, p := range paths {
+		f.paths[p] = true
+	}
+	return f
+}
+
+func TestCopyTo(t *testing.T) {
+	m, docker := newTestManager(t)
+	id := docker.AddContainer(dockertest.Container{Name: "web", Image: "nginx"})
+	fs := newFakeFilesystem("/", "/app/", "/app/old.conf")
+	fs.install(docker, id)
+
+	tests := []struct {
+		dst      string
+		dir      string // where the archive is unpacked
+		uploaded string
+	}{
+		{"/app", "/app", "nginx.conf"},                   // into an existing directory
+		{"/app/new.conf", "/app", "new.conf"},            // under a new name
+		{"/app/old.conf/", "/app", "old.conf"},           // over an existing file
+		{"/app/../app/./site.conf", "/app", "site.conf"}, // cleaned first
+	}
+	for _, tt := range tests {
+		if err := m.CopyTo(id, tt.dst, tarEntries(t, "nginx.conf")); err != nil {
+			t.Fatalf("CopyTo(%s): %v", tt.dst, err)
+		}
+		if got := fs.uploaded[tt.dir]; got != tt.uploaded {
+			t.Errorf("CopyTo(%s) unpacked %q into %s, want %q", tt.dst, got, tt.dir, tt.uploaded)
+		}
+	}
+
+	if err := m.CopyTo(id, "/missing/dir/file", tarEntries(t, "f")); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("missing parent: %v", err)
+	}
+	if err := m.CopyTo(id, "app", tarEntries(t, "f")); err == nil {
+		t.Error("accepted a relative destination")
+	}
+	if _, err := m.StatPath(id, "/nowhere"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("StatPath of a missing path: %v", err)
+	}
+}
+
+func TestUnixMode(t *testing.T) {
+	tests := []struct {
+		raw  uint32
+		want os.FileMode
+	}{
+		{0o100644, 0o644},
+		{0o040755, os.ModeDir | 0o755},
+		{0o120777, os.ModeSymlink | 0o777},
+		{0o104755, os.ModeSetuid | 0o755},
+		{0o041777, os.ModeDir | os.ModeSticky | 0o777},
+		{0o020620, os.ModeDevice | os.ModeCharDevice | 0o620},
+	}
+	for _, tt := range tests {
+		if got := unixMode(tt.raw); got != tt.want {
+			t.Errorf("unixMode(%o) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestListDir(t *testing.T) {
+	m, docker := newTestManager(t)
+	id := docker.AddContainer(dockertest.Container{Name: "web", Image: "nginx"})
+	fs := newFakeFilesystem("/", "/etc/", "/etc/hosts")
+	fs.install(docker, id)
+
+	var mu sync.Mutex
+	exitCode, stderr := 0, ""
+	docker.Exec(func(c dockertest.Container, cmd, env []string, stdin io.Reader, stdout, errOut io.Writer) int {
+		mu.Lock()
+		defer mu.Unlock()
+		if exitCode != 0 {
+			io.WriteString(errOut, stderr)
+			return exitCode
+		}
+		io.WriteString(stdout, "81a4 12 1700000000 hosts\n41ed 4096 1700000000 nginx\n81a4 0 1700000000 with space.conf\n")
+		return 0
+	})
+
+	entries, err := m.ListDir(id, "/etc/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name)
+	}
+	if strings.Join(names, ",") != "nginx,hosts,with space.conf" || entries[0].Path != "/etc/nginx" || entries[1].Size != 12 {
+		t.Errorf("entries = %+v", entries)
+	}
+
+	// images without a shell are listed from a tar of the directory
+	mu.Lock()
+	exitCode, stderr = 126, "exec: \"sh\": executable file not found"
+	mu.Unlock()
+	fs.archives["/etc"] = tarEntries(t, "etc/", "etc/passwd", "etc/ssl/", "etc/ssl/cert.pem").Bytes()
+	entries, err = m.ListDir(id, "/etc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name != "ssl" || entries[1].Name != "passwd" {
+		t.Errorf("entries from tar = %+v", entries)
+	}
+
+	mu.Lock()
+	exitCode, stderr = 1, "cd: can't cd to /etc: Permission denied"
+	mu.Unlock()
+	if _, err := m.ListDir(id, "/etc"); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("denied listing: %v", err)
+	}
+	if _, err := m.ListDir(id, "/etc/hosts"); !errors.Is(err, ErrNotADirectory) {
+		t.Errorf("listing a file: %v", err)
+	}
+}
+
+func TestWriteFileKeepsModeAndOwner(t *testing.T) {
+	m, docker := newTestManager(t)
+	id := docker.AddContainer(dockertest.Container{Name: "web", Image: "nginx"})
+	fs := newFakeFilesystem("/", "/app/", "/app/run.sh")
+	fs.install(docker, id)
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "run.sh", Mode: 0o750, Uid: 1000, Gid: 1000, Uname: "app", Size: 2})
+	tw.Write([]byte("ls"))
+	tw.Close()
+	fs.archives["/app/run.sh"] = buf.Bytes()
+
+	reader, header, err := m.OpenFile(id, "/app/run.sh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "ls" || header.Mode != 0o750 {
+		t.Errorf("OpenFile = %q, mode %o", data, header.Mode)
+	}
+
+	if err := m.WriteFile(id, "/app/run.sh", []byte("echo hi")); err != nil {
+		t.Fatal(err)
+	}
+	written := fs.headers["/app"]
+	if written.Name != "run.sh" || written.Mode != 0o750 || written.Uid != 1000 || written.Uname != "app" || written.Size != 7 {
+		t.Errorf("written header = %+v", written)
+	}
+
+	// new files get 0644
+	if err := m.WriteFile(id, "/app/new.txt", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	if written := fs.headers["/app"]; written.Name != "new.txt" || written.Mode != 0o644 {
+		t.Errorf("new file header = %+v", written)
+	}
+	if _, _, err := m.OpenFile(id, "/missing"); !errors.Is(err, ErrPathNotFound) {
+		t.Errorf("opening a missing file: %v", err)
+	}
+}
@@ -0,0 +1,374 @@
+// Health checks run by LocalCloud against its own instances
+package compute
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+)
+
+// Health states reported in Instance.Health
+const (
+	HealthStarting  = "starting"
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// Number of check results kept per instance
+const healthHistorySize = 50
+
+// Health check part of a create spec
+type HealthCheck struct {
+	Type        string `json:"type"`                           // http, tcp or exec
+	Path        string `json:"path,omitempty"`                 // http only
+	Port        int    `json:"port,omitempty"`                 // container port for http and tcp
+	Command     string `json:"command,omitempty"`              // exec only
+	Interval    int    `json:"interval_seconds,omitempty"`     // default 10
+	Timeout     int    `json:"timeout_seconds,omitempty"`      // default 3
+	Retries     int    `json:"retries,omitempty"`              // failures before unhealthy, default 3
+	StartPeriod int    `json:"start_period_seconds,omitempty"` // failures ignored while starting
+	Action      string `json:"action,omitempty"`               // none, restart or replace
+}
+
+// Single check result
+type HealthResult struct {
+	Time       time.Time `json:"time"`
+	Healthy    bool      `json:"healthy"`
+	Output     string    `json:"output,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+	Action     string    `json:"action,omitempty"` // auto-heal action taken after this result
+	// why the action failed; the instance is left as it was
+	ActionError string `json:"action_error,omitempty"`
+}
+
+// Current health of an instance plus recent results
+type HealthReport struct {
+	ID            string         `json:"id"`
+	Status        string         `json:"status"`
+	FailingStreak int            `json:"failing_streak"`
+	History       []HealthResult `json:"history"`
+}
+
+type healthState struct {
+	status    string
+	streak    int
+	firstSeen time.Time
+	lastCheck time.Time
+	checking  bool
+	history   []HealthResult
+}
+
+type healthMonitor struct {
+	mu     sync.Mutex
+	states map[string]*healthState
+}
+
+func (h *HealthCheck) validate() error {
+	switch h.Type {
+	case "http", "tcp":
+		if h.Port <= 0 {
+			return fmt.Errorf("%s check requires a port", h.Type)
+		}
+	case "exec":
+		if h.Command == "" {
+			return fmt.Errorf("exec check requires a command")
+		}
+	default:
+		return fmt.Errorf("unknown check type %q", h.Type)
+	}
+
+	switch h.Action {
+	case "", "none", "restart", "replace":
+	default:
+		return fmt.Errorf("unknown action %q", h.Action)
+	}
+	return nil
+}
+
+func (h *HealthCheck) interval() time.Duration {
+	if h.Interval <= 0 {
+		return 10 * time.Second
+	}
+	return time.Duration(h.Interval) * time.Second
+}
+
+func (h *HealthCheck) timeout() time.Duration {
+	if h.Timeout <= 0 {
+		return 3 * time.Second
+	}
+	return time.Duration(h.Timeout) * time.Second
+}
+
+func (h *HealthCheck) retries() int {
+	if h.Retries <= 0 {
+		return 3
+	}
+	return h.Retries
+}
+
+// Run health checks in the background until ctx is done
+func (m *Manager) StartHealthMonitor(ctx context.Context) {
+	m.health = &healthMonitor{states: make(map[string]*healthState)}
+
+	go func() {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.scheduleHealthChecks(ctx)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Health history for one instance
+func (m *Manager) HealthReport(containerID string) (*HealthReport, error) {
+	if m.health == nil {
+		return nil, fmt.Errorf("health monitor is not running")
+	}
+
+	containerJSON, err := m.client.ContainerInspect(context.Background(), containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	state, ok := m.health.states[containerJSON.ID]
+	if !ok {
+		return nil, fmt.Errorf("no health check configured for %s", containerID)
+	}
+
+	history := make([]HealthResult, len(state.history))
+	copy(history, state.history)
+	return &HealthReport{
+		ID:            containerJSON.ID,
+		Status:        state.status,
+		FailingStreak: state.streak,
+		History:       history,
+	}, nil
+}
+
+// Status shown in Instance, empty when unknown
+func (m *Manager) healthStatus(containerID string) string {
+	if m.health == nil {
+		return ""
+	}
+
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	if state, ok := m.health.states[containerID]; ok {
+		return state.status
+	}
+	return ""
+}
+
+// Start checks that are due, one in flight per instance
+func (m *Manager) scheduleHealthChecks(ctx context.Context) {
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", labelManaged)),
+	})
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+
+	m.health.mu.Lock()
+	defer m.health.mu.Unlock()
+
+	for _, c := range containers {
+		spec, ok := m.storedSpec(c.ID, c.Labels)
+		if !ok || spec.HealthCheck == nil {
+			continue
+		}
+		seen[c.ID] = true
+
+		state, ok := m.health.states[c.ID]
+		if !ok {
+			state = &healthState{status: HealthStarting, firstSeen: now}
+			m.health.states[c.ID] = state
+		}
+		if state.checking || now.Sub(state.lastCheck) < spec.HealthCheck.interval() {
+			continue
+		}
+
+		state.checking = true
+		state.lastCheck = now
+		go m.runHealthCheck(ctx, c.ID, spec)
+	}
+
+	// Forget instances that no longer exist
+	for id, state := range m.health.states {
+		if !seen[id] && !state.checking {
+			delete(m.health.states, id)
+		}
+	}
+}
+
+func (m *Manager) runHealthCheck(ctx context.Context, containerID string, spec *CreateSpec) {
+	check := spec.HealthCheck
+
+	checkCtx, cancel := context.WithTimeout(ctx, check.timeout())
+	start := time.Now()
+	output, err := m.probe(checkCtx, containerID, check)
+	cancel()
+
+	result := HealthResult{
+		Time:       start,
+		Healthy:    err == nil,
+		Output:     output,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		result.Output = err.Error()
+	}
+
+	m.health.mu.Lock()
+	state := m.health.states[containerID]
+	state.checking = false
+
+	if result.Healthy {
+		state.status = HealthHealthy
+		state.streak = 0
+	} else if state.status != HealthStarting || time.Since(state.firstSeen) > time.Duration(check.StartPeriod)*time.Second {
+		state.streak++
+		if state.streak >= check.retries() {
+			state.status = HealthUnhealthy
+		}
+	}
+
+	heal := state.status == HealthUnhealthy && check.Action != "" && check.Action != "none"
+	if heal {
+		result.Action = check.Action
+	}
+	state.history = append(state.history, result)
+	if len(state.history) > healthHistorySize {
+		state.history = state.history[len(state.history)-healthHistorySize:]
+	}
+	m.health.mu.Unlock()
+
+	if heal {
+		m.heal(ctx, containerID, spec)
+	}
+}
+
+// Perform one check, returning output on success
+func (m *Manager) probe(ctx context.Context, containerID string, check *HealthCheck) (string, error) {
+	if check.Type == "exec" {
+		output, exitCode, err := m.execWithExitCode(ctx, containerID, []string{"sh", "-c", check.Command})
+		if err != nil {
+			return "", err
+		}
+		if exitCode != 0 {
+			return "", fmt.Errorf("exit code %d: %s", exitCode, output)
+		}
+		return output, nil
+	}
+
+	addr, err := m.Address(ctx, containerID, check.Port)
+	if err != nil {
+		return "", err
+	}
+
+	if check.Type == "tcp" {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return "", err
+		}
+		conn.Close()
+		return "connected to " + addr, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+check.Path, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return fmt.Sprintf("HTTP %d", resp.StatusCode), nil
+}
+
+// Restart or replace an unhealthy instance, recording a failure against
+// the result that triggered it
+func (m *Manager) heal(ctx context.Context, containerID string, spec *CreateSpec) {
+	var err error
+	switch spec.HealthCheck.Action {
+	case "restart":
+		err = m.restartUnhealthy(ctx, containerID, spec)
+	case "replace":
+		err = m.replaceUnhealthy(ctx, containerID, spec)
+	}
+	if err == nil {
+		return
+	}
+
+	log.Printf("Failed to %s unhealthy instance %s: %v", spec.HealthCheck.Action, containerID, err)
+	m.health.mu.Lock()
+	if state, ok := m.health.states[containerID]; ok && len(state.history) > 0 {
+		state.history[len(state.history)-1].ActionError = err.Error()
+	}
+	m.health.mu.Unlock()
+}
+
+func (m *Manager) restartUnhealthy(ctx context.Context, containerID string, spec *CreateSpec) error {
+	if err := m.client.ContainerRestart(ctx, containerID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+	if len(spec.Secrets) > 0 {
+		m.restoreSecretFiles(ctx, containerID, *spec)
+	}
+	m.health.mu.Lock()
+	if state, ok := m.health.states[containerID]; ok {
+		state.status = HealthStarting
+		state.streak = 0
+		state.firstSeen = time.Now()
+	}
+	m.health.mu.Unlock()
+	return nil
+}
+
+// Create the replacement before removing the old instance, so a failed
+// create leaves the old one running
+func (m *Manager) replaceUnhealthy(ctx context.Context, containerID string, spec *CreateSpec) error {
+	instance, err := m.Replace(containerID, *spec)
+	if err != nil {
+		return err
+	}
+
+	// Carry history over to the replacement
+	m.health.mu.Lock()
+	if state, ok := m.health.states[containerID]; ok {
+		m.health.states[instance.ID] = &healthState{
+			status:    HealthStarting,
+			firstSeen: time.Now(),
+			history:   state.history,
+		}
+		delete(m.health.states, containerID)
+	}
+	m.health.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,224 @@
+package compute
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/go-connections/nat"
+)
+
+func TestHealthCheckValidate(t *testing.T) {
+	tests := []struct {
+		check HealthCheck
+		ok    bool
+	}{
+		{HealthCheck{Type: "http", Port: 80, Path: "/health"}, true},
+		{HealthCheck{Type: "tcp", Port: 5432, Action: "restart"}, true},
+		{HealthCheck{Type: "exec", Command: "pg_isready", Action: "replace"}, true},
+		{HealthCheck{Type: "http", Action: "none"}, false},
+		{HealthCheck{Type: "tcp", Port: -1}, false},
+		{HealthCheck{Type: "exec"}, false},
+		{HealthCheck{Type: "grpc", Port: 80}, false},
+		{HealthCheck{Type: "tcp", Port: 80, Action: "reboot"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.check.validate(); (err == nil) != tt.ok {
+			t.Errorf("validate(%+v) = %v, want ok %v", tt.check, err, tt.ok)
+		}
+	}
+}
+
+func TestHealthCheckDefaults(t *testing.T) {
+	var check HealthCheck
+	if check.interval() != 10*time.Second || check.timeout() != 3*time.Second || check.retries() != 3 {
+		t.Errorf("defaults = %v, %v, %d", check.interval(), check.timeout(), check.retries())
+	}
+	check = HealthCheck{Interval: 2, Timeout: 1, Retries: 5}
+	if check.interval() != 2*time.Second || check.timeout() != time.Second || check.retries() != 5 {
+		t.Errorf("set = %v, %v, %d", check.interval(), check.timeout(), check.retries())
+	}
+}
+
+// A manager whose container c1 publishes port 80 on an HTTP server
+// answering with status, and a restart counter
+func newHealthTestManager(t *testing.T, status *atomic.Int32) (*Manager, *atomic.Int32) {
+	t.Helper()
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(app.Close)
+	_, port, _ := net.SplitHostPort(app.Listener.Addr().String())
+
+	m, docker := newTestManager(t)
+	docker.AddContainer(dockertest.Container{
+		ID:    "c1",
+		Name:  "web",
+		Ports: nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: port}}},
+	})
+	var restarts atomic.Int32
+	docker.Handle("POST /containers/c1/restart", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		restarts.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	m.health = &healthMonitor{states: make(map[string]*healthState)}
+	return m, &restarts
+}
+
+func TestRunHealthCheck(t *testing.T) {
+	var status atomic.Int32
+	m, restarts := newHealthTestManager(t, &status)
+	spec := &CreateSpec{HealthCheck: &HealthCheck{Type: "http", Port: 80, Path: "/", Retries: 2, Action: "restart"}}
+	m.health.states["c1"] = &healthState{status: HealthStarting, firstSeen: time.Now().Add(-time.Minute)}
+
+	steps := []struct {
+		status int
+		want   string
+		streak int
+		healed bool
+	}{
+		{http.StatusOK, HealthHealthy, 0, false},
+		{http.StatusInternalServerError, HealthHealthy, 1, false},
+		{http.StatusOK, HealthHealthy, 0, false},
+		{http.StatusServiceUnavailable, HealthHealthy, 1, false},
+		// the second failure in a row is unhealthy and restarts it
+		{http.StatusServiceUnavailable, HealthStarting, 0, true},
+	}
+	for i, step := range steps {
+		status.Store(int32(step.status))
+		m.runHealthCheck(context.Background(), "c1", spec)
+
+		report, err := m.HealthReport("c1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Status != step.want || report.FailingStreak != step.streak {
+			t.Errorf("check %d (HTTP %d): %s with streak %d, want %s with %d",
+				i+1, step.status, report.Status, report.FailingStreak, step.want, step.streak)
+		}
+		last := report.History[len(report.History)-1]
+		if last.Healthy != (step.status == http.StatusOK) || (last.Action == "restart") != step.healed {
+			t.Errorf("check %d: result %+v", i+1, last)
+		}
+	}
+	if restarts.Load() != 1 {
+		t.Errorf("restarted %d times, want 1", restarts.Load())
+	}
+}
+
+func TestHealthCheckStartPeriod(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	m, _ := newHealthTestManager(t, &status)
+	spec := &CreateSpec{HealthCheck: &HealthCheck{Type: "http", Port: 80, Retries: 1, StartPeriod: 60}}
+
+	// failures while starting are ignored until the start period is over
+	m.health.states["c1"] = &healthState{status: HealthStarting, firstSeen: time.Now()}
+	m.runHealthCheck(context.Background(), "c1", spec)
+	if state := m.health.states["c1"]; state.status != HealthStarting || state.streak != 0 {
+		t.Errorf("in start period: %s with streak %d", state.status, state.streak)
+	}
+
+	m.health.states["c1"].firstSeen = time.Now().Add(-2 * time.Minute)
+	m.runHealthCheck(context.Background(), "c1", spec)
+	if state := m.health.states["c1"]; state.status != HealthUnhealthy {
+		t.Errorf("after start period: %s, want %s", state.status, HealthUnhealthy)
+	}
+}
+
+func TestHealthHistoryIsBounded(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	m, _ := newHealthTestManager(t, &status)
+	spec := &CreateSpec{HealthCheck: &HealthCheck{Type: "tcp", Port: 80}}
+	m.health.states["c1"] = &healthState{status: HealthStarting, firstSeen: time.Now()}
+
+	for i := 0; i < healthHistorySize+5; i++ {
+		m.runHealthCheck(context.Background(), "c1", spec)
+	}
+	report, err := m.HealthReport("c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.History) != healthHistorySize {
+		t.Errorf("history holds %d results, want %d", len(report.History), healthHistorySize)
+	}
+}
+
+func TestHealthCheckReplace(t *testing.T) {
+	m, docker := newTestManager(t)
+	m.health = &healthMonitor{states: make(map[string]*healthState)}
+
+	spec := CreateSpec{Image: "nginx", Name: "web", HealthCheck: &HealthCheck{Type: "tcp", Port: 80, Retries: 1, Action: "replace"}}
+	instance, err := m.Create(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, err := m.Spec(instance.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.health.states[instance.ID] = &healthState{status: HealthHealthy, firstSeen: time.Now()}
+
+	// nothing listens on the container, so the check fails and it is replaced
+	m.runHealthCheck(context.Background(), instance.ID, stored)
+
+	containers := docker.Containers()
+	if len(containers) != 1 || containers[0].ID == instance.ID || containers[0].Name != "web" {
+		t.Fatalf("containers after replace = %+v", containers)
+	}
+	replacement := containers[0].ID
+	state, ok := m.health.states[replacement]
+	if !ok || state.status != HealthStarting || len(state.history) != 1 {
+		t.Fatalf("replacement health state = %+v", state)
+	}
+	if _, ok := m.health.states[instance.ID]; ok {
+		t.Error("the old instance is still monitored")
+	}
+}
+
+func TestHealthCheckReplaceFails(t *testing.T) {
+	m, docker := newTestManager(t)
+	m.health = &healthMonitor{states: make(map[string]*healthState)}
+
+	spec := CreateSpec{Image: "nginx", Name: "web", HealthCheck: &HealthCheck{Type: "tcp", Port: 80, Retries: 1, Action: "replace"}}
+	instance, err := m.Create(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored, _ := m.Spec(instance.ID)
+	m.health.states[instance.ID] = &healthState{status: HealthHealthy, firstSeen: time.Now()}
+	docker.Handle("POST /containers/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"message": "no space left on device"}`, http.StatusInternalServerError)
+	}))
+
+	m.runHealthCheck(context.Background(), instance.ID, stored)
+
+	// the old instance is left running under its own name
+	containers := docker.Containers()
+	if len(containers) != 1 || containers[0].ID != instance.ID || containers[0].Name != "web" || containers[0].State != "running" {
+		t.Fatalf("containers after a failed replace = %+v", containers)
+	}
+	history := m.health.states[instance.ID].history
+	if len(history) != 1 || history[0].Action != "replace" || !strings.Contains(history[0].ActionError, "no space left") {
+		t.Errorf("history = %+v", history)
+	}
+}
+
+func TestCreateRejectsInvalidHealthCheck(t *testing.T) {
+	m, docker := newTestManager(t)
+	_, err := m.Create(CreateSpec{Image: "nginx", HealthCheck: &HealthCheck{Type: "http"}})
+	if err == nil {
+		t.Fatal("created an instance with an http check without a port")
+	}
+	if len(docker.Containers()) != 0 {
+		t.Error("a container was created")
+	}
+}
@@ -0,0 +1,320 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"localcloud/internal/store"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+var ErrUnknownInstanceType = errors.New("unknown instance type")
+
+// A named size for instances
+type InstanceType struct {
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	CPUs        float64 `json:"cpus"`      // CPU quota, and shares of 1024 per CPU
+	MemoryMB    int     `json:"memory_mb"` // hard limit; swap up to the same again
+	PidsLimit   int64   `json:"pids_limit,omitempty"`
+	DiskMB      int     `json:"disk_mb,omitempty"` // writable layer, where the storage driver supports it
+}
+
+// Built in catalog; config can add types or redefine these
+var DefaultInstanceTypes = []InstanceType{
+	{Name: "lc.nano", Description: "Sidecars and tiny services", CPUs: 0.25, MemoryMB: 256, PidsLimit: 128, DiskMB: 2048},
+	{Name: "lc.micro", Description: "Small web servers and workers", CPUs: 0.5, MemoryMB: 512, PidsLimit: 256, DiskMB: 4096},
+	{Name: "lc.small", Description: "General purpose", CPUs: 1, MemoryMB: 1024, PidsLimit: 512, DiskMB: 8192},
+	{Name: "lc.medium", Description: "Databases and busier services", CPUs: 2, MemoryMB: 2048, PidsLimit: 1024, DiskMB: 16384},
+	{Name: "lc.large", Description: "Build and batch workloads", CPUs: 4, MemoryMB: 4096, PidsLimit: 2048, DiskMB: 32768},
+	{Name: "lc.xlarge", Description: "Heavy workloads", CPUs: 8, MemoryMB: 8192, PidsLimit: 4096, DiskMB: 65536},
+}
+
+// Resources a container actually has, and the type they match
+type Size struct {
+	InstanceType string  `json:"instance_type,omitempty"` // empty if no type matches
+	CPUs         float64 `json:"cpus"`                    // 0 for no limit
+	MemoryMB     int     `json:"memory_mb"`
+	PidsLimit    int64   `json:"pids_limit,omitempty"`
+}
+
+// Catalog of instance types, and the sizes of resized containers. Docker
+// can't change a container's labels, so a resize is recorded here on top of
+// the size in its spec label, and kept in resizedPath across restarts.
+type instanceTypes struct {
+	mu          sync.Mutex
+	types       map[string]InstanceType
+	resized     map[string]Size // container ID
+	resizedPath string
+}
+
+func newInstanceTypes() *instanceTypes {
+	t := &instanceTypes{types: make(map[string]InstanceType), resized: make(map[string]Size)}
+	for _, it := range DefaultInstanceTypes {
+		t.types[it.Name] = it
+	}
+	return t
+}
+
+// Add custom instance types from a JSON file holding a list of types.
+// A missing file is not an error.
+func (m *Manager) LoadInstanceTypes(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read instance types: %w", err)
+	}
+
+	var custom []InstanceType
+	if err := json.Unmarshal(data, &custom); err != nil {
+		return fmt.Errorf("failed to decode instance types: %w", err)
+	}
+	for _, it := range custom {
+		if it.Name == "" || it.CPUs <= 0 || it.MemoryMB <= 0 || it.PidsLimit < 0 || it.DiskMB < 0 {
+			return fmt.Errorf("instance type %q needs a name and positive cpus and memory_mb", it.Name)
+		}
+	}
+
+	m.types.mu.Lock()
+	defer m.types.mu.Unlock()
+	for _, it := range custom {
+		m.types.types[it.Name] = it
+	}
+	return nil
+}
+
+// Load and keep resize records in path, so resized instances keep their new
+// type across restarts and recreates. Without it resizes last until the
+// process exits.
+func (m *Manager) LoadResizes(path string) error {
+	resized := make(map[string]Size)
+	if err := store.Load(path, &resized); err != nil {
+		return err
+	}
+
+	m.types.mu.Lock()
+	defer m.types.mu.Unlock()
+	m.types.resized = resized
+	m.types.resizedPath = path
+	return nil
+}
+
+// Apply change to the resize records in memory and, when they are kept in
+// a file, to the file's records under its lock, so resizes made by other
+// processes (e.g. the CLI in direct mode) are merged rather than lost.
+// change reports whether it changed anything.
+func (m *Manager) updateResizesLocked(change func(resized map[string]Size) bool) error {
+	change(m.types.resized)
+	if m.types.resizedPath == "" {
+		return nil
+	}
+
+	merged := make(map[string]Size)
+	err := store.Update(m.types.resizedPath, &merged, func() (bool, error) {
+		return change(merged), nil
+	})
+	if err != nil {
+		return err
+	}
+	m.types.resized = merged
+	return nil
+}
+
+// Drop the resize record of a removed container, given its full ID
+func (m *Manager) forgetResize(containerID string) {
+	m.types.mu.Lock()
+	defer m.types.mu.Unlock()
+
+	err := m.updateResizesLocked(func(resized map[string]Size) bool {
+		if _, ok := resized[containerID]; !ok {
+			return false
+		}
+		delete(resized, containerID)
+		return true
+	})
+	if err != nil {
+		log.Printf("Failed to save resize records: %v", err)
+	}
+}
+
+// Catalog, smallest first
+func (m *Manager) InstanceTypes() []InstanceType {
+	m.types.mu.Lock()
+	defer m.types.mu.Unlock()
+
+	list := make([]InstanceType, 0, len(m.types.types))
+	for _, it := range m.types.types {
+		list = append(list, it)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].CPUs != list[j].CPUs {
+			return list[i].CPUs < list[j].CPUs
+		}
+		if list[i].MemoryMB != list[j].MemoryMB {
+			return list[i].MemoryMB < list[j].MemoryMB
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+func (m *Manager) InstanceType(name string) (InstanceType, error) {
+	m.types.mu.Lock()
+	defer m.types.mu.Unlock()
+	it, ok := m.types.types[name]
+	if !ok {
+		return it, fmt.Errorf("%w: %s", ErrUnknownInstanceType, name)
+	}
+	return it, nil
+}
+
+// Fill in the spec's resources from its instance type
+func (m *Manager) applyInstanceType(spec *CreateSpec) error {
+	if spec.InstanceType == "" {
+		return nil
+	}
+	it, err := m.InstanceType(spec.InstanceType)
+	if err != nil {
+		return err
+	}
+	// Stored specs carry the type's own values, so recreating from them works
+	if (spec.CPUs != 0 && spec.CPUs != it.CPUs) || (spec.MemoryMB != 0 && spec.MemoryMB != it.MemoryMB) {
+		return fmt.Errorf("invalid resources: set either instance_type or cpus and memory_mb")
+	}
+	spec.CPUs = it.CPUs
+	spec.MemoryMB = it.MemoryMB
+	return nil
+}
+
+func applyResources(hostConfig *container.HostConfig, cpus float64, memoryMB int, pidsLimit int64) {
+	if cpus > 0 {
+		hostConfig.Resources.NanoCPUs = int64(cpus * 1e9)
+		hostConfig.Resources.CPUShares = int64(cpus * 1024)
+	}
+	if memoryMB > 0 {
+		hostConfig.Resources.Memory = int64(memoryMB) * 1024 * 1024
+		hostConfig.Resources.MemorySwap = 2 * hostConfig.Resources.Memory
+	}
+	if pidsLimit > 0 {
+		hostConfig.Resources.PidsLimit = &pidsLimit
+	}
+}
+
+// Disk limits need overlay2 on xfs with pquota; other drivers refuse them
+func isStorageOptError(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "storage-opt")
+}
+
+func sizeFromHostConfig(hostConfig *container.HostConfig) Size {
+	size := Size{
+		CPUs:     float64(hostConfig.NanoCPUs) / 1e9,
+		MemoryMB: int(hostConfig.Memory / (1024 * 1024)),
+	}
+	if hostConfig.PidsLimit != nil && *hostConfig.PidsLimit > 0 {
+		size.PidsLimit = *hostConfig.PidsLimit
+	}
+	return size
+}
+
+// Resources of a LocalCloud container: those of its last resize, or else
+// those its spec asked for. Needs no Docker calls, so listings stay cheap.
+func (m *Manager) sizeOf(containerID string, spec *CreateSpec) Size {
+	m.types.mu.Lock()
+	size, ok := m.types.resized[containerID]
+	m.types.mu.Unlock()
+	if ok || spec == nil {
+		return size
+	}
+
+	size = Size{InstanceType: spec.InstanceType, CPUs: spec.CPUs, MemoryMB: spec.MemoryMB}
+	if it, err := m.InstanceType(spec.InstanceType); err == nil {
+		size.PidsLimit = it.PidsLimit
+	}
+	return size
+}
+
+// The spec label with any resize applied, so instances recreated from it
+// (health replacements, bundles) keep the new type
+func (m *Manager) storedSpec(containerID string, labels map[string]string) (*CreateSpec, bool) {
+	spec, ok := specFromLabels(labels)
+	if !ok {
+		return nil, false
+	}
+	m.types.mu.Lock()
+	size, resized := m.types.resized[containerID]
+	m.types.mu.Unlock()
+	if resized {
+		spec.InstanceType, spec.CPUs, spec.MemoryMB = size.InstanceType, size.CPUs, size.MemoryMB
+	}
+	return spec, true
+}
+
+// Change a running instance to another type in place. The new type is
+// recorded (see LoadResizes) and used when the instance is recreated, e.g.
+// by a health check or a bundle. Disk limits only apply to new instances,
+// and new members of a scaling group get the group template's type.
+func (m *Manager) Resize(containerID, typeName string) (*Instance, error) {
+	ctx := context.Background()
+	it, err := m.InstanceType(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	containerJSON, err := m.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	current := sizeFromHostConfig(containerJSON.HostConfig)
+
+	// Growing counts against the project's quota
+	project := containerJSON.Config.Labels[labelProject]
+	if project == "" {
+		project = DefaultProject
+	}
+	release, err := m.reserveResize(project, current, it)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	var hostConfig container.HostConfig
+	applyResources(&hostConfig, it.CPUs, it.MemoryMB, it.PidsLimit)
+	if _, err := m.client.ContainerUpdate(ctx, containerJSON.ID, container.UpdateConfig{Resources: hostConfig.Resources}); err != nil {
+		return nil, fmt.Errorf("failed to resize container: %w", err)
+	}
+
+	size := Size{InstanceType: it.Name, CPUs: it.CPUs, MemoryMB: it.MemoryMB, PidsLimit: it.PidsLimit}
+	m.types.mu.Lock()
+	err = m.updateResizesLocked(func(resized map[string]Size) bool {
+		resized[containerJSON.ID] = size
+		return true
+	})
+	m.types.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("resized, but failed to record the new type: %w", err)
+	}
+	log.Printf("Resized %s from %s to %s", strings.TrimPrefix(containerJSON.Name, "/"), sizeName(current), it.Name)
+
+	updated, err := m.client.ContainerInspect(ctx, containerJSON.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return m.inspectToInstance(updated), nil
+}
+
+func sizeName(size Size) string {
+	if size.CPUs == 0 && size.MemoryMB == 0 {
+		return "unlimited"
+	}
+	return fmt.Sprintf("%g CPUs/%d MB", size.CPUs, size.MemoryMB)
+}
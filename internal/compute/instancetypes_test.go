@@ -0,0 +1,167 @@
+package compute
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"localcloud/internal/store"
+
+	"github.com/docker/docker/api/types/volume"
+)
+
+func TestLoadInstanceTypes(t *testing.T) {
+	m, _ := newTestManager(t)
+	dir := t.TempDir()
+	if err := m.LoadInstanceTypes(filepath.Join(dir, "missing.json")); err != nil {
+		t.Fatalf("missing file: %v", err)
+	}
+
+	path := filepath.Join(dir, "types.json")
+	os.WriteFile(path, []byte(`[{"name": "gpu.big", "cpus": 16, "memory_mb": 65536}, {"name": "lc.small", "cpus": 1.5, "memory_mb": 1536}]`), 0o644)
+	if err := m.LoadInstanceTypes(path); err != nil {
+		t.Fatal(err)
+	}
+	if it, err := m.InstanceType("lc.small"); err != nil || it.CPUs != 1.5 {
+		t.Errorf("redefined lc.small = %+v, %v", it, err)
+	}
+	list := m.InstanceTypes()
+	if list[0].Name != "lc.nano" || list[len(list)-1].Name != "gpu.big" {
+		t.Errorf("catalog is not smallest first: %+v", list)
+	}
+	if _, err := m.InstanceType("lc.huge"); !errors.Is(err, ErrUnknownInstanceType) {
+		t.Errorf("unknown type: %v", err)
+	}
+
+	os.WriteFile(path, []byte(`[{"name": "broken", "cpus": 0, "memory_mb": 512}]`), 0o644)
+	if err := m.LoadInstanceTypes(path); err == nil {
+		t.Error("accepted a type without CPUs")
+	}
+}
+
+func TestCreateWithInstanceType(t *testing.T) {
+	m, docker := newTestManager(t)
+	instance, err := m.Create(CreateSpec{Name: "web", Image: "nginx", InstanceType: "lc.small"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.InstanceType != "lc.small" {
+		t.Errorf("instance type = %q", instance.InstanceType)
+	}
+	hostConfig := docker.Containers()[0].HostConfig
+	if hostConfig.NanoCPUs != 1e9 || hostConfig.CPUShares != 1024 || hostConfig.Memory != 1<<30 ||
+		*hostConfig.PidsLimit != 512 || hostConfig.StorageOpt["size"] != "8192M" {
+		t.Errorf("host config = %+v", hostConfig.Resources)
+	}
+	spec, _ := m.Spec(instance.ID)
+	if spec.CPUs != 1 || spec.MemoryMB != 1024 {
+		t.Errorf("stored spec = %+v", spec)
+	}
+
+	if _, err := m.Create(CreateSpec{Name: "api", Image: "nginx", InstanceType: "lc.small", CPUs: 4}); err == nil {
+		t.Error("accepted both an instance type and cpus")
+	}
+	if _, err := m.Create(CreateSpec{Name: "api", Image: "nginx", InstanceType: "lc.huge"}); !errors.Is(err, ErrUnknownInstanceType) {
+		t.Errorf("unknown type: %v", err)
+	}
+}
+
+func TestResize(t *testing.T) {
+	m, docker := newTestManager(t)
+	web := managedContainer(t, "web", CreateSpec{Image: "nginx", InstanceType: "lc.micro", CPUs: 0.5, MemoryMB: 512})
+	applyResources(web.HostConfig, 0.5, 512, 256)
+	id := docker.AddContainer(web)
+
+	if instance, err := m.Get(id); err != nil || instance.InstanceType != "lc.micro" {
+		t.Fatalf("Get = %+v, %v", instance, err)
+	}
+	path := filepath.Join(t.TempDir(), "resizes.json")
+	if err := m.LoadResizes(path); err != nil {
+		t.Fatal(err)
+	}
+	instance, err := m.Resize("web", "lc.medium")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if instance.InstanceType != "lc.medium" {
+		t.Errorf("resized to %q", instance.InstanceType)
+	}
+	resized := docker.Containers()[0].HostConfig
+	if resized.NanoCPUs != 2e9 || resized.Memory != 2<<30 || resized.MemorySwap != 4<<30 || *resized.PidsLimit != 1024 {
+		t.Errorf("resources = %+v", resized.Resources)
+	}
+	if spec, _ := m.Spec(id); spec.InstanceType != "lc.medium" || spec.CPUs != 2 {
+		t.Errorf("spec after resize = %+v", spec)
+	}
+
+	// the new type outlives the process
+	reloaded, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := reloaded.LoadResizes(path); err != nil {
+		t.Fatal(err)
+	}
+	if list := reloaded.List(); len(list) != 1 || list[0].InstanceType != "lc.medium" {
+		t.Errorf("after reloading: %+v", list)
+	}
+
+	// growing past the project's quota is refused and leaves the instance alone
+	m.UseQuotas(quotaMap{DefaultProject: {MaxCPUs: 3}})
+	docker.Handle("GET /volumes", volume.ListResponse{})
+	if _, err := m.Resize(id, "lc.large"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("resize over quota: %v", err)
+	}
+	if docker.Containers()[0].HostConfig.NanoCPUs != 2e9 {
+		t.Error("resized over quota")
+	}
+	if _, err := m.Resize(id, "lc.nano"); err != nil {
+		t.Errorf("shrinking: %v", err)
+	}
+}
+
+func TestResizeRecords(t *testing.T) {
+	m, docker := newTestManager(t)
+	path := filepath.Join(t.TempDir(), "resizes.json")
+	if err := m.LoadResizes(path); err != nil {
+		t.Fatal(err)
+	}
+	web := docker.AddContainer(managedContainer(t, "web", CreateSpec{Image: "nginx", InstanceType: "lc.micro"}))
+	api := docker.AddContainer(managedContainer(t, "api", CreateSpec{Image: "nginx", InstanceType: "lc.micro"}))
+
+	// another process sharing the data directory resizes api
+	other, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoadResizes(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Resize(web, "lc.small"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Resize(api, "lc.large"); err != nil {
+		t.Fatal(err)
+	}
+
+	var saved map[string]Size
+	store.Load(path, &saved)
+	if saved[web].InstanceType != "lc.small" || saved[api].InstanceType != "lc.large" {
+		t.Fatalf("saved = %+v", saved)
+	}
+
+	// deleting by name drops that instance's record only
+	if err := m.Delete("web"); err != nil {
+		t.Fatal(err)
+	}
+	saved = nil
+	store.Load(path, &saved)
+	if _, ok := saved[web]; ok || len(saved) != 1 {
+		t.Errorf("after deleting web: %+v", saved)
+	}
+	m.forgetResize(api[:12])
+	if spec, _ := m.Spec(api); spec.InstanceType != "lc.large" {
+		t.Errorf("a prefix dropped the record: %+v", spec)
+	}
+}
@@ -5,25 +5,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
+	"net"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 	"github.com/docker/go-connections/nat"
 	"github.com/google/uuid"
 )
 
 // Docker container info
 type Instance struct {
-	ID      string    `json:"id"`
-	Name    string    `json:"name"`
-	Image   string    `json:"image"`
-	Status  string    `json:"status"`
-	Ports   string    `json:"ports"`
-	Created time.Time `json:"created"`
-	Uptime  string    `json:"uptime"`
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Image        string    `json:"image"`
+	Status       string    `json:"status"`
+	State        string    `json:"state"` // created, running, exited...
+	Ports        string    `json:"ports"`
+	Created      time.Time `json:"created"`
+	Uptime       string    `json:"uptime"`
+	Health       string    `json:"health,omitempty"`
+	IP           string    `json:"ip,omitempty"`
+	Project      string    `json:"project,omitempty"`
+	InstanceType string    `json:"instance_type,omitempty"`
 }
 // Docker container metrics
 type Metrics struct {
@@ -35,9 +46,35 @@ type Metrics struct {
 	NetworkTx   uint64  `json:"network_tx"`
 	Timestamp   time.Time `json:"timestamp"`
 }
+// Labels stored on containers created by LocalCloud
+const (
+	labelManaged = "localcloud.managed"
+	labelSpec    = "localcloud.spec"
+)
+
 // API client
 type Manager struct {
 	client *client.Client
+	health *healthMonitor
+
+	// Resolver settings given to new containers
+	dnsServers []string
+	dnsSearch  []string
+
+	// Host ports reserved for containers being created
+	ports *portAllocator
+
+	// Instance type catalog and container sizes
+	types *instanceTypes
+
+	// Project quotas, and what creates in flight have been granted
+	quotas       QuotaSource
+	quotaMu      sync.Mutex
+	pendingQuota map[string]*Usage
+
+	// Look up secrets and parameters referenced by create specs
+	secrets    SecretResolver
+	parameters ParameterResolver
 }
 
 func NewManager() (*Manager, error) {
@@ -53,7 +90,7 @@ func NewManager() (*Manager, error) {
 		return nil, fmt.Errorf("failed to connect to Docker: %w", err)
 	}
 
-	return &Manager{client: cli}, nil
+	return &Manager{client: cli, ports: newPortAllocator(), types: newInstanceTypes(), pendingQuota: make(map[string]*Usage)}, nil
 }
 
 // Commands 
@@ -72,77 +109,327 @@ func (m *Manager) List() []Instance {
 	return instances
 }
 
-func (m *Manager) Create(image, name, portMapping string) (*Instance, error) {
-	ctx := context.Background()
+// Parameters for a new instance, stored on the container so it can be recreated
+type CreateSpec struct {
+	Image        string            `json:"image"`
+	Name         string            `json:"name"`
+	Command      []string          `json:"command,omitempty"` // overrides the image's CMD
+	Ports        string            `json:"ports"`
+	Env          []string          `json:"env,omitempty"` // KEY=value
+	MemoryMB     int               `json:"memory_mb,omitempty"`
+	Volumes      []string          `json:"volumes,omitempty"` // volume:/path or /host/path:/path
+	Labels       map[string]string `json:"labels,omitempty"`
+	HealthCheck  *HealthCheck      `json:"health_check,omitempty"`
+	Secrets      []SecretRef       `json:"secrets,omitempty"`       // resolved at creation, values never stored here
+	Parameters   []ParameterRef    `json:"parameters,omitempty"`    // env vars resolved at creation
+	Snapshot     string            `json:"snapshot,omitempty"`      // launch from a snapshot instead of Image
+	Project      string            `json:"project,omitempty"`       // quota the instance counts against, default if empty
+	InstanceType string            `json:"instance_type,omitempty"` // sets cpus and memory_mb from the catalog
+	CPUs         float64           `json:"cpus,omitempty"`
+}
+
+// Single container by ID or name
+func (m *Manager) Get(containerID string) (*Instance, error) {
+	containerJSON, err := m.client.ContainerInspect(context.Background(), containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	return m.inspectToInstance(containerJSON), nil
+}
+
+// Containers carrying label key=value, running or not
+func (m *Manager) ListByLabel(key, value string) []Instance {
+	containers, err := m.client.ContainerList(context.Background(), types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", key+"="+value)),
+	})
+	if err != nil {
+		return []Instance{}
+	}
+
+	instances := make([]Instance, 0, len(containers))
+	for _, c := range containers {
+		instances = append(instances, m.containerToInstance(c))
+	}
+
+	return instances
+}
+
+func (m *Manager) Create(spec CreateSpec) (*Instance, error) {
+	return m.CreateWithProgress(context.Background(), spec, nil)
+}
+
+// Create, reporting each step to progress. Cancelling ctx stops the pull or
+// removes the half-made container.
+func (m *Manager) CreateWithProgress(ctx context.Context, spec CreateSpec, progress ProgressFunc) (*Instance, error) {
+	progress = progress.orNoop()
+	progress("validating", 5)
 
 	// Generate name if not provided
-	if name == "" {
-		name = fmt.Sprintf("localcloud-%s", uuid.New().String()[:8])
+	if spec.Name == "" {
+		spec.Name = fmt.Sprintf("localcloud-%s", uuid.New().String()[:8])
 	}
 
-	// Parse port mapping
-	hostConfig := &container.HostConfig{}
-	
-	// If custom port mapping is provided, parse it
-	if portMapping != "" {
-		portBindings, exposedPorts, err := parsePortMapping(portMapping)
+	if spec.Snapshot != "" {
+		snapshot, err := m.GetSnapshot(spec.Snapshot)
 		if err != nil {
-			return nil, fmt.Errorf("invalid port mapping: %w", err)
+			return nil, err
 		}
-		hostConfig.PortBindings = portBindings
+		spec.Image = snapshot.Image
+	}
 
-		config := &container.Config{
-			Image:        image,
-			ExposedPorts: exposedPorts,
-		}
-		// Create container
-		resp, err := m.client.ContainerCreate(ctx, config, hostConfig, nil, nil, name)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create container: %w", err)
+	if spec.HealthCheck != nil {
+		if err := spec.HealthCheck.validate(); err != nil {
+			return nil, fmt.Errorf("invalid health check: %w", err)
 		}
-		// Start container
-		if err := m.client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
-			return nil, fmt.Errorf("failed to start container: %w", err)
+	}
+	if err := validateSecretRefs(spec.Secrets); err != nil {
+		return nil, fmt.Errorf("invalid secrets: %w", err)
+	}
+	if err := validateParameterRefs(spec.Parameters); err != nil {
+		return nil, fmt.Errorf("invalid parameters: %w", err)
+	}
+	if err := m.applyInstanceType(&spec); err != nil {
+		return nil, err
+	}
+	if spec.Project != "" && !ValidProjectName(spec.Project) {
+		return nil, fmt.Errorf("invalid project: %w", ErrInvalidProject)
+	}
+	if spec.CPUs < 0 || spec.MemoryMB < 0 {
+		return nil, fmt.Errorf("invalid resources: cpus and memory_mb must not be negative")
+	}
+
+	// Held until the container exists and is counted like any other
+	releaseQuota, err := m.reserveQuota(projectOf(&spec), &spec, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer releaseQuota()
+
+	// Keep the spec on the container for health checks and auto-healing
+	specJSON, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode spec: %w", err)
+	}
+
+	labels := map[string]string{
+		labelManaged: "true",
+		labelSpec:    string(specJSON),
+		labelProject: projectOf(&spec),
+	}
+	for key, value := range spec.Labels {
+		labels[key] = value
+	}
+
+	// Parameters are looked up now so each new instance gets current values
+	parameterEnv, err := m.resolveParameters(spec.Parameters)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &container.Config{
+		Image:  spec.Image,
+		Cmd:    spec.Command,
+		Env:    append(append([]string{}, spec.Env...), parameterEnv...),
+		Labels: labels,
+	}
+	hostConfig := &container.HostConfig{
+		Binds:     spec.Volumes,
+		DNS:       m.dnsServers,
+		DNSSearch: m.dnsSearch,
+	}
+	if spec.InstanceType != "" {
+		it, _ := m.InstanceType(spec.InstanceType)
+		applyResources(hostConfig, it.CPUs, it.MemoryMB, it.PidsLimit)
+		if it.DiskMB > 0 {
+			hostConfig.StorageOpt = map[string]string{"size": fmt.Sprintf("%dM", it.DiskMB)}
 		}
+	} else {
+		applyResources(hostConfig, spec.CPUs, spec.MemoryMB, 0)
+	}
 
-		// Get updated container info
-		containerJSON, err := m.client.ContainerInspect(ctx, resp.ID)
+	// Host ports are reserved until the container has started
+	if spec.Ports != "" {
+		mappings, err := ParsePorts(spec.Ports)
 		if err != nil {
-			return nil, fmt.Errorf("failed to inspect container: %w", err)
+			return nil, fmt.Errorf("invalid port mapping: %w", err)
 		}
+		portBindings, exposedPorts, release, err := m.allocatePorts(ctx, mappings)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+		hostConfig.PortBindings = portBindings
+		config.ExposedPorts = exposedPorts
+	}
 
-		return m.inspectToInstance(containerJSON), nil
+	// Secret values go into the container config only, not the spec label
+	secretFiles, err := m.applySecrets(ctx, spec, config, hostConfig)
+	if err != nil {
+		return nil, err
 	}
 
-	// Simple container without port mapping
-	config := &container.Config{Image: image}
-	resp, err := m.client.ContainerCreate(ctx, config, hostConfig, nil, nil, name)
+	// Create container
+	progress("creating container", 10)
+	resp, err := m.client.ContainerCreate(ctx, config, hostConfig, nil, nil, spec.Name)
+	if client.IsErrNotFound(err) {
+		// Pull the image on first use and try again
+		if err := m.pullImage(ctx, spec.Image, progress.scaled("pulling image", 10, 70)); err != nil {
+			return nil, err
+		}
+		progress("creating container", 75)
+		resp, err = m.client.ContainerCreate(ctx, config, hostConfig, nil, nil, spec.Name)
+	}
+	if isStorageOptError(err) {
+		// Disk limits are best effort, most storage drivers can't enforce them
+		hostConfig.StorageOpt = nil
+		resp, err = m.client.ContainerCreate(ctx, config, hostConfig, nil, nil, spec.Name)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to create container: %w", err)
 	}
-
+	// Start container
+	progress("starting container", 85)
 	if err := m.client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		// Don't leave a created container behind, e.g. when a port was taken meanwhile
+		m.client.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
 		return nil, fmt.Errorf("failed to start container: %w", err)
 	}
+	if len(secretFiles) > 0 {
+		if err := m.writeSecretFiles(ctx, resp.ID, secretFiles); err != nil {
+			m.Delete(resp.ID)
+			return nil, err
+		}
+	}
 
+	// Get updated container info
+	progress("inspecting container", 95)
 	containerJSON, err := m.client.ContainerInspect(ctx, resp.ID)
 	if err != nil {
+		if ctx.Err() != nil {
+			m.Delete(resp.ID)
+		}
 		return nil, fmt.Errorf("failed to inspect container: %w", err)
 	}
 
 	return m.inspectToInstance(containerJSON), nil
 }
 
+// Point containers created from now on at the given resolvers
+func (m *Manager) UseDNS(servers []string, search string) {
+	m.dnsServers = servers
+	m.dnsSearch = []string{search}
+}
+
+// Gateway of the default bridge network, which containers can reach the host on
+func (m *Manager) BridgeGateway() (string, error) {
+	network, err := m.client.NetworkInspect(context.Background(), "bridge", types.NetworkInspectOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect bridge network: %w", err)
+	}
+	for _, cfg := range network.IPAM.Config {
+		if cfg.Gateway != "" {
+			return cfg.Gateway, nil
+		}
+	}
+	return "", fmt.Errorf("bridge network has no gateway")
+}
+
+// Read back the spec a LocalCloud container was created with, and its
+// instance type and resources since any resize
+func (m *Manager) Spec(containerID string) (*CreateSpec, error) {
+	containerJSON, err := m.client.ContainerInspect(context.Background(), containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	spec, ok := m.storedSpec(containerJSON.ID, containerJSON.Config.Labels)
+	if !ok {
+		return nil, fmt.Errorf("container %s was not created by LocalCloud", containerID)
+	}
+	return spec, nil
+}
+
 func (m *Manager) Delete(containerID string) error {
 	ctx := context.Background()
 
+	// Resize records are kept by full ID, and containerID may be a name
+	fullID := containerID
+	if containerJSON, err := m.client.ContainerInspect(ctx, containerID); err == nil {
+		fullID = containerJSON.ID
+	}
+
 	// Stop container if running
 	if err := m.client.ContainerStop(ctx, containerID, container.StopOptions{}); err != nil {
 		// Continue even if stop fails (container might already be stopped)
 	}
 
 	// Remove container, clean up
-	return m.client.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true})
+	if err := m.client.ContainerRemove(ctx, containerID, types.ContainerRemoveOptions{Force: true}); err != nil {
+		return err
+	}
+	m.forgetResize(fullID)
+	return nil
+}
+
+// Swap an instance for a new one created from spec under its name. The old
+// instance is renamed and stopped to free its name and ports, and removed
+// once the new one exists; if the new one can't be created, the old one is
+// put back as it was.
+func (m *Manager) Replace(containerID string, spec CreateSpec) (*Instance, error) {
+	ctx := context.Background()
+	containerJSON, err := m.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+	name := strings.TrimPrefix(containerJSON.Name, "/")
+	running := containerJSON.State != nil && containerJSON.State.Running
+
+	if err := m.client.ContainerRename(ctx, containerJSON.ID, name+"-replaced-"+containerJSON.ID[:12]); err != nil {
+		return nil, fmt.Errorf("failed to rename container: %w", err)
+	}
+	if running {
+		if err := m.client.ContainerStop(ctx, containerJSON.ID, container.StopOptions{}); err != nil {
+			m.client.ContainerRename(ctx, containerJSON.ID, name)
+			return nil, fmt.Errorf("failed to stop container: %w", err)
+		}
+	}
+
+	instance, err := m.Create(spec)
+	if err != nil {
+		m.client.ContainerRename(ctx, containerJSON.ID, name)
+		if running {
+			m.client.ContainerStart(ctx, containerJSON.ID, types.ContainerStartOptions{})
+		}
+		return nil, fmt.Errorf("failed to create replacement: %w", err)
+	}
+
+	if err := m.Delete(containerJSON.ID); err != nil {
+		log.Printf("Replaced %s, but failed to remove the old instance: %v", name, err)
+	}
+	return instance, nil
+}
+
+// Stop a container, keeping it so it can be started again
+func (m *Manager) Stop(containerID string) error {
+	if err := m.client.ContainerStop(context.Background(), containerID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) Restart(containerID string) error {
+	ctx := context.Background()
+	if err := m.client.ContainerRestart(ctx, containerID, container.StopOptions{}); err != nil {
+		return fmt.Errorf("failed to restart container: %w", err)
+	}
+
+	// Secret files live in the container's tmpfs and are lost on restart
+	if spec, err := m.Spec(containerID); err == nil && len(spec.Secrets) > 0 {
+		if err := m.restoreSecretFiles(ctx, containerID, *spec); err != nil {
+			return fmt.Errorf("failed to restore secret files: %w", err)
+		}
+	}
+	return nil
 }
 
 func (m *Manager) Exec(containerID, command string) (string, error) {
@@ -173,6 +460,228 @@ func (m *Manager) Exec(containerID, command string) (string, error) {
 	return string(output), nil
 }
 
+// Run a command and report its exit code as well as output
+func (m *Manager) execWithExitCode(ctx context.Context, containerID string, cmd []string) (string, int, error) {
+	execConfig := types.ExecConfig{
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	execID, err := m.client.ContainerExecCreate(ctx, containerID, execConfig)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+	resp, err := m.client.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to attach exec: %w", err)
+	}
+	defer resp.Close()
+
+	output, err := io.ReadAll(resp.Reader)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := m.client.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+
+	return string(output), inspect.ExitCode, nil
+}
+
+// Run a command with stdin and stdout attached to the given streams (either
+// may be nil), returning stderr and the exit code
+func (m *Manager) ExecIO(ctx context.Context, containerID string, cmd, env []string, stdin io.Reader, stdout io.Writer) (string, int, error) {
+	return m.execAs(ctx, containerID, "", cmd, env, stdin, stdout)
+}
+
+// ExecIO as the given user, the container's default user if empty
+func (m *Manager) execAs(ctx context.Context, containerID, user string, cmd, env []string, stdin io.Reader, stdout io.Writer) (string, int, error) {
+	execConfig := types.ExecConfig{
+		User:         user,
+		Cmd:          cmd,
+		Env:          env,
+		AttachStdin:  stdin != nil,
+		AttachStdout: true,
+		AttachStderr: true,
+	}
+	execID, err := m.client.ContainerExecCreate(ctx, containerID, execConfig)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create exec: %w", err)
+	}
+	resp, err := m.client.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to attach exec: %w", err)
+	}
+	defer resp.Close()
+
+	if stdin != nil {
+		go func() {
+			io.Copy(resp.Conn, stdin)
+			resp.CloseWrite()
+		}()
+	}
+	if stdout == nil {
+		stdout = io.Discard
+	}
+
+	// Without a TTY stdout and stderr are multiplexed on one stream
+	var stderr strings.Builder
+	if _, err := stdcopy.StdCopy(stdout, &stderr, resp.Reader); err != nil {
+		return stderr.String(), 0, fmt.Errorf("failed to read exec output: %w", err)
+	}
+
+	inspect, err := m.client.ContainerExecInspect(ctx, execID.ID)
+	if err != nil {
+		return stderr.String(), 0, fmt.Errorf("failed to inspect exec: %w", err)
+	}
+
+	return stderr.String(), inspect.ExitCode, nil
+}
+
+// Build an image from a tar build context, returning the build output
+func (m *Manager) BuildImage(buildContext io.Reader, tag, dockerfile string) (string, error) {
+	ctx := context.Background()
+
+	resp, err := m.client.ImageBuild(ctx, buildContext, types.ImageBuildOptions{
+		Tags:        []string{tag},
+		Dockerfile:  dockerfile,
+		Remove:      true,
+		ForceRemove: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to start build: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Build output is a stream of JSON messages
+	var output strings.Builder
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Stream string `json:"stream"`
+			Error  string `json:"error"`
+		}
+		if err := decoder.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			return output.String(), fmt.Errorf("failed to read build output: %w", err)
+		}
+
+		output.WriteString(msg.Stream)
+		if msg.Error != "" {
+			return output.String(), fmt.Errorf("build failed: %s", msg.Error)
+		}
+	}
+
+	return output.String(), nil
+}
+
+func (m *Manager) PullImage(image string) error {
+	return m.pullImage(context.Background(), image, nil)
+}
+
+func (m *Manager) RemoveImage(image string) error {
+	_, err := m.client.ImageRemove(context.Background(), image, types.ImageRemoveOptions{Force: true, PruneChildren: true})
+	if err != nil {
+		return fmt.Errorf("failed to remove image: %w", err)
+	}
+	return nil
+}
+
+// Write images and their layers as a tar, as `docker save` does
+func (m *Manager) SaveImages(images []string, w io.Writer) error {
+	reader, err := m.client.ImageSave(context.Background(), images)
+	if err != nil {
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to save images: %w", err)
+	}
+	return nil
+}
+
+// Load images from a tar written by SaveImages
+func (m *Manager) LoadImages(r io.Reader) error {
+	resp, err := m.client.ImageLoad(context.Background(), r, true)
+	if err != nil {
+		return fmt.Errorf("failed to load images: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Errors during the load are reported in the response stream
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg struct {
+			Error string `json:"error"`
+		}
+		if err := decoder.Decode(&msg); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read load output: %w", err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("failed to load images: %s", msg.Error)
+		}
+	}
+}
+
+func (m *Manager) RemoveVolume(name string) error {
+	if err := m.client.VolumeRemove(context.Background(), name, true); err != nil {
+		return fmt.Errorf("failed to remove volume: %w", err)
+	}
+	return nil
+}
+
+// Block until a container stops, returning its exit code
+func (m *Manager) Wait(ctx context.Context, containerID string) (int, error) {
+	statusCh, errCh := m.client.ContainerWait(ctx, containerID, container.WaitConditionNotRunning)
+	select {
+	case status := <-statusCh:
+		if status.Error != nil {
+			return int(status.StatusCode), fmt.Errorf("failed to wait for container: %s", status.Error.Message)
+		}
+		return int(status.StatusCode), nil
+	case err := <-errCh:
+		return 0, fmt.Errorf("failed to wait for container: %w", err)
+	}
+}
+
+// Stdout and stderr interleaved as plain text, keeping the last maxBytes
+func (m *Manager) Output(containerID string, maxBytes int) (string, error) {
+	reader, err := m.client.ContainerLogs(context.Background(), containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get logs: %w", err)
+	}
+	defer reader.Close()
+
+	out := &tailBuffer{max: maxBytes}
+	if _, err := stdcopy.StdCopy(out, out, reader); err != nil {
+		return "", fmt.Errorf("failed to read logs: %w", err)
+	}
+	return string(out.data), nil
+}
+
+// Writer that keeps only the last max bytes
+type tailBuffer struct {
+	data []byte
+	max  int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = append([]byte(nil), b.data[len(b.data)-b.max:]...)
+	}
+	return len(p), nil
+}
+
 func (m *Manager) GetLogs(containerID string, tail int) (string, error) {
 	ctx := context.Background()
 
@@ -196,6 +705,29 @@ func (m *Manager) GetLogs(containerID string, tail int) (string, error) {
 	return string(logs), nil
 }
 
+// The last tail lines of output as plain text, followed by new output
+// until the container stops, ctx is done or the reader is closed
+func (m *Manager) FollowLogs(ctx context.Context, containerID string, tail int) (io.ReadCloser, error) {
+	reader, err := m.client.ContainerLogs(ctx, containerID, types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+		Tail:       fmt.Sprintf("%d", tail),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get logs: %w", err)
+	}
+
+	// Docker multiplexes stdout and stderr into one framed stream
+	pr, pw := io.Pipe()
+	go func() {
+		defer reader.Close()
+		_, err := stdcopy.StdCopy(pw, pw, reader)
+		pw.CloseWithError(err)
+	}()
+	return pr, nil
+}
+
 func (m *Manager) GetMetrics(containerID string) (*Metrics, error) {
 	ctx := context.Background()
 	// Call stats API		
@@ -244,15 +776,34 @@ func (m *Manager) containerToInstance(c types.Container) Instance {
 		uptime = time.Since(created).Truncate(time.Second).String()
 	}
 
-	return Instance{
+	ip := ""
+	if c.NetworkSettings != nil {
+		for _, network := range c.NetworkSettings.Networks {
+			if network.IPAddress != "" {
+				ip = network.IPAddress
+				break
+			}
+		}
+	}
+
+	instance := Instance{
 		ID:      c.ID,
 		Name:    name,
 		Image:   c.Image,
 		Status:  c.Status,
+		State:   c.State,
 		Ports:   strings.TrimSpace(ports),
 		Created: time.Unix(c.Created, 0),
 		Uptime:  uptime,
+		Health:  m.healthStatus(c.ID),
+		IP:      ip,
+		Project: c.Labels[labelProject],
 	}
+	if c.Labels[labelManaged] == "true" {
+		spec, _ := specFromLabels(c.Labels)
+		instance.InstanceType = m.sizeOf(c.ID, spec).InstanceType
+	}
+	return instance
 }
 
 // similar to containerToInstance but used after creation
@@ -260,12 +811,19 @@ func (m *Manager) inspectToInstance(c types.ContainerJSON) *Instance {
 	name := strings.TrimPrefix(c.Name, "/")
 	
 	ports := ""
+	ip := ""
 	if c.NetworkSettings != nil {
 		for containerPort, bindings := range c.NetworkSettings.Ports {
 			for _, binding := range bindings {
 				ports += fmt.Sprintf("%s:%s ", binding.HostPort, containerPort.Port())
 			}
 		}
+		ip = c.NetworkSettings.IPAddress
+		for _, network := range c.NetworkSettings.Networks {
+			if ip == "" {
+				ip = network.IPAddress
+			}
+		}
 	}
 
 	created, _ := time.Parse(time.RFC3339Nano, c.Created)
@@ -275,35 +833,73 @@ func (m *Manager) inspectToInstance(c types.ContainerJSON) *Instance {
 		uptime = time.Since(created).Truncate(time.Second).String()
 	}
 
-	return &Instance{
+	instance := &Instance{
 		ID:      c.ID,
 		Name:    name,
 		Image:   c.Config.Image,
 		Status:  c.State.Status,
+		State:   c.State.Status,
 		Ports:   strings.TrimSpace(ports),
 		Created: created,
 		Uptime:  uptime,
+		Health:  m.healthStatus(c.ID),
+		IP:      ip,
+		Project: c.Config.Labels[labelProject],
+	}
+	if c.Config.Labels[labelManaged] == "true" {
+		spec, _ := specFromLabels(c.Config.Labels)
+		instance.InstanceType = m.sizeOf(c.ID, spec).InstanceType
 	}
+	return instance
 }
 
-// Parse input port mapping to docker formatting
-func parsePortMapping(mapping string) (nat.PortMap, nat.PortSet, error) {
-	parts := strings.Split(mapping, ":")
-	if len(parts) != 2 {
-		return nil, nil, fmt.Errorf("port mapping must be in format host:container")
+// Address LocalCloud can reach a container port on: the published host
+// port if there is one, otherwise the container's own IP
+func (m *Manager) Address(ctx context.Context, containerID string, port int) (string, error) {
+	containerJSON, err := m.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return "", fmt.Errorf("failed to inspect container: %w", err)
 	}
 
-	containerPort := nat.Port(parts[1] + "/tcp")
-	portBindings := nat.PortMap{
-		containerPort: []nat.PortBinding{
-			{HostIP: "0.0.0.0", HostPort: parts[0]},
-		},
+	settings := containerJSON.NetworkSettings
+	if settings == nil {
+		return "", fmt.Errorf("container has no network settings")
 	}
-	exposedPorts := nat.PortSet{
-		containerPort: struct{}{},
+
+	containerPort := nat.Port(strconv.Itoa(port) + "/tcp")
+	for _, binding := range settings.Ports[containerPort] {
+		if binding.HostPort != "" {
+			host := binding.HostIP
+			if isWildcard(host) {
+				host = "127.0.0.1"
+			}
+			return net.JoinHostPort(host, binding.HostPort), nil
+		}
 	}
 
-	return portBindings, exposedPorts, nil
+	ip := settings.IPAddress
+	for _, network := range settings.Networks {
+		if ip == "" {
+			ip = network.IPAddress
+		}
+	}
+	if ip == "" {
+		return "", fmt.Errorf("container has no reachable address")
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(port)), nil
+}
+
+// Decode the spec label, if the container has one
+func specFromLabels(labels map[string]string) (*CreateSpec, bool) {
+	raw, ok := labels[labelSpec]
+	if !ok {
+		return nil, false
+	}
+	var spec CreateSpec
+	if err := json.Unmarshal([]byte(raw), &spec); err != nil {
+		return nil, false
+	}
+	return &spec, true
 }
 
 func calculateCPUPercent(stats *types.StatsJSON) float64 {
@@ -0,0 +1,39 @@
+package compute
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"localcloud/internal/dockertest"
+)
+
+// A Manager talking to a fake Docker daemon
+func newTestManager(t *testing.T) (*Manager, *dockertest.Server) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	m, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, docker
+}
+
+func TestWaitAndOutput(t *testing.T) {
+	m, docker := newTestManager(t)
+	id := docker.AddContainer(dockertest.Container{Name: "job", Image: "busybox", ExitCode: 3, Output: "line 1\nline 2\n"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := m.Wait(ctx, id); err == nil {
+		t.Error("wait returned while the container was running")
+	}
+
+	docker.SetState(id, "exited")
+	if code, err := m.Wait(context.Background(), id); err != nil || code != 3 {
+		t.Errorf("Wait = %d, %v", code, err)
+	}
+	if out, err := m.Output(id, 7); err != nil || out != "line 2\n" {
+		t.Errorf("Output = %q, %v", out, err)
+	}
+}
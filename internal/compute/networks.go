@@ -0,0 +1,84 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+)
+
+// User-defined Docker network
+type Network struct {
+	Name     string `json:"name"`
+	Driver   string `json:"driver"`
+	Internal bool   `json:"internal,omitempty"`
+	Subnet   string `json:"subnet,omitempty"`
+	Gateway  string `json:"gateway,omitempty"`
+}
+
+// Networks Docker creates itself, which every host has
+var builtinNetworks = map[string]bool{"bridge": true, "host": true, "none": true}
+
+// User-defined networks a container is attached to
+func (m *Manager) Networks(containerID string) ([]Network, error) {
+	ctx := context.Background()
+
+	containerJSON, err := m.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	var networks []Network
+	if containerJSON.NetworkSettings == nil {
+		return networks, nil
+	}
+	for name := range containerJSON.NetworkSettings.Networks {
+		if builtinNetworks[name] {
+			continue
+		}
+		resource, err := m.client.NetworkInspect(ctx, name, types.NetworkInspectOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect network: %w", err)
+		}
+		n := Network{Name: resource.Name, Driver: resource.Driver, Internal: resource.Internal}
+		if len(resource.IPAM.Config) > 0 {
+			n.Subnet = resource.IPAM.Config[0].Subnet
+			n.Gateway = resource.IPAM.Config[0].Gateway
+		}
+		networks = append(networks, n)
+	}
+	sort.Slice(networks, func(i, j int) bool { return networks[i].Name < networks[j].Name })
+	return networks, nil
+}
+
+func (m *Manager) NetworkExists(name string) (bool, error) {
+	_, err := m.client.NetworkInspect(context.Background(), name, types.NetworkInspectOptions{})
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect network: %w", err)
+	}
+	return true, nil
+}
+
+func (m *Manager) CreateNetwork(n Network) error {
+	options := types.NetworkCreate{Driver: n.Driver, Internal: n.Internal}
+	if n.Subnet != "" {
+		options.IPAM = &network.IPAM{Config: []network.IPAMConfig{{Subnet: n.Subnet, Gateway: n.Gateway}}}
+	}
+	if _, err := m.client.NetworkCreate(context.Background(), n.Name, options); err != nil {
+		return fmt.Errorf("failed to create network: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) ConnectNetwork(containerID, name string) error {
+	if err := m.client.NetworkConnect(context.Background(), name, containerID, nil); err != nil {
+		return fmt.Errorf("failed to connect to network %s: %w", name, err)
+	}
+	return nil
+}
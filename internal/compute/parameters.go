@@ -0,0 +1,97 @@
+package compute
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Env vars to fill from the parameter store when an instance is created:
+// one parameter into Env, or every parameter under Path with Env as a prefix
+// (/app/prod/db/host under /app/prod becomes DB_HOST)
+type ParameterRef struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+	Env  string `json:"env,omitempty"`
+}
+
+// Looks up parameter values when instances are created
+type ParameterResolver interface {
+	ParameterValue(name string) (string, error)
+	ParametersByPath(path string) (map[string]string, error)
+}
+
+// Resolve parameters referenced by specs created from now on
+func (m *Manager) UseParameters(resolver ParameterResolver) {
+	m.parameters = resolver
+}
+
+func validateParameterRefs(refs []ParameterRef) error {
+	for _, ref := range refs {
+		switch {
+		case ref.Name != "" && ref.Path != "":
+			return fmt.Errorf("parameter reference needs a name or a path, not both")
+		case ref.Name != "":
+			if !validEnvName.MatchString(ref.Env) {
+				return fmt.Errorf("parameter %s: invalid env var name %q", ref.Name, ref.Env)
+			}
+		case ref.Path != "":
+			if ref.Env != "" && !validEnvName.MatchString(ref.Env) {
+				return fmt.Errorf("parameter path %s: invalid env var prefix %q", ref.Path, ref.Env)
+			}
+		default:
+			return fmt.Errorf("parameter reference needs a name or a path")
+		}
+	}
+	return nil
+}
+
+// Env vars (KEY=value) for the parameters a spec references
+func (m *Manager) resolveParameters(refs []ParameterRef) ([]string, error) {
+	if len(refs) == 0 {
+		return nil, nil
+	}
+	if m.parameters == nil {
+		return nil, fmt.Errorf("parameters are only available through the LocalCloud server")
+	}
+
+	var env []string
+	for _, ref := range refs {
+		if ref.Name != "" {
+			value, err := m.parameters.ParameterValue(ref.Name)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve parameter %s: %w", ref.Name, err)
+			}
+			env = append(env, ref.Env+"="+value)
+			continue
+		}
+
+		values, err := m.parameters.ParametersByPath(ref.Path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve parameters under %s: %w", ref.Path, err)
+		}
+		names := make([]string, 0, len(values))
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			relative := strings.TrimPrefix(name, strings.TrimSuffix(ref.Path, "/")+"/")
+			env = append(env, ref.Env+envName(relative)+"="+values[name])
+		}
+	}
+	return env, nil
+}
+
+// db/host-name becomes DB_HOST_NAME
+func envName(relative string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, relative)
+}
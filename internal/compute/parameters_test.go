@@ -0,0 +1,72 @@
+package compute
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Parameter values by name
+type mapParameters map[string]string
+
+func (p mapParameters) ParameterValue(name string) (string, error) {
+	value, ok := p[name]
+	if !ok {
+		return "", fmt.Errorf("parameter %s not found", name)
+	}
+	return value, nil
+}
+
+func (p mapParameters) ParametersByPath(path string) (map[string]string, error) {
+	values := make(map[string]string)
+	for name, value := range p {
+		if strings.HasPrefix(name, path+"/") {
+			values[name] = value
+		}
+	}
+	return values, nil
+}
+
+func TestValidateParameterRefs(t *testing.T) {
+	tests := []struct {
+		refs []ParameterRef
+		ok   bool
+	}{
+		{[]ParameterRef{{Name: "/app/mode", Env: "MODE"}, {Path: "/app/db"}, {Path: "/app/db", Env: "DB_"}}, true},
+		{[]ParameterRef{{Name: "/app/mode", Path: "/app"}}, false},
+		{[]ParameterRef{{Name: "/app/mode"}}, false},
+		{[]ParameterRef{{Path: "/app", Env: "1"}}, false},
+		{[]ParameterRef{{}}, false},
+	}
+	for _, tt := range tests {
+		if err := validateParameterRefs(tt.refs); (err == nil) != tt.ok {
+			t.Errorf("validateParameterRefs(%+v) = %v, want ok %v", tt.refs, err, tt.ok)
+		}
+	}
+}
+
+func TestCreateWithParameters(t *testing.T) {
+	m, docker := newTestManager(t)
+	spec := CreateSpec{
+		Image:      "nginx",
+		Env:        []string{"MODE=prod"},
+		Parameters: []ParameterRef{{Name: "/app/log-level", Env: "LOG_LEVEL"}, {Path: "/app/db", Env: "DB_"}},
+	}
+	if _, err := m.Create(spec); err == nil {
+		t.Error("resolved parameters without a parameter store")
+	}
+
+	m.UseParameters(mapParameters{
+		"/app/log-level":      "debug",
+		"/app/db/host":        "db.internal",
+		"/app/db/pool/max-io": "8",
+	})
+	if _, err := m.Create(spec); err != nil {
+		t.Fatal(err)
+	}
+	env := docker.Containers()[0].Config.Env
+	want := "MODE=prod,LOG_LEVEL=debug,DB_HOST=db.internal,DB_POOL_MAX_IO=8"
+	if strings.Join(env, ",") != want {
+		t.Errorf("env = %v, want %s", env, want)
+	}
+}
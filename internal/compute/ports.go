@@ -0,0 +1,348 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/go-connections/nat"
+)
+
+var (
+	ErrPortInUse   = errors.New("host port is already in use")
+	ErrNoFreePorts = errors.New("no free host port")
+)
+
+// Defaults for mappings that do not name a host port or bind address
+const (
+	DefaultPortRange   = "20000-29999"
+	DefaultBindAddress = "127.0.0.1"
+)
+
+// One published port. HostPort is a port, a range like 8000-8100, or
+// empty/"auto" for any free port in the allocator's range.
+type PortMapping struct {
+	HostIP        string `json:"host_ip,omitempty"`
+	HostPort      string `json:"host_port"`
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol"`
+}
+
+// Whether the mapping names a single host port
+func (p PortMapping) Fixed() bool {
+	_, err := strconv.Atoi(p.HostPort)
+	return err == nil
+}
+
+// Parse a comma separated list of [bind:]host:container[/proto] mappings,
+// e.g. "8080:80", "auto:80", "0.0.0.0:8000-8100:80/udp" or ":80"
+func ParsePorts(spec string) ([]PortMapping, error) {
+	var mappings []PortMapping
+	for _, field := range strings.FieldsFunc(spec, func(r rune) bool { return r == ',' || r == ' ' }) {
+		mapping, err := parsePortMapping(field)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", field, err)
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+func parsePortMapping(field string) (PortMapping, error) {
+	mapping := PortMapping{Protocol: "tcp"}
+	if rest, proto, ok := strings.Cut(field, "/"); ok {
+		if proto != "tcp" && proto != "udp" {
+			return mapping, fmt.Errorf("protocol must be tcp or udp")
+		}
+		field, mapping.Protocol = rest, proto
+	}
+
+	sep := strings.LastIndex(field, ":")
+	if sep < 0 {
+		return mapping, fmt.Errorf("port mapping must be in format [bind:]host:container")
+	}
+	port, err := strconv.Atoi(field[sep+1:])
+	if err != nil || port < 1 || port > 65535 {
+		return mapping, fmt.Errorf("invalid container port %q", field[sep+1:])
+	}
+	mapping.ContainerPort = port
+
+	field = field[:sep]
+	if sep := strings.LastIndex(field, ":"); sep >= 0 {
+		ip := strings.TrimSuffix(strings.TrimPrefix(field[:sep], "["), "]")
+		if net.ParseIP(ip) == nil {
+			return mapping, fmt.Errorf("invalid bind address %q", ip)
+		}
+		mapping.HostIP, field = ip, field[sep+1:]
+	}
+
+	if field != "" && field != "auto" {
+		if _, _, err := portRange(field); err != nil {
+			return mapping, err
+		}
+	}
+	mapping.HostPort = field
+	return mapping, nil
+}
+
+// Bounds of "8080" or "8000-8100"
+func portRange(value string) (int, int, error) {
+	low, high, isRange := strings.Cut(value, "-")
+	lo, err1 := strconv.Atoi(low)
+	hi, err2 := lo, error(nil)
+	if isRange {
+		hi, err2 = strconv.Atoi(high)
+	}
+	if err1 != nil || err2 != nil || lo < 1 || hi > 65535 || lo > hi {
+		return 0, 0, fmt.Errorf("invalid host port %q", value)
+	}
+	return lo, hi, nil
+}
+
+// Hands out host ports. Ports are reserved between picking them and the
+// container starting, so concurrent creates never pick the same one, and
+// for as long as a load balancer listens on them.
+type portAllocator struct {
+	mu       sync.Mutex
+	reserved map[string]string // proto/port -> holder
+	low      int
+	high     int
+	bindIP   string
+}
+
+func newPortAllocator() *portAllocator {
+	low, high, _ := portRange(DefaultPortRange)
+	return &portAllocator{
+		reserved: make(map[string]string),
+		low:      low,
+		high:     high,
+		bindIP:   DefaultBindAddress,
+	}
+}
+
+// Range auto ports are picked from and the address ports are bound to
+// when a mapping does not name one
+func (m *Manager) UsePorts(portRangeSpec, bindAddress string) error {
+	low, high, err := portRange(portRangeSpec)
+	if err != nil {
+		return fmt.Errorf("invalid port range: %w", err)
+	}
+	if net.ParseIP(bindAddress) == nil {
+		return fmt.Errorf("invalid bind address %q", bindAddress)
+	}
+
+	m.ports.mu.Lock()
+	defer m.ports.mu.Unlock()
+	m.ports.low, m.ports.high, m.ports.bindIP = low, high, bindAddress
+	return nil
+}
+
+// Pick host ports for the mappings. release drops the reservations once
+// the container has started (Docker then holds the ports) or failed.
+func (m *Manager) allocatePorts(ctx context.Context, mappings []PortMapping) (nat.PortMap, nat.PortSet, func(), error) {
+	a := m.ports
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	var picked []string
+	unreserve := func() {
+		for _, key := range picked {
+			delete(a.reserved, key)
+		}
+	}
+	release := func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		unreserve()
+	}
+
+	portBindings := nat.PortMap{}
+	exposedPorts := nat.PortSet{}
+	for _, mapping := range mappings {
+		hostIP := mapping.HostIP
+		if hostIP == "" {
+			hostIP = a.bindIP
+		}
+
+		low, high := a.low, a.high
+		if mapping.HostPort != "" && mapping.HostPort != "auto" {
+			low, high, _ = portRange(mapping.HostPort)
+		}
+
+		port, holder := 0, ""
+		for candidate := low; candidate <= high; candidate++ {
+			key := mapping.Protocol + "/" + strconv.Itoa(candidate)
+			if reservedBy, ok := a.reserved[key]; ok {
+				holder = reservedBy
+				continue
+			}
+			if name := portHolder(containers, hostIP, candidate, mapping.Protocol); name != "" {
+				holder = "container " + name
+				continue
+			}
+			if !hostPortFree(hostIP, candidate, mapping.Protocol) {
+				holder = "another process"
+				continue
+			}
+			port = candidate
+			a.reserved[key] = "a container being created"
+			picked = append(picked, key)
+			break
+		}
+		if port == 0 {
+			// a.mu is already held here
+			unreserve()
+			if mapping.Fixed() {
+				return nil, nil, nil, fmt.Errorf("%w: %s:%s/%s is held by %s", ErrPortInUse, hostIP, mapping.HostPort, mapping.Protocol, holder)
+			}
+			return nil, nil, nil, fmt.Errorf("%w in %d-%d for %s", ErrNoFreePorts, low, high, hostIP)
+		}
+
+		containerPort := nat.Port(fmt.Sprintf("%d/%s", mapping.ContainerPort, mapping.Protocol))
+		portBindings[containerPort] = append(portBindings[containerPort], nat.PortBinding{
+			HostIP:   hostIP,
+			HostPort: strconv.Itoa(port),
+		})
+		exposedPorts[containerPort] = struct{}{}
+	}
+	return portBindings, exposedPorts, release, nil
+}
+
+// Address ports are bound to when a mapping does not name one
+func (m *Manager) BindAddress() string {
+	m.ports.mu.Lock()
+	defer m.ports.mu.Unlock()
+	return m.ports.bindIP
+}
+
+// Keep a host port away from containers until release is called. holder
+// names the user in errors, e.g. "load balancer web".
+func (m *Manager) ReservePort(ctx context.Context, port int, proto, holder string) (func(), error) {
+	a := m.ports
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := proto + "/" + strconv.Itoa(port)
+	if reservedBy, ok := a.reserved[key]; ok {
+		return nil, fmt.Errorf("%w: %d/%s is held by %s", ErrPortInUse, port, proto, reservedBy)
+	}
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	if name := portHolder(containers, a.bindIP, port, proto); name != "" {
+		return nil, fmt.Errorf("%w: %d/%s is held by container %s", ErrPortInUse, port, proto, name)
+	}
+
+	a.reserved[key] = holder
+	release := func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if a.reserved[key] == holder {
+			delete(a.reserved, key)
+		}
+	}
+	return release, nil
+}
+
+// Name of a running container publishing the port on an overlapping address
+func portHolder(containers []types.Container, hostIP string, port int, proto string) string {
+	for _, c := range containers {
+		for _, p := range c.Ports {
+			if int(p.PublicPort) != port || p.Type != proto {
+				continue
+			}
+			if p.IP == hostIP || isWildcard(p.IP) || isWildcard(hostIP) {
+				if len(c.Names) > 0 {
+					return strings.TrimPrefix(c.Names[0], "/")
+				}
+				return c.ID[:12]
+			}
+		}
+	}
+	return ""
+}
+
+func isWildcard(ip string) bool {
+	return ip == "" || ip == "0.0.0.0" || ip == "::"
+}
+
+// Whether nothing on this host listens on the port. Only "address in use"
+// counts; other errors, e.g. a bind address that belongs to a remote
+// Docker host, leave the decision to Docker.
+func hostPortFree(hostIP string, port int, proto string) bool {
+	addr := net.JoinHostPort(hostIP, strconv.Itoa(port))
+	var err error
+	if proto == "udp" {
+		var conn net.PacketConn
+		if conn, err = net.ListenPacket("udp", addr); err == nil {
+			conn.Close()
+		}
+	} else {
+		var listener net.Listener
+		if listener, err = net.Listen("tcp", addr); err == nil {
+			listener.Close()
+		}
+	}
+	return !errors.Is(err, syscall.EADDRINUSE)
+}
+
+// A host port published by a running container
+type PortUsage struct {
+	HostIP        string `json:"host_ip"`
+	HostPort      int    `json:"host_port"`
+	ContainerPort int    `json:"container_port"`
+	Protocol      string `json:"protocol"`
+	ContainerID   string `json:"container_id"`
+	ContainerName string `json:"container_name"`
+	Managed       bool   `json:"managed"` // created by LocalCloud
+}
+
+// Published ports of all running containers, by host port
+func (m *Manager) PortUsage() ([]PortUsage, error) {
+	containers, err := m.client.ContainerList(context.Background(), types.ContainerListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+
+	usage := []PortUsage{}
+	for _, c := range containers {
+		name := c.ID[:12]
+		if len(c.Names) > 0 {
+			name = strings.TrimPrefix(c.Names[0], "/")
+		}
+		for _, p := range c.Ports {
+			if p.PublicPort == 0 {
+				continue
+			}
+			usage = append(usage, PortUsage{
+				HostIP:        p.IP,
+				HostPort:      int(p.PublicPort),
+				ContainerPort: int(p.PrivatePort),
+				Protocol:      p.Type,
+				ContainerID:   c.ID,
+				ContainerName: name,
+				Managed:       c.Labels[labelManaged] == "true",
+			})
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool {
+		if usage[i].HostPort != usage[j].HostPort {
+			return usage[i].HostPort < usage[j].HostPort
+		}
+		return usage[i].HostIP < usage[j].HostIP
+	})
+	return usage, nil
+}
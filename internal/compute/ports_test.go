@@ -0,0 +1,232 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/go-connections/nat"
+)
+
+func TestParsePorts(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []PortMapping
+	}{
+		{"", nil},
+		{"8080:80", []PortMapping{{HostPort: "8080", ContainerPort: 80, Protocol: "tcp"}}},
+		{":80", []PortMapping{{HostPort: "", ContainerPort: 80, Protocol: "tcp"}}},
+		{"auto:53/udp", []PortMapping{{HostPort: "auto", ContainerPort: 53, Protocol: "udp"}}},
+		{"0.0.0.0:8000-8100:80", []PortMapping{{HostIP: "0.0.0.0", HostPort: "8000-8100", ContainerPort: 80, Protocol: "tcp"}}},
+		{"[::1]:8080:80", []PortMapping{{HostIP: "::1", HostPort: "8080", ContainerPort: 80, Protocol: "tcp"}}},
+		{"8080:80, 8443:443", []PortMapping{
+			{HostPort: "8080", ContainerPort: 80, Protocol: "tcp"},
+			{HostPort: "8443", ContainerPort: 443, Protocol: "tcp"},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := ParsePorts(tt.spec)
+		if err != nil {
+			t.Errorf("ParsePorts(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParsePorts(%q) = %+v, want %+v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParsePortsRejects(t *testing.T) {
+	tests := []string{
+		"80",
+		"8080:0",
+		"8080:65536",
+		"8080:http",
+		"8080:80/sctp",
+		"0:80",
+		"70000:80",
+		"8100-8000:80",
+		"8000-:80",
+		"localhost:8080:80",
+		"8080:80,90",
+	}
+	for _, spec := range tests {
+		if _, err := ParsePorts(spec); err == nil {
+			t.Errorf("ParsePorts(%q) succeeded, want error", spec)
+		}
+	}
+}
+
+// Host port each mapping was given
+func hostPorts(t *testing.T, m *Manager, spec string) ([]string, func(), error) {
+	t.Helper()
+	mappings, err := ParsePorts(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bindings, _, release, err := m.allocatePorts(context.Background(), mappings)
+	if err != nil {
+		return nil, nil, err
+	}
+	var ports []string
+	for _, mapping := range mappings {
+		for _, b := range bindings[natPort(mapping)] {
+			ports = append(ports, b.HostIP+":"+b.HostPort)
+		}
+	}
+	return ports, release, nil
+}
+
+func natPort(mapping PortMapping) nat.Port {
+	return nat.Port(strconv.Itoa(mapping.ContainerPort) + "/" + mapping.Protocol)
+}
+
+func TestAllocatePorts(t *testing.T) {
+	// a container publishing 23001 on every address
+	m, docker := newTestManager(t)
+	docker.AddContainer(dockertest.Container{
+		Name:  "web",
+		Image: "nginx",
+		Ports: nat.PortMap{"80/tcp": {{HostIP: "0.0.0.0", HostPort: "23001"}}},
+	})
+	if err := m.UsePorts("23010-23011", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		spec string
+		want []string
+		err  error
+	}{
+		{"23000:80", []string{"127.0.0.1:23000"}, nil},
+		{"23001:80", nil, ErrPortInUse},
+		{"23001:80/udp", []string{"127.0.0.1:23001"}, nil},
+		{"23001-23002:80", []string{"127.0.0.1:23002"}, nil},
+		{"23001-23001:80", nil, ErrNoFreePorts},
+		{"auto:80", []string{"127.0.0.1:23010"}, nil},
+		{":80", []string{"127.0.0.1:23010"}, nil},
+		{"0.0.0.0:auto:80", []string{"0.0.0.0:23010"}, nil},
+	}
+	for _, tt := range tests {
+		got, release, err := hostPorts(t, m, tt.spec)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%q: error %v, want %v", tt.spec, err, tt.err)
+			continue
+		}
+		if err == nil {
+			release()
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestAllocatePortsReserves(t *testing.T) {
+	m, _ := newTestManager(t)
+	if err := m.UsePorts("23020-23021", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	first, releaseFirst, err := hostPorts(t, m, "auto:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, releaseSecond, err := hostPorts(t, m, "auto:80")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first[0] == second[0] {
+		t.Fatalf("both creates were given %s", first[0])
+	}
+	if _, _, err := hostPorts(t, m, "auto:80"); !errors.Is(err, ErrNoFreePorts) {
+		t.Fatalf("third create: error %v, want %v", err, ErrNoFreePorts)
+	}
+	// a mapping that fails gives back what the earlier ones picked
+	releaseSecond()
+	if _, _, err := hostPorts(t, m, "auto:80,auto:81"); !errors.Is(err, ErrNoFreePorts) {
+		t.Fatalf("two more: error %v, want %v", err, ErrNoFreePorts)
+	}
+	if got, _, err := hostPorts(t, m, "auto:80"); err != nil || got[0] != second[0] {
+		t.Fatalf("after release: got %v, %v, want %s", got, err, second[0])
+	}
+	releaseFirst()
+}
+
+func TestAllocatePortsSkipsListeners(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	m, _ := newTestManager(t)
+	spec := strconv.Itoa(port) + ":80"
+	if _, _, err := hostPorts(t, m, spec); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("%s: error %v, want %v", spec, err, ErrPortInUse)
+	}
+}
+
+func TestReservePort(t *testing.T) {
+	m, docker := newTestManager(t)
+	docker.AddContainer(dockertest.Container{
+		Name:  "web",
+		Image: "nginx",
+		Ports: nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: "23040"}}},
+	})
+	if _, err := m.ReservePort(context.Background(), 23040, "tcp", "load balancer web"); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("port of a container: error %v, want %v", err, ErrPortInUse)
+	}
+
+	release, err := m.ReservePort(context.Background(), 23041, "tcp", "load balancer api")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := hostPorts(t, m, "23041:80"); err == nil || !strings.Contains(err.Error(), "load balancer api") {
+		t.Errorf("reserved port: error %v", err)
+	}
+	if _, err := m.ReservePort(context.Background(), 23041, "tcp", "load balancer other"); !errors.Is(err, ErrPortInUse) {
+		t.Errorf("reserved twice: error %v", err)
+	}
+	release()
+	if _, releasePorts, err := hostPorts(t, m, "23041:80"); err != nil {
+		t.Errorf("after release: %v", err)
+	} else {
+		releasePorts()
+	}
+}
+
+func TestCreatePublishesAllocatedPorts(t *testing.T) {
+	m, docker := newTestManager(t)
+	if err := m.UsePorts("23030-23031", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if _, err := m.Create(CreateSpec{Name: name, Image: "nginx", Ports: "auto:80"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var published []string
+	for _, c := range docker.Containers() {
+		for _, b := range c.HostConfig.PortBindings["80/tcp"] {
+			published = append(published, b.HostIP+":"+b.HostPort)
+		}
+	}
+	if !reflect.DeepEqual(published, []string{"127.0.0.1:23030", "127.0.0.1:23031"}) {
+		t.Errorf("published %v", published)
+	}
+	if _, err := m.Create(CreateSpec{Name: "c", Image: "nginx", Ports: "auto:80"}); !errors.Is(err, ErrNoFreePorts) {
+		t.Errorf("third create: %v", err)
+	}
+	if len(docker.Containers()) != 2 {
+		t.Error("a container was created without a port")
+	}
+}
@@ -0,0 +1,94 @@
+package compute
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+)
+
+// Told what a long-running call is doing and roughly how far along it is,
+// as a percentage
+type ProgressFunc func(step string, percent int)
+
+func (p ProgressFunc) orNoop() ProgressFunc {
+	if p == nil {
+		return func(string, int) {}
+	}
+	return p
+}
+
+// Map 0-100 onto from-to of p, always reporting step
+func (p ProgressFunc) scaled(step string, from, to int) ProgressFunc {
+	return func(_ string, percent int) {
+		p(step, from+(to-from)*percent/100)
+	}
+}
+
+// One line of the JSON stream Docker sends while pulling
+type pullMessage struct {
+	ID             string `json:"id"`
+	Status         string `json:"status"`
+	Error          string `json:"error"`
+	ProgressDetail struct {
+		Current int64 `json:"current"`
+		Total   int64 `json:"total"`
+	} `json:"progressDetail"`
+}
+
+func (m *Manager) pullImage(ctx context.Context, image string, progress ProgressFunc) error {
+	progress = progress.orNoop()
+
+	reader, err := m.client.ImagePull(ctx, image, types.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image: %w", err)
+	}
+	defer reader.Close()
+
+	// The pull runs until its progress stream is drained. Layers report
+	// their sizes as they start, so the total grows while pulling.
+	type layer struct{ current, total int64 }
+	layers := make(map[string]*layer)
+	decoder := json.NewDecoder(reader)
+	for {
+		var msg pullMessage
+		if err := decoder.Decode(&msg); err == io.EOF {
+			break
+		} else if err != nil {
+			if ctx.Err() != nil {
+				return fmt.Errorf("failed to pull image: %w", ctx.Err())
+			}
+			return fmt.Errorf("failed to read pull output: %w", err)
+		}
+		if msg.Error != "" {
+			return fmt.Errorf("failed to pull image: %s", msg.Error)
+		}
+		if msg.ID == "" || msg.Status != "Downloading" && msg.Status != "Pull complete" {
+			continue
+		}
+
+		l, ok := layers[msg.ID]
+		if !ok {
+			l = &layer{}
+			layers[msg.ID] = l
+		}
+		if msg.Status == "Pull complete" {
+			l.current = l.total
+		} else if msg.ProgressDetail.Total > 0 {
+			l.current, l.total = msg.ProgressDetail.Current, msg.ProgressDetail.Total
+		}
+
+		var current, total int64
+		for _, l := range layers {
+			current += l.current
+			total += l.total
+		}
+		if total > 0 {
+			progress("pulling image", int(current*100/total))
+		}
+	}
+	progress("pulling image", 100)
+	return nil
+}
@@ -0,0 +1,68 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type progressLog []string
+
+func (p *progressLog) record(step string, percent int) {
+	*p = append(*p, fmt.Sprintf("%s %d", step, percent))
+}
+
+func TestPullProgress(t *testing.T) {
+	m, docker := newTestManager(t)
+	docker.Handle("POST /images/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"status": "Pulling from library/nginx", "id": "latest"}
+{"status": "Downloading", "id": "a", "progressDetail": {"current": 50, "total": 100}}
+{"status": "Downloading", "id": "b", "progressDetail": {"current": 0, "total": 300}}
+{"status": "Pull complete", "id": "a"}
+{"status": "Downloading", "id": "b", "progressDetail": {"current": 300, "total": 300}}
+{"status": "Digest: sha256:abc"}
+`)
+	}))
+
+	var log progressLog
+	if err := m.pullImage(context.Background(), "nginx", log.record); err != nil {
+		t.Fatal(err)
+	}
+	want := "pulling image 50,pulling image 12,pulling image 25,pulling image 100,pulling image 100"
+	if got := strings.Join(log, ","); got != want {
+		t.Errorf("progress = %s, want %s", got, want)
+	}
+
+	// scaled into part of a longer operation
+	log = nil
+	progressFunc := ProgressFunc(log.record)
+	progressFunc.scaled("pulling image", 10, 70)("", 50)
+	if log[0] != "pulling image 40" {
+		t.Errorf("scaled = %v", log)
+	}
+}
+
+func TestPullError(t *testing.T) {
+	m, docker := newTestManager(t)
+	docker.Handle("POST /images/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"error": "manifest unknown"}`+"\n")
+	}))
+	if err := m.PullImage("nginx:missing"); err == nil || !strings.Contains(err.Error(), "manifest unknown") {
+		t.Errorf("PullImage = %v", err)
+	}
+}
+
+func TestCreateWithProgress(t *testing.T) {
+	m, _ := newTestManager(t)
+	var log progressLog
+	if _, err := m.CreateWithProgress(context.Background(), CreateSpec{Name: "web", Image: "nginx"}, log.record); err != nil {
+		t.Fatal(err)
+	}
+	want := "validating 5,creating container 10,starting container 85,inspecting container 95"
+	if got := strings.Join(log, ","); got != want {
+		t.Errorf("progress = %s, want %s", got, want)
+	}
+}
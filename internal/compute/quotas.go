@@ -0,0 +1,284 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// Project of instances and volumes created without one
+const DefaultProject = "default"
+
+// Label recording which project a container or volume counts against
+const labelProject = "localcloud.project"
+
+// Applied when a project has a CPU or memory quota and the spec sets no limit,
+// so every instance is accounted for with what it can actually use
+const (
+	DefaultQuotaCPUs     = 1.0
+	DefaultQuotaMemoryMB = 512
+)
+
+var (
+	ErrQuotaExceeded  = errors.New("quota exceeded")
+	ErrInvalidProject = errors.New("project names must be 1-63 lowercase letters, digits, - or _")
+)
+
+var projectNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+func ValidProjectName(name string) bool {
+	return projectNamePattern.MatchString(name)
+}
+
+// Limits for one project; zero means unlimited
+type Quota struct {
+	MaxInstances    int     `json:"max_instances,omitempty"`
+	MaxCPUs         float64 `json:"max_cpus,omitempty"`
+	MaxMemoryMB     int     `json:"max_memory_mb,omitempty"`
+	MaxVolumes      int     `json:"max_volumes,omitempty"`
+	MaxStorageBytes int64   `json:"max_storage_bytes,omitempty"`
+	MaxPorts        int     `json:"max_ports,omitempty"`
+}
+
+// What a project is using
+type Usage struct {
+	Instances    int     `json:"instances"`
+	CPUs         float64 `json:"cpus"`
+	MemoryMB     int     `json:"memory_mb"`
+	Volumes      int     `json:"volumes"`
+	StorageBytes int64   `json:"storage_bytes"`
+	Ports        int     `json:"ports"`
+
+	volumeNames map[string]bool
+}
+
+// Add other to u, or take it away with sign -1
+func (u *Usage) add(other *Usage, sign int) {
+	u.Instances += sign * other.Instances
+	u.CPUs += float64(sign) * other.CPUs
+	u.MemoryMB += sign * other.MemoryMB
+	u.Volumes += sign * other.Volumes
+	u.StorageBytes += int64(sign) * other.StorageBytes
+	u.Ports += sign * other.Ports
+}
+
+// Which limit a create would break
+type QuotaError struct {
+	Project   string
+	Resource  string
+	Limit     string
+	Used      string
+	Requested string
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s for project %s: %s limit is %s, %s in use, %s requested",
+		ErrQuotaExceeded, e.Project, e.Resource, e.Limit, e.Used, e.Requested)
+}
+
+func (e *QuotaError) Unwrap() error {
+	return ErrQuotaExceeded
+}
+
+// Looks up the quota for a project
+type QuotaSource interface {
+	Quota(project string) (Quota, bool)
+}
+
+// Enforce quotas on instances and volumes created from now on
+func (m *Manager) UseQuotas(source QuotaSource) {
+	m.quotas = source
+}
+
+func projectOf(spec *CreateSpec) string {
+	if spec.Project == "" {
+		return DefaultProject
+	}
+	return spec.Project
+}
+
+// Named volumes a spec mounts; host paths are not counted
+func specVolumes(spec *CreateSpec) []string {
+	var names []string
+	for _, bind := range spec.Volumes {
+		name, _, _ := strings.Cut(bind, ":")
+		if name != "" && !strings.HasPrefix(name, "/") && !strings.HasPrefix(name, ".") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Usage per project of every LocalCloud instance and volume. Storage is
+// only measured when withStorage is set since it asks Docker for disk usage.
+func (m *Manager) ProjectUsage(withStorage bool) (map[string]*Usage, error) {
+	ctx := context.Background()
+	usage := make(map[string]*Usage)
+	project := func(name string) *Usage {
+		if usage[name] == nil {
+			usage[name] = &Usage{volumeNames: make(map[string]bool)}
+		}
+		return usage[name]
+	}
+
+	containers, err := m.client.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Filters: filters.NewArgs(filters.Arg("label", labelManaged)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers: %w", err)
+	}
+	for _, c := range containers {
+		spec, ok := m.storedSpec(c.ID, c.Labels)
+		if !ok {
+			spec = &CreateSpec{}
+		}
+		u := project(projectOf(spec))
+		u.Instances++
+		u.CPUs += spec.CPUs
+		u.MemoryMB += spec.MemoryMB
+		if mappings, err := ParsePorts(spec.Ports); err == nil {
+			u.Ports += len(mappings)
+		}
+		for _, name := range specVolumes(spec) {
+			u.volumeNames[name] = true
+		}
+	}
+
+	volumes, err := m.client.VolumeList(ctx, volume.ListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", labelProject)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list volumes: %w", err)
+	}
+	for _, v := range volumes.Volumes {
+		project(v.Labels[labelProject]).volumeNames[v.Name] = true
+	}
+
+	var sizes map[string]int64
+	if withStorage {
+		disk, err := m.client.DiskUsage(ctx, types.DiskUsageOptions{Types: []types.DiskUsageObject{types.VolumeObject}})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get disk usage: %w", err)
+		}
+		sizes = make(map[string]int64)
+		for _, v := range disk.Volumes {
+			if v.UsageData != nil && v.UsageData.Size > 0 {
+				sizes[v.Name] = v.UsageData.Size
+			}
+		}
+	}
+	for _, u := range usage {
+		u.Volumes = len(u.volumeNames)
+		for name := range u.volumeNames {
+			u.StorageBytes += sizes[name]
+		}
+	}
+	return usage, nil
+}
+
+// Check a create against its project's quota and hold what it asks for
+// until release, so concurrent creates can't both squeeze under a limit.
+// Instances get the default CPU and memory limits if the quota caps them.
+func (m *Manager) reserveQuota(project string, spec *CreateSpec, newVolumes []string) (func(), error) {
+	noop := func() {}
+	if m.quotas == nil {
+		return noop, nil
+	}
+	quota, ok := m.quotas.Quota(project)
+	if !ok {
+		return noop, nil
+	}
+
+	if spec != nil {
+		if quota.MaxCPUs > 0 && spec.CPUs == 0 {
+			spec.CPUs = DefaultQuotaCPUs
+		}
+		if quota.MaxMemoryMB > 0 && spec.MemoryMB == 0 {
+			spec.MemoryMB = DefaultQuotaMemoryMB
+		}
+	}
+
+	requested := &Usage{}
+	if spec != nil {
+		requested.Instances = 1
+		requested.CPUs = spec.CPUs
+		requested.MemoryMB = spec.MemoryMB
+		if mappings, err := ParsePorts(spec.Ports); err == nil {
+			requested.Ports = len(mappings)
+		}
+		newVolumes = append(newVolumes, specVolumes(spec)...)
+	}
+	return m.reserveUsage(project, quota, requested, newVolumes)
+}
+
+// Check a resize against the project's quota; shrinking always fits
+func (m *Manager) reserveResize(project string, current Size, it InstanceType) (func(), error) {
+	if m.quotas == nil {
+		return func() {}, nil
+	}
+	quota, ok := m.quotas.Quota(project)
+	if !ok {
+		return func() {}, nil
+	}
+	requested := &Usage{CPUs: it.CPUs - current.CPUs, MemoryMB: it.MemoryMB - current.MemoryMB}
+	return m.reserveUsage(project, quota, requested, nil)
+}
+
+func (m *Manager) reserveUsage(project string, quota Quota, requested *Usage, newVolumes []string) (func(), error) {
+	m.quotaMu.Lock()
+	defer m.quotaMu.Unlock()
+
+	all, err := m.ProjectUsage(quota.MaxStorageBytes > 0)
+	if err != nil {
+		return nil, err
+	}
+	used := &Usage{}
+	current := all[project]
+	if current != nil {
+		used.add(current, 1)
+	}
+	if pending := m.pendingQuota[project]; pending != nil {
+		used.add(pending, 1)
+	}
+
+	for _, name := range newVolumes {
+		if current == nil || !current.volumeNames[name] {
+			requested.Volumes++
+		}
+	}
+
+	exceeded := func(resource, limit, inUse, asked string) error {
+		return &QuotaError{Project: project, Resource: resource, Limit: limit, Used: inUse, Requested: asked}
+	}
+	switch {
+	case quota.MaxInstances > 0 && requested.Instances > 0 && used.Instances+requested.Instances > quota.MaxInstances:
+		return nil, exceeded("instances", fmt.Sprint(quota.MaxInstances), fmt.Sprint(used.Instances), fmt.Sprint(requested.Instances))
+	case quota.MaxCPUs > 0 && requested.CPUs > 0 && used.CPUs+requested.CPUs > quota.MaxCPUs+1e-9:
+		return nil, exceeded("CPUs", fmt.Sprint(quota.MaxCPUs), fmt.Sprint(used.CPUs), fmt.Sprint(requested.CPUs))
+	case quota.MaxMemoryMB > 0 && requested.MemoryMB > 0 && used.MemoryMB+requested.MemoryMB > quota.MaxMemoryMB:
+		return nil, exceeded("memory", fmt.Sprintf("%d MB", quota.MaxMemoryMB), fmt.Sprintf("%d MB", used.MemoryMB), fmt.Sprintf("%d MB", requested.MemoryMB))
+	case quota.MaxVolumes > 0 && requested.Volumes > 0 && used.Volumes+requested.Volumes > quota.MaxVolumes:
+		return nil, exceeded("volumes", fmt.Sprint(quota.MaxVolumes), fmt.Sprint(used.Volumes), fmt.Sprint(requested.Volumes))
+	case quota.MaxPorts > 0 && requested.Ports > 0 && used.Ports+requested.Ports > quota.MaxPorts:
+		return nil, exceeded("published ports", fmt.Sprint(quota.MaxPorts), fmt.Sprint(used.Ports), fmt.Sprint(requested.Ports))
+	case quota.MaxStorageBytes > 0 && len(newVolumes) > 0 && used.StorageBytes >= quota.MaxStorageBytes:
+		return nil, exceeded("storage", fmt.Sprintf("%d bytes", quota.MaxStorageBytes), fmt.Sprintf("%d bytes", used.StorageBytes), "volumes")
+	}
+
+	if m.pendingQuota[project] == nil {
+		m.pendingQuota[project] = &Usage{}
+	}
+	m.pendingQuota[project].add(requested, 1)
+	return func() {
+		m.quotaMu.Lock()
+		defer m.quotaMu.Unlock()
+		m.pendingQuota[project].add(requested, -1)
+	}, nil
+}
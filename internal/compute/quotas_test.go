@@ -0,0 +1,210 @@
+package compute
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+)
+
+type quotaMap map[string]Quota
+
+func (q quotaMap) Quota(project string) (Quota, bool) {
+	quota, ok := q[project]
+	return quota, ok
+}
+
+func managedContainer(t *testing.T, name string, spec CreateSpec) dockertest.Container {
+	t.Helper()
+	raw, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostConfig := &container.HostConfig{}
+	applyResources(hostConfig, spec.CPUs, spec.MemoryMB, 0)
+	return dockertest.Container{Name: name, Image: "nginx", HostConfig: hostConfig, Labels: map[string]string{
+		labelManaged: "true",
+		labelSpec:    string(raw),
+		labelProject: projectOf(&spec),
+	}}
+}
+
+// A Manager whose daemon runs containers and holds volumes
+func newQuotaManager(t *testing.T, containers []dockertest.Container, volumes []*volume.Volume) (*Manager, *dockertest.Server) {
+	t.Helper()
+	m, docker := newTestManager(t)
+	for _, c := range containers {
+		docker.AddContainer(c)
+	}
+	docker.Handle("GET /volumes", volume.ListResponse{Volumes: volumes})
+	return m, docker
+}
+
+func TestReserveQuota(t *testing.T) {
+	// team uses 1 instance, 1.5 CPUs, 512 MB, 1 port and the data volume
+	m, _ := newQuotaManager(t, []dockertest.Container{
+		managedContainer(t, "c1", CreateSpec{Project: "team", CPUs: 1.5, MemoryMB: 512, Ports: "8080:80", Volumes: []string{"data:/data"}}),
+		managedContainer(t, "c2", CreateSpec{CPUs: 4, MemoryMB: 4096}),
+	}, []*volume.Volume{
+		{Name: "data", Labels: map[string]string{labelProject: "team"}},
+	})
+
+	tests := []struct {
+		name       string
+		quota      Quota
+		spec       *CreateSpec
+		newVolumes []string
+		resource   string // "" when the create fits
+	}{
+		{"no limits", Quota{}, &CreateSpec{CPUs: 64}, nil, ""},
+		{"instances fit", Quota{MaxInstances: 2}, &CreateSpec{}, nil, ""},
+		{"instances full", Quota{MaxInstances: 1}, &CreateSpec{}, nil, "instances"},
+		{"cpus fit", Quota{MaxCPUs: 2}, &CreateSpec{CPUs: 0.5}, nil, ""},
+		{"cpus over", Quota{MaxCPUs: 2}, &CreateSpec{CPUs: 1}, nil, "CPUs"},
+		{"default cpus over", Quota{MaxCPUs: 2}, &CreateSpec{}, nil, "CPUs"},
+		{"memory over", Quota{MaxMemoryMB: 1024}, &CreateSpec{MemoryMB: 1024}, nil, "memory"},
+		{"default memory fits", Quota{MaxMemoryMB: 1024}, &CreateSpec{}, nil, ""},
+		{"ports over", Quota{MaxPorts: 2}, &CreateSpec{Ports: "8081:80,8082:81"}, nil, "published ports"},
+		{"volume already owned", Quota{MaxVolumes: 1}, &CreateSpec{Volumes: []string{"data:/data"}}, nil, ""},
+		{"host paths are not volumes", Quota{MaxVolumes: 1}, &CreateSpec{Volumes: []string{"/srv:/srv", "./conf:/conf"}}, nil, ""},
+		{"new volume over", Quota{MaxVolumes: 1}, &CreateSpec{Volumes: []string{"logs:/logs"}}, nil, "volumes"},
+		{"bare volume over", Quota{MaxVolumes: 1}, nil, []string{"logs"}, "volumes"},
+		{"bare volume fits", Quota{MaxVolumes: 2, MaxInstances: 1}, nil, []string{"logs"}, ""},
+	}
+	for _, tt := range tests {
+		m.UseQuotas(quotaMap{"team": tt.quota})
+		release, err := m.reserveQuota("team", tt.spec, tt.newVolumes)
+		if tt.resource == "" {
+			if err != nil {
+				t.Errorf("%s: %v", tt.name, err)
+			} else {
+				release()
+			}
+			continue
+		}
+		var quotaErr *QuotaError
+		if !errors.As(err, &quotaErr) || quotaErr.Resource != tt.resource || !errors.Is(err, ErrQuotaExceeded) {
+			t.Errorf("%s: error %v, want %s quota exceeded", tt.name, err, tt.resource)
+		}
+	}
+}
+
+func TestReserveQuotaDefaults(t *testing.T) {
+	m, _ := newQuotaManager(t, nil, nil)
+	m.UseQuotas(quotaMap{DefaultProject: {MaxCPUs: 8, MaxMemoryMB: 8192}})
+
+	spec := &CreateSpec{}
+	release, err := m.reserveQuota(DefaultProject, spec, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release()
+	if spec.CPUs != DefaultQuotaCPUs || spec.MemoryMB != DefaultQuotaMemoryMB {
+		t.Errorf("spec limits = %v CPUs, %d MB, want %v, %d", spec.CPUs, spec.MemoryMB, DefaultQuotaCPUs, DefaultQuotaMemoryMB)
+	}
+}
+
+func TestReserveQuotaPending(t *testing.T) {
+	m, _ := newQuotaManager(t, nil, nil)
+	m.UseQuotas(quotaMap{"team": {MaxInstances: 1}})
+
+	release, err := m.reserveQuota("team", &CreateSpec{Project: "team"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a create still in flight holds its share
+	if _, err := m.reserveQuota("team", &CreateSpec{Project: "team"}, nil); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("second create: error %v, want %v", err, ErrQuotaExceeded)
+	}
+	// other projects are not affected
+	if _, err := m.reserveQuota("other", &CreateSpec{Project: "other"}, nil); err != nil {
+		t.Fatalf("other project: %v", err)
+	}
+	release()
+	if _, err := m.reserveQuota("team", &CreateSpec{Project: "team"}, nil); err != nil {
+		t.Fatalf("after release: %v", err)
+	}
+}
+
+func TestProjectUsage(t *testing.T) {
+	m, docker := newQuotaManager(t, []dockertest.Container{
+		managedContainer(t, "web", CreateSpec{Project: "team", CPUs: 1, MemoryMB: 256, Ports: "8080:80,8443:443", Volumes: []string{"data:/data", "/srv:/srv"}}),
+		managedContainer(t, "api", CreateSpec{Project: "team", CPUs: 0.5, MemoryMB: 128, Volumes: []string{"data:/data"}}),
+		managedContainer(t, "tool", CreateSpec{}),
+	}, []*volume.Volume{
+		{Name: "data", Labels: map[string]string{labelProject: "team"}},
+		{Name: "cache", Labels: map[string]string{labelProject: "team"}},
+	})
+	docker.Handle("GET /system/df", types.DiskUsage{Volumes: []*volume.Volume{
+		{Name: "data", UsageData: &volume.UsageData{Size: 1000}},
+		{Name: "cache", UsageData: &volume.UsageData{Size: -1}},
+	}})
+
+	usage, err := m.ProjectUsage(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	team := usage["team"]
+	if team == nil || team.Instances != 2 || team.CPUs != 1.5 || team.MemoryMB != 384 || team.Ports != 2 || team.Volumes != 2 || team.StorageBytes != 1000 {
+		t.Errorf("team usage = %+v", team)
+	}
+	if usage[DefaultProject] == nil || usage[DefaultProject].Instances != 1 {
+		t.Errorf("default usage = %+v", usage[DefaultProject])
+	}
+}
+
+func TestCreateOverQuota(t *testing.T) {
+	m, docker := newQuotaManager(t, []dockertest.Container{
+		managedContainer(t, "web", CreateSpec{Project: "team"}),
+	}, nil)
+	m.UseQuotas(quotaMap{"team": {MaxInstances: 1}})
+
+	if _, err := m.Create(CreateSpec{Name: "api", Image: "nginx", Project: "team"}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("Create = %v, want %v", err, ErrQuotaExceeded)
+	}
+	if n := len(docker.Containers()); n != 1 {
+		t.Errorf("%d containers after a refused create", n)
+	}
+	if _, err := m.Create(CreateSpec{Name: "api", Image: "nginx", Project: "other"}); err != nil {
+		t.Errorf("create in another project: %v", err)
+	}
+}
+
+func TestReserveResize(t *testing.T) {
+	m, _ := newQuotaManager(t, []dockertest.Container{
+		managedContainer(t, "c1", CreateSpec{CPUs: 2, MemoryMB: 2048}),
+	}, nil)
+	m.UseQuotas(quotaMap{DefaultProject: {MaxCPUs: 3, MaxMemoryMB: 4096}})
+	current := Size{CPUs: 2, MemoryMB: 2048}
+
+	tests := []struct {
+		it       InstanceType
+		resource string
+	}{
+		{InstanceType{CPUs: 3, MemoryMB: 4096}, ""},
+		{InstanceType{CPUs: 4, MemoryMB: 2048}, "CPUs"},
+		{InstanceType{CPUs: 2, MemoryMB: 8192}, "memory"},
+		// shrinking always fits
+		{InstanceType{CPUs: 1, MemoryMB: 512}, ""},
+	}
+	for _, tt := range tests {
+		release, err := m.reserveResize(DefaultProject, current, tt.it)
+		if tt.resource == "" {
+			if err != nil {
+				t.Errorf("%+v: %v", tt.it, err)
+			} else {
+				release()
+			}
+			continue
+		}
+		var quotaErr *QuotaError
+		if !errors.As(err, &quotaErr) || quotaErr.Resource != tt.resource {
+			t.Errorf("%+v: error %v, want %s quota exceeded", tt.it, err, tt.resource)
+		}
+	}
+}
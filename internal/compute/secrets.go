@@ -0,0 +1,187 @@
+package compute
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+)
+
+// Where file secrets are mounted, on a tmpfs so they never touch disk
+const SecretsDir = "/run/secrets"
+
+// Created once every secret file is written; the entrypoint waits for it
+const secretsReadyFile = SecretsDir + "/.ready"
+
+var (
+	validEnvName    = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	validSecretFile = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+)
+
+// Secret to inject into an instance, as an env var or a file under SecretsDir
+type SecretRef struct {
+	Name    string `json:"name"`
+	Version int    `json:"version,omitempty"` // latest if 0
+	Env     string `json:"env,omitempty"`
+	File    string `json:"file,omitempty"` // file name, defaults to the secret name
+}
+
+// Looks up secret values when instances are created
+type SecretResolver interface {
+	SecretValue(name string, version int) ([]byte, error)
+}
+
+// Resolve secrets referenced by specs created from now on
+func (m *Manager) UseSecrets(resolver SecretResolver) {
+	m.secrets = resolver
+}
+
+func validateSecretRefs(refs []SecretRef) error {
+	files := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.Name == "" {
+			return fmt.Errorf("secret name is required")
+		}
+		if ref.Env != "" && ref.File != "" {
+			return fmt.Errorf("secret %s: set env or file, not both", ref.Name)
+		}
+		if ref.Env != "" {
+			if !validEnvName.MatchString(ref.Env) {
+				return fmt.Errorf("secret %s: invalid env var name %q", ref.Name, ref.Env)
+			}
+			continue
+		}
+
+		file := secretFileName(ref)
+		if !validSecretFile.MatchString(file) {
+			return fmt.Errorf("secret %s: invalid file name %q", ref.Name, file)
+		}
+		if files[file] {
+			return fmt.Errorf("secret file %s is used twice", file)
+		}
+		files[file] = true
+	}
+	return nil
+}
+
+func secretFileName(ref SecretRef) string {
+	if ref.File != "" {
+		return ref.File
+	}
+	return ref.Name
+}
+
+// Add env secrets to config and set up the tmpfs for file secrets,
+// returning the file contents to write once the container is running
+func (m *Manager) applySecrets(ctx context.Context, spec CreateSpec, config *container.Config, hostConfig *container.HostConfig) (map[string][]byte, error) {
+	if len(spec.Secrets) == 0 {
+		return nil, nil
+	}
+
+	env, files, err := m.resolveSecrets(spec)
+	if err != nil {
+		return nil, err
+	}
+	config.Env = append(config.Env, env...)
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	// Hold the image's command back until the files are in place
+	command, err := m.imageCommand(ctx, spec.Image, spec.Command)
+	if err != nil {
+		return nil, err
+	}
+	if len(command) == 0 {
+		return nil, fmt.Errorf("image %s has no command to start after writing secret files", spec.Image)
+	}
+	config.Entrypoint = []string{"/bin/sh", "-c",
+		"until [ -e " + secretsReadyFile + " ]; do sleep 0.1; done; exec \"$@\"", "sh"}
+	config.Cmd = command
+
+	hostConfig.Tmpfs = map[string]string{SecretsDir: "size=1m,mode=0755,noexec,nosuid"}
+	return files, nil
+}
+
+// Env vars (KEY=value) and file contents for the secrets a spec references
+func (m *Manager) resolveSecrets(spec CreateSpec) ([]string, map[string][]byte, error) {
+	if m.secrets == nil {
+		return nil, nil, fmt.Errorf("secrets are only available through the LocalCloud server")
+	}
+
+	var env []string
+	files := make(map[string][]byte)
+	for _, ref := range spec.Secrets {
+		value, err := m.secrets.SecretValue(ref.Name, ref.Version)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to resolve secret %s: %w", ref.Name, err)
+		}
+		if ref.Env != "" {
+			env = append(env, ref.Env+"="+string(value))
+		} else {
+			files[secretFileName(ref)] = value
+		}
+	}
+	return env, files, nil
+}
+
+// The tmpfs is empty after a restart, so write the files again
+func (m *Manager) restoreSecretFiles(ctx context.Context, containerID string, spec CreateSpec) error {
+	_, files, err := m.resolveSecrets(spec)
+	if err != nil || len(files) == 0 {
+		return err
+	}
+	return m.writeSecretFiles(ctx, containerID, files)
+}
+
+// Entrypoint and command of an image, with cmd replacing the image's command
+// if set, pulling the image if needed
+func (m *Manager) imageCommand(ctx context.Context, image string, cmd []string) ([]string, error) {
+	inspect, _, err := m.client.ImageInspectWithRaw(ctx, image)
+	if client.IsErrNotFound(err) {
+		if err := m.PullImage(image); err != nil {
+			return nil, err
+		}
+		inspect, _, err = m.client.ImageInspectWithRaw(ctx, image)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect image: %w", err)
+	}
+	if inspect.Config == nil {
+		return cmd, nil
+	}
+	if len(cmd) == 0 {
+		cmd = inspect.Config.Cmd
+	}
+	return append(append([]string{}, inspect.Config.Entrypoint...), cmd...), nil
+}
+
+// Write secret files as root, then release the waiting entrypoint
+func (m *Manager) writeSecretFiles(ctx context.Context, containerID string, files map[string][]byte) error {
+	for name, value := range files {
+		target := path.Join(SecretsDir, name)
+		stderr, code, err := m.execAs(ctx, containerID, "0",
+			[]string{"sh", "-c", `cat > "$1" && chmod 0444 "$1"`, "sh", target}, nil, bytes.NewReader(value), nil)
+		if err != nil {
+			return fmt.Errorf("failed to write secret file %s: %w", name, err)
+		}
+		if code != 0 {
+			return fmt.Errorf("failed to write secret file %s: %s", name, strings.TrimSpace(stderr))
+		}
+	}
+
+	stderr, code, err := m.execAs(ctx, containerID, "0", []string{"touch", secretsReadyFile}, nil, nil, nil)
+	if err != nil {
+		return fmt.Errorf("failed to release container entrypoint: %w", err)
+	}
+	if code != 0 {
+		return fmt.Errorf("failed to release container entrypoint: %s", strings.TrimSpace(stderr))
+	}
+	return nil
+}
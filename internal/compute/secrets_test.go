@@ -0,0 +1,113 @@
+package compute
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// Secret values by name, the same for every version
+type mapResolver map[string]string
+
+func (r mapResolver) SecretValue(name string, version int) ([]byte, error) {
+	value, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("secret %q not found", name)
+	}
+	return []byte(value), nil
+}
+
+func TestValidateSecretRefs(t *testing.T) {
+	tests := []struct {
+		refs []SecretRef
+		err  string
+	}{
+		{[]SecretRef{{Name: "db", Env: "DB_PASSWORD"}, {Name: "tls"}, {Name: "key", File: "tls.key"}}, ""},
+		{[]SecretRef{{Env: "X"}}, "name is required"},
+		{[]SecretRef{{Name: "db", Env: "X", File: "x"}}, "not both"},
+		{[]SecretRef{{Name: "db", Env: "1X"}}, "invalid env var name"},
+		{[]SecretRef{{Name: "db", File: "../etc/passwd"}}, "invalid file name"},
+		{[]SecretRef{{Name: "a", File: "x"}, {Name: "b", File: "x"}}, "used twice"},
+	}
+	for _, tt := range tests {
+		err := validateSecretRefs(tt.refs)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("validateSecretRefs(%+v) = %v, want %q", tt.refs, err, tt.err)
+		}
+	}
+}
+
+func TestCreateWithSecrets(t *testing.T) {
+	m, docker := newTestManager(t)
+	m.UseSecrets(mapResolver{"db": "hunter2", "tls": "-----BEGIN KEY-----"})
+	docker.Handle("GET /images/nginx/json", types.ImageInspect{
+		Config: &container.Config{Entrypoint: []string{"/docker-entrypoint.sh"}, Cmd: []string{"nginx"}},
+	})
+
+	var mu sync.Mutex
+	written := make(map[string]string)
+	docker.Exec(func(c dockertest.Container, cmd, env []string, stdin io.Reader, stdout, stderr io.Writer) int {
+		mu.Lock()
+		defer mu.Unlock()
+		if stdin != nil {
+			data, _ := io.ReadAll(stdin)
+			written[cmd[len(cmd)-1]] = string(data)
+		} else {
+			written[strings.Join(cmd, " ")] = ""
+		}
+		return 0
+	})
+
+	_, err := m.Create(CreateSpec{
+		Image:   "nginx",
+		Name:    "web",
+		Env:     []string{"MODE=prod"},
+		Secrets: []SecretRef{{Name: "db", Env: "DB_PASSWORD"}, {Name: "tls", File: "tls.key"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := docker.Containers()[0]
+	if strings.Join(c.Config.Env, ",") != "MODE=prod,DB_PASSWORD=hunter2" {
+		t.Errorf("env = %v", c.Config.Env)
+	}
+	if strings.Contains(c.Labels[labelSpec], "hunter2") {
+		t.Error("secret value stored in the spec label")
+	}
+	if c.Config.Entrypoint[0] != "/bin/sh" || strings.Join(c.Config.Cmd, " ") != "/docker-entrypoint.sh nginx" {
+		t.Errorf("entrypoint %v, cmd %v", c.Config.Entrypoint, c.Config.Cmd)
+	}
+	if _, ok := c.HostConfig.Tmpfs[SecretsDir]; !ok {
+		t.Errorf("tmpfs = %v", c.HostConfig.Tmpfs)
+	}
+	if written["/run/secrets/tls.key"] != "-----BEGIN KEY-----" {
+		t.Errorf("secret files written: %v", written)
+	}
+	if _, ok := written["touch "+secretsReadyFile]; !ok {
+		t.Error("entrypoint not released")
+	}
+}
+
+func TestCreateWithMissingSecret(t *testing.T) {
+	m, docker := newTestManager(t)
+	spec := CreateSpec{Image: "nginx", Secrets: []SecretRef{{Name: "db", Env: "DB_PASSWORD"}}}
+
+	if _, err := m.Create(spec); err == nil || !strings.Contains(err.Error(), "only available through the LocalCloud server") {
+		t.Errorf("without a resolver: %v", err)
+	}
+	m.UseSecrets(mapResolver{})
+	if _, err := m.Create(spec); err == nil || !strings.Contains(err.Error(), `secret "db" not found`) {
+		t.Errorf("missing secret: %v", err)
+	}
+	if len(docker.Containers()) != 0 {
+		t.Error("a container was created")
+	}
+}
@@ -0,0 +1,237 @@
+package compute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/client"
+)
+
+// Snapshots are images committed from containers, tagged SnapshotRepository:<name>
+const SnapshotRepository = "localcloud/snapshots"
+
+// Labels describing a snapshot image
+const (
+	labelSnapshot            = "localcloud.snapshot"
+	labelSnapshotDescription = "localcloud.snapshot.description"
+	labelSnapshotSource      = "localcloud.snapshot.source"
+	labelSnapshotSourceID    = "localcloud.snapshot.source-id"
+	labelSnapshotSourceImage = "localcloud.snapshot.source-image"
+)
+
+var (
+	ErrSnapshotNotFound    = errors.New("snapshot not found")
+	ErrSnapshotExists      = errors.New("snapshot already exists")
+	ErrInvalidSnapshotName = errors.New("snapshot name must start with a letter or digit and contain only letters, digits, _, . and -")
+)
+
+// Names become image tags
+var validSnapshotName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]{0,127}$`)
+
+type Snapshot struct {
+	ID          string    `json:"id"` // image ID
+	Name        string    `json:"name"`
+	Image       string    `json:"image"` // reference to launch from
+	Description string    `json:"description,omitempty"`
+	Source      string    `json:"source"`    // container name
+	SourceID    string    `json:"source_id"` // container ID
+	SourceImage string    `json:"source_image"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Created     time.Time `json:"created"`
+}
+
+type SnapshotInput struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// Commit a container's filesystem into a snapshot image. The container is
+// paused while committing; volumes and tmpfs mounts are not included.
+func (m *Manager) Snapshot(containerID string, in SnapshotInput) (*Snapshot, error) {
+	ctx := context.Background()
+
+	if !validSnapshotName.MatchString(in.Name) {
+		return nil, ErrInvalidSnapshotName
+	}
+	if _, err := m.GetSnapshot(in.Name); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotExists, in.Name)
+	}
+
+	containerJSON, err := m.client.ContainerInspect(ctx, containerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	config, err := m.snapshotConfig(ctx, containerJSON)
+	if err != nil {
+		return nil, err
+	}
+	config.Labels[labelSnapshot] = in.Name
+	config.Labels[labelSnapshotDescription] = in.Description
+	config.Labels[labelSnapshotSource] = strings.TrimPrefix(containerJSON.Name, "/")
+	config.Labels[labelSnapshotSourceID] = containerJSON.ID
+	config.Labels[labelSnapshotSourceImage] = containerJSON.Config.Image
+
+	_, err = m.client.ContainerCommit(ctx, containerJSON.ID, container.CommitOptions{
+		Reference: SnapshotRepository + ":" + in.Name,
+		Comment:   in.Description,
+		Author:    "LocalCloud",
+		Pause:     true,
+		Config:    config,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit container: %w", err)
+	}
+
+	return m.GetSnapshot(in.Name)
+}
+
+// Config for the committed image: the source image's entrypoint and command,
+// without env vars and labels that LocalCloud added to the container.
+// Docker fills in anything left unset from the container, so unwanted keys
+// are kept with empty values instead of being dropped.
+func (m *Manager) snapshotConfig(ctx context.Context, containerJSON types.ContainerJSON) (*container.Config, error) {
+	image, _, err := m.client.ImageInspectWithRaw(ctx, containerJSON.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect source image: %w", err)
+	}
+
+	config := &container.Config{Labels: make(map[string]string)}
+	for key := range containerJSON.Config.Labels {
+		if strings.HasPrefix(key, "localcloud.") {
+			config.Labels[key] = ""
+		}
+	}
+
+	// keep env from the image and the spec; secrets and parameters were injected
+	kept := make(map[string]bool)
+	var imageEnv []string
+	if image.Config != nil {
+		imageEnv = image.Config.Env
+	}
+	spec, _ := specFromLabels(containerJSON.Config.Labels)
+	var specEnv []string
+	if spec != nil {
+		specEnv = spec.Env
+	}
+	for _, env := range append(append([]string{}, imageEnv...), specEnv...) {
+		kept[envKey(env)] = true
+	}
+	for _, env := range containerJSON.Config.Env {
+		if kept[envKey(env)] {
+			config.Env = append(config.Env, env)
+		} else {
+			config.Env = append(config.Env, envKey(env)+"=")
+		}
+	}
+
+	// drop the secret file wrapper, keeping what the instance actually ran
+	if image.Config != nil {
+		config.Entrypoint = image.Config.Entrypoint
+		config.Cmd = image.Config.Cmd
+	}
+	if spec != nil && len(spec.Command) > 0 {
+		config.Cmd = spec.Command
+	}
+	if len(config.Entrypoint) == 0 && len(config.Cmd) == 0 {
+		config.Cmd = containerJSON.Config.Cmd
+	}
+	return config, nil
+}
+
+func envKey(env string) string {
+	key, _, _ := strings.Cut(env, "=")
+	return key
+}
+
+// Snapshots, newest first
+func (m *Manager) ListSnapshots() ([]Snapshot, error) {
+	images, err := m.client.ImageList(context.Background(), types.ImageListOptions{
+		Filters: filters.NewArgs(filters.Arg("label", labelSnapshot)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list images: %w", err)
+	}
+
+	snapshots := []Snapshot{}
+	for _, image := range images {
+		// images committed outside LocalCloud can inherit the labels
+		name := image.Labels[labelSnapshot]
+		if !hasTag(image.RepoTags, SnapshotRepository+":"+name) {
+			continue
+		}
+		snapshots = append(snapshots, Snapshot{
+			ID:          image.ID,
+			Name:        name,
+			Image:       SnapshotRepository + ":" + name,
+			Description: image.Labels[labelSnapshotDescription],
+			Source:      image.Labels[labelSnapshotSource],
+			SourceID:    image.Labels[labelSnapshotSourceID],
+			SourceImage: image.Labels[labelSnapshotSourceImage],
+			SizeBytes:   image.Size,
+			Created:     time.Unix(image.Created, 0),
+		})
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Created.After(snapshots[j].Created) })
+	return snapshots, nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Manager) GetSnapshot(name string) (*Snapshot, error) {
+	image, _, err := m.client.ImageInspectWithRaw(context.Background(), SnapshotRepository+":"+name)
+	if client.IsErrNotFound(err) {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect snapshot: %w", err)
+	}
+
+	var labels map[string]string
+	if image.Config != nil {
+		labels = image.Config.Labels
+	}
+	if labels[labelSnapshot] != name {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, name)
+	}
+
+	created, _ := time.Parse(time.RFC3339Nano, image.Created)
+	return &Snapshot{
+		ID:          image.ID,
+		Name:        name,
+		Image:       SnapshotRepository + ":" + name,
+		Description: labels[labelSnapshotDescription],
+		Source:      labels[labelSnapshotSource],
+		SourceID:    labels[labelSnapshotSourceID],
+		SourceImage: labels[labelSnapshotSourceImage],
+		SizeBytes:   image.Size,
+		Created:     created,
+	}, nil
+}
+
+// Remove a snapshot image; instances launched from it must be deleted first
+func (m *Manager) DeleteSnapshot(name string) error {
+	if _, err := m.GetSnapshot(name); err != nil {
+		return err
+	}
+	_, err := m.client.ImageRemove(context.Background(), SnapshotRepository+":"+name, types.ImageRemoveOptions{PruneChildren: true})
+	if err != nil {
+		return fmt.Errorf("failed to remove snapshot: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package compute
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+)
+
+// Fake image store for snapshots committed through the API
+type snapshotImages struct {
+	mu        sync.Mutex
+	committed *container.Config
+	reference string
+	removed   bool
+}
+
+func (f *snapshotImages) install(docker *dockertest.Server, name string) {
+	docker.Handle("GET /images/nginx/json", types.ImageInspect{
+		Config: &container.Config{
+			Env:        []string{"PATH=/usr/bin", "NGINX_VERSION=1.25"},
+			Entrypoint: []string{"/docker-entrypoint.sh"},
+			Cmd:        []string{"nginx"},
+		},
+	})
+	docker.Handle("POST /commit", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.committed = &container.Config{}
+		json.NewDecoder(r.Body).Decode(f.committed)
+		f.reference = r.URL.Query().Get("repo") + ":" + r.URL.Query().Get("tag")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(types.IDResponse{ID: "sha256:snap"})
+	}))
+	docker.Handle("GET /images/"+SnapshotRepository+":"+name+"/json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		if f.committed == nil || f.removed {
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]string{"message": "No such image"})
+			return
+		}
+		json.NewEncoder(w).Encode(types.ImageInspect{
+			ID:      "sha256:snap",
+			Created: time.Now().Format(time.RFC3339Nano),
+			Size:    1024,
+			Config:  f.committed,
+		})
+	}))
+	docker.Handle("DELETE /images/"+SnapshotRepository+":"+name, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.removed = true
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte("[]"))
+	}))
+}
+
+func TestSnapshot(t *testing.T) {
+	m, docker := newTestManager(t)
+	images := &snapshotImages{}
+	images.install(docker, "web-v1")
+
+	spec, _ := json.Marshal(CreateSpec{Image: "nginx", Env: []string{"MODE=prod"}})
+	id := docker.AddContainer(dockertest.Container{
+		Name:   "web",
+		Image:  "nginx",
+		Labels: map[string]string{labelManaged: "true", labelSpec: string(spec)},
+		Config: &container.Config{
+			Image:      "nginx",
+			Env:        []string{"PATH=/usr/bin", "NGINX_VERSION=1.25", "MODE=prod", "DB_PASSWORD=hunter2"},
+			Entrypoint: []string{"/bin/sh", "-c", "wait for secrets"},
+			Cmd:        []string{"/docker-entrypoint.sh", "nginx"},
+		},
+	})
+
+	snapshot, err := m.Snapshot(id, SnapshotInput{Name: "web-v1", Description: "before upgrade"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Image != SnapshotRepository+":web-v1" || snapshot.Source != "web" || snapshot.SourceID != id ||
+		snapshot.SourceImage != "nginx" || snapshot.Description != "before upgrade" {
+		t.Errorf("snapshot = %+v", snapshot)
+	}
+
+	config := images.committed
+	if images.reference != SnapshotRepository+":web-v1" {
+		t.Errorf("committed as %s", images.reference)
+	}
+	// injected values are blanked, LocalCloud labels cleared
+	if got := strings.Join(config.Env, ","); got != "PATH=/usr/bin,NGINX_VERSION=1.25,MODE=prod,DB_PASSWORD=" {
+		t.Errorf("env = %s", got)
+	}
+	if config.Labels[labelSpec] != "" || config.Labels[labelManaged] != "" {
+		t.Errorf("labels = %v", config.Labels)
+	}
+	if config.Entrypoint[0] != "/docker-entrypoint.sh" || config.Cmd[0] != "nginx" {
+		t.Errorf("entrypoint %v, cmd %v", config.Entrypoint, config.Cmd)
+	}
+
+	if _, err := m.Snapshot(id, SnapshotInput{Name: "web-v1"}); !errors.Is(err, ErrSnapshotExists) {
+		t.Errorf("snapshot twice: %v", err)
+	}
+	if _, err := m.Snapshot(id, SnapshotInput{Name: "-bad"}); !errors.Is(err, ErrInvalidSnapshotName) {
+		t.Errorf("invalid name: %v", err)
+	}
+}
+
+func TestCreateFromSnapshot(t *testing.T) {
+	m, docker := newTestManager(t)
+	images := &snapshotImages{}
+	images.install(docker, "web-v1")
+
+	if _, err := m.Create(CreateSpec{Snapshot: "web-v1"}); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Fatalf("missing snapshot: %v", err)
+	}
+
+	images.committed = &container.Config{Labels: map[string]string{labelSnapshot: "web-v1"}}
+	if _, err := m.Create(CreateSpec{Snapshot: "web-v1"}); err != nil {
+		t.Fatal(err)
+	}
+	if image := docker.Containers()[0].Image; image != SnapshotRepository+":web-v1" {
+		t.Errorf("launched from %s", image)
+	}
+
+	if err := m.DeleteSnapshot("web-v1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.GetSnapshot("web-v1"); !errors.Is(err, ErrSnapshotNotFound) {
+		t.Errorf("after deleting: %v", err)
+	}
+}
+
+func TestListSnapshots(t *testing.T) {
+	m, docker := newTestManager(t)
+	docker.Handle("GET /images/json", []types.ImageSummary{
+		{ID: "sha256:old", RepoTags: []string{SnapshotRepository + ":old"}, Labels: map[string]string{labelSnapshot: "old"}, Created: 100},
+		{ID: "sha256:new", RepoTags: []string{SnapshotRepository + ":new"}, Labels: map[string]string{labelSnapshot: "new"}, Created: 200},
+		// committed from a snapshot outside LocalCloud, inheriting its labels
+		{ID: "sha256:copy", RepoTags: []string{"myapp:latest"}, Labels: map[string]string{labelSnapshot: "old"}, Created: 300},
+	})
+
+	snapshots, err := m.ListSnapshots()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 2 || snapshots[0].Name != "new" || snapshots[1].Name != "old" {
+		t.Errorf("snapshots = %+v", snapshots)
+	}
+}
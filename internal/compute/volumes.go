@@ -0,0 +1,84 @@
+package compute
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+	"github.com/docker/docker/client"
+)
+
+// Image for short-lived containers that work on volume contents
+const helperImage = "alpine:3.20"
+
+// Label on helper containers, which are not LocalCloud instances
+const labelHelper = "localcloud.helper"
+
+// Whether a named volume exists
+func (m *Manager) VolumeExists(name string) (bool, error) {
+	_, err := m.client.VolumeInspect(context.Background(), name)
+	if client.IsErrNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect volume: %w", err)
+	}
+	return true, nil
+}
+
+// Create a named volume, counted against project's quota (the default
+// project if empty)
+func (m *Manager) CreateVolume(name, project string) error {
+	if project == "" {
+		project = DefaultProject
+	}
+	if !ValidProjectName(project) {
+		return fmt.Errorf("invalid project: %w", ErrInvalidProject)
+	}
+	release, err := m.reserveQuota(project, nil, []string{name})
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	options := volume.CreateOptions{Name: name, Labels: map[string]string{labelProject: project}}
+	if _, err := m.client.VolumeCreate(context.Background(), options); err != nil {
+		return fmt.Errorf("failed to create volume: %w", err)
+	}
+	return nil
+}
+
+// Run cmd in a throwaway container with a volume mounted at mountPath,
+// streaming stdin and stdout like ExecIO
+func (m *Manager) RunWithVolume(ctx context.Context, volumeName, mountPath string, readOnly bool, cmd []string, stdin io.Reader, stdout io.Writer) (string, int, error) {
+	bind := volumeName + ":" + mountPath
+	if readOnly {
+		bind += ":ro"
+	}
+	config := &container.Config{
+		Image:  helperImage,
+		Cmd:    []string{"tail", "-f", "/dev/null"},
+		Labels: map[string]string{labelHelper: "volume"},
+	}
+	hostConfig := &container.HostConfig{Binds: []string{bind}, NetworkMode: "none"}
+
+	resp, err := m.client.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+	if client.IsErrNotFound(err) {
+		if err := m.PullImage(helperImage); err != nil {
+			return "", 0, err
+		}
+		resp, err = m.client.ContainerCreate(ctx, config, hostConfig, nil, nil, "")
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create helper container: %w", err)
+	}
+	defer m.client.ContainerRemove(context.Background(), resp.ID, types.ContainerRemoveOptions{Force: true})
+
+	if err := m.client.ContainerStart(ctx, resp.ID, types.ContainerStartOptions{}); err != nil {
+		return "", 0, fmt.Errorf("failed to start helper container: %w", err)
+	}
+	return m.ExecIO(ctx, resp.ID, cmd, nil, stdin, stdout)
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
 )
 
@@ -10,6 +11,18 @@ type Config struct {
 	LogLevel    string
 	DockerHost  string
 	MetricsEnabled bool
+	DataDir     string // where subsystems persist their state
+	DNSEnabled  bool
+	DNSAddr     string // listen address, bridge gateway port 53 if empty
+	DNSZone     string
+	DNSUpstream string // resolver for names outside the zone
+	FunctionIdleSeconds int // warm function containers are removed after this
+	MasterKey   string // passphrase the secrets key is derived from, generated if empty
+	BackupDir   string // volume backup archives, DataDir/backups/archives if empty
+	PortRange   string // host ports handed out for auto and empty mappings
+	BindAddress string // host address ports are published on unless a mapping names one
+	InstanceTypesFile string // custom instance types, DataDir/instance-types.json if empty
+	APIToken    string // required from API clients when set
 }
 
 func New() *Config {
@@ -18,9 +31,43 @@ func New() *Config {
 		LogLevel:       getEnv("LOCALCLOUD_LOG_LEVEL", "INFO"),
 		DockerHost:     getEnv("DOCKER_HOST", ""),
 		MetricsEnabled: getEnvBool("LOCALCLOUD_METRICS", true),
+		DataDir:        getEnv("LOCALCLOUD_DATA_DIR", defaultDataDir()),
+		DNSEnabled:     getEnvBool("LOCALCLOUD_DNS", true),
+		DNSAddr:        getEnv("LOCALCLOUD_DNS_ADDR", ""),
+		DNSZone:        getEnv("LOCALCLOUD_DNS_ZONE", "localcloud.internal"),
+		DNSUpstream:    getEnv("LOCALCLOUD_DNS_UPSTREAM", "8.8.8.8:53"),
+		FunctionIdleSeconds: getEnvInt("LOCALCLOUD_FUNCTION_IDLE_SECONDS", 300),
+		MasterKey:      getEnv("LOCALCLOUD_MASTER_KEY", ""),
+		BackupDir:      getEnv("LOCALCLOUD_BACKUP_DIR", ""),
+		PortRange:      getEnv("LOCALCLOUD_PORT_RANGE", "20000-29999"),
+		BindAddress:    getEnv("LOCALCLOUD_BIND_ADDRESS", "127.0.0.1"),
+		InstanceTypesFile: getEnv("LOCALCLOUD_INSTANCE_TYPES", ""),
+		APIToken:       getEnv("LOCALCLOUD_API_TOKEN", ""),
 	}
 }
 
+// Custom instance types file, under the data directory unless configured
+func (c *Config) InstanceTypesPath() string {
+	if c.InstanceTypesFile != "" {
+		return c.InstanceTypesFile
+	}
+	return filepath.Join(c.DataDir, "instance-types.json")
+}
+
+// Instance types of resized instances, which their labels can't record
+func (c *Config) ResizesPath() string {
+	return filepath.Join(c.DataDir, "resizes.json")
+}
+
+// ~/.localcloud, or a relative directory if there is no home
+func defaultDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ".localcloud"
+	}
+	return filepath.Join(home, ".localcloud")
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -0,0 +1,612 @@
+// Managed databases on top of compute.Manager
+package databases
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+)
+
+// Label placed on database containers
+const LabelDatabase = "localcloud.database"
+
+const (
+	StatusCreating    = "creating"
+	StatusAvailable   = "available"
+	StatusBackingUp   = "backing-up"
+	StatusRestoring   = "restoring"
+	StatusUnavailable = "unavailable"
+	StatusFailed      = "failed"
+)
+
+const (
+	readyTimeout     = 5 * time.Minute
+	checkInterval    = 10 * time.Second
+	defaultRetention = 7
+	defaultUsername  = "localcloud"
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type Database struct {
+	Name            string     `json:"name"`
+	Engine          string     `json:"engine"` // postgres or mysql
+	Version         string     `json:"version"`
+	DBName          string     `json:"db_name"`
+	Username        string     `json:"username"`
+	Password        string     `json:"password"`
+	RootPassword    string     `json:"root_password,omitempty"` // mysql only
+	Port            int        `json:"port,omitempty"`          // host port, 0 for any free port
+	MemoryMB        int        `json:"memory_mb,omitempty"`
+	BackupInterval  int        `json:"backup_interval_hours,omitempty"` // 0 for manual backups only
+	BackupRetention int        `json:"backup_retention"`                // automatic backups kept
+	InstanceID      string     `json:"instance_id,omitempty"`
+	Volume          string     `json:"volume"`
+	Endpoint        string     `json:"endpoint,omitempty"` // host:port
+	Status          string     `json:"status"`
+	Error           string     `json:"error,omitempty"`
+	LastBackup      *time.Time `json:"last_backup,omitempty"`
+	Created         time.Time  `json:"created"`
+}
+
+// Database plus how to connect to it
+type Info struct {
+	Database
+	ConnectionString string   `json:"connection_string,omitempty"`
+	ShellCommand     []string `json:"shell_command"` // run inside the instance
+}
+
+type Backup struct {
+	ID        string    `json:"id"`
+	Database  string    `json:"database"`
+	Engine    string    `json:"engine"`
+	Size      int64     `json:"size"`
+	Automatic bool      `json:"automatic"`
+	Created   time.Time `json:"created"`
+}
+
+type Service struct {
+	manager *compute.Manager
+	dir     string
+
+	mu        sync.Mutex
+	databases map[string]*Database
+	backups   map[string][]Backup
+	busy      map[string]bool // backup or restore in progress
+	creating  map[string]bool // being provisioned by this process
+}
+
+func NewService(manager *compute.Manager, dataDir string) (*Service, error) {
+	s := &Service{
+		manager:   manager,
+		dir:       filepath.Join(dataDir, "databases"),
+		databases: make(map[string]*Database),
+		backups:   make(map[string][]Backup),
+		busy:      make(map[string]bool),
+		creating:  make(map[string]bool),
+	}
+	if err := store.Load(filepath.Join(s.dir, "databases.json"), &s.databases); err != nil {
+		return nil, err
+	}
+	for name := range s.databases {
+		var backups []Backup
+		if err := store.Load(s.backupsPath(name), &backups); err != nil {
+			return nil, err
+		}
+		s.backups[name] = backups
+	}
+	return s, nil
+}
+
+// Track instance health and take automatic backups until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(checkInterval)
+		defer ticker.Stop()
+
+		s.check()
+		for {
+			select {
+			case <-ticker.C:
+				s.check()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) List() []Info {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	infos := make([]Info, 0, len(s.databases))
+	for _, db := range s.databases {
+		infos = append(infos, info(db))
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Name < infos[j].Name })
+	return infos
+}
+
+func (s *Service) Get(name string) (*Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db, ok := s.databases[name]
+	if !ok {
+		return nil, fmt.Errorf("database %q not found", name)
+	}
+	result := info(db)
+	return &result, nil
+}
+
+// Record a new database and provision it in the background; poll Get for
+// the status to become available
+func (s *Service) Create(spec Database) (*Info, error) {
+	if !validName.MatchString(spec.Name) {
+		return nil, fmt.Errorf("database name must be lowercase letters, digits and dashes")
+	}
+	e, err := lookupEngine(spec.Engine)
+	if err != nil {
+		return nil, err
+	}
+	if spec.Version == "" {
+		spec.Version = e.defaultVersion
+	}
+	if spec.Username == "" {
+		spec.Username = defaultUsername
+	}
+	if spec.DBName == "" {
+		spec.DBName = strings.ReplaceAll(spec.Name, "-", "_")
+	}
+	if spec.Password == "" {
+		spec.Password = generatePassword()
+	}
+	if spec.Engine == "mysql" {
+		spec.RootPassword = generatePassword()
+	}
+	if spec.BackupRetention <= 0 {
+		spec.BackupRetention = defaultRetention
+	}
+	if spec.Port < 0 || spec.Port > 65535 {
+		return nil, fmt.Errorf("invalid port %d", spec.Port)
+	}
+	spec.Volume = "localcloud-db-" + spec.Name
+	spec.Status = StatusCreating
+	spec.InstanceID = ""
+	spec.Endpoint = ""
+	spec.Error = ""
+	spec.LastBackup = nil
+	spec.Created = time.Now()
+
+	s.mu.Lock()
+	if _, exists := s.databases[spec.Name]; exists {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("database %q already exists", spec.Name)
+	}
+	db := spec
+	s.databases[spec.Name] = &db
+	s.creating[spec.Name] = true
+	err = s.saveLocked()
+	result := info(&db)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	go s.provision(spec)
+	return &result, nil
+}
+
+// Start the engine container and wait for it to accept connections
+func (s *Service) provision(db Database) {
+	defer func() {
+		s.mu.Lock()
+		delete(s.creating, db.Name)
+		s.mu.Unlock()
+	}()
+	e, _ := lookupEngine(db.Engine)
+
+	hostPort := ""
+	if db.Port > 0 {
+		hostPort = fmt.Sprint(db.Port)
+	}
+	instance, err := s.manager.Create(compute.CreateSpec{
+		Image:    e.image + ":" + db.Version,
+		Name:     "db-" + db.Name,
+		Ports:    fmt.Sprintf("%s:%d", hostPort, e.port),
+		Env:      e.env(&db),
+		MemoryMB: db.MemoryMB,
+		Volumes:  []string{db.Volume + ":" + e.dataDir},
+		Labels:   map[string]string{LabelDatabase: db.Name},
+	})
+	if err != nil {
+		s.update(db.Name, func(d *Database) {
+			d.Status = StatusFailed
+			d.Error = err.Error()
+		})
+		return
+	}
+	s.mu.Lock()
+	_, exists := s.databases[db.Name]
+	s.mu.Unlock()
+	if !exists {
+		// deleted while the instance was starting
+		s.manager.Delete(instance.ID)
+		return
+	}
+	s.update(db.Name, func(d *Database) { d.InstanceID = instance.ID })
+
+	ctx, cancel := context.WithTimeout(context.Background(), readyTimeout)
+	defer cancel()
+
+	endpoint, err := s.manager.Address(ctx, instance.ID, e.port)
+	if err == nil {
+		err = s.waitReady(ctx, &db, instance.ID)
+	}
+	if err != nil {
+		s.update(db.Name, func(d *Database) {
+			d.Status = StatusFailed
+			d.Error = err.Error()
+		})
+		return
+	}
+
+	s.update(db.Name, func(d *Database) {
+		d.Endpoint = endpoint
+		d.Status = StatusAvailable
+		d.Error = ""
+	})
+}
+
+func (s *Service) waitReady(ctx context.Context, db *Database, instanceID string) error {
+	e, _ := lookupEngine(db.Engine)
+	for {
+		if _, code, err := s.manager.ExecIO(ctx, instanceID, e.ready(db), e.execEnv(db), nil, nil); err == nil && code == 0 {
+			return nil
+		}
+
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			logs, _ := s.manager.GetLogs(instanceID, 20)
+			return fmt.Errorf("database did not become ready: %s", logs)
+		}
+	}
+}
+
+// Remove the instance, its volume and backups
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	db, ok := s.databases[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("database %q not found", name)
+	}
+	if s.busy[name] {
+		s.mu.Unlock()
+		return fmt.Errorf("database %q has a backup or restore in progress", name)
+	}
+	delete(s.databases, name)
+	delete(s.backups, name)
+	err := s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range s.manager.ListByLabel(LabelDatabase, name) {
+		s.manager.Delete(instance.ID)
+	}
+	if err := s.manager.RemoveVolume(db.Volume); err != nil {
+		log.Printf("databases: %v", err)
+	}
+	return os.RemoveAll(filepath.Join(s.dir, name))
+}
+
+// Dump the database to local storage
+func (s *Service) Backup(name string) (*Backup, error) {
+	return s.backup(name, false)
+}
+
+func (s *Service) backup(name string, automatic bool) (*Backup, error) {
+	db, err := s.acquire(name, StatusBackingUp)
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseDB(name)
+
+	e, _ := lookupEngine(db.Engine)
+	b := Backup{
+		ID:        s.newBackupID(name),
+		Database:  name,
+		Engine:    db.Engine,
+		Automatic: automatic,
+		Created:   time.Now(),
+	}
+
+	path := s.backupFile(name, b.ID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	stderr, code, err := s.manager.ExecIO(context.Background(), db.InstanceID, e.dump(&db), e.execEnv(&db), nil, file)
+	file.Close()
+	if err == nil && code != 0 {
+		err = fmt.Errorf("dump exited with code %d: %s", code, strings.TrimSpace(stderr))
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	if stat, err := os.Stat(path); err == nil {
+		b.Size = stat.Size()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backups := append(s.backups[name], b)
+	if automatic {
+		backups = s.pruneLocked(name, backups, db.BackupRetention)
+	}
+	s.backups[name] = backups
+	if d, ok := s.databases[name]; ok {
+		d.LastBackup = &b.Created
+	}
+	if err := store.Save(s.backupsPath(name), backups); err != nil {
+		return nil, err
+	}
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Drop the oldest automatic backups beyond the retention count
+func (s *Service) pruneLocked(name string, backups []Backup, retention int) []Backup {
+	automatic := 0
+	for _, b := range backups {
+		if b.Automatic {
+			automatic++
+		}
+	}
+
+	kept := backups[:0]
+	for _, b := range backups {
+		if b.Automatic && automatic > retention {
+			automatic--
+			os.Remove(s.backupFile(name, b.ID))
+			continue
+		}
+		kept = append(kept, b)
+	}
+	return kept
+}
+
+// Oldest first
+func (s *Service) Backups(name string) ([]Backup, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.databases[name]; !ok {
+		return nil, fmt.Errorf("database %q not found", name)
+	}
+	backups := make([]Backup, len(s.backups[name]))
+	copy(backups, s.backups[name])
+	return backups, nil
+}
+
+// Load a backup into the database, replacing what is there. An empty
+// backupID restores the latest backup.
+func (s *Service) Restore(name, backupID string) (*Backup, error) {
+	s.mu.Lock()
+	var backup *Backup
+	for i, b := range s.backups[name] {
+		if b.ID == backupID || backupID == "" {
+			backup = &s.backups[name][i]
+		}
+	}
+	s.mu.Unlock()
+	if backup == nil {
+		if backupID == "" {
+			return nil, fmt.Errorf("database %q has no backups", name)
+		}
+		return nil, fmt.Errorf("backup %q not found", backupID)
+	}
+	restored := *backup
+
+	db, err := s.acquire(name, StatusRestoring)
+	if err != nil {
+		return nil, err
+	}
+	defer s.releaseDB(name)
+
+	file, err := os.Open(s.backupFile(name, restored.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer file.Close()
+
+	e, _ := lookupEngine(db.Engine)
+	stderr, code, err := s.manager.ExecIO(context.Background(), db.InstanceID, e.restore(&db), e.execEnv(&db), file, nil)
+	if err == nil && code != 0 {
+		err = fmt.Errorf("restore exited with code %d: %s", code, strings.TrimSpace(stderr))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore database: %w", err)
+	}
+	return &restored, nil
+}
+
+// Path of a backup's SQL file
+func (s *Service) BackupFile(name, backupID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, b := range s.backups[name] {
+		if b.ID == backupID {
+			return s.backupFile(name, b.ID), nil
+		}
+	}
+	return "", fmt.Errorf("backup %q not found", backupID)
+}
+
+// Mark an available database busy for a backup or restore
+func (s *Service) acquire(name, status string) (Database, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db, ok := s.databases[name]
+	if !ok {
+		return Database{}, fmt.Errorf("database %q not found", name)
+	}
+	if s.busy[name] {
+		return Database{}, fmt.Errorf("database %q has a backup or restore in progress", name)
+	}
+	if db.Status != StatusAvailable {
+		return Database{}, fmt.Errorf("database %q is %s", name, db.Status)
+	}
+	s.busy[name] = true
+	db.Status = status
+	return *db, nil
+}
+
+func (s *Service) releaseDB(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.busy, name)
+	if db, ok := s.databases[name]; ok {
+		db.Status = StatusAvailable
+	}
+}
+
+// Refresh status from the instances and run automatic backups that are due
+func (s *Service) check() {
+	s.mu.Lock()
+	var dbs []Database
+	for name, db := range s.databases {
+		// databases left creating by a previous run are checked like any other
+		if db.InstanceID == "" || s.creating[name] || s.busy[name] {
+			continue
+		}
+		dbs = append(dbs, *db)
+	}
+	s.mu.Unlock()
+
+	for _, db := range dbs {
+		e, _ := lookupEngine(db.Engine)
+
+		status := StatusUnavailable
+		ctx, cancel := context.WithTimeout(context.Background(), checkInterval)
+		if _, code, err := s.manager.ExecIO(ctx, db.InstanceID, e.ready(&db), e.execEnv(&db), nil, nil); err == nil && code == 0 {
+			status = StatusAvailable
+		}
+		endpoint, _ := s.manager.Address(ctx, db.InstanceID, e.port)
+		cancel()
+
+		s.update(db.Name, func(d *Database) {
+			if s.busy[d.Name] {
+				return
+			}
+			d.Status = status
+			if endpoint != "" {
+				d.Endpoint = endpoint
+			}
+		})
+
+		due := db.LastBackup == nil || time.Since(*db.LastBackup) >= time.Duration(db.BackupInterval)*time.Hour
+		if status == StatusAvailable && db.BackupInterval > 0 && due {
+			if _, err := s.backup(db.Name, true); err != nil {
+				log.Printf("databases: automatic backup of %s: %v", db.Name, err)
+			}
+		}
+	}
+}
+
+// Change a database record and persist it, if it still exists
+func (s *Service) update(name string, change func(*Database)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	db, ok := s.databases[name]
+	if !ok {
+		return
+	}
+	before := *db
+	change(db)
+	if *db == before {
+		return
+	}
+	if err := s.saveLocked(); err != nil {
+		log.Printf("databases: %v", err)
+	}
+}
+
+func (s *Service) saveLocked() error {
+	return store.Save(filepath.Join(s.dir, "databases.json"), s.databases)
+}
+
+// Backup IDs are timestamps, made unique within the second
+func (s *Service) newBackupID(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	base := time.Now().UTC().Format("20060102-150405")
+	id := base
+	for n := 2; ; n++ {
+		taken := false
+		for _, b := range s.backups[name] {
+			if b.ID == id {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return id
+		}
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+func (s *Service) backupsPath(name string) string {
+	return filepath.Join(s.dir, name, "backups.json")
+}
+
+func (s *Service) backupFile(name, id string) string {
+	return filepath.Join(s.dir, name, "backups", id+".sql")
+}
+
+func info(db *Database) Info {
+	e, _ := lookupEngine(db.Engine)
+	return Info{
+		Database:         *db,
+		ConnectionString: e.connectionString(db),
+		ShellCommand:     e.shell(db),
+	}
+}
+
+// Random hex, safe in connection strings without escaping
+func generatePassword() string {
+	buf := make([]byte, 12)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
@@ -0,0 +1,288 @@
+// Internal DNS and service discovery for LocalCloud instances
+package dns
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+)
+
+const (
+	refreshInterval = 2 * time.Second
+	defaultTTL      = 5 // seconds, records change as instances come and go
+)
+
+// Record sources
+const (
+	SourceInstance = "instance"
+	SourceCustom   = "custom"
+)
+
+type Record struct {
+	Name   string `json:"name"`           // fully qualified, no trailing dot
+	Type   string `json:"type"`           // A, CNAME, TXT or SRV
+	Value  string `json:"value"`          // IP, target name or text
+	Port   int    `json:"port,omitempty"` // SRV only
+	TTL    int    `json:"ttl,omitempty"`
+	Source string `json:"source"`
+}
+
+// Supplies records for things other than instances (groups, load balancers...)
+type Source func() []Record
+
+type Service struct {
+	manager  *compute.Manager
+	zone     string
+	upstream string
+	path     string
+
+	mu      sync.RWMutex
+	custom  []Record
+	records []Record
+	sources []Source
+
+	udp net.PacketConn
+	tcp net.Listener
+}
+
+func NewService(manager *compute.Manager, zone, upstream, dataDir string) (*Service, error) {
+	s := &Service{
+		manager:  manager,
+		zone:     strings.ToLower(strings.Trim(zone, ".")),
+		upstream: upstream,
+		path:     filepath.Join(dataDir, "dns.json"),
+	}
+	if err := store.Load(s.path, &s.custom); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Service) Zone() string {
+	return s.zone
+}
+
+// Register a source of generated records; call before Start
+func (s *Service) AddSource(source Source) {
+	s.sources = append(s.sources, source)
+}
+
+// Listen on addr (UDP and TCP) and keep records in sync with instances
+func (s *Service) Start(ctx context.Context, addr string) error {
+	udp, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on udp %s: %w", addr, err)
+	}
+	tcp, err := net.Listen("tcp", addr)
+	if err != nil {
+		udp.Close()
+		return fmt.Errorf("failed to listen on tcp %s: %w", addr, err)
+	}
+	s.udp = udp
+	s.tcp = tcp
+
+	s.refresh()
+	go s.serveUDP()
+	go s.serveTCP()
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.refresh()
+			case <-ctx.Done():
+				udp.Close()
+				tcp.Close()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Every record currently served, generated and custom
+func (s *Service) Records() []Record {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]Record, len(s.records))
+	copy(records, s.records)
+	return records
+}
+
+// Records matching a name, of any type
+func (s *Service) Lookup(name string) []Record {
+	name = s.qualify(name)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matches []Record
+	for _, r := range s.records {
+		if r.Name == name {
+			matches = append(matches, r)
+		}
+	}
+	return matches
+}
+
+func (s *Service) AddRecord(r Record) (*Record, error) {
+	r.Name = s.qualify(r.Name)
+	r.Type = strings.ToUpper(r.Type)
+	r.Source = SourceCustom
+	if r.TTL <= 0 {
+		r.TTL = defaultTTL
+	}
+	if err := validate(r); err != nil {
+		return nil, err
+	}
+	if r.Type == "CNAME" || r.Type == "SRV" {
+		r.Value = strings.ToLower(strings.TrimSuffix(r.Value, "."))
+	}
+
+	s.mu.Lock()
+	for _, existing := range s.custom {
+		if existing.Name == r.Name && existing.Type == r.Type && existing.Value == r.Value && existing.Port == r.Port {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("record already exists")
+		}
+	}
+	s.custom = append(s.custom, r)
+	err := store.Save(s.path, s.custom)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.refresh()
+	return &r, nil
+}
+
+// Remove custom records by name, optionally only one type
+func (s *Service) DeleteRecords(name, recordType string) (int, error) {
+	name = s.qualify(name)
+	recordType = strings.ToUpper(recordType)
+
+	s.mu.Lock()
+	kept := s.custom[:0]
+	removed := 0
+	for _, r := range s.custom {
+		if r.Name == name && (recordType == "" || r.Type == recordType) {
+			removed++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	s.custom = kept
+	err := store.Save(s.path, s.custom)
+	s.mu.Unlock()
+	if err != nil {
+		return 0, err
+	}
+	if removed == 0 {
+		return 0, fmt.Errorf("no custom records named %s", name)
+	}
+
+	s.refresh()
+	return removed, nil
+}
+
+// Fully qualified name for a label like "db" or an existing FQDN
+func (s *Service) qualify(name string) string {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	if name == s.zone || strings.HasSuffix(name, "."+s.zone) {
+		return name
+	}
+	return name + "." + s.zone
+}
+
+func (s *Service) inZone(name string) bool {
+	return name == s.zone || strings.HasSuffix(name, "."+s.zone)
+}
+
+// Rebuild generated records from instances and sources
+func (s *Service) refresh() {
+	var records []Record
+
+	for _, instance := range s.manager.List() {
+		if instance.State != "running" || instance.IP == "" {
+			continue
+		}
+		name := s.qualify(instance.Name)
+		records = append(records, Record{Name: name, Type: "A", Value: instance.IP, TTL: defaultTTL, Source: SourceInstance})
+
+		// SRV for each published container port
+		for _, mapping := range strings.Fields(instance.Ports) {
+			parts := strings.Split(mapping, ":")
+			port, err := strconv.Atoi(parts[len(parts)-1])
+			if err != nil {
+				continue
+			}
+			records = append(records, Record{
+				Name:   s.ServiceName(instance.Name),
+				Type:   "SRV",
+				Value:  name,
+				Port:   port,
+				TTL:    defaultTTL,
+				Source: SourceInstance,
+			})
+		}
+	}
+
+	for _, source := range s.sources {
+		for _, r := range source() {
+			r.Name = s.qualify(r.Name)
+			if r.TTL <= 0 {
+				r.TTL = defaultTTL
+			}
+			records = append(records, r)
+		}
+	}
+
+	s.mu.Lock()
+	records = append(records, s.custom...)
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+	s.records = records
+	s.mu.Unlock()
+}
+
+// SRV name for a service, _<name>._tcp.<zone>
+func (s *Service) ServiceName(name string) string {
+	return "_" + strings.ToLower(name) + "._tcp." + s.zone
+}
+
+func validate(r Record) error {
+	if r.Name == "" {
+		return fmt.Errorf("record name is required")
+	}
+	switch r.Type {
+	case "A":
+		if ip := net.ParseIP(r.Value); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("A record value must be an IPv4 address")
+		}
+	case "CNAME":
+		if r.Value == "" {
+			return fmt.Errorf("CNAME record needs a target name")
+		}
+	case "TXT":
+	case "SRV":
+		if r.Value == "" || r.Port <= 0 || r.Port > 65535 {
+			return fmt.Errorf("SRV record needs a target name and port")
+		}
+	default:
+		return fmt.Errorf("unsupported record type %q", r.Type)
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package dns
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/go-connections/nat"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+func newTestService(t *testing.T) (*Service, *dockertest.Server, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s, err := NewService(manager, "localcloud.internal.", "127.0.0.1:1", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, docker, dir
+}
+
+func query(t *testing.T, name string, qtype dnsmessage.Type) []byte {
+	t.Helper()
+	msg := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: 42, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: dnsmessage.MustNewName(name), Type: qtype, Class: dnsmessage.ClassINET}},
+	}
+	req, err := msg.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func parse(t *testing.T, resp []byte) dnsmessage.Message {
+	t.Helper()
+	var msg dnsmessage.Message
+	if err := msg.Unpack(resp); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestQualify(t *testing.T) {
+	s, _, _ := newTestService(t)
+	tests := map[string]string{
+		"db":                     "db.localcloud.internal",
+		"DB.":                    "db.localcloud.internal",
+		"db.localcloud.internal": "db.localcloud.internal",
+		"localcloud.internal":    "localcloud.internal",
+		"a.b":                    "a.b.localcloud.internal",
+	}
+	for name, want := range tests {
+		if got := s.qualify(name); got != want {
+			t.Errorf("qualify(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestInstanceRecords(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	docker.AddContainer(dockertest.Container{
+		Name:  "web",
+		Image: "nginx",
+		IP:    "172.17.0.2",
+		Ports: nat.PortMap{"80/tcp": {{HostPort: "8080"}}},
+	})
+	docker.AddContainer(dockertest.Container{Name: "stopped", Image: "nginx", IP: "172.17.0.3", State: "exited"})
+	docker.AddContainer(dockertest.Container{Name: "noip", Image: "nginx"})
+	s.AddSource(func() []Record {
+		return []Record{{Name: "lb", Type: "CNAME", Value: "web.localcloud.internal", Source: "loadbalancer"}}
+	})
+	s.refresh()
+
+	a := s.Lookup("web")
+	if len(a) != 1 || a[0].Type != "A" || a[0].Value != "172.17.0.2" || a[0].Source != SourceInstance {
+		t.Errorf("web = %+v", a)
+	}
+	srv := s.Lookup(s.ServiceName("web"))
+	if len(srv) != 1 || srv[0].Value != "web.localcloud.internal" || srv[0].Port != 80 {
+		t.Errorf("SRV = %+v", srv)
+	}
+	if len(s.Lookup("stopped")) != 0 || len(s.Lookup("noip")) != 0 {
+		t.Error("records for instances that are not reachable")
+	}
+	if lb := s.Lookup("lb"); len(lb) != 1 || lb[0].TTL != defaultTTL {
+		t.Errorf("source records = %+v", lb)
+	}
+
+	// records follow the instance away
+	docker.SetState("web", "exited")
+	s.refresh()
+	if len(s.Lookup("web")) != 0 {
+		t.Error("record kept for a stopped instance")
+	}
+}
+
+func TestCustomRecords(t *testing.T) {
+	s, _, dir := newTestService(t)
+
+	tests := []struct {
+		r   Record
+		err string
+	}{
+		{Record{Name: "api", Type: "a", Value: "10.0.0.5"}, ""},
+		{Record{Name: "www", Type: "CNAME", Value: "API.localcloud.internal."}, ""},
+		{Record{Name: "api", Type: "TXT", Value: "v=1"}, ""},
+		{Record{Name: "api", Type: "A", Value: "10.0.0.5"}, "already exists"},
+		{Record{Name: "api", Type: "A", Value: "::1"}, "IPv4"},
+		{Record{Name: "api", Type: "CNAME"}, "target name"},
+		{Record{Name: "_api._tcp", Type: "SRV", Value: "api"}, "port"},
+		{Record{Name: "api", Type: "MX", Value: "mail"}, "unsupported"},
+	}
+	for _, tt := range tests {
+		_, err := s.AddRecord(tt.r)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("AddRecord(%+v) = %v, want %q", tt.r, err, tt.err)
+		}
+	}
+	if www := s.Lookup("www"); len(www) != 1 || www[0].Value != "api.localcloud.internal" || www[0].Source != SourceCustom {
+		t.Errorf("www = %+v", www)
+	}
+
+	removed, err := s.DeleteRecords("api", "txt")
+	if err != nil || removed != 1 {
+		t.Fatalf("DeleteRecords = %d, %v", removed, err)
+	}
+	if _, err := s.DeleteRecords("missing", ""); err == nil {
+		t.Error("deleted records that do not exist")
+	}
+
+	// custom records survive a restart
+	reopened, err := NewService(s.manager, "localcloud.internal", "", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.refresh()
+	if got := len(reopened.Records()); got != 2 {
+		t.Errorf("after reopening: %d records, want 2", got)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	docker.AddContainer(dockertest.Container{
+		Name:  "db",
+		Image: "postgres",
+		IP:    "172.17.0.4",
+		Ports: nat.PortMap{"5432/tcp": {{HostPort: "5432"}}},
+	})
+	s.refresh()
+	if _, err := s.AddRecord(Record{Name: "primary", Type: "CNAME", Value: "db.localcloud.internal"}); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := parse(t, s.handle(query(t, "db.localcloud.internal.", dnsmessage.TypeA), true))
+	if msg.ID != 42 || !msg.Authoritative || msg.RCode != dnsmessage.RCodeSuccess || len(msg.Answers) != 1 {
+		t.Fatalf("A response = %+v", msg)
+	}
+	if a := msg.Answers[0].Body.(*dnsmessage.AResource).A; net.IP(a[:]).String() != "172.17.0.4" {
+		t.Errorf("A = %v", a)
+	}
+
+	// a CNAME to one of ours is followed
+	msg = parse(t, s.handle(query(t, "primary.localcloud.internal.", dnsmessage.TypeA), true))
+	if len(msg.Answers) != 2 || msg.Answers[0].Header.Type != dnsmessage.TypeCNAME || msg.Answers[1].Header.Type != dnsmessage.TypeA {
+		t.Errorf("CNAME response = %+v", msg.Answers)
+	}
+
+	// SRV answers carry the target's address
+	msg = parse(t, s.handle(query(t, "_db._tcp.localcloud.internal.", dnsmessage.TypeSRV), true))
+	if len(msg.Answers) != 1 || msg.Answers[0].Body.(*dnsmessage.SRVResource).Port != 5432 || len(msg.Additionals) != 1 {
+		t.Errorf("SRV response = %+v", msg)
+	}
+
+	msg = parse(t, s.handle(query(t, "missing.localcloud.internal.", dnsmessage.TypeA), true))
+	if msg.RCode != dnsmessage.RCodeNameError {
+		t.Errorf("missing name: %v", msg.RCode)
+	}
+
+	// the name exists, just not with that type
+	msg = parse(t, s.handle(query(t, "db.localcloud.internal.", dnsmessage.TypeTXT), true))
+	if msg.RCode != dnsmessage.RCodeSuccess || len(msg.Answers) != 0 {
+		t.Errorf("other type: %v with %d answers", msg.RCode, len(msg.Answers))
+	}
+}
+
+func TestHandleTruncatesLargeUDPAnswers(t *testing.T) {
+	s, _, _ := newTestService(t)
+	for i := 0; i < 10; i++ {
+		if _, err := s.AddRecord(Record{Name: "big", Type: "TXT", Value: strings.Repeat(string(rune('a'+i)), 100)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	msg := parse(t, s.handle(query(t, "big.localcloud.internal.", dnsmessage.TypeTXT), true))
+	if !msg.Truncated || len(msg.Answers) != 0 {
+		t.Errorf("UDP: truncated %v with %d answers", msg.Truncated, len(msg.Answers))
+	}
+	msg = parse(t, s.handle(query(t, "big.localcloud.internal.", dnsmessage.TypeTXT), false))
+	if msg.Truncated || len(msg.Answers) != 10 {
+		t.Errorf("TCP: truncated %v with %d answers", msg.Truncated, len(msg.Answers))
+	}
+}
+
+func TestForward(t *testing.T) {
+	s, _, _ := newTestService(t)
+	upstream, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer upstream.Close()
+	s.upstream = upstream.LocalAddr().String()
+
+	go func() {
+		buf := make([]byte, 512)
+		n, addr, err := upstream.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		var msg dnsmessage.Message
+		msg.Unpack(buf[:n])
+		msg.Response = true
+		msg.Answers = []dnsmessage.Resource{{
+			Header: dnsmessage.ResourceHeader{Name: msg.Questions[0].Name, Type: dnsmessage.TypeA, Class: dnsmessage.ClassINET},
+			Body:   &dnsmessage.AResource{A: [4]byte{93, 184, 216, 34}},
+		}}
+		resp, _ := msg.Pack()
+		upstream.WriteTo(resp, addr)
+	}()
+
+	msg := parse(t, s.handle(query(t, "example.com.", dnsmessage.TypeA), true))
+	if msg.ID != 42 || len(msg.Answers) != 1 {
+		t.Errorf("forwarded response = %+v", msg)
+	}
+
+	// the upstream is gone now
+	upstream.Close()
+	msg = parse(t, s.handle(query(t, "example.org.", dnsmessage.TypeA), true))
+	if msg.RCode != dnsmessage.RCodeServerFailure {
+		t.Errorf("without upstream: %v", msg.RCode)
+	}
+}
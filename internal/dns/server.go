@@ -0,0 +1,277 @@
+package dns
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	maxUDPSize      = 512
+	upstreamTimeout = 3 * time.Second
+)
+
+func (s *Service) serveUDP() {
+	buf := make([]byte, 4096)
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req := make([]byte, n)
+		copy(req, buf[:n])
+
+		go func() {
+			if resp := s.handle(req, true); resp != nil {
+				s.udp.WriteTo(resp, addr)
+			}
+		}()
+	}
+}
+
+func (s *Service) serveTCP() {
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			return
+		}
+		go s.handleTCPConn(conn)
+	}
+}
+
+// TCP messages are prefixed with a two byte length
+func (s *Service) handleTCPConn(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+		var length uint16
+		if err := binary.Read(conn, binary.BigEndian, &length); err != nil {
+			return
+		}
+		req := make([]byte, length)
+		if _, err := io.ReadFull(conn, req); err != nil {
+			return
+		}
+
+		resp := s.handle(req, false)
+		if resp == nil {
+			return
+		}
+		out := make([]byte, 2+len(resp))
+		binary.BigEndian.PutUint16(out, uint16(len(resp)))
+		copy(out[2:], resp)
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+// Answer names in the zone, forward everything else upstream
+func (s *Service) handle(req []byte, udp bool) []byte {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(req)
+	if err != nil {
+		return nil
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil
+	}
+
+	name := strings.ToLower(strings.TrimSuffix(question.Name.String(), "."))
+	if !s.inZone(name) {
+		if resp, err := s.forward(req); err == nil {
+			return resp
+		}
+		return s.reply(header, question, dnsmessage.RCodeServerFailure, nil, nil, false)
+	}
+
+	answers, additional, exists := s.answer(name, question.Type)
+	rcode := dnsmessage.RCodeSuccess
+	if !exists {
+		rcode = dnsmessage.RCodeNameError
+	}
+
+	resp := s.reply(header, question, rcode, answers, additional, false)
+	if udp && len(resp) > maxUDPSize {
+		resp = s.reply(header, question, rcode, nil, nil, true)
+	}
+	return resp
+}
+
+// Records answering a question, extra A records for SRV targets, and
+// whether the name exists at all
+func (s *Service) answer(name string, qtype dnsmessage.Type) ([]Record, []Record, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	byName := func(n string) []Record {
+		var matches []Record
+		for _, r := range s.records {
+			if r.Name == n {
+				matches = append(matches, r)
+			}
+		}
+		return matches
+	}
+
+	records := byName(name)
+	if len(records) == 0 {
+		return nil, nil, false
+	}
+
+	var answers []Record
+	for _, r := range records {
+		if qtype == dnsmessage.TypeALL || recordType(r.Type) == qtype {
+			answers = append(answers, r)
+		}
+	}
+
+	// Follow a CNAME one level when the target is ours
+	if len(answers) == 0 && qtype != dnsmessage.TypeCNAME {
+		for _, r := range records {
+			if r.Type != "CNAME" {
+				continue
+			}
+			answers = append(answers, r)
+			for _, target := range byName(r.Value) {
+				if recordType(target.Type) == qtype {
+					answers = append(answers, target)
+				}
+			}
+		}
+	}
+
+	var additional []Record
+	for _, r := range answers {
+		if r.Type != "SRV" {
+			continue
+		}
+		for _, target := range byName(r.Value) {
+			if target.Type == "A" {
+				additional = append(additional, target)
+			}
+		}
+	}
+	return answers, additional, true
+}
+
+func (s *Service) reply(req dnsmessage.Header, question dnsmessage.Question, rcode dnsmessage.RCode, answers, additional []Record, truncated bool) []byte {
+	builder := dnsmessage.NewBuilder(make([]byte, 0, maxUDPSize), dnsmessage.Header{
+		ID:                 req.ID,
+		Response:           true,
+		OpCode:             req.OpCode,
+		Authoritative:      rcode != dnsmessage.RCodeServerFailure,
+		Truncated:          truncated,
+		RecursionDesired:   req.RecursionDesired,
+		RecursionAvailable: true,
+		RCode:              rcode,
+	})
+	builder.EnableCompression()
+
+	if err := builder.StartQuestions(); err != nil {
+		return nil
+	}
+	if err := builder.Question(question); err != nil {
+		return nil
+	}
+
+	if err := builder.StartAnswers(); err != nil {
+		return nil
+	}
+	for _, r := range answers {
+		if err := addResource(&builder, r); err != nil {
+			return nil
+		}
+	}
+
+	if err := builder.StartAdditionals(); err != nil {
+		return nil
+	}
+	for _, r := range additional {
+		if err := addResource(&builder, r); err != nil {
+			return nil
+		}
+	}
+
+	resp, err := builder.Finish()
+	if err != nil {
+		return nil
+	}
+	return resp
+}
+
+func addResource(builder *dnsmessage.Builder, r Record) error {
+	name, err := dnsmessage.NewName(r.Name + ".")
+	if err != nil {
+		return err
+	}
+	header := dnsmessage.ResourceHeader{Name: name, Class: dnsmessage.ClassINET, TTL: uint32(r.TTL)}
+
+	switch r.Type {
+	case "A":
+		ip := net.ParseIP(r.Value).To4()
+		if ip == nil {
+			return nil
+		}
+		var a [4]byte
+		copy(a[:], ip)
+		return builder.AResource(header, dnsmessage.AResource{A: a})
+	case "CNAME":
+		target, err := dnsmessage.NewName(r.Value + ".")
+		if err != nil {
+			return err
+		}
+		return builder.CNAMEResource(header, dnsmessage.CNAMEResource{CNAME: target})
+	case "TXT":
+		return builder.TXTResource(header, dnsmessage.TXTResource{TXT: []string{r.Value}})
+	case "SRV":
+		target, err := dnsmessage.NewName(r.Value + ".")
+		if err != nil {
+			return err
+		}
+		return builder.SRVResource(header, dnsmessage.SRVResource{Port: uint16(r.Port), Target: target})
+	}
+	return nil
+}
+
+func recordType(t string) dnsmessage.Type {
+	switch t {
+	case "A":
+		return dnsmessage.TypeA
+	case "CNAME":
+		return dnsmessage.TypeCNAME
+	case "TXT":
+		return dnsmessage.TypeTXT
+	case "SRV":
+		return dnsmessage.TypeSRV
+	}
+	return 0
+}
+
+// Pass a query for a name outside the zone to the upstream resolver
+func (s *Service) forward(req []byte) ([]byte, error) {
+	conn, err := net.DialTimeout("udp", s.upstream, upstreamTimeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(upstreamTimeout))
+	if _, err := conn.Write(req); err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 4096)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
@@ -0,0 +1,506 @@
+// Fake Docker daemon for tests. It keeps containers in memory and answers
+// the calls LocalCloud makes to create, start, stop, inspect, list and
+// remove and rename them, update their resources, wait for them and read
+// their logs; anything else can be answered with Handle.
+package dockertest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
+)
+
+// API version the fake reports
+const apiVersion = "1.44"
+
+type Container struct {
+	ID         string
+	Name       string
+	Image      string
+	Labels     map[string]string
+	State      string // created, running or exited
+	Created    time.Time
+	Ports      nat.PortMap // published ports
+	IP         string      // address on the bridge network
+	Output     string      // what the logs call returns, as stdout
+	ExitCode   int         // what wait reports once the container stops
+	RunToExit  bool        // stop with ExitCode as soon as it is started
+	Config     *container.Config
+	HostConfig *container.HostConfig
+}
+
+// Runs a command exec'd in a container. stdin is nil unless the client
+// attached it. Returns the exit code.
+type ExecFunc func(c Container, cmd, env []string, stdin io.Reader, stdout, stderr io.Writer) int
+
+type Server struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	containers map[string]*Container // by ID
+	routes     map[string]interface{}
+	requests   []string
+	onCreate   func(*Container)
+	execFunc   ExecFunc
+	execs      map[string]*execution
+	nextID     int
+}
+
+// How often a wait checks whether its container stopped
+const waitInterval = 10 * time.Millisecond
+
+type execution struct {
+	container Container
+	config    types.ExecConfig
+	exitCode  int
+	done      bool
+}
+
+var (
+	versionPrefix = regexp.MustCompile(`^/v[0-9.]+`)
+	containerPath = regexp.MustCompile(`^/containers/([^/]+)(/[a-z]+)?$`)
+	execPath      = regexp.MustCompile(`^/exec/([^/]+)/(start|json)$`)
+)
+
+// Start a fake daemon and point DOCKER_HOST at it for the rest of the test
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	s := &Server{
+		containers: make(map[string]*Container),
+		routes:     make(map[string]interface{}),
+		execs:      make(map[string]*execution),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	t.Setenv("DOCKER_HOST", "tcp://"+s.Listener.Addr().String())
+	t.Setenv("DOCKER_API_VERSION", apiVersion)
+	return s
+}
+
+// Answer "METHOD /path" (without the API version) with response: nil for
+// 204, an http.HandlerFunc, or anything else as JSON. Handled routes take
+// precedence over the built-in container calls.
+func (s *Server) Handle(route string, response interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[route] = response
+}
+
+// Add a container as if it had been created outside the test. ID, State
+// and Created get defaults if unset.
+func (s *Server) AddContainer(c Container) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.ID == "" {
+		c.ID = s.newIDLocked()
+	}
+	if c.State == "" {
+		c.State = "running"
+	}
+	if c.Created.IsZero() {
+		c.Created = time.Now()
+	}
+	if c.Config == nil {
+		c.Config = &container.Config{Image: c.Image, Labels: c.Labels}
+	}
+	s.containers[c.ID] = &c
+	return c.ID
+}
+
+// Adjust containers created through the API before they are stored, e.g.
+// to publish a port on a server the test runs
+func (s *Server) OnCreate(fn func(c *Container)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onCreate = fn
+}
+
+// Run commands exec'd in containers with fn. Without one they succeed
+// with no output.
+func (s *Server) Exec(fn ExecFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.execFunc = fn
+}
+
+// Current containers, oldest first
+func (s *Server) Containers() []Container {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Container, 0, len(s.containers))
+	for _, c := range s.containers {
+		list = append(list, *c)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].Created.Equal(list[j].Created) {
+			return list[i].Created.Before(list[j].Created)
+		}
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Set a container's state, e.g. to make it look like it exited
+func (s *Server) SetState(idOrName, state string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c := s.findLocked(idOrName); c != nil {
+		c.State = state
+	}
+}
+
+// Routes called so far, e.g. "POST /containers/create"
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func (s *Server) newIDLocked() string {
+	s.nextID++
+	return fmt.Sprintf("%064x", s.nextID)
+}
+
+// By ID, unique ID prefix or name, as Docker looks them up
+func (s *Server) findLocked(ref string) *Container {
+	if c, ok := s.containers[ref]; ok {
+		return c
+	}
+	var found *Container
+	for _, c := range s.containers {
+		if c.Name == strings.TrimPrefix(ref, "/") {
+			return c
+		}
+		if strings.HasPrefix(c.ID, ref) {
+			if found != nil {
+				return nil
+			}
+			found = c
+		}
+	}
+	return found
+}
+
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	path := versionPrefix.ReplaceAllString(r.URL.Path, "")
+	route := r.Method + " " + path
+
+	s.mu.Lock()
+	s.requests = append(s.requests, route)
+	response, handled := s.routes[route]
+	s.mu.Unlock()
+
+	w.Header().Set("Api-Version", apiVersion)
+	if handled {
+		switch resp := response.(type) {
+		case nil:
+			w.WriteHeader(http.StatusNoContent)
+		case http.HandlerFunc:
+			resp(w, r)
+		default:
+			writeJSON(w, http.StatusOK, resp)
+		}
+		return
+	}
+
+	switch {
+	case path == "/_ping":
+		w.Write([]byte("OK"))
+	case route == "GET /containers/json":
+		s.list(w, r)
+	case route == "POST /containers/create":
+		s.create(w, r)
+	case execPath.MatchString(path):
+		match := execPath.FindStringSubmatch(path)
+		s.execCall(w, r, match[1], match[2])
+	default:
+		match := containerPath.FindStringSubmatch(path)
+		if match == nil {
+			writeError(w, http.StatusNotFound, "page not found: "+route)
+			return
+		}
+		if route == "POST /containers/"+match[1]+"/wait" {
+			s.wait(w, r, match[1])
+			return
+		}
+		s.containerCall(w, r, match[1], match[2])
+	}
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	args, err := filters.FromJSON(r.URL.Query().Get("filters"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	all := r.URL.Query().Get("all") == "1"
+
+	list := []types.Container{}
+	for _, c := range s.Containers() {
+		if !all && c.State != "running" {
+			continue
+		}
+		if !args.MatchKVList("label", c.Labels) || !args.ExactMatch("status", c.State) {
+			continue
+		}
+		if args.Contains("name") && !args.Match("name", c.Name) {
+			continue
+		}
+		summary := types.Container{
+			ID:      c.ID,
+			Names:   []string{"/" + c.Name},
+			Image:   c.Image,
+			Labels:  c.Labels,
+			State:   c.State,
+			Status:  c.State,
+			Created: c.Created.Unix(),
+		}
+		if c.IP != "" {
+			summary.NetworkSettings = &types.SummaryNetworkSettings{
+				Networks: map[string]*network.EndpointSettings{"bridge": {IPAddress: c.IP}},
+			}
+		}
+		for port, bindings := range c.Ports {
+			for _, b := range bindings {
+				var public uint16
+				fmt.Sscan(b.HostPort, &public)
+				summary.Ports = append(summary.Ports, types.Port{IP: b.HostIP, PrivatePort: uint16(port.Int()), PublicPort: public, Type: port.Proto()})
+			}
+		}
+		list = append(list, summary)
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		*container.Config
+		HostConfig       *container.HostConfig
+		NetworkingConfig *network.NetworkingConfig
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Config == nil {
+		writeError(w, http.StatusBadRequest, "invalid create request")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	name := r.URL.Query().Get("name")
+	id := s.newIDLocked()
+	if name == "" {
+		name = "container-" + id[len(id)-6:]
+	}
+	for _, c := range s.containers {
+		if c.Name == name {
+			writeError(w, http.StatusConflict, fmt.Sprintf("Conflict. The container name %q is already in use", "/"+name))
+			return
+		}
+	}
+	c := &Container{
+		ID:         id,
+		Name:       name,
+		Image:      body.Image,
+		Labels:     body.Labels,
+		State:      "created",
+		Created:    time.Now(),
+		Config:     body.Config,
+		HostConfig: body.HostConfig,
+	}
+	if s.onCreate != nil {
+		s.onCreate(c)
+	}
+	s.containers[id] = c
+	writeJSON(w, http.StatusCreated, container.CreateResponse{ID: id, Warnings: []string{}})
+}
+
+func (s *Server) containerCall(w http.ResponseWriter, r *http.Request, ref, action string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.findLocked(ref)
+	if c == nil {
+		writeError(w, http.StatusNotFound, "No such container: "+ref)
+		return
+	}
+
+	switch r.Method + " " + action {
+	case "GET /json":
+		writeJSON(w, http.StatusOK, inspect(c))
+	case "POST /start", "POST /restart":
+		c.State = "running"
+		if c.HostConfig != nil && len(c.HostConfig.PortBindings) > 0 {
+			c.Ports = c.HostConfig.PortBindings
+		}
+		if c.RunToExit {
+			c.State = "exited"
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case "POST /stop", "POST /kill":
+		c.State = "exited"
+		w.WriteHeader(http.StatusNoContent)
+	case "POST /rename":
+		name := r.URL.Query().Get("name")
+		for _, other := range s.containers {
+			if other.Name == name && other.ID != c.ID {
+				writeError(w, http.StatusConflict, fmt.Sprintf("Conflict. The container name %q is already in use", "/"+name))
+				return
+			}
+		}
+		c.Name = name
+		w.WriteHeader(http.StatusNoContent)
+	case "POST /update":
+		var update container.UpdateConfig
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if c.HostConfig == nil {
+			c.HostConfig = &container.HostConfig{}
+		}
+		c.HostConfig.Resources = update.Resources
+		writeJSON(w, http.StatusOK, container.ContainerUpdateOKBody{})
+	case "GET /logs":
+		w.Header().Set("Content-Type", "application/vnd.docker.multiplexed-stream")
+		w.WriteHeader(http.StatusOK)
+		stdcopy.NewStdWriter(w, stdcopy.Stdout).Write([]byte(c.Output))
+	case "POST /exec":
+		var config types.ExecConfig
+		if err := json.NewDecoder(r.Body).Decode(&config); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		id := s.newIDLocked()
+		s.execs[id] = &execution{container: *c, config: config}
+		writeJSON(w, http.StatusCreated, types.IDResponse{ID: id})
+	case "DELETE ":
+		if c.State == "running" && r.URL.Query().Get("force") != "1" {
+			writeError(w, http.StatusConflict, "You cannot remove a running container")
+			return
+		}
+		delete(s.containers, c.ID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, http.StatusNotFound, "page not found: "+r.Method+" /containers/"+ref+action)
+	}
+}
+
+// Block until the container is no longer running, it is removed or the
+// client gives up
+func (s *Server) wait(w http.ResponseWriter, r *http.Request, ref string) {
+	ticker := time.NewTicker(waitInterval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		c := s.findLocked(ref)
+		var state string
+		var code int
+		if c != nil {
+			state, code = c.State, c.ExitCode
+		}
+		s.mu.Unlock()
+
+		if c == nil || state != "running" {
+			writeJSON(w, http.StatusOK, container.WaitResponse{StatusCode: int64(code)})
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// Start an exec on a hijacked connection, or report how it ended
+func (s *Server) execCall(w http.ResponseWriter, r *http.Request, id, action string) {
+	s.mu.Lock()
+	e, ok := s.execs[id]
+	fn := s.execFunc
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "No such exec instance: "+id)
+		return
+	}
+
+	if action == "json" {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writeJSON(w, http.StatusOK, types.ContainerExecInspect{ExecID: id, ContainerID: e.container.ID, Running: !e.done, ExitCode: e.exitCode})
+		return
+	}
+
+	// the start options precede the hijacked stream
+	var start types.ExecStartCheck
+	json.NewDecoder(r.Body).Decode(&start)
+
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	defer conn.Close()
+	buf.WriteString("HTTP/1.1 101 UPGRADED\r\nContent-Type: application/vnd.docker.raw-stream\r\nConnection: Upgrade\r\nUpgrade: tcp\r\n\r\n")
+	buf.Flush()
+
+	var stdin io.Reader
+	if e.config.AttachStdin {
+		stdin = buf.Reader
+	}
+	code := 0
+	if fn != nil {
+		code = fn(e.container, e.config.Cmd, e.config.Env, stdin, stdcopy.NewStdWriter(conn, stdcopy.Stdout), stdcopy.NewStdWriter(conn, stdcopy.Stderr))
+	}
+
+	s.mu.Lock()
+	e.exitCode = code
+	e.done = true
+	s.mu.Unlock()
+}
+
+func inspect(c *Container) types.ContainerJSON {
+	config := *c.Config
+	config.Labels = c.Labels
+	hostConfig := c.HostConfig
+	if hostConfig == nil {
+		hostConfig = &container.HostConfig{}
+	}
+	return types.ContainerJSON{
+		ContainerJSONBase: &types.ContainerJSONBase{
+			ID:         c.ID,
+			Name:       "/" + c.Name,
+			Image:      c.Image,
+			Created:    c.Created.Format(time.RFC3339Nano),
+			State:      &types.ContainerState{Status: c.State, Running: c.State == "running"},
+			HostConfig: hostConfig,
+		},
+		Config: &config,
+		NetworkSettings: &types.NetworkSettings{
+			NetworkSettingsBase: types.NetworkSettingsBase{Ports: c.Ports},
+		},
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"message": message})
+}
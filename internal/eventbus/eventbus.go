@@ -0,0 +1,677 @@
+// Event bus: rules route LocalCloud and custom events to targets
+package eventbus
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/queues"
+	"localcloud/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Sources of events LocalCloud emits itself
+const (
+	SourceCompute = "localcloud.compute"
+	SourceImages  = "localcloud.images"
+	SourceAlarms  = "localcloud.alarms"
+)
+
+const (
+	bufferSize         = 1000
+	eventsKept         = 200
+	deliveriesKept     = 500
+	maxTargets         = 5
+	defaultMaxAttempts = 4
+	maxAttempts        = 10
+	maxBackoff         = 30 * time.Second
+	deliveryTimeout    = 10 * time.Second
+	redactedSecret     = "********"
+)
+
+// Delivery outcomes
+const (
+	StatusDelivered    = "delivered"
+	StatusFailed       = "failed"
+	StatusDeadLettered = "dead_lettered"
+)
+
+var (
+	ErrRuleNotFound = errors.New("rule not found")
+	ErrRuleExists   = errors.New("rule already exists")
+	ErrInvalidRule  = errors.New("invalid rule")
+	ErrInvalidEvent = errors.New("invalid event")
+)
+
+var validName = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]{0,62}$`)
+
+type Event struct {
+	ID         string          `json:"id"`
+	Source     string          `json:"source"`
+	DetailType string          `json:"detail-type"`
+	Time       time.Time       `json:"time"`
+	Resources  []string        `json:"resources,omitempty"`
+	Detail     json.RawMessage `json:"detail"`
+}
+
+// Sends events matching a pattern to its targets
+type Rule struct {
+	Name         string          `json:"name"`
+	Description  string          `json:"description,omitempty"`
+	EventPattern json.RawMessage `json:"event_pattern"`
+	Targets      []Target        `json:"targets"`
+	Disabled     bool            `json:"disabled,omitempty"`
+	Created      time.Time       `json:"created"`
+}
+
+type Target struct {
+	ID              string `json:"id"`
+	Type            string `json:"type"`                        // webhook, queue or exec
+	URL             string `json:"url,omitempty"`               // webhook
+	Secret          string `json:"secret,omitempty"`            // webhook HMAC key, never shown again
+	Queue           string `json:"queue,omitempty"`             // queue name
+	Instance        string `json:"instance,omitempty"`          // exec: instance ID or name
+	Command         string `json:"command,omitempty"`           // exec: run with sh -c, the event on stdin
+	MaxAttempts     int    `json:"max_attempts"`                // first try plus retries
+	DeadLetterQueue string `json:"dead_letter_queue,omitempty"` // gets events that failed every attempt
+}
+
+// Outcome of sending one event to one target
+type Delivery struct {
+	ID         string    `json:"id"`
+	Time       time.Time `json:"time"`
+	Rule       string    `json:"rule"`
+	Target     string    `json:"target"`
+	TargetType string    `json:"target_type"`
+	EventID    string    `json:"event_id"`
+	DetailType string    `json:"detail_type"`
+	Status     string    `json:"status"` // delivered, failed or dead_lettered
+	Attempts   int       `json:"attempts"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type Service struct {
+	manager *compute.Manager
+	queues  *queues.Service
+	dir     string
+	client  *http.Client
+	events  chan Event
+	ctx     context.Context // cancels retries on shutdown
+
+	mu         sync.Mutex
+	rules      map[string]*Rule
+	patterns   map[string]pattern
+	recent     []Event
+	deliveries []Delivery
+}
+
+func NewService(manager *compute.Manager, queueService *queues.Service, dataDir string) (*Service, error) {
+	s := &Service{
+		manager:  manager,
+		queues:   queueService,
+		dir:      filepath.Join(dataDir, "eventbus"),
+		client:   &http.Client{Timeout: deliveryTimeout},
+		events:   make(chan Event, bufferSize),
+		ctx:      context.Background(),
+		rules:    make(map[string]*Rule),
+		patterns: make(map[string]pattern),
+	}
+	if err := store.Load(filepath.Join(s.dir, "rules.json"), &s.rules); err != nil {
+		return nil, err
+	}
+	if err := store.Load(filepath.Join(s.dir, "deliveries.json"), &s.deliveries); err != nil {
+		return nil, err
+	}
+	for name, rule := range s.rules {
+		p, err := parsePattern(rule.EventPattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		s.patterns[name] = p
+	}
+	return s, nil
+}
+
+// Route events, including Docker lifecycle events, until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	s.ctx = ctx
+	go func() {
+		for {
+			select {
+			case event := <-s.events:
+				s.dispatch(event)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go s.manager.WatchEvents(ctx, s.lifecycle)
+}
+
+// Publish an event from LocalCloud itself. Never blocks; events are
+// dropped if the bus is that far behind.
+func (s *Service) Emit(source, detailType string, resources []string, detail interface{}) {
+	data, err := json.Marshal(detail)
+	if err != nil {
+		log.Printf("eventbus: failed to encode %s event: %v", detailType, err)
+		return
+	}
+	s.enqueue(Event{
+		ID:         uuid.New().String(),
+		Source:     source,
+		DetailType: detailType,
+		Time:       time.Now(),
+		Resources:  resources,
+		Detail:     data,
+	})
+}
+
+// Publish custom events, returning them with their IDs
+func (s *Service) Put(events []Event) ([]Event, error) {
+	for i := range events {
+		event := &events[i]
+		if event.Source == "" || event.DetailType == "" {
+			return nil, fmt.Errorf("%w: source and detail-type are required", ErrInvalidEvent)
+		}
+		if strings.HasPrefix(event.Source, "localcloud.") {
+			return nil, fmt.Errorf("%w: sources starting with localcloud. are reserved", ErrInvalidEvent)
+		}
+		if len(event.Detail) == 0 {
+			event.Detail = json.RawMessage("{}")
+		}
+		var detail map[string]interface{}
+		if err := json.Unmarshal(event.Detail, &detail); err != nil {
+			return nil, fmt.Errorf("%w: detail must be a JSON object", ErrInvalidEvent)
+		}
+	}
+	for i := range events {
+		events[i].ID = uuid.New().String()
+		events[i].Time = time.Now()
+		s.enqueue(events[i])
+	}
+	return events, nil
+}
+
+func (s *Service) enqueue(event Event) {
+	select {
+	case s.events <- event:
+	default:
+		log.Printf("eventbus: dropped %s event %s, the bus is full", event.DetailType, event.ID)
+	}
+}
+
+// Docker container and image changes as bus events
+func (s *Service) lifecycle(e compute.LifecycleEvent) {
+	if e.Type == "image" {
+		s.Emit(SourceImages, "Image Action", []string{e.Name}, map[string]interface{}{
+			"image":  e.Name,
+			"action": e.Action,
+		})
+		return
+	}
+
+	states := map[string]string{
+		"create":  "created",
+		"start":   "running",
+		"die":     "exited",
+		"stop":    "stopped",
+		"restart": "restarted",
+		"oom":     "oom_killed",
+		"pause":   "paused",
+		"unpause": "running",
+		"destroy": "deleted",
+	}
+	detail := map[string]interface{}{
+		"instance":    e.Name,
+		"instance_id": e.ID,
+		"action":      e.Action,
+		"state":       states[e.Action],
+		"image":       e.Image,
+		"managed":     e.Managed,
+	}
+	if e.Project != "" {
+		detail["project"] = e.Project
+	}
+	if code, err := strconv.Atoi(e.ExitCode); err == nil {
+		detail["exit_code"] = code
+	}
+	s.Emit(SourceCompute, "Instance State Change", []string{e.Name}, detail)
+}
+
+// Match an event against every enabled rule and start its deliveries
+func (s *Service) dispatch(event Event) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	s.recent = append(s.recent, event)
+	if len(s.recent) > eventsKept {
+		s.recent = s.recent[len(s.recent)-eventsKept:]
+	}
+	var matched []Rule
+	for name, rule := range s.rules {
+		if !rule.Disabled && s.patterns[name].matches(fields) {
+			matched = append(matched, *rule)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, rule := range matched {
+		for _, target := range rule.Targets {
+			go s.deliver(rule.Name, target, event, data)
+		}
+	}
+}
+
+// Send with retries and exponential backoff, then dead-letter
+func (s *Service) deliver(rule string, target Target, event Event, body []byte) {
+	delivery := Delivery{
+		ID:         uuid.New().String(),
+		Rule:       rule,
+		Target:     target.ID,
+		TargetType: target.Type,
+		EventID:    event.ID,
+		DetailType: event.DetailType,
+		Status:     StatusDelivered,
+	}
+
+	var err error
+	backoff := time.Second
+	for delivery.Attempts < target.MaxAttempts {
+		if delivery.Attempts > 0 {
+			select {
+			case <-time.After(backoff):
+			case <-s.ctx.Done():
+				err = s.ctx.Err()
+			}
+			if s.ctx.Err() != nil {
+				break
+			}
+			backoff = min(2*backoff, maxBackoff)
+		}
+		delivery.Attempts++
+		if err = s.send(rule, target, event, body); err == nil {
+			break
+		}
+	}
+
+	if err != nil {
+		delivery.Status = StatusFailed
+		delivery.Error = err.Error()
+		if target.DeadLetterQueue != "" {
+			if dlqErr := s.deadLetter(rule, target, body, err); dlqErr != nil {
+				delivery.Error += "; dead-letter queue: " + dlqErr.Error()
+			} else {
+				delivery.Status = StatusDeadLettered
+			}
+		}
+		log.Printf("eventbus: rule %s target %s: %s after %d attempts: %s", rule, target.ID, delivery.Status, delivery.Attempts, delivery.Error)
+	}
+	delivery.Time = time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deliveries = append(s.deliveries, delivery)
+	if len(s.deliveries) > deliveriesKept {
+		s.deliveries = s.deliveries[len(s.deliveries)-deliveriesKept:]
+	}
+	if err := store.Save(filepath.Join(s.dir, "deliveries.json"), s.deliveries); err != nil {
+		log.Printf("eventbus: failed to save deliveries: %v", err)
+	}
+}
+
+func (s *Service) send(rule string, target Target, event Event, body []byte) error {
+	switch target.Type {
+	case "webhook":
+		return s.sendWebhook(target, event, body)
+
+	case "queue":
+		return s.sendQueue(target.Queue, queues.SendInput{Body: string(body)}, rule, target, event)
+
+	case "exec":
+		ctx, cancel := context.WithTimeout(s.ctx, deliveryTimeout)
+		defer cancel()
+		stderr, code, err := s.manager.ExecIO(ctx, target.Instance, []string{"sh", "-c", target.Command},
+			[]string{"LOCALCLOUD_EVENT=" + string(body)}, bytes.NewReader(body), nil)
+		if err != nil {
+			return err
+		}
+		if code != 0 {
+			return fmt.Errorf("exit code %d: %s", code, strings.TrimSpace(stderr))
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown target type %q", target.Type)
+}
+
+// POST the event, signed with the target's secret as
+// X-LocalCloud-Signature: t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">
+func (s *Service) sendWebhook(target Target, event Event, body []byte) error {
+	req, err := http.NewRequestWithContext(s.ctx, http.MethodPost, target.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-LocalCloud-Event-Id", event.ID)
+	req.Header.Set("X-LocalCloud-Event-Type", event.DetailType)
+	if target.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set("X-LocalCloud-Signature", "t="+timestamp+",v1="+Sign(target.Secret, timestamp, body))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Webhook signature for a timestamp and body
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) sendQueue(name string, in queues.SendInput, rule string, target Target, event Event) error {
+	queue, err := s.queues.GetQueue(name)
+	if err != nil {
+		return fmt.Errorf("queue %s: %w", name, err)
+	}
+	if queue.FIFO {
+		in.GroupID = rule
+		in.DedupID = event.ID + "-" + target.ID
+	}
+	_, err = s.queues.Send(name, in)
+	return err
+}
+
+// Park a failed event in the target's dead-letter queue, with why it failed
+func (s *Service) deadLetter(rule string, target Target, body []byte, cause error) error {
+	in := queues.SendInput{
+		Body: string(body),
+		Attributes: map[string]queues.MessageAttribute{
+			"RULE_NAME":     {DataType: "String", StringValue: rule},
+			"TARGET_ID":     {DataType: "String", StringValue: target.ID},
+			"ERROR_MESSAGE": {DataType: "String", StringValue: cause.Error()},
+		},
+	}
+	var event Event
+	json.Unmarshal(body, &event)
+	return s.sendQueue(target.DeadLetterQueue, in, rule, target, event)
+}
+
+func (s *Service) ListRules() []Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rules := make([]Rule, 0, len(s.rules))
+	for _, rule := range s.rules {
+		rules = append(rules, redacted(rule))
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Name < rules[j].Name })
+	return rules
+}
+
+func (s *Service) GetRule(name string) (*Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, ok := s.rules[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, name)
+	}
+	result := redacted(rule)
+	return &result, nil
+}
+
+func (s *Service) CreateRule(rule Rule) (*Rule, error) {
+	p, err := s.validate(&rule)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.rules[rule.Name]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrRuleExists, rule.Name)
+	}
+	rule.Created = time.Now()
+	s.rules[rule.Name] = &rule
+	s.patterns[rule.Name] = p
+	if err := s.saveRulesLocked(); err != nil {
+		delete(s.rules, rule.Name)
+		delete(s.patterns, rule.Name)
+		return nil, err
+	}
+	result := redacted(&rule)
+	return &result, nil
+}
+
+// Replace a rule's pattern and targets. Webhook secrets sent back redacted
+// keep their current value.
+func (s *Service) UpdateRule(name string, rule Rule) (*Rule, error) {
+	rule.Name = name
+	p, err := s.validate(&rule)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.rules[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, name)
+	}
+	for i := range rule.Targets {
+		if rule.Targets[i].Secret != redactedSecret {
+			continue
+		}
+		rule.Targets[i].Secret = ""
+		for _, old := range current.Targets {
+			if old.ID == rule.Targets[i].ID {
+				rule.Targets[i].Secret = old.Secret
+			}
+		}
+	}
+	rule.Created = current.Created
+	previous := *current
+	*current = rule
+	s.patterns[name] = p
+	if err := s.saveRulesLocked(); err != nil {
+		*current = previous
+		s.patterns[name], _ = parsePattern(previous.EventPattern)
+		return nil, err
+	}
+	result := redacted(current)
+	return &result, nil
+}
+
+func (s *Service) SetEnabled(name string, enabled bool) (*Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule, ok := s.rules[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrRuleNotFound, name)
+	}
+	rule.Disabled = !enabled
+	if err := s.saveRulesLocked(); err != nil {
+		rule.Disabled = enabled
+		return nil, err
+	}
+	result := redacted(rule)
+	return &result, nil
+}
+
+func (s *Service) DeleteRule(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.rules[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrRuleNotFound, name)
+	}
+	delete(s.rules, name)
+	delete(s.patterns, name)
+	return s.saveRulesLocked()
+}
+
+// Recent events, newest first
+func (s *Service) Events() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]Event, 0, len(s.recent))
+	for i := len(s.recent) - 1; i >= 0; i-- {
+		events = append(events, s.recent[i])
+	}
+	return events
+}
+
+// Delivery log, newest first, optionally for one rule or status
+func (s *Service) Deliveries(rule, status string) []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deliveries := []Delivery{}
+	for i := len(s.deliveries) - 1; i >= 0; i-- {
+		d := s.deliveries[i]
+		if (rule == "" || d.Rule == rule) && (status == "" || d.Status == status) {
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries
+}
+
+// Whether an event would match a pattern, to try patterns out
+func (s *Service) TestPattern(eventPattern json.RawMessage, event Event) (bool, error) {
+	p, err := parsePattern(eventPattern)
+	if err != nil {
+		return false, err
+	}
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	return p.matches(fields), nil
+}
+
+func (s *Service) saveRulesLocked() error {
+	if err := store.Save(filepath.Join(s.dir, "rules.json"), s.rules); err != nil {
+		return fmt.Errorf("failed to save rules: %w", err)
+	}
+	return nil
+}
+
+// Check a rule and fill in defaults
+func (s *Service) validate(rule *Rule) (pattern, error) {
+	if !validName.MatchString(rule.Name) {
+		return nil, fmt.Errorf("%w: name must be 1-63 letters, digits, '.', '_' or '-'", ErrInvalidRule)
+	}
+	p, err := parsePattern(rule.EventPattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(rule.Targets) == 0 || len(rule.Targets) > maxTargets {
+		return nil, fmt.Errorf("%w: a rule needs between 1 and %d targets", ErrInvalidRule, maxTargets)
+	}
+
+	ids := make(map[string]bool)
+	for i := range rule.Targets {
+		target := &rule.Targets[i]
+		if target.ID == "" {
+			target.ID = fmt.Sprintf("%s-%d", target.Type, i+1)
+		}
+		if ids[target.ID] {
+			return nil, fmt.Errorf("%w: duplicate target id %s", ErrInvalidRule, target.ID)
+		}
+		ids[target.ID] = true
+
+		switch target.Type {
+		case "webhook":
+			u, err := url.Parse(target.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("%w: webhook target %s needs an http or https url", ErrInvalidRule, target.ID)
+			}
+		case "queue":
+			if _, err := s.queues.GetQueue(target.Queue); err != nil {
+				return nil, fmt.Errorf("%w: target %s: queue %q: %v", ErrInvalidRule, target.ID, target.Queue, err)
+			}
+		case "exec":
+			if target.Instance == "" || target.Command == "" {
+				return nil, fmt.Errorf("%w: exec target %s needs an instance and a command", ErrInvalidRule, target.ID)
+			}
+		default:
+			return nil, fmt.Errorf("%w: target %s has unknown type %q (webhook, queue or exec)", ErrInvalidRule, target.ID, target.Type)
+		}
+		if target.Type != "webhook" && target.Secret != "" {
+			return nil, fmt.Errorf("%w: only webhook targets take a secret", ErrInvalidRule)
+		}
+
+		if target.MaxAttempts == 0 {
+			target.MaxAttempts = defaultMaxAttempts
+		}
+		if target.MaxAttempts < 1 || target.MaxAttempts > maxAttempts {
+			return nil, fmt.Errorf("%w: max attempts must be between 1 and %d", ErrInvalidRule, maxAttempts)
+		}
+		if target.DeadLetterQueue != "" {
+			if _, err := s.queues.GetQueue(target.DeadLetterQueue); err != nil {
+				return nil, fmt.Errorf("%w: target %s: dead-letter queue %q: %v", ErrInvalidRule, target.ID, target.DeadLetterQueue, err)
+			}
+		}
+	}
+	return p, nil
+}
+
+// Copy of a rule safe to hand out
+func redacted(rule *Rule) Rule {
+	result := *rule
+	result.Targets = append([]Target(nil), rule.Targets...)
+	for i := range result.Targets {
+		if result.Targets[i].Secret != "" {
+			result.Targets[i].Secret = redactedSecret
+		}
+	}
+	return result
+}
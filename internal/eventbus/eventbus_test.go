@@ -0,0 +1,317 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/queues"
+)
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		secret, timestamp, body string
+		want                    string
+	}{
+		{"whsec", "1700000000", `{"id":"1"}`, "60734808e731b08d45bee887cade715d87211348f1bcb975b46c8d2e7fa5dbcd"},
+		{"whsec", "1700000000", "", "ab5fdf6f7cdf5f7abf2f4d61c6b0376dc6bf75beafc17135e5fd06513ee7afd8"},
+	}
+	for _, tt := range tests {
+		if got := Sign(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+			t.Errorf("Sign(%q, %q, %q) = %s, want %s", tt.secret, tt.timestamp, tt.body, got, tt.want)
+		}
+	}
+	if Sign("other", "1700000000", []byte(`{"id":"1"}`)) == tests[0].want {
+		t.Error("signature does not depend on the secret")
+	}
+}
+
+func newTestService(t *testing.T) (*Service, *queues.Service) {
+	t.Helper()
+	dir := t.TempDir()
+	queueService, err := queues.NewService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"orders", "failed"} {
+		if _, err := queueService.CreateQueue(queues.Queue{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := NewService(nil, queueService, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, queueService
+}
+
+// Take the next event off the bus and route it
+func dispatchNext(t *testing.T, s *Service) Event {
+	t.Helper()
+	select {
+	case event := <-s.events:
+		s.dispatch(event)
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event on the bus")
+		return Event{}
+	}
+}
+
+// Wait until a rule has n deliveries
+func waitDeliveries(t *testing.T, s *Service, rule string, n int) []Delivery {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if deliveries := s.Deliveries(rule, ""); len(deliveries) >= n {
+			return deliveries
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rule %s has %d deliveries, want %d", rule, len(s.Deliveries(rule, "")), n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func receiveAll(t *testing.T, q *queues.Service, name string) []queues.Message {
+	t.Helper()
+	wait := 0
+	messages, err := q.Receive(context.Background(), name, queues.ReceiveInput{MaxMessages: 10, WaitSeconds: &wait})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+func TestCreateRuleValidation(t *testing.T) {
+	s, _ := newTestService(t)
+	pattern := json.RawMessage(`{"source": ["shop"]}`)
+	tests := []struct {
+		rule Rule
+		err  string
+	}{
+		{Rule{Name: "bad name", EventPattern: pattern, Targets: []Target{{Type: "queue", Queue: "orders"}}}, "name"},
+		{Rule{Name: "r", EventPattern: pattern}, "between 1 and"},
+		{Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "webhook", URL: "localhost:80"}}}, "http or https"},
+		{Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "queue", Queue: "missing"}}}, "queue \"missing\""},
+		{Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "exec", Instance: "web"}}}, "instance and a command"},
+		{Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "email"}}}, "unknown type"},
+		{Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "queue", Queue: "orders", Secret: "s"}}}, "secret"},
+		{Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "queue", Queue: "orders", MaxAttempts: maxAttempts + 1}}}, "max attempts"},
+		{Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "queue", Queue: "orders", DeadLetterQueue: "missing"}}}, "dead-letter queue"},
+		{Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "queue", Queue: "orders"}, {Type: "queue", Queue: "failed"}, {ID: "queue-1", Type: "queue", Queue: "orders"}}}, "duplicate target id"},
+	}
+	for _, tt := range tests {
+		if _, err := s.CreateRule(tt.rule); !errors.Is(err, ErrInvalidRule) || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("CreateRule(%s) = %v, want %q", tt.err, err, tt.err)
+		}
+	}
+
+	rule, err := s.CreateRule(Rule{Name: "r", EventPattern: pattern, Targets: []Target{{Type: "queue", Queue: "orders"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Targets[0].ID != "queue-1" || rule.Targets[0].MaxAttempts != defaultMaxAttempts {
+		t.Errorf("defaults = %+v", rule.Targets[0])
+	}
+	if _, err := s.CreateRule(*rule); !errors.Is(err, ErrRuleExists) {
+		t.Errorf("creating twice: %v", err)
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	s, _ := newTestService(t)
+	var mu sync.Mutex
+	var received []*http.Request
+	var bodies [][]byte
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, r)
+		bodies = append(bodies, body)
+		mu.Unlock()
+	}))
+	defer hook.Close()
+
+	_, err := s.CreateRule(Rule{
+		Name:         "big-orders",
+		EventPattern: json.RawMessage(`{"source": ["shop"], "detail": {"total": [{"numeric": [">", 100]}]}}`),
+		Targets:      []Target{{Type: "webhook", URL: hook.URL, Secret: "whsec"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Put([]Event{{Source: "shop", DetailType: "Order Placed", Detail: json.RawMessage(`{"total": 20}`)}}); err != nil {
+		t.Fatal(err)
+	}
+	events, err := s.Put([]Event{{Source: "shop", DetailType: "Order Placed", Detail: json.RawMessage(`{"total": 250}`)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatchNext(t, s)
+	dispatchNext(t, s)
+
+	deliveries := waitDeliveries(t, s, "big-orders", 1)
+	if deliveries[0].Status != StatusDelivered || deliveries[0].EventID != events[0].ID || deliveries[0].Attempts != 1 {
+		t.Errorf("delivery = %+v", deliveries[0])
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 1 {
+		t.Fatalf("%d webhook calls, want 1", len(received))
+	}
+	r := received[0]
+	if r.Header.Get("X-LocalCloud-Event-Id") != events[0].ID || r.Header.Get("X-LocalCloud-Event-Type") != "Order Placed" {
+		t.Errorf("headers = %v", r.Header)
+	}
+	timestamp, signature, _ := strings.Cut(strings.TrimPrefix(r.Header.Get("X-LocalCloud-Signature"), "t="), ",v1=")
+	if signature != Sign("whsec", timestamp, bodies[0]) {
+		t.Errorf("signature %q does not match the body", r.Header.Get("X-LocalCloud-Signature"))
+	}
+	if len(s.Events()) != 2 {
+		t.Errorf("recent events = %d", len(s.Events()))
+	}
+}
+
+func TestRetryAndDeadLetter(t *testing.T) {
+	s, q := newTestService(t)
+	var mu sync.Mutex
+	calls := 0
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		mu.Unlock()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer hook.Close()
+
+	_, err := s.CreateRule(Rule{
+		Name:         "orders",
+		EventPattern: json.RawMessage(`{"detail-type": ["Order Placed"]}`),
+		Targets: []Target{
+			{ID: "hook", Type: "webhook", URL: hook.URL, MaxAttempts: 2, DeadLetterQueue: "failed"},
+			{ID: "queue", Type: "queue", Queue: "orders"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Put([]Event{{Source: "shop", DetailType: "Order Placed"}})
+	event := dispatchNext(t, s)
+
+	waitDeliveries(t, s, "orders", 2)
+	if d := s.Deliveries("orders", StatusDeadLettered); len(d) != 1 || d[0].Target != "hook" || d[0].Attempts != 2 || d[0].Error != "HTTP 503" {
+		t.Errorf("dead-lettered = %+v", d)
+	}
+	if d := s.Deliveries("orders", StatusDelivered); len(d) != 1 || d[0].Target != "queue" {
+		t.Errorf("delivered = %+v", d)
+	}
+	mu.Lock()
+	if calls != 2 {
+		t.Errorf("%d webhook calls, want 2", calls)
+	}
+	mu.Unlock()
+
+	failed := receiveAll(t, q, "failed")
+	if len(failed) != 1 || failed[0].Attributes["TARGET_ID"].StringValue != "hook" || failed[0].Attributes["ERROR_MESSAGE"].StringValue != "HTTP 503" {
+		t.Fatalf("dead-letter queue = %+v", failed)
+	}
+	var parked Event
+	if err := json.Unmarshal([]byte(failed[0].Body), &parked); err != nil || parked.ID != event.ID {
+		t.Errorf("parked event = %s, %v", failed[0].Body, err)
+	}
+	if orders := receiveAll(t, q, "orders"); len(orders) != 1 {
+		t.Errorf("queue target got %d messages", len(orders))
+	}
+}
+
+func TestPutValidation(t *testing.T) {
+	s, _ := newTestService(t)
+	tests := []Event{
+		{DetailType: "Order Placed"},
+		{Source: "localcloud.compute", DetailType: "Instance State Change"},
+		{Source: "shop", DetailType: "Order Placed", Detail: json.RawMessage(`[1, 2]`)},
+	}
+	for _, event := range tests {
+		if _, err := s.Put([]Event{event}); !errors.Is(err, ErrInvalidEvent) {
+			t.Errorf("Put(%+v) = %v", event, err)
+		}
+	}
+	if len(s.events) != 0 {
+		t.Error("invalid events were published")
+	}
+}
+
+func TestUpdateRuleKeepsSecret(t *testing.T) {
+	s, _ := newTestService(t)
+	rule, err := s.CreateRule(Rule{
+		Name:         "hook",
+		EventPattern: json.RawMessage(`{"source": ["shop"]}`),
+		Targets:      []Target{{ID: "a", Type: "webhook", URL: "http://localhost:9000", Secret: "whsec"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rule.Targets[0].Secret != redactedSecret {
+		t.Errorf("secret shown: %q", rule.Targets[0].Secret)
+	}
+
+	rule.EventPattern = json.RawMessage(`{"source": ["billing"]}`)
+	if _, err := s.UpdateRule("hook", *rule); err != nil {
+		t.Fatal(err)
+	}
+	if secret := s.rules["hook"].Targets[0].Secret; secret != "whsec" {
+		t.Errorf("secret after update = %q", secret)
+	}
+
+	if _, err := s.SetEnabled("hook", false); err != nil {
+		t.Fatal(err)
+	}
+	s.Put([]Event{{Source: "billing", DetailType: "Invoice"}})
+	dispatchNext(t, s)
+	time.Sleep(50 * time.Millisecond)
+	if d := s.Deliveries("hook", ""); len(d) != 0 {
+		t.Errorf("disabled rule delivered %+v", d)
+	}
+	if err := s.DeleteRule("hook"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetRule("hook"); !errors.Is(err, ErrRuleNotFound) {
+		t.Errorf("after delete: %v", err)
+	}
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	s, _ := newTestService(t)
+	s.lifecycle(compute.LifecycleEvent{Type: "container", Action: "die", ID: "abc", Name: "web", ExitCode: "137", Project: "team", Managed: true})
+	s.lifecycle(compute.LifecycleEvent{Type: "image", Action: "pull", Name: "nginx:latest"})
+
+	event := <-s.events
+	var detail map[string]interface{}
+	json.Unmarshal(event.Detail, &detail)
+	if event.Source != SourceCompute || event.DetailType != "Instance State Change" || event.Resources[0] != "web" {
+		t.Errorf("event = %+v", event)
+	}
+	if detail["state"] != "exited" || detail["exit_code"] != 137.0 || detail["project"] != "team" || detail["managed"] != true {
+		t.Errorf("detail = %v", detail)
+	}
+
+	event = <-s.events
+	if event.Source != SourceImages || !strings.Contains(string(event.Detail), `"action":"pull"`) {
+		t.Errorf("image event = %+v %s", event, event.Detail)
+	}
+
+	matched, err := s.TestPattern(json.RawMessage(`{"source": ["localcloud.images"], "detail": {"action": ["pull"]}}`), event)
+	if err != nil || !matched {
+		t.Errorf("TestPattern = %v, %v", matched, err)
+	}
+}
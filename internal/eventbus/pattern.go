@@ -0,0 +1,219 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// An event pattern is a JSON object mirroring the event's shape. Nested
+// objects match nested fields and leaves are arrays of alternatives: plain
+// values match equal values, and objects are filters:
+//
+//	{"prefix": "web-"}, {"suffix": ".fifo"}, {"anything-but": ["a", "b"]},
+//	{"numeric": [">", 80, "<=", 100]}, {"exists": false}
+//
+// A field holding an array matches if any element does.
+type pattern map[string]interface{}
+
+func parsePattern(raw json.RawMessage) (pattern, error) {
+	var p pattern
+	if err := json.Unmarshal(raw, &p); err != nil {
+		return nil, fmt.Errorf("%w: event pattern must be a JSON object", ErrInvalidRule)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: event pattern must not be empty", ErrInvalidRule)
+	}
+	if err := validatePattern(p, ""); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func validatePattern(p map[string]interface{}, path string) error {
+	for key, value := range p {
+		field := strings.TrimPrefix(path+"."+key, ".")
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if err := validatePattern(v, field); err != nil {
+				return err
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				return fmt.Errorf("%w: %s must list at least one value", ErrInvalidRule, field)
+			}
+			for _, condition := range v {
+				switch c := condition.(type) {
+				case map[string]interface{}:
+					if err := validateFilter(c, field); err != nil {
+						return err
+					}
+				case []interface{}:
+					return fmt.Errorf("%w: %s values must not be arrays", ErrInvalidRule, field)
+				}
+			}
+		default:
+			return fmt.Errorf("%w: %s must be an object or an array of values", ErrInvalidRule, field)
+		}
+	}
+	return nil
+}
+
+func validateFilter(filter map[string]interface{}, field string) error {
+	if len(filter) != 1 {
+		return fmt.Errorf("%w: %s filters take exactly one of prefix, suffix, anything-but, numeric or exists", ErrInvalidRule, field)
+	}
+	for name, arg := range filter {
+		switch name {
+		case "prefix", "suffix":
+			if _, ok := arg.(string); !ok {
+				return fmt.Errorf("%w: %s %s needs a string", ErrInvalidRule, field, name)
+			}
+		case "exists":
+			if _, ok := arg.(bool); !ok {
+				return fmt.Errorf("%w: %s exists needs true or false", ErrInvalidRule, field)
+			}
+		case "anything-but":
+			switch a := arg.(type) {
+			case map[string]interface{}, nil:
+				return fmt.Errorf("%w: %s anything-but needs a value or a list of values", ErrInvalidRule, field)
+			case []interface{}:
+				if len(a) == 0 {
+					return fmt.Errorf("%w: %s anything-but needs a value or a list of values", ErrInvalidRule, field)
+				}
+				for _, e := range a {
+					switch e.(type) {
+					case map[string]interface{}, []interface{}:
+						return fmt.Errorf("%w: %s anything-but needs a value or a list of values", ErrInvalidRule, field)
+					}
+				}
+			}
+		case "numeric":
+			terms, ok := arg.([]interface{})
+			if !ok || len(terms) == 0 || len(terms)%2 != 0 || len(terms) > 4 {
+				return fmt.Errorf("%w: %s numeric needs one or two operator and number pairs", ErrInvalidRule, field)
+			}
+			for i := 0; i < len(terms); i += 2 {
+				op, _ := terms[i].(string)
+				if _, isNumber := terms[i+1].(float64); !isNumber || !validOperator(op) {
+					return fmt.Errorf("%w: %s numeric needs operators =, <, <=, > or >= each followed by a number", ErrInvalidRule, field)
+				}
+			}
+		default:
+			return fmt.Errorf("%w: %s has unknown filter %q", ErrInvalidRule, field, name)
+		}
+	}
+	return nil
+}
+
+func validOperator(op string) bool {
+	switch op {
+	case "=", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+// Whether a decoded JSON event matches the pattern
+func (p pattern) matches(event map[string]interface{}) bool {
+	return matchObject(p, event)
+}
+
+func matchObject(p map[string]interface{}, event map[string]interface{}) bool {
+	for key, want := range p {
+		value, present := event[key]
+		switch w := want.(type) {
+		case map[string]interface{}:
+			nested, _ := value.(map[string]interface{})
+			if !matchObject(w, nested) {
+				return false
+			}
+		case []interface{}:
+			if !matchAny(w, value, present) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func matchAny(conditions []interface{}, value interface{}, present bool) bool {
+	values := []interface{}{value}
+	if list, ok := value.([]interface{}); ok {
+		values = list
+	}
+
+	for _, condition := range conditions {
+		filter, isFilter := condition.(map[string]interface{})
+		if isFilter {
+			if exists, ok := filter["exists"]; ok {
+				if exists.(bool) == present {
+					return true
+				}
+				continue
+			}
+		}
+		if !present {
+			continue
+		}
+		for _, v := range values {
+			if isFilter && matchFilter(filter, v) || !isFilter && condition == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchFilter(filter map[string]interface{}, value interface{}) bool {
+	for name, arg := range filter {
+		switch name {
+		case "prefix":
+			s, ok := value.(string)
+			return ok && strings.HasPrefix(s, arg.(string))
+		case "suffix":
+			s, ok := value.(string)
+			return ok && strings.HasSuffix(s, arg.(string))
+		case "anything-but":
+			excluded, ok := arg.([]interface{})
+			if !ok {
+				excluded = []interface{}{arg}
+			}
+			for _, e := range excluded {
+				if e == value {
+					return false
+				}
+			}
+			return true
+		case "numeric":
+			n, ok := value.(float64)
+			if !ok {
+				return false
+			}
+			terms := arg.([]interface{})
+			for i := 0; i < len(terms); i += 2 {
+				if !compareNumber(n, terms[i].(string), terms[i+1].(float64)) {
+					return false
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+func compareNumber(value float64, op string, operand float64) bool {
+	switch op {
+	case "=":
+		return value == operand
+	case "<":
+		return value < operand
+	case "<=":
+		return value <= operand
+	case ">":
+		return value > operand
+	case ">=":
+		return value >= operand
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package eventbus
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParsePatternRejects(t *testing.T) {
+	tests := []string{
+		`[]`,
+		`"source"`,
+		`{}`,
+		`{"source": "localcloud.compute"}`,
+		`{"source": []}`,
+		`{"source": [["a"]]}`,
+		`{"detail": {"name": 3}}`,
+		`{"source": [{"prefix": 1}]}`,
+		`{"source": [{"prefix": "a", "suffix": "b"}]}`,
+		`{"source": [{"contains": "a"}]}`,
+		`{"source": [{"exists": "yes"}]}`,
+		`{"source": [{"anything-but": []}]}`,
+		`{"source": [{"anything-but": {"prefix": "a"}}]}`,
+		`{"source": [{"anything-but": null}]}`,
+		`{"cpu": [{"numeric": [">"]}]}`,
+		`{"cpu": [{"numeric": ["~", 80]}]}`,
+		`{"cpu": [{"numeric": [">", "80"]}]}`,
+		`{"cpu": [{"numeric": [">", 1, "<", 2, "=", 3]}]}`,
+	}
+	for _, raw := range tests {
+		if _, err := parsePattern(json.RawMessage(raw)); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("parsePattern(%s): error %v, want %v", raw, err, ErrInvalidRule)
+		}
+	}
+}
+
+func TestPatternMatches(t *testing.T) {
+	event := `{
+		"source": "localcloud.compute",
+		"detail-type": "Instance State Change",
+		"resources": ["web-1", "db-1"],
+		"detail": {"name": "web-1", "state": "running", "cpu": 85.5, "tags": ["blue", "canary"], "queue": "jobs.fifo"}
+	}`
+	tests := []struct {
+		pattern string
+		want    bool
+	}{
+		{`{"source": ["localcloud.compute"]}`, true},
+		{`{"source": ["localcloud.queues"]}`, false},
+		{`{"source": ["localcloud.queues", "localcloud.compute"]}`, true},
+		{`{"source": ["localcloud.compute"], "detail-type": ["Volume Created"]}`, false},
+		{`{"detail": {"state": ["running"]}}`, true},
+		{`{"detail": {"state": ["stopped"]}}`, false},
+		{`{"detail": {"missing": {"deeper": ["x"]}}}`, false},
+		// arrays in the event match if any element does
+		{`{"resources": ["db-1"]}`, true},
+		{`{"detail": {"tags": ["canary"]}}`, true},
+		{`{"detail": {"tags": ["green"]}}`, false},
+		{`{"detail": {"name": [{"prefix": "web-"}]}}`, true},
+		{`{"detail": {"name": [{"prefix": "db-"}]}}`, false},
+		{`{"detail": {"queue": [{"suffix": ".fifo"}]}}`, true},
+		{`{"detail": {"cpu": [{"prefix": "8"}]}}`, false},
+		{`{"detail": {"state": [{"anything-but": "stopped"}]}}`, true},
+		{`{"detail": {"state": [{"anything-but": ["stopped", "running"]}]}}`, false},
+		{`{"detail": {"cpu": [{"numeric": [">", 80]}]}}`, true},
+		{`{"detail": {"cpu": [{"numeric": [">", 80, "<=", 85]}]}}`, false},
+		{`{"detail": {"cpu": [{"numeric": [">=", 85.5, "<", 100]}]}}`, true},
+		{`{"detail": {"cpu": [{"numeric": ["=", 85.5]}]}}`, true},
+		{`{"detail": {"state": [{"numeric": [">", 0]}]}}`, false},
+		{`{"detail": {"name": [{"exists": true}]}}`, true},
+		{`{"detail": {"owner": [{"exists": true}]}}`, false},
+		{`{"detail": {"owner": [{"exists": false}]}}`, true},
+		{`{"detail": {"name": [{"exists": false}]}}`, false},
+		// a missing field only matches exists: false
+		{`{"detail": {"owner": [{"anything-but": "root"}]}}`, false},
+		{`{"detail": {"owner": ["root", {"exists": false}]}}`, true},
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(event), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		p, err := parsePattern(json.RawMessage(tt.pattern))
+		if err != nil {
+			t.Errorf("parsePattern(%s): %v", tt.pattern, err)
+			continue
+		}
+		if got := p.matches(decoded); got != tt.want {
+			t.Errorf("%s: matches = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+// Serverless functions on top of compute.Manager
+package functions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Label placed on every warm container
+const LabelFunction = "localcloud.function"
+
+const (
+	defaultMemoryMB = 128
+	defaultTimeout  = 30 // seconds
+	maxCodeSize     = 50 << 20
+	invocationsKept = 100
+	buildDockerfile = "Dockerfile.localcloud"
+)
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type Function struct {
+	Name     string    `json:"name"`
+	Runtime  string    `json:"runtime"` // python, node or go
+	Handler  string    `json:"handler"`
+	MemoryMB int       `json:"memory_mb"`
+	Timeout  int       `json:"timeout_seconds"`
+	Env      []string  `json:"env,omitempty"`
+	Image    string    `json:"image"`
+	Version  int       `json:"version"`
+	CodeSize int64     `json:"code_size"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Function plus its warm pool
+type Status struct {
+	Function
+	Warm int `json:"warm"`
+	Busy int `json:"busy"`
+}
+
+type Invocation struct {
+	RequestID  string          `json:"request_id"`
+	Function   string          `json:"function"`
+	Version    int             `json:"version"`
+	Time       time.Time       `json:"time"`
+	Status     string          `json:"status"` // success, error or timeout
+	DurationMs int64           `json:"duration_ms"`
+	ColdStart  bool            `json:"cold_start"`
+	InitMs     int64           `json:"init_duration_ms,omitempty"` // container start on a cold start
+	Result     json.RawMessage `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	Logs       string          `json:"logs"`
+}
+
+type Service struct {
+	manager     *compute.Manager
+	dir         string
+	idleTimeout time.Duration
+
+	mu          sync.Mutex
+	functions   map[string]*Function
+	invocations map[string][]Invocation
+	pools       map[string]*pool
+}
+
+func NewService(manager *compute.Manager, dataDir string, idleTimeout time.Duration) (*Service, error) {
+	s := &Service{
+		manager:     manager,
+		dir:         filepath.Join(dataDir, "functions"),
+		idleTimeout: idleTimeout,
+		functions:   make(map[string]*Function),
+		invocations: make(map[string][]Invocation),
+		pools:       make(map[string]*pool),
+	}
+	if err := store.Load(filepath.Join(s.dir, "functions.json"), &s.functions); err != nil {
+		return nil, err
+	}
+	for name := range s.functions {
+		var invocations []Invocation
+		if err := store.Load(s.invocationsPath(name), &invocations); err != nil {
+			return nil, err
+		}
+		s.invocations[name] = invocations
+		s.pools[name] = newPool()
+	}
+	return s, nil
+}
+
+// Remove containers left by a previous run and evict idle ones until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.functions))
+	for name := range s.functions {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+
+	for _, name := range names {
+		for _, instance := range s.manager.ListByLabel(LabelFunction, name) {
+			s.manager.Delete(instance.ID)
+		}
+	}
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.evictIdle()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.functions))
+	for name, fn := range s.functions {
+		warm, busy := s.pools[name].counts()
+		statuses = append(statuses, Status{Function: *fn, Warm: warm, Busy: busy})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (s *Service) Get(name string) (*Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn, ok := s.functions[name]
+	if !ok {
+		return nil, fmt.Errorf("function %q not found", name)
+	}
+	warm, busy := s.pools[name].counts()
+	return &Status{Function: *fn, Warm: warm, Busy: busy}, nil
+}
+
+// Package code (a zip archive) into a runtime image and make it the
+// function's current version. Returns the image build output.
+func (s *Service) Deploy(spec Function, code []byte) (*Function, string, error) {
+	if !validName.MatchString(spec.Name) {
+		return nil, "", fmt.Errorf("function name must be lowercase letters, digits and dashes")
+	}
+	rt, err := lookupRuntime(spec.Runtime)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(code) > maxCodeSize {
+		return nil, "", fmt.Errorf("code archive is larger than %d MB", maxCodeSize>>20)
+	}
+	if spec.Handler == "" {
+		spec.Handler = rt.defaultHandler
+	}
+	if spec.MemoryMB <= 0 {
+		spec.MemoryMB = defaultMemoryMB
+	}
+	if spec.Timeout <= 0 {
+		spec.Timeout = defaultTimeout
+	}
+
+	s.mu.Lock()
+	previous, exists := s.functions[spec.Name]
+	s.mu.Unlock()
+
+	spec.Version = 1
+	spec.Created = time.Now()
+	if exists {
+		spec.Version = previous.Version + 1
+		spec.Created = previous.Created
+	}
+	spec.Updated = time.Now()
+	spec.CodeSize = int64(len(code))
+	spec.Image = fmt.Sprintf("localcloud-fn-%s:v%d", spec.Name, spec.Version)
+
+	buildContext, err := packageCode(code, rt, spec.Handler)
+	if err != nil {
+		return nil, "", err
+	}
+	buildLog, err := s.manager.BuildImage(buildContext, spec.Image, buildDockerfile)
+	if err != nil {
+		return nil, buildLog, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(s.dir, spec.Name), 0o755); err != nil {
+		return nil, buildLog, fmt.Errorf("failed to create function directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, spec.Name, "code.zip"), code, 0o600); err != nil {
+		return nil, buildLog, fmt.Errorf("failed to save code: %w", err)
+	}
+
+	s.mu.Lock()
+	s.functions[spec.Name] = &spec
+	oldPool := s.pools[spec.Name]
+	s.pools[spec.Name] = newPool()
+	err = s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, buildLog, err
+	}
+
+	// Warm containers run the old code
+	if oldPool != nil {
+		for _, id := range oldPool.drain() {
+			s.manager.Delete(id)
+		}
+	}
+	if exists {
+		s.manager.RemoveImage(previous.Image)
+	}
+
+	return &spec, buildLog, nil
+}
+
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	fn, ok := s.functions[name]
+	if !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("function %q not found", name)
+	}
+	p := s.pools[name]
+	delete(s.functions, name)
+	delete(s.pools, name)
+	delete(s.invocations, name)
+	err := s.saveLocked()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, id := range p.drain() {
+		s.manager.Delete(id)
+	}
+	s.manager.RemoveImage(fn.Image)
+	return os.RemoveAll(filepath.Join(s.dir, name))
+}
+
+// Most recent invocations first
+func (s *Service) Invocations(name string, limit int) ([]Invocation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.functions[name]; !ok {
+		return nil, fmt.Errorf("function %q not found", name)
+	}
+
+	history := s.invocations[name]
+	result := make([]Invocation, 0, len(history))
+	for i := len(history) - 1; i >= 0 && (limit <= 0 || len(result) < limit); i-- {
+		result = append(result, history[i])
+	}
+	return result, nil
+}
+
+func (s *Service) record(inv Invocation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.functions[inv.Function]; !ok {
+		return
+	}
+	history := append(s.invocations[inv.Function], inv)
+	if len(history) > invocationsKept {
+		history = history[len(history)-invocationsKept:]
+	}
+	s.invocations[inv.Function] = history
+
+	if err := store.Save(s.invocationsPath(inv.Function), history); err != nil {
+		log.Printf("functions: %v", err)
+	}
+}
+
+func (s *Service) saveLocked() error {
+	return store.Save(filepath.Join(s.dir, "functions.json"), s.functions)
+}
+
+func (s *Service) invocationsPath(name string) string {
+	return filepath.Join(s.dir, name, "invocations.json")
+}
+
+func newRequestID() string {
+	return uuid.New().String()
+}
+
+// Turn a zip of handler code into a tar build context with the runtime
+// shim and Dockerfile added
+func packageCode(code []byte, rt runtime, handler string) (io.Reader, error) {
+	archive, err := zip.NewReader(bytes.NewReader(code), int64(len(code)))
+	if err != nil {
+		return nil, fmt.Errorf("code must be a zip archive: %w", err)
+	}
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, file := range archive.File {
+		name := path.Clean(strings.TrimPrefix(file.Name, "/"))
+		if name == "." || strings.HasPrefix(name, "../") || file.FileInfo().IsDir() {
+			continue
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+
+		if err := addTarFile(tw, name, data, int64(file.Mode().Perm())); err != nil {
+			return nil, err
+		}
+	}
+
+	shim, dockerfile := rt.render(handler)
+	if err := addTarFile(tw, rt.shimFile, []byte(shim), 0o644); err != nil {
+		return nil, err
+	}
+	if err := addTarFile(tw, buildDockerfile, []byte(dockerfile), 0o644); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write build context: %w", err)
+	}
+	return &buf, nil
+}
+
+func addTarFile(tw *tar.Writer, name string, data []byte, mode int64) error {
+	if mode == 0 {
+		mode = 0o644
+	}
+	header := &tar.Header{Name: name, Mode: mode, Size: int64(len(data)), ModTime: time.Now()}
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write build context: %w", err)
+	}
+	if _, err := tw.Write(data); err != nil {
+		return fmt.Errorf("failed to write build context: %w", err)
+	}
+	return nil
+}
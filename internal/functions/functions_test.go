@@ -0,0 +1,364 @@
+package functions
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/go-connections/nat"
+)
+
+func newTestService(t *testing.T) (*Service, *dockertest.Server, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	docker.Handle("POST /build", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		io.WriteString(w, `{"stream":"Step 1/4 : FROM python:3.12-slim\n"}`+"\n")
+		io.WriteString(w, `{"stream":"Successfully built\n"}`+"\n")
+	}))
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s, err := NewService(manager, dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, docker, dir
+}
+
+// Publish the shim port of every container the service starts on a fake
+// runtime. Events with "fail" make the handler raise, and events with
+// "sleep" take that many milliseconds.
+func withRuntime(t *testing.T, docker *dockertest.Server) {
+	t.Helper()
+	shim := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			io.WriteString(w, `{"status":"ok"}`)
+			return
+		}
+		var event struct {
+			Name  string `json:"name"`
+			Fail  bool   `json:"fail"`
+			Sleep int    `json:"sleep"`
+		}
+		json.NewDecoder(r.Body).Decode(&event)
+		time.Sleep(time.Duration(event.Sleep) * time.Millisecond)
+		if event.Fail {
+			json.NewEncoder(w).Encode(shimResponse{Error: "Traceback: boom", Logs: "about to fail\n"})
+			return
+		}
+		json.NewEncoder(w).Encode(shimResponse{Result: json.RawMessage(`"hello ` + event.Name + `"`), Logs: "called\n"})
+	}))
+	t.Cleanup(shim.Close)
+	_, port, _ := net.SplitHostPort(shim.Listener.Addr().String())
+	docker.OnCreate(func(c *dockertest.Container) {
+		c.Ports = nat.PortMap{"8080/tcp": {{HostIP: "127.0.0.1", HostPort: port}}}
+	})
+}
+
+func zipCode(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func deploy(t *testing.T, s *Service, spec Function) *Function {
+	t.Helper()
+	fn, _, err := s.Deploy(spec, zipCode(t, map[string]string{"handler.py": "def handle(event):\n    return event\n"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fn
+}
+
+func functionContainers(docker *dockertest.Server, name string) []dockertest.Container {
+	var list []dockertest.Container
+	for _, c := range docker.Containers() {
+		if c.Labels[LabelFunction] == name {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+func TestPackageCode(t *testing.T) {
+	code := zipCode(t, map[string]string{
+		"app/handler.py":    "def handle(event): pass",
+		"/requirements.txt": "requests",
+		"../../etc/passwd":  "root",
+	})
+	buildContext, err := packageCode(code, runtimes["python"], "app.handler.handle")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	tr := tar.NewReader(buildContext)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[header.Name] = string(data)
+	}
+	if len(files) != 4 {
+		t.Errorf("build context holds %d files: %v", len(files), files)
+	}
+	if _, ok := files["app/handler.py"]; !ok {
+		t.Error("handler code missing")
+	}
+	if _, ok := files["requirements.txt"]; !ok {
+		t.Error("absolute path not made relative")
+	}
+	if !strings.Contains(files[buildDockerfile], "LOCALCLOUD_HANDLER=app.handler.handle") {
+		t.Errorf("Dockerfile without the handler:\n%s", files[buildDockerfile])
+	}
+	if _, ok := files["localcloud_shim.py"]; !ok {
+		t.Error("runtime shim missing")
+	}
+
+	if _, err := packageCode([]byte("not a zip"), runtimes["python"], "handler.handle"); err == nil {
+		t.Error("packaged something that is not a zip archive")
+	}
+}
+
+func TestDeployValidation(t *testing.T) {
+	s, _, _ := newTestService(t)
+	code := zipCode(t, map[string]string{"index.js": ""})
+	tests := []struct {
+		spec Function
+		err  string
+	}{
+		{Function{Name: "Hello", Runtime: "node"}, "lowercase"},
+		{Function{Name: "hello", Runtime: "ruby"}, "unknown runtime"},
+	}
+	for _, tt := range tests {
+		if _, _, err := s.Deploy(tt.spec, code); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Deploy(%+v) = %v, want %q", tt.spec, err, tt.err)
+		}
+	}
+}
+
+func TestDeployAndRedeploy(t *testing.T) {
+	s, docker, dir := newTestService(t)
+
+	fn, buildLog, err := s.Deploy(Function{Name: "hello", Runtime: "node"}, zipCode(t, map[string]string{"index.js": ""}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fn.Version != 1 || fn.Image != "localcloud-fn-hello:v1" || fn.Handler != "index.handler" ||
+		fn.MemoryMB != defaultMemoryMB || fn.Timeout != defaultTimeout {
+		t.Errorf("deployed %+v", fn)
+	}
+	if !strings.Contains(buildLog, "Successfully built") {
+		t.Errorf("build log = %q", buildLog)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "functions", "hello", "code.zip")); err != nil {
+		t.Error(err)
+	}
+
+	docker.Handle("DELETE /images/localcloud-fn-hello:v1", []interface{}{})
+	fn = deploy(t, s, Function{Name: "hello", Runtime: "node", MemoryMB: 256})
+	if fn.Version != 2 || fn.Image != "localcloud-fn-hello:v2" || fn.MemoryMB != 256 {
+		t.Errorf("redeployed %+v", fn)
+	}
+	removed := false
+	for _, route := range docker.Requests() {
+		removed = removed || route == "DELETE /images/localcloud-fn-hello:v1"
+	}
+	if !removed {
+		t.Error("the previous version's image was not removed")
+	}
+
+	// functions survive a restart
+	reopened, err := NewService(s.manager, dir, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status, err := reopened.Get("hello"); err != nil || status.Version != 2 {
+		t.Errorf("after reopening: %+v, %v", status, err)
+	}
+}
+
+func TestDeployBuildFailure(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	docker.Handle("POST /build", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"stream":"Step 1/4\n"}`+"\n"+`{"error":"pip install failed"}`+"\n")
+	}))
+	_, buildLog, err := s.Deploy(Function{Name: "hello", Runtime: "python"}, zipCode(t, map[string]string{"handler.py": ""}))
+	if err == nil || !strings.Contains(err.Error(), "pip install failed") || buildLog != "Step 1/4\n" {
+		t.Errorf("Deploy = %q, %v", buildLog, err)
+	}
+	if len(s.List()) != 0 {
+		t.Error("a function whose build failed was saved")
+	}
+}
+
+func TestInvokeReusesWarmContainers(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	withRuntime(t, docker)
+	deploy(t, s, Function{Name: "hello", Runtime: "python"})
+
+	first, err := s.Invoke(context.Background(), "hello", []byte(`{"name":"ada"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Status != "success" || !first.ColdStart || string(first.Result) != `"hello ada"` || first.Logs != "called\n" {
+		t.Errorf("first invocation = %+v", first)
+	}
+
+	second, err := s.Invoke(context.Background(), "hello", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Status != "success" || second.ColdStart {
+		t.Errorf("second invocation = %+v", second)
+	}
+	if containers := functionContainers(docker, "hello"); len(containers) != 1 {
+		t.Errorf("%d containers for two invocations in a row", len(containers))
+	}
+	if status, _ := s.Get("hello"); status.Warm != 1 || status.Busy != 0 {
+		t.Errorf("pool = %d warm, %d busy", status.Warm, status.Busy)
+	}
+
+	history, err := s.Invocations("hello", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].RequestID != second.RequestID {
+		t.Errorf("history = %+v", history)
+	}
+
+	if _, err := s.Invoke(context.Background(), "hello", []byte(`{not json`)); err == nil {
+		t.Error("invoked with an invalid event")
+	}
+}
+
+func TestInvokeErrors(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	withRuntime(t, docker)
+	deploy(t, s, Function{Name: "hello", Runtime: "python", Timeout: 1})
+
+	// a handler error leaves the container reusable
+	inv, err := s.Invoke(context.Background(), "hello", []byte(`{"fail":true}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "error" || inv.Error != "Traceback: boom" || inv.Logs != "about to fail\n" {
+		t.Errorf("failed invocation = %+v", inv)
+	}
+	if len(functionContainers(docker, "hello")) != 1 {
+		t.Error("container removed after a handler error")
+	}
+
+	// a stuck one is removed
+	inv, err = s.Invoke(context.Background(), "hello", []byte(`{"sleep":1500}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inv.Status != "timeout" || !strings.Contains(inv.Error, "timed out after 1s") {
+		t.Errorf("slow invocation = %+v", inv)
+	}
+	if len(functionContainers(docker, "hello")) != 0 {
+		t.Error("container kept after a timeout")
+	}
+	if status, _ := s.Get("hello"); status.Warm != 0 || status.Busy != 0 {
+		t.Errorf("pool = %d warm, %d busy", status.Warm, status.Busy)
+	}
+}
+
+func TestDeleteRemovesContainers(t *testing.T) {
+	s, docker, dir := newTestService(t)
+	withRuntime(t, docker)
+	deploy(t, s, Function{Name: "hello", Runtime: "python"})
+	docker.Handle("DELETE /images/localcloud-fn-hello:v1", []interface{}{})
+	if _, err := s.Invoke(context.Background(), "hello", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Delete("hello"); err != nil {
+		t.Fatal(err)
+	}
+	if len(functionContainers(docker, "hello")) != 0 {
+		t.Error("warm container kept")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "functions", "hello")); !os.IsNotExist(err) {
+		t.Error("function directory kept")
+	}
+	if _, err := s.Invoke(context.Background(), "hello", nil); err == nil {
+		t.Error("invoked a deleted function")
+	}
+}
+
+func TestPoolConcurrencyLimit(t *testing.T) {
+	p := newPool()
+	for i := 0; i < maxConcurrency; i++ {
+		if _, cold, err := p.acquire(context.Background()); err != nil || !cold {
+			t.Fatalf("slot %d: cold %v, %v", i+1, cold, err)
+		}
+		p.started(true)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, _, err := p.acquire(ctx); err == nil {
+		t.Error("acquired a slot beyond the concurrency limit")
+	}
+
+	// a returned container is handed out warm
+	wc := &warmContainer{id: "c1"}
+	if !p.release(wc) {
+		t.Fatal("release on an open pool failed")
+	}
+	if got, cold, err := p.acquire(context.Background()); err != nil || cold || got != wc {
+		t.Errorf("acquire = %v, %v, %v", got, cold, err)
+	}
+}
+
+func TestPoolEvictAndDrain(t *testing.T) {
+	p := newPool()
+	p.idle = []*warmContainer{
+		{id: "old", lastUsed: time.Now().Add(-time.Hour)},
+		{id: "new", lastUsed: time.Now()},
+	}
+	if evicted := p.evict(time.Minute); len(evicted) != 1 || evicted[0] != "old" {
+		t.Errorf("evicted %v", evicted)
+	}
+	if ids := p.drain(); len(ids) != 1 || ids[0] != "new" {
+		t.Errorf("drained %v", ids)
+	}
+	if _, _, err := p.acquire(context.Background()); err == nil {
+		t.Error("acquired from a drained pool")
+	}
+}
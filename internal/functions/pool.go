@@ -0,0 +1,303 @@
+package functions
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+)
+
+const (
+	maxConcurrency = 10 // containers per function
+	startTimeout   = 60 * time.Second
+)
+
+type warmContainer struct {
+	id       string
+	addr     string
+	lastUsed time.Time
+}
+
+// Warm containers for one function version
+type pool struct {
+	mu       sync.Mutex
+	idle     []*warmContainer
+	busy     int
+	starting int
+	closed   bool
+}
+
+func newPool() *pool {
+	return &pool{}
+}
+
+func (p *pool) counts() (warm, busy int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle), p.busy
+}
+
+// Take an idle container, or a slot to start one (cold is true and the
+// container nil). Waits while the function is at its concurrency limit.
+func (p *pool) acquire(ctx context.Context) (wc *warmContainer, cold bool, err error) {
+	for {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return nil, false, fmt.Errorf("function was redeployed or deleted")
+		}
+		if n := len(p.idle); n > 0 {
+			wc = p.idle[n-1]
+			p.idle = p.idle[:n-1]
+			p.busy++
+			p.mu.Unlock()
+			return wc, false, nil
+		}
+		if p.busy+p.starting < maxConcurrency {
+			p.starting++
+			p.mu.Unlock()
+			return nil, true, nil
+		}
+		p.mu.Unlock()
+
+		select {
+		case <-time.After(50 * time.Millisecond):
+		case <-ctx.Done():
+			return nil, false, fmt.Errorf("function is at its concurrency limit of %d", maxConcurrency)
+		}
+	}
+}
+
+// Finish a cold start slot
+func (p *pool) started(ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.starting--
+	if ok {
+		p.busy++
+	}
+}
+
+// Return a container after an invocation; false means the pool is closed
+// and the caller should remove it
+func (p *pool) release(wc *warmContainer) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.busy--
+	if p.closed {
+		return false
+	}
+	wc.lastUsed = time.Now()
+	p.idle = append(p.idle, wc)
+	return true
+}
+
+// Drop a busy container that should not be reused
+func (p *pool) discard() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.busy--
+}
+
+// Idle containers unused for longer than timeout
+func (p *pool) evict(timeout time.Duration) []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var evicted []string
+	kept := p.idle[:0]
+	for _, wc := range p.idle {
+		if time.Since(wc.lastUsed) > timeout {
+			evicted = append(evicted, wc.id)
+			continue
+		}
+		kept = append(kept, wc)
+	}
+	p.idle = kept
+	return evicted
+}
+
+// Close the pool, returning idle containers to remove
+func (p *pool) drain() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	ids := make([]string, 0, len(p.idle))
+	for _, wc := range p.idle {
+		ids = append(ids, wc.id)
+	}
+	p.idle = nil
+	return ids
+}
+
+// Run a function with an event (JSON, may be empty)
+func (s *Service) Invoke(ctx context.Context, name string, event []byte) (*Invocation, error) {
+	s.mu.Lock()
+	fn, ok := s.functions[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("function %q not found", name)
+	}
+	function := *fn
+	p := s.pools[name]
+	s.mu.Unlock()
+
+	if len(bytes.TrimSpace(event)) > 0 && !json.Valid(event) {
+		return nil, fmt.Errorf("event must be valid JSON")
+	}
+
+	inv := Invocation{
+		RequestID: newRequestID(),
+		Function:  name,
+		Version:   function.Version,
+		Time:      time.Now(),
+	}
+
+	timeout := time.Duration(function.Timeout) * time.Second
+	acquireCtx, cancel := context.WithTimeout(ctx, timeout)
+	wc, cold, err := p.acquire(acquireCtx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	if cold {
+		inv.ColdStart = true
+		start := time.Now()
+		wc, err = s.startContainer(ctx, function)
+		inv.InitMs = time.Since(start).Milliseconds()
+		p.started(err == nil)
+		if err != nil {
+			inv.Status = "error"
+			inv.Error = err.Error()
+			s.record(inv)
+			return &inv, nil
+		}
+	}
+
+	invokeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	resp, err := callShim(invokeCtx, wc.addr, event)
+	inv.DurationMs = time.Since(start).Milliseconds()
+
+	switch {
+	case errors.Is(invokeCtx.Err(), context.DeadlineExceeded):
+		// The handler may be stuck, so the container is not reused
+		inv.Status = "timeout"
+		inv.Error = fmt.Sprintf("function timed out after %s", timeout)
+		p.discard()
+		s.manager.Delete(wc.id)
+	case err != nil:
+		inv.Status = "error"
+		inv.Error = err.Error()
+		p.discard()
+		s.manager.Delete(wc.id)
+	default:
+		inv.Result = resp.Result
+		inv.Logs = resp.Logs
+		inv.Status = "success"
+		if resp.Error != "" {
+			inv.Status = "error"
+			inv.Error = resp.Error
+		}
+		if !p.release(wc) {
+			s.manager.Delete(wc.id)
+		}
+	}
+
+	s.record(inv)
+	return &inv, nil
+}
+
+// Start a container for the function and wait for its shim
+func (s *Service) startContainer(ctx context.Context, function Function) (*warmContainer, error) {
+	instance, err := s.manager.Create(compute.CreateSpec{
+		Image:    function.Image,
+		Name:     fmt.Sprintf("fn-%s-%s", function.Name, newRequestID()[:8]),
+		Env:      function.Env,
+		MemoryMB: function.MemoryMB,
+		Labels:   map[string]string{LabelFunction: function.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, startTimeout)
+	defer cancel()
+
+	addr, err := s.manager.Address(ctx, instance.ID, shimPort)
+	if err != nil {
+		s.manager.Delete(instance.ID)
+		return nil, err
+	}
+
+	for {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+"/health", nil)
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return &warmContainer{id: instance.ID, addr: addr, lastUsed: time.Now()}, nil
+			}
+		}
+
+		select {
+		case <-time.After(100 * time.Millisecond):
+		case <-ctx.Done():
+			logs, _ := s.manager.GetLogs(instance.ID, 20)
+			s.manager.Delete(instance.ID)
+			return nil, fmt.Errorf("function runtime did not start: %s", logs)
+		}
+	}
+}
+
+type shimResponse struct {
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+	Logs   string          `json:"logs"`
+}
+
+func callShim(ctx context.Context, addr string, event []byte) (*shimResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "http://"+addr+"/invoke", bytes.NewReader(event))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("invocation failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body shimResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("invalid runtime response: %w", err)
+	}
+	return &body, nil
+}
+
+func (s *Service) evictIdle() {
+	s.mu.Lock()
+	pools := make([]*pool, 0, len(s.pools))
+	for _, p := range s.pools {
+		pools = append(pools, p)
+	}
+	s.mu.Unlock()
+
+	for _, p := range pools {
+		for _, id := range p.evict(s.idleTimeout) {
+			s.manager.Delete(id)
+		}
+	}
+}
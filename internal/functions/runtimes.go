@@ -0,0 +1,230 @@
+package functions
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Port the runtime shim listens on inside the container
+const shimPort = 8080
+
+// How a runtime packages handler code into an image
+type runtime struct {
+	defaultHandler string
+	shimFile       string
+	shim           string
+	dockerfile     string
+}
+
+// Supported runtimes. Each image runs a small shim that serves
+// GET /health and POST /invoke, calling the handler with the event and
+// capturing whatever it prints as the invocation's logs.
+var runtimes = map[string]runtime{
+	"python": {
+		defaultHandler: "handler.handle",
+		shimFile:       "localcloud_shim.py",
+		shim:           pythonShim,
+		dockerfile: `FROM python:3.12-slim
+WORKDIR /fn
+COPY . /fn
+RUN if [ -f requirements.txt ]; then pip install --no-cache-dir -r requirements.txt; fi
+ENV LOCALCLOUD_HANDLER={{HANDLER}}
+CMD ["python", "/fn/localcloud_shim.py"]
+`,
+	},
+	"node": {
+		defaultHandler: "index.handler",
+		shimFile:       "localcloud_shim.js",
+		shim:           nodeShim,
+		dockerfile: `FROM node:20-slim
+WORKDIR /fn
+COPY . /fn
+RUN if [ -f package.json ]; then npm install --omit=dev; fi
+ENV LOCALCLOUD_HANDLER={{HANDLER}}
+CMD ["node", "/fn/localcloud_shim.js"]
+`,
+	},
+	"go": {
+		defaultHandler: "Handle",
+		shimFile:       "localcloud_shim.go",
+		shim:           goShim,
+		dockerfile: `FROM golang:1.23-alpine AS build
+WORKDIR /src
+COPY . /src
+RUN [ -f go.mod ] || go mod init function
+RUN go mod tidy && CGO_ENABLED=0 go build -o /function .
+
+FROM alpine:3.20
+COPY --from=build /function /function
+CMD ["/function"]
+`,
+	},
+}
+
+func lookupRuntime(name string) (runtime, error) {
+	rt, ok := runtimes[name]
+	if !ok {
+		names := make([]string, 0, len(runtimes))
+		for n := range runtimes {
+			names = append(names, n)
+		}
+		return runtime{}, fmt.Errorf("unknown runtime %q (supported: %s)", name, strings.Join(names, ", "))
+	}
+	return rt, nil
+}
+
+// Shim and Dockerfile with the handler filled in
+func (rt runtime) render(handler string) (shim, dockerfile string) {
+	shim = strings.ReplaceAll(rt.shim, "{{HANDLER}}", handler)
+	dockerfile = strings.ReplaceAll(rt.dockerfile, "{{HANDLER}}", handler)
+	return shim, dockerfile
+}
+
+const pythonShim = `import contextlib
+import importlib
+import io
+import json
+import os
+import sys
+import traceback
+from http.server import BaseHTTPRequestHandler, HTTPServer
+
+sys.path.insert(0, "/fn")
+module_name, func_name = os.environ["LOCALCLOUD_HANDLER"].rsplit(".", 1)
+handler = getattr(importlib.import_module(module_name), func_name)
+
+
+class Shim(BaseHTTPRequestHandler):
+    def do_GET(self):
+        self.send({"status": "ok"})
+
+    def do_POST(self):
+        length = int(self.headers.get("Content-Length") or 0)
+        raw = self.rfile.read(length) if length else b""
+        logs = io.StringIO()
+        body = {}
+        try:
+            event = json.loads(raw) if raw else None
+            with contextlib.redirect_stdout(logs), contextlib.redirect_stderr(logs):
+                body["result"] = handler(event)
+        except Exception:
+            body["error"] = traceback.format_exc()
+        body["logs"] = logs.getvalue()
+        self.send(body)
+
+    def send(self, body):
+        data = json.dumps(body, default=str).encode()
+        self.send_response(200)
+        self.send_header("Content-Type", "application/json")
+        self.send_header("Content-Length", str(len(data)))
+        self.end_headers()
+        self.wfile.write(data)
+
+    def log_message(self, *args):
+        pass
+
+
+HTTPServer(("0.0.0.0", 8080), Shim).serve_forever()
+`
+
+const nodeShim = `const http = require('http');
+const util = require('util');
+
+const spec = process.env.LOCALCLOUD_HANDLER;
+const dot = spec.lastIndexOf('.');
+const handler = require('/fn/' + spec.slice(0, dot))[spec.slice(dot + 1)];
+
+function send(res, body) {
+    const data = JSON.stringify(body);
+    res.writeHead(200, { 'Content-Type': 'application/json', 'Content-Length': Buffer.byteLength(data) });
+    res.end(data);
+}
+
+http.createServer((req, res) => {
+    if (req.method === 'GET') return send(res, { status: 'ok' });
+
+    let raw = '';
+    req.on('data', chunk => raw += chunk);
+    req.on('end', async () => {
+        const logs = [];
+        const original = { log: console.log, info: console.info, warn: console.warn, error: console.error };
+        for (const key of Object.keys(original)) {
+            console[key] = (...args) => logs.push(util.format(...args));
+        }
+
+        const body = {};
+        try {
+            body.result = await handler(raw ? JSON.parse(raw) : null);
+        } catch (err) {
+            body.error = String((err && err.stack) || err);
+        }
+        Object.assign(console, original);
+        body.logs = logs.join('\n');
+        send(res, body);
+    });
+}).listen(8080, '0.0.0.0');
+`
+
+const goShim = `package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+)
+
+func main() {
+	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{\"status\":\"ok\"}"))
+	})
+
+	http.HandleFunc("/invoke", func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&event)
+
+		body := map[string]interface{}{}
+		body["logs"] = localcloudCapture(func() {
+			defer func() {
+				if p := recover(); p != nil {
+					body["error"] = fmt.Sprint("panic: ", p)
+				}
+			}()
+			result, err := {{HANDLER}}(event)
+			if err != nil {
+				body["error"] = err.Error()
+				return
+			}
+			body["result"] = result
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(body)
+	})
+
+	http.ListenAndServe(":8080", nil)
+}
+
+// Only one invocation runs per container, so swapping stdout is safe
+func localcloudCapture(fn func()) string {
+	r, w, err := os.Pipe()
+	if err != nil {
+		fn()
+		return ""
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	os.Stdout, os.Stderr = stdout, stderr
+	w.Close()
+	return <-done
+}
+`
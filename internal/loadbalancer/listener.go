@@ -0,0 +1,398 @@
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"localcloud/internal/compute"
+)
+
+// Cookie used for sticky HTTP sessions
+const stickyCookie = "LOCALCLOUD_LB_TARGET"
+
+const (
+	dialTimeout   = 5 * time.Second
+	healthTimeout = 2 * time.Second
+)
+
+type target struct {
+	id      string
+	name    string
+	addr    string
+	healthy bool
+
+	active    int64
+	requests  uint64
+	errors    uint64
+	bytesIn   uint64
+	bytesOut  uint64
+	latencyUs uint64 // total, divided by requests for the average
+}
+
+// One balancer's socket and target list
+type listener struct {
+	manager *compute.Manager
+
+	mu      sync.Mutex
+	cfg     Balancer
+	targets []*target
+	next    uint64
+	ln      net.Listener
+	server  *http.Server
+	release func() // drops the port reservation
+	err     error
+}
+
+func newListener(cfg Balancer, manager *compute.Manager) *listener {
+	return &listener{cfg: cfg, manager: manager}
+}
+
+// Listen on the manager's bind address, holding the port in the compute
+// port allocator so containers are not given it
+func (l *listener) start() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	port := l.cfg.ListenPort
+	release, err := l.manager.ReservePort(context.Background(), port, "tcp", "load balancer "+l.cfg.Name)
+	if err != nil {
+		l.err = err
+		return err
+	}
+	ln, err := net.Listen("tcp", net.JoinHostPort(l.manager.BindAddress(), strconv.Itoa(port)))
+	if err != nil {
+		release()
+		l.err = err
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+	l.ln, l.release, l.err = ln, release, nil
+
+	if l.cfg.Protocol == "http" {
+		l.server = &http.Server{Handler: l}
+		go l.server.Serve(ln)
+	} else {
+		go l.acceptLoop(ln)
+	}
+	return nil
+}
+
+func (l *listener) stop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.server != nil {
+		l.server.Close()
+	} else if l.ln != nil {
+		l.ln.Close()
+	}
+	if l.release != nil {
+		l.release()
+	}
+	l.ln, l.release = nil, nil
+}
+
+func (l *listener) running() (bool, string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return false, l.err.Error()
+	}
+	return l.ln != nil, ""
+}
+
+func (l *listener) setConfig(cfg Balancer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+}
+
+func (l *listener) stats() []TargetStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	stats := make([]TargetStats, 0, len(l.targets))
+	for _, t := range l.targets {
+		requests := atomic.LoadUint64(&t.requests)
+		avg := 0.0
+		if requests > 0 {
+			avg = float64(atomic.LoadUint64(&t.latencyUs)) / float64(requests) / 1000
+		}
+		stats = append(stats, TargetStats{
+			ID:                t.id,
+			Name:              t.name,
+			Address:           t.addr,
+			Healthy:           t.healthy,
+			ActiveConnections: atomic.LoadInt64(&t.active),
+			Requests:          requests,
+			Errors:            atomic.LoadUint64(&t.errors),
+			BytesIn:           atomic.LoadUint64(&t.bytesIn),
+			BytesOut:          atomic.LoadUint64(&t.bytesOut),
+			AvgLatencyMs:      avg,
+		})
+	}
+	return stats
+}
+
+// Re-resolve the target group and health check every target
+func (l *listener) refresh(ctx context.Context) {
+	l.mu.Lock()
+	cfg := l.cfg
+	existing := make(map[string]*target, len(l.targets))
+	for _, t := range l.targets {
+		existing[t.id] = t
+	}
+	l.mu.Unlock()
+
+	var targets []*target
+	for _, instance := range resolveTargets(l.manager, cfg.Targets) {
+		if instance.State != "running" {
+			continue
+		}
+
+		addr, err := l.manager.Address(ctx, instance.ID, cfg.Targets.Port)
+		healthy := err == nil && instance.Health != compute.HealthUnhealthy
+		if healthy && cfg.HealthCheck != nil {
+			healthy = probeTarget(ctx, addr, cfg.HealthCheck.Path) == nil
+		}
+
+		// Keep counters for targets we already knew about
+		t, ok := existing[instance.ID]
+		if !ok {
+			t = &target{id: instance.ID, name: instance.Name}
+		}
+		targets = append(targets, t)
+
+		l.mu.Lock()
+		t.addr = addr
+		t.healthy = healthy
+		l.mu.Unlock()
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].id < targets[j].id })
+
+	l.mu.Lock()
+	l.targets = targets
+	l.mu.Unlock()
+}
+
+// Choose a healthy target, honouring a sticky target ID or client key
+func (l *listener) pick(stickyID, clientKey string) *target {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var healthy []*target
+	for _, t := range l.targets {
+		if t.healthy {
+			healthy = append(healthy, t)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if l.cfg.Sticky {
+		for _, t := range healthy {
+			if stickyID != "" && t.id == stickyID {
+				return t
+			}
+		}
+		if clientKey != "" {
+			h := fnv.New32a()
+			h.Write([]byte(clientKey))
+			return healthy[h.Sum32()%uint32(len(healthy))]
+		}
+	}
+
+	if l.cfg.Algorithm == LeastConnections {
+		best := healthy[0]
+		for _, t := range healthy[1:] {
+			if atomic.LoadInt64(&t.active) < atomic.LoadInt64(&best.active) {
+				best = t
+			}
+		}
+		return best
+	}
+
+	t := healthy[l.next%uint64(len(healthy))]
+	l.next++
+	return t
+}
+
+// HTTP proxying
+func (l *listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l.mu.Lock()
+	sticky := l.cfg.Sticky
+	l.mu.Unlock()
+
+	stickyID := ""
+	if sticky {
+		if cookie, err := r.Cookie(stickyCookie); err == nil {
+			stickyID = cookie.Value
+		}
+	}
+
+	t := l.pick(stickyID, "")
+	if t == nil {
+		http.Error(w, "no healthy targets", http.StatusServiceUnavailable)
+		return
+	}
+
+	atomic.AddInt64(&t.active, 1)
+	defer atomic.AddInt64(&t.active, -1)
+	start := time.Now()
+
+	if r.Body != nil {
+		r.Body = &countingReader{ReadCloser: r.Body, count: &t.bytesIn}
+	}
+	counted := &countingWriter{ResponseWriter: w, count: &t.bytesOut}
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.SetURL(&url.URL{Scheme: "http", Host: t.addr})
+			pr.SetXForwarded()
+		},
+		ModifyResponse: func(resp *http.Response) error {
+			if sticky && stickyID != t.id {
+				cookie := &http.Cookie{Name: stickyCookie, Value: t.id, Path: "/", HttpOnly: true}
+				resp.Header.Add("Set-Cookie", cookie.String())
+			}
+			if resp.StatusCode >= 500 {
+				atomic.AddUint64(&t.errors, 1)
+			}
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			atomic.AddUint64(&t.errors, 1)
+			http.Error(w, "bad gateway: "+err.Error(), http.StatusBadGateway)
+		},
+	}
+	proxy.ServeHTTP(counted, r)
+
+	atomic.AddUint64(&t.requests, 1)
+	atomic.AddUint64(&t.latencyUs, uint64(time.Since(start).Microseconds()))
+}
+
+// Raw TCP proxying
+func (l *listener) acceptLoop(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		go l.handleConn(conn)
+	}
+}
+
+func (l *listener) handleConn(client net.Conn) {
+	defer client.Close()
+
+	clientIP, _, _ := net.SplitHostPort(client.RemoteAddr().String())
+	t := l.pick("", clientIP)
+	if t == nil {
+		return
+	}
+
+	atomic.AddInt64(&t.active, 1)
+	defer atomic.AddInt64(&t.active, -1)
+
+	// Latency for TCP is the time to connect to the target
+	start := time.Now()
+	backend, err := net.DialTimeout("tcp", t.addr, dialTimeout)
+	atomic.AddUint64(&t.requests, 1)
+	atomic.AddUint64(&t.latencyUs, uint64(time.Since(start).Microseconds()))
+	if err != nil {
+		atomic.AddUint64(&t.errors, 1)
+		return
+	}
+	defer backend.Close()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		n, _ := io.Copy(backend, client)
+		atomic.AddUint64(&t.bytesIn, uint64(n))
+		if tcp, ok := backend.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		n, _ := io.Copy(client, backend)
+		atomic.AddUint64(&t.bytesOut, uint64(n))
+		if tcp, ok := client.(*net.TCPConn); ok {
+			tcp.CloseWrite()
+		}
+	}()
+	wg.Wait()
+}
+
+// HTTP GET path on addr, or a TCP connect when path is empty
+func probeTarget(ctx context.Context, addr, path string) error {
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+
+	if path == "" {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+path, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+type countingReader struct {
+	io.ReadCloser
+	count *uint64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddUint64(r.count, uint64(n))
+	return n, err
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	count *uint64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	atomic.AddUint64(w.count, uint64(n))
+	return n, err
+}
+
+// Keep streaming responses flowing through the proxy
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
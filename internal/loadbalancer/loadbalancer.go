@@ -0,0 +1,300 @@
+// Layer 7 / layer 4 load balancers running inside LocalCloud
+package loadbalancer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"localcloud/internal/autoscaling"
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+)
+
+const refreshInterval = 5 * time.Second
+
+// Balancing algorithms
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+)
+
+type Balancer struct {
+	Name        string        `json:"name"`
+	Protocol    string        `json:"protocol"` // http or tcp
+	ListenPort  int           `json:"listen_port"`
+	Algorithm   string        `json:"algorithm"`
+	Sticky      bool          `json:"sticky"` // cookie for http, client IP for tcp
+	Targets     TargetGroup   `json:"targets"`
+	HealthCheck *TargetHealth `json:"health_check,omitempty"`
+	Created     time.Time     `json:"created"`
+}
+
+// Instances traffic is sent to
+type TargetGroup struct {
+	Instances []string `json:"instances,omitempty"` // instance IDs or names
+	Group     string   `json:"group,omitempty"`     // auto scaling group name
+	Port      int      `json:"port"`                // container port
+}
+
+// Health check the balancer runs against each target on every refresh
+type TargetHealth struct {
+	Path string `json:"path,omitempty"` // http GET path, tcp connect if empty
+}
+
+// Per-target counters
+type TargetStats struct {
+	ID                string  `json:"id"`
+	Name              string  `json:"name"`
+	Address           string  `json:"address"`
+	Healthy           bool    `json:"healthy"`
+	ActiveConnections int64   `json:"active_connections"`
+	Requests          uint64  `json:"requests"`
+	Errors            uint64  `json:"errors"`
+	BytesIn           uint64  `json:"bytes_in"`
+	BytesOut          uint64  `json:"bytes_out"`
+	AvgLatencyMs      float64 `json:"avg_latency_ms"`
+}
+
+// Balancer plus its live state
+type Status struct {
+	Balancer
+	Running     bool          `json:"running"`
+	Error       string        `json:"error,omitempty"`
+	TargetStats []TargetStats `json:"target_stats"`
+}
+
+type Service struct {
+	manager *compute.Manager
+	path    string
+
+	mu        sync.Mutex
+	balancers map[string]*Balancer
+	listeners map[string]*listener
+	ctx       context.Context
+}
+
+func NewService(manager *compute.Manager, dataDir string) (*Service, error) {
+	s := &Service{
+		manager:   manager,
+		path:      filepath.Join(dataDir, "loadbalancers.json"),
+		balancers: make(map[string]*Balancer),
+		listeners: make(map[string]*listener),
+	}
+	if err := store.Load(s.path, &s.balancers); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Open listeners for saved balancers and keep target lists fresh
+func (s *Service) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	for name, b := range s.balancers {
+		l := newListener(*b, s.manager)
+		if err := l.start(); err != nil {
+			log.Printf("loadbalancer %s: %v", name, err)
+		}
+		s.listeners[name] = l
+	}
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		s.refreshAll()
+		for {
+			select {
+			case <-ticker.C:
+				s.refreshAll()
+			case <-ctx.Done():
+				s.stopAll()
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) List() []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]Status, 0, len(s.balancers))
+	for name, b := range s.balancers {
+		statuses = append(statuses, s.statusLocked(name, b))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (s *Service) Get(name string) (*Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.balancers[name]
+	if !ok {
+		return nil, fmt.Errorf("load balancer %q not found", name)
+	}
+	status := s.statusLocked(name, b)
+	return &status, nil
+}
+
+func (s *Service) Create(b Balancer) (*Balancer, error) {
+	if b.Protocol == "" {
+		b.Protocol = "http"
+	}
+	if b.Algorithm == "" {
+		b.Algorithm = RoundRobin
+	}
+	if err := validate(&b); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.balancers[b.Name]; exists {
+		return nil, fmt.Errorf("load balancer %q already exists", b.Name)
+	}
+	for _, other := range s.balancers {
+		if other.ListenPort == b.ListenPort {
+			return nil, fmt.Errorf("port %d is already used by load balancer %q", b.ListenPort, other.Name)
+		}
+	}
+
+	b.Created = time.Now()
+	l := newListener(b, s.manager)
+	if err := l.start(); err != nil {
+		return nil, err
+	}
+
+	s.balancers[b.Name] = &b
+	s.listeners[b.Name] = l
+	if err := store.Save(s.path, s.balancers); err != nil {
+		return nil, err
+	}
+
+	go l.refresh(s.context())
+	return &b, nil
+}
+
+// Replace the target group of a balancer
+func (s *Service) SetTargets(name string, targets TargetGroup) (*Balancer, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.balancers[name]
+	if !ok {
+		return nil, fmt.Errorf("load balancer %q not found", name)
+	}
+	updated := *b
+	updated.Targets = targets
+	if err := validate(&updated); err != nil {
+		return nil, err
+	}
+
+	*b = updated
+	if err := store.Save(s.path, s.balancers); err != nil {
+		return nil, err
+	}
+
+	l := s.listeners[name]
+	l.setConfig(updated)
+	go l.refresh(s.context())
+	return &updated, nil
+}
+
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.balancers[name]; !ok {
+		return fmt.Errorf("load balancer %q not found", name)
+	}
+	if l, ok := s.listeners[name]; ok {
+		l.stop()
+		delete(s.listeners, name)
+	}
+	delete(s.balancers, name)
+	return store.Save(s.path, s.balancers)
+}
+
+func (s *Service) statusLocked(name string, b *Balancer) Status {
+	status := Status{Balancer: *b, TargetStats: []TargetStats{}}
+	if l, ok := s.listeners[name]; ok {
+		status.Running, status.Error = l.running()
+		status.TargetStats = l.stats()
+	}
+	return status
+}
+
+func (s *Service) refreshAll() {
+	s.mu.Lock()
+	listeners := make([]*listener, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		listeners = append(listeners, l)
+	}
+	s.mu.Unlock()
+
+	for _, l := range listeners {
+		l.refresh(s.context())
+	}
+}
+
+func (s *Service) stopAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, l := range s.listeners {
+		l.stop()
+	}
+}
+
+func (s *Service) context() context.Context {
+	if s.ctx == nil {
+		return context.Background()
+	}
+	return s.ctx
+}
+
+// Instances currently in the target group
+func resolveTargets(manager *compute.Manager, group TargetGroup) []compute.Instance {
+	var instances []compute.Instance
+	for _, id := range group.Instances {
+		if instance, err := manager.Get(id); err == nil {
+			instances = append(instances, *instance)
+		}
+	}
+	if group.Group != "" {
+		instances = append(instances, manager.ListByLabel(autoscaling.LabelGroup, group.Group)...)
+	}
+	return instances
+}
+
+func validate(b *Balancer) error {
+	if b.Name == "" {
+		return fmt.Errorf("load balancer name is required")
+	}
+	if b.Protocol != "http" && b.Protocol != "tcp" {
+		return fmt.Errorf("protocol must be http or tcp")
+	}
+	if b.Algorithm != RoundRobin && b.Algorithm != LeastConnections {
+		return fmt.Errorf("algorithm must be %s or %s", RoundRobin, LeastConnections)
+	}
+	if b.ListenPort <= 0 || b.ListenPort > 65535 {
+		return fmt.Errorf("listen port must be between 1 and 65535")
+	}
+	if b.Targets.Port <= 0 {
+		return fmt.Errorf("target port is required")
+	}
+	if len(b.Targets.Instances) == 0 && b.Targets.Group == "" {
+		return fmt.Errorf("targets need instances or a scaling group")
+	}
+	return nil
+}
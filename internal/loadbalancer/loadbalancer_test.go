@@ -0,0 +1,329 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/go-connections/nat"
+)
+
+func newTestService(t *testing.T) (*Service, *dockertest.Server, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s, err := NewService(manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.stopAll)
+	return s, docker, dir
+}
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// An instance publishing container port 80 on an HTTP server that answers
+// with its name, or with status if that is set
+func addBackend(t *testing.T, docker *dockertest.Server, name string, status *int) {
+	t.Helper()
+	backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status != nil && *status != 0 {
+			w.WriteHeader(*status)
+		}
+		io.WriteString(w, name)
+	}))
+	t.Cleanup(backend.Close)
+	_, port, _ := net.SplitHostPort(backend.Listener.Addr().String())
+	docker.AddContainer(dockertest.Container{
+		ID:    name,
+		Name:  name,
+		Image: "nginx",
+		Ports: nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: port}}},
+	})
+}
+
+func createBalancer(t *testing.T, s *Service, b Balancer) string {
+	t.Helper()
+	if b.ListenPort == 0 {
+		b.ListenPort = freePort(t)
+	}
+	if _, err := s.Create(b); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for the first refresh Create starts, so that it doesn't replace
+	// targets the test has already sent traffic to
+	l := s.listeners[b.Name]
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(5 * time.Millisecond) {
+		l.mu.Lock()
+		n := len(l.targets)
+		l.mu.Unlock()
+		if n > 0 {
+			break
+		}
+	}
+	return fmt.Sprintf("127.0.0.1:%d", b.ListenPort)
+}
+
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestValidate(t *testing.T) {
+	targets := TargetGroup{Instances: []string{"web"}, Port: 80}
+	tests := []struct {
+		b   Balancer
+		err string
+	}{
+		{Balancer{Name: "lb", Protocol: "http", Algorithm: RoundRobin, ListenPort: 8080, Targets: targets}, ""},
+		{Balancer{Name: "lb", Protocol: "tcp", Algorithm: LeastConnections, ListenPort: 5432, Targets: TargetGroup{Group: "db", Port: 5432}}, ""},
+		{Balancer{Protocol: "http", Algorithm: RoundRobin, ListenPort: 8080, Targets: targets}, "name is required"},
+		{Balancer{Name: "lb", Protocol: "udp", Algorithm: RoundRobin, ListenPort: 8080, Targets: targets}, "protocol"},
+		{Balancer{Name: "lb", Protocol: "http", Algorithm: "random", ListenPort: 8080, Targets: targets}, "algorithm"},
+		{Balancer{Name: "lb", Protocol: "http", Algorithm: RoundRobin, ListenPort: 70000, Targets: targets}, "listen port"},
+		{Balancer{Name: "lb", Protocol: "http", Algorithm: RoundRobin, ListenPort: 8080, Targets: TargetGroup{Instances: []string{"web"}}}, "target port"},
+		{Balancer{Name: "lb", Protocol: "http", Algorithm: RoundRobin, ListenPort: 8080, Targets: TargetGroup{Port: 80}}, "instances or a scaling group"},
+	}
+	for _, tt := range tests {
+		err := validate(&tt.b)
+		if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("validate(%+v) = %v, want %q", tt.b, err, tt.err)
+		}
+	}
+}
+
+func TestHTTPRoundRobin(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	addBackend(t, docker, "a", nil)
+	addBackend(t, docker, "b", nil)
+	addr := createBalancer(t, s, Balancer{Name: "web", Targets: TargetGroup{Instances: []string{"a", "b"}, Port: 80}})
+
+	var got []string
+	for i := 0; i < 4; i++ {
+		_, body := get(t, http.DefaultClient, "http://"+addr+"/")
+		got = append(got, body)
+	}
+	if strings.Join(got, ",") != "a,b,a,b" {
+		t.Errorf("responses = %v, want alternating a and b", got)
+	}
+
+	status, err := s.Get("web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Running || len(status.TargetStats) != 2 {
+		t.Fatalf("status = %+v", status)
+	}
+	for _, stats := range status.TargetStats {
+		if stats.Requests != 2 || stats.BytesOut != 2 || !stats.Healthy {
+			t.Errorf("stats for %s = %+v", stats.Name, stats)
+		}
+	}
+}
+
+func TestHTTPSticky(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	addBackend(t, docker, "a", nil)
+	addBackend(t, docker, "b", nil)
+	addr := createBalancer(t, s, Balancer{Name: "web", Sticky: true, Targets: TargetGroup{Instances: []string{"a", "b"}, Port: 80}})
+
+	resp, err := http.Get("http://" + addr + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	cookies := resp.Cookies()
+	if len(cookies) != 1 || cookies[0].Name != stickyCookie {
+		t.Fatalf("cookies = %v", cookies)
+	}
+
+	for i := 0; i < 3; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://"+addr+"/", nil)
+		req.AddCookie(cookies[0])
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != string(first) {
+			t.Errorf("request %d went to %s, want %s", i+1, body, first)
+		}
+		if len(resp.Cookies()) != 0 {
+			t.Errorf("cookie set again for a sticky client")
+		}
+	}
+}
+
+func TestHTTPSkipsUnhealthyTargets(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	failing := http.StatusInternalServerError
+	addBackend(t, docker, "a", nil)
+	addBackend(t, docker, "b", &failing)
+	addr := createBalancer(t, s, Balancer{
+		Name:        "web",
+		Targets:     TargetGroup{Instances: []string{"a", "b", "missing"}, Port: 80},
+		HealthCheck: &TargetHealth{Path: "/health"},
+	})
+
+	for i := 0; i < 3; i++ {
+		if _, body := get(t, http.DefaultClient, "http://"+addr+"/"); body != "a" {
+			t.Errorf("request %d went to %s", i+1, body)
+		}
+	}
+
+	// nothing healthy left
+	docker.SetState("a", "exited")
+	s.listeners["web"].refresh(context.Background())
+	if status, _ := get(t, http.DefaultClient, "http://"+addr+"/"); status != http.StatusServiceUnavailable {
+		t.Errorf("status with no healthy targets = %d, want 503", status)
+	}
+}
+
+func TestTCP(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	echo, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer echo.Close()
+	go func() {
+		for {
+			conn, err := echo.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				io.Copy(conn, conn)
+				conn.Close()
+			}()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(echo.Addr().String())
+	docker.AddContainer(dockertest.Container{
+		ID:    "db",
+		Name:  "db",
+		Image: "postgres",
+		Ports: nat.PortMap{"5432/tcp": {{HostIP: "127.0.0.1", HostPort: port}}},
+	})
+	addr := createBalancer(t, s, Balancer{Name: "db", Protocol: "tcp", Targets: TargetGroup{Instances: []string{"db"}, Port: 5432}})
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(conn, "ping")
+	conn.(*net.TCPConn).CloseWrite()
+	reply, err := io.ReadAll(conn)
+	conn.Close()
+	if err != nil || string(reply) != "ping" {
+		t.Errorf("reply = %q, %v", reply, err)
+	}
+}
+
+func TestPickLeastConnections(t *testing.T) {
+	l := newListener(Balancer{Algorithm: LeastConnections}, nil)
+	l.targets = []*target{
+		{id: "a", healthy: true, active: 3},
+		{id: "b", healthy: true, active: 1},
+		{id: "c", healthy: false, active: 0},
+		{id: "d", healthy: true, active: 2},
+	}
+	if got := l.pick("", ""); got.id != "b" {
+		t.Errorf("picked %s, want b", got.id)
+	}
+
+	// sticky client keys always land on the same healthy target
+	l.cfg.Sticky = true
+	first := l.pick("", "10.0.0.7")
+	for i := 0; i < 5; i++ {
+		if got := l.pick("", "10.0.0.7"); got != first {
+			t.Errorf("client moved from %s to %s", first.id, got.id)
+		}
+	}
+	if got := l.pick("d", ""); got.id != "d" {
+		t.Errorf("sticky ID d picked %s", got.id)
+	}
+	if got := l.pick("c", ""); got.id == "c" {
+		t.Error("sticky ID sent traffic to an unhealthy target")
+	}
+}
+
+func TestCreateRejectsConflicts(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	addBackend(t, docker, "a", nil)
+	port := freePort(t)
+	targets := TargetGroup{Instances: []string{"a"}, Port: 80}
+	createBalancer(t, s, Balancer{Name: "web", ListenPort: port, Targets: targets})
+
+	if _, err := s.Create(Balancer{Name: "web", ListenPort: freePort(t), Targets: targets}); err == nil {
+		t.Error("created a balancer with a name in use")
+	}
+	if _, err := s.Create(Balancer{Name: "other", ListenPort: port, Targets: targets}); err == nil {
+		t.Error("created a balancer on a port in use")
+	}
+
+	// a port an instance publishes
+	published := freePort(t)
+	docker.AddContainer(dockertest.Container{
+		Name:  "b",
+		Image: "nginx",
+		Ports: nat.PortMap{"80/tcp": {{HostIP: "127.0.0.1", HostPort: fmt.Sprint(published)}}},
+	})
+	if _, err := s.Create(Balancer{Name: "api", ListenPort: published, Targets: targets}); !errors.Is(err, compute.ErrPortInUse) {
+		t.Errorf("port of an instance: error %v, want %v", err, compute.ErrPortInUse)
+	}
+}
+
+func TestDeleteAndReopen(t *testing.T) {
+	s, docker, dir := newTestService(t)
+	addBackend(t, docker, "a", nil)
+	addr := createBalancer(t, s, Balancer{Name: "web", Targets: TargetGroup{Instances: []string{"a"}, Port: 80}})
+	createBalancer(t, s, Balancer{Name: "api", Targets: TargetGroup{Instances: []string{"a"}, Port: 80}})
+
+	if err := s.Delete("web"); err != nil {
+		t.Fatal(err)
+	}
+	if conn, err := net.Dial("tcp", addr); err == nil {
+		conn.Close()
+		t.Error("deleted balancer still accepts connections")
+	}
+
+	reopened, err := NewService(s.manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if list := reopened.List(); len(list) != 1 || list[0].Name != "api" || list[0].Running {
+		t.Errorf("after reopening: %+v", list)
+	}
+}
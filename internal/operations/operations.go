@@ -0,0 +1,339 @@
+// Long-running operations: work started by an API request that carries on
+// after the response, with progress that can be polled, watched and cancelled
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"localcloud/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusCancelled = "cancelled"
+
+	// Finished operations are kept this long, and at most maxFinished of them
+	retention   = 24 * time.Hour
+	maxFinished = 200
+)
+
+var (
+	ErrOperationNotFound = errors.New("operation not found")
+	ErrNotCancellable    = errors.New("operation already finished")
+)
+
+type Operation struct {
+	ID       string          `json:"id"`
+	Kind     string          `json:"kind"`   // e.g. instance.create
+	Target   string          `json:"target"` // what the operation acts on
+	Status   string          `json:"status"`
+	Progress int             `json:"progress"` // percent
+	Step     string          `json:"step,omitempty"`
+	Result   json.RawMessage `json:"result,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Created  time.Time       `json:"created"`
+	Updated  time.Time       `json:"updated"`
+	Finished *time.Time      `json:"finished,omitempty"`
+}
+
+func (op *Operation) Done() bool {
+	return op.Status != StatusRunning
+}
+
+// Reports what the work is doing and how far along it is, as a percentage
+type Progress func(step string, percent int)
+
+// The work itself. It should stop early once ctx is cancelled; whatever it
+// returns becomes the operation's result.
+type Func func(ctx context.Context, progress Progress) (interface{}, error)
+
+type Service struct {
+	dir string
+	ctx context.Context
+
+	mu          sync.Mutex
+	operations  map[string]*Operation
+	cancels     map[string]context.CancelFunc
+	done        map[string]chan struct{}
+	subscribers map[chan Operation]struct{}
+}
+
+func NewService(dataDir string) (*Service, error) {
+	s := &Service{
+		dir:         filepath.Join(dataDir, "operations"),
+		ctx:         context.Background(),
+		operations:  make(map[string]*Operation),
+		cancels:     make(map[string]context.CancelFunc),
+		done:        make(map[string]chan struct{}),
+		subscribers: make(map[chan Operation]struct{}),
+	}
+	if err := store.Load(s.path(), &s.operations); err != nil {
+		return nil, err
+	}
+
+	// Nothing survives a restart, so operations still running were cut short
+	interrupted := false
+	for _, op := range s.operations {
+		if !op.Done() {
+			s.finish(op, StatusFailed, nil, "interrupted by server restart")
+			interrupted = true
+		}
+	}
+	if interrupted {
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Prune finished operations until ctx is done. Running operations are
+// cancelled along with ctx.
+func (s *Service) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.mu.Lock()
+				if s.prune() {
+					if err := s.save(); err != nil {
+						log.Printf("Failed to save operations: %v", err)
+					}
+				}
+				s.mu.Unlock()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Start fn in the background and return the new operation straight away
+func (s *Service) Run(kind, target string, fn Func) Operation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	op := &Operation{
+		ID:      uuid.New().String(),
+		Kind:    kind,
+		Target:  target,
+		Status:  StatusRunning,
+		Created: now,
+		Updated: now,
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.operations[op.ID] = op
+	s.cancels[op.ID] = cancel
+	s.done[op.ID] = make(chan struct{})
+	s.prune()
+	if err := s.save(); err != nil {
+		log.Printf("Failed to save operations: %v", err)
+	}
+	s.notify(op)
+
+	go s.run(ctx, op.ID, fn)
+	return *op
+}
+
+func (s *Service) run(ctx context.Context, id string, fn Func) {
+	progress := func(step string, percent int) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		op := s.operations[id]
+		if op.Done() || ctx.Err() != nil || op.Step == step && op.Progress == percent {
+			return
+		}
+		op.Step, op.Progress, op.Updated = step, percent, time.Now()
+		s.notify(op)
+	}
+
+	result, err := fn(ctx, progress)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Work that completed despite a late cancel still succeeded
+	op := s.operations[id]
+	switch {
+	case err != nil && ctx.Err() != nil:
+		s.finish(op, StatusCancelled, nil, "cancelled")
+	case err != nil:
+		s.finish(op, StatusFailed, nil, err.Error())
+	case result == nil:
+		s.finish(op, StatusSucceeded, nil, "")
+	default:
+		encoded, encodeErr := json.Marshal(result)
+		if encodeErr != nil {
+			s.finish(op, StatusFailed, nil, fmt.Sprintf("failed to encode result: %v", encodeErr))
+			break
+		}
+		s.finish(op, StatusSucceeded, encoded, "")
+	}
+
+	s.cancels[id]()
+	delete(s.cancels, id)
+	close(s.done[id])
+	delete(s.done, id)
+	if err := s.save(); err != nil {
+		log.Printf("Failed to save operations: %v", err)
+	}
+}
+
+// Caller holds s.mu
+func (s *Service) finish(op *Operation, status string, result json.RawMessage, message string) {
+	now := time.Now()
+	op.Status, op.Result, op.Error = status, result, message
+	op.Updated, op.Finished = now, &now
+	if status == StatusSucceeded {
+		op.Progress, op.Step = 100, ""
+	}
+	s.notify(op)
+}
+
+func (s *Service) Get(id string) (Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, ok := s.operations[id]
+	if !ok {
+		return Operation{}, fmt.Errorf("%w: %s", ErrOperationNotFound, id)
+	}
+	return *op, nil
+}
+
+// Newest first, optionally only those with the given kind or status
+func (s *Service) List(kind, status string) []Operation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Operation, 0, len(s.operations))
+	for _, op := range s.operations {
+		if kind != "" && op.Kind != kind || status != "" && op.Status != status {
+			continue
+		}
+		list = append(list, *op)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Created.After(list[j].Created)
+	})
+	return list
+}
+
+// Ask a running operation to stop. It reports cancelled once the work
+// has wound down.
+func (s *Service) Cancel(id string) (Operation, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	op, ok := s.operations[id]
+	if !ok {
+		return Operation{}, fmt.Errorf("%w: %s", ErrOperationNotFound, id)
+	}
+	cancel, running := s.cancels[id]
+	if !running {
+		return *op, fmt.Errorf("%w: %s is %s", ErrNotCancellable, id, op.Status)
+	}
+	cancel()
+	op.Step, op.Updated = "cancelling", time.Now()
+	s.notify(op)
+	return *op, nil
+}
+
+// Block until the operation finishes or ctx is done, returning it as it
+// stands either way
+func (s *Service) Wait(ctx context.Context, id string) (Operation, error) {
+	s.mu.Lock()
+	done, running := s.done[id]
+	s.mu.Unlock()
+
+	if running {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+	return s.Get(id)
+}
+
+// Receive every operation update until cancel is called. Slow subscribers
+// miss updates rather than holding up the work.
+func (s *Service) Subscribe() (<-chan Operation, func()) {
+	ch := make(chan Operation, 32)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	cancel := func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// Caller holds s.mu
+func (s *Service) notify(op *Operation) {
+	for ch := range s.subscribers {
+		select {
+		case ch <- *op:
+		default:
+		}
+	}
+}
+
+// Drop finished operations past retention, then the oldest beyond
+// maxFinished. Caller holds s.mu.
+func (s *Service) prune() bool {
+	var finished []*Operation
+	pruned := false
+	for id, op := range s.operations {
+		if !op.Done() {
+			continue
+		}
+		if time.Since(*op.Finished) > retention {
+			delete(s.operations, id)
+			pruned = true
+			continue
+		}
+		finished = append(finished, op)
+	}
+
+	if len(finished) > maxFinished {
+		sort.Slice(finished, func(i, j int) bool {
+			return finished[i].Finished.After(*finished[j].Finished)
+		})
+		for _, op := range finished[maxFinished:] {
+			delete(s.operations, op.ID)
+		}
+		pruned = true
+	}
+	return pruned
+}
+
+// Caller holds s.mu
+func (s *Service) save() error {
+	return store.Save(s.path(), s.operations)
+}
+
+func (s *Service) path() string {
+	return filepath.Join(s.dir, "operations.json")
+}
@@ -0,0 +1,185 @@
+package operations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func newTestService(t *testing.T) (*Service, string) {
+	t.Helper()
+	dir := t.TempDir()
+	s, err := NewService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, dir
+}
+
+func wait(t *testing.T, s *Service, id string) Operation {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	op, err := s.Wait(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !op.Done() {
+		t.Fatalf("operation %s still running: %+v", id, op)
+	}
+	return op
+}
+
+func TestRun(t *testing.T) {
+	s, _ := newTestService(t)
+	updates, unsubscribe := s.Subscribe()
+	defer unsubscribe()
+
+	release := make(chan struct{})
+	op := s.Run("instance.create", "web", func(ctx context.Context, progress Progress) (interface{}, error) {
+		progress("pulling image", 40)
+		progress("pulling image", 40)
+		<-release
+		return map[string]string{"id": "abc"}, nil
+	})
+	if op.Status != StatusRunning || op.Kind != "instance.create" || op.Target != "web" {
+		t.Errorf("started = %+v", op)
+	}
+	close(release)
+	op = wait(t, s, op.ID)
+	if op.Status != StatusSucceeded || op.Progress != 100 || string(op.Result) != `{"id":"abc"}` || op.Finished == nil {
+		t.Errorf("finished = %+v", op)
+	}
+
+	// started, one progress update (repeats are dropped), finished
+	var steps []string
+	for len(steps) < 3 {
+		select {
+		case update := <-updates:
+			steps = append(steps, update.Status+":"+update.Step)
+		case <-time.After(time.Second):
+			t.Fatalf("updates = %v", steps)
+		}
+	}
+	if steps[0] != "running:" || steps[1] != "running:pulling image" || steps[2] != "succeeded:" {
+		t.Errorf("updates = %v", steps)
+	}
+
+	failed := s.Run("snapshot.create", "web", func(context.Context, Progress) (interface{}, error) {
+		return nil, errors.New("no space left")
+	})
+	if op := wait(t, s, failed.ID); op.Status != StatusFailed || op.Error != "no space left" {
+		t.Errorf("failed = %+v", op)
+	}
+	if list := s.List("", StatusFailed); len(list) != 1 || list[0].ID != failed.ID {
+		t.Errorf("List(failed) = %+v", list)
+	}
+	if list := s.List("instance.create", ""); len(list) != 1 || list[0].ID != op.ID {
+		t.Errorf("List(instance.create) = %+v", list)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	s, _ := newTestService(t)
+	op := s.Run("backup.create", "data", func(ctx context.Context, progress Progress) (interface{}, error) {
+		<-ctx.Done()
+		progress("too late", 90)
+		return nil, ctx.Err()
+	})
+
+	cancelling, err := s.Cancel(op.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cancelling.Step != "cancelling" {
+		t.Errorf("cancelling = %+v", cancelling)
+	}
+	op = wait(t, s, op.ID)
+	if op.Status != StatusCancelled || op.Step != "cancelling" {
+		t.Errorf("cancelled = %+v", op)
+	}
+	if _, err := s.Cancel(op.ID); !errors.Is(err, ErrNotCancellable) {
+		t.Errorf("cancelling twice: %v", err)
+	}
+	if _, err := s.Cancel("missing"); !errors.Is(err, ErrOperationNotFound) {
+		t.Errorf("cancelling a missing operation: %v", err)
+	}
+}
+
+func TestWaitTimesOut(t *testing.T) {
+	s, _ := newTestService(t)
+	release := make(chan struct{})
+	started := s.Run("image.pull", "nginx", func(context.Context, Progress) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	op, err := s.Wait(ctx, started.ID)
+	if err != nil || op.Status != StatusRunning {
+		t.Errorf("Wait = %+v, %v", op, err)
+	}
+	close(release)
+	wait(t, s, started.ID)
+	if _, err := s.Wait(context.Background(), "missing"); !errors.Is(err, ErrOperationNotFound) {
+		t.Errorf("waiting for a missing operation: %v", err)
+	}
+}
+
+func TestRestartInterruptsRunning(t *testing.T) {
+	s, dir := newTestService(t)
+	release := make(chan struct{})
+	running := s.Run("image.pull", "nginx", func(context.Context, Progress) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	done := s.Run("image.pull", "redis", func(context.Context, Progress) (interface{}, error) {
+		return nil, nil
+	})
+	wait(t, s, done.ID)
+
+	reopened, err := NewService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if op, _ := reopened.Get(running.ID); op.Status != StatusFailed || op.Error != "interrupted by server restart" {
+		t.Errorf("interrupted = %+v", op)
+	}
+	if op, _ := reopened.Get(done.ID); op.Status != StatusSucceeded {
+		t.Errorf("finished = %+v", op)
+	}
+	close(release)
+	wait(t, s, running.ID)
+}
+
+func TestPrune(t *testing.T) {
+	s, _ := newTestService(t)
+	now := time.Now()
+	old := now.Add(-retention - time.Minute)
+	s.operations["old"] = &Operation{ID: "old", Status: StatusSucceeded, Finished: &old}
+	s.operations["running"] = &Operation{ID: "running", Status: StatusRunning}
+	for i := 0; i < maxFinished+1; i++ {
+		finished := now.Add(time.Duration(-i) * time.Second)
+		id := fmt.Sprintf("op-%03d", i)
+		s.operations[id] = &Operation{ID: id, Status: StatusSucceeded, Finished: &finished}
+	}
+
+	if !s.prune() {
+		t.Fatal("nothing pruned")
+	}
+	if _, ok := s.operations["old"]; ok {
+		t.Error("kept an operation past retention")
+	}
+	if _, ok := s.operations["running"]; !ok {
+		t.Error("pruned a running operation")
+	}
+	if len(s.operations) != maxFinished+1 {
+		t.Errorf("%d operations left, want %d", len(s.operations), maxFinished+1)
+	}
+	if _, ok := s.operations[fmt.Sprintf("op-%03d", maxFinished)]; ok {
+		t.Error("kept the oldest finished operation")
+	}
+}
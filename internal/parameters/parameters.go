@@ -0,0 +1,466 @@
+// Hierarchical parameter store with versions, labels and change notifications
+package parameters
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/store"
+)
+
+// Parameter types
+const (
+	TypeString       = "String"
+	TypeStringList   = "StringList" // comma-separated values
+	TypeSecureString = "SecureString"
+)
+
+const (
+	maxVersions   = 100 // oldest unlabelled versions are dropped past this
+	maxValueSize  = 8 * 1024
+	maxNameLength = 1011
+	maxDepth      = 15
+)
+
+var (
+	ErrParameterNotFound = errors.New("parameter not found")
+	ErrVersionNotFound   = errors.New("parameter version not found")
+	ErrParameterExists   = errors.New("parameter already exists, set overwrite to replace it")
+	ErrInvalidParameter  = errors.New("invalid parameter")
+)
+
+var (
+	validSegment = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	validLabel   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]{0,99}$`)
+)
+
+// Encrypts SecureString values, implemented by the secrets service
+type Crypter interface {
+	Seal(plaintext []byte, context string) ([]byte, error)
+	Open(sealed []byte, context string) ([]byte, error)
+}
+
+// One version of a parameter as returned to callers. SecureString values
+// are only filled in when decryption is asked for.
+type Parameter struct {
+	Name        string    `json:"name"`
+	Type        string    `json:"type"`
+	Value       string    `json:"value,omitempty"`
+	Version     int       `json:"version"`
+	Labels      []string  `json:"labels,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Modified    time.Time `json:"modified"`
+}
+
+type PutInput struct {
+	Name        string `json:"name"`
+	Value       string `json:"value"`
+	Type        string `json:"type"` // String if empty
+	Description string `json:"description"`
+	Overwrite   bool   `json:"overwrite"`
+}
+
+// Sent to subscribers; values are left out so secure ones never leak
+type Change struct {
+	Action  string    `json:"action"` // put, label or delete
+	Name    string    `json:"name"`
+	Type    string    `json:"type,omitempty"`
+	Version int       `json:"version,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+type version struct {
+	Version  int       `json:"version"`
+	Type     string    `json:"type"`
+	Value    string    `json:"value,omitempty"`
+	Sealed   []byte    `json:"sealed,omitempty"` // SecureString value
+	Labels   []string  `json:"labels,omitempty"`
+	Modified time.Time `json:"modified"`
+}
+
+type record struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	Versions    []*version `json:"versions"` // oldest first
+}
+
+type Service struct {
+	dir     string
+	crypter Crypter
+
+	mu          sync.Mutex
+	parameters  map[string]*record
+	subscribers map[chan Change]struct{}
+}
+
+func NewService(dataDir string, crypter Crypter) (*Service, error) {
+	s := &Service{
+		dir:         filepath.Join(dataDir, "parameters"),
+		crypter:     crypter,
+		parameters:  make(map[string]*record),
+		subscribers: make(map[chan Change]struct{}),
+	}
+	if err := store.Load(filepath.Join(s.dir, "parameters.json"), &s.parameters); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func validateName(name string) error {
+	if !strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") || len(name) > maxNameLength {
+		return fmt.Errorf("%w: name must look like /app/env/key", ErrInvalidParameter)
+	}
+	segments := strings.Split(name[1:], "/")
+	if len(segments) > maxDepth {
+		return fmt.Errorf("%w: name is more than %d levels deep", ErrInvalidParameter, maxDepth)
+	}
+	for _, segment := range segments {
+		if !validSegment.MatchString(segment) {
+			return fmt.Errorf("%w: name segment %q may only contain letters, digits, _, . and -", ErrInvalidParameter, segment)
+		}
+	}
+	return nil
+}
+
+// Store a new version, returning its number
+func (s *Service) Put(in PutInput) (int, error) {
+	if err := validateName(in.Name); err != nil {
+		return 0, err
+	}
+	if in.Type == "" {
+		in.Type = TypeString
+	}
+	switch in.Type {
+	case TypeString, TypeStringList, TypeSecureString:
+	default:
+		return 0, fmt.Errorf("%w: type must be String, StringList or SecureString", ErrInvalidParameter)
+	}
+	if in.Value == "" {
+		return 0, fmt.Errorf("%w: value is required", ErrInvalidParameter)
+	}
+	if len(in.Value) > maxValueSize {
+		return 0, fmt.Errorf("%w: value is larger than %d bytes", ErrInvalidParameter, maxValueSize)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, exists := s.parameters[in.Name]
+	if exists && !in.Overwrite {
+		return 0, fmt.Errorf("%w: %s", ErrParameterExists, in.Name)
+	}
+	if !exists {
+		r = &record{Name: in.Name}
+	}
+
+	v := &version{Version: 1, Type: in.Type, Modified: time.Now()}
+	if n := len(r.Versions); n > 0 {
+		v.Version = r.Versions[n-1].Version + 1
+	}
+	if in.Type == TypeSecureString {
+		sealed, err := s.crypter.Seal([]byte(in.Value), sealContext(in.Name, v.Version))
+		if err != nil {
+			return 0, fmt.Errorf("failed to encrypt parameter: %w", err)
+		}
+		v.Sealed = sealed
+	} else {
+		v.Value = in.Value
+	}
+
+	old := *r
+	r.Versions = append(r.Versions, v)
+	if in.Description != "" {
+		r.Description = in.Description
+	}
+	r.prune()
+
+	s.parameters[in.Name] = r
+	if err := s.save(); err != nil {
+		if exists {
+			*r = old
+		} else {
+			delete(s.parameters, in.Name)
+		}
+		return 0, err
+	}
+
+	s.notify(Change{Action: "put", Name: in.Name, Type: in.Type, Version: v.Version, Time: v.Modified})
+	return v.Version, nil
+}
+
+// Parameter by name, optionally with a :version or :label selector
+func (s *Service) Get(name string, decrypt bool) (*Parameter, error) {
+	name, selector := splitSelector(name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.parameters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrParameterNotFound, name)
+	}
+	v, err := r.find(selector)
+	if err != nil {
+		return nil, err
+	}
+	return s.parameter(r, v, decrypt)
+}
+
+// Latest versions of parameters under path, only direct children unless recursive
+func (s *Service) GetByPath(path string, recursive, decrypt bool) ([]Parameter, error) {
+	path = strings.TrimSuffix(path, "/")
+	if path != "" {
+		if err := validateName(path); err != nil {
+			return nil, err
+		}
+	}
+	prefix := path + "/"
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var list []Parameter
+	for name, r := range s.parameters {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if !recursive && strings.Contains(name[len(prefix):], "/") {
+			continue
+		}
+		p, err := s.parameter(r, r.Versions[len(r.Versions)-1], decrypt)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, *p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Every stored version, oldest first
+func (s *Service) History(name string, decrypt bool) ([]Parameter, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.parameters[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrParameterNotFound, name)
+	}
+
+	history := make([]Parameter, 0, len(r.Versions))
+	for _, v := range r.Versions {
+		p, err := s.parameter(r, v, decrypt)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *p)
+	}
+	return history, nil
+}
+
+// Attach labels to a version (the latest if 0), moving them off any other version
+func (s *Service) Label(name string, versionNumber int, labels []string) error {
+	if len(labels) == 0 {
+		return fmt.Errorf("%w: at least one label is required", ErrInvalidParameter)
+	}
+	for _, label := range labels {
+		if !validLabel.MatchString(label) {
+			return fmt.Errorf("%w: label %q must start with a letter or _ and contain only letters, digits, _, . and -", ErrInvalidParameter, label)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.parameters[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrParameterNotFound, name)
+	}
+	selector := ""
+	if versionNumber > 0 {
+		selector = strconv.Itoa(versionNumber)
+	}
+	target, err := r.find(selector)
+	if err != nil {
+		return err
+	}
+
+	for _, v := range r.Versions {
+		v.Labels = removeLabels(v.Labels, labels)
+	}
+	target.Labels = append(target.Labels, labels...)
+	sort.Strings(target.Labels)
+
+	if err := s.save(); err != nil {
+		return err
+	}
+	s.notify(Change{Action: "label", Name: name, Type: target.Type, Version: target.Version, Time: time.Now()})
+	return nil
+}
+
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.parameters[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrParameterNotFound, name)
+	}
+
+	delete(s.parameters, name)
+	if err := s.save(); err != nil {
+		s.parameters[name] = r
+		return err
+	}
+	s.notify(Change{Action: "delete", Name: name, Time: time.Now()})
+	return nil
+}
+
+// Receive changes until cancel is called. Slow subscribers miss changes
+// rather than holding up writers.
+func (s *Service) Subscribe() (<-chan Change, func()) {
+	ch := make(chan Change, 32)
+
+	s.mu.Lock()
+	s.subscribers[ch] = struct{}{}
+	s.mu.Unlock()
+
+	cancel := func() {
+		s.mu.Lock()
+		delete(s.subscribers, ch)
+		s.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// Decrypted latest value, for injection into instances
+func (s *Service) ParameterValue(name string) (string, error) {
+	p, err := s.Get(name, true)
+	if err != nil {
+		return "", err
+	}
+	return p.Value, nil
+}
+
+// Decrypted latest values of every parameter under path, by name
+func (s *Service) ParametersByPath(path string) (map[string]string, error) {
+	list, err := s.GetByPath(path, true, true)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(list))
+	for _, p := range list {
+		values[p.Name] = p.Value
+	}
+	return values, nil
+}
+
+// Caller holds s.mu
+func (s *Service) notify(change Change) {
+	for ch := range s.subscribers {
+		select {
+		case ch <- change:
+		default:
+		}
+	}
+}
+
+// Caller holds s.mu
+func (s *Service) save() error {
+	return store.Save(filepath.Join(s.dir, "parameters.json"), s.parameters)
+}
+
+// Caller holds s.mu
+func (s *Service) parameter(r *record, v *version, decrypt bool) (*Parameter, error) {
+	p := &Parameter{
+		Name:        r.Name,
+		Type:        v.Type,
+		Value:       v.Value,
+		Version:     v.Version,
+		Labels:      append([]string(nil), v.Labels...),
+		Description: r.Description,
+		Modified:    v.Modified,
+	}
+	if v.Type == TypeSecureString && decrypt {
+		value, err := s.crypter.Open(v.Sealed, sealContext(r.Name, v.Version))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt parameter: %w", err)
+		}
+		p.Value = string(value)
+	}
+	return p, nil
+}
+
+// Version by number or label, the latest if selector is empty
+func (r *record) find(selector string) (*version, error) {
+	if selector == "" {
+		return r.Versions[len(r.Versions)-1], nil
+	}
+	if n, err := strconv.Atoi(selector); err == nil {
+		for _, v := range r.Versions {
+			if v.Version == n {
+				return v, nil
+			}
+		}
+	} else {
+		for _, v := range r.Versions {
+			for _, label := range v.Labels {
+				if label == selector {
+					return v, nil
+				}
+			}
+		}
+	}
+	return nil, fmt.Errorf("%w: %s:%s", ErrVersionNotFound, r.Name, selector)
+}
+
+// Drop the oldest unlabelled versions beyond maxVersions
+func (r *record) prune() {
+	for len(r.Versions) > maxVersions {
+		i := 0
+		for i < len(r.Versions)-1 && len(r.Versions[i].Labels) > 0 {
+			i++
+		}
+		r.Versions = append(append([]*version(nil), r.Versions[:i]...), r.Versions[i+1:]...)
+	}
+}
+
+// Split /a/b:selector into the name and selector
+func splitSelector(name string) (string, string) {
+	if i := strings.LastIndex(name, ":"); i >= 0 && !strings.Contains(name[i:], "/") {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+func removeLabels(labels, remove []string) []string {
+	kept := labels[:0]
+	for _, label := range labels {
+		found := false
+		for _, r := range remove {
+			if label == r {
+				found = true
+				break
+			}
+		}
+		if !found {
+			kept = append(kept, label)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return kept
+}
+
+// Binds a SecureString ciphertext to its parameter and version
+func sealContext(name string, version int) string {
+	return "parameter:" + name + "#" + strconv.Itoa(version)
+}
@@ -0,0 +1,220 @@
+package parameters
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"localcloud/internal/secrets"
+)
+
+func newTestService(t *testing.T) (*Service, string) {
+	t.Helper()
+	dir := t.TempDir()
+	crypter, err := secrets.NewService(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(dir, crypter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, dir
+}
+
+func put(t *testing.T, s *Service, in PutInput) int {
+	t.Helper()
+	version, err := s.Put(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return version
+}
+
+func TestPutValidation(t *testing.T) {
+	s, _ := newTestService(t)
+	tests := []PutInput{
+		{Name: "app/db", Value: "x"},
+		{Name: "/app/db/", Value: "x"},
+		{Name: "/app/d b", Value: "x"},
+		{Name: "/" + strings.Repeat("a/", maxDepth) + "a", Value: "x"},
+		{Name: "/app/db", Value: "x", Type: "Integer"},
+		{Name: "/app/db", Value: ""},
+		{Name: "/app/db", Value: strings.Repeat("x", maxValueSize+1)},
+	}
+	for _, in := range tests {
+		if _, err := s.Put(in); !errors.Is(err, ErrInvalidParameter) {
+			t.Errorf("Put(%s) = %v, want %v", in.Name, err, ErrInvalidParameter)
+		}
+	}
+}
+
+func TestVersionsAndLabels(t *testing.T) {
+	s, _ := newTestService(t)
+	put(t, s, PutInput{Name: "/app/db/host", Value: "db-1", Description: "primary"})
+	if _, err := s.Put(PutInput{Name: "/app/db/host", Value: "db-2"}); !errors.Is(err, ErrParameterExists) {
+		t.Fatalf("Put without overwrite = %v", err)
+	}
+	if v := put(t, s, PutInput{Name: "/app/db/host", Value: "db-2", Overwrite: true}); v != 2 {
+		t.Fatalf("second version = %d", v)
+	}
+
+	if err := s.Label("/app/db/host", 1, []string{"stable"}); err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"/app/db/host":        "db-2",
+		"/app/db/host:1":      "db-1",
+		"/app/db/host:stable": "db-1",
+	}
+	for name, want := range tests {
+		p, err := s.Get(name, false)
+		if err != nil || p.Value != want || p.Description != "primary" {
+			t.Errorf("Get(%s) = %+v, %v, want %s", name, p, err, want)
+		}
+	}
+	if _, err := s.Get("/app/db/host:3", false); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("missing version: %v", err)
+	}
+
+	// a label lives on one version at a time
+	if err := s.Label("/app/db/host", 0, []string{"stable"}); err != nil {
+		t.Fatal(err)
+	}
+	history, _ := s.History("/app/db/host", false)
+	if len(history[0].Labels) != 0 || len(history[1].Labels) != 1 {
+		t.Errorf("labels after moving: %v, %v", history[0].Labels, history[1].Labels)
+	}
+	if err := s.Label("/app/db/host", 0, []string{"1bad"}); !errors.Is(err, ErrInvalidParameter) {
+		t.Errorf("invalid label: %v", err)
+	}
+}
+
+func TestPruneKeepsLabelledVersions(t *testing.T) {
+	s, _ := newTestService(t)
+	put(t, s, PutInput{Name: "/app/key", Value: "v1"})
+	if err := s.Label("/app/key", 1, []string{"first"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxVersions+5; i++ {
+		put(t, s, PutInput{Name: "/app/key", Value: "v", Overwrite: true})
+	}
+
+	history, _ := s.History("/app/key", false)
+	if len(history) != maxVersions {
+		t.Fatalf("%d versions kept, want %d", len(history), maxVersions)
+	}
+	if history[0].Version != 1 || history[1].Version != 8 {
+		t.Errorf("oldest versions kept = %d, %d", history[0].Version, history[1].Version)
+	}
+}
+
+func TestGetByPath(t *testing.T) {
+	s, _ := newTestService(t)
+	for _, name := range []string{"/app/prod/db/host", "/app/prod/db/port", "/app/prod/mode", "/app/staging/mode"} {
+		put(t, s, PutInput{Name: name, Value: "x"})
+	}
+
+	names := func(list []Parameter) string {
+		var out []string
+		for _, p := range list {
+			out = append(out, p.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	direct, _ := s.GetByPath("/app/prod", false, false)
+	if got := names(direct); got != "/app/prod/mode" {
+		t.Errorf("direct children = %s", got)
+	}
+	all, _ := s.GetByPath("/app/prod/", true, false)
+	if got := names(all); got != "/app/prod/db/host,/app/prod/db/port,/app/prod/mode" {
+		t.Errorf("recursive = %s", got)
+	}
+	if _, err := s.GetByPath("app", true, false); err == nil {
+		t.Error("accepted an invalid path")
+	}
+}
+
+func TestSecureString(t *testing.T) {
+	s, dir := newTestService(t)
+	put(t, s, PutInput{Name: "/app/db/password", Value: "hunter2", Type: TypeSecureString})
+
+	if p, _ := s.Get("/app/db/password", false); p.Value != "" {
+		t.Errorf("value returned without decryption: %q", p.Value)
+	}
+	if value, err := s.ParameterValue("/app/db/password"); err != nil || value != "hunter2" {
+		t.Errorf("ParameterValue = %q, %v", value, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "parameters", "parameters.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("hunter2")) {
+		t.Error("secure value stored in plain text")
+	}
+
+	// values survive a restart
+	crypter, _ := secrets.NewService(dir, "passphrase")
+	reopened, err := NewService(dir, crypter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values, err := reopened.ParametersByPath("/app"); err != nil || values["/app/db/password"] != "hunter2" {
+		t.Errorf("after reopening: %v, %v", values, err)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	s, _ := newTestService(t)
+	changes, cancel := s.Subscribe()
+
+	put(t, s, PutInput{Name: "/app/key", Value: "secret", Type: TypeSecureString})
+	if err := s.Label("/app/key", 0, []string{"live"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("/app/key"); err != nil {
+		t.Fatal(err)
+	}
+
+	var actions []string
+	for i := 0; i < 3; i++ {
+		change := <-changes
+		actions = append(actions, change.Action)
+		if change.Name != "/app/key" {
+			t.Errorf("change for %s", change.Name)
+		}
+	}
+	if strings.Join(actions, ",") != "put,label,delete" {
+		t.Errorf("changes = %v", actions)
+	}
+
+	cancel()
+	put(t, s, PutInput{Name: "/app/other", Value: "x"})
+	select {
+	case change := <-changes:
+		t.Errorf("change after cancel: %+v", change)
+	default:
+	}
+
+	if err := s.Delete("/app/key"); !errors.Is(err, ErrParameterNotFound) {
+		t.Errorf("deleting twice: %v", err)
+	}
+}
+
+func TestSplitSelector(t *testing.T) {
+	tests := []struct{ in, name, selector string }{
+		{"/app/key", "/app/key", ""},
+		{"/app/key:3", "/app/key", "3"},
+		{"/app/key:live", "/app/key", "live"},
+		{"/app:x/key", "/app:x/key", ""},
+	}
+	for _, tt := range tests {
+		if name, selector := splitSelector(tt.in); name != tt.name || selector != tt.selector {
+			t.Errorf("splitSelector(%q) = %q, %q", tt.in, name, selector)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package queues
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Account and region used in queue URLs and ARNs
+const (
+	AccountID = "000000000000"
+	Region    = "us-east-1"
+)
+
+func QueueARN(name string) string {
+	return "arn:aws:sqs:" + Region + ":" + AccountID + ":" + name
+}
+
+// Queue name from an ARN or URL
+func nameFromARN(arn string) string {
+	if i := strings.LastIndexAny(arn, ":/"); i >= 0 {
+		return arn[i+1:]
+	}
+	return arn
+}
+
+type redrivePolicy struct {
+	DeadLetterTargetArn string      `json:"deadLetterTargetArn"`
+	MaxReceiveCount     json.Number `json:"maxReceiveCount"` // SDKs send a string or a number
+}
+
+// Set queue fields from SQS attribute names (CreateQueue, SetQueueAttributes)
+func applyAttributes(q *Queue, attrs map[string]string) error {
+	intAttr := func(name, value string, dest *int) error {
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be an integer", ErrInvalidParameter, name)
+		}
+		*dest = n
+		return nil
+	}
+	boolAttr := func(name, value string, dest *bool) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%w: %s must be true or false", ErrInvalidParameter, name)
+		}
+		*dest = b
+		return nil
+	}
+
+	for name, value := range attrs {
+		var err error
+		switch name {
+		case "VisibilityTimeout":
+			err = intAttr(name, value, &q.VisibilityTimeout)
+		case "DelaySeconds":
+			err = intAttr(name, value, &q.DelaySeconds)
+		case "MessageRetentionPeriod":
+			err = intAttr(name, value, &q.RetentionSeconds)
+		case "ReceiveMessageWaitTimeSeconds":
+			err = intAttr(name, value, &q.ReceiveWaitSeconds)
+		case "MaximumMessageSize":
+			err = intAttr(name, value, &q.MaxMessageSize)
+		case "FifoQueue":
+			err = boolAttr(name, value, &q.FIFO)
+		case "ContentBasedDeduplication":
+			err = boolAttr(name, value, &q.ContentBasedDedup)
+		case "RedrivePolicy":
+			if value == "" {
+				q.DeadLetterQueue, q.MaxReceiveCount = "", 0
+				continue
+			}
+			var policy redrivePolicy
+			if err := json.Unmarshal([]byte(value), &policy); err != nil {
+				return fmt.Errorf("%w: RedrivePolicy must be JSON", ErrInvalidParameter)
+			}
+			count, err := policy.MaxReceiveCount.Int64()
+			if err != nil {
+				return fmt.Errorf("%w: maxReceiveCount must be an integer", ErrInvalidParameter)
+			}
+			q.DeadLetterQueue = nameFromARN(policy.DeadLetterTargetArn)
+			q.MaxReceiveCount = int(count)
+		case "Policy", "KmsMasterKeyId", "KmsDataKeyReusePeriodSeconds", "SqsManagedSseEnabled",
+			"DeduplicationScope", "FifoThroughputLimit", "RedriveAllowPolicy":
+			// accepted for compatibility, no effect locally
+		default:
+			return fmt.Errorf("%w: unknown attribute %s", ErrInvalidParameter, name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Queue attributes by SQS name, limited to names (or "All")
+func (st *Status) Attributes(names []string) map[string]string {
+	all := map[string]string{
+		"QueueArn":                              QueueARN(st.Name),
+		"VisibilityTimeout":                     strconv.Itoa(st.VisibilityTimeout),
+		"DelaySeconds":                          strconv.Itoa(st.DelaySeconds),
+		"MessageRetentionPeriod":                strconv.Itoa(st.RetentionSeconds),
+		"ReceiveMessageWaitTimeSeconds":         strconv.Itoa(st.ReceiveWaitSeconds),
+		"MaximumMessageSize":                    strconv.Itoa(st.MaxMessageSize),
+		"ApproximateNumberOfMessages":           strconv.Itoa(st.Visible),
+		"ApproximateNumberOfMessagesNotVisible": strconv.Itoa(st.InFlight),
+		"ApproximateNumberOfMessagesDelayed":    strconv.Itoa(st.Delayed),
+		"CreatedTimestamp":                      strconv.FormatInt(st.Created.Unix(), 10),
+		"LastModifiedTimestamp":                 strconv.FormatInt(st.Modified.Unix(), 10),
+	}
+	if st.FIFO {
+		all["FifoQueue"] = "true"
+		all["ContentBasedDeduplication"] = strconv.FormatBool(st.ContentBasedDedup)
+	}
+	if st.DeadLetterQueue != "" {
+		policy, _ := json.Marshal(map[string]interface{}{
+			"deadLetterTargetArn": QueueARN(st.DeadLetterQueue),
+			"maxReceiveCount":     st.MaxReceiveCount,
+		})
+		all["RedrivePolicy"] = string(policy)
+	}
+
+	result := make(map[string]string)
+	for _, name := range names {
+		if name == "All" {
+			return all
+		}
+		if value, ok := all[name]; ok {
+			result[name] = value
+		}
+	}
+	return result
+}
+
+// Queue from SQS attributes, as CreateQueue receives them
+func QueueFromAttributes(name string, attrs map[string]string) (Queue, error) {
+	q := Queue{Name: name}
+	err := applyAttributes(&q, attrs)
+	return q, err
+}
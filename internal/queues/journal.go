@@ -0,0 +1,167 @@
+package queues
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Compact once the journal has this many more entries than live messages
+const compactSlack = 1000
+
+// Append-only log of message changes for one queue. Replaying it gives the
+// queue's messages; compaction rewrites it as one put per live message.
+type journal struct {
+	path    string
+	file    *os.File
+	entries int
+}
+
+type journalEntry struct {
+	Op      string   `json:"op"` // put, del or purge
+	Message *Message `json:"message,omitempty"`
+	ID      string   `json:"id,omitempty"`
+}
+
+// Open a journal, returning the messages it holds in arrival order
+func openJournal(path string) (*journal, []*Message, error) {
+	j := &journal{path: path}
+
+	var order []string
+	messages := make(map[string]*Message)
+
+	if file, err := os.Open(path); err == nil {
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 4*maxMessageSizeLimit)
+		for scanner.Scan() {
+			var entry journalEntry
+			if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+				// a torn write at the end of the file after a crash
+				break
+			}
+			j.entries++
+
+			switch entry.Op {
+			case "put":
+				if _, ok := messages[entry.Message.ID]; !ok {
+					order = append(order, entry.Message.ID)
+				}
+				messages[entry.Message.ID] = entry.Message
+			case "del":
+				delete(messages, entry.ID)
+			case "purge":
+				messages = make(map[string]*Message)
+				order = nil
+			}
+		}
+		file.Close()
+		if err := scanner.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to read queue journal: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("failed to open queue journal: %w", err)
+	}
+
+	live := make([]*Message, 0, len(messages))
+	for _, id := range order {
+		if m, ok := messages[id]; ok {
+			live = append(live, m)
+			delete(messages, id) // ids seen twice keep their first position
+		}
+	}
+
+	if err := j.compact(live); err != nil {
+		return nil, nil, err
+	}
+	return j, live, nil
+}
+
+func (j *journal) put(m *Message) error {
+	return j.append(journalEntry{Op: "put", Message: m})
+}
+
+func (j *journal) del(id string) error {
+	return j.append(journalEntry{Op: "del", ID: id})
+}
+
+func (j *journal) purge() error {
+	return j.append(journalEntry{Op: "purge"})
+}
+
+func (j *journal) append(entry journalEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write queue journal: %w", err)
+	}
+	j.entries++
+	return nil
+}
+
+// Compact when the journal has grown well past the live messages
+func (j *journal) maybeCompact(live []*Message) error {
+	if j.entries < len(live)+compactSlack {
+		return nil
+	}
+	return j.compact(live)
+}
+
+// Rewrite the journal with only the live messages
+func (j *journal) compact(live []*Message) error {
+	tmp := j.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to compact queue journal: %w", err)
+	}
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, m := range live {
+		if err := encoder.Encode(journalEntry{Op: "put", Message: m}); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to compact queue journal: %w", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact queue journal: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to compact queue journal: %w", err)
+	}
+	file.Close()
+
+	if err := os.Rename(tmp, j.path); err != nil {
+		return fmt.Errorf("failed to compact queue journal: %w", err)
+	}
+
+	if j.file != nil {
+		j.file.Close()
+	}
+	j.file, err = os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open queue journal: %w", err)
+	}
+	j.entries = len(live)
+	return nil
+}
+
+func (j *journal) close() error {
+	if j.file == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+// Close and delete the journal
+func (j *journal) remove() error {
+	j.close()
+	if err := os.Remove(j.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove queue journal: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,807 @@
+// SQS-style message queues kept in LocalCloud's own process
+package queues
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"localcloud/internal/store"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultVisibilityTimeout = 30
+	defaultRetention         = 4 * 24 * 60 * 60 // seconds
+	maxMessageSizeLimit      = 256 * 1024
+	maxWaitSeconds           = 20
+	maxReceiveBatch          = 10
+	dedupWindow              = 5 * time.Minute
+	sampleInterval           = 5 * time.Second
+	samplesKept              = 360 // 30 minutes
+)
+
+var (
+	ErrQueueNotFound        = errors.New("queue does not exist")
+	ErrQueueExists          = errors.New("a queue with this name already exists with different attributes")
+	ErrInvalidReceiptHandle = errors.New("receipt handle is invalid")
+	ErrInvalidParameter     = errors.New("invalid parameter")
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9_-]{1,80}$`)
+
+type Queue struct {
+	Name               string    `json:"name"`
+	FIFO               bool      `json:"fifo"`
+	VisibilityTimeout  int       `json:"visibility_timeout"` // seconds
+	DelaySeconds       int       `json:"delay_seconds"`
+	RetentionSeconds   int       `json:"retention_seconds"`
+	ReceiveWaitSeconds int       `json:"receive_wait_seconds"` // default long polling
+	MaxMessageSize     int       `json:"max_message_size"`
+	ContentBasedDedup  bool      `json:"content_based_deduplication,omitempty"`
+	DeadLetterQueue    string    `json:"dead_letter_queue,omitempty"` // queue name
+	MaxReceiveCount    int       `json:"max_receive_count,omitempty"`
+	Created            time.Time `json:"created"`
+	Modified           time.Time `json:"modified"`
+}
+
+type MessageAttribute struct {
+	DataType    string `json:"DataType"` // String, Number or Binary, optionally with a .suffix
+	StringValue string `json:"StringValue,omitempty"`
+	BinaryValue []byte `json:"BinaryValue,omitempty"`
+}
+
+type Message struct {
+	ID            string                      `json:"id"`
+	Body          string                      `json:"body"`
+	Attributes    map[string]MessageAttribute `json:"attributes,omitempty"`
+	GroupID       string                      `json:"group_id,omitempty"`
+	DedupID       string                      `json:"deduplication_id,omitempty"`
+	Sequence      string                      `json:"sequence_number,omitempty"`
+	SourceQueue   string                      `json:"source_queue,omitempty"` // set when moved to a dead-letter queue
+	Sent          time.Time                   `json:"sent"`
+	VisibleAt     time.Time                   `json:"visible_at"`
+	ReceiveCount  int                         `json:"receive_count"`
+	FirstReceived time.Time                   `json:"first_received,omitempty"`
+	ReceiptHandle string                      `json:"receipt_handle,omitempty"`
+}
+
+type Stats struct {
+	Visible          int     `json:"visible"`
+	InFlight         int     `json:"in_flight"`
+	Delayed          int     `json:"delayed"`
+	OldestAgeSeconds float64 `json:"oldest_age_seconds"`
+}
+
+type Status struct {
+	Queue
+	Stats
+}
+
+// Queue depth at a point in time, for charts
+type Sample struct {
+	Time time.Time `json:"time"`
+	Stats
+}
+
+type SendInput struct {
+	Body         string                      `json:"body"`
+	DelaySeconds *int                        `json:"delay_seconds,omitempty"` // queue default if nil
+	Attributes   map[string]MessageAttribute `json:"attributes,omitempty"`
+	GroupID      string                      `json:"group_id,omitempty"`
+	DedupID      string                      `json:"deduplication_id,omitempty"`
+}
+
+type SendResult struct {
+	MessageID string `json:"message_id"`
+	Sequence  string `json:"sequence_number,omitempty"`
+}
+
+type ReceiveInput struct {
+	MaxMessages       int  `json:"max_messages"`
+	VisibilityTimeout *int `json:"visibility_timeout,omitempty"` // queue default if nil
+	WaitSeconds       *int `json:"wait_seconds,omitempty"`       // queue default if nil
+}
+
+type dedupEntry struct {
+	result  SendResult
+	expires time.Time
+}
+
+type queueState struct {
+	Queue
+	messages []*Message // arrival order
+	journal  *journal
+	dedup    map[string]dedupEntry
+	sequence uint64
+	samples  []Sample
+	changed  chan struct{} // closed when messages may have become available
+}
+
+type Service struct {
+	dir string
+
+	mu     sync.Mutex
+	queues map[string]*queueState
+}
+
+func NewService(dataDir string) (*Service, error) {
+	s := &Service{
+		dir:    filepath.Join(dataDir, "queues"),
+		queues: make(map[string]*queueState),
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create queue directory: %w", err)
+	}
+
+	var saved map[string]Queue
+	if err := store.Load(s.metaPath(), &saved); err != nil {
+		return nil, err
+	}
+	for name, q := range saved {
+		j, messages, err := openJournal(s.journalPath(name))
+		if err != nil {
+			return nil, err
+		}
+		state := &queueState{
+			Queue:    q,
+			messages: messages,
+			journal:  j,
+			dedup:    make(map[string]dedupEntry),
+			changed:  make(chan struct{}),
+		}
+		for _, m := range messages {
+			var seq uint64
+			fmt.Sscan(m.Sequence, &seq)
+			if seq > state.sequence {
+				state.sequence = seq
+			}
+		}
+		s.queues[name] = state
+	}
+	return s, nil
+}
+
+// Expire old messages and record depth samples until ctx is done
+func (s *Service) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(sampleInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.tick()
+			case <-ctx.Done():
+				s.mu.Lock()
+				for _, q := range s.queues {
+					q.journal.close()
+				}
+				s.mu.Unlock()
+				return
+			}
+		}
+	}()
+}
+
+func (s *Service) tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, q := range s.queues {
+		retention := time.Duration(q.RetentionSeconds) * time.Second
+		kept := q.messages[:0]
+		for _, m := range q.messages {
+			if now.Sub(m.Sent) > retention {
+				q.journal.del(m.ID)
+				continue
+			}
+			kept = append(kept, m)
+		}
+		q.messages = kept
+
+		for id, entry := range q.dedup {
+			if now.After(entry.expires) {
+				delete(q.dedup, id)
+			}
+		}
+
+		q.samples = append(q.samples, Sample{Time: now, Stats: q.stats(now)})
+		if len(q.samples) > samplesKept {
+			q.samples = q.samples[len(q.samples)-samplesKept:]
+		}
+
+		if err := q.journal.maybeCompact(q.messages); err != nil {
+			log.Printf("queues: %v", err)
+		}
+
+		// messages whose visibility timeout ran out are available again
+		q.notify()
+	}
+}
+
+// Create a queue, or return the existing one if its attributes match
+func (s *Service) CreateQueue(q Queue) (*Status, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.queues[q.Name]; ok {
+		q.Created, q.Modified = existing.Created, existing.Modified
+		if q != existing.Queue {
+			return nil, ErrQueueExists
+		}
+		return existing.status(time.Now()), nil
+	}
+	if err := s.checkDeadLetterLocked(&q); err != nil {
+		return nil, err
+	}
+
+	j, _, err := openJournal(s.journalPath(q.Name))
+	if err != nil {
+		return nil, err
+	}
+	q.Created = time.Now()
+	q.Modified = q.Created
+	state := &queueState{
+		Queue:   q,
+		journal: j,
+		dedup:   make(map[string]dedupEntry),
+		changed: make(chan struct{}),
+	}
+	s.queues[q.Name] = state
+	if err := s.saveLocked(); err != nil {
+		return nil, err
+	}
+	return state.status(time.Now()), nil
+}
+
+// Change queue settings with SQS attribute names
+func (s *Service) SetAttributes(name string, attrs map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return ErrQueueNotFound
+	}
+
+	q := state.Queue
+	if err := applyAttributes(&q, attrs); err != nil {
+		return err
+	}
+	if q.FIFO != state.FIFO {
+		return fmt.Errorf("%w: FifoQueue cannot be changed", ErrInvalidParameter)
+	}
+	if err := q.normalize(); err != nil {
+		return err
+	}
+	if err := s.checkDeadLetterLocked(&q); err != nil {
+		return err
+	}
+	q.Modified = time.Now()
+	state.Queue = q
+	return s.saveLocked()
+}
+
+func (s *Service) GetQueue(name string) (*Status, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return nil, ErrQueueNotFound
+	}
+	return state.status(time.Now()), nil
+}
+
+// Queues whose names start with prefix
+func (s *Service) ListQueues(prefix string) []Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	statuses := make([]Status, 0, len(s.queues))
+	for name, state := range s.queues {
+		if strings.HasPrefix(name, prefix) {
+			statuses = append(statuses, *state.status(now))
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+func (s *Service) DeleteQueue(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return ErrQueueNotFound
+	}
+	delete(s.queues, name)
+	state.notify()
+	if err := state.journal.remove(); err != nil {
+		return err
+	}
+	return s.saveLocked()
+}
+
+func (s *Service) Purge(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return ErrQueueNotFound
+	}
+	state.messages = nil
+	return state.journal.purge()
+}
+
+func (s *Service) Send(name string, in SendInput) (*SendResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return nil, ErrQueueNotFound
+	}
+	if err := state.validateSend(&in); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if state.FIFO {
+		if entry, ok := state.dedup[in.DedupID]; ok && now.Before(entry.expires) {
+			// duplicate within the deduplication window
+			return &entry.result, nil
+		}
+	}
+
+	delay := state.DelaySeconds
+	if in.DelaySeconds != nil {
+		delay = *in.DelaySeconds
+	}
+
+	m := &Message{
+		ID:         uuid.New().String(),
+		Body:       in.Body,
+		Attributes: in.Attributes,
+		GroupID:    in.GroupID,
+		DedupID:    in.DedupID,
+		Sent:       now,
+		VisibleAt:  now.Add(time.Duration(delay) * time.Second),
+	}
+	if state.FIFO {
+		state.sequence++
+		m.Sequence = fmt.Sprintf("%020d", state.sequence)
+	}
+
+	if err := state.journal.put(m); err != nil {
+		return nil, err
+	}
+	state.messages = append(state.messages, m)
+
+	result := SendResult{MessageID: m.ID, Sequence: m.Sequence}
+	if state.FIFO {
+		state.dedup[in.DedupID] = dedupEntry{result: result, expires: now.Add(dedupWindow)}
+	}
+	state.notify()
+	return &result, nil
+}
+
+// Receive up to MaxMessages, waiting up to WaitSeconds for any to arrive.
+// Returned messages are copies carrying a fresh receipt handle.
+func (s *Service) Receive(ctx context.Context, name string, in ReceiveInput) ([]Message, error) {
+	if in.MaxMessages <= 0 {
+		in.MaxMessages = 1
+	}
+	if in.MaxMessages > maxReceiveBatch {
+		return nil, fmt.Errorf("%w: MaxNumberOfMessages must be between 1 and %d", ErrInvalidParameter, maxReceiveBatch)
+	}
+	if in.VisibilityTimeout != nil && (*in.VisibilityTimeout < 0 || *in.VisibilityTimeout > 43200) {
+		return nil, fmt.Errorf("%w: VisibilityTimeout must be between 0 and 43200", ErrInvalidParameter)
+	}
+	if in.WaitSeconds != nil && (*in.WaitSeconds < 0 || *in.WaitSeconds > maxWaitSeconds) {
+		return nil, fmt.Errorf("%w: WaitTimeSeconds must be between 0 and %d", ErrInvalidParameter, maxWaitSeconds)
+	}
+
+	s.mu.Lock()
+	state, ok := s.queues[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrQueueNotFound
+	}
+	wait := state.ReceiveWaitSeconds
+	if in.WaitSeconds != nil {
+		wait = *in.WaitSeconds
+	}
+	s.mu.Unlock()
+
+	deadline := time.Now().Add(time.Duration(wait) * time.Second)
+	for {
+		s.mu.Lock()
+		state, ok := s.queues[name]
+		if !ok {
+			s.mu.Unlock()
+			return nil, ErrQueueNotFound
+		}
+		messages, next := s.receiveLocked(state, in)
+		changed := state.changed
+		s.mu.Unlock()
+
+		now := time.Now()
+		if len(messages) > 0 || !now.Before(deadline) {
+			return messages, nil
+		}
+
+		// wake for new messages, the next message becoming visible, or the deadline
+		wake := deadline
+		if !next.IsZero() && next.Before(wake) {
+			wake = next
+		}
+		timer := time.NewTimer(wake.Sub(now))
+		select {
+		case <-changed:
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return []Message{}, nil
+		}
+		timer.Stop()
+	}
+}
+
+// Take visible messages, moving ones past their receive limit to the
+// dead-letter queue. Also returns when the next hidden message shows up.
+func (s *Service) receiveLocked(state *queueState, in ReceiveInput) ([]Message, time.Time) {
+	now := time.Now()
+	visibility := state.VisibilityTimeout
+	if in.VisibilityTimeout != nil {
+		visibility = *in.VisibilityTimeout
+	}
+
+	var deadLetter *queueState
+	if state.DeadLetterQueue != "" && state.MaxReceiveCount > 0 {
+		deadLetter = s.queues[state.DeadLetterQueue]
+	}
+
+	received := []Message{}
+	var next time.Time
+	blockedGroups := make(map[string]bool)
+	kept := state.messages[:0]
+
+	for _, m := range state.messages {
+		if len(received) >= in.MaxMessages {
+			kept = append(kept, m)
+			continue
+		}
+		if m.VisibleAt.After(now) {
+			// in FIFO queues a message in flight holds back the rest of its group
+			if state.FIFO {
+				blockedGroups[m.GroupID] = true
+			}
+			if next.IsZero() || m.VisibleAt.Before(next) {
+				next = m.VisibleAt
+			}
+			kept = append(kept, m)
+			continue
+		}
+		if state.FIFO && blockedGroups[m.GroupID] {
+			kept = append(kept, m)
+			continue
+		}
+
+		if deadLetter != nil && m.ReceiveCount >= state.MaxReceiveCount {
+			if err := deadLetter.moveIn(m, state.Name, now); err != nil {
+				log.Printf("queues: %v", err)
+				kept = append(kept, m)
+				continue
+			}
+			state.journal.del(m.ID)
+			continue
+		}
+
+		m.ReceiveCount++
+		if m.FirstReceived.IsZero() {
+			m.FirstReceived = now
+		}
+		m.VisibleAt = now.Add(time.Duration(visibility) * time.Second)
+		m.ReceiptHandle = newReceiptHandle()
+		if err := state.journal.put(m); err != nil {
+			log.Printf("queues: %v", err)
+		}
+		received = append(received, *m)
+		kept = append(kept, m)
+	}
+	state.messages = kept
+	return received, next
+}
+
+// Accept a message that exceeded its receive count on another queue
+func (q *queueState) moveIn(m *Message, source string, now time.Time) error {
+	moved := *m
+	moved.SourceQueue = source
+	moved.VisibleAt = now
+	moved.ReceiptHandle = ""
+	if q.FIFO {
+		q.sequence++
+		moved.Sequence = fmt.Sprintf("%020d", q.sequence)
+	}
+	if err := q.journal.put(&moved); err != nil {
+		return err
+	}
+	q.messages = append(q.messages, &moved)
+	q.notify()
+	return nil
+}
+
+// Delete a received message. Handles of messages already gone are accepted,
+// as SQS does.
+func (s *Service) DeleteMessage(name, receiptHandle string) error {
+	if receiptHandle == "" {
+		return ErrInvalidReceiptHandle
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return ErrQueueNotFound
+	}
+	for i, m := range state.messages {
+		if m.ReceiptHandle == receiptHandle {
+			state.messages = append(state.messages[:i], state.messages[i+1:]...)
+			if err := state.journal.del(m.ID); err != nil {
+				return err
+			}
+			state.notify() // may unblock the message's FIFO group
+			return nil
+		}
+	}
+	return nil
+}
+
+// Change how long a received message stays hidden, counted from now
+func (s *Service) ChangeVisibility(name, receiptHandle string, seconds int) error {
+	if seconds < 0 || seconds > 43200 {
+		return fmt.Errorf("%w: VisibilityTimeout must be between 0 and 43200", ErrInvalidParameter)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return ErrQueueNotFound
+	}
+	for _, m := range state.messages {
+		if m.ReceiptHandle == receiptHandle {
+			if !m.VisibleAt.After(time.Now()) {
+				return fmt.Errorf("%w: message is not in flight", ErrInvalidReceiptHandle)
+			}
+			m.VisibleAt = time.Now().Add(time.Duration(seconds) * time.Second)
+			if err := state.journal.put(m); err != nil {
+				return err
+			}
+			state.notify()
+			return nil
+		}
+	}
+	return ErrInvalidReceiptHandle
+}
+
+// Move messages from a dead-letter queue back to the queues they came
+// from, returning how many were moved
+func (s *Service) Redrive(name string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return 0, ErrQueueNotFound
+	}
+
+	now := time.Now()
+	moved := 0
+	kept := state.messages[:0]
+	for _, m := range state.messages {
+		source, ok := s.queues[m.SourceQueue]
+		if m.SourceQueue == "" || !ok || m.VisibleAt.After(now) {
+			kept = append(kept, m)
+			continue
+		}
+
+		back := *m
+		back.SourceQueue = ""
+		back.ReceiveCount = 0
+		back.FirstReceived = time.Time{}
+		if err := source.moveIn(&back, "", now); err != nil {
+			kept = append(kept, m)
+			continue
+		}
+		state.journal.del(m.ID)
+		moved++
+	}
+	state.messages = kept
+	return moved, nil
+}
+
+func (s *Service) Samples(name string) ([]Sample, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.queues[name]
+	if !ok {
+		return nil, ErrQueueNotFound
+	}
+	samples := make([]Sample, len(state.samples))
+	copy(samples, state.samples)
+	return samples, nil
+}
+
+func (s *Service) checkDeadLetterLocked(q *Queue) error {
+	if q.DeadLetterQueue == "" {
+		return nil
+	}
+	dlq, ok := s.queues[q.DeadLetterQueue]
+	if !ok {
+		return fmt.Errorf("%w: dead-letter queue %s does not exist", ErrInvalidParameter, q.DeadLetterQueue)
+	}
+	if dlq.FIFO != q.FIFO {
+		return fmt.Errorf("%w: a dead-letter queue must be the same type as its source queue", ErrInvalidParameter)
+	}
+	if q.DeadLetterQueue == q.Name {
+		return fmt.Errorf("%w: a queue cannot be its own dead-letter queue", ErrInvalidParameter)
+	}
+	return nil
+}
+
+func (s *Service) saveLocked() error {
+	saved := make(map[string]Queue, len(s.queues))
+	for name, state := range s.queues {
+		saved[name] = state.Queue
+	}
+	return store.Save(s.metaPath(), saved)
+}
+
+func (s *Service) metaPath() string {
+	return filepath.Join(s.dir, "queues.json")
+}
+
+func (s *Service) journalPath(name string) string {
+	return filepath.Join(s.dir, name+".log")
+}
+
+// Fill in defaults and check the settings
+func (q *Queue) normalize() error {
+	if strings.HasSuffix(q.Name, ".fifo") {
+		q.FIFO = true
+	}
+	base := strings.TrimSuffix(q.Name, ".fifo")
+	if !validName.MatchString(base) || len(q.Name) > 80 {
+		return fmt.Errorf("%w: queue names are 1-80 letters, digits, dashes and underscores", ErrInvalidParameter)
+	}
+	if q.FIFO && !strings.HasSuffix(q.Name, ".fifo") {
+		return fmt.Errorf("%w: FIFO queue names must end in .fifo", ErrInvalidParameter)
+	}
+	if q.VisibilityTimeout == 0 {
+		q.VisibilityTimeout = defaultVisibilityTimeout
+	}
+	if q.RetentionSeconds == 0 {
+		q.RetentionSeconds = defaultRetention
+	}
+	if q.MaxMessageSize == 0 {
+		q.MaxMessageSize = maxMessageSizeLimit
+	}
+
+	switch {
+	case q.VisibilityTimeout < 0 || q.VisibilityTimeout > 43200:
+		return fmt.Errorf("%w: VisibilityTimeout must be between 0 and 43200", ErrInvalidParameter)
+	case q.DelaySeconds < 0 || q.DelaySeconds > 900:
+		return fmt.Errorf("%w: DelaySeconds must be between 0 and 900", ErrInvalidParameter)
+	case q.RetentionSeconds < 60 || q.RetentionSeconds > 1209600:
+		return fmt.Errorf("%w: MessageRetentionPeriod must be between 60 and 1209600", ErrInvalidParameter)
+	case q.ReceiveWaitSeconds < 0 || q.ReceiveWaitSeconds > maxWaitSeconds:
+		return fmt.Errorf("%w: ReceiveMessageWaitTimeSeconds must be between 0 and %d", ErrInvalidParameter, maxWaitSeconds)
+	case q.MaxMessageSize < 1024 || q.MaxMessageSize > maxMessageSizeLimit:
+		return fmt.Errorf("%w: MaximumMessageSize must be between 1024 and %d", ErrInvalidParameter, maxMessageSizeLimit)
+	case q.ContentBasedDedup && !q.FIFO:
+		return fmt.Errorf("%w: ContentBasedDeduplication is only for FIFO queues", ErrInvalidParameter)
+	case q.DeadLetterQueue != "" && q.MaxReceiveCount < 1:
+		return fmt.Errorf("%w: maxReceiveCount must be at least 1", ErrInvalidParameter)
+	}
+	return nil
+}
+
+func (q *queueState) validateSend(in *SendInput) error {
+	if in.Body == "" {
+		return fmt.Errorf("%w: message body must not be empty", ErrInvalidParameter)
+	}
+	size := len(in.Body)
+	for name, attr := range in.Attributes {
+		size += len(name) + len(attr.DataType) + len(attr.StringValue) + len(attr.BinaryValue)
+	}
+	if size > q.MaxMessageSize {
+		return fmt.Errorf("%w: message is larger than %d bytes", ErrInvalidParameter, q.MaxMessageSize)
+	}
+	if in.DelaySeconds != nil && (*in.DelaySeconds < 0 || *in.DelaySeconds > 900) {
+		return fmt.Errorf("%w: DelaySeconds must be between 0 and 900", ErrInvalidParameter)
+	}
+
+	if !q.FIFO {
+		if in.GroupID != "" || in.DedupID != "" {
+			return fmt.Errorf("%w: MessageGroupId and MessageDeduplicationId are only for FIFO queues", ErrInvalidParameter)
+		}
+		return nil
+	}
+
+	// FIFO queues only support a queue-wide delay
+	if in.DelaySeconds != nil {
+		return fmt.Errorf("%w: FIFO queues do not support per-message DelaySeconds", ErrInvalidParameter)
+	}
+	if in.GroupID == "" {
+		return fmt.Errorf("%w: MessageGroupId is required for FIFO queues", ErrInvalidParameter)
+	}
+	if in.DedupID == "" {
+		if !q.ContentBasedDedup {
+			return fmt.Errorf("%w: MessageDeduplicationId is required unless ContentBasedDeduplication is enabled", ErrInvalidParameter)
+		}
+		sum := sha256.Sum256([]byte(in.Body))
+		in.DedupID = hex.EncodeToString(sum[:])
+	}
+	return nil
+}
+
+func (q *queueState) stats(now time.Time) Stats {
+	var stats Stats
+	for _, m := range q.messages {
+		switch {
+		case !m.VisibleAt.After(now):
+			stats.Visible++
+		case m.ReceiptHandle != "" && m.ReceiveCount > 0:
+			stats.InFlight++
+		default:
+			stats.Delayed++
+		}
+		if age := now.Sub(m.Sent).Seconds(); age > stats.OldestAgeSeconds {
+			stats.OldestAgeSeconds = age
+		}
+	}
+	return stats
+}
+
+func (q *queueState) status(now time.Time) *Status {
+	return &Status{Queue: q.Queue, Stats: q.stats(now)}
+}
+
+// Wake long-polling receivers
+func (q *queueState) notify() {
+	close(q.changed)
+	q.changed = make(chan struct{})
+}
+
+func newReceiptHandle() string {
+	buf := make([]byte, 32)
+	rand.Read(buf)
+	return base64.RawURLEncoding.EncodeToString(buf)
+}
@@ -0,0 +1,239 @@
+package queues
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func newTestService(t *testing.T, queues ...Queue) *Service {
+	t.Helper()
+	s, err := NewService(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, q := range queues {
+		if _, err := s.CreateQueue(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func seconds(n int) *int {
+	return &n
+}
+
+// Receive without waiting, returning the bodies
+func receive(t *testing.T, s *Service, name string, in ReceiveInput) []Message {
+	t.Helper()
+	in.WaitSeconds = seconds(0)
+	messages, err := s.Receive(context.Background(), name, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return messages
+}
+
+func bodies(messages []Message) []string {
+	var out []string
+	for _, m := range messages {
+		out = append(out, m.Body)
+	}
+	return out
+}
+
+func send(t *testing.T, s *Service, name string, in SendInput) *SendResult {
+	t.Helper()
+	result, err := s.Send(name, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestVisibility(t *testing.T) {
+	s := newTestService(t, Queue{Name: "jobs"})
+	send(t, s, "jobs", SendInput{Body: "one"})
+
+	first := receive(t, s, "jobs", ReceiveInput{})
+	if len(first) != 1 || first[0].ReceiveCount != 1 || first[0].ReceiptHandle == "" {
+		t.Fatalf("first receive = %+v", first)
+	}
+	if again := receive(t, s, "jobs", ReceiveInput{}); len(again) != 0 {
+		t.Fatalf("message in flight was received again: %v", bodies(again))
+	}
+	if status, _ := s.GetQueue("jobs"); status.InFlight != 1 || status.Visible != 0 {
+		t.Fatalf("stats = %+v, want 1 in flight", status.Stats)
+	}
+
+	// making it visible again hands it out with a new handle
+	if err := s.ChangeVisibility("jobs", first[0].ReceiptHandle, 0); err != nil {
+		t.Fatal(err)
+	}
+	second := receive(t, s, "jobs", ReceiveInput{})
+	if len(second) != 1 || second[0].ReceiveCount != 2 || second[0].ReceiptHandle == first[0].ReceiptHandle {
+		t.Fatalf("second receive = %+v", second)
+	}
+
+	// a stale handle is accepted but deletes nothing
+	if err := s.DeleteMessage("jobs", first[0].ReceiptHandle); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.ChangeVisibility("jobs", second[0].ReceiptHandle, 0); err != nil {
+		t.Fatalf("message was deleted with a stale handle: %v", err)
+	}
+	third := receive(t, s, "jobs", ReceiveInput{VisibilityTimeout: seconds(60)})
+	if err := s.DeleteMessage("jobs", third[0].ReceiptHandle); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := s.GetQueue("jobs"); status.InFlight+status.Visible+status.Delayed != 0 {
+		t.Fatalf("stats after delete = %+v", status.Stats)
+	}
+	if err := s.ChangeVisibility("jobs", third[0].ReceiptHandle, 0); !errors.Is(err, ErrInvalidReceiptHandle) {
+		t.Fatalf("ChangeVisibility after delete: error %v, want %v", err, ErrInvalidReceiptHandle)
+	}
+}
+
+func TestDelay(t *testing.T) {
+	s := newTestService(t, Queue{Name: "jobs"})
+	send(t, s, "jobs", SendInput{Body: "later", DelaySeconds: seconds(60)})
+	send(t, s, "jobs", SendInput{Body: "now"})
+
+	if got := bodies(receive(t, s, "jobs", ReceiveInput{MaxMessages: 10})); len(got) != 1 || got[0] != "now" {
+		t.Fatalf("received %v, want [now]", got)
+	}
+	if status, _ := s.GetQueue("jobs"); status.Delayed != 1 {
+		t.Fatalf("stats = %+v, want 1 delayed", status.Stats)
+	}
+}
+
+func TestFIFOOrder(t *testing.T) {
+	s := newTestService(t, Queue{Name: "orders.fifo"})
+	var sequences []string
+	for _, m := range []struct{ group, body string }{{"a", "a1"}, {"a", "a2"}, {"b", "b1"}, {"a", "a3"}} {
+		result := send(t, s, "orders.fifo", SendInput{Body: m.body, GroupID: m.group, DedupID: m.body})
+		sequences = append(sequences, result.Sequence)
+	}
+	for i := 1; i < len(sequences); i++ {
+		if sequences[i] <= sequences[i-1] {
+			t.Fatalf("sequence numbers not increasing: %v", sequences)
+		}
+	}
+
+	first := receive(t, s, "orders.fifo", ReceiveInput{})
+	if got := bodies(first); len(got) != 1 || got[0] != "a1" {
+		t.Fatalf("first receive = %v, want [a1]", got)
+	}
+	// a1 in flight holds back the rest of group a
+	if got := bodies(receive(t, s, "orders.fifo", ReceiveInput{MaxMessages: 10})); len(got) != 1 || got[0] != "b1" {
+		t.Fatalf("second receive = %v, want [b1]", got)
+	}
+	if err := s.DeleteMessage("orders.fifo", first[0].ReceiptHandle); err != nil {
+		t.Fatal(err)
+	}
+	if got := bodies(receive(t, s, "orders.fifo", ReceiveInput{MaxMessages: 10})); len(got) != 2 || got[0] != "a2" || got[1] != "a3" {
+		t.Fatalf("after delete = %v, want [a2 a3]", got)
+	}
+}
+
+func TestFIFODedup(t *testing.T) {
+	s := newTestService(t,
+		Queue{Name: "explicit.fifo"},
+		Queue{Name: "content.fifo", ContentBasedDedup: true},
+	)
+
+	first := send(t, s, "explicit.fifo", SendInput{Body: "x", GroupID: "g", DedupID: "d1"})
+	dup := send(t, s, "explicit.fifo", SendInput{Body: "y", GroupID: "g", DedupID: "d1"})
+	if dup.MessageID != first.MessageID || dup.Sequence != first.Sequence {
+		t.Errorf("duplicate got %+v, want %+v", dup, first)
+	}
+	send(t, s, "explicit.fifo", SendInput{Body: "x", GroupID: "g", DedupID: "d2"})
+	if got := bodies(receive(t, s, "explicit.fifo", ReceiveInput{MaxMessages: 10})); len(got) != 2 {
+		t.Errorf("explicit.fifo holds %v, want [x x]", got)
+	}
+
+	first = send(t, s, "content.fifo", SendInput{Body: "x", GroupID: "g"})
+	if dup := send(t, s, "content.fifo", SendInput{Body: "x", GroupID: "other"}); dup.MessageID != first.MessageID {
+		t.Errorf("same body was not deduplicated")
+	}
+	send(t, s, "content.fifo", SendInput{Body: "y", GroupID: "g"})
+	if got := bodies(receive(t, s, "content.fifo", ReceiveInput{MaxMessages: 10})); len(got) != 2 {
+		t.Errorf("content.fifo holds %v, want [x y]", got)
+	}
+}
+
+func TestSendRejects(t *testing.T) {
+	s := newTestService(t, Queue{Name: "jobs"}, Queue{Name: "orders.fifo"})
+	tests := []struct {
+		queue string
+		in    SendInput
+		err   error
+	}{
+		{"missing", SendInput{Body: "x"}, ErrQueueNotFound},
+		{"jobs", SendInput{}, ErrInvalidParameter},
+		{"jobs", SendInput{Body: "x", DelaySeconds: seconds(901)}, ErrInvalidParameter},
+		{"jobs", SendInput{Body: "x", GroupID: "g"}, ErrInvalidParameter},
+		{"orders.fifo", SendInput{Body: "x", DedupID: "d"}, ErrInvalidParameter},
+		{"orders.fifo", SendInput{Body: "x", GroupID: "g"}, ErrInvalidParameter},
+		{"orders.fifo", SendInput{Body: "x", GroupID: "g", DedupID: "d", DelaySeconds: seconds(1)}, ErrInvalidParameter},
+	}
+	for _, tt := range tests {
+		if _, err := s.Send(tt.queue, tt.in); !errors.Is(err, tt.err) {
+			t.Errorf("Send(%s, %+v): error %v, want %v", tt.queue, tt.in, err, tt.err)
+		}
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	s := newTestService(t,
+		Queue{Name: "jobs-dlq"},
+		Queue{Name: "jobs", DeadLetterQueue: "jobs-dlq", MaxReceiveCount: 2},
+	)
+	send(t, s, "jobs", SendInput{Body: "poison"})
+
+	for i := 0; i < 2; i++ {
+		if got := receive(t, s, "jobs", ReceiveInput{VisibilityTimeout: seconds(0)}); len(got) != 1 {
+			t.Fatalf("receive %d got %d messages", i+1, len(got))
+		}
+	}
+	if got := receive(t, s, "jobs", ReceiveInput{}); len(got) != 0 {
+		t.Fatalf("message past its receive count was received again")
+	}
+	moved := receive(t, s, "jobs-dlq", ReceiveInput{VisibilityTimeout: seconds(0)})
+	if len(moved) != 1 || moved[0].SourceQueue != "jobs" {
+		t.Fatalf("dead-letter queue holds %+v", moved)
+	}
+
+	if n, err := s.Redrive("jobs-dlq"); err != nil || n != 1 {
+		t.Fatalf("Redrive = %d, %v, want 1", n, err)
+	}
+	back := receive(t, s, "jobs", ReceiveInput{})
+	if len(back) != 1 || back[0].ReceiveCount != 1 {
+		t.Fatalf("redriven message = %+v", back)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.CreateQueue(Queue{Name: "orders.fifo"}); err != nil {
+		t.Fatal(err)
+	}
+	first := send(t, s, "orders.fifo", SendInput{Body: "x", GroupID: "g", DedupID: "1"})
+
+	s, err = NewService(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second := send(t, s, "orders.fifo", SendInput{Body: "y", GroupID: "g", DedupID: "2"})
+	if second.Sequence <= first.Sequence {
+		t.Errorf("sequence went from %s to %s after reopening", first.Sequence, second.Sequence)
+	}
+	if got := bodies(receive(t, s, "orders.fifo", ReceiveInput{MaxMessages: 10})); len(got) != 2 || got[0] != "x" {
+		t.Errorf("after reopening the queue holds %v, want [x y]", got)
+	}
+}
@@ -0,0 +1,162 @@
+package queues
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AWS Signature Version 4, which the SDKs sign every request with. The
+// server's API token is the secret access key; any access key ID will do.
+
+const (
+	sigV4Algorithm  = "AWS4-HMAC-SHA256"
+	sigV4TimeFormat = "20060102T150405Z"
+	// how far a request's X-Amz-Date may be from the server's clock
+	maxSignatureSkew = 15 * time.Minute
+)
+
+func authError(status int, code, format string, args ...interface{}) *sqsError {
+	return &sqsError{status, code, code, fmt.Sprintf(format, args...)}
+}
+
+// Check r's signature against secret. The body is read to hash it and put
+// back for the handler.
+func verifySignature(r *http.Request, secret string, now time.Time) *sqsError {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		return authError(http.StatusForbidden, "MissingAuthenticationToken",
+			"Requests must be signed with AWS Signature Version 4, using the server's API token as the secret access key")
+	}
+	fields, ok := strings.CutPrefix(auth, sigV4Algorithm+" ")
+	if !ok {
+		return authError(http.StatusBadRequest, "IncompleteSignature", "Only %s signatures are supported", sigV4Algorithm)
+	}
+
+	params := make(map[string]string)
+	for _, field := range strings.Split(fields, ",") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(field), "="); ok {
+			params[key] = value
+		}
+	}
+	// Credential=<access key>/<date>/<region>/<service>/aws4_request
+	credential := strings.Split(params["Credential"], "/")
+	signedHeaders := params["SignedHeaders"]
+	if len(credential) != 5 || credential[4] != "aws4_request" || signedHeaders == "" || params["Signature"] == "" {
+		return authError(http.StatusBadRequest, "IncompleteSignature", "Authorization header is missing Credential, SignedHeaders or Signature")
+	}
+	scope := strings.Join(credential[1:], "/")
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	signedAt, err := time.Parse(sigV4TimeFormat, amzDate)
+	if err != nil || !strings.HasPrefix(amzDate, credential[1]) {
+		return authError(http.StatusBadRequest, "IncompleteSignature", "X-Amz-Date is missing or does not match the credential scope")
+	}
+	if skew := now.Sub(signedAt); skew > maxSignatureSkew || skew < -maxSignatureSkew {
+		return authError(http.StatusForbidden, "RequestExpired", "Signature expired or not yet valid: signed at %s", amzDate)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return authError(http.StatusBadRequest, "IncompleteSignature", "failed to read request body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	sum := sha256.Sum256(body)
+	payloadHash := hex.EncodeToString(sum[:])
+	// the body must be the one that was signed, so no UNSIGNED-PAYLOAD
+	if sent := r.Header.Get("X-Amz-Content-Sha256"); sent != "" && sent != payloadHash {
+		return authError(http.StatusBadRequest, "XAmzContentSHA256Mismatch", "X-Amz-Content-Sha256 does not match the request body")
+	}
+
+	canonical := strings.Join([]string{
+		r.Method,
+		canonicalURI(r.URL),
+		canonicalQuery(r.URL.Query()),
+		canonicalHeaders(r, strings.Split(signedHeaders, ";")),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonical))
+	stringToSign := strings.Join([]string{sigV4Algorithm, amzDate, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + secret)
+	for _, part := range credential[1:] {
+		key = hmacSHA256(key, part)
+	}
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(params["Signature"])) {
+		return authError(http.StatusForbidden, "SignatureDoesNotMatch",
+			"The request signature does not match; sign with the server's API token as the secret access key")
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// The path as sent, encoded once more as the SDKs do for services other than S3
+func canonicalURI(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	return strings.ReplaceAll(path, "%", "%25")
+}
+
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// Each signed header as name:value on its own line, ending with a newline
+func canonicalHeaders(r *http.Request, names []string) string {
+	var b strings.Builder
+	for _, name := range names {
+		var values []string
+		switch name {
+		case "host":
+			values = []string{r.Host}
+		case "content-length":
+			values = []string{strconv.FormatInt(r.ContentLength, 10)}
+		default:
+			values = append([]string(nil), r.Header.Values(name)...)
+		}
+		for i, value := range values {
+			values[i] = strings.Join(strings.Fields(value), " ")
+		}
+		b.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+	return b.String()
+}
+
+// Percent-encode everything but the unreserved characters of RFC 3986
+func uriEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-' || c == '_' || c == '.' || c == '~' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,93 @@
+package queues
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The get-vanilla case of the AWS Signature Version 4 test suite
+const (
+	vanillaSecret    = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+	vanillaDate      = "20150830T123600Z"
+	vanillaSignature = "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+)
+
+func vanillaRequest(signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "http://example.amazonaws.com/", nil)
+	r.Header.Set("X-Amz-Date", vanillaDate)
+	r.Header.Set("Authorization", sigV4Algorithm+" Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature="+signature)
+	return r
+}
+
+func TestVerifySignature(t *testing.T) {
+	signedAt, _ := time.Parse(sigV4TimeFormat, vanillaDate)
+	if err := verifySignature(vanillaRequest(vanillaSignature), vanillaSecret, signedAt.Add(time.Minute)); err != nil {
+		t.Fatalf("valid signature rejected: %+v", err)
+	}
+
+	badHash := vanillaRequest(vanillaSignature)
+	badHash.Header.Set("X-Amz-Content-Sha256", strings.Repeat("0", 64))
+	noScope := vanillaRequest(vanillaSignature)
+	noScope.Header.Set("Authorization", sigV4Algorithm+" Signature="+vanillaSignature)
+	unsigned := vanillaRequest(vanillaSignature)
+	unsigned.Header.Del("Authorization")
+
+	tests := []struct {
+		name   string
+		r      *http.Request
+		secret string
+		at     time.Time
+		code   string
+	}{
+		{"wrong secret", vanillaRequest(vanillaSignature), "other", signedAt, "SignatureDoesNotMatch"},
+		{"tampered", vanillaRequest(strings.Repeat("0", 64)), vanillaSecret, signedAt, "SignatureDoesNotMatch"},
+		{"expired", vanillaRequest(vanillaSignature), vanillaSecret, signedAt.Add(time.Hour), "RequestExpired"},
+		{"payload hash", badHash, vanillaSecret, signedAt, "XAmzContentSHA256Mismatch"},
+		{"incomplete", noScope, vanillaSecret, signedAt, "IncompleteSignature"},
+		{"unsigned", unsigned, vanillaSecret, signedAt, "MissingAuthenticationToken"},
+	}
+	for _, tt := range tests {
+		if err := verifySignature(tt.r, tt.secret, tt.at); err == nil || err.code != tt.code {
+			t.Errorf("%s: %+v, want %s", tt.name, err, tt.code)
+		}
+	}
+}
+
+func TestVerifySignatureKeepsBody(t *testing.T) {
+	body := "Action=ListQueues"
+	r := httptest.NewRequest(http.MethodPost, "http://localhost/sqs", strings.NewReader(body))
+	sum := sha256.Sum256([]byte(body))
+	r.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	r.Header.Set("X-Amz-Date", vanillaDate)
+	r.Header.Set("Authorization", sigV4Algorithm+" Credential=AKID/20150830/us-east-1/sqs/aws4_request, SignedHeaders=host;x-amz-date, Signature=00")
+	signedAt, _ := time.Parse(sigV4TimeFormat, vanillaDate)
+
+	if err := verifySignature(r, vanillaSecret, signedAt); err == nil || err.code != "SignatureDoesNotMatch" {
+		t.Fatalf("err = %+v", err)
+	}
+	if read, _ := io.ReadAll(r.Body); string(read) != body {
+		t.Errorf("body after verifying = %q", read)
+	}
+}
+
+func TestSQSRequiresSignature(t *testing.T) {
+	s := newTestService(t)
+	server := httptest.NewServer(s.SQSHandler("/sqs", "token"))
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/sqs", "application/x-www-form-urlencoded", strings.NewReader("Action=ListQueues"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(data), "MissingAuthenticationToken") {
+		t.Errorf("unsigned request: %d %s", resp.StatusCode, data)
+	}
+}
@@ -0,0 +1,778 @@
+package queues
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Subset of the SQS API spoken by the AWS SDKs: the JSON protocol
+// (X-Amz-Target: AmazonSQS.<Action>) and the older query protocol
+// (form-encoded Action=<Action>, XML responses).
+
+const sqsNamespace = "http://queue.amazonaws.com/doc/2012-11-05/"
+
+type sqsHandler struct {
+	service *Service
+	prefix  string // path the handler is mounted on, e.g. /sqs
+	secret  string // secret access key requests must be signed with, if set
+}
+
+// HTTP handler for the SQS protocol mounted at prefix. Queue URLs look like
+// http://<host><prefix>/000000000000/<name>. With a secret, every request
+// must carry an AWS Signature Version 4 made with it as the secret key.
+func (s *Service) SQSHandler(prefix, secret string) http.Handler {
+	return &sqsHandler{service: s, prefix: strings.TrimRight(prefix, "/"), secret: secret}
+}
+
+// Request parameters of every supported action, from either protocol
+type sqsRequest struct {
+	QueueName                   string
+	QueueUrl                    string
+	QueueNamePrefix             string
+	Attributes                  map[string]string
+	AttributeNames              []string
+	MessageSystemAttributeNames []string
+	MessageAttributeNames       []string
+	MessageBody                 string
+	MessageAttributes           map[string]MessageAttribute
+	DelaySeconds                *int
+	MaxNumberOfMessages         *int
+	VisibilityTimeout           *int
+	WaitTimeSeconds             *int
+	ReceiptHandle               string
+	MessageGroupId              string
+	MessageDeduplicationId      string
+	Entries                     []sqsEntry
+}
+
+type sqsEntry struct {
+	Id                     string
+	MessageBody            string
+	DelaySeconds           *int
+	MessageAttributes      map[string]MessageAttribute
+	MessageGroupId         string
+	MessageDeduplicationId string
+	ReceiptHandle          string
+	VisibilityTimeout      *int
+}
+
+type sqsError struct {
+	status    int
+	code      string // JSON protocol error type
+	queryCode string // query protocol error code
+	message   string
+}
+
+func (e *sqsError) Error() string {
+	return e.message
+}
+
+func invalidParameter(format string, args ...interface{}) *sqsError {
+	msg := fmt.Sprintf(format, args...)
+	return &sqsError{http.StatusBadRequest, "InvalidParameterValue", "InvalidParameterValue", msg}
+}
+
+// Map service errors onto SQS error codes
+func toSQSError(err error) *sqsError {
+	var sqsErr *sqsError
+	switch {
+	case errors.As(err, &sqsErr):
+		return sqsErr
+	case errors.Is(err, ErrQueueNotFound):
+		return &sqsError{http.StatusBadRequest, "QueueDoesNotExist", "AWS.SimpleQueueService.NonExistentQueue", "The specified queue does not exist."}
+	case errors.Is(err, ErrQueueExists):
+		return &sqsError{http.StatusBadRequest, "QueueNameExists", "QueueAlreadyExists", err.Error()}
+	case errors.Is(err, ErrInvalidReceiptHandle):
+		return &sqsError{http.StatusBadRequest, "ReceiptHandleIsInvalid", "ReceiptHandleIsInvalid", err.Error()}
+	case errors.Is(err, ErrInvalidParameter):
+		return invalidParameter("%s", strings.TrimPrefix(err.Error(), ErrInvalidParameter.Error()+": "))
+	}
+	return &sqsError{http.StatusInternalServerError, "InternalError", "InternalError", err.Error()}
+}
+
+func (h *sqsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	requestID := uuid.New().String()
+
+	if h.secret != "" {
+		if err := verifySignature(r, h.secret, time.Now()); err != nil {
+			if r.Header.Get("X-Amz-Target") != "" {
+				h.writeJSON(w, requestID, nil, err)
+			} else {
+				h.writeXML(w, requestID, "", nil, err)
+			}
+			return
+		}
+	}
+
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		action := strings.TrimPrefix(target, "AmazonSQS.")
+		var req sqsRequest
+		body, err := io.ReadAll(r.Body)
+		if err == nil && len(body) > 0 {
+			err = json.Unmarshal(body, &req)
+		}
+		if err != nil {
+			h.writeJSON(w, requestID, nil, &sqsError{http.StatusBadRequest, "SerializationException", "SerializationException", "invalid request body"})
+			return
+		}
+		result, err := h.dispatch(r, action, &req)
+		h.writeJSON(w, requestID, result, err)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.writeXML(w, requestID, "", nil, invalidParameter("invalid form body"))
+		return
+	}
+	action := r.Form.Get("Action")
+	req := parseQueryRequest(r.Form)
+	if req.QueueUrl == "" {
+		// the query protocol may post to the queue URL itself
+		if parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, h.prefix), "/"), "/"); len(parts) == 2 {
+			req.QueueUrl = parts[1]
+		}
+	}
+	result, err := h.dispatch(r, action, req)
+	h.writeXML(w, requestID, action, result, err)
+}
+
+func (h *sqsHandler) dispatch(r *http.Request, action string, req *sqsRequest) (interface{}, error) {
+	s := h.service
+
+	queueName := func() (string, error) {
+		if req.QueueUrl == "" {
+			return "", &sqsError{http.StatusBadRequest, "MissingParameter", "MissingParameter", "QueueUrl is required"}
+		}
+		return nameFromARN(req.QueueUrl), nil
+	}
+
+	switch action {
+	case "CreateQueue":
+		if req.QueueName == "" {
+			return nil, &sqsError{http.StatusBadRequest, "MissingParameter", "MissingParameter", "QueueName is required"}
+		}
+		q, err := QueueFromAttributes(req.QueueName, req.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := s.CreateQueue(q); err != nil {
+			return nil, err
+		}
+		return &queueURLResult{QueueUrl: h.queueURL(r, req.QueueName)}, nil
+
+	case "GetQueueUrl":
+		if _, err := s.GetQueue(req.QueueName); err != nil {
+			return nil, err
+		}
+		return &queueURLResult{QueueUrl: h.queueURL(r, req.QueueName)}, nil
+
+	case "ListQueues":
+		result := &listQueuesResult{QueueUrls: []string{}}
+		for _, q := range s.ListQueues(req.QueueNamePrefix) {
+			result.QueueUrls = append(result.QueueUrls, h.queueURL(r, q.Name))
+		}
+		return result, nil
+
+	case "DeleteQueue":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.DeleteQueue(name)
+
+	case "PurgeQueue":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.Purge(name)
+
+	case "GetQueueAttributes":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		status, err := s.GetQueue(name)
+		if err != nil {
+			return nil, err
+		}
+		names := req.AttributeNames
+		if len(names) == 0 {
+			names = []string{"All"}
+		}
+		return &queueAttributesResult{Attributes: attributeMap(status.Attributes(names))}, nil
+
+	case "SetQueueAttributes":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.SetAttributes(name, req.Attributes)
+
+	case "SendMessage":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		return h.send(name, sqsEntry{
+			MessageBody:            req.MessageBody,
+			DelaySeconds:           req.DelaySeconds,
+			MessageAttributes:      req.MessageAttributes,
+			MessageGroupId:         req.MessageGroupId,
+			MessageDeduplicationId: req.MessageDeduplicationId,
+		})
+
+	case "SendMessageBatch":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBatch(req.Entries); err != nil {
+			return nil, err
+		}
+		result := &sendBatchResult{Successful: []sendResult{}, Failed: []batchError{}}
+		for _, entry := range req.Entries {
+			sent, err := h.send(name, entry)
+			if err != nil {
+				if errors.Is(err, ErrQueueNotFound) {
+					return nil, err
+				}
+				result.Failed = append(result.Failed, batchFailure(entry.Id, err))
+				continue
+			}
+			sent.Id = entry.Id
+			result.Successful = append(result.Successful, *sent)
+		}
+		return result, nil
+
+	case "ReceiveMessage":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		in := ReceiveInput{VisibilityTimeout: req.VisibilityTimeout, WaitSeconds: req.WaitTimeSeconds}
+		if req.MaxNumberOfMessages != nil {
+			in.MaxMessages = *req.MaxNumberOfMessages
+		}
+		messages, err := s.Receive(r.Context(), name, in)
+		if err != nil {
+			return nil, err
+		}
+		systemNames := append(req.AttributeNames, req.MessageSystemAttributeNames...)
+		result := &receiveResult{Messages: []receivedMessage{}}
+		for _, m := range messages {
+			result.Messages = append(result.Messages, toReceived(m, systemNames, req.MessageAttributeNames))
+		}
+		return result, nil
+
+	case "DeleteMessage":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.DeleteMessage(name, req.ReceiptHandle)
+
+	case "DeleteMessageBatch":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBatch(req.Entries); err != nil {
+			return nil, err
+		}
+		result := &deleteBatchResult{Successful: []batchSuccess{}, Failed: []batchError{}}
+		for _, entry := range req.Entries {
+			if err := s.DeleteMessage(name, entry.ReceiptHandle); err != nil {
+				if errors.Is(err, ErrQueueNotFound) {
+					return nil, err
+				}
+				result.Failed = append(result.Failed, batchFailure(entry.Id, err))
+				continue
+			}
+			result.Successful = append(result.Successful, batchSuccess{Id: entry.Id})
+		}
+		return result, nil
+
+	case "ChangeMessageVisibility":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		if req.VisibilityTimeout == nil {
+			return nil, &sqsError{http.StatusBadRequest, "MissingParameter", "MissingParameter", "VisibilityTimeout is required"}
+		}
+		return nil, s.ChangeVisibility(name, req.ReceiptHandle, *req.VisibilityTimeout)
+
+	case "ChangeMessageVisibilityBatch":
+		name, err := queueName()
+		if err != nil {
+			return nil, err
+		}
+		if err := checkBatch(req.Entries); err != nil {
+			return nil, err
+		}
+		result := &changeVisibilityBatchResult{Successful: []batchSuccess{}, Failed: []batchError{}}
+		for _, entry := range req.Entries {
+			timeout := 0
+			if entry.VisibilityTimeout != nil {
+				timeout = *entry.VisibilityTimeout
+			}
+			if err := s.ChangeVisibility(name, entry.ReceiptHandle, timeout); err != nil {
+				if errors.Is(err, ErrQueueNotFound) {
+					return nil, err
+				}
+				result.Failed = append(result.Failed, batchFailure(entry.Id, err))
+				continue
+			}
+			result.Successful = append(result.Successful, batchSuccess{Id: entry.Id})
+		}
+		return result, nil
+	}
+
+	return nil, &sqsError{http.StatusBadRequest, "UnsupportedOperation", "InvalidAction", fmt.Sprintf("action %q is not supported", action)}
+}
+
+func (h *sqsHandler) send(queue string, entry sqsEntry) (*sendResult, error) {
+	sent, err := h.service.Send(queue, SendInput{
+		Body:         entry.MessageBody,
+		DelaySeconds: entry.DelaySeconds,
+		Attributes:   entry.MessageAttributes,
+		GroupID:      entry.MessageGroupId,
+		DedupID:      entry.MessageDeduplicationId,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &sendResult{
+		MessageId:              sent.MessageID,
+		MD5OfMessageBody:       md5Hex([]byte(entry.MessageBody)),
+		MD5OfMessageAttributes: attributesMD5(entry.MessageAttributes),
+		SequenceNumber:         sent.Sequence,
+	}, nil
+}
+
+func (h *sqsHandler) queueURL(r *http.Request, name string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + h.prefix + "/" + AccountID + "/" + name
+}
+
+func checkBatch(entries []sqsEntry) error {
+	if len(entries) == 0 {
+		return &sqsError{http.StatusBadRequest, "EmptyBatchRequest", "AWS.SimpleQueueService.EmptyBatchRequest", "the batch request does not contain any entries"}
+	}
+	if len(entries) > maxReceiveBatch {
+		return &sqsError{http.StatusBadRequest, "TooManyEntriesInBatchRequest", "AWS.SimpleQueueService.TooManyEntriesInBatchRequest", "a batch may contain at most 10 entries"}
+	}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if seen[entry.Id] {
+			return &sqsError{http.StatusBadRequest, "BatchEntryIdsNotDistinct", "AWS.SimpleQueueService.BatchEntryIdsNotDistinct", "batch entry ids must be distinct"}
+		}
+		seen[entry.Id] = true
+	}
+	return nil
+}
+
+func batchFailure(id string, err error) batchError {
+	sqsErr := toSQSError(err)
+	return batchError{Id: id, Code: sqsErr.queryCode, Message: sqsErr.message, SenderFault: sqsErr.status < 500}
+}
+
+// Message as ReceiveMessage returns it, with the requested attributes
+func toReceived(m Message, systemNames, attributeNames []string) receivedMessage {
+	out := receivedMessage{
+		MessageId:     m.ID,
+		ReceiptHandle: m.ReceiptHandle,
+		MD5OfBody:     md5Hex([]byte(m.Body)),
+		Body:          m.Body,
+	}
+
+	system := map[string]string{
+		"SenderId":                         AccountID,
+		"SentTimestamp":                    strconv.FormatInt(m.Sent.UnixMilli(), 10),
+		"ApproximateReceiveCount":          strconv.Itoa(m.ReceiveCount),
+		"ApproximateFirstReceiveTimestamp": strconv.FormatInt(m.FirstReceived.UnixMilli(), 10),
+	}
+	if m.GroupID != "" {
+		system["MessageGroupId"] = m.GroupID
+		system["MessageDeduplicationId"] = m.DedupID
+		system["SequenceNumber"] = m.Sequence
+	}
+	attrs := make(map[string]string)
+	for _, name := range systemNames {
+		if name == "All" {
+			attrs = system
+			break
+		}
+		if value, ok := system[name]; ok {
+			attrs[name] = value
+		}
+	}
+	if len(attrs) > 0 {
+		out.Attributes = attributeMap(attrs)
+	}
+
+	selected := make(map[string]MessageAttribute)
+	for name, attr := range m.Attributes {
+		for _, pattern := range attributeNames {
+			if pattern == "All" || pattern == ".*" || pattern == name ||
+				(strings.HasSuffix(pattern, ".*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))) {
+				selected[name] = attr
+				break
+			}
+		}
+	}
+	if len(selected) > 0 {
+		out.MessageAttributes = messageAttributeMap(selected)
+		out.MD5OfMessageAttributes = attributesMD5(selected)
+	}
+	return out
+}
+
+func md5Hex(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Digest of message attributes the SDKs verify: for each attribute in name
+// order, length-prefixed name, data type and value with a transport byte
+func attributesMD5(attrs map[string]MessageAttribute) string {
+	if len(attrs) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	h := md5.New()
+	writeField := func(b []byte) {
+		var length [4]byte
+		binary.BigEndian.PutUint32(length[:], uint32(len(b)))
+		h.Write(length[:])
+		h.Write(b)
+	}
+	for _, name := range names {
+		attr := attrs[name]
+		writeField([]byte(name))
+		writeField([]byte(attr.DataType))
+		if strings.HasPrefix(attr.DataType, "Binary") {
+			h.Write([]byte{2})
+			writeField(attr.BinaryValue)
+		} else {
+			h.Write([]byte{1})
+			writeField([]byte(attr.StringValue))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Build a request from query protocol parameters, where lists and maps are
+// flattened as Name.1.Field=value
+func parseQueryRequest(form url.Values) *sqsRequest {
+	req := &sqsRequest{
+		QueueName:              form.Get("QueueName"),
+		QueueUrl:               form.Get("QueueUrl"),
+		QueueNamePrefix:        form.Get("QueueNamePrefix"),
+		MessageBody:            form.Get("MessageBody"),
+		ReceiptHandle:          form.Get("ReceiptHandle"),
+		MessageGroupId:         form.Get("MessageGroupId"),
+		MessageDeduplicationId: form.Get("MessageDeduplicationId"),
+		DelaySeconds:           queryInt(form, "DelaySeconds"),
+		MaxNumberOfMessages:    queryInt(form, "MaxNumberOfMessages"),
+		VisibilityTimeout:      queryInt(form, "VisibilityTimeout"),
+		WaitTimeSeconds:        queryInt(form, "WaitTimeSeconds"),
+		MessageAttributes:      queryMessageAttributes(form, "MessageAttribute"),
+	}
+
+	for _, attr := range queryList(form, "Attribute") {
+		if req.Attributes == nil {
+			req.Attributes = make(map[string]string)
+		}
+		req.Attributes[attr.Get("Name")] = attr.Get("Value")
+	}
+	for _, name := range queryList(form, "AttributeName") {
+		req.AttributeNames = append(req.AttributeNames, name.Get(""))
+	}
+	for _, name := range queryList(form, "MessageSystemAttributeName") {
+		req.MessageSystemAttributeNames = append(req.MessageSystemAttributeNames, name.Get(""))
+	}
+	for _, name := range queryList(form, "MessageAttributeName") {
+		req.MessageAttributeNames = append(req.MessageAttributeNames, name.Get(""))
+	}
+
+	for _, prefix := range []string{"SendMessageBatchRequestEntry", "DeleteMessageBatchRequestEntry", "ChangeMessageVisibilityBatchRequestEntry"} {
+		for _, entry := range queryList(form, prefix) {
+			req.Entries = append(req.Entries, sqsEntry{
+				Id:                     entry.Get("Id"),
+				MessageBody:            entry.Get("MessageBody"),
+				DelaySeconds:           queryInt(entry, "DelaySeconds"),
+				MessageAttributes:      queryMessageAttributes(entry, "MessageAttribute"),
+				MessageGroupId:         entry.Get("MessageGroupId"),
+				MessageDeduplicationId: entry.Get("MessageDeduplicationId"),
+				ReceiptHandle:          entry.Get("ReceiptHandle"),
+				VisibilityTimeout:      queryInt(entry, "VisibilityTimeout"),
+			})
+		}
+	}
+	return req
+}
+
+// Members of a flattened list, Prefix.N[.Rest], in index order. Each
+// member's values are keyed by Rest ("" for scalar members).
+func queryList(form url.Values, prefix string) []url.Values {
+	members := make(map[int]url.Values)
+	for key, values := range form {
+		if !strings.HasPrefix(key, prefix+".") {
+			continue
+		}
+		rest := strings.TrimPrefix(key, prefix+".")
+		indexPart, field, _ := strings.Cut(rest, ".")
+		index, err := strconv.Atoi(indexPart)
+		if err != nil {
+			continue
+		}
+		if members[index] == nil {
+			members[index] = url.Values{}
+		}
+		members[index][field] = values
+	}
+
+	indexes := make([]int, 0, len(members))
+	for index := range members {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	list := make([]url.Values, 0, len(indexes))
+	for _, index := range indexes {
+		list = append(list, members[index])
+	}
+	return list
+}
+
+func queryMessageAttributes(form url.Values, prefix string) map[string]MessageAttribute {
+	var attrs map[string]MessageAttribute
+	for _, member := range queryList(form, prefix) {
+		if attrs == nil {
+			attrs = make(map[string]MessageAttribute)
+		}
+		attr := MessageAttribute{
+			DataType:    member.Get("Value.DataType"),
+			StringValue: member.Get("Value.StringValue"),
+		}
+		if binary := member.Get("Value.BinaryValue"); binary != "" {
+			attr.BinaryValue, _ = base64.StdEncoding.DecodeString(binary)
+		}
+		attrs[member.Get("Name")] = attr
+	}
+	return attrs
+}
+
+func queryInt(form url.Values, key string) *int {
+	value := form.Get(key)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+func (h *sqsHandler) writeJSON(w http.ResponseWriter, requestID string, result interface{}, err error) {
+	w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+	w.Header().Set("x-amzn-RequestId", requestID)
+
+	if err != nil {
+		sqsErr := toSQSError(err)
+		fault := "Sender"
+		if sqsErr.status >= 500 {
+			fault = "Receiver"
+		}
+		// lets SDKs map JSON errors to the codes of the query protocol
+		w.Header().Set("x-amzn-query-error", sqsErr.queryCode+";"+fault)
+		w.WriteHeader(sqsErr.status)
+		json.NewEncoder(w).Encode(map[string]string{
+			"__type":  "com.amazonaws.sqs#" + sqsErr.code,
+			"message": sqsErr.message,
+		})
+		return
+	}
+
+	if result == nil {
+		w.Write([]byte("{}"))
+		return
+	}
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *sqsHandler) writeXML(w http.ResponseWriter, requestID, action string, result interface{}, err error) {
+	w.Header().Set("Content-Type", "text/xml")
+
+	if err != nil {
+		sqsErr := toSQSError(err)
+		fault := "Sender"
+		if sqsErr.status >= 500 {
+			fault = "Receiver"
+		}
+		w.WriteHeader(sqsErr.status)
+		xml.NewEncoder(w).Encode(struct {
+			XMLName   xml.Name `xml:"ErrorResponse"`
+			Xmlns     string   `xml:"xmlns,attr"`
+			Type      string   `xml:"Error>Type"`
+			Code      string   `xml:"Error>Code"`
+			Message   string   `xml:"Error>Message"`
+			RequestID string   `xml:"RequestId"`
+		}{Xmlns: sqsNamespace, Type: fault, Code: sqsErr.queryCode, Message: sqsErr.message, RequestID: requestID})
+		return
+	}
+
+	// <ActionResponse><ActionResult>...</ActionResult><ResponseMetadata>...
+	encoder := xml.NewEncoder(w)
+	response := xml.StartElement{
+		Name: xml.Name{Local: action + "Response"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "xmlns"}, Value: sqsNamespace}},
+	}
+	encoder.EncodeToken(response)
+	if result != nil {
+		encoder.EncodeElement(result, xml.StartElement{Name: xml.Name{Local: action + "Result"}})
+	}
+	encoder.EncodeElement(struct {
+		RequestID string `xml:"RequestId"`
+	}{requestID}, xml.StartElement{Name: xml.Name{Local: "ResponseMetadata"}})
+	encoder.EncodeToken(response.End())
+	encoder.Flush()
+}
+
+// Response shapes. JSON uses maps and lists directly; XML repeats an element
+// per member.
+
+type queueURLResult struct {
+	QueueUrl string `json:"QueueUrl" xml:"QueueUrl"`
+}
+
+type listQueuesResult struct {
+	QueueUrls []string `json:"QueueUrls" xml:"QueueUrl"`
+}
+
+type queueAttributesResult struct {
+	Attributes attributeMap `json:"Attributes" xml:"Attribute"`
+}
+
+type sendResult struct {
+	Id                     string `json:"Id,omitempty" xml:"Id,omitempty"`
+	MessageId              string `json:"MessageId" xml:"MessageId"`
+	MD5OfMessageBody       string `json:"MD5OfMessageBody" xml:"MD5OfMessageBody"`
+	MD5OfMessageAttributes string `json:"MD5OfMessageAttributes,omitempty" xml:"MD5OfMessageAttributes,omitempty"`
+	SequenceNumber         string `json:"SequenceNumber,omitempty" xml:"SequenceNumber,omitempty"`
+}
+
+type batchSuccess struct {
+	Id string `json:"Id" xml:"Id"`
+}
+
+type batchError struct {
+	Id          string `json:"Id" xml:"Id"`
+	Code        string `json:"Code" xml:"Code"`
+	Message     string `json:"Message" xml:"Message"`
+	SenderFault bool   `json:"SenderFault" xml:"SenderFault"`
+}
+
+type sendBatchResult struct {
+	Successful []sendResult `json:"Successful" xml:"SendMessageBatchResultEntry"`
+	Failed     []batchError `json:"Failed" xml:"BatchResultErrorEntry"`
+}
+
+type deleteBatchResult struct {
+	Successful []batchSuccess `json:"Successful" xml:"DeleteMessageBatchResultEntry"`
+	Failed     []batchError   `json:"Failed" xml:"BatchResultErrorEntry"`
+}
+
+type changeVisibilityBatchResult struct {
+	Successful []batchSuccess `json:"Successful" xml:"ChangeMessageVisibilityBatchResultEntry"`
+	Failed     []batchError   `json:"Failed" xml:"BatchResultErrorEntry"`
+}
+
+type receiveResult struct {
+	Messages []receivedMessage `json:"Messages" xml:"Message"`
+}
+
+type receivedMessage struct {
+	MessageId              string              `json:"MessageId" xml:"MessageId"`
+	ReceiptHandle          string              `json:"ReceiptHandle" xml:"ReceiptHandle"`
+	MD5OfBody              string              `json:"MD5OfBody" xml:"MD5OfBody"`
+	Body                   string              `json:"Body" xml:"Body"`
+	Attributes             attributeMap        `json:"Attributes,omitempty" xml:"Attribute,omitempty"`
+	MD5OfMessageAttributes string              `json:"MD5OfMessageAttributes,omitempty" xml:"MD5OfMessageAttributes,omitempty"`
+	MessageAttributes      messageAttributeMap `json:"MessageAttributes,omitempty" xml:"MessageAttribute,omitempty"`
+}
+
+// Name/value pairs, a JSON object or repeated <Attribute><Name/><Value/> elements
+type attributeMap map[string]string
+
+func (m attributeMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		entry := struct {
+			Name  string `xml:"Name"`
+			Value string `xml:"Value"`
+		}{name, m[name]}
+		if err := e.EncodeElement(entry, start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type messageAttributeMap map[string]MessageAttribute
+
+func (m messageAttributeMap) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		attr := m[name]
+		entry := struct {
+			Name        string `xml:"Name"`
+			DataType    string `xml:"Value>DataType"`
+			StringValue string `xml:"Value>StringValue,omitempty"`
+			BinaryValue string `xml:"Value>BinaryValue,omitempty"`
+		}{Name: name, DataType: attr.DataType, StringValue: attr.StringValue}
+		if len(attr.BinaryValue) > 0 {
+			entry.BinaryValue = base64.StdEncoding.EncodeToString(attr.BinaryValue)
+		}
+		if err := e.EncodeElement(entry, start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
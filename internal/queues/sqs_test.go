@@ -0,0 +1,228 @@
+package queues
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newSQSServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	s := newTestService(t)
+	mux := http.NewServeMux()
+	mux.Handle("/sqs/", s.SQSHandler("/sqs", ""))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Call an action with the JSON protocol, decoding the response into out
+func callJSON(t *testing.T, server *httptest.Server, action string, in interface{}, out interface{}) int {
+	t.Helper()
+	body, _ := json.Marshal(in)
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/sqs/", strings.NewReader(string(body)))
+	req.Header.Set("X-Amz-Target", "AmazonSQS."+action)
+	req.Header.Set("Content-Type", "application/x-amz-json-1.0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s: %v", action, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func callQuery(t *testing.T, target string, form url.Values, out interface{}) int {
+	t.Helper()
+	resp, err := http.PostForm(target, form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := xml.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("%s: %v", form.Get("Action"), err)
+	}
+	return resp.StatusCode
+}
+
+func TestSQSJSONProtocol(t *testing.T) {
+	server := newSQSServer(t)
+
+	var created struct{ QueueUrl string }
+	if status := callJSON(t, server, "CreateQueue", map[string]interface{}{
+		"QueueName":  "jobs",
+		"Attributes": map[string]string{"VisibilityTimeout": "60"},
+	}, &created); status != http.StatusOK {
+		t.Fatalf("CreateQueue: HTTP %d", status)
+	}
+	if created.QueueUrl != server.URL+"/sqs/"+AccountID+"/jobs" {
+		t.Errorf("queue URL = %q", created.QueueUrl)
+	}
+
+	attrs := map[string]MessageAttribute{"priority": {DataType: "Number", StringValue: "1"}}
+	var sent sendResult
+	callJSON(t, server, "SendMessage", map[string]interface{}{
+		"QueueUrl":          created.QueueUrl,
+		"MessageBody":       "hello",
+		"MessageAttributes": attrs,
+	}, &sent)
+	if sent.MessageId == "" || sent.MD5OfMessageBody != "5d41402abc4b2a76b9719d911017c592" || sent.MD5OfMessageAttributes == "" {
+		t.Errorf("SendMessage = %+v", sent)
+	}
+
+	var received receiveResult
+	callJSON(t, server, "ReceiveMessage", map[string]interface{}{
+		"QueueUrl":              created.QueueUrl,
+		"AttributeNames":        []string{"ApproximateReceiveCount"},
+		"MessageAttributeNames": []string{"All"},
+	}, &received)
+	if len(received.Messages) != 1 {
+		t.Fatalf("received %d messages", len(received.Messages))
+	}
+	m := received.Messages[0]
+	if m.Body != "hello" || m.Attributes["ApproximateReceiveCount"] != "1" || len(m.Attributes) != 1 ||
+		m.MD5OfMessageAttributes != sent.MD5OfMessageAttributes || m.MessageAttributes["priority"].StringValue != "1" {
+		t.Errorf("received %+v", m)
+	}
+
+	var queueAttrs queueAttributesResult
+	callJSON(t, server, "GetQueueAttributes", map[string]interface{}{
+		"QueueUrl":       created.QueueUrl,
+		"AttributeNames": []string{"VisibilityTimeout", "ApproximateNumberOfMessagesNotVisible"},
+	}, &queueAttrs)
+	if queueAttrs.Attributes["VisibilityTimeout"] != "60" || queueAttrs.Attributes["ApproximateNumberOfMessagesNotVisible"] != "1" {
+		t.Errorf("attributes = %v", queueAttrs.Attributes)
+	}
+
+	if status := callJSON(t, server, "DeleteMessage", map[string]interface{}{
+		"QueueUrl":      created.QueueUrl,
+		"ReceiptHandle": m.ReceiptHandle,
+	}, nil); status != http.StatusOK {
+		t.Errorf("DeleteMessage: HTTP %d", status)
+	}
+}
+
+func TestSQSJSONErrors(t *testing.T) {
+	server := newSQSServer(t)
+	callJSON(t, server, "CreateQueue", map[string]interface{}{"QueueName": "jobs"}, nil)
+
+	tests := []struct {
+		action string
+		in     map[string]interface{}
+		code   string
+	}{
+		{"SendMessage", map[string]interface{}{"QueueUrl": "missing", "MessageBody": "x"}, "QueueDoesNotExist"},
+		{"CreateQueue", map[string]interface{}{}, "MissingParameter"},
+		{"DeleteMessage", map[string]interface{}{"QueueUrl": "jobs", "ReceiptHandle": ""}, "ReceiptHandleIsInvalid"},
+		{"SendMessageBatch", map[string]interface{}{"QueueUrl": "jobs", "Entries": []map[string]string{
+			{"Id": "a", "MessageBody": "1"}, {"Id": "a", "MessageBody": "2"},
+		}}, "BatchEntryIdsNotDistinct"},
+		{"TagQueue", map[string]interface{}{"QueueUrl": "jobs"}, "UnsupportedOperation"},
+	}
+	for _, tt := range tests {
+		var body struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		status := callJSON(t, server, tt.action, tt.in, &body)
+		if status != http.StatusBadRequest || body.Type != "com.amazonaws.sqs#"+tt.code {
+			t.Errorf("%s: HTTP %d %s, want %s", tt.action, status, body.Type, tt.code)
+		}
+	}
+}
+
+func TestSQSBatch(t *testing.T) {
+	server := newSQSServer(t)
+	callJSON(t, server, "CreateQueue", map[string]interface{}{"QueueName": "jobs"}, nil)
+
+	var result sendBatchResult
+	callJSON(t, server, "SendMessageBatch", map[string]interface{}{
+		"QueueUrl": "jobs",
+		"Entries": []map[string]interface{}{
+			{"Id": "ok", "MessageBody": "one"},
+			{"Id": "bad", "MessageBody": ""},
+		},
+	}, &result)
+	if len(result.Successful) != 1 || result.Successful[0].Id != "ok" || len(result.Failed) != 1 || result.Failed[0].Id != "bad" || !result.Failed[0].SenderFault {
+		t.Errorf("SendMessageBatch = %+v", result)
+	}
+}
+
+func TestSQSQueryProtocol(t *testing.T) {
+	server := newSQSServer(t)
+
+	var created struct {
+		URL string `xml:"CreateQueueResult>QueueUrl"`
+	}
+	callQuery(t, server.URL+"/sqs/", url.Values{"Action": {"CreateQueue"}, "QueueName": {"jobs"}, "Attribute.1.Name": {"DelaySeconds"}, "Attribute.1.Value": {"0"}}, &created)
+	if !strings.HasSuffix(created.URL, "/sqs/"+AccountID+"/jobs") {
+		t.Fatalf("queue URL = %q", created.URL)
+	}
+
+	// the older SDKs post to the queue URL
+	var sent struct {
+		MessageID string `xml:"SendMessageResult>MessageId"`
+	}
+	callQuery(t, created.URL, url.Values{
+		"Action":                               {"SendMessage"},
+		"MessageBody":                          {"hello"},
+		"MessageAttribute.1.Name":              {"kind"},
+		"MessageAttribute.1.Value.DataType":    {"String"},
+		"MessageAttribute.1.Value.StringValue": {"email"},
+	}, &sent)
+	if sent.MessageID == "" {
+		t.Fatal("no message ID")
+	}
+
+	var received struct {
+		Messages []struct {
+			ID    string `xml:"MessageId"`
+			Body  string `xml:"Body"`
+			Attrs []struct {
+				Name  string `xml:"Name"`
+				Value string `xml:"Value>StringValue"`
+			} `xml:"MessageAttribute"`
+		} `xml:"ReceiveMessageResult>Message"`
+	}
+	callQuery(t, created.URL, url.Values{"Action": {"ReceiveMessage"}, "MessageAttributeName.1": {"All"}}, &received)
+	if len(received.Messages) != 1 {
+		t.Fatalf("received %d messages", len(received.Messages))
+	}
+	m := received.Messages[0]
+	if m.ID != sent.MessageID || m.Body != "hello" || len(m.Attrs) != 1 || m.Attrs[0].Name != "kind" || m.Attrs[0].Value != "email" {
+		t.Errorf("received %+v", m)
+	}
+
+	var failed struct {
+		Code string `xml:"Error>Code"`
+	}
+	if status := callQuery(t, server.URL+"/sqs/", url.Values{"Action": {"PurgeQueue"}, "QueueUrl": {"missing"}}, &failed); status != http.StatusBadRequest ||
+		failed.Code != "AWS.SimpleQueueService.NonExistentQueue" {
+		t.Errorf("PurgeQueue on a missing queue: HTTP %d %s", status, failed.Code)
+	}
+}
+
+func TestAttributesMD5(t *testing.T) {
+	if attributesMD5(nil) != "" {
+		t.Error("digest for no attributes")
+	}
+
+	// order of attributes does not matter, values do
+	a := map[string]MessageAttribute{"a": {DataType: "String", StringValue: "1"}, "b": {DataType: "Binary", BinaryValue: []byte{1, 2}}}
+	b := map[string]MessageAttribute{"b": {DataType: "Binary", BinaryValue: []byte{1, 2}}, "a": {DataType: "String", StringValue: "1"}}
+	if attributesMD5(a) != attributesMD5(b) {
+		t.Error("digest depends on map order")
+	}
+	b["a"] = MessageAttribute{DataType: "String", StringValue: "2"}
+	if attributesMD5(a) == attributesMD5(b) {
+		t.Error("digest ignores values")
+	}
+}
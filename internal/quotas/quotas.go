@@ -0,0 +1,142 @@
+// Per-project resource quotas, enforced by the compute manager
+package quotas
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+)
+
+// Quota name that applies to every project without its own
+const AllProjects = "*"
+
+var (
+	ErrQuotaNotFound = errors.New("quota not found")
+	ErrInvalidQuota  = errors.New("limits must not be negative")
+)
+
+// Limits and usage of one project
+type Status struct {
+	Project   string         `json:"project"`
+	Quota     *compute.Quota `json:"quota,omitempty"` // nil when unlimited
+	Inherited bool           `json:"inherited"`       // the quota comes from "*"
+	Usage     compute.Usage  `json:"usage"`
+}
+
+type Service struct {
+	manager *compute.Manager
+	path    string
+
+	mu     sync.Mutex
+	quotas map[string]compute.Quota
+}
+
+func NewService(manager *compute.Manager, dataDir string) (*Service, error) {
+	s := &Service{
+		manager: manager,
+		path:    filepath.Join(dataDir, "quotas", "quotas.json"),
+		quotas:  make(map[string]compute.Quota),
+	}
+	if err := store.Load(s.path, &s.quotas); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Quota for a project, falling back to the "*" quota
+func (s *Service) Quota(project string) (compute.Quota, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if quota, ok := s.quotas[project]; ok {
+		return quota, true
+	}
+	quota, ok := s.quotas[AllProjects]
+	return quota, ok
+}
+
+func (s *Service) Set(project string, quota compute.Quota) error {
+	if project != AllProjects && !compute.ValidProjectName(project) {
+		return compute.ErrInvalidProject
+	}
+	if quota.MaxInstances < 0 || quota.MaxCPUs < 0 || quota.MaxMemoryMB < 0 ||
+		quota.MaxVolumes < 0 || quota.MaxStorageBytes < 0 || quota.MaxPorts < 0 {
+		return ErrInvalidQuota
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.quotas[project] = quota
+	return store.Save(s.path, s.quotas)
+}
+
+func (s *Service) Delete(project string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.quotas[project]; !ok {
+		return ErrQuotaNotFound
+	}
+	delete(s.quotas, project)
+	return store.Save(s.path, s.quotas)
+}
+
+// Usage against limits for every project with a quota or resources
+func (s *Service) Report() ([]Status, error) {
+	usage, err := s.manager.ProjectUsage(true)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	projects := make(map[string]bool)
+	for project := range usage {
+		projects[project] = true
+	}
+	for project := range s.quotas {
+		projects[project] = true
+	}
+
+	report := make([]Status, 0, len(projects))
+	for project := range projects {
+		status := Status{Project: project}
+		if u := usage[project]; u != nil {
+			status.Usage = *u
+		}
+		if quota, ok := s.quotas[project]; ok {
+			status.Quota = &quota
+		} else if quota, ok := s.quotas[AllProjects]; ok {
+			status.Quota = &quota
+			status.Inherited = true
+		}
+		report = append(report, status)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Project < report[j].Project
+	})
+	return report, nil
+}
+
+// Status of one project, which may have no quota or resources yet
+func (s *Service) Get(project string) (*Status, error) {
+	report, err := s.Report()
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range report {
+		if status.Project == project {
+			return &status, nil
+		}
+	}
+
+	status := &Status{Project: project}
+	if quota, ok := s.Quota(project); ok {
+		status.Quota = &quota
+		status.Inherited = project != AllProjects
+	}
+	return status, nil
+}
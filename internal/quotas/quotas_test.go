@@ -0,0 +1,127 @@
+package quotas
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+)
+
+func TestQuotaFallback(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Quota("team"); ok {
+		t.Fatal("no quotas set, but team has one")
+	}
+
+	if err := s.Set(AllProjects, compute.Quota{MaxInstances: 5}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("team", compute.Quota{MaxInstances: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		project string
+		want    int
+	}{
+		{"team", 10},
+		{"other", 5},
+		{compute.DefaultProject, 5},
+		{AllProjects, 5},
+	}
+	for _, tt := range tests {
+		quota, ok := s.Quota(tt.project)
+		if !ok || quota.MaxInstances != tt.want {
+			t.Errorf("Quota(%q) = %+v, %v, want %d instances", tt.project, quota, ok, tt.want)
+		}
+	}
+
+	// quotas survive a restart, and deleting one falls back to "*"
+	s, err = NewService(nil, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete("team"); err != nil {
+		t.Fatal(err)
+	}
+	if quota, _ := s.Quota("team"); quota.MaxInstances != 5 {
+		t.Errorf("after delete, team has %d instances, want 5", quota.MaxInstances)
+	}
+	if err := s.Delete("team"); !errors.Is(err, ErrQuotaNotFound) {
+		t.Errorf("second delete: error %v, want %v", err, ErrQuotaNotFound)
+	}
+}
+
+func TestSetRejects(t *testing.T) {
+	s, err := NewService(nil, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		project string
+		quota   compute.Quota
+		err     error
+	}{
+		{"Team", compute.Quota{}, compute.ErrInvalidProject},
+		{"", compute.Quota{}, compute.ErrInvalidProject},
+		{"team/a", compute.Quota{}, compute.ErrInvalidProject},
+		{"team", compute.Quota{MaxCPUs: -1}, ErrInvalidQuota},
+		{"team", compute.Quota{MaxStorageBytes: -1}, ErrInvalidQuota},
+	}
+	for _, tt := range tests {
+		if err := s.Set(tt.project, tt.quota); !errors.Is(err, tt.err) {
+			t.Errorf("Set(%q, %+v): error %v, want %v", tt.project, tt.quota, err, tt.err)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	docker := dockertest.NewServer(t)
+	spec, _ := json.Marshal(compute.CreateSpec{Project: "team", CPUs: 1})
+	docker.AddContainer(dockertest.Container{Name: "web", Image: "nginx", HostConfig: &container.HostConfig{Resources: container.Resources{NanoCPUs: 1e9}}, Labels: map[string]string{
+		"localcloud.managed": "true",
+		"localcloud.spec":    string(spec),
+	}})
+	docker.Handle("GET /volumes", volume.ListResponse{})
+	docker.Handle("GET /system/df", types.DiskUsage{})
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := NewService(manager, t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Set(AllProjects, compute.Quota{MaxInstances: 5})
+	s.Set("batch", compute.Quota{MaxCPUs: 8})
+
+	report, err := s.Report()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report) != 3 || report[0].Project != AllProjects || report[1].Project != "batch" || report[2].Project != "team" {
+		t.Fatalf("report = %+v", report)
+	}
+	if team := report[2]; !team.Inherited || team.Quota.MaxInstances != 5 || team.Usage.Instances != 1 || team.Usage.CPUs != 1 {
+		t.Errorf("team = %+v", team)
+	}
+
+	// projects without resources or a quota of their own still inherit
+	status, err := s.Get("empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Inherited || status.Quota == nil || status.Usage.Instances != 0 {
+		t.Errorf("empty = %+v", status)
+	}
+}
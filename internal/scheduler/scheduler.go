@@ -0,0 +1,554 @@
+// Cron-scheduled jobs that run one-off containers to completion
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// Labels placed on run containers
+const (
+	LabelJob = "localcloud.scheduler.job"
+	LabelRun = "localcloud.scheduler.run"
+)
+
+// What to do when a run is due while an earlier one is still going
+const (
+	ConcurrencyAllow   = "allow"
+	ConcurrencyForbid  = "forbid"  // skip the new run
+	ConcurrencyReplace = "replace" // stop the old run
+)
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+	StatusTimedOut  = "timed-out"
+	StatusReplaced  = "replaced"
+	StatusSkipped   = "skipped"
+	StatusStopped   = "stopped"
+)
+
+const (
+	tickInterval        = time.Second
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 200
+	maxLogBytes         = 64 * 1024
+)
+
+var ErrJobNotFound = errors.New("job not found")
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+type Job struct {
+	Name           string             `json:"name"`
+	Schedule       string             `json:"schedule"` // cron expression, in the server's time zone
+	Spec           compute.CreateSpec `json:"spec"`
+	Concurrency    string             `json:"concurrency"`
+	TimeoutSeconds int                `json:"timeout_seconds,omitempty"` // 0 for no limit
+	HistoryLimit   int                `json:"history_limit"`             // runs kept
+	Suspended      bool               `json:"suspended"`
+	Created        time.Time          `json:"created"`
+}
+
+type Run struct {
+	ID         string     `json:"id"`
+	Job        string     `json:"job"`
+	Trigger    string     `json:"trigger"` // schedule or manual
+	Status     string     `json:"status"`
+	InstanceID string     `json:"instance_id,omitempty"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	Started    time.Time  `json:"started"`
+	Finished   *time.Time `json:"finished,omitempty"`
+	DurationMS int64      `json:"duration_ms"`
+	Error      string     `json:"error,omitempty"`
+	Logs       string     `json:"logs,omitempty"`
+}
+
+// Job plus its live state
+type JobStatus struct {
+	Job
+	NextRun *time.Time `json:"next_run,omitempty"`
+	Active  int        `json:"active"`
+	LastRun *Run       `json:"last_run,omitempty"`
+}
+
+type activeRun struct {
+	cancel context.CancelFunc
+	reason string // status to record when cancelled, e.g. replaced
+}
+
+type Service struct {
+	manager *compute.Manager
+	dir     string
+
+	mu        sync.Mutex
+	jobs      map[string]*Job
+	schedules map[string]*Schedule
+	next      map[string]time.Time
+	runs      map[string][]*Run // oldest first
+	active    map[string]map[string]*activeRun
+}
+
+func NewService(manager *compute.Manager, dataDir string) (*Service, error) {
+	s := &Service{
+		manager:   manager,
+		dir:       filepath.Join(dataDir, "scheduler"),
+		jobs:      make(map[string]*Job),
+		schedules: make(map[string]*Schedule),
+		next:      make(map[string]time.Time),
+		runs:      make(map[string][]*Run),
+		active:    make(map[string]map[string]*activeRun),
+	}
+	if err := store.Load(filepath.Join(s.dir, "jobs.json"), &s.jobs); err != nil {
+		return nil, err
+	}
+	for name, job := range s.jobs {
+		schedule, err := ParseCron(job.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", name, err)
+		}
+		s.schedules[name] = schedule
+
+		var runs []*Run
+		if err := store.Load(s.runsPath(name), &runs); err != nil {
+			return nil, err
+		}
+		s.runs[name] = runs
+	}
+	return s, nil
+}
+
+// Launch runs as they come due until ctx is done. Runs missed while
+// LocalCloud was down are not made up.
+func (s *Service) Start(ctx context.Context) {
+	s.recover()
+
+	go func() {
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				s.tick(now)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Close out runs that were in progress when LocalCloud stopped
+func (s *Service) recover() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, runs := range s.runs {
+		changed := false
+		for _, run := range runs {
+			if run.Status != StatusRunning {
+				continue
+			}
+			if run.InstanceID != "" {
+				if logs, err := s.manager.Output(run.InstanceID, maxLogBytes); err == nil {
+					run.Logs = logs
+				}
+				s.manager.Delete(run.InstanceID)
+			}
+			now := time.Now()
+			run.Status = StatusFailed
+			run.Error = "interrupted by a LocalCloud restart"
+			run.Finished = &now
+			run.DurationMS = now.Sub(run.Started).Milliseconds()
+			changed = true
+		}
+		if changed {
+			if err := s.saveRuns(name); err != nil {
+				log.Printf("Failed to save runs of job %s: %v", name, err)
+			}
+		}
+	}
+}
+
+func (s *Service) tick(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for name, job := range s.jobs {
+		next, ok := s.next[name]
+		if !ok {
+			s.next[name] = s.schedules[name].Next(now)
+			continue
+		}
+		if next.IsZero() || now.Before(next) {
+			continue
+		}
+
+		s.next[name] = s.schedules[name].Next(now)
+		if !job.Suspended {
+			s.launch(job, "schedule")
+		}
+	}
+}
+
+func validate(job *Job) (*Schedule, error) {
+	if !validName.MatchString(job.Name) {
+		return nil, fmt.Errorf("job name must be lowercase letters, digits and dashes")
+	}
+	schedule, err := ParseCron(job.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	if !schedule.fires() {
+		return nil, fmt.Errorf("schedule %q never fires", job.Schedule)
+	}
+	if job.Spec.Image == "" && job.Spec.Snapshot == "" {
+		return nil, fmt.Errorf("spec.image or spec.snapshot is required")
+	}
+
+	if job.Concurrency == "" {
+		job.Concurrency = ConcurrencyAllow
+	}
+	switch job.Concurrency {
+	case ConcurrencyAllow, ConcurrencyForbid, ConcurrencyReplace:
+	default:
+		return nil, fmt.Errorf("concurrency must be allow, forbid or replace")
+	}
+
+	if job.TimeoutSeconds < 0 {
+		return nil, fmt.Errorf("timeout_seconds must not be negative")
+	}
+	if job.HistoryLimit <= 0 {
+		job.HistoryLimit = defaultHistoryLimit
+	}
+	if job.HistoryLimit > maxHistoryLimit {
+		job.HistoryLimit = maxHistoryLimit
+	}
+	return schedule, nil
+}
+
+func (s *Service) Create(job Job) (*JobStatus, error) {
+	schedule, err := validate(&job)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.Name]; exists {
+		return nil, fmt.Errorf("job %q already exists", job.Name)
+	}
+	job.Created = time.Now()
+	s.jobs[job.Name] = &job
+	if err := s.saveJobs(); err != nil {
+		delete(s.jobs, job.Name)
+		return nil, err
+	}
+	s.schedules[job.Name] = schedule
+	s.next[job.Name] = schedule.Next(time.Now())
+	return s.status(&job), nil
+}
+
+func (s *Service) List() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		list = append(list, *s.status(job))
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (s *Service) Get(name string) (*JobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	return s.status(job), nil
+}
+
+// Pause or resume scheduled runs; manual runs are still allowed
+func (s *Service) SetSuspended(name string, suspended bool) (*JobStatus, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	job.Suspended = suspended
+	if err := s.saveJobs(); err != nil {
+		job.Suspended = !suspended
+		return nil, err
+	}
+	return s.status(job), nil
+}
+
+// Stop any active runs and remove the job with its history
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	delete(s.jobs, name)
+	if err := s.saveJobs(); err != nil {
+		s.jobs[name] = job
+		return err
+	}
+
+	for _, active := range s.active[name] {
+		active.reason = StatusStopped
+		active.cancel()
+	}
+	delete(s.schedules, name)
+	delete(s.next, name)
+	delete(s.runs, name)
+	if err := os.Remove(s.runsPath(name)); err != nil && !os.IsNotExist(err) {
+		log.Printf("Failed to remove run history of job %s: %v", name, err)
+	}
+	return nil
+}
+
+// Start a run now, subject to the job's concurrency policy
+func (s *Service) Trigger(name string) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	run := *s.launch(job, "manual")
+	return &run, nil
+}
+
+// Recent runs, newest first and without logs
+func (s *Service) Runs(name string, limit int) ([]Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[name]; !ok {
+		return nil, fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+
+	runs := s.runs[name]
+	list := make([]Run, 0, len(runs))
+	for i := len(runs) - 1; i >= 0 && len(list) < limit; i-- {
+		run := *runs[i]
+		run.Logs = ""
+		list = append(list, run)
+	}
+	return list, nil
+}
+
+// One run with its logs
+func (s *Service) GetRun(name, id string) (*Run, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, run := range s.runs[name] {
+		if run.ID == id {
+			copied := *run
+			return &copied, nil
+		}
+	}
+	return nil, fmt.Errorf("run %s of job %s not found", id, name)
+}
+
+// Apply the concurrency policy and start a run. Caller holds s.mu.
+func (s *Service) launch(job *Job, trigger string) *Run {
+	now := time.Now()
+	run := &Run{
+		ID:      uuid.New().String()[:8],
+		Job:     job.Name,
+		Trigger: trigger,
+		Status:  StatusRunning,
+		Started: now,
+	}
+
+	if active := s.active[job.Name]; len(active) > 0 {
+		switch job.Concurrency {
+		case ConcurrencyForbid:
+			run.Status = StatusSkipped
+			run.Error = fmt.Sprintf("%d earlier run(s) still active", len(active))
+			run.Finished = &now
+			s.record(job, run)
+			return run
+		case ConcurrencyReplace:
+			for _, a := range active {
+				a.reason = StatusReplaced
+				a.cancel()
+			}
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if job.TimeoutSeconds > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), time.Duration(job.TimeoutSeconds)*time.Second)
+	}
+	if s.active[job.Name] == nil {
+		s.active[job.Name] = make(map[string]*activeRun)
+	}
+	s.active[job.Name][run.ID] = &activeRun{cancel: cancel}
+	s.record(job, run)
+
+	spec := job.Spec
+	go s.execute(ctx, spec, run)
+	return run
+}
+
+// Create the container, wait for it to exit and clean it up
+func (s *Service) execute(ctx context.Context, spec compute.CreateSpec, run *Run) {
+	spec.Name = fmt.Sprintf("localcloud-job-%s-%s", run.Job, run.ID)
+	spec.HealthCheck = nil // a run that exits is not unhealthy
+
+	labels := make(map[string]string, len(spec.Labels)+2)
+	for key, value := range spec.Labels {
+		labels[key] = value
+	}
+	labels[LabelJob] = run.Job
+	labels[LabelRun] = run.ID
+	spec.Labels = labels
+
+	instance, err := s.manager.Create(spec)
+	if err != nil {
+		s.finish(run, StatusFailed, nil, "", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	run.InstanceID = instance.ID
+	s.mu.Unlock()
+
+	status, errMessage := StatusSucceeded, ""
+	exitCode, err := s.manager.Wait(ctx, instance.ID)
+	var code *int
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		status, errMessage = StatusTimedOut, "run exceeded its timeout"
+	case ctx.Err() != nil:
+		status = s.cancelReason(run)
+	case err != nil:
+		status, errMessage = StatusFailed, err.Error()
+	default:
+		code = &exitCode
+		if exitCode != 0 {
+			status = StatusFailed
+		}
+	}
+
+	// stop and remove the container whatever happened, keeping its logs
+	logs, err := s.manager.Output(instance.ID, maxLogBytes)
+	if err != nil {
+		logs = ""
+	}
+	if err := s.manager.Delete(instance.ID); err != nil {
+		log.Printf("Failed to remove container of job %s run %s: %v", run.Job, run.ID, err)
+	}
+	s.finish(run, status, code, logs, errMessage)
+}
+
+func (s *Service) cancelReason(run *Run) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if active, ok := s.active[run.Job][run.ID]; ok && active.reason != "" {
+		return active.reason
+	}
+	return StatusStopped
+}
+
+func (s *Service) finish(run *Run, status string, exitCode *int, logs, errMessage string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if active, ok := s.active[run.Job][run.ID]; ok {
+		active.cancel()
+		delete(s.active[run.Job], run.ID)
+	}
+
+	now := time.Now()
+	run.Status = status
+	run.ExitCode = exitCode
+	run.Logs = logs
+	run.Error = errMessage
+	run.Finished = &now
+	run.DurationMS = now.Sub(run.Started).Milliseconds()
+
+	if _, ok := s.jobs[run.Job]; !ok {
+		return // deleted while running
+	}
+	if err := s.saveRuns(run.Job); err != nil {
+		log.Printf("Failed to save runs of job %s: %v", run.Job, err)
+	}
+}
+
+// Add a run to the history, trimming it to the job's limit. Caller holds s.mu.
+func (s *Service) record(job *Job, run *Run) {
+	runs := append(s.runs[job.Name], run)
+
+	// never drop runs that are still going
+	for len(runs) > job.HistoryLimit {
+		i := 0
+		for i < len(runs)-1 && runs[i].Status == StatusRunning {
+			i++
+		}
+		runs = append(runs[:i:i], runs[i+1:]...)
+	}
+	s.runs[job.Name] = runs
+
+	if err := s.saveRuns(job.Name); err != nil {
+		log.Printf("Failed to save runs of job %s: %v", job.Name, err)
+	}
+}
+
+// Caller holds s.mu
+func (s *Service) status(job *Job) *JobStatus {
+	status := &JobStatus{Job: *job, Active: len(s.active[job.Name])}
+	if next, ok := s.next[job.Name]; ok && !next.IsZero() && !job.Suspended {
+		status.NextRun = &next
+	}
+	if runs := s.runs[job.Name]; len(runs) > 0 {
+		last := *runs[len(runs)-1]
+		last.Logs = ""
+		status.LastRun = &last
+	}
+	return status
+}
+
+// Caller holds s.mu
+func (s *Service) saveJobs() error {
+	return store.Save(filepath.Join(s.dir, "jobs.json"), s.jobs)
+}
+
+// Caller holds s.mu
+func (s *Service) saveRuns(name string) error {
+	return store.Save(s.runsPath(name), s.runs[name])
+}
+
+func (s *Service) runsPath(name string) string {
+	return filepath.Join(s.dir, "runs", name+".json")
+}
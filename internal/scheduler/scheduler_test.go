@@ -0,0 +1,292 @@
+package scheduler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"localcloud/internal/compute"
+	"localcloud/internal/dockertest"
+)
+
+// Run containers by their command: "true" and "false" exit at once with
+// 0 and 1, anything else keeps running until stopped
+func newTestService(t *testing.T) (*Service, *dockertest.Server, string) {
+	t.Helper()
+	docker := dockertest.NewServer(t)
+	docker.OnCreate(func(c *dockertest.Container) {
+		if len(c.Config.Cmd) == 0 {
+			return
+		}
+		switch c.Config.Cmd[0] {
+		case "true":
+			c.RunToExit = true
+			c.Output = "done\n"
+		case "false":
+			c.RunToExit = true
+			c.ExitCode = 1
+			c.Output = "oops\n"
+		}
+	})
+	manager, err := compute.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	s, err := NewService(manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, docker, dir
+}
+
+func job(name string, command ...string) Job {
+	return Job{
+		Name:     name,
+		Schedule: "@hourly",
+		Spec:     compute.CreateSpec{Image: "busybox", Command: command},
+	}
+}
+
+func create(t *testing.T, s *Service, j Job) {
+	t.Helper()
+	if _, err := s.Create(j); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Wait for a run to leave the running status
+func waitRun(t *testing.T, s *Service, name, id string) *Run {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		run, err := s.GetRun(name, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.Status != StatusRunning {
+			return run
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run %s of %s still running", id, name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// Wait for a run's container to be created and return its ID
+func instanceOf(t *testing.T, s *Service, name, id string) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		run, err := s.GetRun(name, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if run.InstanceID != "" {
+			return run.InstanceID
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("run %s of %s has no container", id, name)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCreateValidation(t *testing.T) {
+	s, _, _ := newTestService(t)
+	tests := []struct {
+		change func(*Job)
+		err    string
+	}{
+		{func(j *Job) { j.Name = "Nightly" }, "job name"},
+		{func(j *Job) { j.Schedule = "61 * * * *" }, "61"},
+		{func(j *Job) { j.Schedule = "0 0 31 2 *" }, "never fires"},
+		{func(j *Job) { j.Spec.Image = "" }, "spec.image"},
+		{func(j *Job) { j.Concurrency = "queue" }, "concurrency"},
+		{func(j *Job) { j.TimeoutSeconds = -1 }, "timeout_seconds"},
+	}
+	for _, tt := range tests {
+		j := job("nightly", "true")
+		tt.change(&j)
+		if _, err := s.Create(j); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("Create(%+v) = %v, want %q", j, err, tt.err)
+		}
+	}
+
+	j := job("nightly", "true")
+	j.HistoryLimit = maxHistoryLimit + 1
+	status, err := s.Create(j)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Concurrency != ConcurrencyAllow || status.HistoryLimit != maxHistoryLimit || status.NextRun == nil {
+		t.Errorf("defaults = %+v", status)
+	}
+	if _, err := s.Create(j); err == nil {
+		t.Error("created a job twice")
+	}
+}
+
+func TestTrigger(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	create(t, s, job("ok", "true"))
+	create(t, s, job("broken", "false"))
+
+	run, err := s.Trigger("ok")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if run.Status != StatusRunning || run.Trigger != "manual" {
+		t.Errorf("triggered run = %+v", run)
+	}
+	done := waitRun(t, s, "ok", run.ID)
+	if done.Status != StatusSucceeded || done.ExitCode == nil || *done.ExitCode != 0 || done.Logs != "done\n" || done.Finished == nil {
+		t.Errorf("finished run = %+v", done)
+	}
+
+	run, _ = s.Trigger("broken")
+	if done := waitRun(t, s, "broken", run.ID); done.Status != StatusFailed || *done.ExitCode != 1 || done.Logs != "oops\n" {
+		t.Errorf("failed run = %+v", done)
+	}
+
+	// run containers are removed and history is listed without logs
+	if containers := docker.Containers(); len(containers) != 0 {
+		t.Errorf("%d containers left", len(containers))
+	}
+	runs, _ := s.Runs("ok", 10)
+	if len(runs) != 1 || runs[0].Logs != "" {
+		t.Errorf("runs = %+v", runs)
+	}
+	if _, err := s.Trigger("missing"); err == nil {
+		t.Error("triggered a missing job")
+	}
+}
+
+func TestConcurrencyPolicies(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	forbid := job("forbid", "sleep", "60")
+	forbid.Concurrency = ConcurrencyForbid
+	create(t, s, forbid)
+	replace := job("replace", "sleep", "60")
+	replace.Concurrency = ConcurrencyReplace
+	create(t, s, replace)
+
+	first, _ := s.Trigger("forbid")
+	if second, _ := s.Trigger("forbid"); second.Status != StatusSkipped {
+		t.Errorf("second forbid run = %+v", second)
+	}
+	if status, _ := s.Get("forbid"); status.Active != 1 {
+		t.Errorf("%d active forbid runs", status.Active)
+	}
+
+	old, _ := s.Trigger("replace")
+	current, _ := s.Trigger("replace")
+	if done := waitRun(t, s, "replace", old.ID); done.Status != StatusReplaced {
+		t.Errorf("replaced run = %+v", done)
+	}
+
+	// stopping the container ends the run like an exit
+	docker.SetState(instanceOf(t, s, "replace", current.ID), "exited")
+	if done := waitRun(t, s, "replace", current.ID); done.Status != StatusSucceeded {
+		t.Errorf("current run = %+v", done)
+	}
+
+	// deleting a job stops its runs
+	instanceOf(t, s, "forbid", first.ID)
+	if err := s.Delete("forbid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("forbid"); err == nil {
+		t.Error("deleted job still listed")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(docker.Containers()) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("container of a deleted job left running")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	s, docker, _ := newTestService(t)
+	j := job("slow", "sleep", "60")
+	j.TimeoutSeconds = 1
+	create(t, s, j)
+
+	run, _ := s.Trigger("slow")
+	done := waitRun(t, s, "slow", run.ID)
+	if done.Status != StatusTimedOut || done.ExitCode != nil {
+		t.Errorf("timed out run = %+v", done)
+	}
+	if containers := docker.Containers(); len(containers) != 0 {
+		t.Errorf("%d containers left", len(containers))
+	}
+}
+
+func TestHistoryLimit(t *testing.T) {
+	s, _, _ := newTestService(t)
+	j := job("often", "true")
+	j.HistoryLimit = 2
+	create(t, s, j)
+
+	var last string
+	for i := 0; i < 4; i++ {
+		run, _ := s.Trigger("often")
+		waitRun(t, s, "often", run.ID)
+		last = run.ID
+	}
+	runs, _ := s.Runs("often", 10)
+	if len(runs) != 2 || runs[0].ID != last {
+		t.Errorf("runs kept = %+v", runs)
+	}
+}
+
+func TestTick(t *testing.T) {
+	s, _, _ := newTestService(t)
+	create(t, s, job("due", "true"))
+	paused := job("paused", "true")
+	paused.Suspended = true
+	create(t, s, paused)
+
+	s.tick(time.Now().Add(2 * time.Hour))
+	if runs, _ := s.Runs("due", 10); len(runs) != 1 || runs[0].Trigger != "schedule" {
+		t.Errorf("due runs = %+v", runs)
+	}
+	if runs, _ := s.Runs("paused", 10); len(runs) != 0 {
+		t.Errorf("suspended job ran: %+v", runs)
+	}
+	if status, _ := s.Get("paused"); status.NextRun != nil {
+		t.Errorf("suspended job has a next run: %v", status.NextRun)
+	}
+	runs, _ := s.Runs("due", 10)
+	waitRun(t, s, "due", runs[0].ID)
+}
+
+func TestRecoverAfterRestart(t *testing.T) {
+	s, docker, dir := newTestService(t)
+	create(t, s, job("long", "sleep", "60"))
+	run, _ := s.Trigger("long")
+	instanceID := instanceOf(t, s, "long", run.ID)
+
+	// a second service over the same data finds the run interrupted
+	reopened, err := NewService(s.manager, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reopened.recover()
+	done, err := reopened.GetRun("long", run.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != StatusFailed || !strings.Contains(done.Error, "restart") {
+		t.Errorf("recovered run = %+v", done)
+	}
+	if status, err := reopened.Get("long"); err != nil || status.Schedule != "@hourly" {
+		t.Errorf("job after reopening = %+v, %v", status, err)
+	}
+
+	docker.SetState(instanceID, "exited")
+	waitRun(t, s, "long", run.ID)
+}
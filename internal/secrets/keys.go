@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"localcloud/internal/store"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt cost parameters for deriving the master key
+const (
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// Encrypted with the derived key to detect a wrong passphrase at startup
+var checkPlaintext = []byte("localcloud-secrets")
+
+// Salt and check value; the key itself is never stored
+type keyInfo struct {
+	Salt  []byte `json:"salt"`
+	Check []byte `json:"check"`
+}
+
+// Derive the master key from passphrase, or from a passphrase generated
+// into dir on first start if none is given
+func deriveKey(dir, passphrase string) (cipher.AEAD, error) {
+	if passphrase == "" {
+		generated, err := generatedPassphrase(filepath.Join(dir, "master.key"))
+		if err != nil {
+			return nil, err
+		}
+		passphrase = generated
+	}
+
+	infoPath := filepath.Join(dir, "keyinfo.json")
+	var info keyInfo
+	if err := store.Load(infoPath, &info); err != nil {
+		return nil, err
+	}
+	fresh := len(info.Salt) == 0
+	if fresh {
+		info.Salt = make([]byte, 16)
+		if _, err := rand.Read(info.Salt); err != nil {
+			return nil, fmt.Errorf("failed to generate salt: %w", err)
+		}
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), info.Salt, scryptN, scryptR, scryptP, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive master key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	if fresh {
+		if info.Check, err = seal(aead, checkPlaintext, nil); err != nil {
+			return nil, err
+		}
+		if err := store.Save(infoPath, info); err != nil {
+			return nil, err
+		}
+		return aead, nil
+	}
+
+	plain, err := open(aead, info.Check, nil)
+	if err != nil || !bytes.Equal(plain, checkPlaintext) {
+		return nil, fmt.Errorf("master key does not match the one existing secrets were encrypted with")
+	}
+	return aead, nil
+}
+
+// Read the generated passphrase, creating it if this is the first start
+func generatedPassphrase(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("failed to read master key: %w", err)
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate master key: %w", err)
+	}
+	passphrase := hex.EncodeToString(raw)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", fmt.Errorf("failed to create secrets directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(passphrase+"\n"), 0o600); err != nil {
+		return "", fmt.Errorf("failed to write master key: %w", err)
+	}
+	log.Printf("Generated a secrets master key in %s; set LOCALCLOUD_MASTER_KEY to use your own passphrase instead", path)
+	return passphrase, nil
+}
+
+// Encrypt with a random nonce, returned in front of the ciphertext
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, ciphertext, additional)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return plain, nil
+}
@@ -0,0 +1,268 @@
+// Versioned secrets, encrypted at rest with a master key derived at startup
+package secrets
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"localcloud/internal/store"
+)
+
+const (
+	maxValueSize   = 64 * 1024
+	generatedBytes = 32 // random bytes in a generated value
+)
+
+var (
+	ErrSecretNotFound  = errors.New("secret not found")
+	ErrVersionNotFound = errors.New("secret version not found")
+	ErrSecretExists    = errors.New("secret already exists")
+)
+
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]{0,127}$`)
+
+// Secret metadata; values are only returned by Value
+type Secret struct {
+	Name           string    `json:"name"`
+	Description    string    `json:"description,omitempty"`
+	CurrentVersion int       `json:"current_version"`
+	Versions       []Version `json:"versions"`
+	Created        time.Time `json:"created"`
+	Updated        time.Time `json:"updated"`
+}
+
+type Version struct {
+	Version   int       `json:"version"`
+	Generated bool      `json:"generated,omitempty"` // value was generated by LocalCloud
+	Created   time.Time `json:"created"`
+}
+
+// Secret with its encrypted values by version, as stored on disk
+type record struct {
+	Secret
+	Values map[int][]byte `json:"values"`
+}
+
+type Service struct {
+	dir  string
+	aead cipher.AEAD
+
+	mu      sync.Mutex
+	secrets map[string]*record
+}
+
+// passphrase may be empty, in which case one is generated on first start
+func NewService(dataDir, passphrase string) (*Service, error) {
+	s := &Service{
+		dir:     filepath.Join(dataDir, "secrets"),
+		secrets: make(map[string]*record),
+	}
+
+	aead, err := deriveKey(s.dir, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	s.aead = aead
+
+	if err := store.Load(filepath.Join(s.dir, "secrets.json"), &s.secrets); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Create a secret, generating a random value if value is empty
+func (s *Service) Create(name, description string, value []byte) (*Secret, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("invalid secret name %q", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.secrets[name]; ok {
+		return nil, fmt.Errorf("%w: %s", ErrSecretExists, name)
+	}
+
+	now := time.Now()
+	r := &record{
+		Secret: Secret{
+			Name:        name,
+			Description: description,
+			Created:     now,
+		},
+		Values: make(map[int][]byte),
+	}
+	if err := s.addVersion(r, value, now); err != nil {
+		return nil, err
+	}
+
+	s.secrets[name] = r
+	if err := s.save(); err != nil {
+		delete(s.secrets, name)
+		return nil, err
+	}
+	return r.copy(), nil
+}
+
+// Store a new current version, generating a random value if value is empty.
+// Older versions stay readable for instances pinned to them.
+func (s *Service) Rotate(name string, value []byte) (*Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.secrets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	// rotate a copy so a failed save leaves the current version in place
+	r := old.clone()
+	if err := s.addVersion(r, value, time.Now()); err != nil {
+		return nil, err
+	}
+
+	s.secrets[name] = r
+	if err := s.save(); err != nil {
+		s.secrets[name] = old
+		return nil, err
+	}
+	return r.copy(), nil
+}
+
+func (s *Service) addVersion(r *record, value []byte, now time.Time) error {
+	generated := len(value) == 0
+	if generated {
+		raw := make([]byte, generatedBytes)
+		if _, err := rand.Read(raw); err != nil {
+			return fmt.Errorf("failed to generate secret value: %w", err)
+		}
+		value = []byte(base64.RawURLEncoding.EncodeToString(raw))
+	}
+	if len(value) > maxValueSize {
+		return fmt.Errorf("secret value is larger than %d bytes", maxValueSize)
+	}
+
+	version := r.CurrentVersion + 1
+	sealed, err := seal(s.aead, value, additionalData(r.Name, version))
+	if err != nil {
+		return err
+	}
+
+	r.Values[version] = sealed
+	r.Versions = append(r.Versions, Version{Version: version, Generated: generated, Created: now})
+	r.CurrentVersion = version
+	r.Updated = now
+	return nil
+}
+
+func (s *Service) List() []Secret {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	list := make([]Secret, 0, len(s.secrets))
+	for _, r := range s.secrets {
+		list = append(list, *r.copy())
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+func (s *Service) Get(name string) (*Secret, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.secrets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return r.copy(), nil
+}
+
+// Decrypted value of a version, the current one if version is 0
+func (s *Service) Value(name string, version int) ([]byte, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.secrets[name]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	if version == 0 {
+		version = r.CurrentVersion
+	}
+	sealed, ok := r.Values[version]
+	if !ok {
+		return nil, 0, fmt.Errorf("%w: %s version %d", ErrVersionNotFound, name, version)
+	}
+
+	value, err := open(s.aead, sealed, additionalData(name, version))
+	if err != nil {
+		return nil, 0, err
+	}
+	return value, version, nil
+}
+
+// Lets compute.Manager inject secrets into instances
+func (s *Service) SecretValue(name string, version int) ([]byte, error) {
+	value, _, err := s.Value(name, version)
+	return value, err
+}
+
+func (s *Service) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.secrets[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+
+	delete(s.secrets, name)
+	if err := s.save(); err != nil {
+		s.secrets[name] = r
+		return err
+	}
+	return nil
+}
+
+// Caller holds s.mu
+func (s *Service) save() error {
+	return store.Save(filepath.Join(s.dir, "secrets.json"), s.secrets)
+}
+
+func (r *record) clone() *record {
+	c := &record{Secret: *r.copy(), Values: make(map[int][]byte, len(r.Values))}
+	for version, sealed := range r.Values {
+		c.Values[version] = sealed
+	}
+	return c
+}
+
+func (r *record) copy() *Secret {
+	secret := r.Secret
+	secret.Versions = append([]Version(nil), r.Versions...)
+	return &secret
+}
+
+// Binds each ciphertext to its secret and version so values can't be swapped
+func additionalData(name string, version int) []byte {
+	return []byte(name + "#" + strconv.Itoa(version))
+}
+
+// Encrypt data for another service with the master key. context must be the
+// same when opening it and should not collide with a secret name.
+func (s *Service) Seal(plaintext []byte, context string) ([]byte, error) {
+	return seal(s.aead, plaintext, []byte(context))
+}
+
+func (s *Service) Open(sealed []byte, context string) ([]byte, error) {
+	return open(s.aead, sealed, []byte(context))
+}
@@ -0,0 +1,207 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRotate(t *testing.T) {
+	s, err := NewService(t.TempDir(), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("db-password", "", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Rotate("db-password", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+	secret, err := s.Rotate("db-password", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.CurrentVersion != 3 || len(secret.Versions) != 3 || !secret.Versions[2].Generated || secret.Versions[1].Generated {
+		t.Fatalf("after rotating: %+v", secret)
+	}
+
+	tests := []struct {
+		version int
+		want    string // "" for the generated value
+		err     error
+	}{
+		{1, "v1", nil},
+		{2, "v2", nil},
+		{0, "", nil},
+		{3, "", nil},
+		{4, "", ErrVersionNotFound},
+	}
+	for _, tt := range tests {
+		value, version, err := s.Value("db-password", tt.version)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("version %d: error %v, want %v", tt.version, err, tt.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if tt.want != "" && string(value) != tt.want {
+			t.Errorf("version %d = %q, want %q", tt.version, value, tt.want)
+		}
+		if tt.want == "" && (version != 3 || len(value) < generatedBytes) {
+			t.Errorf("version %d = %q (version %d), want a generated value at version 3", tt.version, value, version)
+		}
+	}
+
+	if _, err := s.Rotate("missing", []byte("x")); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("rotating a missing secret: error %v, want %v", err, ErrSecretNotFound)
+	}
+	if _, err := s.Rotate("db-password", make([]byte, maxValueSize+1)); err == nil {
+		t.Error("rotating to an oversized value succeeded")
+	}
+	if secret, _ := s.Get("db-password"); secret.CurrentVersion != 3 {
+		t.Errorf("failed rotation changed the current version to %d", secret.CurrentVersion)
+	}
+}
+
+func TestRotateSaveFails(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("db-password", "", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	// a directory where the temporary file goes makes saving fail
+	if err := os.Mkdir(filepath.Join(dir, "secrets", "secrets.json.tmp"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Rotate("db-password", []byte("v2")); err == nil {
+		t.Fatal("rotating succeeded without saving")
+	}
+	if secret, _ := s.Get("db-password"); secret.CurrentVersion != 1 || len(secret.Versions) != 1 {
+		t.Errorf("after a failed save: %+v", secret)
+	}
+	if value, version, err := s.Value("db-password", 0); err != nil || string(value) != "v1" || version != 1 {
+		t.Errorf("current value = %q (version %d), %v", value, version, err)
+	}
+}
+
+func TestCreateRejects(t *testing.T) {
+	s, err := NewService(t.TempDir(), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("token", "", []byte("x")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"", "-token", "a/b", "has space"} {
+		if _, err := s.Create(name, "", []byte("x")); err == nil {
+			t.Errorf("Create(%q) succeeded, want error", name)
+		}
+	}
+	if _, err := s.Create("token", "", []byte("y")); !errors.Is(err, ErrSecretExists) {
+		t.Errorf("creating token twice: error %v, want %v", err, ErrSecretExists)
+	}
+}
+
+func TestEncryptedAtRest(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("api-key", "", []byte("plaintext-value")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Rotate("api-key", []byte("second-value")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "secrets", "secrets.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("plaintext-value")) || bytes.Contains(data, []byte("passphrase")) {
+		t.Fatal("secrets.json holds a plaintext value or the passphrase")
+	}
+
+	// ciphertexts are bound to their version
+	r := s.secrets["api-key"]
+	r.Values[1], r.Values[2] = r.Values[2], r.Values[1]
+	if _, _, err := s.Value("api-key", 1); err == nil {
+		t.Error("a swapped ciphertext decrypted")
+	}
+}
+
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("token", "", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewService(dir, "wrong"); err == nil {
+		t.Fatal("opened with the wrong passphrase")
+	}
+	s, err = NewService(dir, "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _, err := s.Value("token", 0); err != nil || string(value) != "value" {
+		t.Fatalf("after reopening: %q, %v", value, err)
+	}
+}
+
+func TestGeneratedPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewService(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create("token", "", []byte("value")); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(dir, "secrets", "master.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("master.key mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	s, err = NewService(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _, err := s.Value("token", 0); err != nil || string(value) != "value" {
+		t.Fatalf("after reopening: %q, %v", value, err)
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	s, err := NewService(t.TempDir(), "passphrase")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed, err := s.Seal([]byte("data"), "backup:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := s.Open(sealed, "backup:1"); err != nil || string(plain) != "data" {
+		t.Fatalf("Open = %q, %v", plain, err)
+	}
+	if _, err := s.Open(sealed, "backup:2"); err == nil {
+		t.Error("opened with a different context")
+	}
+	if _, err := s.Open(sealed[:4], "backup:1"); err == nil {
+		t.Error("opened a truncated ciphertext")
+	}
+}
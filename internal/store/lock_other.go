@@ -0,0 +1,14 @@
+//go:build !unix
+
+package store
+
+import "sync"
+
+// Without flock, Update is only serialized within this process
+var fileLocks sync.Map
+
+func lockFile(path string) (func(), error) {
+	mu, _ := fileLocks.LoadOrStore(path, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock, nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "state.json")
+
+	state := map[string]int{"untouched": 1}
+	if err := Load(path, &state); err != nil || state["untouched"] != 1 {
+		t.Fatalf("loading a missing file: %v, %v", state, err)
+	}
+
+	if err := Save(path, map[string]int{"a": 1, "b": 2}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	var loaded map[string]int
+	if err := Load(path, &loaded); err != nil || loaded["a"] != 1 || loaded["b"] != 2 {
+		t.Fatalf("Load = %v, %v", loaded, err)
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(path, []byte("{not json"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]int
+	if err := Load(path, &v); err == nil {
+		t.Error("loaded a corrupt file")
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counts.json")
+
+	// concurrent writers each see the others' changes
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(key string) {
+			defer wg.Done()
+			counts := make(map[string]int)
+			err := Update(path, &counts, func() (bool, error) {
+				counts[key]++
+				return true, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(fmt.Sprintf("k%d", i%5))
+	}
+	wg.Wait()
+
+	var counts map[string]int
+	if err := Load(path, &counts); err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 5 || counts["k0"] != 4 || counts["k4"] != 4 {
+		t.Errorf("counts = %v", counts)
+	}
+
+	// nothing is written unless fn succeeded and changed something
+	before, _ := os.ReadFile(path)
+	Update(path, &counts, func() (bool, error) {
+		counts["k0"] = 100
+		return false, nil
+	})
+	Update(path, &counts, func() (bool, error) {
+		counts["k0"] = 100
+		return true, errors.New("failed")
+	})
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("file changed to %s", after)
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Volume backups, newest first, optionally only those of one volume
+func (c *Client) ListBackups(ctx context.Context, volume string) ([]Backup, error) {
+	query := url.Values{}
+	if volume != "" {
+		query.Set("volume", volume)
+	}
+	var list []Backup
+	err := c.do(ctx, http.MethodGet, "/backups", query, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateBackup(ctx context.Context, in BackupInput) (*Backup, error) {
+	var backup Backup
+	if err := c.do(ctx, http.MethodPost, "/backups", nil, in, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// Start a backup; its result is a *Backup
+func (c *Client) CreateBackupAsync(ctx context.Context, in BackupInput) (*Operation, error) {
+	return c.startOperation(ctx, http.MethodPost, "/backups", in)
+}
+
+// Delete old backups, returning those removed
+func (c *Client) PruneBackups(ctx context.Context, in PruneInput) ([]Backup, error) {
+	var list []Backup
+	err := c.do(ctx, http.MethodPost, "/backups/prune", nil, in, &list)
+	return list, err
+}
+
+func (c *Client) GetBackup(ctx context.Context, id string) (*Backup, error) {
+	var backup Backup
+	if err := c.do(ctx, http.MethodGet, "/backups/"+segment(id), nil, nil, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+func (c *Client) DeleteBackup(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/backups/"+segment(id), nil, nil, nil)
+}
+
+func (c *Client) RestoreBackup(ctx context.Context, id string, in RestoreInput) (*Backup, error) {
+	var backup Backup
+	if err := c.do(ctx, http.MethodPost, "/backups/"+segment(id)+"/restore", nil, in, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// Start a restore; its result is a *Backup
+func (c *Client) RestoreBackupAsync(ctx context.Context, id string, in RestoreInput) (*Operation, error) {
+	return c.startOperation(ctx, http.MethodPost, "/backups/"+segment(id)+"/restore", in)
+}
+
+func (c *Client) VerifyBackup(ctx context.Context, id string) (*Backup, error) {
+	var backup Backup
+	if err := c.do(ctx, http.MethodPost, "/backups/"+segment(id)+"/verify", nil, nil, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// Start a verification; its result is a *Backup
+func (c *Client) VerifyBackupAsync(ctx context.Context, id string) (*Operation, error) {
+	return c.startOperation(ctx, http.MethodPost, "/backups/"+segment(id)+"/verify", nil)
+}
+
+func (c *Client) ListBackupPlans(ctx context.Context) ([]BackupPlanStatus, error) {
+	var list []BackupPlanStatus
+	err := c.do(ctx, http.MethodGet, "/backup-plans", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateBackupPlan(ctx context.Context, plan BackupPlan) (*BackupPlanStatus, error) {
+	var status BackupPlanStatus
+	if err := c.do(ctx, http.MethodPost, "/backup-plans", nil, plan, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Delete a plan; its backups are kept
+func (c *Client) DeleteBackupPlan(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/backup-plans/"+segment(name), nil, nil, nil)
+}
+
+func (c *Client) RunBackupPlan(ctx context.Context, name string) (*Backup, error) {
+	var backup Backup
+	if err := c.do(ctx, http.MethodPost, "/backup-plans/"+segment(name)+"/run", nil, nil, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// Start a plan's backup; its result is a *Backup
+func (c *Client) RunBackupPlanAsync(ctx context.Context, name string) (*Operation, error) {
+	return c.startOperation(ctx, http.MethodPost, "/backup-plans/"+segment(name)+"/run", nil)
+}
+
+// Export the environment as a gzipped bundle. The caller closes the reader.
+func (c *Client) ExportBundle(ctx context.Context, opts BundleOptions) (io.ReadCloser, error) {
+	body, _, err := c.stream(ctx, http.MethodPost, "/bundles/export", nil, opts)
+	return body, err
+}
+
+// Recreate an environment from a bundle written by ExportBundle
+func (c *Client) ImportBundle(ctx context.Context, bundle io.Reader, opts ImportOptions) (*ImportReport, error) {
+	query := url.Values{"dry_run": {strconv.FormatBool(opts.DryRun)}}
+	if opts.OnConflict != "" {
+		query.Set("on_conflict", opts.OnConflict)
+	}
+	var report ImportReport
+	if err := c.doRaw(ctx, http.MethodPost, "/bundles/import", query, "application/gzip", bundle, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
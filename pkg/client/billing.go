@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// Quotas and usage of every project
+func (c *Client) ListQuotas(ctx context.Context) ([]QuotaStatus, error) {
+	var list []QuotaStatus
+	err := c.do(ctx, http.MethodGet, "/quotas", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) GetQuota(ctx context.Context, project string) (*QuotaStatus, error) {
+	var status QuotaStatus
+	if err := c.do(ctx, http.MethodGet, "/quotas/"+segment(project), nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) SetQuota(ctx context.Context, project string, quota Quota) (*Quota, error) {
+	var updated Quota
+	if err := c.do(ctx, http.MethodPut, "/quotas/"+segment(project), nil, quota, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *Client) DeleteQuota(ctx context.Context, project string) error {
+	return c.do(ctx, http.MethodDelete, "/quotas/"+segment(project), nil, nil, nil)
+}
+
+func (c *Client) GetPrices(ctx context.Context) (*PriceSheet, error) {
+	var prices PriceSheet
+	if err := c.do(ctx, http.MethodGet, "/billing/prices", nil, nil, &prices); err != nil {
+		return nil, err
+	}
+	return &prices, nil
+}
+
+func (c *Client) SetPrices(ctx context.Context, prices PriceSheet) (*PriceSheet, error) {
+	var updated PriceSheet
+	if err := c.do(ctx, http.MethodPut, "/billing/prices", nil, prices, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *Client) BillingReport(ctx context.Context, in ReportInput) (*BillingReport, error) {
+	query := url.Values{}
+	for key, value := range map[string]string{"by": in.By, "tag_key": in.TagKey, "from": in.From, "to": in.To} {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	var report BillingReport
+	if err := c.do(ctx, http.MethodGet, "/billing/report", query, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (c *Client) ListBudgets(ctx context.Context) ([]BudgetStatus, error) {
+	var list []BudgetStatus
+	err := c.do(ctx, http.MethodGet, "/billing/budgets", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateBudget(ctx context.Context, budget Budget) (*Budget, error) {
+	var created Budget
+	if err := c.do(ctx, http.MethodPost, "/billing/budgets", nil, budget, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) DeleteBudget(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/billing/budgets/"+segment(name), nil, nil, nil)
+}
+
+func (c *Client) ListBudgetAlerts(ctx context.Context) ([]BudgetAlert, error) {
+	var list []BudgetAlert
+	err := c.do(ctx, http.MethodGet, "/billing/alerts", nil, nil, &list)
+	return list, err
+}
@@ -0,0 +1,208 @@
+// Package client is a typed Go client for the LocalCloud REST API, for
+// scripts and test suites that drive a running `localcloud web`.
+//
+//	c := client.New("http://localhost:8080")
+//	inst, err := c.CreateInstance(ctx, client.CreateSpec{Image: "nginx:alpine"})
+//
+// Every method returns an *APIError when the server answers with an error.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type Client struct {
+	endpoint   string
+	httpClient *http.Client
+	header     http.Header
+}
+
+type Option func(*Client)
+
+// Use hc instead of http.DefaultClient, e.g. for timeouts or TLS settings
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// Send a header with every request, including the WebSocket handshake
+func WithHeader(key, value string) Option {
+	return func(c *Client) {
+		c.header.Add(key, value)
+	}
+}
+
+// Authenticate with the server's API token (LOCALCLOUD_API_TOKEN)
+func WithToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// endpoint is the server's base URL, e.g. http://localhost:8080
+func New(endpoint string, opts ...Option) *Client {
+	c := &Client{
+		endpoint:   strings.TrimRight(endpoint, "/"),
+		httpClient: http.DefaultClient,
+		header:     http.Header{},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Client) Endpoint() string {
+	return c.endpoint
+}
+
+// An error response from the server
+type APIError struct {
+	StatusCode int
+	Message    string
+	Data       json.RawMessage // sent with some errors, e.g. a function's build log
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s (HTTP %d)", e.Message, e.StatusCode)
+}
+
+// Whether err is an APIError with the given HTTP status
+func IsStatus(err error, status int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == status
+}
+
+func IsNotFound(err error) bool {
+	return IsStatus(err, http.StatusNotFound)
+}
+
+func IsConflict(err error) bool {
+	return IsStatus(err, http.StatusConflict)
+}
+
+// Every JSON response is wrapped in this
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   string          `json:"error"`
+}
+
+// Send a JSON body (if not nil) and decode the response data into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	contentType := ""
+	if body != nil {
+		contentType = "application/json"
+	}
+	return c.doRaw(ctx, method, path, query, contentType, reader, out)
+}
+
+// Send body as is with the given content type and decode the response
+// data into out
+func (c *Client) doRaw(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, contentType, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("invalid response from server (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if !env.Success {
+		return &APIError{StatusCode: resp.StatusCode, Message: env.Error, Data: env.Data}
+	}
+	if out != nil && len(env.Data) > 0 && string(env.Data) != "null" {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
+
+// Make a request whose successful response isn't JSON, such as a download.
+// The caller closes the body.
+func (c *Client) stream(ctx context.Context, method, path string, query url.Values, body interface{}) (io.ReadCloser, http.Header, error) {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader, contentType = bytes.NewReader(data), "application/json"
+	}
+
+	resp, err := c.send(ctx, method, path, query, contentType, reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	// errors still come back in the JSON envelope
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		var env envelope
+		if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+			return nil, nil, &APIError{StatusCode: resp.StatusCode, Message: http.StatusText(resp.StatusCode)}
+		}
+		return nil, nil, &APIError{StatusCode: resp.StatusCode, Message: env.Error, Data: env.Data}
+	}
+	return resp.Body, resp.Header, nil
+}
+
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, contentType string, body io.Reader) (*http.Response, error) {
+	u := c.endpoint + "/api/v1" + path
+	if encoded := query.Encode(); encoded != "" {
+		u += "?" + encoded
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reach LocalCloud at %s: %w", c.endpoint, err)
+	}
+	return resp, nil
+}
+
+// Escape a name for use as one path segment
+func segment(name string) string {
+	return url.PathEscape(name)
+}
+
+// The server's OpenAPI document
+func (c *Client) OpenAPI(ctx context.Context) (json.RawMessage, error) {
+	body, _, err := c.stream(ctx, http.MethodGet, "/openapi.json", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	doc, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
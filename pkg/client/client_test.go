@@ -0,0 +1,161 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// A request as the fake server saw it
+type request struct {
+	method, path, rawPath, query string
+	header                       http.Header
+	body                         string
+}
+
+// Answer every request with status and the JSON envelope around data (or
+// errMsg), recording what was asked
+func newTestServer(t *testing.T, status int, data interface{}, errMsg string) (*httptest.Server, *request) {
+	t.Helper()
+	seen := &request{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*seen = request{r.Method, r.URL.Path, r.URL.EscapedPath(), r.URL.RawQuery, r.Header.Clone(), string(body)}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success": status < 400,
+			"data":    data,
+			"error":   errMsg,
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, seen
+}
+
+func TestDecodesEnvelope(t *testing.T) {
+	srv, seen := newTestServer(t, http.StatusOK, Secret{Name: "db-password", CurrentVersion: 2}, "")
+	c := New(srv.URL+"/", WithHeader("Authorization", "Bearer token"))
+
+	secret, err := c.GetSecret(context.Background(), "db-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Name != "db-password" || secret.CurrentVersion != 2 {
+		t.Errorf("secret = %+v", secret)
+	}
+	if seen.method != http.MethodGet || seen.path != "/api/v1/secrets/db-password" {
+		t.Errorf("requested %s %s", seen.method, seen.path)
+	}
+	if seen.header.Get("Authorization") != "Bearer token" {
+		t.Errorf("headers = %v", seen.header)
+	}
+
+	if _, err := c.RotateSecret(context.Background(), "db-password", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if seen.header.Get("Content-Type") != "application/json" || !strings.Contains(seen.body, `"value":"s3cret"`) {
+		t.Errorf("sent %q as %s", seen.body, seen.header.Get("Content-Type"))
+	}
+}
+
+func TestAPIError(t *testing.T) {
+	srv, _ := newTestServer(t, http.StatusNotFound, map[string]string{"log": "build failed"}, "secret not found")
+	c := New(srv.URL)
+
+	_, err := c.GetSecret(context.Background(), "missing")
+	apiErr, ok := err.(*APIError)
+	if !ok {
+		t.Fatalf("err = %T %v", err, err)
+	}
+	if apiErr.Message != "secret not found" || string(apiErr.Data) != `{"log":"build failed"}` {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	if !IsNotFound(err) || IsConflict(err) || err.Error() != "secret not found (HTTP 404)" {
+		t.Errorf("classified %v wrongly", err)
+	}
+
+	// streamed responses report errors the same way
+	_, err = c.FollowLogs(context.Background(), "web", 10)
+	if !IsNotFound(err) || !strings.Contains(err.Error(), "secret not found") {
+		t.Errorf("FollowLogs: %v", err)
+	}
+}
+
+func TestInvalidResponse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	c := New(srv.URL)
+
+	if _, err := c.GetSecret(context.Background(), "x"); err == nil || !strings.Contains(err.Error(), "HTTP 502") {
+		t.Errorf("GetSecret: %v", err)
+	}
+	if _, err := c.OpenAPI(context.Background()); !IsStatus(err, http.StatusBadGateway) {
+		t.Errorf("OpenAPI: %v", err)
+	}
+}
+
+func TestPathsAndQueries(t *testing.T) {
+	srv, seen := newTestServer(t, http.StatusOK, nil, "")
+	c := New(srv.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		call           func() error
+		rawPath, query string
+	}{
+		{func() error {
+			_, err := c.GetParameter(ctx, "/app/db/url", ParameterQuery{Decrypt: true, Version: 3})
+			return err
+		}, "/api/v1/parameters/app/db/url", "decrypt=true&version=3"},
+		{func() error {
+			_, err := c.GetParameter(ctx, "/app/a b/c?d", ParameterQuery{Label: "prod"})
+			return err
+		}, "/api/v1/parameters/app/a%20b/c%3Fd", "label=prod"},
+		{func() error {
+			_, err := c.ListParameters(ctx, "/app", ParameterQuery{Recursive: true})
+			return err
+		}, "/api/v1/parameters", "path=%2Fapp&recursive=true"},
+		{func() error {
+			_, _, err := c.SecretValue(ctx, "a/b", 2)
+			return err
+		}, "/api/v1/secrets/a%2Fb/value", "version=2"},
+		{func() error {
+			_, err := c.Logs(ctx, "web", 0)
+			return err
+		}, "/api/v1/containers/web/logs", ""},
+	}
+	for _, tt := range tests {
+		if err := tt.call(); err != nil {
+			t.Fatal(err)
+		}
+		if seen.rawPath != tt.rawPath || seen.query != tt.query {
+			t.Errorf("requested %s?%s, want %s?%s", seen.rawPath, seen.query, tt.rawPath, tt.query)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/openapi.json" {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{"openapi":"3.0.3"}`)
+	}))
+	defer srv.Close()
+
+	doc, err := New(srv.URL).OpenAPI(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(doc) != `{"openapi":"3.0.3"}` {
+		t.Errorf("document = %s", doc)
+	}
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+func (c *Client) ListSecrets(ctx context.Context) ([]Secret, error) {
+	var list []Secret
+	err := c.do(ctx, http.MethodGet, "/secrets", nil, nil, &list)
+	return list, err
+}
+
+type secretRequest struct {
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+	Value       string `json:"value,omitempty"`
+}
+
+// Create a secret; an empty value is generated
+func (c *Client) CreateSecret(ctx context.Context, name, description, value string) (*Secret, error) {
+	var secret Secret
+	body := secretRequest{Name: name, Description: description, Value: value}
+	if err := c.do(ctx, http.MethodPost, "/secrets", nil, body, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+func (c *Client) GetSecret(ctx context.Context, name string) (*Secret, error) {
+	var secret Secret
+	if err := c.do(ctx, http.MethodGet, "/secrets/"+segment(name), nil, nil, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+func (c *Client) DeleteSecret(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/secrets/"+segment(name), nil, nil, nil)
+}
+
+// A secret's value and its version, the latest if version is 0
+func (c *Client) SecretValue(ctx context.Context, name string, version int) (string, int, error) {
+	query := url.Values{}
+	if version > 0 {
+		query.Set("version", strconv.Itoa(version))
+	}
+	var result struct {
+		Version int    `json:"version"`
+		Value   string `json:"value"`
+	}
+	err := c.do(ctx, http.MethodGet, "/secrets/"+segment(name)+"/value", query, nil, &result)
+	return result.Value, result.Version, err
+}
+
+// Add a new version; an empty value is generated
+func (c *Client) RotateSecret(ctx context.Context, name, value string) (*Secret, error) {
+	var secret Secret
+	if err := c.do(ctx, http.MethodPost, "/secrets/"+segment(name)+"/rotate", nil, secretRequest{Value: value}, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// Hierarchical names keep their slashes, e.g. /app/db/url
+func parameterPath(prefix, name string) string {
+	parts := strings.Split(strings.TrimPrefix(name, "/"), "/")
+	for i, part := range parts {
+		parts[i] = url.PathEscape(part)
+	}
+	return prefix + "/" + strings.Join(parts, "/")
+}
+
+// The parameters under path, including nested paths with q.Recursive
+func (c *Client) ListParameters(ctx context.Context, path string, q ParameterQuery) ([]Parameter, error) {
+	query := url.Values{"path": {path}}
+	if q.Recursive {
+		query.Set("recursive", "true")
+	}
+	if q.Decrypt {
+		query.Set("decrypt", "true")
+	}
+	var list []Parameter
+	err := c.do(ctx, http.MethodGet, "/parameters", query, nil, &list)
+	return list, err
+}
+
+// A parameter's latest version, or the one picked by q.Version or q.Label
+func (c *Client) GetParameter(ctx context.Context, name string, q ParameterQuery) (*Parameter, error) {
+	query := url.Values{}
+	if q.Decrypt {
+		query.Set("decrypt", "true")
+	}
+	if q.Version > 0 {
+		query.Set("version", strconv.Itoa(q.Version))
+	}
+	if q.Label != "" {
+		query.Set("label", q.Label)
+	}
+	var param Parameter
+	if err := c.do(ctx, http.MethodGet, parameterPath("/parameters", name), query, nil, &param); err != nil {
+		return nil, err
+	}
+	return &param, nil
+}
+
+// Create a parameter or add a version, returning the new version number
+func (c *Client) PutParameter(ctx context.Context, in PutInput) (int, error) {
+	var result struct {
+		Version int `json:"version"`
+	}
+	err := c.do(ctx, http.MethodPut, parameterPath("/parameters", in.Name), nil, in, &result)
+	return result.Version, err
+}
+
+func (c *Client) DeleteParameter(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, parameterPath("/parameters", name), nil, nil, nil)
+}
+
+func (c *Client) ParameterHistory(ctx context.Context, name string, decrypt bool) ([]Parameter, error) {
+	query := url.Values{}
+	if decrypt {
+		query.Set("decrypt", "true")
+	}
+	var list []Parameter
+	err := c.do(ctx, http.MethodGet, parameterPath("/parameter-history", name), query, nil, &list)
+	return list, err
+}
+
+// Attach labels to a version, the latest if version is 0
+func (c *Client) LabelParameter(ctx context.Context, name string, version int, labels ...string) error {
+	body := struct {
+		Version int      `json:"version"`
+		Labels  []string `json:"labels"`
+	}{version, labels}
+	return c.do(ctx, http.MethodPost, parameterPath("/parameter-labels", name), nil, body, nil)
+}
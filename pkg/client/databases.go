@@ -0,0 +1,65 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+func (c *Client) ListDatabases(ctx context.Context) ([]DatabaseInfo, error) {
+	var list []DatabaseInfo
+	err := c.do(ctx, http.MethodGet, "/databases", nil, nil, &list)
+	return list, err
+}
+
+// Create a database; it keeps provisioning after this returns, until its
+// status is available
+func (c *Client) CreateDatabase(ctx context.Context, db Database) (*DatabaseInfo, error) {
+	var info DatabaseInfo
+	if err := c.do(ctx, http.MethodPost, "/databases", nil, db, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) GetDatabase(ctx context.Context, name string) (*DatabaseInfo, error) {
+	var info DatabaseInfo
+	if err := c.do(ctx, http.MethodGet, "/databases/"+segment(name), nil, nil, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) DeleteDatabase(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/databases/"+segment(name), nil, nil, nil)
+}
+
+func (c *Client) ListDatabaseBackups(ctx context.Context, name string) ([]DatabaseBackup, error) {
+	var list []DatabaseBackup
+	err := c.do(ctx, http.MethodGet, "/databases/"+segment(name)+"/backups", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) BackupDatabase(ctx context.Context, name string) (*DatabaseBackup, error) {
+	var backup DatabaseBackup
+	if err := c.do(ctx, http.MethodPost, "/databases/"+segment(name)+"/backups", nil, nil, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
+
+// Download a backup as SQL. The caller closes the reader.
+func (c *Client) DownloadDatabaseBackup(ctx context.Context, name, backupID string) (io.ReadCloser, error) {
+	body, _, err := c.stream(ctx, http.MethodGet, "/databases/"+segment(name)+"/backups/"+segment(backupID), nil, nil)
+	return body, err
+}
+
+// Restore a backup, the latest if backupID is empty
+func (c *Client) RestoreDatabase(ctx context.Context, name, backupID string) (*DatabaseBackup, error) {
+	var backup DatabaseBackup
+	body := map[string]string{"backup_id": backupID}
+	if err := c.do(ctx, http.MethodPost, "/databases/"+segment(name)+"/restore", nil, body, &backup); err != nil {
+		return nil, err
+	}
+	return &backup, nil
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/websocket"
+)
+
+// Subscribe to live updates over the /ws WebSocket: the instance list every
+// few seconds, parameter changes and operation progress. The channel is
+// closed when ctx is done or the connection drops, after which the returned
+// function reports why.
+func (c *Client) Events(ctx context.Context) (<-chan LiveUpdate, func() error, error) {
+	if !strings.HasPrefix(c.endpoint, "http") {
+		return nil, nil, fmt.Errorf("endpoint must be an http or https URL: %s", c.endpoint)
+	}
+	wsURL := "ws" + strings.TrimPrefix(c.endpoint, "http") + "/ws"
+
+	dialer := *websocket.DefaultDialer
+	if transport, ok := c.httpClient.Transport.(*http.Transport); ok {
+		dialer.TLSClientConfig = transport.TLSClientConfig
+	}
+	conn, resp, err := dialer.DialContext(ctx, wsURL, c.header)
+	if err != nil {
+		if resp != nil {
+			return nil, nil, &APIError{StatusCode: resp.StatusCode, Message: "failed to open event stream"}
+		}
+		return nil, nil, fmt.Errorf("failed to reach LocalCloud at %s: %w", c.endpoint, err)
+	}
+
+	updates := make(chan LiveUpdate)
+	var readErr error
+	done := make(chan struct{})
+
+	// Closing the connection unblocks the reader below
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		conn.Close()
+	}()
+
+	go func() {
+		defer close(updates)
+		defer close(done)
+		for {
+			var update LiveUpdate
+			if err := conn.ReadJSON(&update); err != nil {
+				if ctx.Err() == nil {
+					readErr = fmt.Errorf("event stream closed: %w", err)
+				}
+				return
+			}
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates, func() error { return readErr }, nil
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) ListFunctions(ctx context.Context) ([]FunctionStatus, error) {
+	var list []FunctionStatus
+	err := c.do(ctx, http.MethodGet, "/functions", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) GetFunction(ctx context.Context, name string) (*FunctionStatus, error) {
+	var status FunctionStatus
+	if err := c.do(ctx, http.MethodGet, "/functions/"+segment(name), nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Build and deploy a function from a zip of its code. The build log is
+// returned even when the build fails.
+func (c *Client) DeployFunction(ctx context.Context, spec FunctionSpec, code io.Reader) (*Deployment, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	fields := url.Values{
+		"name":    {spec.Name},
+		"runtime": {spec.Runtime},
+		"handler": {spec.Handler},
+		"env":     spec.Env,
+	}
+	if spec.MemoryMB > 0 {
+		fields.Set("memory_mb", strconv.Itoa(spec.MemoryMB))
+	}
+	if spec.Timeout > 0 {
+		fields.Set("timeout_seconds", strconv.Itoa(spec.Timeout))
+	}
+	for key, values := range fields {
+		for _, value := range values {
+			if err := writer.WriteField(key, value); err != nil {
+				return nil, fmt.Errorf("failed to encode request: %w", err)
+			}
+		}
+	}
+	part, err := writer.CreateFormFile("code", spec.Name+".zip")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	if _, err := io.Copy(part, code); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var deployment Deployment
+	err = c.doRaw(ctx, http.MethodPost, "/functions", nil, writer.FormDataContentType(), &buf, &deployment)
+	if apiErr, ok := err.(*APIError); ok && len(apiErr.Data) > 0 {
+		json.Unmarshal(apiErr.Data, &deployment)
+	}
+	return &deployment, err
+}
+
+func (c *Client) DeleteFunction(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/functions/"+segment(name), nil, nil, nil)
+}
+
+// Invoke a function with event, any JSON-encodable value, or nil for none
+func (c *Client) InvokeFunction(ctx context.Context, name string, event interface{}) (*Invocation, error) {
+	var invocation Invocation
+	if err := c.do(ctx, http.MethodPost, "/functions/"+segment(name)+"/invoke", nil, event, &invocation); err != nil {
+		return nil, err
+	}
+	return &invocation, nil
+}
+
+// Recent invocations, the server's default number if limit is 0
+func (c *Client) ListInvocations(ctx context.Context, name string, limit int) ([]Invocation, error) {
+	var list []Invocation
+	err := c.do(ctx, http.MethodGet, "/functions/"+segment(name)+"/invocations", limitQuery(limit), nil, &list)
+	return list, err
+}
+
+func limitQuery(limit int) url.Values {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	return query
+}
@@ -0,0 +1,241 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+)
+
+func (c *Client) ListInstances(ctx context.Context) ([]Instance, error) {
+	var list []Instance
+	err := c.do(ctx, http.MethodGet, "/containers", nil, nil, &list)
+	return list, err
+}
+
+// Find an instance by name, ID or ID prefix
+func (c *Client) GetInstance(ctx context.Context, id string) (*Instance, error) {
+	list, err := c.ListInstances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i, inst := range list {
+		if inst.Name == id || strings.HasPrefix(inst.ID, id) {
+			return &list[i], nil
+		}
+	}
+	return nil, &APIError{StatusCode: http.StatusNotFound, Message: "instance not found: " + id}
+}
+
+// Create an instance and wait until it is running
+func (c *Client) CreateInstance(ctx context.Context, spec CreateSpec) (*Instance, error) {
+	var inst Instance
+	if err := c.do(ctx, http.MethodPost, "/containers", nil, spec, &inst); err != nil {
+		return nil, err
+	}
+	return &inst, nil
+}
+
+// Start creating an instance; its result is an *Instance
+func (c *Client) CreateInstanceAsync(ctx context.Context, spec CreateSpec) (*Operation, error) {
+	return c.startOperation(ctx, http.MethodPost, "/containers", spec)
+}
+
+func (c *Client) DeleteInstance(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/containers/"+segment(id), nil, nil, nil)
+}
+
+func (c *Client) DeleteInstanceAsync(ctx context.Context, id string) (*Operation, error) {
+	return c.startOperation(ctx, http.MethodDelete, "/containers/"+segment(id), nil)
+}
+
+func (c *Client) ResizeInstance(ctx context.Context, id, instanceType string) (*Instance, error) {
+	var inst Instance
+	body := map[string]string{"instance_type": instanceType}
+	if err := c.do(ctx, http.MethodPost, "/containers/"+segment(id)+"/resize", nil, body, &inst); err != nil {
+		return nil, err
+	}
+	return &inst, nil
+}
+
+// Start a resize; its result is an *Instance
+func (c *Client) ResizeInstanceAsync(ctx context.Context, id, instanceType string) (*Operation, error) {
+	body := map[string]string{"instance_type": instanceType}
+	return c.startOperation(ctx, http.MethodPost, "/containers/"+segment(id)+"/resize", body)
+}
+
+// The last tail lines of output (0 for the server's default)
+func (c *Client) Logs(ctx context.Context, id string, tail int) (string, error) {
+	var logs string
+	err := c.do(ctx, http.MethodGet, "/containers/"+segment(id)+"/logs", tailQuery(tail), nil, &logs)
+	return logs, err
+}
+
+// Stream output as it is written, starting with the last tail lines, until
+// the instance stops or ctx is done. The caller closes the reader.
+func (c *Client) FollowLogs(ctx context.Context, id string, tail int) (io.ReadCloser, error) {
+	query := tailQuery(tail)
+	query.Set("follow", "true")
+	body, _, err := c.stream(ctx, http.MethodGet, "/containers/"+segment(id)+"/logs", query, nil)
+	return body, err
+}
+
+func tailQuery(tail int) url.Values {
+	query := url.Values{}
+	if tail > 0 {
+		query.Set("tail", strconv.Itoa(tail))
+	}
+	return query
+}
+
+func (c *Client) Metrics(ctx context.Context, id string) (*Metrics, error) {
+	var metrics Metrics
+	if err := c.do(ctx, http.MethodGet, "/containers/"+segment(id)+"/metrics", nil, nil, &metrics); err != nil {
+		return nil, err
+	}
+	return &metrics, nil
+}
+
+func (c *Client) Health(ctx context.Context, id string) (*HealthReport, error) {
+	var report HealthReport
+	if err := c.do(ctx, http.MethodGet, "/containers/"+segment(id)+"/health", nil, nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// Run a shell command in the instance and return its output
+func (c *Client) Exec(ctx context.Context, id, command string) (string, error) {
+	var output string
+	body := map[string]string{"command": command}
+	err := c.do(ctx, http.MethodPost, "/containers/"+segment(id)+"/exec", nil, body, &output)
+	return output, err
+}
+
+func (c *Client) ListPorts(ctx context.Context) ([]PortUsage, error) {
+	var list []PortUsage
+	err := c.do(ctx, http.MethodGet, "/ports", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) ListInstanceTypes(ctx context.Context) ([]InstanceType, error) {
+	var list []InstanceType
+	err := c.do(ctx, http.MethodGet, "/instance-types", nil, nil, &list)
+	return list, err
+}
+
+// Download a file from an instance, or a directory (or the file, with
+// asTar) as a tar archive. The caller closes the reader.
+func (c *Client) DownloadFile(ctx context.Context, id, filePath string, asTar bool) (io.ReadCloser, error) {
+	query := url.Values{"path": {filePath}}
+	if asTar {
+		query.Set("format", "tar")
+	}
+	body, _, err := c.stream(ctx, http.MethodGet, "/containers/"+segment(id)+"/files", query, nil)
+	return body, err
+}
+
+// Upload data as a file at filePath, or into it if it is a directory. mode
+// is the file's permissions, 0644 if zero.
+func (c *Client) UploadFile(ctx context.Context, id, filePath, name string, data io.Reader, mode int) (*FileInfo, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if mode != 0 {
+		if err := writer.WriteField("mode", fmt.Sprintf("%04o", mode)); err != nil {
+			return nil, fmt.Errorf("failed to encode request: %w", err)
+		}
+	}
+	if name == "" {
+		name = path.Base(filePath)
+	}
+	part, err := writer.CreateFormFile("file", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	if _, err := io.Copy(part, data); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	var uploaded []*FileInfo
+	query := url.Values{"path": {filePath}}
+	if err := c.doRaw(ctx, http.MethodPut, "/containers/"+segment(id)+"/files", query, writer.FormDataContentType(), &buf, &uploaded); err != nil {
+		return nil, err
+	}
+	if len(uploaded) == 0 {
+		return nil, nil
+	}
+	return uploaded[0], nil
+}
+
+// Extract a tar archive into the directory dir
+func (c *Client) UploadTar(ctx context.Context, id, dir string, archive io.Reader) error {
+	query := url.Values{"path": {dir}}
+	return c.doRaw(ctx, http.MethodPut, "/containers/"+segment(id)+"/files", query, "application/x-tar", archive, nil)
+}
+
+// List a directory or preview a text file
+func (c *Client) BrowsePath(ctx context.Context, id, filePath string) (*PathContents, error) {
+	var contents PathContents
+	query := url.Values{"path": {filePath}}
+	if err := c.do(ctx, http.MethodGet, "/containers/"+segment(id)+"/fs", query, nil, &contents); err != nil {
+		return nil, err
+	}
+	return &contents, nil
+}
+
+// Save a text file, keeping its mode and owner if it exists
+func (c *Client) SaveFile(ctx context.Context, id, filePath, content string) (*FileInfo, error) {
+	var info FileInfo
+	query := url.Values{"path": {filePath}}
+	body := map[string]string{"content": content}
+	if err := c.do(ctx, http.MethodPut, "/containers/"+segment(id)+"/fs", query, body, &info); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (c *Client) ListSnapshots(ctx context.Context) ([]Snapshot, error) {
+	var list []Snapshot
+	err := c.do(ctx, http.MethodGet, "/snapshots", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) GetSnapshot(ctx context.Context, name string) (*Snapshot, error) {
+	var snapshot Snapshot
+	if err := c.do(ctx, http.MethodGet, "/snapshots/"+segment(name), nil, nil, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+type snapshotRequest struct {
+	SnapshotInput
+	InstanceID string `json:"instance_id"`
+}
+
+func (c *Client) CreateSnapshot(ctx context.Context, instanceID string, in SnapshotInput) (*Snapshot, error) {
+	var snapshot Snapshot
+	body := snapshotRequest{SnapshotInput: in, InstanceID: instanceID}
+	if err := c.do(ctx, http.MethodPost, "/snapshots", nil, body, &snapshot); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Start a snapshot; its result is a *Snapshot
+func (c *Client) CreateSnapshotAsync(ctx context.Context, instanceID string, in SnapshotInput) (*Operation, error) {
+	return c.startOperation(ctx, http.MethodPost, "/snapshots", snapshotRequest{SnapshotInput: in, InstanceID: instanceID})
+}
+
+func (c *Client) DeleteSnapshot(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/snapshots/"+segment(name), nil, nil, nil)
+}
@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+func (c *Client) ListSchedules(ctx context.Context) ([]ScheduledJobStatus, error) {
+	var list []ScheduledJobStatus
+	err := c.do(ctx, http.MethodGet, "/schedules", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateSchedule(ctx context.Context, job ScheduledJob) (*ScheduledJobStatus, error) {
+	var status ScheduledJobStatus
+	if err := c.do(ctx, http.MethodPost, "/schedules", nil, job, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) GetSchedule(ctx context.Context, name string) (*ScheduledJobStatus, error) {
+	return c.scheduleAction(ctx, http.MethodGet, name, "")
+}
+
+func (c *Client) DeleteSchedule(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/schedules/"+segment(name), nil, nil, nil)
+}
+
+func (c *Client) SuspendSchedule(ctx context.Context, name string) (*ScheduledJobStatus, error) {
+	return c.scheduleAction(ctx, http.MethodPost, name, "/suspend")
+}
+
+func (c *Client) ResumeSchedule(ctx context.Context, name string) (*ScheduledJobStatus, error) {
+	return c.scheduleAction(ctx, http.MethodPost, name, "/resume")
+}
+
+func (c *Client) scheduleAction(ctx context.Context, method, name, action string) (*ScheduledJobStatus, error) {
+	var status ScheduledJobStatus
+	if err := c.do(ctx, method, "/schedules/"+segment(name)+action, nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Start a run now; poll GetScheduleRun for its outcome
+func (c *Client) RunSchedule(ctx context.Context, name string) (*Run, error) {
+	var run Run
+	if err := c.do(ctx, http.MethodPost, "/schedules/"+segment(name)+"/run", nil, nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Recent runs, the server's default number if limit is 0
+func (c *Client) ListScheduleRuns(ctx context.Context, name string, limit int) ([]Run, error) {
+	var list []Run
+	err := c.do(ctx, http.MethodGet, "/schedules/"+segment(name)+"/runs", limitQuery(limit), nil, &list)
+	return list, err
+}
+
+func (c *Client) GetScheduleRun(ctx context.Context, name, runID string) (*Run, error) {
+	var run Run
+	if err := c.do(ctx, http.MethodGet, "/schedules/"+segment(name)+"/runs/"+segment(runID), nil, nil, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Batch jobs, optionally only those in a job queue or with a status
+func (c *Client) ListJobs(ctx context.Context, queue, status string) ([]BatchJobStatus, error) {
+	query := url.Values{}
+	if queue != "" {
+		query.Set("queue", queue)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+	var list []BatchJobStatus
+	err := c.do(ctx, http.MethodGet, "/jobs", query, nil, &list)
+	return list, err
+}
+
+func (c *Client) SubmitJob(ctx context.Context, in SubmitInput) (*BatchJobStatus, error) {
+	var status BatchJobStatus
+	if err := c.do(ctx, http.MethodPost, "/jobs", nil, in, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) GetJob(ctx context.Context, id string) (*BatchJobStatus, error) {
+	var status BatchJobStatus
+	if err := c.do(ctx, http.MethodGet, "/jobs/"+segment(id), nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// An array job's children
+func (c *Client) JobChildren(ctx context.Context, id string) ([]BatchJob, error) {
+	var list []BatchJob
+	err := c.do(ctx, http.MethodGet, "/jobs/"+segment(id)+"/children", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CancelJob(ctx context.Context, id, reason string) (*BatchJobStatus, error) {
+	var status BatchJobStatus
+	body := map[string]string{"reason": reason}
+	if err := c.do(ctx, http.MethodPost, "/jobs/"+segment(id)+"/cancel", nil, body, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Output of a job attempt, the latest if attempt is 0
+func (c *Client) JobLogs(ctx context.Context, id string, attempt int) (string, error) {
+	query := url.Values{}
+	if attempt > 0 {
+		query.Set("attempt", strconv.Itoa(attempt))
+	}
+	var result struct {
+		Logs string `json:"logs"`
+	}
+	err := c.do(ctx, http.MethodGet, "/jobs/"+segment(id)+"/logs", query, nil, &result)
+	return result.Logs, err
+}
+
+func (c *Client) ListJobQueues(ctx context.Context) ([]JobQueueStatus, error) {
+	var list []JobQueueStatus
+	err := c.do(ctx, http.MethodGet, "/job-queues", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateJobQueue(ctx context.Context, queue JobQueue) (*JobQueue, error) {
+	var created JobQueue
+	if err := c.do(ctx, http.MethodPost, "/job-queues", nil, queue, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Change how many jobs a queue runs at once
+func (c *Client) SetJobQueueParallelism(ctx context.Context, name string, maxParallel int) (*JobQueue, error) {
+	var queue JobQueue
+	body := map[string]int{"max_parallel": maxParallel}
+	if err := c.do(ctx, http.MethodPut, "/job-queues/"+segment(name), nil, body, &queue); err != nil {
+		return nil, err
+	}
+	return &queue, nil
+}
+
+func (c *Client) DeleteJobQueue(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/job-queues/"+segment(name), nil, nil, nil)
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) ListAlarms(ctx context.Context) ([]AlarmStatus, error) {
+	var list []AlarmStatus
+	err := c.do(ctx, http.MethodGet, "/alarms", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateAlarm(ctx context.Context, alarm Alarm) (*AlarmStatus, error) {
+	var status AlarmStatus
+	if err := c.do(ctx, http.MethodPost, "/alarms", nil, alarm, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) GetAlarm(ctx context.Context, name string) (*AlarmStatus, error) {
+	var status AlarmStatus
+	if err := c.do(ctx, http.MethodGet, "/alarms/"+segment(name), nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) DeleteAlarm(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/alarms/"+segment(name), nil, nil, nil)
+}
+
+func (c *Client) AlarmHistory(ctx context.Context, name string) ([]AlarmTransition, error) {
+	var list []AlarmTransition
+	err := c.do(ctx, http.MethodGet, "/alarms/"+segment(name)+"/history", nil, nil, &list)
+	return list, err
+}
+
+// Force an alarm into state to try out its actions
+func (c *Client) SetAlarmState(ctx context.Context, name, state, reason string) (*AlarmStatus, error) {
+	var status AlarmStatus
+	body := map[string]string{"state": state, "reason": reason}
+	if err := c.do(ctx, http.MethodPost, "/alarms/"+segment(name)+"/state", nil, body, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) ListEventRules(ctx context.Context) ([]EventRule, error) {
+	var list []EventRule
+	err := c.do(ctx, http.MethodGet, "/eventbus/rules", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateEventRule(ctx context.Context, rule EventRule) (*EventRule, error) {
+	return c.eventRule(ctx, http.MethodPost, "/eventbus/rules", rule)
+}
+
+func (c *Client) GetEventRule(ctx context.Context, name string) (*EventRule, error) {
+	return c.eventRule(ctx, http.MethodGet, "/eventbus/rules/"+segment(name), nil)
+}
+
+// Replace a rule; redacted webhook secrets are kept
+func (c *Client) UpdateEventRule(ctx context.Context, rule EventRule) (*EventRule, error) {
+	return c.eventRule(ctx, http.MethodPut, "/eventbus/rules/"+segment(rule.Name), rule)
+}
+
+func (c *Client) DeleteEventRule(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/eventbus/rules/"+segment(name), nil, nil, nil)
+}
+
+func (c *Client) EnableEventRule(ctx context.Context, name string) (*EventRule, error) {
+	return c.eventRule(ctx, http.MethodPost, "/eventbus/rules/"+segment(name)+"/enable", nil)
+}
+
+func (c *Client) DisableEventRule(ctx context.Context, name string) (*EventRule, error) {
+	return c.eventRule(ctx, http.MethodPost, "/eventbus/rules/"+segment(name)+"/disable", nil)
+}
+
+func (c *Client) eventRule(ctx context.Context, method, path string, body interface{}) (*EventRule, error) {
+	var rule EventRule
+	if err := c.do(ctx, method, path, nil, body, &rule); err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// Recent events, newest first
+func (c *Client) ListEvents(ctx context.Context) ([]Event, error) {
+	var list []Event
+	err := c.do(ctx, http.MethodGet, "/eventbus/events", nil, nil, &list)
+	return list, err
+}
+
+// Publish custom events, returning them with their IDs and times filled in
+func (c *Client) PutEvents(ctx context.Context, events ...Event) ([]Event, error) {
+	var list []Event
+	body := map[string][]Event{"events": events}
+	err := c.do(ctx, http.MethodPost, "/eventbus/events", nil, body, &list)
+	return list, err
+}
+
+// Delivery log, newest first, optionally only one rule's or one status
+func (c *Client) ListDeliveries(ctx context.Context, rule, status string) ([]Delivery, error) {
+	query := url.Values{}
+	if rule != "" {
+		query.Set("rule", rule)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+	var list []Delivery
+	err := c.do(ctx, http.MethodGet, "/eventbus/deliveries", query, nil, &list)
+	return list, err
+}
+
+// Whether event matches pattern, a JSON event pattern
+func (c *Client) TestEventPattern(ctx context.Context, pattern json.RawMessage, event Event) (bool, error) {
+	body := struct {
+		EventPattern json.RawMessage `json:"event_pattern"`
+		Event        Event           `json:"event"`
+	}{pattern, event}
+	var result struct {
+		Matched bool `json:"matched"`
+	}
+	err := c.do(ctx, http.MethodPost, "/eventbus/test-pattern", nil, body, &result)
+	return result.Matched, err
+}
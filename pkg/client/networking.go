@@ -0,0 +1,120 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+func (c *Client) ListScalingGroups(ctx context.Context) ([]ScalingGroupStatus, error) {
+	var list []ScalingGroupStatus
+	err := c.do(ctx, http.MethodGet, "/autoscaling/groups", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateScalingGroup(ctx context.Context, group ScalingGroup) (*ScalingGroup, error) {
+	var created ScalingGroup
+	if err := c.do(ctx, http.MethodPost, "/autoscaling/groups", nil, group, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) GetScalingGroup(ctx context.Context, name string) (*ScalingGroupStatus, error) {
+	var status ScalingGroupStatus
+	if err := c.do(ctx, http.MethodGet, "/autoscaling/groups/"+segment(name), nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) SetCapacity(ctx context.Context, name string, capacity Capacity) (*ScalingGroup, error) {
+	var group ScalingGroup
+	if err := c.do(ctx, http.MethodPut, "/autoscaling/groups/"+segment(name)+"/capacity", nil, capacity, &group); err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (c *Client) DeleteScalingGroup(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/autoscaling/groups/"+segment(name), nil, nil, nil)
+}
+
+func (c *Client) ScalingActivities(ctx context.Context, name string) ([]ScalingActivity, error) {
+	var list []ScalingActivity
+	err := c.do(ctx, http.MethodGet, "/autoscaling/groups/"+segment(name)+"/activities", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) ScalingSamples(ctx context.Context, name string) ([]ScalingSample, error) {
+	var list []ScalingSample
+	err := c.do(ctx, http.MethodGet, "/autoscaling/groups/"+segment(name)+"/samples", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) ListLoadBalancers(ctx context.Context) ([]LoadBalancerStatus, error) {
+	var list []LoadBalancerStatus
+	err := c.do(ctx, http.MethodGet, "/loadbalancers", nil, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateLoadBalancer(ctx context.Context, lb LoadBalancer) (*LoadBalancer, error) {
+	var created LoadBalancer
+	if err := c.do(ctx, http.MethodPost, "/loadbalancers", nil, lb, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client) GetLoadBalancer(ctx context.Context, name string) (*LoadBalancerStatus, error) {
+	var status LoadBalancerStatus
+	if err := c.do(ctx, http.MethodGet, "/loadbalancers/"+segment(name), nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) SetTargets(ctx context.Context, name string, targets TargetGroup) (*LoadBalancer, error) {
+	var lb LoadBalancer
+	if err := c.do(ctx, http.MethodPut, "/loadbalancers/"+segment(name)+"/targets", nil, targets, &lb); err != nil {
+		return nil, err
+	}
+	return &lb, nil
+}
+
+func (c *Client) DeleteLoadBalancer(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/loadbalancers/"+segment(name), nil, nil, nil)
+}
+
+// Every DNS record, or with a name only those answering it
+func (c *Client) ListDNSRecords(ctx context.Context, name string) ([]DNSRecord, error) {
+	query := url.Values{}
+	if name != "" {
+		query.Set("name", name)
+	}
+	var list []DNSRecord
+	err := c.do(ctx, http.MethodGet, "/dns/records", query, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateDNSRecord(ctx context.Context, record DNSRecord) (*DNSRecord, error) {
+	var created DNSRecord
+	if err := c.do(ctx, http.MethodPost, "/dns/records", nil, record, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+// Delete the static records for name, of every type if recordType is empty,
+// returning how many were removed
+func (c *Client) DeleteDNSRecords(ctx context.Context, name, recordType string) (int, error) {
+	query := url.Values{"name": {name}}
+	if recordType != "" {
+		query.Set("type", recordType)
+	}
+	var result struct {
+		Removed int `json:"removed"`
+	}
+	err := c.do(ctx, http.MethodDelete, "/dns/records", query, nil, &result)
+	return result.Removed, err
+}
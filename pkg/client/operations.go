@@ -0,0 +1,94 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Longest the server holds a single wait, see GetOperation
+const maxWait = 2 * time.Minute
+
+// Make a request with ?async=true, returning the operation it started
+func (c *Client) startOperation(ctx context.Context, method, path string, body interface{}) (*Operation, error) {
+	var op Operation
+	if err := c.do(ctx, method, path, url.Values{"async": {"true"}}, body, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// Operations, newest first, optionally only one kind (e.g. instance.create)
+// or status
+func (c *Client) ListOperations(ctx context.Context, kind, status string) ([]Operation, error) {
+	query := url.Values{}
+	if kind != "" {
+		query.Set("kind", kind)
+	}
+	if status != "" {
+		query.Set("status", status)
+	}
+	var list []Operation
+	err := c.do(ctx, http.MethodGet, "/operations", query, nil, &list)
+	return list, err
+}
+
+// Get an operation, holding the request up to wait (at most two minutes)
+// for it to finish
+func (c *Client) GetOperation(ctx context.Context, id string, wait time.Duration) (*Operation, error) {
+	query := url.Values{}
+	if seconds := int(wait / time.Second); seconds > 0 {
+		query.Set("wait", strconv.Itoa(seconds))
+	}
+	var op Operation
+	if err := c.do(ctx, http.MethodGet, "/operations/"+segment(id), query, nil, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
+
+// Block until the operation finishes or ctx is done. A failed or cancelled
+// operation is returned along with an error.
+func (c *Client) WaitOperation(ctx context.Context, id string) (*Operation, error) {
+	for {
+		wait := maxWait
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			wait = time.Until(deadline)
+		}
+		op, err := c.GetOperation(ctx, id, wait)
+		if err != nil {
+			return nil, err
+		}
+		switch op.Status {
+		case OperationSucceeded:
+			return op, nil
+		case OperationFailed:
+			return op, fmt.Errorf("operation %s failed: %s", op.ID, op.Error)
+		case OperationCancelled:
+			return op, fmt.Errorf("operation %s was cancelled", op.ID)
+		}
+		if err := ctx.Err(); err != nil {
+			return op, err
+		}
+		// A sub-second wait returns at once; don't spin
+		if wait < time.Second {
+			select {
+			case <-time.After(200 * time.Millisecond):
+			case <-ctx.Done():
+				return op, ctx.Err()
+			}
+		}
+	}
+}
+
+// Ask a running operation to stop; it reports cancelled once it has
+func (c *Client) CancelOperation(ctx context.Context, id string) (*Operation, error) {
+	var op Operation
+	if err := c.do(ctx, http.MethodPost, "/operations/"+segment(id)+"/cancel", nil, nil, &op); err != nil {
+		return nil, err
+	}
+	return &op, nil
+}
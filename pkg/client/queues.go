@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Queues, optionally only those whose name starts with prefix
+func (c *Client) ListQueues(ctx context.Context, prefix string) ([]QueueStatus, error) {
+	query := url.Values{}
+	if prefix != "" {
+		query.Set("prefix", prefix)
+	}
+	var list []QueueStatus
+	err := c.do(ctx, http.MethodGet, "/queues", query, nil, &list)
+	return list, err
+}
+
+func (c *Client) CreateQueue(ctx context.Context, queue Queue) (*QueueStatus, error) {
+	var status QueueStatus
+	if err := c.do(ctx, http.MethodPost, "/queues", nil, queue, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) GetQueue(ctx context.Context, name string) (*QueueStatus, error) {
+	var status QueueStatus
+	if err := c.do(ctx, http.MethodGet, "/queues/"+segment(name), nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (c *Client) DeleteQueue(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodDelete, "/queues/"+segment(name), nil, nil, nil)
+}
+
+func (c *Client) PurgeQueue(ctx context.Context, name string) error {
+	return c.do(ctx, http.MethodPost, "/queues/"+segment(name)+"/purge", nil, nil, nil)
+}
+
+// Move dead-lettered messages back to their source queue, returning how
+// many were moved
+func (c *Client) RedriveQueue(ctx context.Context, name string) (int, error) {
+	var result struct {
+		Moved int `json:"moved"`
+	}
+	err := c.do(ctx, http.MethodPost, "/queues/"+segment(name)+"/redrive", nil, nil, &result)
+	return result.Moved, err
+}
+
+func (c *Client) SendMessage(ctx context.Context, name string, in SendInput) (*SendResult, error) {
+	var result SendResult
+	if err := c.do(ctx, http.MethodPost, "/queues/"+segment(name)+"/messages", nil, in, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Receive up to in.MaxMessages messages, long polling for in.WaitSeconds
+func (c *Client) ReceiveMessages(ctx context.Context, name string, in ReceiveInput) ([]Message, error) {
+	query := url.Values{}
+	if in.MaxMessages > 0 {
+		query.Set("max", strconv.Itoa(in.MaxMessages))
+	}
+	if in.VisibilityTimeout != nil {
+		query.Set("visibility", strconv.Itoa(*in.VisibilityTimeout))
+	}
+	if in.WaitSeconds != nil {
+		query.Set("wait", strconv.Itoa(*in.WaitSeconds))
+	}
+	var list []Message
+	err := c.do(ctx, http.MethodGet, "/queues/"+segment(name)+"/messages", query, nil, &list)
+	return list, err
+}
+
+func (c *Client) DeleteMessage(ctx context.Context, name, receiptHandle string) error {
+	query := url.Values{"receipt_handle": {receiptHandle}}
+	return c.do(ctx, http.MethodDelete, "/queues/"+segment(name)+"/messages", query, nil, nil)
+}
+
+func (c *Client) QueueSamples(ctx context.Context, name string) ([]QueueSample, error) {
+	var list []QueueSample
+	err := c.do(ctx, http.MethodGet, "/queues/"+segment(name)+"/samples", nil, nil, &list)
+	return list, err
+}
@@ -0,0 +1,223 @@
+package client
+
+import (
+	"encoding/json"
+	"time"
+
+	"localcloud/internal/alarms"
+	"localcloud/internal/autoscaling"
+	"localcloud/internal/backups"
+	"localcloud/internal/batch"
+	"localcloud/internal/billing"
+	"localcloud/internal/bundles"
+	"localcloud/internal/compute"
+	"localcloud/internal/databases"
+	"localcloud/internal/dns"
+	"localcloud/internal/eventbus"
+	"localcloud/internal/functions"
+	"localcloud/internal/loadbalancer"
+	"localcloud/internal/operations"
+	"localcloud/internal/parameters"
+	"localcloud/internal/queues"
+	"localcloud/internal/quotas"
+	"localcloud/internal/scheduler"
+	"localcloud/internal/secrets"
+)
+
+// The API's types are the server's own, so they can't drift apart
+
+// Instances
+type (
+	Instance     = compute.Instance
+	CreateSpec   = compute.CreateSpec
+	Metrics      = compute.Metrics
+	HealthReport = compute.HealthReport
+	FileInfo     = compute.FileInfo
+	PortUsage    = compute.PortUsage
+	InstanceType = compute.InstanceType
+	Quota        = compute.Quota
+)
+
+// Snapshots
+type (
+	Snapshot      = compute.Snapshot
+	SnapshotInput = compute.SnapshotInput
+)
+
+// Auto scaling
+type (
+	ScalingGroup       = autoscaling.Group
+	ScalingGroupStatus = autoscaling.GroupStatus
+	ScalingActivity    = autoscaling.Activity
+	ScalingSample      = autoscaling.Sample
+)
+
+// Load balancers
+type (
+	LoadBalancer       = loadbalancer.Balancer
+	LoadBalancerStatus = loadbalancer.Status
+	TargetGroup        = loadbalancer.TargetGroup
+)
+
+type DNSRecord = dns.Record
+
+// Functions
+type (
+	Function       = functions.Function
+	FunctionStatus = functions.Status
+	Invocation     = functions.Invocation
+)
+
+// Databases
+type (
+	Database       = databases.Database
+	DatabaseInfo   = databases.Info
+	DatabaseBackup = databases.Backup
+)
+
+// Queues
+type (
+	Queue        = queues.Queue
+	QueueStatus  = queues.Status
+	SendInput    = queues.SendInput
+	SendResult   = queues.SendResult
+	ReceiveInput = queues.ReceiveInput
+	Message      = queues.Message
+	QueueSample  = queues.Sample
+)
+
+type Secret = secrets.Secret
+
+// Parameters
+type (
+	Parameter       = parameters.Parameter
+	PutInput        = parameters.PutInput
+	ParameterChange = parameters.Change
+)
+
+// Schedules
+type (
+	ScheduledJob       = scheduler.Job
+	ScheduledJobStatus = scheduler.JobStatus
+	Run                = scheduler.Run
+)
+
+// Batch
+type (
+	BatchJob       = batch.Job
+	BatchJobStatus = batch.JobStatus
+	SubmitInput    = batch.SubmitInput
+	JobQueue       = batch.Queue
+	JobQueueStatus = batch.QueueStatus
+)
+
+// Backups
+type (
+	Backup           = backups.Backup
+	BackupInput      = backups.CreateInput
+	RestoreInput     = backups.RestoreInput
+	PruneInput       = backups.PruneInput
+	BackupPlan       = backups.Plan
+	BackupPlanStatus = backups.PlanStatus
+)
+
+// Bundles
+type (
+	BundleOptions = bundles.Options
+	ImportOptions = bundles.ImportOptions
+	ImportReport  = bundles.Report
+)
+
+type QuotaStatus = quotas.Status
+
+// Billing
+type (
+	PriceSheet    = billing.PriceSheet
+	BillingReport = billing.Report
+	ReportInput   = billing.ReportInput
+	Budget        = billing.Budget
+	BudgetStatus  = billing.BudgetStatus
+	BudgetAlert   = billing.Alert
+)
+
+// Alarms
+type (
+	Alarm           = alarms.Alarm
+	AlarmStatus     = alarms.AlarmStatus
+	AlarmTransition = alarms.Transition
+)
+
+// Event bus
+type (
+	EventRule = eventbus.Rule
+	Event     = eventbus.Event
+	Delivery  = eventbus.Delivery
+)
+
+type Operation = operations.Operation
+
+const (
+	OperationRunning   = operations.StatusRunning
+	OperationSucceeded = operations.StatusSucceeded
+	OperationFailed    = operations.StatusFailed
+	OperationCancelled = operations.StatusCancelled
+)
+
+// A directory listing or text file preview from BrowsePath
+type PathContents struct {
+	*FileInfo
+	Entries   []FileInfo `json:"entries,omitempty"`
+	Content   string     `json:"content"`
+	Binary    bool       `json:"binary"`
+	Truncated bool       `json:"truncated"`
+	Editable  bool       `json:"editable"`
+}
+
+// Settings for DeployFunction; the code goes alongside as a zip
+type FunctionSpec struct {
+	Name     string
+	Runtime  string
+	Handler  string
+	Env      []string // KEY=VALUE
+	MemoryMB int
+	Timeout  int // seconds
+}
+
+// A function deployment and the output of its image build
+type Deployment struct {
+	Function *Function `json:"function"`
+	BuildLog string    `json:"build_log"`
+}
+
+// Only the fields that are set change
+type Capacity struct {
+	Min     *int `json:"min,omitempty"`
+	Max     *int `json:"max,omitempty"`
+	Desired *int `json:"desired,omitempty"`
+}
+
+// Options for GetParameter and ListParameters
+type ParameterQuery struct {
+	Decrypt   bool
+	Version   int    // GetParameter only
+	Label     string // GetParameter only
+	Recursive bool   // ListParameters only
+}
+
+// A message from the /ws event stream; only the fields for one kind of
+// update are set
+type LiveUpdate struct {
+	Containers []Instance       `json:"containers,omitempty"`
+	Parameter  *ParameterChange `json:"parameter,omitempty"`
+	Operation  *Operation       `json:"operation,omitempty"`
+	Timestamp  time.Time        `json:"timestamp"`
+}
+
+// Decode a finished operation's result, e.g. the *Instance of an
+// instance.create
+func DecodeResult(op *Operation, out interface{}) error {
+	if len(op.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(op.Result, out)
+}